@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/korjavin/medicationtrackerbot/internal/bot"
+	"github.com/korjavin/medicationtrackerbot/internal/rxnorm"
 	"github.com/korjavin/medicationtrackerbot/internal/scheduler"
 	"github.com/korjavin/medicationtrackerbot/internal/server"
 	"github.com/korjavin/medicationtrackerbot/internal/store"
@@ -45,6 +47,19 @@ func main() {
 	defer s.Close()
 	log.Println("Database initialized at", dbPath)
 
+	// Periodic PASSIVE WAL checkpoints keep the WAL from growing; "0" disables them
+	walInterval := time.Hour
+	if v := os.Getenv("WAL_CHECKPOINT_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid WAL_CHECKPOINT_INTERVAL %q: use a duration like 30m, or 0 to disable", v)
+		}
+		walInterval = d
+	}
+	if walInterval > 0 {
+		go runWALCheckpoints(s, walInterval)
+	}
+
 	// 3. Bot
 	var tgBot *bot.Bot
 	if botToken != "" {
@@ -77,6 +92,9 @@ func main() {
 		PrivateKey: os.Getenv("VAPID_PRIVATE_KEY"),
 		Subject:    os.Getenv("VAPID_SUBJECT"),
 	}
+	if c, err := strconv.Atoi(os.Getenv("WEBPUSH_CONCURRENCY")); err == nil && c > 0 {
+		vapidConfig.Concurrency = c
+	}
 
 	// 5. Server (Initialize first to get WebPush service)
 	oidcConfig := server.OIDCConfig{
@@ -96,9 +114,21 @@ func main() {
 	srv := server.New(s, tgBot, botToken, allowedUserID, oidcConfig, botUsername, vapidConfig)
 
 	if tgBot != nil {
-		// Scheduler needs WebPush service from server
-		sch := scheduler.New(s, tgBot, allowedUserID, srv.GetWebPushService())
+		tgBot.SetWebhookService(srv.GetWebhookService())
+		tgBot.SetRxNormClient(rxnorm.New())
+
+		// Scheduler needs WebPush and webhook services from server
+		sch := scheduler.New(s, tgBot, allowedUserID, srv.GetWebPushService(), srv.GetWebhookService())
+		// Unconfirmed doses count as missed after MISSED_AFTER; "0" leaves them pending
+		if v := os.Getenv("MISSED_AFTER"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				log.Fatalf("Invalid MISSED_AFTER %q: use a duration like 6h, or 0 to disable", v)
+			}
+			sch.SetMissedAfter(d)
+		}
 		sch.Start()
+		srv.SetScheduler(sch)
 		log.Println("Scheduler started")
 	}
 
@@ -118,3 +148,17 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// runWALCheckpoints checkpoints the WAL on every tick and logs what was done
+func runWALCheckpoints(s *store.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		res, err := s.CheckpointWAL(context.Background())
+		if err != nil {
+			log.Printf("Error checkpointing WAL: %v", err)
+			continue
+		}
+		log.Printf("WAL checkpoint: %d/%d frames, busy=%v, size %d -> %d bytes",
+			res.CheckpointedFrames, res.LogFrames, res.Busy, res.WALSizeBefore, res.WALSizeAfter)
+	}
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strconv"
@@ -18,12 +20,25 @@ func main() {
 	csvPath := flag.String("csv", "", "Path to CSV file")
 	userID := flag.Int64("user", 0, "User ID (optional, will use first user if not provided)")
 	dbPath := flag.String("db", "data.db", "Path to SQLite database")
+	chunkSize := flag.Int("chunk", 500, "Number of records committed per transaction")
+	dateLayout := flag.String("layout", "2006-01-02 15:04", "Go time layout of the date column")
+	tz := flag.String("tz", "", "IANA time zone of the dates, e.g. Europe/Berlin (default: read them as UTC)")
 	flag.Parse()
 
 	if *csvPath == "" {
 		log.Fatal("Please provide -csv <path>")
 	}
 
+	// Without -tz dates are read as UTC, as they always were
+	loc := time.UTC
+	if *tz != "" {
+		l, err := time.LoadLocation(*tz)
+		if err != nil {
+			log.Fatalf("Invalid -tz %q: %v", *tz, err)
+		}
+		loc = l
+	}
+
 	// Open database
 	s, err := store.New(*dbPath)
 	if err != nil {
@@ -54,6 +69,8 @@ func main() {
 
 	reader := csv.NewReader(file)
 	reader.TrimLeadingSpace = true
+	// Short or long rows are reported per column below rather than aborting the import
+	reader.FieldsPerRecord = -1
 
 	// Read header
 	header, err := reader.Read()
@@ -75,19 +92,26 @@ func main() {
 		}
 	}
 
-	// Parse date layout as per CSV format
-	dateLayout := "2006-01-02 15:04"
-
 	var readings []store.BloodPressure
-	var skippedRows int
+	var skipped []int
 	rowNum := 1
 
 	for {
 		row, err := reader.Read()
-		if err != nil {
+		if err == io.EOF {
 			break
 		}
 		rowNum++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				log.Fatalf("Failed to read CSV: %v", err)
+			}
+			// Report the malformed record and carry on with the next one
+			log.Printf("Error: Row %d - Malformed CSV row: %v", rowNum, err)
+			skipped = append(skipped, rowNum)
+			continue
+		}
 
 		// Skip empty rows
 		if len(row) == 0 || (len(row) == 1 && strings.TrimSpace(row[0]) == "") {
@@ -99,13 +123,14 @@ func main() {
 		// Parse Date (required)
 		dateStr := getCol(row, colMap, "date")
 		if dateStr != "" {
-			parsedTime, err := time.Parse(dateLayout, strings.TrimSpace(dateStr))
+			parsedTime, err := time.ParseInLocation(*dateLayout, strings.TrimSpace(dateStr), loc)
 			if err != nil {
-				log.Printf("Warning: Row %d - Invalid date format '%s': %v", rowNum, dateStr, err)
-				skippedRows++
+				log.Printf("Error: Row %d - Cannot parse date '%s' with layout '%s', skipping", rowNum, dateStr, *dateLayout)
+				skipped = append(skipped, rowNum)
 				continue
 			}
-			bp.MeasuredAt = parsedTime
+			// Stored as UTC like the rest of the readings
+			bp.MeasuredAt = parsedTime.UTC()
 		}
 
 		// Parse Systolic (required)
@@ -113,8 +138,8 @@ func main() {
 		if systolicStr != "" {
 			systolic, err := strconv.Atoi(strings.TrimSpace(systolicStr))
 			if err != nil {
-				log.Printf("Warning: Row %d - Invalid systolic value '%s': %v", rowNum, systolicStr, err)
-				skippedRows++
+				log.Printf("Error: Row %d - Invalid systolic value '%s', skipping", rowNum, systolicStr)
+				skipped = append(skipped, rowNum)
 				continue
 			}
 			bp.Systolic = systolic
@@ -125,8 +150,8 @@ func main() {
 		if diastolicStr != "" {
 			diastolic, err := strconv.Atoi(strings.TrimSpace(diastolicStr))
 			if err != nil {
-				log.Printf("Warning: Row %d - Invalid diastolic value '%s': %v", rowNum, diastolicStr, err)
-				skippedRows++
+				log.Printf("Error: Row %d - Invalid diastolic value '%s', skipping", rowNum, diastolicStr)
+				skipped = append(skipped, rowNum)
 				continue
 			}
 			bp.Diastolic = diastolic
@@ -181,20 +206,35 @@ func main() {
 		}
 	}
 
-	log.Printf("Parsed %d records from CSV, %d rows skipped due to errors", len(readings), skippedRows)
+	log.Printf("Parsed %d records from CSV, %d rows skipped due to errors", len(readings), len(skipped))
+	if len(skipped) > 0 {
+		rows := make([]string, len(skipped))
+		for i, n := range skipped {
+			rows[i] = strconv.Itoa(n)
+		}
+		log.Printf("Skipped rows: %s", strings.Join(rows, ", "))
+	}
 
 	if len(readings) == 0 {
 		log.Fatal("No valid records to import")
 	}
 
-	// Import readings
+	// Import readings in chunks; re-running after a failure resumes from the last committed chunk
 	ctx := context.Background()
-	err = s.ImportBloodPressureReadings(ctx, *userID, readings)
+	result, err := s.ImportBloodPressureReadingsChunked(ctx, *userID, readings, *chunkSize, func(p store.BPImportResult) {
+		log.Printf("Imported %d/%d records (%d duplicates skipped)...", p.Processed, p.Total, p.Duplicates)
+	})
 	if err != nil {
-		log.Fatalf("Failed to import blood pressure readings: %v", err)
+		log.Fatalf("Failed to import blood pressure readings after %d/%d records (re-run to resume): %v", result.Processed, result.Total, err)
 	}
 
-	fmt.Printf("Imported %d blood pressure records for user %d\n", len(readings), *userID)
+	if result.Resumed > 0 {
+		log.Printf("Resumed previous import, skipped %d already imported records", result.Resumed)
+	}
+	for _, e := range result.Errors {
+		log.Printf("Skipped invalid record %d: %s", e.Index+1, e.Reason)
+	}
+	fmt.Printf("Imported %d blood pressure records for user %d (%d duplicates skipped)\n", result.Imported, *userID, result.Duplicates)
 }
 
 func getCol(row []string, colMap map[string]int, colName string) string {
@@ -0,0 +1,241 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// addMedTimeout is how long an unfinished /addmed conversation is kept
+const addMedTimeout = 10 * time.Minute
+
+const (
+	addMedStepName = iota
+	addMedStepDosage
+	addMedStepTimes
+)
+
+// addMedConversation tracks a chat's progress through the /addmed flow
+type addMedConversation struct {
+	step      int
+	name      string
+	dosage    string
+	expiresAt time.Time
+}
+
+// addMedState holds the active /addmed conversations keyed by chat ID
+type addMedState struct {
+	mu    sync.Mutex
+	convs map[int64]*addMedConversation
+}
+
+func (s *addMedState) get(chatID int64, now time.Time) *addMedConversation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, ok := s.convs[chatID]
+	if !ok {
+		return nil
+	}
+	if now.After(conv.expiresAt) {
+		delete(s.convs, chatID)
+		return nil
+	}
+	return conv
+}
+
+func (s *addMedState) put(chatID int64, conv *addMedConversation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.convs == nil {
+		s.convs = make(map[int64]*addMedConversation)
+	}
+	s.convs[chatID] = conv
+}
+
+// remove drops the conversation and reports whether one existed
+func (s *addMedState) remove(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.convs[chatID]
+	delete(s.convs, chatID)
+	return ok
+}
+
+// handleAddMedCommand starts the guided medication creation flow
+func (b *Bot) handleAddMedCommand(msg *tgbotapi.Message, msgConfig *tgbotapi.MessageConfig) {
+	b.addMed.put(msg.Chat.ID, &addMedConversation{
+		step:      addMedStepName,
+		expiresAt: time.Now().Add(addMedTimeout),
+	})
+	msgConfig.Text = "💊 Let's add a medication.\n\nWhat is its name?\n\nSend /cancel at any time to stop."
+}
+
+// handleCancelCommand aborts an active /addmed conversation
+func (b *Bot) handleCancelCommand(msg *tgbotapi.Message, msgConfig *tgbotapi.MessageConfig) {
+	if b.addMed.remove(msg.Chat.ID) {
+		msgConfig.Text = "Cancelled. No medication was added."
+	} else {
+		msgConfig.Text = "Nothing to cancel."
+	}
+}
+
+// handleAddMedReply advances the /addmed conversation with a plain text answer.
+// Returns false if the chat has no active conversation.
+func (b *Bot) handleAddMedReply(msg *tgbotapi.Message) bool {
+	conv := b.addMed.get(msg.Chat.ID, time.Now())
+	if conv == nil {
+		return false
+	}
+
+	text := strings.TrimSpace(msg.Text)
+	msgConfig := tgbotapi.NewMessage(msg.Chat.ID, "")
+
+	switch conv.step {
+	case addMedStepName:
+		if text == "" {
+			msgConfig.Text = "Please send the medication name."
+			break
+		}
+		conv.name = text
+		conv.step = addMedStepDosage
+		msgConfig.Text = fmt.Sprintf("Dosage for %s? (e.g. 10mg, or \"skip\")", text)
+	case addMedStepDosage:
+		if !strings.EqualFold(text, "skip") {
+			conv.dosage = text
+		}
+		conv.step = addMedStepTimes
+		msgConfig.Text = "When do you take it? Send times like \"08:00 20:00\", slot names like \"morning bedtime\", or \"as needed\"."
+	case addMedStepTimes:
+		slotTimes, err := b.store.GetSlotTimes(b.allowedUserID)
+		if err != nil {
+			log.Printf("Error loading schedule slots: %v", err)
+			msgConfig.Text = "Error loading schedule slots. Please try again."
+			break
+		}
+		cfg, err := parseAddMedTimes(text, slotTimes)
+		if err != nil {
+			msgConfig.Text = fmt.Sprintf("⚠️ %v.\n\nPlease try again or send /cancel.", err)
+			break
+		}
+		b.addMed.remove(msg.Chat.ID)
+		msgConfig.Text = b.createMedicationFromConversation(conv, cfg)
+	}
+
+	conv.expiresAt = time.Now().Add(addMedTimeout)
+	b.api.Send(msgConfig)
+	return true
+}
+
+// parseAddMedTimes turns the user's answer into a schedule. Accepts HH:MM times and
+// slot names separated by spaces or commas, or "as needed"/"prn".
+func parseAddMedTimes(text string, slotTimes map[string]string) (*store.ScheduleConfig, error) {
+	lower := strings.ToLower(strings.TrimSpace(text))
+	if lower == "as needed" || lower == "prn" {
+		return &store.ScheduleConfig{Type: "as_needed"}, nil
+	}
+
+	cfg := &store.ScheduleConfig{Type: "daily"}
+	seen := make(map[string]bool)
+	for _, tok := range strings.FieldsFunc(lower, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if seen[tok] {
+			continue
+		}
+		seen[tok] = true
+
+		if _, ok := slotTimes[tok]; ok {
+			cfg.Slots = append(cfg.Slots, tok)
+			continue
+		}
+		t, err := time.Parse("15:04", tok)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't understand %q, use HH:MM or a slot name", tok)
+		}
+		cfg.Times = append(cfg.Times, t.Format("15:04"))
+	}
+
+	if len(cfg.Times) == 0 && len(cfg.Slots) == 0 {
+		return nil, fmt.Errorf("no times given")
+	}
+	sort.Strings(cfg.Times)
+	return cfg, nil
+}
+
+// createMedicationFromConversation saves the medication and returns the summary reply
+func (b *Bot) createMedicationFromConversation(conv *addMedConversation, cfg *store.ScheduleConfig) string {
+	schedule, err := json.Marshal(cfg)
+	if err != nil {
+		return "Error building schedule."
+	}
+
+	var rxcui, normalizedName string
+	if b.rxnorm != nil {
+		rxcui, normalizedName, _ = b.rxnorm.SearchRxNorm(conv.name)
+	}
+
+	id, err := b.store.CreateMedication(conv.name, conv.dosage, string(schedule), nil, nil, rxcui, normalizedName)
+	if err != nil {
+		log.Printf("Error creating medication from /addmed: %v", err)
+		return "Error saving medication."
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("✅ Added %s", conv.name))
+	if conv.dosage != "" {
+		sb.WriteString(" (" + conv.dosage + ")")
+	}
+	sb.WriteString("\n")
+	if cfg.Type == "as_needed" {
+		sb.WriteString("Schedule: as needed\n")
+	} else {
+		sb.WriteString("Schedule: " + strings.Join(append(append([]string{}, cfg.Times...), cfg.Slots...), ", ") + "\n")
+	}
+	if normalizedName != "" && !strings.EqualFold(normalizedName, conv.name) {
+		sb.WriteString("Matched: " + normalizedName + "\n")
+	}
+
+	for _, w := range b.interactionWarnings(rxcui) {
+		sb.WriteString("\n⚠️ " + w)
+	}
+
+	log.Printf("Created medication %d (%s) via /addmed", id, conv.name)
+	return strings.TrimSpace(sb.String())
+}
+
+// interactionWarnings checks the new medication against all active ones
+func (b *Bot) interactionWarnings(rxcui string) []string {
+	if b.rxnorm == nil || rxcui == "" {
+		return nil
+	}
+
+	meds, err := b.store.ListMedications(false)
+	if err != nil {
+		return nil
+	}
+	var rxcuis []string
+	for _, m := range meds {
+		if m.RxCUI != "" {
+			rxcuis = append(rxcuis, m.RxCUI)
+		}
+	}
+	// The new medication is already in the list, so a check needs at least one other
+	if len(rxcuis) < 2 {
+		return nil
+	}
+
+	warnings, err := b.rxnorm.CheckInteractions(rxcuis)
+	if err != nil {
+		log.Printf("Error checking interactions: %v", err)
+		return nil
+	}
+	return warnings
+}
@@ -6,19 +6,27 @@ import (
 	"encoding/csv"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/rxnorm"
 	"github.com/korjavin/medicationtrackerbot/internal/store"
+	"github.com/korjavin/medicationtrackerbot/internal/webhook"
 )
 
 type Bot struct {
 	api           *tgbotapi.BotAPI
 	store         *store.Store
 	allowedUserID int64
+	webhooks      *webhook.Service
+	rxnorm        *rxnorm.Client
+	addMed        addMedState
+	downloadRange downloadRangeState
+	lastReminder  lastReminderState
 }
 
 func New(token string, allowedUserID int64, s *store.Store) (*Bot, error) {
@@ -40,6 +48,25 @@ func New(token string, allowedUserID int64, s *store.Store) (*Bot, error) {
 	}, nil
 }
 
+// NewWithAPI wraps an already configured Telegram API client (e.g. pointing at a custom endpoint)
+func NewWithAPI(api *tgbotapi.BotAPI, allowedUserID int64, s *store.Store) *Bot {
+	return &Bot{
+		api:           api,
+		store:         s,
+		allowedUserID: allowedUserID,
+	}
+}
+
+// SetWebhookService enables forwarding of bot-originated events to webhooks
+func (b *Bot) SetWebhookService(w *webhook.Service) {
+	b.webhooks = w
+}
+
+// SetRxNormClient enables RxNorm lookups and interaction checks for /addmed
+func (b *Bot) SetRxNormClient(c *rxnorm.Client) {
+	b.rxnorm = c
+}
+
 // Username returns the bot's username from the Telegram API
 func (b *Bot) Username() string {
 	return b.api.Self.UserName
@@ -52,27 +79,36 @@ func (b *Bot) Start() {
 	updates := b.api.GetUpdatesChan(u)
 
 	for update := range updates {
-		if update.Message == nil && update.CallbackQuery == nil {
-			continue
-		}
+		b.handleUpdate(update)
+	}
+}
 
-		var fromID int64
-		if update.Message != nil {
-			fromID = update.Message.From.ID
-		} else if update.CallbackQuery != nil {
-			fromID = update.CallbackQuery.From.ID
-		}
+// handleUpdate dispatches an update from the allowed user; everything else is ignored
+func (b *Bot) handleUpdate(update tgbotapi.Update) {
+	var fromID int64
+	switch {
+	case update.Message != nil:
+		fromID = update.Message.From.ID
+	case update.CallbackQuery != nil:
+		fromID = update.CallbackQuery.From.ID
+	case update.InlineQuery != nil:
+		fromID = update.InlineQuery.From.ID
+	default:
+		return
+	}
 
-		if fromID != b.allowedUserID {
-			log.Printf("Ignoring update from unauthorized user: %d", fromID)
-			continue
-		}
+	if fromID != b.allowedUserID {
+		log.Printf("Ignoring update from unauthorized user: %d", fromID)
+		return
+	}
 
-		if update.Message != nil {
-			b.handleMessage(update.Message)
-		} else if update.CallbackQuery != nil {
-			b.handleCallback(update.CallbackQuery)
-		}
+	switch {
+	case update.Message != nil:
+		b.handleMessage(update.Message)
+	case update.CallbackQuery != nil:
+		b.handleCallback(update.CallbackQuery)
+	case update.InlineQuery != nil:
+		b.handleInlineQuery(update.InlineQuery)
 	}
 }
 
@@ -83,7 +119,23 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 		return
 	}
 
+	// Dates sent after choosing a custom /download range
+	if !msg.IsCommand() && b.handleDownloadRangeReply(msg) {
+		return
+	}
+
+	// Typing "taken" or "done" confirms the most recent medication reminder
+	if !msg.IsCommand() && b.handleTakenReply(msg) {
+		return
+	}
+
+	// Replying to a medication message attaches a note to its intakes
+	if msg.ReplyToMessage != nil && !msg.IsCommand() && b.handleIntakeNoteReply(msg) {
+		return
+	}
+
 	if !msg.IsCommand() {
+		b.handleAddMedReply(msg)
 		return
 	}
 
@@ -94,20 +146,32 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 
 **Medication Commands:**
 /start - Start the bot and open the Mini App
+/addmed - Add a medication step by step (name, dosage, times)
+/cancel - Cancel adding a medication
 /log - Manually log a dose for any medication (useful for "As Needed" meds)
 /stock - View medication inventory status
-/download - Export medication, blood pressure, and weight history to CSV
+/stats - View 30-day adherence, highlighting missed critical medications
+/schedule - Show the next doses and move a dose time or skip today
+/download [from to] - Export medication, blood pressure, weight and sleep history to CSV (dates as YYYY-MM-DD)
 
-**Blood Pressure & Weight:**
+**Blood Pressure, Weight & Sleep:**
 /bp <systolic> <diastolic> [pulse] - Log blood pressure reading
   Example: /bp 130 80 72
-/bphistory - View recent blood pressure history (last 10 readings)
-/bpstats - View blood pressure statistics (30-day averages)
+/bphistory [n] - View recent blood pressure history (last 10 readings, up to 50)
+/bpstats - View blood pressure statistics (30-day averages, trend)
+/bpreminder [on|off|snooze|dontbug] - Show or change BP reminders
 /weight <kg> - Log weight in kilograms
   Example: /weight 75.5
-/weighthistory - View recent weight history (last 10 entries)
+/weighthistory [n] - View recent weight history (last 10 entries, up to 50)
+/weightreminder [on|off|snooze|dontbug] - Show or change weight reminders
+/sleep - View the last 7 nights of sleep
+/sleepstats - View sleep statistics (30-day averages, bedtime consistency)
 /goal <weight> <date> - Set weight goal
   Example: /goal 110 2026-06-01
+/glucose <value> [mgdl|mmol] [context] - Log blood glucose
+  Example: /glucose 5.8 mmol fasting
+/temp <celsius> [symptoms] - Log temperature and symptoms
+  Example: /temp 38.5 sore throat
 
 **Workout Commands:**
 /workout - Start an ad-hoc (unscheduled) workout
@@ -126,10 +190,14 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 1. Click the "Menu" button to open the App
 2. Add your medications and set schedules
 3. The bot will notify you when it's time to take them
-4. Click "Confirm" on the notification to log usage
+4. Click "Confirm" on the notification (or just send "taken") to log usage
 5. Use the tabs to track your BP readings, weight, and workouts
 6. Use /download to export all data for any time period`
 		msgConfig.ParseMode = "Markdown"
+	case "addmed":
+		b.handleAddMedCommand(msg, &msgConfig)
+	case "cancel":
+		b.handleCancelCommand(msg, &msgConfig)
 	case "log":
 		// Fetch active medications
 		meds, err := b.store.ListMedications(false)
@@ -152,6 +220,16 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 		msgConfig.Text = "Select medication to log:"
 		msgConfig.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
 	case "download":
+		if args := strings.TrimSpace(msg.CommandArguments()); args != "" {
+			from, to, err := parseDownloadRange(args)
+			if err != nil {
+				msgConfig.Text = fmt.Sprintf("⚠️ %v.", err)
+				break
+			}
+			b.sendExport(msg.Chat.ID, from, to)
+			return
+		}
+
 		rows := [][]tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardRow(
 				tgbotapi.NewInlineKeyboardButtonData("Since last download", "download:since_last"),
@@ -165,6 +243,9 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 			tgbotapi.NewInlineKeyboardRow(
 				tgbotapi.NewInlineKeyboardButtonData("Last 30 days", "download:30"),
 			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Custom range", "download:custom"),
+			),
 		}
 
 		msgConfig.Text = "Select time period for export:"
@@ -172,19 +253,35 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 	case "bp":
 		b.handleBPCommand(msg, &msgConfig)
 	case "bphistory":
-		b.handleBPHistoryCommand(&msgConfig)
+		b.handleBPHistoryCommand(msg, &msgConfig)
 	case "bpstats":
 		b.handleBPStatsCommand(&msgConfig)
+	case "bpreminder":
+		b.handleBPReminderCommand(msg, &msgConfig)
+	case "weightreminder":
+		b.handleWeightReminderCommand(msg, &msgConfig)
 	case "weight":
 		b.handleWeightCommand(msg, &msgConfig)
 	case "weighthistory":
-		b.handleWeightHistoryCommand(&msgConfig)
+		b.handleWeightHistoryCommand(msg, &msgConfig)
+	case "sleep":
+		b.handleSleepCommand(&msgConfig)
+	case "sleepstats":
+		b.handleSleepStatsCommand(&msgConfig)
+	case "glucose":
+		b.handleGlucoseCommand(msg, &msgConfig)
+	case "temp":
+		b.handleTempCommand(msg, &msgConfig)
 	case "goal":
 		b.handleGoalCommand(msg, &msgConfig)
 	case "bpgoal":
 		b.handleBPGoalCommand(msg, &msgConfig)
 	case "stock":
 		b.handleStockCommand(&msgConfig)
+	case "stats":
+		b.handleStatsCommand(&msgConfig)
+	case "schedule":
+		b.handleScheduleCommand(&msgConfig)
 	case "workout":
 		b.handleAdHocWorkoutCommand(&msgConfig)
 	case "startnext":
@@ -200,6 +297,50 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 	b.api.Send(msgConfig)
 }
 
+// sendTakenConfirmation confirms a dose and remembers the message so that a
+// reply to it can be attached as a note to the confirmed intakes.
+func (b *Bot) sendTakenConfirmation(chatID int64, text string, intakeIDs []int64) {
+	sent, err := b.api.Send(tgbotapi.NewMessage(chatID, text+"\n\n📝 Reply to this message to add a note."))
+	if err != nil {
+		log.Printf("Error sending confirmation: %v", err)
+		return
+	}
+	for _, id := range intakeIDs {
+		if err := b.store.AddIntakeNoteMessage(id, sent.MessageID); err != nil {
+			log.Printf("Error linking confirmation to intake %d: %v", id, err)
+		}
+	}
+}
+
+// handleIntakeNoteReply saves the text of a reply to a medication message
+// as the note of the intakes that message was sent for.
+func (b *Bot) handleIntakeNoteReply(msg *tgbotapi.Message) bool {
+	note := strings.TrimSpace(msg.Text)
+	if note == "" {
+		return false
+	}
+
+	intakeIDs, err := b.store.GetIntakeIDsByReminderMessage(msg.ReplyToMessage.MessageID)
+	if err != nil {
+		log.Printf("Error looking up intakes for reply: %v", err)
+		return false
+	}
+	if len(intakeIDs) == 0 {
+		return false // Not a reply to a medication message
+	}
+
+	for _, id := range intakeIDs {
+		if err := b.store.SetIntakeNotes(id, note); err != nil {
+			log.Printf("Error saving note for intake %d: %v", id, err)
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Error saving note."))
+			return true
+		}
+	}
+
+	b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "📝 Note saved."))
+	return true
+}
+
 func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 	callbackCfg := tgbotapi.NewCallback(cb.ID, "")
 	b.api.Request(callbackCfg)
@@ -210,19 +351,22 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 		medIDStr := data[8:]
 		medID, _ := strconv.ParseInt(medIDStr, 10, 64)
 
-		// Find pending intake
-		pending, err := b.store.GetPendingIntakes()
+		// Find the pending intake scheduled closest to now, so an early or late
+		// confirmation doesn't take another occurrence of the same medication
+		window, err := b.store.ConfirmWindow()
+		if err != nil {
+			log.Printf("Error getting confirm window: %v", err)
+			return
+		}
+		pending, err := b.store.GetNearestPendingIntake(medID, time.Now(), window)
 		if err != nil {
 			log.Printf("Error getting pending: %v", err)
 			return
 		}
 
 		var logID int64
-		for _, p := range pending {
-			if p.MedicationID == medID {
-				logID = p.ID
-				break
-			}
+		if pending != nil {
+			logID = pending.ID
 		}
 
 		if logID != 0 {
@@ -238,9 +382,10 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 				log.Printf("Error configuring intake: %v", err)
 				return
 			}
+			b.webhooks.NotifyIntakeConfirmed(b.allowedUserID, logID)
 
-			// Decrement inventory (only affects medications with tracking enabled)
-			if err := b.store.DecrementInventory(medID, 1); err != nil {
+			// Take one dose from inventory (only affects medications with tracking enabled)
+			if err := b.store.DecrementInventoryByDose(medID); err != nil {
 				log.Printf("Error decrementing inventory: %v", err)
 			}
 
@@ -250,7 +395,7 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 			})
 			b.api.Send(edit)
 
-			b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "✅ Marked as taken."))
+			b.sendTakenConfirmation(cb.Message.Chat.ID, "✅ Marked as taken.", []int64{logID})
 		} else {
 			// Maybe it was already taken?
 			b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "⚠️ No pending intake found (or already taken)."))
@@ -273,9 +418,10 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 			log.Printf("Error confirming manual intake: %v", err)
 			return
 		}
+		b.webhooks.NotifyIntakeConfirmed(b.allowedUserID, logID)
 
-		// Decrement inventory (only affects medications with tracking enabled)
-		if err := b.store.DecrementInventory(medID, 1); err != nil {
+		// Take one dose from inventory (only affects medications with tracking enabled)
+		if err := b.store.DecrementInventoryByDose(medID); err != nil {
 			log.Printf("Error decrementing inventory: %v", err)
 		}
 
@@ -292,7 +438,7 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 			medName = med.Name
 		}
 
-		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, fmt.Sprintf("✅ Logged %s at %s", medName, now.Format("15:04"))))
+		b.sendTakenConfirmation(cb.Message.Chat.ID, fmt.Sprintf("✅ Logged %s at %s", medName, now.Format("15:04")), []int64{logID})
 
 	} else if len(data) > 17 && data[:17] == "confirm_schedule:" {
 		tsStr := data[17:]
@@ -323,9 +469,10 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 
 		// Decrement inventory for all medications in this schedule
 		for _, p := range pending {
-			if err := b.store.DecrementInventory(p.MedicationID, 1); err != nil {
+			if err := b.store.DecrementInventoryByDose(p.MedicationID); err != nil {
 				log.Printf("Error decrementing inventory for med %d: %v", p.MedicationID, err)
 			}
+			b.webhooks.NotifyIntakeConfirmed(b.allowedUserID, p.ID)
 		}
 
 		// Update message to remove buttons
@@ -334,7 +481,17 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 		})
 		b.api.Send(edit)
 
-		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "✅ All medications for this time marked as taken."))
+		var intakeIDs []int64
+		for _, p := range pending {
+			intakeIDs = append(intakeIDs, p.ID)
+		}
+		b.sendTakenConfirmation(cb.Message.Chat.ID, "✅ All medications for this time marked as taken.", intakeIDs)
+	} else if strings.HasPrefix(data, "med_snooze:") {
+		b.handleMedSnoozeCallback(cb, data)
+	} else if strings.HasPrefix(data, "group_snooze:") {
+		b.handleGroupSnoozeCallback(cb, data)
+	} else if strings.HasPrefix(data, "sched_bump:") || strings.HasPrefix(data, "sched_skip:") {
+		b.handleScheduleCallback(cb, data)
 	} else if strings.HasPrefix(data, "workout_start_") || strings.HasPrefix(data, "workout_snooze1") || strings.HasPrefix(data, "workout_snooze2") || strings.HasPrefix(data, "workout_skip_") || strings.HasPrefix(data, "workout_finish_") {
 		// Workout callbacks
 		b.handleWorkoutCallback(cb, data)
@@ -379,6 +536,16 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 	}
 }
 
+// sendLogged sends a proactive message and records the attempt in the notification log.
+// Logging is best-effort and never fails the send.
+func (b *Bot) sendLogged(msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
+	sent, err := b.api.Send(msg)
+	if logErr := b.store.LogNotification(b.allowedUserID, store.ChannelTelegram, strconv.FormatInt(msg.ChatID, 10), msg.Text, err); logErr != nil {
+		log.Printf("Failed to log notification: %v", logErr)
+	}
+	return sent, err
+}
+
 func (b *Bot) SendNotification(text string, medicationID int64) (int, error) {
 	msg := tgbotapi.NewMessage(b.allowedUserID, text)
 
@@ -386,14 +553,51 @@ func (b *Bot) SendNotification(text string, medicationID int64) (int, error) {
 	// Passing medicationID in callback data: "confirm:<id>"
 	data := "confirm:" + strconv.FormatInt(medicationID, 10)
 	btn := tgbotapi.NewInlineKeyboardButtonData("✅ Confirm Intake", data)
-	row := tgbotapi.NewInlineKeyboardRow(btn)
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(row)
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(btn)}
+	if snooze := b.medSnoozeRow(medicationID); len(snooze) > 0 {
+		rows = append(rows, snooze)
+	}
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
 
-	sentMsg, err := b.api.Send(msg)
+	sentMsg, err := b.sendLogged(msg)
 	return sentMsg.MessageID, err
 }
 
-// SendSimpleNotification sends a notification with custom buttons
+// SendReminder re-sends an unconfirmed dose, rendered with the user's reminder template
+func (b *Bot) SendReminder(med store.Medication, scheduledAt time.Time) (int, error) {
+	text := fmt.Sprintf("🔔 REMINDER: You haven't confirmed taking %s yet on %s!",
+		store.RenderReminderTemplate(b.reminderTemplate(), med, scheduledAt), scheduledAt.Format("15:04"))
+	text += b.injectionSiteHint(med.ID)
+	msgID, err := b.SendNotification(text, med.ID)
+	if err == nil {
+		b.lastReminder.set(b.allowedUserID, lastReminder{messageID: msgID, medIDs: []int64{med.ID}, target: scheduledAt, sentAt: time.Now()})
+	}
+	return msgID, err
+}
+
+// injectionSiteHint names the suggested next site for medications with a site rotation
+func (b *Bot) injectionSiteHint(medID int64) string {
+	site, err := b.store.NextInjectionSite(medID)
+	if err != nil {
+		log.Printf("Error getting next injection site: %v", err)
+		return ""
+	}
+	if site == "" {
+		return ""
+	}
+	return " — 💉 next site: " + site
+}
+
+// reminderTemplate loads the user's reminder template, falling back to the default
+func (b *Bot) reminderTemplate() string {
+	tmpl, err := b.store.GetReminderTemplate(b.allowedUserID)
+	if err != nil {
+		log.Printf("Error loading reminder template: %v", err)
+		return store.DefaultReminderTemplate
+	}
+	return tmpl
+}
+
 // SendSimpleNotification sends a notification with custom buttons
 func (b *Bot) SendSimpleNotification(text string, buttons []tgbotapi.InlineKeyboardButton) (int, error) {
 	msg := tgbotapi.NewMessage(b.allowedUserID, text)
@@ -406,7 +610,7 @@ func (b *Bot) SendSimpleNotification(text string, buttons []tgbotapi.InlineKeybo
 		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
 	}
 
-	sentMsg, err := b.api.Send(msg)
+	sentMsg, err := b.sendLogged(msg)
 	return sentMsg.MessageID, err
 }
 
@@ -421,7 +625,7 @@ func (b *Bot) SendWorkoutStaleNotification(text string, sessionID int64) (int, e
 	row := tgbotapi.NewInlineKeyboardRow(finishBtn, dismissBtn)
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(row)
 
-	sentMsg, err := b.api.Send(msg)
+	sentMsg, err := b.sendLogged(msg)
 	return sentMsg.MessageID, err
 }
 
@@ -432,15 +636,14 @@ func (b *Bot) DeleteMessage(messageID int) error {
 	return err
 }
 
-func (b *Bot) SendGroupNotification(meds []store.Medication, target time.Time) error {
+// SendGroupNotification sends the reminder for all doses due at target and returns its
+// message ID, so the scheduler can track it as each dose's first reminder
+func (b *Bot) SendGroupNotification(meds []store.Medication, target time.Time) (int, error) {
 	var sb string
 	sb = fmt.Sprintf("💊 Time to take your medications (%s):\n\n", target.Format("15:04"))
+	tmpl := b.reminderTemplate()
 	for _, m := range meds {
-		if m.Dosage != "" {
-			sb += fmt.Sprintf("- %s (%s)\n", m.Name, m.Dosage)
-		} else {
-			sb += fmt.Sprintf("- %s\n", m.Name)
-		}
+		sb += "- " + store.RenderReminderTemplate(tmpl, m, target) + b.injectionSiteHint(m.ID) + "\n"
 	}
 
 	msg := tgbotapi.NewMessage(b.allowedUserID, sb)
@@ -460,17 +663,29 @@ func (b *Bot) SendGroupNotification(meds []store.Medication, target time.Time) e
 	btn := tgbotapi.NewInlineKeyboardButtonData("✅✅ Confirm ALL", data)
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(btn))
 
+	// 3. Snooze the whole group
+	if snooze := b.groupSnoozeRow(target); len(snooze) > 0 {
+		rows = append(rows, snooze)
+	}
+
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
 
-	_, err := b.api.Send(msg)
-	return err
+	sent, err := b.sendLogged(msg)
+	if err == nil {
+		medIDs := make([]int64, len(meds))
+		for i, m := range meds {
+			medIDs[i] = m.ID
+		}
+		b.lastReminder.set(b.allowedUserID, lastReminder{messageID: sent.MessageID, medIDs: medIDs, target: target, sentAt: time.Now()})
+	}
+	return sent.MessageID, err
 }
 
 // SendLowStockWarning sends a low stock warning message to the user
 func (b *Bot) SendLowStockWarning(text string) error {
 	msg := tgbotapi.NewMessage(b.allowedUserID, text)
 	msg.ParseMode = "Markdown"
-	_, err := b.api.Send(msg)
+	_, err := b.sendLogged(msg)
 	return err
 }
 
@@ -495,56 +710,79 @@ func (b *Bot) handleDownloadCallback(cb *tgbotapi.CallbackQuery, option string)
 		since = time.Now().AddDate(0, 0, -14)
 	case "30":
 		since = time.Now().AddDate(0, 0, -30)
+	case "custom":
+		b.downloadRange.await(cb.Message.Chat.ID, time.Now().Add(downloadRangeTimeout))
+		b.removeButtons(cb)
+		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, downloadRangePrompt))
+		return
 	default:
 		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "Unknown download option."))
 		return
 	}
 
+	if !b.sendExport(cb.Message.Chat.ID, since, time.Time{}) {
+		return
+	}
+
+	// Update last download timestamp
+	if err := b.store.UpdateLastDownload(time.Now()); err != nil {
+		log.Printf("Error updating last download: %v", err)
+	}
+
+	b.removeButtons(cb)
+}
+
+// removeButtons clears the inline keyboard of the message a callback came from
+func (b *Bot) removeButtons(cb *tgbotapi.CallbackQuery) {
+	edit := tgbotapi.NewEditMessageReplyMarkup(cb.Message.Chat.ID, cb.Message.MessageID, tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+	})
+	b.api.Send(edit)
+}
+
+// sendExport sends the medication, BP, weight and sleep CSVs for records between since and
+// until (zero for now). Returns false if nothing was sent.
+func (b *Bot) sendExport(chatID int64, since, until time.Time) bool {
 	// Get medication intakes
-	intakes, err := b.store.GetIntakesSince(since)
+	intakes, err := b.store.GetIntakesBetween(since, until)
 	if err != nil {
 		log.Printf("Error getting intakes: %v", err)
-		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "❌ Error retrieving intake data."))
-		return
+		b.api.Send(tgbotapi.NewMessage(chatID, "❌ Error retrieving intake data."))
+		return false
 	}
 
 	// Get blood pressure readings
-	bpReadings, err := b.store.GetBloodPressureReadings(context.Background(), b.allowedUserID, since)
+	bpReadings, err := b.store.GetBloodPressureReadingsBetween(context.Background(), b.allowedUserID, since, until)
 	if err != nil {
 		log.Printf("Error getting BP readings: %v", err)
-		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "❌ Error retrieving blood pressure data."))
-		return
+		b.api.Send(tgbotapi.NewMessage(chatID, "❌ Error retrieving blood pressure data."))
+		return false
 	}
 
 	// Get weight logs
-	weightLogs, err := b.store.GetWeightLogs(context.Background(), b.allowedUserID, since)
+	weightLogs, err := b.store.GetWeightLogsBetween(context.Background(), b.allowedUserID, since, until)
 	if err != nil {
 		log.Printf("Error getting weight logs: %v", err)
 	}
 
-	if len(intakes) == 0 && len(bpReadings) == 0 && len(weightLogs) == 0 {
-		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "No records found for the selected period."))
-		return
+	// Get sleep logs
+	sleepLogs, err := b.store.GetSleepLogsBetween(context.Background(), b.allowedUserID, since, until)
+	if err != nil {
+		log.Printf("Error getting sleep logs: %v", err)
 	}
 
-	// Update last download timestamp
-	if err := b.store.UpdateLastDownload(time.Now()); err != nil {
-		log.Printf("Error updating last download: %v", err)
+	if len(intakes) == 0 && len(bpReadings) == 0 && len(weightLogs) == 0 && len(sleepLogs) == 0 {
+		b.api.Send(tgbotapi.NewMessage(chatID, "No records found for the selected period."))
+		return false
 	}
 
-	// Remove buttons
-	edit := tgbotapi.NewEditMessageReplyMarkup(cb.Message.Chat.ID, cb.Message.MessageID, tgbotapi.InlineKeyboardMarkup{
-		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
-	})
-	b.api.Send(edit)
-
 	// Send medication CSV if available
 	if len(intakes) > 0 {
 		csvData, err := b.generateCSV(intakes)
 		if err != nil {
 			log.Printf("Error generating medication CSV: %v", err)
 		} else {
-			doc := tgbotapi.NewDocument(cb.Message.Chat.ID, tgbotapi.FileBytes{
+			doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
 				Name:  "medication_export.csv",
 				Bytes: csvData,
 			})
@@ -559,7 +797,7 @@ func (b *Bot) handleDownloadCallback(cb *tgbotapi.CallbackQuery, option string)
 		if err != nil {
 			log.Printf("Error generating BP CSV: %v", err)
 		} else {
-			doc := tgbotapi.NewDocument(cb.Message.Chat.ID, tgbotapi.FileBytes{
+			doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
 				Name:  "blood_pressure_export.csv",
 				Bytes: bpCSV,
 			})
@@ -574,7 +812,7 @@ func (b *Bot) handleDownloadCallback(cb *tgbotapi.CallbackQuery, option string)
 		if err != nil {
 			log.Printf("Error generating weight CSV: %v", err)
 		} else {
-			doc := tgbotapi.NewDocument(cb.Message.Chat.ID, tgbotapi.FileBytes{
+			doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
 				Name:  "weight_export.csv",
 				Bytes: weightCSV,
 			})
@@ -582,6 +820,23 @@ func (b *Bot) handleDownloadCallback(cb *tgbotapi.CallbackQuery, option string)
 			b.api.Send(doc)
 		}
 	}
+
+	// Send sleep CSV if available
+	if len(sleepLogs) > 0 {
+		sleepCSV, err := b.generateSleepCSV(sleepLogs)
+		if err != nil {
+			log.Printf("Error generating sleep CSV: %v", err)
+		} else {
+			doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+				Name:  "sleep_export.csv",
+				Bytes: sleepCSV,
+			})
+			doc.Caption = fmt.Sprintf("Sleep export (%d records)", len(sleepLogs))
+			b.api.Send(doc)
+		}
+	}
+
+	return true
 }
 
 func (b *Bot) generateCSV(intakes []store.IntakeWithMedication) ([]byte, error) {
@@ -589,7 +844,7 @@ func (b *Bot) generateCSV(intakes []store.IntakeWithMedication) ([]byte, error)
 	writer := csv.NewWriter(buf)
 
 	// Write header
-	if err := writer.Write([]string{"date time", "medicine name", "dosage"}); err != nil {
+	if err := writer.Write([]string{"date time", "medicine name", "dosage", "notes", "substituted with"}); err != nil {
 		return nil, err
 	}
 
@@ -600,7 +855,7 @@ func (b *Bot) generateCSV(intakes []store.IntakeWithMedication) ([]byte, error)
 		if intake.TakenAt != nil {
 			dateTime = intake.TakenAt.Format("2006-01-02 15:04")
 		}
-		row := []string{dateTime, intake.MedicationName, intake.MedicationDosage}
+		row := []string{dateTime, intake.MedicationName, intake.MedicationDosage, intake.Notes, intake.SubstitutedWith}
 		if err := writer.Write(row); err != nil {
 			return nil, err
 		}
@@ -637,14 +892,14 @@ Pulse: heart rate (optional)`
 	}
 
 	systolic, err := strconv.Atoi(parts[0])
-	if err != nil || systolic < 60 || systolic > 250 {
-		msgConfig.Text = "❌ Invalid systolic value (60-250)"
+	if err != nil || systolic < store.MinSystolic || systolic > store.MaxSystolic {
+		msgConfig.Text = fmt.Sprintf("❌ Invalid systolic value (%d-%d)", store.MinSystolic, store.MaxSystolic)
 		return
 	}
 
 	diastolic, err := strconv.Atoi(parts[1])
-	if err != nil || diastolic < 40 || diastolic > 150 {
-		msgConfig.Text = "❌ Invalid diastolic value (40-150)"
+	if err != nil || diastolic < store.MinDiastolic || diastolic > store.MaxDiastolic {
+		msgConfig.Text = fmt.Sprintf("❌ Invalid diastolic value (%d-%d)", store.MinDiastolic, store.MaxDiastolic)
 		return
 	}
 
@@ -652,8 +907,8 @@ Pulse: heart rate (optional)`
 	pulsePresent := false
 	if len(parts) >= 3 {
 		pulse, err = strconv.Atoi(parts[2])
-		if err != nil || pulse < 40 || pulse > 200 {
-			msgConfig.Text = "❌ Invalid pulse value (40-200)"
+		if err != nil || pulse < store.MinPulse || pulse > store.MaxPulse {
+			msgConfig.Text = fmt.Sprintf("❌ Invalid pulse value (%d-%d)", store.MinPulse, store.MaxPulse)
 			return
 		}
 		pulsePresent = true
@@ -671,13 +926,17 @@ Pulse: heart rate (optional)`
 	if pulsePresent {
 		bp.Pulse = &pulse
 	}
+	if err := b.store.ApplyBPDefaults(bp); err != nil {
+		log.Printf("Error getting BP defaults: %v", err)
+	}
 
-	_, err = b.store.CreateBloodPressureReading(context.Background(), bp)
+	bp.ID, err = b.store.CreateBloodPressureReading(context.Background(), bp)
 	if err != nil {
 		log.Printf("Error creating BP reading: %v", err)
 		msgConfig.Text = "❌ Error saving blood pressure reading."
 		return
 	}
+	b.webhooks.NotifyBPReading(b.allowedUserID, bp)
 
 	pulseStr := ""
 	if pulsePresent {
@@ -686,9 +945,30 @@ Pulse: heart rate (optional)`
 	msgConfig.Text = fmt.Sprintf("✅ Blood pressure recorded: %d/%d%s\n📊 Category: %s", systolic, diastolic, pulseStr, category)
 }
 
-func (b *Bot) handleBPHistoryCommand(msgConfig *tgbotapi.MessageConfig) {
-	since := time.Now().AddDate(0, 0, -30)
-	readings, err := b.store.GetBloodPressureReadings(context.Background(), b.allowedUserID, since)
+const (
+	defaultHistoryLimit = 10
+	maxHistoryLimit     = 50
+)
+
+// parseHistoryLimit parses the optional entry count of history commands,
+// clamping it to 1..maxHistoryLimit and defaulting to defaultHistoryLimit.
+func parseHistoryLimit(args string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil {
+		return defaultHistoryLimit
+	}
+	if n < 1 {
+		return 1
+	}
+	if n > maxHistoryLimit {
+		return maxHistoryLimit
+	}
+	return n
+}
+
+func (b *Bot) handleBPHistoryCommand(msg *tgbotapi.Message, msgConfig *tgbotapi.MessageConfig) {
+	limit := parseHistoryLimit(msg.CommandArguments())
+	readings, err := b.store.GetRecentBloodPressureReadings(context.Background(), b.allowedUserID, time.Time{}, limit)
 	if err != nil {
 		log.Printf("Error getting BP readings: %v", err)
 		msgConfig.Text = "❌ Error retrieving blood pressure history."
@@ -696,17 +976,12 @@ func (b *Bot) handleBPHistoryCommand(msgConfig *tgbotapi.MessageConfig) {
 	}
 
 	if len(readings) == 0 {
-		msgConfig.Text = "📈 Blood Pressure History (last 10):\n\nNo records for the last 30 days."
+		msgConfig.Text = fmt.Sprintf("📈 Blood Pressure History (last %d):\n\nNo records yet.", limit)
 		return
 	}
 
-	// Limit to 10
-	if len(readings) > 10 {
-		readings = readings[:10]
-	}
-
 	var sb strings.Builder
-	sb.WriteString("📈 Blood Pressure History (last 10):\n\n")
+	sb.WriteString(fmt.Sprintf("📈 Blood Pressure History (last %d):\n\n", limit))
 
 	for _, bp := range readings {
 		dateStr := bp.MeasuredAt.Format("02.01.2006 15:04")
@@ -726,7 +1001,7 @@ func (b *Bot) handleBPHistoryCommand(msgConfig *tgbotapi.MessageConfig) {
 
 func (b *Bot) handleBPStatsCommand(msgConfig *tgbotapi.MessageConfig) {
 	since := time.Now().AddDate(0, 0, -30)
-	readings, err := b.store.GetBloodPressureReadings(context.Background(), b.allowedUserID, since)
+	readings, err := b.store.GetBloodPressureReadings(context.Background(), b.allowedUserID, since, 0, 0)
 	if err != nil {
 		log.Printf("Error getting BP readings: %v", err)
 		msgConfig.Text = "❌ Error retrieving blood pressure statistics."
@@ -792,6 +1067,30 @@ func (b *Bot) handleBPStatsCommand(msgConfig *tgbotapi.MessageConfig) {
 		msgConfig.Text += fmt.Sprintf("\nMax: %d/%d, pulse —", maxSys, maxDia)
 		msgConfig.Text += fmt.Sprintf("\nMin: %d/%d, pulse —", minSys, minDia)
 	}
+
+	stats, err := b.store.GetBPDailyWeightedStats(context.Background(), b.allowedUserID)
+	if err != nil {
+		log.Printf("Error getting BP trend: %v", err)
+		return
+	}
+	if line := bpTrendLine(stats); line != "" {
+		msgConfig.Text += "\n\n" + line
+	}
+}
+
+// bpTrendLine describes the systolic trend of the last 14 vs 60 days, or nothing when
+// there is no 60-day history to compare against
+func bpTrendLine(stats *store.BPStats) string {
+	switch stats.Trend {
+	case store.BPTrendRising:
+		return "📈 Your systolic is trending up vs last 60 days."
+	case store.BPTrendFalling:
+		return "📉 Your systolic is trending down vs last 60 days."
+	}
+	if stats.Stats14 == nil || stats.Stats60 == nil || stats.Stats60.Days == stats.Stats14.Days {
+		return ""
+	}
+	return "➡️ Your systolic is stable vs last 60 days."
 }
 
 func (b *Bot) generateBPCSV(readings []store.BloodPressure) ([]byte, error) {
@@ -824,48 +1123,43 @@ func (b *Bot) generateBPCSV(readings []store.BloodPressure) ([]byte, error) {
 	return buf.Bytes(), writer.Error()
 }
 
+// generateWeightCSV exports weight logs in Libra's import format
 func (b *Bot) generateWeightCSV(logs []store.WeightLog) ([]byte, error) {
 	buf := &bytes.Buffer{}
-	writer := csv.NewWriter(buf)
-
-	// Write header in Libra format
-	writer.Write([]string{"#Version: 6"})
-	writer.Write([]string{"#Units: kg"})
-	writer.Write([]string{""})
-	writer.Write([]string{"#date;weight;weight trend;body fat;body fat trend;muscle mass;muscle mass trend;log"})
-
-	// Write data rows
-	for _, w := range logs {
-		dateTime := w.MeasuredAt.Format("2006-01-02T15:04:05.000Z")
-
-		weight := fmt.Sprintf("%.1f", w.Weight)
-		weightTrend := ""
-		if w.WeightTrend != nil {
-			weightTrend = fmt.Sprintf("%.1f", *w.WeightTrend)
-		}
-
-		bodyFat := ""
-		if w.BodyFat != nil {
-			bodyFat = fmt.Sprintf("%.1f", *w.BodyFat)
-		}
+	err := store.WriteWeightLibra(buf, logs)
+	return buf.Bytes(), err
+}
 
-		bodyFatTrend := ""
-		if w.BodyFatTrend != nil {
-			bodyFatTrend = fmt.Sprintf("%.1f", *w.BodyFatTrend)
-		}
+func (b *Bot) generateSleepCSV(logs []store.SleepLog) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
 
-		muscleMass := ""
-		if w.MuscleMass != nil {
-			muscleMass = fmt.Sprintf("%.1f", *w.MuscleMass)
-		}
+	// Write header
+	if err := writer.Write([]string{"date", "start", "end", "total minutes", "deep minutes", "rem minutes", "light minutes", "awake minutes", "heart rate", "spo2", "notes"}); err != nil {
+		return nil, err
+	}
 
-		muscleMassTrend := ""
-		if w.MuscleMassTrend != nil {
-			muscleMassTrend = fmt.Sprintf("%.1f", *w.MuscleMassTrend)
+	optional := func(v *int) string {
+		if v == nil {
+			return ""
 		}
+		return strconv.Itoa(*v)
+	}
 
+	// Write data rows
+	for _, l := range logs {
 		row := []string{
-			dateTime + ";" + weight + ";" + weightTrend + ";" + bodyFat + ";" + bodyFatTrend + ";" + muscleMass + ";" + muscleMassTrend + ";" + w.Notes,
+			l.Day,
+			l.StartTime.Format("2006-01-02 15:04"),
+			l.EndTime.Format("2006-01-02 15:04"),
+			optional(l.TotalMinutes),
+			optional(l.DeepMinutes),
+			optional(l.REMMinutes),
+			optional(l.LightMinutes),
+			optional(l.AwakeMinutes),
+			optional(l.HeartRateAvg),
+			optional(l.SpO2Avg),
+			strings.ReplaceAll(l.Notes, "\n", " "),
 		}
 		if err := writer.Write(row); err != nil {
 			return nil, err
@@ -925,25 +1219,20 @@ The system will automatically calculate your weight trend over time.`
 		return
 	}
 
-	// Get last weight log to calculate trend
+	// Get last weight log to calculate trends
 	lastLog, err := b.store.GetLastWeightLog(context.Background(), b.allowedUserID)
 	if err != nil {
 		log.Printf("Error getting last weight log: %v", err)
 	}
 
-	var previousTrend *float64
-	if lastLog != nil && lastLog.WeightTrend != nil {
-		previousTrend = lastLog.WeightTrend
-	}
-
-	weightTrend := store.CalculateWeightTrend(weight, previousTrend)
-
+	// Body fat and muscle mass aren't measured here; their trends carry forward
 	wLog := &store.WeightLog{
-		UserID:      b.allowedUserID,
-		MeasuredAt:  time.Now(),
-		Weight:      weight,
-		WeightTrend: &weightTrend,
+		UserID:     b.allowedUserID,
+		MeasuredAt: time.Now(),
+		Weight:     weight,
 	}
+	wLog.SetTrends(lastLog)
+	weightTrend := *wLog.WeightTrend
 
 	_, err = b.store.CreateWeightLog(context.Background(), wLog)
 	if err != nil {
@@ -966,9 +1255,9 @@ The system will automatically calculate your weight trend over time.`
 	msgConfig.Text = fmt.Sprintf("✅ Weight recorded: %.1f kg\n📊 Trend: %.1f kg%s", weight, weightTrend, trendInfo)
 }
 
-func (b *Bot) handleWeightHistoryCommand(msgConfig *tgbotapi.MessageConfig) {
-	since := time.Now().AddDate(0, 0, -30)
-	logs, err := b.store.GetWeightLogs(context.Background(), b.allowedUserID, since)
+func (b *Bot) handleWeightHistoryCommand(msg *tgbotapi.Message, msgConfig *tgbotapi.MessageConfig) {
+	limit := parseHistoryLimit(msg.CommandArguments())
+	logs, err := b.store.GetRecentWeightLogs(context.Background(), b.allowedUserID, time.Time{}, limit)
 	if err != nil {
 		log.Printf("Error getting weight logs: %v", err)
 		msgConfig.Text = "❌ Error retrieving weight history."
@@ -976,17 +1265,12 @@ func (b *Bot) handleWeightHistoryCommand(msgConfig *tgbotapi.MessageConfig) {
 	}
 
 	if len(logs) == 0 {
-		msgConfig.Text = "📊 Weight History (last 10):\n\nNo records for the last 30 days."
+		msgConfig.Text = fmt.Sprintf("📊 Weight History (last %d):\n\nNo records yet.", limit)
 		return
 	}
 
-	// Limit to 10
-	if len(logs) > 10 {
-		logs = logs[:10]
-	}
-
 	var sb strings.Builder
-	sb.WriteString("📊 Weight History (last 10):\n\n")
+	sb.WriteString(fmt.Sprintf("📊 Weight History (last %d):\n\n", limit))
 
 	for _, w := range logs {
 		dateStr := w.MeasuredAt.Format("02.01.2006 15:04")
@@ -1000,6 +1284,95 @@ func (b *Bot) handleWeightHistoryCommand(msgConfig *tgbotapi.MessageConfig) {
 	msgConfig.Text = sb.String()
 }
 
+// sleepHistoryDays is how far back /sleep looks
+const sleepHistoryDays = 7
+
+func (b *Bot) handleSleepCommand(msgConfig *tgbotapi.MessageConfig) {
+	logs, err := b.store.GetSleepLogs(context.Background(), b.allowedUserID, time.Now().AddDate(0, 0, -sleepHistoryDays))
+	if err != nil {
+		log.Printf("Error getting sleep logs: %v", err)
+		msgConfig.Text = "❌ Error retrieving sleep history."
+		return
+	}
+
+	if len(logs) == 0 {
+		msgConfig.Text = fmt.Sprintf("😴 Sleep (last %d nights):\n\nNo sleep data yet.", sleepHistoryDays)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("😴 Sleep (last %d nights):\n\n", sleepHistoryDays))
+
+	for _, l := range logs {
+		dateStr := l.Day
+		if day, err := time.Parse("2006-01-02", l.Day); err == nil {
+			dateStr = day.Format("02.01.2006")
+		}
+
+		// Logs without a total fall back to the time in bed
+		total := int(l.EndTime.Sub(l.StartTime).Minutes())
+		if l.TotalMinutes != nil {
+			total = *l.TotalMinutes
+		}
+
+		var details []string
+		if l.DeepMinutes != nil {
+			details = append(details, "deep "+formatSleepMinutes(*l.DeepMinutes))
+		}
+		if l.REMMinutes != nil {
+			details = append(details, "REM "+formatSleepMinutes(*l.REMMinutes))
+		}
+		if l.HeartRateAvg != nil {
+			details = append(details, fmt.Sprintf("HR %d", *l.HeartRateAvg))
+		}
+		detailStr := ""
+		if len(details) > 0 {
+			detailStr = " (" + strings.Join(details, ", ") + ")"
+		}
+		sb.WriteString(fmt.Sprintf("%s — %s%s\n", dateStr, formatSleepMinutes(total), detailStr))
+	}
+
+	msgConfig.Text = sb.String()
+}
+
+func (b *Bot) handleSleepStatsCommand(msgConfig *tgbotapi.MessageConfig) {
+	stats, err := b.store.GetSleepStats(context.Background(), b.allowedUserID, 30)
+	if err != nil {
+		log.Printf("Error getting sleep stats: %v", err)
+		msgConfig.Text = "❌ Error retrieving sleep statistics."
+		return
+	}
+
+	if stats.Nights == 0 {
+		msgConfig.Text = "😴 Sleep Statistics (30 days):\n\nNo sleep data yet."
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("😴 Sleep Statistics (30 days):\n\n")
+	sb.WriteString(fmt.Sprintf("Nights: %d\n", stats.Nights))
+	if stats.AvgTotalMinutes != nil {
+		sb.WriteString(fmt.Sprintf("Average sleep: %s\n", formatSleepMinutes(int(math.Round(*stats.AvgTotalMinutes)))))
+	}
+	if stats.AvgDeepPercent != nil {
+		sb.WriteString(fmt.Sprintf("Deep: %.1f%%\n", *stats.AvgDeepPercent))
+	}
+	if stats.AvgREMPercent != nil {
+		sb.WriteString(fmt.Sprintf("REM: %.1f%%\n", *stats.AvgREMPercent))
+	}
+	sb.WriteString(fmt.Sprintf("Bedtime: %s, wake up: %s\n", stats.AvgBedtime, stats.AvgWakeTime))
+	if stats.ConsistencyScore != nil {
+		sb.WriteString(fmt.Sprintf("Consistency: %d/100 (bedtime ±%.0f min)\n", *stats.ConsistencyScore, *stats.BedtimeStdDevMinutes))
+	}
+
+	msgConfig.Text = sb.String()
+}
+
+// formatSleepMinutes renders minutes as e.g. "7h 05m"
+func formatSleepMinutes(minutes int) string {
+	return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+}
+
 func (b *Bot) handleGoalCommand(msg *tgbotapi.Message, msgConfig *tgbotapi.MessageConfig) {
 	args := msg.CommandArguments()
 	if args == "" {
@@ -1147,14 +1520,14 @@ func (b *Bot) handleStockCommand(msgConfig *tgbotapi.MessageConfig) {
 	sb.WriteString("📦 **Medication Inventory**\n\n")
 
 	// Check for low stock (< 7 days)
-	lowStockMeds, _ := b.store.GetMedicationsLowOnStock(7)
+	lowStockMeds, _ := b.store.GetMedicationsLowOnStock(b.allowedUserID, 7)
 	lowStockIDs := make(map[int64]bool)
 	for _, m := range lowStockMeds {
 		lowStockIDs[m.ID] = true
 	}
 
 	for _, m := range trackedMeds {
-		daysRemaining := b.store.GetDaysOfStockRemaining(&m)
+		daysRemaining := b.store.GetDaysOfStockRemaining(b.allowedUserID, &m)
 
 		icon := "✅"
 		if lowStockIDs[m.ID] {
@@ -1166,7 +1539,12 @@ func (b *Bot) handleStockCommand(msgConfig *tgbotapi.MessageConfig) {
 			daysStr = fmt.Sprintf(" (~%.0f days)", *daysRemaining)
 		}
 
-		sb.WriteString(fmt.Sprintf("%s **%s**: %d units%s\n", icon, m.Name, *m.InventoryCount, daysStr))
+		packsStr := ""
+		if packs := m.PacksRemaining(); packs != nil {
+			packsStr = fmt.Sprintf(", %g packs", *packs)
+		}
+
+		sb.WriteString(fmt.Sprintf("%s **%s**: %g units%s%s\n", icon, m.Name, *m.InventoryCount, packsStr, daysStr))
 	}
 
 	if len(lowStockMeds) > 0 {
@@ -1176,3 +1554,47 @@ func (b *Bot) handleStockCommand(msgConfig *tgbotapi.MessageConfig) {
 	msgConfig.Text = sb.String()
 	msgConfig.ParseMode = "Markdown"
 }
+
+// handleStatsCommand shows 30-day medication adherence, listing critical-med misses first
+func (b *Bot) handleStatsCommand(msgConfig *tgbotapi.MessageConfig) {
+	stats, err := b.store.GetAdherenceStats(time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		log.Printf("Error getting adherence stats: %v", err)
+		msgConfig.Text = "❌ Error retrieving adherence stats."
+		return
+	}
+
+	if stats.Taken+stats.Missed == 0 {
+		msgConfig.Text = "📊 No scheduled doses in the last 30 days."
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📊 **Adherence (last 30 days)**\n\n")
+	sb.WriteString(fmt.Sprintf("Overall: %.0f%% (%d/%d doses)\n", stats.Rate, stats.Taken, stats.Taken+stats.Missed))
+	sb.WriteString(fmt.Sprintf("Weighted score: %.0f%%\n", stats.WeightedScore))
+
+	if stats.CriticalMisses > 0 {
+		sb.WriteString(fmt.Sprintf("\n🚨 **%d missed dose(s) of critical medications:**\n", stats.CriticalMisses))
+		for _, m := range stats.Medications {
+			if m.Priority == store.PriorityCritical && m.Missed > 0 {
+				sb.WriteString(fmt.Sprintf("• **%s**: %d missed\n", m.Name, m.Missed))
+			}
+		}
+	}
+
+	sb.WriteString("\n**By medication:**\n")
+	for _, m := range stats.Medications {
+		icon := "✅"
+		if m.Missed > 0 {
+			icon = "⚠️"
+		}
+		if m.Priority == store.PriorityCritical {
+			icon = "🔴"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s: %.0f%% (%d/%d)\n", icon, m.Name, m.Rate, m.Taken, m.Taken+m.Missed))
+	}
+
+	msgConfig.Text = sb.String()
+	msgConfig.ParseMode = "Markdown"
+}
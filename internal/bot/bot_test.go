@@ -0,0 +1,682 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/rxnorm"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// commandMessage builds a message the way Telegram delivers a bot command
+func commandMessage(text string) *tgbotapi.Message {
+	cmdLen := len(text)
+	if i := strings.Index(text, " "); i >= 0 {
+		cmdLen = i
+	}
+	return &tgbotapi.Message{
+		Text:     text,
+		Chat:     &tgbotapi.Chat{ID: 123},
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: cmdLen}},
+	}
+}
+
+func TestParseHistoryLimit(t *testing.T) {
+	cases := map[string]int{
+		"":    10,
+		"abc": 10,
+		"5":   5,
+		"0":   1,
+		"-3":  1,
+		"100": 50,
+	}
+	for in, want := range cases {
+		if got := parseHistoryLimit(in); got != want {
+			t.Errorf("parseHistoryLimit(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestHandleBPHistoryCommand_Limit(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	userID := int64(123)
+	for i := 0; i < 60; i++ {
+		s.CreateBloodPressureReading(context.Background(), &store.BloodPressure{
+			UserID:     userID,
+			MeasuredAt: time.Now().Add(-time.Duration(i) * 24 * time.Hour),
+			Systolic:   120,
+			Diastolic:  80,
+		})
+	}
+
+	b := &Bot{store: s, allowedUserID: userID}
+
+	countEntries := func(text string) int {
+		return strings.Count(text, "120/80")
+	}
+
+	msgConfig := tgbotapi.NewMessage(userID, "")
+	b.handleBPHistoryCommand(commandMessage("/bphistory 5"), &msgConfig)
+	if got := countEntries(msgConfig.Text); got != 5 {
+		t.Errorf("Expected 5 entries, got %d", got)
+	}
+
+	msgConfig = tgbotapi.NewMessage(userID, "")
+	b.handleBPHistoryCommand(commandMessage("/bphistory 500"), &msgConfig)
+	if got := countEntries(msgConfig.Text); got != 50 {
+		t.Errorf("Expected clamped 50 entries, got %d", got)
+	}
+
+	msgConfig = tgbotapi.NewMessage(userID, "")
+	b.handleBPHistoryCommand(commandMessage("/bphistory"), &msgConfig)
+	if got := countEntries(msgConfig.Text); got != 10 {
+		t.Errorf("Expected default 10 entries, got %d", got)
+	}
+}
+
+func TestHandleBPStatsCommand_Trend(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	// 130 over the last ten days after a month at 140
+	userID := int64(123)
+	now := time.Now()
+	for i := 1; i <= 50; i++ {
+		if i > 10 && i < 20 {
+			continue
+		}
+		sys := 130
+		if i >= 20 {
+			sys = 140
+		}
+		s.CreateBloodPressureReading(context.Background(), &store.BloodPressure{
+			UserID:     userID,
+			MeasuredAt: now.AddDate(0, 0, -i),
+			Systolic:   sys,
+			Diastolic:  80,
+		})
+	}
+
+	b := &Bot{store: s, allowedUserID: userID}
+	msgConfig := tgbotapi.NewMessage(userID, "")
+	b.handleBPStatsCommand(&msgConfig)
+	if !strings.Contains(msgConfig.Text, "📉 Your systolic is trending down vs last 60 days.") {
+		t.Errorf("Expected a falling trend line, got %q", msgConfig.Text)
+	}
+}
+
+func TestHandleWeightHistoryCommand_Limit(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	userID := int64(123)
+	for i := 0; i < 20; i++ {
+		s.CreateWeightLog(context.Background(), &store.WeightLog{
+			UserID:     userID,
+			MeasuredAt: time.Now().Add(-time.Duration(i) * 24 * time.Hour),
+			Weight:     80,
+		})
+	}
+
+	b := &Bot{store: s, allowedUserID: userID}
+
+	msgConfig := tgbotapi.NewMessage(userID, "")
+	b.handleWeightHistoryCommand(commandMessage("/weighthistory 5"), &msgConfig)
+	if got := strings.Count(msgConfig.Text, "80.0 kg"); got != 5 {
+		t.Errorf("Expected 5 entries, got %d", got)
+	}
+}
+
+func TestHandleSleepCommands(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	userID := int64(123)
+	b := &Bot{store: s, allowedUserID: userID}
+
+	msgConfig := tgbotapi.NewMessage(userID, "")
+	b.handleSleepCommand(&msgConfig)
+	if !strings.Contains(msgConfig.Text, "No sleep data yet.") {
+		t.Errorf("Expected the empty message, got %q", msgConfig.Text)
+	}
+	msgConfig = tgbotapi.NewMessage(userID, "")
+	b.handleSleepStatsCommand(&msgConfig)
+	if !strings.Contains(msgConfig.Text, "No sleep data yet.") {
+		t.Errorf("Expected the empty stats message, got %q", msgConfig.Text)
+	}
+
+	end := time.Now().Add(-time.Hour)
+	total, deep, rem, hr := 425, 90, 80, 58
+	s.ImportSleepLogs(context.Background(), userID, []store.SleepLog{
+		{StartTime: end.Add(-8 * time.Hour), EndTime: end, Day: "2025-06-01", TotalMinutes: &total, DeepMinutes: &deep, REMMinutes: &rem, HeartRateAvg: &hr},
+		// Stages and total missing: shown as the time in bed
+		{StartTime: end.Add(-32 * time.Hour), EndTime: end.Add(-24 * time.Hour), Day: "2025-05-31"},
+	})
+
+	msgConfig = tgbotapi.NewMessage(userID, "")
+	b.handleSleepCommand(&msgConfig)
+	if !strings.Contains(msgConfig.Text, "01.06.2025 — 7h 05m (deep 1h 30m, REM 1h 20m, HR 58)") {
+		t.Errorf("Expected the full night, got %q", msgConfig.Text)
+	}
+	if !strings.Contains(msgConfig.Text, "31.05.2025 — 8h 00m\n") {
+		t.Errorf("Expected the night without stages, got %q", msgConfig.Text)
+	}
+
+	msgConfig = tgbotapi.NewMessage(userID, "")
+	b.handleSleepStatsCommand(&msgConfig)
+	if !strings.Contains(msgConfig.Text, "Nights: 2") || !strings.Contains(msgConfig.Text, "Average sleep: 7h 05m") {
+		t.Errorf("Unexpected stats: %q", msgConfig.Text)
+	}
+}
+
+func TestIntakeNoteReply(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 555, "chat": {"id": 123}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+
+	userID := int64(123)
+	b := &Bot{api: api, store: s, allowedUserID: userID}
+
+	medID, _ := s.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	intakeID, _ := s.CreateIntake(medID, userID, time.Now())
+	s.ConfirmIntake(intakeID, time.Now())
+
+	// Confirmation message (id 555 from mock) becomes the reply target
+	b.sendTakenConfirmation(userID, "✅ Marked as taken.", []int64{intakeID})
+
+	// It isn't a reminder, so reminder cleanup never deletes it
+	if reminders, _ := s.GetIntakeReminders(intakeID); len(reminders) != 0 {
+		t.Errorf("Expected the confirmation not to be stored as a reminder, got %v", reminders)
+	}
+
+	b.handleMessage(&tgbotapi.Message{
+		Text:           "felt dizzy after",
+		Chat:           &tgbotapi.Chat{ID: userID},
+		From:           &tgbotapi.User{ID: userID},
+		ReplyToMessage: &tgbotapi.Message{MessageID: 555},
+	})
+
+	intake, _ := s.GetIntake(intakeID)
+	if intake.Notes != "felt dizzy after" {
+		t.Errorf("Expected note to be saved, got %q", intake.Notes)
+	}
+
+	intakes, _ := s.GetIntakesSince(time.Now().AddDate(0, 0, -1))
+	csvData, err := b.generateCSV(intakes)
+	if err != nil {
+		t.Fatalf("generateCSV: %v", err)
+	}
+	if !strings.Contains(string(csvData), "felt dizzy after") {
+		t.Errorf("Expected note in CSV export, got:\n%s", csvData)
+	}
+}
+
+func TestAddMedReplyToPrompt(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	var sent []string
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if text := r.FormValue("text"); text != "" {
+			sent = append(sent, text)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer tg.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	// Answering the prompt with Telegram's reply feature still reaches the conversation
+	b.handleMessage(commandMessage("/addmed"))
+	b.handleMessage(&tgbotapi.Message{
+		Text:           "Aspirin",
+		Chat:           &tgbotapi.Chat{ID: 123},
+		From:           &tgbotapi.User{ID: 123},
+		ReplyToMessage: &tgbotapi.Message{MessageID: 1},
+	})
+	if len(sent) != 2 || !strings.Contains(sent[1], "Dosage for Aspirin?") {
+		t.Errorf("Expected the dosage prompt, got %v", sent)
+	}
+}
+
+func TestAddMedFlow(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	var sent []string
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if text := r.FormValue("text"); text != "" {
+			sent = append(sent, text)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer tg.Close()
+
+	rxnav := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/rxcui.json"):
+			w.Write([]byte(`{"idGroup":{"rxnormId":["1191"]}}`))
+		case strings.HasSuffix(r.URL.Path, "/properties.json"):
+			w.Write([]byte(`{"properties":{"name":"aspirin"}}`))
+		default:
+			w.Write([]byte(`{"fullInteractionTypeGroup":[{"fullInteractionType":[{"interactionPair":[{
+				"interactionConcept":[
+					{"minConceptItem":{"name":"aspirin","rxcui":"1191"}},
+					{"minConceptItem":{"name":"warfarin","rxcui":"11289"}}
+				],
+				"severity":"high",
+				"description":"Increased bleeding risk"
+			}]}]}]}`))
+		}
+	}))
+	defer rxnav.Close()
+
+	rx := rxnorm.New()
+	rx.SetRESTEndpoint(rxnav.URL)
+	rx.SetInteractionEndpoint(rxnav.URL + "/interaction/list.json")
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+
+	userID := int64(123)
+	b := &Bot{api: api, store: s, allowedUserID: userID, rxnorm: rx}
+
+	// Existing medication to interact with
+	s.CreateMedication("Warfarin", "5mg", `{"type":"daily","times":["09:00"]}`, nil, nil, "11289", "warfarin")
+
+	text := func(t string) *tgbotapi.Message {
+		return &tgbotapi.Message{Text: t, Chat: &tgbotapi.Chat{ID: userID}, From: &tgbotapi.User{ID: userID}}
+	}
+
+	b.handleMessage(commandMessage("/addmed"))
+	b.handleMessage(text("Aspirin"))
+	b.handleMessage(text("100mg"))
+	b.handleMessage(text("20:00, 08:00 bedtime"))
+
+	meds, err := s.ListMedications(false)
+	if err != nil {
+		t.Fatalf("ListMedications failed: %v", err)
+	}
+	if len(meds) != 2 {
+		t.Fatalf("Expected 2 medications, got %d", len(meds))
+	}
+	med := meds[0]
+	if med.Name != "Aspirin" {
+		med = meds[1]
+	}
+	if med.Name != "Aspirin" || med.Dosage != "100mg" || med.RxCUI != "1191" || med.NormalizedName != "aspirin" {
+		t.Errorf("Unexpected medication: %+v", med)
+	}
+
+	cfg, err := med.ValidSchedule()
+	if err != nil {
+		t.Fatalf("Invalid schedule %q: %v", med.Schedule, err)
+	}
+	if cfg.Type != "daily" || strings.Join(cfg.Times, ",") != "08:00,20:00" || strings.Join(cfg.Slots, ",") != "bedtime" {
+		t.Errorf("Unexpected schedule: %+v", cfg)
+	}
+
+	if len(sent) != 4 {
+		t.Fatalf("Expected 4 replies, got %d: %v", len(sent), sent)
+	}
+	if !strings.Contains(sent[3], "Increased bleeding risk") {
+		t.Errorf("Expected interaction warning in final reply, got %q", sent[3])
+	}
+
+	// The conversation is over, so plain text is ignored again
+	b.handleMessage(text("hello"))
+	if len(sent) != 4 {
+		t.Errorf("Expected no reply after flow finished, got %v", sent[4:])
+	}
+}
+
+func TestAddMedCancel(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+	b.handleMessage(commandMessage("/addmed"))
+	b.handleMessage(commandMessage("/cancel"))
+
+	if b.handleAddMedReply(&tgbotapi.Message{Text: "Aspirin", Chat: &tgbotapi.Chat{ID: 123}}) {
+		t.Error("Expected no active conversation after /cancel")
+	}
+
+	// Expired conversations are dropped
+	b.addMed.put(123, &addMedConversation{expiresAt: time.Now().Add(-time.Minute)})
+	if b.handleAddMedReply(&tgbotapi.Message{Text: "Aspirin", Chat: &tgbotapi.Chat{ID: 123}}) {
+		t.Error("Expected expired conversation to be ignored")
+	}
+}
+
+func TestSendGroupNotification_CustomTemplate(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sent = append(sent, r.FormValue("text"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	if err := s.SetReminderTemplate(123, "{time} → {name} [{dosage}]"); err != nil {
+		t.Fatalf("SetReminderTemplate failed: %v", err)
+	}
+
+	target := time.Date(2026, 1, 1, 8, 0, 0, 0, time.Local)
+	meds := []store.Medication{{ID: 1, Name: "Aspirin", Dosage: "100mg"}}
+	if _, err := b.SendGroupNotification(meds, target); err != nil {
+		t.Fatalf("SendGroupNotification failed: %v", err)
+	}
+	if _, err := b.SendReminder(meds[0], target); err != nil {
+		t.Fatalf("SendReminder failed: %v", err)
+	}
+
+	if len(sent) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(sent))
+	}
+	for _, text := range sent {
+		if !strings.Contains(text, "08:00 → Aspirin [100mg]") {
+			t.Errorf("Expected rendered template in %q", text)
+		}
+	}
+}
+
+func TestDownloadCustomRange(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	medID, _ := s.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	for _, at := range []time.Time{
+		time.Date(2024, 2, 29, 8, 0, 0, 0, time.Local),
+		time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local),
+		time.Date(2024, 3, 31, 8, 0, 0, 0, time.Local),
+		time.Date(2024, 4, 1, 8, 0, 0, 0, time.Local),
+	} {
+		s.CreateIntake(medID, 123, at)
+	}
+	s.CreateBloodPressureReading(context.Background(), &store.BloodPressure{
+		UserID: 123, MeasuredAt: time.Date(2024, 3, 10, 9, 0, 0, 0, time.Local), Systolic: 120, Diastolic: 80,
+	})
+
+	var texts, captions []string
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if text := r.FormValue("text"); text != "" {
+			texts = append(texts, text)
+		}
+		if caption := r.FormValue("caption"); caption != "" {
+			captions = append(captions, caption)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer tg.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	// Dates given with the command
+	b.handleMessage(commandMessage("/download 2024-03-01 2024-03-31"))
+	want := []string{"Medication export (2 records)", "Blood pressure export (1 records)"}
+	if strings.Join(captions, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected captions %v, got %v", want, captions)
+	}
+
+	// Custom range button, then the dates as a plain message
+	captions = nil
+	b.handleCallback(&tgbotapi.CallbackQuery{
+		ID:      "1",
+		Data:    "download:custom",
+		Message: &tgbotapi.Message{MessageID: 5, Chat: &tgbotapi.Chat{ID: 123}},
+	})
+	b.handleMessage(&tgbotapi.Message{Text: "2024-04-01 2024-03-01", Chat: &tgbotapi.Chat{ID: 123}})
+	if len(captions) != 0 || !strings.Contains(texts[len(texts)-1], "must not be after") {
+		t.Errorf("Expected reversed range to be rejected, got captions %v, last text %q", captions, texts[len(texts)-1])
+	}
+	b.handleMessage(&tgbotapi.Message{Text: "2024-04-01 2024-04-30", Chat: &tgbotapi.Chat{ID: 123}})
+	if strings.Join(captions, "|") != "Medication export (1 records)" {
+		t.Errorf("Expected only the April intake, got %v", captions)
+	}
+
+	// The prompt is answered once; later messages are not treated as dates
+	if b.handleDownloadRangeReply(&tgbotapi.Message{Text: "2024-04-01 2024-04-30", Chat: &tgbotapi.Chat{ID: 123}}) {
+		t.Error("Expected no pending range prompt after the export")
+	}
+}
+
+func TestConfirmCallback_NearestPendingIntake(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer tg.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	medID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	now := time.Now()
+	older, _ := s.CreateIntake(medID, 123, now.Add(-6*time.Hour))
+	nearer, _ := s.CreateIntake(medID, 123, now.Add(-5*time.Minute))
+
+	b.handleCallback(&tgbotapi.CallbackQuery{
+		ID:      "1",
+		Data:    "confirm:" + strconv.FormatInt(medID, 10),
+		Message: &tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: 123}},
+	})
+
+	if in, _ := s.GetIntake(nearer); in.Status != "TAKEN" {
+		t.Errorf("Expected the intake nearest to now to be confirmed, got %s", in.Status)
+	}
+	if in, _ := s.GetIntake(older); in.Status != "PENDING" {
+		t.Errorf("Expected the older intake to stay pending, got %s", in.Status)
+	}
+}
+
+func TestConfirmCallback_ConfirmWindowSetting(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer tg.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	medID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	intakeID, _ := s.CreateIntake(medID, 123, time.Now().Add(-2*time.Hour))
+	confirm := func() {
+		b.handleCallback(&tgbotapi.CallbackQuery{
+			ID:      "1",
+			Data:    "confirm:" + strconv.FormatInt(medID, 10),
+			Message: &tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: 123}},
+		})
+	}
+
+	// Two hours late is outside a one hour window
+	if err := s.SetConfirmWindowMinutes(60); err != nil {
+		t.Fatalf("SetConfirmWindowMinutes: %v", err)
+	}
+	confirm()
+	if in, _ := s.GetIntake(intakeID); in.Status != "PENDING" {
+		t.Errorf("Expected the intake outside the window to stay pending, got %s", in.Status)
+	}
+
+	s.SetConfirmWindowMinutes(180)
+	confirm()
+	if in, _ := s.GetIntake(intakeID); in.Status != "TAKEN" {
+		t.Errorf("Expected the intake within the window to be confirmed, got %s", in.Status)
+	}
+}
+
+func TestConfirmScheduleCallback_DosePerIntake(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer tg.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	count := 30.0
+	twoTabs, _ := s.CreateMedication("Levetiracetam", "500mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	s.SetDosePerIntake(twoTabs, 2)
+	s.SetInventory(twoTabs, &count)
+	oneTab, _ := s.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	s.SetInventory(oneTab, &count)
+
+	at := time.Now().Add(-5 * time.Minute).Truncate(time.Second)
+	s.CreateIntake(twoTabs, 123, at)
+	s.CreateIntake(oneTab, 123, at)
+
+	b.handleCallback(&tgbotapi.CallbackQuery{
+		ID:      "1",
+		Data:    "confirm_schedule:" + strconv.FormatInt(at.Unix(), 10),
+		Message: &tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: 123}},
+	})
+
+	if m, _ := s.GetMedication(twoTabs); *m.InventoryCount != 28 {
+		t.Errorf("Expected two tablets taken, got %v left", *m.InventoryCount)
+	}
+	if m, _ := s.GetMedication(oneTab); *m.InventoryCount != 29 {
+		t.Errorf("Expected one tablet taken, got %v left", *m.InventoryCount)
+	}
+}
+
+func TestSendNotification_LogsAttempts(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if fail {
+			w.Write([]byte(`{"ok":false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	if _, err := b.SendNotification("💊 Time for Aspirin", 1); err != nil {
+		t.Fatalf("SendNotification failed: %v", err)
+	}
+	fail = true
+	if _, err := b.SendNotification("🔔 REMINDER: Aspirin", 1); err == nil {
+		t.Fatal("Expected the blocked send to fail")
+	}
+
+	entries, err := s.GetNotificationLog(123, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetNotificationLog failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 log entries, got %d", len(entries))
+	}
+	// Newest first
+	failed, ok := entries[0], entries[1]
+	if !ok.Success || ok.Error != "" || ok.Channel != store.ChannelTelegram || ok.Target != "123" || ok.Summary != "💊 Time for Aspirin" {
+		t.Errorf("Unexpected successful entry: %+v", ok)
+	}
+	if failed.Success || !strings.Contains(failed.Error, "blocked") {
+		t.Errorf("Unexpected failed entry: %+v", failed)
+	}
+}
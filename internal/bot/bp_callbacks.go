@@ -30,7 +30,7 @@ func (b *Bot) SendBPReminderNotification(userID int64, enhanced bool) (int, erro
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = keyboard
 
-	sent, err := b.api.Send(msg)
+	sent, err := b.sendLogged(msg)
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// downloadRangeTimeout is how long the bot waits for a custom /download range
+const downloadRangeTimeout = 10 * time.Minute
+
+const downloadRangePrompt = "📅 Send the range to export as two dates, e.g. \"2024-03-01 2024-03-31\". Both days are included."
+
+// downloadRangeState remembers chats that were asked for a custom export range
+type downloadRangeState struct {
+	mu      sync.Mutex
+	waiting map[int64]time.Time // chat ID -> prompt expiry
+}
+
+func (s *downloadRangeState) await(chatID int64, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.waiting == nil {
+		s.waiting = make(map[int64]time.Time)
+	}
+	s.waiting[chatID] = until
+}
+
+// take reports whether the chat has an unexpired prompt and clears it
+func (s *downloadRangeState) take(chatID int64, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.waiting[chatID]
+	delete(s.waiting, chatID)
+	return ok && !now.After(expiresAt)
+}
+
+// parseDownloadRange parses "YYYY-MM-DD YYYY-MM-DD" into the first instant of the
+// first day and the last instant of the second, in local time
+func parseDownloadRange(text string) (from, to time.Time, err error) {
+	parts := strings.Fields(strings.ReplaceAll(text, ",", " "))
+	if len(parts) != 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("expected two dates like 2024-03-01 2024-03-31")
+	}
+	from, err = time.ParseInLocation("2006-01-02", parts[0], time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date %q, use YYYY-MM-DD", parts[0])
+	}
+	to, err = time.ParseInLocation("2006-01-02", parts[1], time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date %q, use YYYY-MM-DD", parts[1])
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the start date must not be after the end date")
+	}
+	return from, to.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// handleDownloadRangeReply answers a custom range prompt with the export.
+// Returns false if the chat wasn't asked for a range.
+func (b *Bot) handleDownloadRangeReply(msg *tgbotapi.Message) bool {
+	if !b.downloadRange.take(msg.Chat.ID, time.Now()) {
+		return false
+	}
+
+	from, to, err := parseDownloadRange(msg.Text)
+	if err != nil {
+		// Keep waiting so the user can correct the dates
+		b.downloadRange.await(msg.Chat.ID, time.Now().Add(downloadRangeTimeout))
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⚠️ %v. Please try again.", err)))
+		return true
+	}
+
+	b.sendExport(msg.Chat.ID, from, to)
+	return true
+}
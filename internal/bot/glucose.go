@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// Plausible glucose readings, in mg/dL
+const (
+	minGlucoseMgdl = 10
+	maxGlucoseMgdl = 1000
+)
+
+// handleGlucoseCommand logs a reading: /glucose <value> [mgdl|mmol] [meal context].
+// Without a unit the value is read in the preferred unit.
+func (b *Bot) handleGlucoseCommand(msg *tgbotapi.Message, msgConfig *tgbotapi.MessageConfig) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 {
+		msgConfig.Text = `**Glucose Logging**
+
+Usage: /glucose <value> [mgdl|mmol] [context]
+
+Examples:
+  /glucose 105 - Log 105 in your preferred unit
+  /glucose 5.8 mmol fasting - Log 5.8 mmol/L before breakfast
+
+Contexts: ` + strings.Join(store.MealContexts, ", ")
+		msgConfig.ParseMode = "Markdown"
+		return
+	}
+
+	value, err := strconv.ParseFloat(strings.Replace(args[0], ",", ".", 1), 64)
+	if err != nil {
+		msgConfig.Text = "❌ Invalid format. Use: /glucose <value> [mgdl|mmol] [context]"
+		return
+	}
+
+	unit, err := b.store.GetGlucoseUnit()
+	if err != nil {
+		log.Printf("Error getting glucose unit: %v", err)
+		unit = store.GlucoseUnitMgdl
+	}
+
+	var mealContext string
+	for _, arg := range args[1:] {
+		if u, err := store.ParseGlucoseUnit(arg); err == nil {
+			unit = u
+			continue
+		}
+		c := strings.ToLower(arg)
+		if !store.ValidMealContext(c) {
+			msgConfig.Text = fmt.Sprintf("❌ Unknown unit or context %q. Contexts: %s", arg, strings.Join(store.MealContexts, ", "))
+			return
+		}
+		mealContext = c
+	}
+
+	mgdl := store.GlucoseToMgdl(value, unit)
+	if mgdl < minGlucoseMgdl || mgdl > maxGlucoseMgdl {
+		msgConfig.Text = fmt.Sprintf("❌ Invalid glucose value (%.0f-%.0f mg/dL)", float64(minGlucoseMgdl), float64(maxGlucoseMgdl))
+		return
+	}
+
+	g := &store.GlucoseLog{
+		UserID:      b.allowedUserID,
+		MeasuredAt:  time.Now(),
+		ValueMgdl:   mgdl,
+		MealContext: mealContext,
+	}
+	if _, err := b.store.CreateGlucoseLog(context.Background(), g); err != nil {
+		log.Printf("Error creating glucose log: %v", err)
+		msgConfig.Text = "❌ Error saving glucose reading."
+		return
+	}
+
+	text := "✅ Glucose recorded: " + formatGlucose(mgdl, unit)
+	if mealContext != "" {
+		text += " (" + strings.ReplaceAll(mealContext, "_", " ") + ")"
+	}
+	switch {
+	case mgdl < store.GlucoseRangeLowMgdl:
+		text += "\n⚠️ Below target range"
+	case mgdl > store.GlucoseRangeHighMgdl:
+		text += "\n⚠️ Above target range"
+	}
+	msgConfig.Text = text
+}
+
+// formatGlucose renders a stored mg/dL value in the given unit
+func formatGlucose(mgdl float64, unit string) string {
+	if unit == store.GlucoseUnitMmol {
+		return fmt.Sprintf("%.1f %s", store.GlucoseFromMgdl(mgdl, unit), unit)
+	}
+	return fmt.Sprintf("%.0f %s", store.GlucoseFromMgdl(mgdl, unit), unit)
+}
@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// inlineQueryLookups maps what the user types after @bot to the lookups it selects
+var inlineQueryLookups = map[string][]string{
+	"bp":     {"bp"},
+	"weight": {"weight"},
+	"next":   {"next"},
+	"dose":   {"next"},
+	"meds":   {"next"},
+}
+
+// handleInlineQuery answers "@bot bp", "@bot weight" or "@bot next" with the latest
+// reading or the upcoming dose, ready to share in any chat. An empty or unknown query
+// offers all of them. Results are personal and cached by Telegram for a second at most.
+func (b *Bot) handleInlineQuery(q *tgbotapi.InlineQuery) {
+	lookups, ok := inlineQueryLookups[strings.ToLower(strings.TrimSpace(q.Query))]
+	if !ok {
+		lookups = []string{"bp", "weight", "next"}
+	}
+
+	results := []interface{}{}
+	for _, lookup := range lookups {
+		var article *tgbotapi.InlineQueryResultArticle
+		switch lookup {
+		case "bp":
+			article = b.latestBPArticle()
+		case "weight":
+			article = b.latestWeightArticle()
+		case "next":
+			article = b.nextDoseArticle(time.Now())
+		}
+		if article != nil {
+			results = append(results, *article)
+		}
+	}
+
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: q.ID,
+		Results:       results,
+		IsPersonal:    true,
+		CacheTime:     1,
+	}
+	if _, err := b.api.Request(answer); err != nil {
+		log.Printf("Error answering inline query: %v", err)
+	}
+}
+
+func (b *Bot) latestBPArticle() *tgbotapi.InlineQueryResultArticle {
+	readings, err := b.store.GetRecentBloodPressureReadings(context.Background(), b.allowedUserID, time.Time{}, 1)
+	if err != nil {
+		log.Printf("Error getting latest BP reading: %v", err)
+		return nil
+	}
+	if len(readings) == 0 {
+		return nil
+	}
+
+	bp := readings[0]
+	pulseStr := ""
+	if bp.Pulse != nil {
+		pulseStr = fmt.Sprintf(", pulse %d", *bp.Pulse)
+	}
+	category := bp.Category
+	if category == "" {
+		category = store.CalculateBPCategory(bp.Systolic, bp.Diastolic)
+	}
+	text := fmt.Sprintf("🩺 Blood pressure %d/%d%s (%s), %s", bp.Systolic, bp.Diastolic, pulseStr, category, bp.MeasuredAt.Format("02.01.2006 15:04"))
+
+	article := tgbotapi.NewInlineQueryResultArticle("bp", fmt.Sprintf("Latest BP: %d/%d", bp.Systolic, bp.Diastolic), text)
+	article.Description = text
+	return &article
+}
+
+func (b *Bot) latestWeightArticle() *tgbotapi.InlineQueryResultArticle {
+	w, err := b.store.GetLastWeightLog(context.Background(), b.allowedUserID)
+	if err != nil {
+		log.Printf("Error getting latest weight: %v", err)
+		return nil
+	}
+	if w == nil {
+		return nil
+	}
+
+	trendStr := ""
+	if w.WeightTrend != nil {
+		trendStr = fmt.Sprintf(" (trend %.1f kg)", *w.WeightTrend)
+	}
+	text := fmt.Sprintf("⚖️ Weight %.1f kg%s, %s", w.Weight, trendStr, w.MeasuredAt.Format("02.01.2006 15:04"))
+
+	article := tgbotapi.NewInlineQueryResultArticle("weight", fmt.Sprintf("Latest weight: %.1f kg", w.Weight), text)
+	article.Description = text
+	return &article
+}
+
+// nextDoseArticle lists the active medications due at the earliest upcoming dose time
+func (b *Bot) nextDoseArticle(now time.Time) *tgbotapi.InlineQueryResultArticle {
+	meds, err := b.store.ListMedications(false)
+	if err != nil {
+		log.Printf("Error listing medications: %v", err)
+		return nil
+	}
+
+	var next time.Time
+	var due []string
+	for _, m := range meds {
+		at, ok := b.store.NextOccurrence(b.allowedUserID, &m, now)
+		if !ok {
+			continue
+		}
+		switch {
+		case next.IsZero() || at.Before(next):
+			next = at
+			due = []string{fmt.Sprintf("%s (%s)", m.Name, m.Dosage)}
+		case at.Equal(next):
+			due = append(due, fmt.Sprintf("%s (%s)", m.Name, m.Dosage))
+		}
+	}
+	if len(due) == 0 {
+		return nil
+	}
+	sort.Strings(due)
+
+	when := next.Format("15:04")
+	if y, m, d := next.Date(); y != now.Year() || m != now.Month() || d != now.Day() {
+		when = next.Format("Mon 02.01 15:04")
+	}
+	text := fmt.Sprintf("💊 Next dose at %s: %s", when, strings.Join(due, ", "))
+
+	article := tgbotapi.NewInlineQueryResultArticle("next", "Next dose at "+when, text)
+	article.Description = strings.Join(due, ", ")
+	return &article
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestHandleUpdate_InlineQuery(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	var mu sync.Mutex
+	var answers, cacheTimes []string
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if strings.HasSuffix(r.URL.Path, "/answerInlineQuery") {
+			mu.Lock()
+			answers = append(answers, r.FormValue("results"))
+			cacheTimes = append(cacheTimes, r.FormValue("cache_time"))
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": true}`))
+	}))
+	defer tg.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	ctx := context.Background()
+	pulse := 68
+	s.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: 123, MeasuredAt: time.Now().Add(-48 * time.Hour), Systolic: 141, Diastolic: 92})
+	s.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: 123, MeasuredAt: time.Now().Add(-time.Hour), Systolic: 124, Diastolic: 79, Pulse: &pulse})
+	trend := 81.3
+	s.CreateWeightLog(ctx, &store.WeightLog{UserID: 123, MeasuredAt: time.Now(), Weight: 80.9, WeightTrend: &trend})
+	s.CreateMedication("Lisinopril", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+
+	query := func(from int64, text string) []tgbotapi.InlineQueryResultArticle {
+		t.Helper()
+		mu.Lock()
+		answers = nil
+		mu.Unlock()
+
+		b.handleUpdate(tgbotapi.Update{InlineQuery: &tgbotapi.InlineQuery{
+			ID:    "q1",
+			From:  &tgbotapi.User{ID: from},
+			Query: text,
+		}})
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(answers) == 0 {
+			return nil
+		}
+		var results []tgbotapi.InlineQueryResultArticle
+		if err := json.Unmarshal([]byte(answers[0]), &results); err != nil {
+			t.Fatalf("Failed to decode results %q: %v", answers[0], err)
+		}
+		return results
+	}
+	text := func(a tgbotapi.InlineQueryResultArticle) string {
+		content, _ := a.InputMessageContent.(map[string]interface{})
+		s, _ := content["message_text"].(string)
+		return s
+	}
+
+	results := query(123, "bp")
+	// Telegram caches answers for five minutes unless told otherwise
+	if len(cacheTimes) != 1 || cacheTimes[0] != "1" {
+		t.Errorf("Expected a one second cache time, got %v", cacheTimes)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected one BP result, got %+v", results)
+	}
+	if !strings.Contains(text(results[0]), "124/79") || !strings.Contains(text(results[0]), "pulse 68") {
+		t.Errorf("Expected the latest reading 124/79 with pulse 68, got %q", text(results[0]))
+	}
+
+	// An empty query offers everything
+	results = query(123, "")
+	if len(results) != 3 {
+		t.Fatalf("Expected BP, weight and next dose results, got %d", len(results))
+	}
+	if !strings.Contains(text(results[1]), "80.9 kg") || !strings.Contains(text(results[2]), "Lisinopril") {
+		t.Errorf("Unexpected weight or next dose results: %q, %q", text(results[1]), text(results[2]))
+	}
+
+	if results := query(999, "bp"); results != nil {
+		t.Errorf("Expected queries from other users to be ignored, got %+v", results)
+	}
+}
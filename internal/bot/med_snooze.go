@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// medSnoozeRow builds one snooze button per configured duration.
+// Callback data: "med_snooze:<medID>:<minutes>"
+func (b *Bot) medSnoozeRow(medicationID int64) []tgbotapi.InlineKeyboardButton {
+	return b.snoozeRow(fmt.Sprintf("med_snooze:%d", medicationID))
+}
+
+// groupSnoozeRow is the snooze row of a group notification, snoozing every dose of the
+// schedule at once. Callback data: "group_snooze:<unix>:<minutes>"
+func (b *Bot) groupSnoozeRow(target time.Time) []tgbotapi.InlineKeyboardButton {
+	return b.snoozeRow(fmt.Sprintf("group_snooze:%d", target.Unix()))
+}
+
+func (b *Bot) snoozeRow(prefix string) []tgbotapi.InlineKeyboardButton {
+	minutes, err := b.store.GetMedSnoozeMinutes()
+	if err != nil {
+		log.Printf("Error loading snooze durations: %v", err)
+		minutes = store.DefaultMedSnoozeMinutes
+	}
+
+	row := make([]tgbotapi.InlineKeyboardButton, 0, len(minutes))
+	for _, m := range minutes {
+		data := fmt.Sprintf("%s:%d", prefix, m)
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("⏰ "+formatSnoozeDuration(m), data))
+	}
+	return row
+}
+
+// formatSnoozeDuration renders minutes as e.g. "15m", "1h" or "1h30m"
+func formatSnoozeDuration(minutes int) string {
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	default:
+		return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+	}
+}
+
+// handleMedSnoozeCallback postpones the reminder for the medication's pending dose
+func (b *Bot) handleMedSnoozeCallback(cb *tgbotapi.CallbackQuery, data string) {
+	parts := strings.Split(strings.TrimPrefix(data, "med_snooze:"), ":")
+	if len(parts) != 2 {
+		return
+	}
+	medID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes <= 0 {
+		return
+	}
+
+	window, err := b.store.ConfirmWindow()
+	if err != nil {
+		log.Printf("Error getting confirm window: %v", err)
+		return
+	}
+	now := time.Now()
+	pending, err := b.store.GetNearestPendingIntake(medID, now, window)
+	if err != nil {
+		log.Printf("Error getting pending intake: %v", err)
+		return
+	}
+	if pending == nil {
+		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "⚠️ No pending intake found (or already taken)."))
+		return
+	}
+
+	until := now.Add(time.Duration(minutes) * time.Minute)
+	if err := b.store.SnoozeIntake(pending.ID, until); err != nil {
+		log.Printf("Error snoozing intake %d: %v", pending.ID, err)
+		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "❌ Error snoozing reminder."))
+		return
+	}
+
+	// Remove buttons
+	edit := tgbotapi.NewEditMessageReplyMarkup(cb.Message.Chat.ID, cb.Message.MessageID, tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+	})
+	b.api.Send(edit)
+
+	b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, fmt.Sprintf("⏰ Snoozed for %s, I'll remind you again at %s.", formatSnoozeDuration(minutes), until.Format("15:04"))))
+}
+
+// handleGroupSnoozeCallback postpones the reminders of every pending dose of a schedule
+func (b *Bot) handleGroupSnoozeCallback(cb *tgbotapi.CallbackQuery, data string) {
+	parts := strings.Split(strings.TrimPrefix(data, "group_snooze:"), ":")
+	if len(parts) != 2 {
+		return
+	}
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes <= 0 {
+		return
+	}
+
+	pending, err := b.store.GetPendingIntakesBySchedule(b.allowedUserID, time.Unix(ts, 0))
+	if err != nil {
+		log.Printf("Error getting pending intakes: %v", err)
+		return
+	}
+	if len(pending) == 0 {
+		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "⚠️ No pending intakes found (or already taken)."))
+		return
+	}
+
+	until := time.Now().Add(time.Duration(minutes) * time.Minute)
+	for _, p := range pending {
+		if err := b.store.SnoozeIntake(p.ID, until); err != nil {
+			log.Printf("Error snoozing intake %d: %v", p.ID, err)
+			b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "❌ Error snoozing reminder."))
+			return
+		}
+	}
+
+	// Remove buttons
+	edit := tgbotapi.NewEditMessageReplyMarkup(cb.Message.Chat.ID, cb.Message.MessageID, tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+	})
+	b.api.Send(edit)
+
+	b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, fmt.Sprintf("⏰ Snoozed for %s, I'll remind you again at %s.", formatSnoozeDuration(minutes), until.Format("15:04"))))
+}
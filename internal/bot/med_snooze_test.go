@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestFormatSnoozeDuration(t *testing.T) {
+	cases := map[int]string{15: "15m", 60: "1h", 90: "1h30m", 120: "2h"}
+	for in, want := range cases {
+		if got := formatSnoozeDuration(in); got != want {
+			t.Errorf("formatSnoozeDuration(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSendNotification_SnoozePresets(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	var markups []string
+	var texts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if m := r.FormValue("reply_markup"); m != "" {
+			markups = append(markups, m)
+		}
+		if text := r.FormValue("text"); text != "" {
+			texts = append(texts, text)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	snoozeData := func() []string {
+		t.Helper()
+		var markup tgbotapi.InlineKeyboardMarkup
+		if err := json.Unmarshal([]byte(markups[len(markups)-1]), &markup); err != nil {
+			t.Fatalf("Failed to decode reply markup: %v", err)
+		}
+		if len(markup.InlineKeyboard) != 2 {
+			t.Fatalf("Expected confirm and snooze rows, got %d rows", len(markup.InlineKeyboard))
+		}
+		var data []string
+		for _, btn := range markup.InlineKeyboard[1] {
+			data = append(data, *btn.CallbackData)
+		}
+		return data
+	}
+
+	// Defaults
+	if _, err := b.SendNotification("🔔 REMINDER: Aspirin", 5); err != nil {
+		t.Fatalf("SendNotification failed: %v", err)
+	}
+	want := []string{"med_snooze:5:15", "med_snooze:5:30", "med_snooze:5:60"}
+	if got := snoozeData(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected default snooze buttons %v, got %v", want, got)
+	}
+
+	// Configured presets
+	if err := s.SetMedSnoozeMinutes([]int{90, 10, 10}); err != nil {
+		t.Fatalf("SetMedSnoozeMinutes failed: %v", err)
+	}
+	if _, err := b.SendNotification("🔔 REMINDER: Aspirin", 5); err != nil {
+		t.Fatalf("SendNotification failed: %v", err)
+	}
+	want = []string{"med_snooze:5:10", "med_snooze:5:90"}
+	if got := snoozeData(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected configured snooze buttons %v, got %v", want, got)
+	}
+
+	// Snoozing a preset holds off the pending dose for that long
+	medID, _ := s.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	intakeID, _ := s.CreateIntake(medID, 123, time.Now().Add(-70*time.Minute))
+	before := time.Now()
+	b.handleCallback(&tgbotapi.CallbackQuery{
+		ID:      "1",
+		Data:    "med_snooze:" + strconv.FormatInt(medID, 10) + ":90",
+		Message: &tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: 123}},
+	})
+
+	pending, err := s.GetPendingIntakes()
+	if err != nil || len(pending) != 1 || pending[0].ID != intakeID {
+		t.Fatalf("Expected the intake to stay pending, got %v (%v)", pending, err)
+	}
+	until := pending[0].SnoozedUntil
+	if until == nil || until.Before(before.Add(89*time.Minute)) || until.After(time.Now().Add(91*time.Minute)) {
+		t.Fatalf("Expected snooze of 90 minutes, got %v", until)
+	}
+	if due, _ := s.GetDueSnoozedIntakes(time.Now()); len(due) != 0 {
+		t.Errorf("Expected no due snoozes yet, got %d", len(due))
+	}
+	if due, _ := s.GetDueSnoozedIntakes(time.Now().Add(2 * time.Hour)); len(due) != 1 {
+		t.Errorf("Expected the snooze to be due after 2 hours, got %d", len(due))
+	}
+	if last := texts[len(texts)-1]; !strings.HasPrefix(last, "⏰ Snoozed for 1h30m") {
+		t.Errorf("Unexpected snooze reply %q", last)
+	}
+}
+
+func TestGroupSnoozeCallback(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	target := time.Now().Add(-70 * time.Minute).Truncate(time.Minute)
+	medA, _ := s.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	medB, _ := s.CreateMedication("Vitamin D", "1000IU", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	s.CreateIntake(medA, 123, target)
+	s.CreateIntake(medB, 123, target)
+
+	b.handleCallback(&tgbotapi.CallbackQuery{
+		ID:      "1",
+		Data:    "group_snooze:" + strconv.FormatInt(target.Unix(), 10) + ":30",
+		Message: &tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: 123}},
+	})
+
+	if due, _ := s.GetDueSnoozedIntakes(time.Now().Add(time.Hour)); len(due) != 2 {
+		t.Errorf("Expected both doses of the group to be snoozed, got %d", len(due))
+	}
+	if due, _ := s.GetDueSnoozedIntakes(time.Now()); len(due) != 0 {
+		t.Errorf("Expected no due snoozes yet, got %d", len(due))
+	}
+}
@@ -0,0 +1,205 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const reminderCommandUsage = "Usage: /%s [on|off|snooze|dontbug]\n" +
+	"on - enable reminders\n" +
+	"off - disable reminders\n" +
+	"snooze - pause for 2 hours\n" +
+	"dontbug - pause for 24 hours"
+
+// handleBPReminderCommand shows or changes the BP reminder state
+func (b *Bot) handleBPReminderCommand(msg *tgbotapi.Message, msgConfig *tgbotapi.MessageConfig) {
+	// Loading the state first creates it for new users, so snoozes have a row to update
+	state, err := b.store.GetBPReminderState(b.allowedUserID)
+	if err != nil {
+		log.Printf("Error loading BP reminder state: %v", err)
+		msgConfig.Text = "Error loading BP reminder settings."
+		return
+	}
+
+	arg := strings.ToLower(strings.TrimSpace(msg.CommandArguments()))
+	switch arg {
+	case "":
+	case "on":
+		err = b.store.SetBPReminderEnabled(b.allowedUserID, true)
+	case "off":
+		err = b.store.SetBPReminderEnabled(b.allowedUserID, false)
+	case "snooze":
+		err = b.store.SnoozeBPReminder(b.allowedUserID)
+	case "dontbug":
+		err = b.store.DontBugMeBPReminder(b.allowedUserID)
+	default:
+		msgConfig.Text = fmt.Sprintf(reminderCommandUsage, "bpreminder")
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating BP reminder (%s): %v", arg, err)
+		msgConfig.Text = "Error updating BP reminder settings."
+		return
+	}
+	if arg != "" {
+		if state, err = b.store.GetBPReminderState(b.allowedUserID); err != nil {
+			msgConfig.Text = "Error loading BP reminder settings."
+			return
+		}
+	}
+
+	now := time.Now()
+	var next time.Time
+	if state.Enabled {
+		var lastMeasured *time.Time
+		if last, err := b.store.GetLastBPReading(context.Background(), b.allowedUserID); err == nil && last != nil {
+			lastMeasured = &last.MeasuredAt
+		}
+		next = nextBPReminderAt(now, state.SnoozedUntil, state.DontRemindUntil, state.LastNotificationSentAt, lastMeasured, state.PreferredReminderHour)
+	}
+	msgConfig.Text = formatReminderStatus("📊 BP reminders", state.Enabled, state.SnoozedUntil, state.DontRemindUntil, next, now)
+}
+
+// handleWeightReminderCommand shows or changes the weight reminder state
+func (b *Bot) handleWeightReminderCommand(msg *tgbotapi.Message, msgConfig *tgbotapi.MessageConfig) {
+	state, err := b.store.GetWeightReminderState(b.allowedUserID)
+	if err != nil {
+		log.Printf("Error loading weight reminder state: %v", err)
+		msgConfig.Text = "Error loading weight reminder settings."
+		return
+	}
+
+	arg := strings.ToLower(strings.TrimSpace(msg.CommandArguments()))
+	switch arg {
+	case "":
+	case "on":
+		err = b.store.SetWeightReminderEnabled(b.allowedUserID, true)
+	case "off":
+		err = b.store.SetWeightReminderEnabled(b.allowedUserID, false)
+	case "snooze":
+		err = b.store.SnoozeWeightReminder(b.allowedUserID)
+	case "dontbug":
+		err = b.store.DontBugMeWeightReminder(b.allowedUserID)
+	default:
+		msgConfig.Text = fmt.Sprintf(reminderCommandUsage, "weightreminder")
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating weight reminder (%s): %v", arg, err)
+		msgConfig.Text = "Error updating weight reminder settings."
+		return
+	}
+	if arg != "" {
+		if state, err = b.store.GetWeightReminderState(b.allowedUserID); err != nil {
+			msgConfig.Text = "Error loading weight reminder settings."
+			return
+		}
+	}
+
+	now := time.Now()
+	var next time.Time
+	if state.Enabled {
+		var lastMeasured *time.Time
+		if last, err := b.store.GetLastWeightLog(context.Background(), b.allowedUserID); err == nil && last != nil {
+			lastMeasured = &last.MeasuredAt
+		}
+		next = nextWeightReminderAt(now, state.SnoozedUntil, state.DontRemindUntil, state.LastNotificationSentAt, lastMeasured, state.PreferredReminderHour)
+	}
+	msgConfig.Text = formatReminderStatus("⚖️ Weight reminders", state.Enabled, state.SnoozedUntil, state.DontRemindUntil, next, now)
+}
+
+func formatReminderStatus(title string, enabled bool, snoozedUntil, dontRemindUntil *time.Time, next, now time.Time) string {
+	if !enabled {
+		return title + ": off"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(title + ": on")
+	if snoozedUntil != nil && now.Before(*snoozedUntil) {
+		sb.WriteString(fmt.Sprintf("\nSnoozed until %s", snoozedUntil.In(now.Location()).Format("Jan 2 15:04")))
+	}
+	if dontRemindUntil != nil && now.Before(*dontRemindUntil) {
+		sb.WriteString(fmt.Sprintf("\nPaused until %s", dontRemindUntil.In(now.Location()).Format("Jan 2 15:04")))
+	}
+	if !next.IsZero() {
+		sb.WriteString(fmt.Sprintf("\nNext reminder: around %s", next.In(now.Location()).Format("Mon Jan 2 15:04")))
+	}
+	return sb.String()
+}
+
+// nextBPReminderAt estimates the earliest time the scheduler will send a BP reminder:
+// within an hour of the preferred hour, at most once a day, not on a day that already
+// has a reading and not within 12 hours of the last one.
+func nextBPReminderAt(now time.Time, snoozedUntil, dontRemindUntil, lastSent, lastMeasured *time.Time, preferredHour int) time.Time {
+	earliest := latest(now, snoozedUntil, dontRemindUntil)
+	if lastMeasured != nil {
+		earliest = latest(earliest, ptr(lastMeasured.Add(12*time.Hour)))
+	}
+
+	loc := now.Location()
+	day := time.Date(earliest.Year(), earliest.Month(), earliest.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i < 3; i++ {
+		d := day.AddDate(0, 0, i)
+		if sameDay(lastMeasured, d) || sameDay(lastSent, d) {
+			continue
+		}
+		windowStart := d.Add(time.Duration(preferredHour-1) * time.Hour)
+		windowEnd := d.Add(time.Duration(preferredHour+2) * time.Hour)
+		if t := latest(earliest, &windowStart); t.Before(windowEnd) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// nextWeightReminderAt estimates the earliest time the scheduler will send a weight
+// reminder: within two hours of the preferred hour, a week after the last measurement
+// and the last reminder.
+func nextWeightReminderAt(now time.Time, snoozedUntil, dontRemindUntil, lastSent, lastMeasured *time.Time, preferredHour int) time.Time {
+	earliest := latest(now, snoozedUntil, dontRemindUntil)
+	for _, t := range []*time.Time{lastMeasured, lastSent} {
+		if t != nil {
+			earliest = latest(earliest, ptr(t.Add(7*24*time.Hour)))
+		}
+	}
+
+	loc := now.Location()
+	day := time.Date(earliest.Year(), earliest.Month(), earliest.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i < 2; i++ {
+		d := day.AddDate(0, 0, i)
+		windowStart := d.Add(time.Duration(preferredHour-2) * time.Hour)
+		windowEnd := d.Add(time.Duration(preferredHour+3) * time.Hour)
+		if t := latest(earliest, &windowStart); t.Before(windowEnd) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// latest returns the latest of base and the non-nil times
+func latest(base time.Time, times ...*time.Time) time.Time {
+	for _, t := range times {
+		if t != nil && t.After(base) {
+			base = *t
+		}
+	}
+	return base
+}
+
+func sameDay(t *time.Time, day time.Time) bool {
+	if t == nil {
+		return false
+	}
+	y1, m1, d1 := t.In(day.Location()).Date()
+	y2, m2, d2 := day.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestHandleBPReminderCommand(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+	b := &Bot{store: s, allowedUserID: 123}
+
+	run := func(text string) string {
+		msgConfig := tgbotapi.NewMessage(123, "")
+		b.handleBPReminderCommand(commandMessage(text), &msgConfig)
+		return msgConfig.Text
+	}
+
+	if reply := run("/bpreminder"); !strings.Contains(reply, "on") || !strings.Contains(reply, "Next reminder") {
+		t.Errorf("Expected status with next reminder, got %q", reply)
+	}
+
+	if reply := run("/bpreminder off"); !strings.HasSuffix(reply, "off") {
+		t.Errorf("Expected reminders off, got %q", reply)
+	}
+	state, _ := s.GetBPReminderState(123)
+	if state.Enabled {
+		t.Error("Expected BP reminders to be disabled")
+	}
+
+	run("/bpreminder on")
+	state, _ = s.GetBPReminderState(123)
+	if !state.Enabled {
+		t.Error("Expected BP reminders to be enabled")
+	}
+
+	if reply := run("/bpreminder snooze"); !strings.Contains(reply, "Snoozed until") {
+		t.Errorf("Expected snooze in reply, got %q", reply)
+	}
+	state, _ = s.GetBPReminderState(123)
+	if state.SnoozedUntil == nil || time.Until(*state.SnoozedUntil) < 110*time.Minute {
+		t.Errorf("Expected a 2 hour snooze, got %v", state.SnoozedUntil)
+	}
+
+	run("/bpreminder dontbug")
+	state, _ = s.GetBPReminderState(123)
+	if state.DontRemindUntil == nil || time.Until(*state.DontRemindUntil) < 23*time.Hour {
+		t.Errorf("Expected a 24 hour pause, got %v", state.DontRemindUntil)
+	}
+
+	if reply := run("/bpreminder later"); !strings.HasPrefix(reply, "Usage") {
+		t.Errorf("Expected usage for unknown subcommand, got %q", reply)
+	}
+}
+
+func TestHandleWeightReminderCommand(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+	b := &Bot{store: s, allowedUserID: 123}
+
+	run := func(text string) {
+		msgConfig := tgbotapi.NewMessage(123, "")
+		b.handleWeightReminderCommand(commandMessage(text), &msgConfig)
+	}
+
+	run("/weightreminder off")
+	state, _ := s.GetWeightReminderState(123)
+	if state.Enabled {
+		t.Error("Expected weight reminders to be disabled")
+	}
+
+	run("/weightreminder on")
+	run("/weightreminder snooze")
+	state, _ = s.GetWeightReminderState(123)
+	if !state.Enabled {
+		t.Error("Expected weight reminders to be enabled")
+	}
+	if state.SnoozedUntil == nil || time.Until(*state.SnoozedUntil) < 110*time.Minute {
+		t.Errorf("Expected a 2 hour snooze, got %v", state.SnoozedUntil)
+	}
+
+	run("/weightreminder dontbug")
+	state, _ = s.GetWeightReminderState(123)
+	if state.DontRemindUntil == nil || time.Until(*state.DontRemindUntil) < 23*time.Hour {
+		t.Errorf("Expected a 24 hour pause, got %v", state.DontRemindUntil)
+	}
+}
+
+func TestNextBPReminderAt(t *testing.T) {
+	now := time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC)
+
+	// Before the window: today at preferred hour - 1
+	if got, want := nextBPReminderAt(now, nil, nil, nil, nil, 20), time.Date(2026, 3, 10, 19, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// Measured today: tomorrow's window
+	measured := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	if got, want := nextBPReminderAt(now, nil, nil, nil, &measured, 20), time.Date(2026, 3, 11, 19, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// Snoozed into the window
+	snoozed := time.Date(2026, 3, 10, 20, 30, 0, 0, time.UTC)
+	if got := nextBPReminderAt(now, &snoozed, nil, nil, nil, 20); !got.Equal(snoozed) {
+		t.Errorf("Expected %v, got %v", snoozed, got)
+	}
+
+	// Paused past the window: tomorrow
+	paused := time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC)
+	if got, want := nextBPReminderAt(now, nil, &paused, nil, nil, 20), time.Date(2026, 3, 11, 19, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
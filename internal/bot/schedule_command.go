@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// scheduleBumpMinutes is how far one tap on /schedule moves a dose
+const scheduleBumpMinutes = 30
+
+// handleScheduleCommand lists the active medications with their next dose and buttons
+// to move that dose time or skip today
+func (b *Bot) handleScheduleCommand(msgConfig *tgbotapi.MessageConfig) {
+	text, markup := b.scheduleView(time.Now())
+	msgConfig.Text = text
+	if markup != nil {
+		msgConfig.ReplyMarkup = *markup
+	}
+}
+
+// scheduleView renders /schedule. Callback data: "sched_bump:<medID>:<HHMM>:<minutes>"
+// and "sched_skip:<medID>".
+func (b *Bot) scheduleView(now time.Time) (string, *tgbotapi.InlineKeyboardMarkup) {
+	meds, err := b.store.ListMedications(false)
+	if err != nil {
+		log.Printf("Error listing medications: %v", err)
+		return "Error fetching medications.", nil
+	}
+
+	var sb strings.Builder
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, m := range meds {
+		next, ok := b.store.NextOccurrence(b.allowedUserID, &m, now)
+		if !ok {
+			continue
+		}
+
+		when := next.Format("15:04")
+		if y, mo, d := next.Date(); y != now.Year() || mo != now.Month() || d != now.Day() {
+			when = next.Format("Mon 15:04")
+		}
+		sb.WriteString(fmt.Sprintf("• %s (%s) — next %s", m.Name, m.Dosage, when))
+		if skipped, err := b.store.IsMedicationSkipped(m.ID, now); err == nil && skipped {
+			sb.WriteString(" (skipped today)")
+		}
+		sb.WriteString("\n")
+
+		// Slot times are shared with other medications, so only the schedule's own
+		// times can be moved from here
+		if !ownsScheduleTime(&m, next.Format("15:04")) {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("⏭ Skip %s today", m.Name), fmt.Sprintf("sched_skip:%d", m.ID)),
+			))
+			continue
+		}
+		hhmm := next.Format("1504")
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("➖%dm %s", scheduleBumpMinutes, m.Name), fmt.Sprintf("sched_bump:%d:%s:%d", m.ID, hhmm, -scheduleBumpMinutes)),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("➕%dm", scheduleBumpMinutes), fmt.Sprintf("sched_bump:%d:%s:%d", m.ID, hhmm, scheduleBumpMinutes)),
+			tgbotapi.NewInlineKeyboardButtonData("⏭ Skip today", fmt.Sprintf("sched_skip:%d", m.ID)),
+		))
+	}
+
+	if len(rows) == 0 {
+		return "No scheduled medications.", nil
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return "🗓 Next doses\n\n" + sb.String() + "\nMove a dose time or skip today's doses:", &markup
+}
+
+// ownsScheduleTime reports whether hhmm (HH:MM) is listed in the medication's own schedule
+// times rather than coming from a slot
+func ownsScheduleTime(m *store.Medication, hhmm string) bool {
+	cfg, err := m.ValidSchedule()
+	if err != nil {
+		return false
+	}
+	for _, t := range cfg.Times {
+		if t == hhmm {
+			return true
+		}
+	}
+	return false
+}
+
+// handleScheduleCallback applies a /schedule button and refreshes the list
+func (b *Bot) handleScheduleCallback(cb *tgbotapi.CallbackQuery, data string) {
+	var reply string
+	switch {
+	case strings.HasPrefix(data, "sched_bump:"):
+		parts := strings.Split(strings.TrimPrefix(data, "sched_bump:"), ":")
+		if len(parts) != 3 || len(parts[1]) != 4 {
+			return
+		}
+		medID, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return
+		}
+		minutes, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return
+		}
+		from := parts[1][:2] + ":" + parts[1][2:]
+
+		med, err := b.store.GetMedication(medID)
+		if err != nil || med == nil {
+			b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "⚠️ Medication not found."))
+			return
+		}
+		to, err := b.store.ShiftScheduleTime(medID, from, time.Duration(minutes)*time.Minute)
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, fmt.Sprintf("⚠️ Couldn't move %s: %v.", med.Name, err)))
+			return
+		}
+		reply = fmt.Sprintf("⏰ %s moved from %s to %s.", med.Name, from, to)
+
+	case strings.HasPrefix(data, "sched_skip:"):
+		medID, err := strconv.ParseInt(strings.TrimPrefix(data, "sched_skip:"), 10, 64)
+		if err != nil {
+			return
+		}
+		med, err := b.store.GetMedication(medID)
+		if err != nil || med == nil {
+			b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "⚠️ Medication not found."))
+			return
+		}
+		if err := b.store.SkipMedicationOn(medID, time.Now()); err != nil {
+			log.Printf("Error skipping medication %d: %v", medID, err)
+			b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "❌ Error skipping medication."))
+			return
+		}
+		reply = fmt.Sprintf("⏭ No %s reminders today.", med.Name)
+
+	default:
+		return
+	}
+
+	text, markup := b.scheduleView(time.Now())
+	if markup != nil {
+		b.api.Send(tgbotapi.NewEditMessageTextAndMarkup(cb.Message.Chat.ID, cb.Message.MessageID, text, *markup))
+	} else {
+		b.api.Send(tgbotapi.NewEditMessageText(cb.Message.Chat.ID, cb.Message.MessageID, text))
+	}
+	b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, reply))
+}
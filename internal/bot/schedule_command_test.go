@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestScheduleCallbacks(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	var texts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if strings.HasSuffix(r.URL.Path, "/sendMessage") {
+			texts = append(texts, r.FormValue("text"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	medID, _ := s.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	id := strconv.FormatInt(medID, 10)
+	tap := func(data string) {
+		b.handleCallback(&tgbotapi.CallbackQuery{
+			ID:      "1",
+			Data:    data,
+			Message: &tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: 123}},
+		})
+	}
+	times := func() []string {
+		t.Helper()
+		med, _ := s.GetMedication(medID)
+		cfg, err := med.ValidSchedule()
+		if err != nil {
+			t.Fatalf("Stored schedule is invalid: %v", err)
+		}
+		return cfg.Times
+	}
+
+	tap("sched_bump:" + id + ":0800:30")
+	if got := strings.Join(times(), ","); got != "08:30,20:00" {
+		t.Errorf("Expected 08:00 moved to 08:30, got %s", got)
+	}
+	if last := texts[len(texts)-1]; last != "⏰ Aspirin moved from 08:00 to 08:30." {
+		t.Errorf("Unexpected reply %q", last)
+	}
+
+	tap("sched_bump:" + id + ":2000:-30")
+	if got := strings.Join(times(), ","); got != "08:30,19:30" {
+		t.Errorf("Expected 20:00 moved to 19:30, got %s", got)
+	}
+
+	// A time that isn't in the schedule is left alone
+	tap("sched_bump:" + id + ":0800:30")
+	if got := strings.Join(times(), ","); got != "08:30,19:30" {
+		t.Errorf("Expected the schedule unchanged, got %s", got)
+	}
+	if last := texts[len(texts)-1]; !strings.HasPrefix(last, "⚠️ Couldn't move Aspirin") {
+		t.Errorf("Unexpected reply %q", last)
+	}
+
+	tap("sched_skip:" + id)
+	if skipped, _ := s.IsMedicationSkipped(medID, time.Now()); !skipped {
+		t.Error("Expected today to be skipped")
+	}
+	if text, _ := b.scheduleView(time.Now()); !strings.Contains(text, "Aspirin (100mg)") || !strings.Contains(text, "(skipped today)") {
+		t.Errorf("Expected the list to show the skip, got %q", text)
+	}
+}
+
+func TestScheduleView_SlotTimesNotBumpable(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	b := &Bot{store: s, allowedUserID: 123}
+	slotID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","slots":["morning"]}`, nil, nil, "", "")
+	ownID, _ := s.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+
+	now := time.Date(2026, 3, 10, 1, 0, 0, 0, time.Local)
+	text, markup := b.scheduleView(now)
+	if !strings.Contains(text, "Metformin (500mg)") {
+		t.Fatalf("Expected the slot medication to be listed, got %q", text)
+	}
+
+	var data []string
+	for _, row := range markup.InlineKeyboard {
+		for _, btn := range row {
+			data = append(data, *btn.CallbackData)
+		}
+	}
+	all := strings.Join(data, " ")
+	if strings.Contains(all, "sched_bump:"+strconv.FormatInt(slotID, 10)+":") {
+		t.Errorf("Expected no bump buttons for a slot time, got %v", data)
+	}
+	if !strings.Contains(all, "sched_skip:"+strconv.FormatInt(slotID, 10)) {
+		t.Errorf("Expected the slot medication to keep its skip button, got %v", data)
+	}
+	if !strings.Contains(all, "sched_bump:"+strconv.FormatInt(ownID, 10)+":0800:30") {
+		t.Errorf("Expected bump buttons for the medication's own time, got %v", data)
+	}
+}
@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// handleTempCommand logs a temperature with optional symptoms: /temp 38.5 [symptoms]
+func (b *Bot) handleTempCommand(msg *tgbotapi.Message, msgConfig *tgbotapi.MessageConfig) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 {
+		msgConfig.Text = `**Temperature Logging**
+
+Usage: /temp <celsius> [symptoms]
+
+Examples:
+  /temp 38.5 - Log 38.5 °C
+  /temp 37.9 sore throat, headache - Log with symptoms`
+		msgConfig.ParseMode = "Markdown"
+		return
+	}
+
+	temp, err := strconv.ParseFloat(strings.Replace(args[0], ",", ".", 1), 64)
+	if err != nil {
+		msgConfig.Text = "❌ Invalid format. Use: /temp <celsius> [symptoms]"
+		return
+	}
+
+	l := &store.SymptomLog{
+		UserID:      b.allowedUserID,
+		MeasuredAt:  time.Now(),
+		Temperature: &temp,
+		Symptoms:    strings.Join(args[1:], " "),
+	}
+	if err := l.Validate(); err != nil {
+		msgConfig.Text = fmt.Sprintf("❌ Invalid temperature (%.0f-%.0f °C)", store.MinTemperatureC, store.MaxTemperatureC)
+		return
+	}
+
+	if _, err := b.store.CreateSymptomLog(context.Background(), l); err != nil {
+		log.Printf("Error creating symptom log: %v", err)
+		msgConfig.Text = "❌ Error saving temperature."
+		return
+	}
+
+	text := fmt.Sprintf("✅ Temperature recorded: %.1f °C", temp)
+	if temp >= store.FeverThresholdC {
+		text = fmt.Sprintf("🤒 Temperature recorded: %.1f °C (fever)", temp)
+	}
+	if l.Symptoms != "" {
+		text += "\nSymptoms: " + l.Symptoms
+	}
+	msgConfig.Text = text
+}
@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// takenReplyWindow is how long after a medication reminder a typed "taken" still confirms it
+const takenReplyWindow = 2 * time.Hour
+
+// affirmativeReplies are the texts (lowercased, without trailing punctuation) that confirm a
+// reminder. Only words about the dose itself: a generic "ok" or "yes" is usually meant for
+// something else.
+var affirmativeReplies = map[string]bool{
+	"taken":   true,
+	"done":    true,
+	"took it": true,
+	"✅":       true,
+}
+
+// lastReminder is the intake context of the most recent medication reminder
+type lastReminder struct {
+	messageID int
+	medIDs    []int64
+	target    time.Time // Scheduled time of the reminded doses
+	sentAt    time.Time
+}
+
+// lastReminderState remembers the most recent medication reminder per chat
+type lastReminderState struct {
+	mu        sync.Mutex
+	reminders map[int64]lastReminder
+}
+
+func (s *lastReminderState) set(chatID int64, r lastReminder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reminders == nil {
+		s.reminders = make(map[int64]lastReminder)
+	}
+	s.reminders[chatID] = r
+}
+
+// get returns the chat's last reminder if it was sent within the reply window
+func (s *lastReminderState) get(chatID int64, now time.Time) (lastReminder, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.reminders[chatID]
+	if !ok || now.Sub(r.sentAt) > takenReplyWindow {
+		return lastReminder{}, false
+	}
+	return r, true
+}
+
+func (s *lastReminderState) clear(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.reminders, chatID)
+}
+
+// isAffirmativeReply reports whether text means "I took it"
+func isAffirmativeReply(text string) bool {
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = strings.TrimRight(text, ".! ")
+	return affirmativeReplies[text]
+}
+
+// handleTakenReply confirms the doses of the most recent medication reminder when the
+// user types "taken", "done" etc. instead of pressing a button. Replies to other
+// messages are left alone (they add notes), as are messages during /addmed.
+// Returns false if the message wasn't handled.
+func (b *Bot) handleTakenReply(msg *tgbotapi.Message) bool {
+	if !isAffirmativeReply(msg.Text) || b.addMed.get(msg.Chat.ID, time.Now()) != nil {
+		return false
+	}
+
+	reminder, ok := b.lastReminder.get(msg.Chat.ID, time.Now())
+	if msg.ReplyToMessage != nil && (!ok || msg.ReplyToMessage.MessageID != reminder.messageID) {
+		return false
+	}
+	if !ok {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "⚠️ No recent reminder to confirm. Use /log to record a dose."))
+		return true
+	}
+	b.lastReminder.clear(msg.Chat.ID)
+
+	now := time.Now()
+	var confirmed []int64
+	for _, medID := range reminder.medIDs {
+		pending, err := b.store.GetNearestPendingIntake(medID, reminder.target, time.Minute)
+		if err != nil {
+			log.Printf("Error getting pending intake for med %d: %v", medID, err)
+			continue
+		}
+		if pending == nil {
+			continue // Already confirmed or skipped
+		}
+
+		// Clean up other reminders for this dose
+		reminders, _ := b.store.GetIntakeReminders(pending.ID)
+		for _, msgID := range reminders {
+			if msgID != reminder.messageID {
+				b.api.Send(tgbotapi.NewDeleteMessage(msg.Chat.ID, msgID))
+			}
+		}
+
+		if err := b.store.ConfirmIntake(pending.ID, now); err != nil {
+			log.Printf("Error confirming intake %d: %v", pending.ID, err)
+			continue
+		}
+		b.webhooks.NotifyIntakeConfirmed(b.allowedUserID, pending.ID)
+
+		// Take one dose from inventory (only affects medications with tracking enabled)
+		if err := b.store.DecrementInventoryByDose(medID); err != nil {
+			log.Printf("Error decrementing inventory: %v", err)
+		}
+		confirmed = append(confirmed, pending.ID)
+	}
+
+	if len(confirmed) == 0 {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "⚠️ No pending intake found (or already taken)."))
+		return true
+	}
+
+	// Remove the buttons from the reminder
+	edit := tgbotapi.NewEditMessageReplyMarkup(msg.Chat.ID, reminder.messageID, tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+	})
+	b.api.Send(edit)
+
+	text := "✅ Marked as taken."
+	if len(confirmed) > 1 {
+		text = fmt.Sprintf("✅ Marked %d medications as taken.", len(confirmed))
+	}
+	b.sendTakenConfirmation(msg.Chat.ID, text, confirmed)
+	return true
+}
@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestTakenReply_ConfirmsLastReminder(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 42, "chat": {"id": 123}}}`))
+	}))
+	defer tg.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	medA, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	medB, _ := s.CreateMedication("Lisinopril", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	medC, _ := s.CreateMedication("Vitamin D", "1000IU", `{"type":"daily","times":["12:00"]}`, nil, nil, "", "")
+
+	target := time.Now().Add(-10 * time.Minute).Truncate(time.Minute)
+	aDue, _ := s.CreateIntake(medA, 123, target)
+	bDue, _ := s.CreateIntake(medB, 123, target)
+	aEarlier, _ := s.CreateIntake(medA, 123, target.Add(-12*time.Hour))
+	cOther, _ := s.CreateIntake(medC, 123, target.Add(-time.Hour))
+
+	meds := []store.Medication{{ID: medA, Name: "Metformin"}, {ID: medB, Name: "Lisinopril"}}
+	if _, err := b.SendGroupNotification(meds, target); err != nil {
+		t.Fatalf("SendGroupNotification failed: %v", err)
+	}
+
+	b.handleMessage(&tgbotapi.Message{
+		Text: "Taken!",
+		Chat: &tgbotapi.Chat{ID: 123},
+		From: &tgbotapi.User{ID: 123},
+	})
+
+	want := map[int64]string{aDue: "TAKEN", bDue: "TAKEN", aEarlier: "PENDING", cOther: "PENDING"}
+	for id, status := range want {
+		if in, _ := s.GetIntake(id); in.Status != status {
+			t.Errorf("Intake %d: expected %s, got %s", id, status, in.Status)
+		}
+	}
+
+	// The reminder is used up: another "done" doesn't confirm anything else
+	bLater, _ := s.CreateIntake(medB, 123, target.Add(time.Minute))
+	b.handleMessage(&tgbotapi.Message{Text: "done", Chat: &tgbotapi.Chat{ID: 123}, From: &tgbotapi.User{ID: 123}})
+	if in, _ := s.GetIntake(bLater); in.Status != "PENDING" {
+		t.Errorf("Expected a second reply not to confirm anything, got %s", in.Status)
+	}
+}
+
+func TestTakenReply_ExpiredReminder(t *testing.T) {
+	s, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 42, "chat": {"id": 123}}}`))
+	}))
+	defer tg.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	b := &Bot{api: api, store: s, allowedUserID: 123}
+
+	medID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	target := time.Now().Add(-3 * time.Hour).Truncate(time.Minute)
+	id, _ := s.CreateIntake(medID, 123, target)
+	b.lastReminder.set(123, lastReminder{messageID: 42, medIDs: []int64{medID}, target: target, sentAt: time.Now().Add(-takenReplyWindow - time.Minute)})
+
+	b.handleMessage(&tgbotapi.Message{Text: "taken", Chat: &tgbotapi.Chat{ID: 123}, From: &tgbotapi.User{ID: 123}})
+
+	if in, _ := s.GetIntake(id); in.Status != "PENDING" {
+		t.Errorf("Expected a stale reminder not to be confirmed, got %s", in.Status)
+	}
+}
+
+func TestIsAffirmativeReply(t *testing.T) {
+	for text, want := range map[string]bool{
+		"taken":    true,
+		"Done!":    true,
+		"took it.": true,
+		"✅":        true,
+		"ok":       false,
+		"yes":      false,
+		"👍":        false,
+		"not yet":  false,
+	} {
+		if got := isAffirmativeReply(text); got != want {
+			t.Errorf("isAffirmativeReply(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
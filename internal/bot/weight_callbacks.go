@@ -28,7 +28,7 @@ func (b *Bot) SendWeightReminderNotification(userID int64) (int, error) {
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = keyboard
 
-	sent, err := b.api.Send(msg)
+	sent, err := b.sendLogged(msg)
 	if err != nil {
 		return 0, err
 	}
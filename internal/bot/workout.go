@@ -27,7 +27,7 @@ func (b *Bot) SendWorkoutNotification(text string, sessionID int64) (int, error)
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = keyboard
 
-	sentMsg, err := b.api.Send(msg)
+	sentMsg, err := b.sendLogged(msg)
 	if err != nil {
 		return 0, fmt.Errorf("failed to send workout notification: %w", err)
 	}
@@ -36,16 +36,17 @@ func (b *Bot) SendWorkoutNotification(text string, sessionID int64) (int, error)
 	return sentMsg.MessageID, nil
 }
 
-// SendExercisePrompt sends a prompt for a specific exercise during workout
-func (b *Bot) SendExercisePrompt(sessionID int64, exerciseID int64, exerciseName string, sets, repsMin int, repsMax *int, weightKg *float64) (int, error) {
+// SendExercisePrompt sends a prompt for a specific exercise during workout.
+// weight is the formatted target weight, "" for none.
+func (b *Bot) SendExercisePrompt(sessionID int64, exerciseID int64, exerciseName string, sets, repsMin int, repsMax *int, weight string) (int, error) {
 	repsStr := fmt.Sprintf("%d", repsMin)
 	if repsMax != nil && *repsMax != repsMin {
 		repsStr = fmt.Sprintf("%d-%d", repsMin, *repsMax)
 	}
 
 	text := fmt.Sprintf("**%s**\n%d sets × %s reps", exerciseName, sets, repsStr)
-	if weightKg != nil {
-		text += fmt.Sprintf(" @ %.0fkg", *weightKg)
+	if weight != "" {
+		text += " @ " + weight
 	}
 
 	// Create inline keyboard for exercise actions
@@ -75,8 +75,8 @@ func (b *Bot) sendExerciseListPage(sessionID int64, chatID int64, page int) (int
 		label += fmt.Sprintf(" (%d×%s", ex.TargetSets, repsStr)
 
 		// Add weight if present
-		if ex.TargetWeightKg != nil {
-			label += fmt.Sprintf(" @ %.0fkg", *ex.TargetWeightKg)
+		if weight := ex.TargetWeightLabel(); weight != "" {
+			label += " @ " + weight
 		}
 		label += ")"
 
@@ -252,7 +252,7 @@ func (b *Bot) handleSelectExerciseCallback(cb *tgbotapi.CallbackQuery, sessionID
 
 	// Send exercise prompt for the selected exercise
 	_, err = b.SendExercisePrompt(sessionID, exerciseID, exercise.ExerciseName,
-		exercise.TargetSets, exercise.TargetRepsMin, exercise.TargetRepsMax, exercise.TargetWeightKg)
+		exercise.TargetSets, exercise.TargetRepsMin, exercise.TargetRepsMax, exercise.TargetWeightLabel())
 	if err != nil {
 		log.Printf("Failed to send exercise prompt: %v", err)
 		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, "❌ Error adding exercise."))
@@ -143,7 +143,7 @@ func (b *Bot) startExerciseLoop(sessionID, variantID int64, chatID int64) {
 
 	for i, ex := range exercises {
 		_, err := b.SendExercisePrompt(sessionID, ex.ID, fmt.Sprintf("%d. %s", i+1, ex.ExerciseName),
-			ex.TargetSets, ex.TargetRepsMin, ex.TargetRepsMax, ex.TargetWeightKg)
+			ex.TargetSets, ex.TargetRepsMin, ex.TargetRepsMax, ex.TargetWeightLabel())
 		if err != nil {
 			log.Printf("Failed to send exercise prompt: %v", err)
 		}
@@ -46,7 +46,7 @@ func (s *Server) handleGetBloodPressure(ctx context.Context, req *mcp.CallToolRe
 	// Get the user ID from config
 	userID := s.config.UserID
 
-	readings, err := s.store.GetBloodPressureReadings(ctx, userID, startDate)
+	readings, err := s.store.GetBloodPressureReadings(ctx, userID, startDate, 0, 0)
 	if err != nil {
 		log.Printf("[MCP] Failed to fetch BP readings: %v", err)
 		return nil, BloodPressureResponse{}, err
@@ -9,22 +9,64 @@ import (
 	"time"
 )
 
+const (
+	defaultRESTURL        = "https://rxnav.nlm.nih.gov/REST"
+	defaultInteractionURL = "https://lhncbc.nlm.nih.gov/RxNav/APIs/api/interaction/list.json"
+)
+
 type Client struct {
-	httpClient *http.Client
+	httpClient     *http.Client
+	restURL        string
+	interactionURL string
 }
 
 func New() *Client {
 	return &Client{
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		restURL:        defaultRESTURL,
+		interactionURL: defaultInteractionURL,
 	}
 }
 
+// SetRESTEndpoint overrides the RxNav REST base URL used for name lookups (used by tests).
+func (c *Client) SetRESTEndpoint(endpoint string) {
+	c.restURL = strings.TrimSuffix(endpoint, "/")
+}
+
+// SetInteractionEndpoint overrides the interaction list URL (used by tests).
+func (c *Client) SetInteractionEndpoint(endpoint string) {
+	c.interactionURL = endpoint
+}
+
+// Interaction describes a single drug-drug interaction between two RxCUIs.
+type Interaction struct {
+	Drug1       string `json:"drug1"`
+	Rxcui1      string `json:"rxcui1"`
+	Drug2       string `json:"drug2"`
+	Rxcui2      string `json:"rxcui2"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// severeLevels are the severities worth a proactive alert rather than just a warning
+var severeLevels = map[string]bool{
+	"high":            true,
+	"severe":          true,
+	"major":           true,
+	"contraindicated": true,
+}
+
+// IsSevere reports whether the interaction is serious enough to alert about
+func (in Interaction) IsSevere() bool {
+	return severeLevels[strings.ToLower(in.Severity)]
+}
+
 // SearchRxNorm searches for a medication by name and returns the RxCUI and normalized name.
 // It returns empty strings if not found or if the API fails, behaving gracefully.
 func (c *Client) SearchRxNorm(name string) (string, string, error) {
 	// 1. Get RxCUI (Exact Match)
 	// URL: https://rxnav.nlm.nih.gov/REST/rxcui.json?name=...
-	searchURL := fmt.Sprintf("https://rxnav.nlm.nih.gov/REST/rxcui.json?name=%s", url.QueryEscape(name))
+	searchURL := fmt.Sprintf("%s/rxcui.json?name=%s", c.restURL, url.QueryEscape(name))
 	resp, err := c.httpClient.Get(searchURL)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to search rxnorm: %w", err)
@@ -53,7 +95,7 @@ func (c *Client) SearchRxNorm(name string) (string, string, error) {
 
 	// 2. Get Properties (Normalized Name)
 	// URL: https://rxnav.nlm.nih.gov/REST/rxcui/{rxcui}/properties.json
-	propURL := fmt.Sprintf("https://rxnav.nlm.nih.gov/REST/rxcui/%s/properties.json", rxcui)
+	propURL := fmt.Sprintf("%s/rxcui/%s/properties.json", c.restURL, rxcui)
 	respProp, err := c.httpClient.Get(propURL)
 	if err != nil {
 		// If we got ID but failed to get name, just return ID
@@ -75,7 +117,7 @@ func (c *Client) SearchRxNorm(name string) (string, string, error) {
 
 func (c *Client) searchApproximate(term string) string {
 	// URL: https://rxnav.nlm.nih.gov/REST/approximateTerm.json?term=...&maxEntries=1
-	searchURL := fmt.Sprintf("https://rxnav.nlm.nih.gov/REST/approximateTerm.json?term=%s&maxEntries=1", url.QueryEscape(term))
+	searchURL := fmt.Sprintf("%s/approximateTerm.json?term=%s&maxEntries=1", c.restURL, url.QueryEscape(term))
 	resp, err := c.httpClient.Get(searchURL)
 	if err != nil {
 		return ""
@@ -104,13 +146,27 @@ func (c *Client) searchApproximate(term string) string {
 // CheckInteractions checks for drug-drug interactions between a list of RxCUIs.
 // Returns a list of warning messages.
 func (c *Client) CheckInteractions(rxcuis []string) ([]string, error) {
+	interactions, err := c.GetInteractions(rxcuis)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for _, in := range interactions {
+		warnings = append(warnings, fmt.Sprintf("Interaction between %s and %s: %s", in.Drug1, in.Drug2, in.Description))
+	}
+	return warnings, nil
+}
+
+// GetInteractions returns structured drug-drug interactions between a list of RxCUIs.
+func (c *Client) GetInteractions(rxcuis []string) ([]Interaction, error) {
 	if len(rxcuis) < 2 {
 		return nil, nil
 	}
 
 	// URL: https://lhncbc.nlm.nih.gov/RxNav/APIs/api/interaction/list.json?rxcuis=...
 	ids := strings.Join(rxcuis, "+")
-	checkURL := fmt.Sprintf("https://lhncbc.nlm.nih.gov/RxNav/APIs/api/interaction/list.json?rxcuis=%s", ids)
+	checkURL := fmt.Sprintf("%s?rxcuis=%s", c.interactionURL, ids)
 
 	resp, err := c.httpClient.Get(checkURL)
 	if err != nil {
@@ -132,6 +188,7 @@ func (c *Client) CheckInteractions(rxcuis []string) ([]string, error) {
 							Rxcui string `json:"rxcui"`
 						} `json:"minConceptItem"`
 					} `json:"interactionConcept"`
+					Severity    string `json:"severity"`
 					Description string `json:"description"`
 				} `json:"interactionPair"`
 			} `json:"fullInteractionType"`
@@ -142,29 +199,40 @@ func (c *Client) CheckInteractions(rxcuis []string) ([]string, error) {
 		return nil, fmt.Errorf("failed to decode interaction response: %w", err)
 	}
 
-	var warnings []string
+	var interactions []Interaction
 	seen := make(map[string]bool)
 
 	for _, group := range interactionResp.FullInteractionTypeGroup {
 		for _, fit := range group.FullInteractionType {
 			for _, pair := range fit.InteractionPair {
 				if len(pair.InteractionConcept) >= 2 {
-					m1 := pair.InteractionConcept[0].MinConceptItem.Name
-					m2 := pair.InteractionConcept[1].MinConceptItem.Name
-					desc := pair.Description
+					c1 := pair.InteractionConcept[0].MinConceptItem
+					c2 := pair.InteractionConcept[1].MinConceptItem
 
 					// De-duplicate because API might return same pair twice
-					key := fmt.Sprintf("%s-%s", m1, m2)
+					key := fmt.Sprintf("%s-%s", c1.Name, c2.Name)
 					if seen[key] {
 						continue
 					}
 					seen[key] = true
 
-					warnings = append(warnings, fmt.Sprintf("Interaction between %s and %s: %s", m1, m2, desc))
+					severity := pair.Severity
+					if severity == "" || severity == "N/A" {
+						severity = "unknown"
+					}
+
+					interactions = append(interactions, Interaction{
+						Drug1:       c1.Name,
+						Rxcui1:      c1.Rxcui,
+						Drug2:       c2.Name,
+						Rxcui2:      c2.Rxcui,
+						Severity:    severity,
+						Description: pair.Description,
+					})
 				}
 			}
 		}
 	}
 
-	return warnings, nil
+	return interactions, nil
 }
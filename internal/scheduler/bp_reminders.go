@@ -124,8 +124,13 @@ func (s *Scheduler) sendBPReminder(ctx context.Context, userID int64, enhanced b
 	telegramSuccess := false
 	webPushSuccess := false
 
+	channels := s.reminderChannels(store.ReminderBP)
+	if !channels.Telegram && !channels.WebPush {
+		return nil // Silenced by the user
+	}
+
 	// Send Telegram notification
-	if s.bot != nil {
+	if s.bot != nil && channels.Telegram {
 		msgID, err := s.bot.SendBPReminderNotification(userID, enhanced)
 		if err != nil {
 			log.Printf("Failed to send Telegram BP reminder: %v", err)
@@ -136,7 +141,7 @@ func (s *Scheduler) sendBPReminder(ctx context.Context, userID int64, enhanced b
 	}
 
 	// Send Web Push notification
-	if s.webPush != nil {
+	if s.webPush != nil && channels.WebPush {
 		if err := s.webPush.SendBPReminderNotification(ctx, userID, enhanced); err != nil {
 			log.Printf("Failed to send Web Push BP reminder: %v", err)
 		} else {
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	webpushlib "github.com/SherClockHolmes/webpush-go"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/bot"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+	"github.com/korjavin/medicationtrackerbot/internal/webpush"
+)
+
+func TestSendBPReminder_TelegramDisabled(t *testing.T) {
+	db := newTestStore(t)
+
+	var mu sync.Mutex
+	var telegramSends, pushes int
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/sendMessage") {
+			mu.Lock()
+			telegramSends++
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer tg.Close()
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		pushes++
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer endpoint.Close()
+
+	uaKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	if err := db.CreatePushSubscription(123456, endpoint.URL, base64.RawURLEncoding.EncodeToString(auth), base64.RawURLEncoding.EncodeToString(uaKey.PublicKey().Bytes())); err != nil {
+		t.Fatalf("CreatePushSubscription: %v", err)
+	}
+	privateKey, publicKey, err := webpushlib.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate VAPID keys: %v", err)
+	}
+	push := webpush.New(db, publicKey, privateKey, "mailto:test@example.com")
+	sched := New(db, bot.NewWithAPI(api, 123456, db), 123456, push, nil)
+
+	if err := db.SetReminderChannels(map[string]store.ReminderChannels{
+		store.ReminderBP: {Telegram: false, WebPush: true},
+	}); err != nil {
+		t.Fatalf("SetReminderChannels: %v", err)
+	}
+
+	if err := sched.sendBPReminder(context.Background(), 123456, false); err != nil {
+		t.Fatalf("sendBPReminder: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if telegramSends != 0 {
+		t.Errorf("Expected no Telegram message, got %d", telegramSends)
+	}
+	if pushes != 1 {
+		t.Errorf("Expected 1 web push, got %d", pushes)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/korjavin/medicationtrackerbot/internal/bot"
 	"github.com/korjavin/medicationtrackerbot/internal/store"
+	"github.com/korjavin/medicationtrackerbot/internal/webhook"
 	"github.com/korjavin/medicationtrackerbot/internal/webpush"
 )
 
@@ -18,17 +21,33 @@ type Scheduler struct {
 	allowedUserID     int64
 	lastLowStockCheck time.Time
 	webPush           *webpush.Service
+	webhooks          *webhook.Service
+	// missedAfter is how long a dose may stay unconfirmed before it counts as missed (0 never)
+	missedAfter time.Duration
+	// scheduleMu keeps overlapping schedule ticks (e.g. a slow DB) from running concurrently
+	scheduleMu sync.Mutex
 }
 
-func New(store *store.Store, bot *bot.Bot, allowedUserID int64, webPush *webpush.Service) *Scheduler {
+func New(store *store.Store, bot *bot.Bot, allowedUserID int64, webPush *webpush.Service, webhooks *webhook.Service) *Scheduler {
 	return &Scheduler{
 		store:         store,
 		bot:           bot,
 		allowedUserID: allowedUserID,
 		webPush:       webPush,
+		webhooks:      webhooks,
+		missedAfter:   DefaultMissedAfter,
 	}
 }
 
+// DefaultMissedAfter is how long a dose may stay unconfirmed before it is marked missed
+const DefaultMissedAfter = 6 * time.Hour
+
+// SetMissedAfter sets how long a dose may stay unconfirmed before it is marked missed;
+// 0 leaves overdue doses pending
+func (s *Scheduler) SetMissedAfter(d time.Duration) {
+	s.missedAfter = d
+}
+
 func (s *Scheduler) Start() {
 	// Check every minute
 	ticker := time.NewTicker(1 * time.Minute)
@@ -37,6 +56,15 @@ func (s *Scheduler) Start() {
 			if err := s.checkSchedule(); err != nil {
 				log.Printf("Error checking schedule: %v", err)
 			}
+			if _, err := s.checkSnoozedIntakes(); err != nil {
+				log.Printf("Error checking snoozed intakes: %v", err)
+			}
+			if _, err := s.checkAutoConfirm(time.Now()); err != nil {
+				log.Printf("Error auto-confirming overdue intakes: %v", err)
+			}
+			if _, err := s.checkMissedIntakes(); err != nil {
+				log.Printf("Error marking overdue intakes missed: %v", err)
+			}
 		}
 	}()
 
@@ -44,7 +72,7 @@ func (s *Scheduler) Start() {
 	retryTicker := time.NewTicker(60 * time.Minute)
 	go func() {
 		for range retryTicker.C {
-			if err := s.checkReminders(); err != nil {
+			if _, err := s.checkReminders(); err != nil {
 				log.Printf("Error checking reminders: %v", err)
 			}
 		}
@@ -62,6 +90,41 @@ func (s *Scheduler) Start() {
 		}
 	}()
 
+	// Archive medications whose course has ended, every hour
+	expiredTicker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for range expiredTicker.C {
+			if err := s.checkExpiredMedications(); err != nil {
+				log.Printf("Error archiving expired medications: %v", err)
+			}
+		}
+	}()
+
+	// Enforce the data retention policy at startup, then once a day
+	retentionTicker := time.NewTicker(24 * time.Hour)
+	go func() {
+		for {
+			if err := s.enforceRetention(); err != nil {
+				log.Printf("Error enforcing data retention: %v", err)
+			}
+			<-retentionTicker.C
+		}
+	}()
+
+	// Retry web pushes that failed for a transient reason, every minute
+	if s.webPush != nil {
+		pushRetryTicker := time.NewTicker(1 * time.Minute)
+		go func() {
+			for range pushRetryTicker.C {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				if err := s.webPush.RetryQueued(ctx, time.Now()); err != nil {
+					log.Printf("Error retrying web pushes: %v", err)
+				}
+				cancel()
+			}
+		}()
+	}
+
 	// Check workout notifications every minute
 	workoutTicker := time.NewTicker(1 * time.Minute)
 	go func() {
@@ -105,8 +168,120 @@ func (s *Scheduler) Start() {
 	}()
 }
 
+// notificationGroup holds the medications due at the same target time
+type notificationGroup struct {
+	Target    time.Time
+	Meds      []store.Medication
+	IntakeIDs []int64
+}
+
+// TickSummary reports what one on-demand scheduler cycle did
+type TickSummary struct {
+	IntakesCreated     int  `json:"intakes_created"`
+	NotificationGroups int  `json:"notification_groups"`
+	OutOfStock         int  `json:"out_of_stock"`
+	RemindersSent      int  `json:"reminders_sent"`
+	SnoozedReminders   int  `json:"snoozed_reminders"`
+	AutoConfirmed      int  `json:"auto_confirmed"`
+	MarkedMissed       int  `json:"marked_missed"`
+	ScheduleSkipped    bool `json:"schedule_skipped,omitempty"` // A regular tick was still running
+}
+
+// Tick runs one scheduler cycle right away, as the tickers would: create the intakes
+// due by now and notify about them, re-send reminders for overdue doses and for doses
+// whose snooze ran out, assume overdue auto-confirm doses taken and mark the other
+// long-overdue doses missed. Meant for debugging and testing.
+func (s *Scheduler) Tick() (*TickSummary, error) {
+	summary, err := s.runSchedule(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if summary.RemindersSent, err = s.checkReminders(); err != nil {
+		return nil, err
+	}
+	if summary.SnoozedReminders, err = s.checkSnoozedIntakes(); err != nil {
+		return nil, err
+	}
+	if summary.AutoConfirmed, err = s.checkAutoConfirm(time.Now()); err != nil {
+		return nil, err
+	}
+	if summary.MarkedMissed, err = s.checkMissedIntakes(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
 func (s *Scheduler) checkSchedule() error {
-	now := time.Now()
+	_, err := s.runSchedule(time.Now())
+	return err
+}
+
+// runSchedule creates the intakes due by now and sends their notifications
+func (s *Scheduler) runSchedule(now time.Time) (*TickSummary, error) {
+	summary := &TickSummary{}
+
+	// Single-flight: skip this tick if the previous one is still running
+	if !s.scheduleMu.TryLock() {
+		log.Printf("Previous schedule check still running, skipping tick")
+		summary.ScheduleSkipped = true
+		return summary, nil
+	}
+	defer s.scheduleMu.Unlock()
+
+	groups, outOfStock, err := s.createDueIntakes(now)
+	if err != nil {
+		return nil, err
+	}
+	summary.NotificationGroups = len(groups)
+	summary.OutOfStock = len(outOfStock)
+	for _, group := range groups {
+		summary.IntakesCreated += len(group.IntakeIDs)
+	}
+
+	if len(outOfStock) > 0 {
+		s.sendRestockNudge(outOfStock)
+	}
+
+	channels := s.reminderChannels(store.ReminderMedication)
+
+	// Process Groups
+	for _, group := range groups {
+		// Send Telegram Notification
+		if channels.Telegram {
+			go func(meds []store.Medication, target time.Time, iIDs []int64) {
+				msgID, err := s.bot.SendGroupNotification(meds, target)
+				if err != nil {
+					log.Printf("Failed to send group notification: %v", err)
+					return
+				}
+				// The notification is each dose's first reminder
+				for _, id := range iIDs {
+					if err := s.store.AddIntakeReminder(id, msgID); err != nil {
+						log.Printf("Failed to track notification of intake %d: %v", id, err)
+					}
+				}
+			}(group.Meds, group.Target, group.IntakeIDs)
+		}
+
+		// Send Web Push Notification
+		if s.webPush != nil && channels.WebPush {
+			go func(meds []store.Medication, target time.Time, iIDs []int64) {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				if err := s.webPush.SendMedicationNotification(ctx, s.allowedUserID, meds, target, iIDs); err != nil {
+					log.Printf("Failed to send web push notification: %v", err)
+				}
+			}(group.Meds, group.Target, group.IntakeIDs)
+		}
+	}
+
+	return summary, nil
+}
+
+// createDueIntakes creates pending intakes for every dose due by now and returns them
+// grouped by target time. Intakes that already exist are skipped, so repeated calls are idempotent.
+// Doses of medications with zero inventory are marked MISSED instead and returned separately.
+func (s *Scheduler) createDueIntakes(now time.Time) ([]*notificationGroup, []store.Medication, error) {
 	// Truncate to minute to avoid sub-minute drifts if needed, but DB comparison handles equality.
 	// Actually, store stores time.Time. SQLite driver stores it as string usually or timestamp.
 	// For idempotency, we should standardise the "Scheduled At" time we insert.
@@ -114,20 +289,14 @@ func (s *Scheduler) checkSchedule() error {
 
 	meds, err := s.store.ListMedications(false)
 	if err != nil {
-		return err
-	}
-
-	// Group By Target Time
-	type NotificationGroup struct {
-		Target time.Time
-		Meds   []store.Medication
+		return nil, nil, err
 	}
 
 	// Key: Unix timestamp of target time
-	groups := make(map[int64]*NotificationGroup)
+	groups := make(map[int64]*notificationGroup)
 
 	for _, med := range meds {
-		cfg, err := med.ValidSchedule()
+		cfg, err := s.store.ExpandSchedule(s.allowedUserID, &med)
 		if err != nil {
 			log.Printf("Invalid schedule for med %d: %v", med.ID, err)
 			continue
@@ -138,6 +307,11 @@ func (s *Scheduler) checkSchedule() error {
 			continue
 		}
 
+		// Skip if silenced for today
+		if s.isSkipped(med.ID, now) {
+			continue
+		}
+
 		// If "weekly", check current day
 		if cfg.Type == "weekly" {
 			todayIdx := int(now.Weekday()) // 0=Sunday
@@ -190,7 +364,7 @@ func (s *Scheduler) checkSchedule() error {
 				// Add to Group
 				ts := target.Unix()
 				if _, ok := groups[ts]; !ok {
-					groups[ts] = &NotificationGroup{
+					groups[ts] = &notificationGroup{
 						Target: target,
 						Meds:   []store.Medication{},
 					}
@@ -200,53 +374,79 @@ func (s *Scheduler) checkSchedule() error {
 		}
 	}
 
-	// Process Groups
+	// Create Intakes for all meds in each group. Only newly created intakes are
+	// kept, so a concurrent caller that got there first does not cause a duplicate notification.
+	var due []*notificationGroup
+	var outOfStock []store.Medication
 	for _, group := range groups {
-		if len(group.Meds) == 0 {
-			continue
-		}
-
-		// Create Intakes for all meds in group
-		var intakeIDs []int64
+		created := &notificationGroup{Target: group.Target}
 		for _, med := range group.Meds {
-			log.Printf("Triggering medication %s (%s) scheduled for %s", med.Name, med.Dosage, med.Schedule)
-			id, err := s.store.CreateIntake(med.ID, s.allowedUserID, group.Target)
+			id, isNew, err := s.store.CreateIntakeIfAbsent(med.ID, s.allowedUserID, group.Target)
 			if err != nil {
 				log.Printf("Failed to create intake log: %v", err)
-			} else {
-				intakeIDs = append(intakeIDs, id)
+				continue
 			}
-		}
-
-		// Send Telegram Notification
-		go func(meds []store.Medication, target time.Time) {
-			if err := s.bot.SendGroupNotification(meds, target); err != nil {
-				log.Printf("Failed to send group notification: %v", err)
+			if !isNew {
+				continue
 			}
-		}(group.Meds, group.Target)
-
-		// Send Web Push Notification
-		if s.webPush != nil {
-			go func(meds []store.Medication, target time.Time, iIDs []int64) {
-				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-				defer cancel()
-				if err := s.webPush.SendMedicationNotification(ctx, s.allowedUserID, meds, target, iIDs); err != nil {
-					log.Printf("Failed to send web push notification: %v", err)
+			// A dose that can't be taken is not worth a confirm prompt
+			if med.InventoryCount != nil && *med.InventoryCount <= 0 {
+				if err := s.store.MarkIntakeMissed(id, store.OutOfStockReason); err != nil {
+					log.Printf("Failed to mark out-of-stock intake %d missed: %v", id, err)
 				}
-			}(group.Meds, group.Target, intakeIDs)
+				log.Printf("Medication %s is out of stock, marked dose at %s as missed", med.Name, group.Target.Format("15:04"))
+				outOfStock = append(outOfStock, med)
+				continue
+			}
+			log.Printf("Triggering medication %s (%s) scheduled for %s", med.Name, med.Dosage, med.Schedule)
+			created.Meds = append(created.Meds, med)
+			created.IntakeIDs = append(created.IntakeIDs, id)
+		}
+		if len(created.Meds) > 0 {
+			due = append(due, created)
 		}
 	}
 
-	return nil
+	return due, outOfStock, nil
+}
+
+// sendRestockNudge tells the user which doses were skipped because the medication ran out
+func (s *Scheduler) sendRestockNudge(meds []store.Medication) {
+	var sb strings.Builder
+	sb.WriteString("📦 **Out of Stock**\n\nThese doses were marked as missed because you have none left:\n\n")
+	for _, m := range meds {
+		sb.WriteString(fmt.Sprintf("• **%s** (%s)\n", m.Name, m.Dosage))
+	}
+	sb.WriteString("\nRestock in the app to resume reminders.")
+
+	if err := s.bot.SendLowStockWarning(sb.String()); err != nil {
+		log.Printf("Failed to send restock nudge: %v", err)
+	}
 }
 
-func (s *Scheduler) checkReminders() error {
+// minReminderSpacing is the least time between two reminders of the same dose. A bit under
+// the hourly reminder loop, so the loop's own timing never makes it skip a round.
+const minReminderSpacing = 50 * time.Minute
+
+// checkReminders re-sends the reminder for doses pending over an hour and returns how
+// many went out. Doses reminded less than minReminderSpacing ago are left alone.
+func (s *Scheduler) checkReminders() (int, error) {
+	// Repeat reminders only go out via Telegram
+	if !s.reminderChannels(store.ReminderMedication).Telegram {
+		return 0, nil
+	}
+
 	pending, err := s.store.GetPendingIntakes()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
+	sent := 0
+
 	for _, p := range pending {
+		if p.SnoozedUntil != nil {
+			continue // checkSnoozedIntakes reminds once the snooze runs out
+		}
 		scheduledAt := p.ScheduledAt
 		if time.Since(scheduledAt) > 1*time.Hour {
 			// Send reminder
@@ -257,18 +457,174 @@ func (s *Scheduler) checkReminders() error {
 			if med == nil { // deleted?
 				continue
 			}
+			if s.isSkipped(med.ID, scheduledAt.Local()) {
+				continue
+			}
+			// Tick can run this at any time, so space the reminders by when they went out
+			last, err := s.store.GetLastIntakeReminderAt(p.ID)
+			if err != nil {
+				log.Printf("Error getting last reminder of intake %d: %v", p.ID, err)
+				continue
+			}
+			if last != nil && time.Since(*last) < minReminderSpacing {
+				continue
+			}
 
-			text := fmt.Sprintf("🔔 REMINDER: You haven't confirmed taking %s (%s) yet on %s!",
-				med.Name, med.Dosage, scheduledAt.Format("15:04"))
-
-			msgID, err := s.bot.SendNotification(text, med.ID)
+			msgID, err := s.bot.SendReminder(*med, scheduledAt)
 			if err != nil {
 				log.Printf("Failed to send reminder: %v", err)
 			} else {
 				s.store.AddIntakeReminder(p.ID, msgID)
+				sent++
 			}
 		}
 	}
+	return sent, nil
+}
+
+// checkAutoConfirm assumes overdue doses of auto-confirm medications taken and removes
+// their reminder messages, which no longer need an answer
+func (s *Scheduler) checkAutoConfirm(now time.Time) (int, error) {
+	confirmed, err := s.store.AutoConfirmOverdueIntakes(now)
+	for _, intake := range confirmed {
+		log.Printf("Auto-confirmed intake %d of medication %d scheduled at %s", intake.ID, intake.MedicationID, intake.ScheduledAt.Format(time.RFC3339))
+		msgIDs, err := s.store.GetIntakeReminders(intake.ID)
+		if err != nil {
+			log.Printf("Error getting reminders of intake %d: %v", intake.ID, err)
+			continue
+		}
+		for _, msgID := range msgIDs {
+			s.bot.DeleteMessage(msgID)
+		}
+	}
+	return len(confirmed), err
+}
+
+// checkMissedIntakes marks doses left unconfirmed past the grace period as missed and
+// removes their reminder messages, which can no longer be answered
+func (s *Scheduler) checkMissedIntakes() (int, error) {
+	if s.missedAfter <= 0 {
+		return 0, nil
+	}
+	missed, err := s.store.MarkOverdueIntakesMissed(s.missedAfter)
+	for _, intake := range missed {
+		log.Printf("Marked intake %d of medication %d scheduled at %s missed", intake.ID, intake.MedicationID, intake.ScheduledAt.Format(time.RFC3339))
+		msgIDs, err := s.store.GetIntakeReminders(intake.ID)
+		if err != nil {
+			log.Printf("Error getting reminders of intake %d: %v", intake.ID, err)
+			continue
+		}
+		for _, msgID := range msgIDs {
+			s.bot.DeleteMessage(msgID)
+		}
+	}
+	return len(missed), err
+}
+
+// isSkipped reports whether the medication's reminders are silenced on t's day
+func (s *Scheduler) isSkipped(medID int64, t time.Time) bool {
+	skipped, err := s.store.IsMedicationSkipped(medID, t)
+	if err != nil {
+		log.Printf("Error checking skip date for med %d: %v", medID, err)
+		return false
+	}
+	return skipped
+}
+
+// reminderChannels returns where a type of reminder goes, falling back to every
+// channel if the preference can't be loaded
+func (s *Scheduler) reminderChannels(reminderType string) store.ReminderChannels {
+	channels, err := s.store.ReminderChannelsFor(reminderType)
+	if err != nil {
+		log.Printf("Error loading %s reminder channels: %v", reminderType, err)
+		return store.ReminderChannels{Telegram: true, WebPush: true}
+	}
+	return channels
+}
+
+// checkSnoozedIntakes re-sends the reminder for doses whose snooze has run out and
+// returns how many went out; afterwards they fall back to the hourly reminders
+func (s *Scheduler) checkSnoozedIntakes() (int, error) {
+	due, err := s.store.GetDueSnoozedIntakes(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	telegram := s.reminderChannels(store.ReminderMedication).Telegram
+
+	sent := 0
+
+	for _, p := range due {
+		if err := s.store.ClearIntakeSnooze(p.ID); err != nil {
+			log.Printf("Error clearing snooze for intake %d: %v", p.ID, err)
+			continue
+		}
+		med, err := s.store.GetMedication(p.MedicationID)
+		if err != nil || med == nil || !telegram || s.isSkipped(med.ID, p.ScheduledAt.Local()) {
+			continue
+		}
+
+		msgID, err := s.bot.SendReminder(*med, p.ScheduledAt)
+		if err != nil {
+			log.Printf("Failed to send snoozed reminder: %v", err)
+		} else {
+			s.store.AddIntakeReminder(p.ID, msgID)
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// checkExpiredMedications archives medications past their end date, removes their
+// pending intakes and reminder messages, and tells the user the course is complete
+func (s *Scheduler) checkExpiredMedications() error {
+	archived, err := s.store.ArchiveExpiredMedications()
+	if err != nil {
+		return err
+	}
+
+	for _, med := range archived {
+		log.Printf("Archived medication %s (%d): end date passed", med.Name, med.ID)
+
+		pending, err := s.store.GetPendingIntakesForMedication(med.ID)
+		if err != nil {
+			log.Printf("Error getting pending intakes for cleanup: %v", err)
+		}
+		for _, p := range pending {
+			msgIDs, err := s.store.GetIntakeReminders(p.ID)
+			if err == nil {
+				for _, msgID := range msgIDs {
+					s.bot.DeleteMessage(msgID)
+				}
+			}
+			s.store.DeleteIntake(p.ID)
+		}
+
+		text := fmt.Sprintf("🏁 Course of %s complete. It has been archived and you won't get further reminders.", med.Name)
+		if _, err := s.bot.SendSimpleNotification(text, nil); err != nil {
+			log.Printf("Failed to send course complete notification: %v", err)
+		}
+	}
+	return nil
+}
+
+// enforceRetention purges health records older than the configured retention window
+func (s *Scheduler) enforceRetention() error {
+	days, err := s.store.GetRetentionDays()
+	if err != nil {
+		return err
+	}
+	if days <= 0 {
+		return nil
+	}
+
+	result, err := s.store.PurgeOlderThan(s.allowedUserID, days)
+	if err != nil {
+		return err
+	}
+	if n := result.Intakes + result.BloodPressure + result.Weight + result.Sleep; n > 0 {
+		log.Printf("Retention: purged %d intakes, %d BP readings, %d weight logs, %d sleep logs older than %d days",
+			result.Intakes, result.BloodPressure, result.Weight, result.Sleep, days)
+	}
 	return nil
 }
 
@@ -290,7 +646,7 @@ func (s *Scheduler) checkLowStock() {
 		}
 	}
 
-	meds, err := s.store.GetMedicationsLowOnStock(7)
+	meds, err := s.store.GetMedicationsLowOnStock(s.allowedUserID, 7)
 	if err != nil {
 		log.Printf("Error checking low stock: %v", err)
 		return
@@ -306,12 +662,16 @@ func (s *Scheduler) checkLowStock() {
 	sb = "⚠️ **Low Stock Warning**\n\nThe following medications are running low (< 7 days):\n\n"
 
 	for _, m := range meds {
-		daysRemaining := s.store.GetDaysOfStockRemaining(&m)
+		daysRemaining := s.store.GetDaysOfStockRemaining(s.allowedUserID, &m)
 		daysStr := ""
 		if daysRemaining != nil {
 			daysStr = fmt.Sprintf(" (~%.0f days left)", *daysRemaining)
 		}
-		sb += fmt.Sprintf("• **%s**: %d units%s\n", m.Name, *m.InventoryCount, daysStr)
+		packsStr := ""
+		if packs := m.PacksRemaining(); packs != nil {
+			packsStr = fmt.Sprintf(", %g packs", *packs)
+		}
+		sb += fmt.Sprintf("• **%s**: %g units%s%s\n", m.Name, *m.InventoryCount, packsStr, daysStr)
 	}
 
 	sb += "\nPlease restock soon!"
@@ -327,5 +687,7 @@ func (s *Scheduler) checkLowStock() {
 		}
 	}
 
+	s.webhooks.NotifyLowStock(s.allowedUserID, meds)
+
 	s.lastLowStockCheck = time.Now()
 }
@@ -0,0 +1,618 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/bot"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func newTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	// A file-backed DB so that concurrent goroutines share the same database
+	path := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	s, err := store.New(path)
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func countIntakes(t *testing.T, s *store.Store, medID int64) int {
+	t.Helper()
+	history, err := s.GetIntakeHistory(int(medID), 30)
+	if err != nil {
+		t.Fatalf("GetIntakeHistory: %v", err)
+	}
+	return len(history)
+}
+
+func TestCreateDueIntakes_ConcurrentTicks(t *testing.T) {
+	db := newTestStore(t)
+	sched := New(db, nil, 123456, nil, nil)
+
+	now := time.Now()
+	due := now.Add(-30 * time.Minute)
+	if due.Day() != now.Day() {
+		t.Skip("Too close to midnight for a same-day schedule")
+	}
+	schedule := fmt.Sprintf(`{"type":"daily","times":["%s"]}`, due.Format("15:04"))
+	medID, err := db.CreateMedication("Med A", "10mg", schedule, nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("CreateMedication: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	notified := 0
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			groups, _, err := sched.createDueIntakes(now)
+			if err != nil {
+				t.Errorf("createDueIntakes: %v", err)
+				return
+			}
+			mu.Lock()
+			for _, g := range groups {
+				notified += len(g.Meds)
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if n := countIntakes(t, db, medID); n != 1 {
+		t.Errorf("Expected exactly 1 intake for the schedule time, got %d", n)
+	}
+	if notified != 1 {
+		t.Errorf("Expected exactly 1 notification, got %d", notified)
+	}
+
+	// A later tick must not create anything new
+	groups, _, err := sched.createDueIntakes(now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("createDueIntakes: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("Expected no new groups on repeated tick, got %d", len(groups))
+	}
+}
+
+func TestCheckSchedule_SkipsOverlappingTick(t *testing.T) {
+	db := newTestStore(t)
+	sched := New(db, nil, 123456, nil, nil)
+
+	medID, err := db.CreateMedication("Med A", "10mg", `{"type":"daily","times":["00:00"]}`, nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("CreateMedication: %v", err)
+	}
+
+	// Simulate a tick that is still running
+	sched.scheduleMu.Lock()
+	if err := sched.checkSchedule(); err != nil {
+		t.Fatalf("checkSchedule: %v", err)
+	}
+	sched.scheduleMu.Unlock()
+
+	if n := countIntakes(t, db, medID); n != 0 {
+		t.Errorf("Expected overlapping tick to be skipped, got %d intakes", n)
+	}
+}
+
+func TestCheckSchedule_OutOfStockMarkedMissed(t *testing.T) {
+	db := newTestStore(t)
+
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		if text := r.FormValue("text"); text != "" {
+			sent = append(sent, text)
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	sched := New(db, bot.NewWithAPI(api, 123456, db), 123456, nil, nil)
+
+	now := time.Now()
+	due := now.Add(-30 * time.Minute)
+	if due.Day() != now.Day() {
+		t.Skip("Too close to midnight for a same-day schedule")
+	}
+	schedule := fmt.Sprintf(`{"type":"daily","times":["%s"]}`, due.Format("15:04"))
+	medID, err := db.CreateMedication("Med A", "10mg", schedule, nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("CreateMedication: %v", err)
+	}
+	zero := 0.0
+	if err := db.SetInventory(medID, &zero); err != nil {
+		t.Fatalf("SetInventory: %v", err)
+	}
+
+	if err := sched.checkSchedule(); err != nil {
+		t.Fatalf("checkSchedule: %v", err)
+	}
+
+	target := time.Date(now.Year(), now.Month(), now.Day(), due.Hour(), due.Minute(), 0, 0, now.Location())
+	intake, err := db.GetIntakeBySchedule(medID, target)
+	if err != nil || intake == nil {
+		t.Fatalf("Expected intake for due dose, got %v (%v)", intake, err)
+	}
+	if intake.Status != "MISSED" || intake.Notes != store.OutOfStockReason {
+		t.Errorf("Expected MISSED with reason %q, got %s / %q", store.OutOfStockReason, intake.Status, intake.Notes)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 {
+		t.Fatalf("Expected only the restock nudge to be sent, got %d messages: %v", len(sent), sent)
+	}
+	if !strings.Contains(sent[0], "Out of Stock") || !strings.Contains(sent[0], "Med A") {
+		t.Errorf("Unexpected restock nudge: %q", sent[0])
+	}
+}
+
+func TestSnoozedIntakeRemindedWhenDue(t *testing.T) {
+	db := newTestStore(t)
+
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		if text := r.FormValue("text"); text != "" {
+			sent = append(sent, text)
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	sched := New(db, bot.NewWithAPI(api, 123456, db), 123456, nil, nil)
+
+	medID, _ := db.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	intakeID, _ := db.CreateIntake(medID, 123456, time.Now().Add(-2*time.Hour))
+	if err := db.SnoozeIntake(intakeID, time.Now().Add(30*time.Minute)); err != nil {
+		t.Fatalf("SnoozeIntake: %v", err)
+	}
+
+	// Neither the hourly reminder nor the snooze check fires while snoozed
+	if _, err := sched.checkReminders(); err != nil {
+		t.Fatalf("checkReminders: %v", err)
+	}
+	if _, err := sched.checkSnoozedIntakes(); err != nil {
+		t.Fatalf("checkSnoozedIntakes: %v", err)
+	}
+	mu.Lock()
+	if len(sent) != 0 {
+		t.Fatalf("Expected no reminders while snoozed, got %v", sent)
+	}
+	mu.Unlock()
+
+	// Once the snooze runs out the reminder is sent and hourly reminders resume
+	if err := db.SnoozeIntake(intakeID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("SnoozeIntake: %v", err)
+	}
+	if _, err := sched.checkSnoozedIntakes(); err != nil {
+		t.Fatalf("checkSnoozedIntakes: %v", err)
+	}
+	mu.Lock()
+	if len(sent) != 1 || !strings.Contains(sent[0], "REMINDER") {
+		t.Fatalf("Expected one reminder after the snooze, got %v", sent)
+	}
+	mu.Unlock()
+
+	pending, _ := db.GetPendingIntakes()
+	if len(pending) != 1 || pending[0].SnoozedUntil != nil {
+		t.Errorf("Expected the snooze to be cleared, got %+v", pending)
+	}
+	if reminders, _ := db.GetIntakeReminders(intakeID); len(reminders) != 1 {
+		t.Errorf("Expected the reminder message to be tracked, got %v", reminders)
+	}
+}
+
+func TestSkipMedicationToday(t *testing.T) {
+	db := newTestStore(t)
+
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		if text := r.FormValue("text"); text != "" {
+			sent = append(sent, text)
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	sched := New(db, bot.NewWithAPI(api, 123456, db), 123456, nil, nil)
+
+	skippedID, _ := db.CreateMedication("Skipped", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	otherID, _ := db.CreateMedication("Other", "5mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+
+	today := time.Date(2024, 5, 6, 9, 0, 0, 0, time.Local)
+	if err := db.SkipMedicationOn(skippedID, today); err != nil {
+		t.Fatalf("SkipMedicationOn: %v", err)
+	}
+
+	groups, _, err := sched.createDueIntakes(today)
+	if err != nil {
+		t.Fatalf("createDueIntakes: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Meds) != 1 || groups[0].Meds[0].ID != otherID {
+		t.Fatalf("Expected only the other medication to be due today, got %+v", groups)
+	}
+	if n := countIntakes(t, db, skippedID); n != 0 {
+		t.Errorf("Expected no intake for the skipped medication today, got %d", n)
+	}
+
+	// Reminders resume the next day
+	groups, _, err = sched.createDueIntakes(today.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("createDueIntakes: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Meds) != 2 {
+		t.Fatalf("Expected both medications to be due tomorrow, got %+v", groups)
+	}
+
+	// Doses already pending when the day is skipped aren't reminded about either
+	pending, _ := db.GetPendingIntakes()
+	for _, p := range pending {
+		db.ConfirmIntake(p.ID, p.ScheduledAt)
+	}
+	earlier := time.Now().Add(-2 * time.Hour)
+	db.CreateIntake(skippedID, 123456, earlier)
+	db.CreateIntake(otherID, 123456, earlier)
+	if err := db.SkipMedicationOn(skippedID, earlier); err != nil {
+		t.Fatalf("SkipMedicationOn: %v", err)
+	}
+	if _, err := sched.checkReminders(); err != nil {
+		t.Fatalf("checkReminders: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || !strings.Contains(sent[0], "Other") {
+		t.Errorf("Expected one reminder for the other medication, got %v", sent)
+	}
+}
+
+func TestTick(t *testing.T) {
+	db := newTestStore(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	sched := New(db, bot.NewWithAPI(api, 123456, db), 123456, nil, nil)
+
+	now := time.Now()
+	due := now.Add(-30 * time.Minute)
+	if due.Day() != now.Day() {
+		t.Skip("Too close to midnight for a same-day schedule")
+	}
+	schedule := fmt.Sprintf(`{"type":"daily","times":["%s"]}`, due.Format("15:04"))
+	medID, err := db.CreateMedication("Med A", "10mg", schedule, nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("CreateMedication: %v", err)
+	}
+
+	summary, err := sched.Tick()
+	if err != nil {
+		t.Fatalf("Tick: %v", err)
+	}
+	if summary.IntakesCreated != 1 || summary.NotificationGroups != 1 || summary.OutOfStock != 0 {
+		t.Errorf("Expected one intake in one notification group, got %+v", summary)
+	}
+	// Half an hour overdue is too early for a repeat reminder
+	if summary.RemindersSent != 0 {
+		t.Errorf("Expected no repeat reminders, got %d", summary.RemindersSent)
+	}
+
+	target := time.Date(now.Year(), now.Month(), now.Day(), due.Hour(), due.Minute(), 0, 0, now.Location())
+	intake, err := db.GetIntakeBySchedule(medID, target)
+	if err != nil || intake == nil || intake.Status != "PENDING" {
+		t.Fatalf("Expected a pending intake for the due dose, got %+v (%v)", intake, err)
+	}
+
+	summary, err = sched.Tick()
+	if err != nil {
+		t.Fatalf("Tick: %v", err)
+	}
+	if summary.IntakesCreated != 0 || summary.NotificationGroups != 0 {
+		t.Errorf("Expected a repeated tick to create nothing, got %+v", summary)
+	}
+}
+
+func TestRunSchedule_GroupNotificationSnoozeButtons(t *testing.T) {
+	db := newTestStore(t)
+
+	markups := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if strings.HasSuffix(r.URL.Path, "/sendMessage") {
+			select {
+			case markups <- r.FormValue("reply_markup"):
+			default:
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	sched := New(db, bot.NewWithAPI(api, 123456, db), 123456, nil, nil)
+
+	now := time.Now()
+	due := now.Add(-30 * time.Minute)
+	if due.Day() != now.Day() {
+		t.Skip("Too close to midnight for a same-day schedule")
+	}
+	schedule := fmt.Sprintf(`{"type":"daily","times":["%s"]}`, due.Format("15:04"))
+	db.CreateMedication("Med A", "10mg", schedule, nil, nil, "", "")
+	db.CreateMedication("Med B", "5mg", schedule, nil, nil, "", "")
+
+	if _, err := sched.runSchedule(now); err != nil {
+		t.Fatalf("runSchedule: %v", err)
+	}
+
+	var markup string
+	select {
+	case markup = <-markups:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a group notification")
+	}
+
+	target := time.Date(now.Year(), now.Month(), now.Day(), due.Hour(), due.Minute(), 0, 0, now.Location())
+	for _, minutes := range store.DefaultMedSnoozeMinutes {
+		data := fmt.Sprintf("group_snooze:%d:%d", target.Unix(), minutes)
+		if !strings.Contains(markup, data) {
+			t.Errorf("Expected snooze button %q in %s", data, markup)
+		}
+	}
+
+	// The notification is tracked as each dose's first reminder, for response times
+	pending, _ := db.GetPendingIntakes()
+	if len(pending) != 2 {
+		t.Fatalf("Expected two pending intakes, got %d", len(pending))
+	}
+	for _, p := range pending {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			last, _ := db.GetLastIntakeReminderAt(p.ID)
+			if last != nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected the notification to be tracked for intake %d", p.ID)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestCheckReminders_SpacedByLastReminder(t *testing.T) {
+	db := newTestStore(t)
+
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		if text := r.FormValue("text"); text != "" {
+			sent = append(sent, text)
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	sched := New(db, bot.NewWithAPI(api, 123456, db), 123456, nil, nil)
+
+	medID, _ := db.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	intakeID, _ := db.CreateIntake(medID, 123456, time.Now().Add(-2*time.Hour))
+
+	// Repeated checks (e.g. ticks from the admin endpoint) remind only once
+	for i := 0; i < 3; i++ {
+		if _, err := sched.checkReminders(); err != nil {
+			t.Fatalf("checkReminders: %v", err)
+		}
+	}
+	if reminders, _ := db.GetIntakeReminders(intakeID); len(reminders) != 1 {
+		t.Errorf("Expected one reminder, got %d", len(reminders))
+	}
+	mu.Lock()
+	if len(sent) != 1 {
+		t.Errorf("Expected one reminder message, got %v", sent)
+	}
+	mu.Unlock()
+}
+
+func TestCheckAutoConfirm(t *testing.T) {
+	db := newTestStore(t)
+
+	var mu sync.Mutex
+	deleted := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/deleteMessage") {
+			mu.Lock()
+			deleted++
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": true}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	sched := New(db, bot.NewWithAPI(api, 123456, db), 123456, nil, nil)
+
+	schedule := `{"type":"daily","times":["09:00"]}`
+	autoID, _ := db.CreateMedication("Auto Med", "10mg", schedule, nil, nil, "", "")
+	manualID, _ := db.CreateMedication("Manual Med", "10mg", schedule, nil, nil, "", "")
+	inventory := 10.0
+	for _, id := range []int64{autoID, manualID} {
+		if err := db.SetInventory(id, &inventory); err != nil {
+			t.Fatalf("SetInventory: %v", err)
+		}
+	}
+	window := 120
+	if err := db.SetAutoConfirmAfter(autoID, &window); err != nil {
+		t.Fatalf("SetAutoConfirmAfter: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Minute)
+	overdue, _ := db.CreateIntake(autoID, 123456, now.Add(-3*time.Hour))
+	recent, _ := db.CreateIntake(autoID, 123456, now.Add(-time.Hour))
+	manual, _ := db.CreateIntake(manualID, 123456, now.Add(-3*time.Hour))
+	db.AddIntakeReminder(overdue, 42)
+
+	confirmed, err := sched.checkAutoConfirm(now)
+	if err != nil {
+		t.Fatalf("checkAutoConfirm: %v", err)
+	}
+	if confirmed != 1 {
+		t.Fatalf("Expected 1 auto-confirmed intake, got %d", confirmed)
+	}
+
+	intake, _ := db.GetIntake(overdue)
+	if intake.Status != "TAKEN" || intake.TakenAt == nil || !intake.TakenAt.Equal(now.Add(-3*time.Hour)) {
+		t.Errorf("Expected the overdue dose taken at its scheduled time, got %+v", intake)
+	}
+	if intake.Notes != store.AutoConfirmedNote {
+		t.Errorf("Expected note %q, got %q", store.AutoConfirmedNote, intake.Notes)
+	}
+	med, _ := db.GetMedication(autoID)
+	if med.InventoryCount == nil || *med.InventoryCount != 9 {
+		t.Errorf("Expected inventory 9 after auto-confirm, got %v", med.InventoryCount)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected the reminder message to be deleted, got %d deletions", deleted)
+	}
+
+	// Still inside the window, and medications without auto-confirm stay pending
+	if intake, _ := db.GetIntake(recent); intake.Status != "PENDING" {
+		t.Errorf("Expected the recent dose to stay pending, got %s", intake.Status)
+	}
+	if intake, _ := db.GetIntake(manual); intake.Status != "PENDING" {
+		t.Errorf("Expected the manual medication's dose to stay pending, got %s", intake.Status)
+	}
+	if med, _ := db.GetMedication(manualID); *med.InventoryCount != 10 {
+		t.Errorf("Expected the manual medication's inventory untouched, got %v", *med.InventoryCount)
+	}
+
+	if confirmed, _ := sched.checkAutoConfirm(now); confirmed != 0 {
+		t.Errorf("Expected a repeated check to confirm nothing, got %d", confirmed)
+	}
+}
+
+func TestCheckMissedIntakes(t *testing.T) {
+	db := newTestStore(t)
+
+	var mu sync.Mutex
+	deleted := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/deleteMessage") {
+			mu.Lock()
+			deleted++
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": true}`))
+	}))
+	defer server.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	sched := New(db, bot.NewWithAPI(api, 123456, db), 123456, nil, nil)
+
+	schedule := `{"type":"daily","times":["09:00"]}`
+	medID, _ := db.CreateMedication("Lisinopril", "10mg", schedule, nil, nil, "", "")
+	archivedID, _ := db.CreateMedication("Old Med", "10mg", schedule, nil, nil, "", "")
+	autoID, _ := db.CreateMedication("Auto Med", "10mg", schedule, nil, nil, "", "")
+	db.UpdateMedication(archivedID, "Old Med", "10mg", schedule, true, nil, nil, "", "", nil)
+	window := 60
+	db.SetAutoConfirmAfter(autoID, &window)
+
+	now := time.Now()
+	overdue, _ := db.CreateIntake(medID, 123456, now.Add(-7*time.Hour))
+	recent, _ := db.CreateIntake(medID, 123456, now.Add(-5*time.Hour))
+	snoozed, _ := db.CreateIntake(medID, 123456, now.Add(-8*time.Hour))
+	archived, _ := db.CreateIntake(archivedID, 123456, now.Add(-7*time.Hour))
+	db.SnoozeIntake(snoozed, now.Add(30*time.Minute))
+	db.AddIntakeReminder(overdue, 42)
+
+	// Disabled, nothing is touched
+	sched.SetMissedAfter(0)
+	if n, _ := sched.checkMissedIntakes(); n != 0 {
+		t.Fatalf("Expected nothing marked while disabled, got %d", n)
+	}
+
+	sched.SetMissedAfter(DefaultMissedAfter)
+	n, err := sched.checkMissedIntakes()
+	if err != nil {
+		t.Fatalf("checkMissedIntakes: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("Expected 1 intake marked missed, got %d", n)
+	}
+	intake, _ := db.GetIntake(overdue)
+	if intake.Status != "MISSED" || intake.Notes != store.OverdueReason {
+		t.Errorf("Expected the overdue dose missed with note %q, got %+v", store.OverdueReason, intake)
+	}
+	mu.Lock()
+	if deleted != 1 {
+		t.Errorf("Expected the reminder message to be deleted, got %d deletions", deleted)
+	}
+	mu.Unlock()
+
+	for name, id := range map[string]int64{"recent": recent, "snoozed": snoozed, "archived": archived} {
+		if intake, _ := db.GetIntake(id); intake.Status != "PENDING" {
+			t.Errorf("Expected the %s dose to stay pending, got %s", name, intake.Status)
+		}
+	}
+
+	// Auto-confirm medications are assumed taken instead
+	auto, _ := db.CreateIntake(autoID, 123456, now.Add(-7*time.Hour))
+	if n, _ := sched.checkMissedIntakes(); n != 0 {
+		t.Errorf("Expected auto-confirm doses to be left alone, got %d marked", n)
+	}
+	if intake, _ := db.GetIntake(auto); intake.Status != "PENDING" {
+		t.Errorf("Expected the auto-confirm dose to stay pending, got %s", intake.Status)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
 )
 
 // checkWeightReminders checks if any users need weight reminder notifications
@@ -104,8 +106,13 @@ func (s *Scheduler) sendWeightReminder(ctx context.Context, userID int64) error
 	telegramSuccess := false
 	webPushSuccess := false
 
+	channels := s.reminderChannels(store.ReminderWeight)
+	if !channels.Telegram && !channels.WebPush {
+		return nil // Silenced by the user
+	}
+
 	// Send Telegram notification
-	if s.bot != nil {
+	if s.bot != nil && channels.Telegram {
 		msgID, err := s.bot.SendWeightReminderNotification(userID)
 		if err != nil {
 			log.Printf("Failed to send Telegram weight reminder: %v", err)
@@ -116,7 +123,7 @@ func (s *Scheduler) sendWeightReminder(ctx context.Context, userID int64) error
 	}
 
 	// Send Web Push notification
-	if s.webPush != nil {
+	if s.webPush != nil && channels.WebPush {
 		if err := s.webPush.SendWeightReminderNotification(ctx, userID); err != nil {
 			log.Printf("Failed to send Web Push weight reminder: %v", err)
 		} else {
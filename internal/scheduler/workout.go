@@ -53,6 +53,15 @@ func (s *Scheduler) checkWorkoutNotifications() error {
 		return fmt.Errorf("failed to list workout groups: %w", err)
 	}
 
+	// No workouts are planned on skip dates (vacation, injury)
+	skipToday, err := s.store.IsWorkoutSkipDate(s.allowedUserID, now)
+	if err != nil {
+		return fmt.Errorf("failed to check workout skip dates: %w", err)
+	}
+	if skipToday {
+		return nil
+	}
+
 	for _, group := range groups {
 		// 4. Check if today matches one of the scheduled days
 		todayIdx := int(now.Weekday())
@@ -207,31 +216,35 @@ func (s *Scheduler) sendWorkoutNotification(session *store.WorkoutSession, group
 				repsStr = fmt.Sprintf("%d", ex.TargetRepsMin)
 			}
 			message += fmt.Sprintf("%d. **%s**: %d × %s", i+1, ex.ExerciseName, ex.TargetSets, repsStr)
-			if ex.TargetWeightKg != nil {
-				message += fmt.Sprintf(" @ %.0fkg", *ex.TargetWeightKg)
+			if weight := ex.TargetWeightLabel(); weight != "" {
+				message += " @ " + weight
 			}
 			message += "\n"
 		}
 	}
 
-	// Delete previous notification if exists to avoid clutter
-	if session.NotificationMessageID != nil {
-		s.bot.DeleteMessage(*session.NotificationMessageID)
-	}
+	channels := s.reminderChannels(store.ReminderWorkout)
 
-	// Send notification with inline buttons via bot
-	messageID, err := s.bot.SendWorkoutNotification(message, session.ID)
-	if err != nil {
-		return err
-	}
+	if channels.Telegram {
+		// Delete previous notification if exists to avoid clutter
+		if session.NotificationMessageID != nil {
+			s.bot.DeleteMessage(*session.NotificationMessageID)
+		}
+
+		// Send notification with inline buttons via bot
+		messageID, err := s.bot.SendWorkoutNotification(message, session.ID)
+		if err != nil {
+			return err
+		}
 
-	// Store message ID for later editing
-	if err := s.store.SetSessionNotificationMessageID(session.ID, messageID); err != nil {
-		log.Printf("Failed to store notification message ID: %v", err)
+		// Store message ID for later editing
+		if err := s.store.SetSessionNotificationMessageID(session.ID, messageID); err != nil {
+			log.Printf("Failed to store notification message ID: %v", err)
+		}
 	}
 
 	// Send Web Push
-	if s.webPush != nil {
+	if s.webPush != nil && channels.WebPush {
 		ctx := context.Background()
 		if err := s.webPush.SendWorkoutNotification(ctx, s.allowedUserID, session, group, variant); err != nil {
 			log.Printf("Failed to send Web Push workout: %v", err)
@@ -0,0 +1,206 @@
+package scheduler
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	webpushlib "github.com/SherClockHolmes/webpush-go"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/bot"
+	"github.com/korjavin/medicationtrackerbot/internal/webpush"
+)
+
+// decryptPush reverses the aes128gcm content encoding (RFC 8291) of a push body
+func decryptPush(t *testing.T, body []byte, key *ecdh.PrivateKey, auth []byte) []byte {
+	t.Helper()
+	salt := body[:16]
+	rs := binary.BigEndian.Uint32(body[16:20])
+	idLen := int(body[20])
+	serverPub, err := ecdh.P256().NewPublicKey(body[21 : 21+idLen])
+	if err != nil {
+		t.Fatalf("Invalid server key: %v", err)
+	}
+	ciphertext := body[21+idLen:]
+	if uint32(len(ciphertext)) > rs {
+		t.Fatalf("Expected a single record")
+	}
+
+	shared, err := key.ECDH(serverPub)
+	if err != nil {
+		t.Fatalf("ECDH failed: %v", err)
+	}
+	keyInfo := append(append([]byte("WebPush: info\x00"), key.PublicKey().Bytes()...), serverPub.Bytes()...)
+	prkKey, _ := hkdf.Extract(sha256.New, shared, auth)
+	ikm, _ := hkdf.Expand(sha256.New, prkKey, string(keyInfo), 32)
+	prk, _ := hkdf.Extract(sha256.New, ikm, salt)
+	cek, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt push: %v", err)
+	}
+	// Strip padding: the data ends at the last 0x02 delimiter
+	plain = bytes.TrimRight(plain, "\x00")
+	return plain[:len(plain)-1]
+}
+
+func TestCheckWorkoutNotifications_SendsPushInAdvanceWindow(t *testing.T) {
+	db := newTestStore(t)
+
+	now := time.Now()
+	start := now.Add(10 * time.Minute)
+	later := now.Add(2 * time.Hour)
+	if start.Day() != now.Day() || later.Day() != now.Day() {
+		t.Skip("Too close to midnight for a same-day schedule")
+	}
+
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer tg.Close()
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+
+	var mu sync.Mutex
+	var pushes [][]byte
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		pushes = append(pushes, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer endpoint.Close()
+
+	uaKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	if err := db.CreatePushSubscription(123456, endpoint.URL, base64.RawURLEncoding.EncodeToString(auth), base64.RawURLEncoding.EncodeToString(uaKey.PublicKey().Bytes())); err != nil {
+		t.Fatalf("CreatePushSubscription: %v", err)
+	}
+
+	privateKey, publicKey, err := webpushlib.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate VAPID keys: %v", err)
+	}
+	push := webpush.New(db, publicKey, privateKey, "mailto:test@example.com")
+	sched := New(db, bot.NewWithAPI(api, 123456, db), 123456, push, nil)
+
+	today := fmt.Sprintf("[%d]", int(now.Weekday()))
+	due, err := db.CreateWorkoutGroup("Strength", "", false, 123456, today, start.Format("15:04"), 15)
+	if err != nil {
+		t.Fatalf("CreateWorkoutGroup: %v", err)
+	}
+	if _, err := db.CreateWorkoutVariant(due.ID, "Day A", nil, ""); err != nil {
+		t.Fatalf("CreateWorkoutVariant: %v", err)
+	}
+	// Starts in 2 hours, outside its 15 minute window
+	notYet, err := db.CreateWorkoutGroup("Cardio", "", false, 123456, today, later.Format("15:04"), 15)
+	if err != nil {
+		t.Fatalf("CreateWorkoutGroup: %v", err)
+	}
+	if _, err := db.CreateWorkoutVariant(notYet.ID, "Run", nil, ""); err != nil {
+		t.Fatalf("CreateWorkoutVariant: %v", err)
+	}
+
+	if err := sched.checkWorkoutNotifications(); err != nil {
+		t.Fatalf("checkWorkoutNotifications: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pushes) != 1 {
+		t.Fatalf("Expected 1 push, got %d", len(pushes))
+	}
+
+	var payload webpush.NotificationPayload
+	if err := json.Unmarshal(decryptPush(t, pushes[0], uaKey, auth), &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if payload.Title != "Workout in 15 minutes" {
+		t.Errorf("Unexpected title %q", payload.Title)
+	}
+	if want := "Strength - Day A at " + start.Format("15:04"); payload.Body != want {
+		t.Errorf("Expected body %q, got %q", want, payload.Body)
+	}
+	if payload.Data["type"] != "workout" || payload.Data["group_name"] != "Strength" || payload.Data["variant"] != "Day A" {
+		t.Errorf("Unexpected data: %v", payload.Data)
+	}
+
+	session, err := db.GetSessionByGroupAndDate(due.ID, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	if err != nil || session == nil {
+		t.Fatalf("Expected a session for today: %v", err)
+	}
+	if want := fmt.Sprintf("/?action=workout_start&session_id=%d", session.ID); payload.Data["url"] != want {
+		t.Errorf("Expected deep link %q, got %v", want, payload.Data["url"])
+	}
+}
+
+func TestCheckWorkoutNotifications_SkipDate(t *testing.T) {
+	db := newTestStore(t)
+
+	now := time.Now()
+	start := now.Add(10 * time.Minute)
+	if start.Day() != now.Day() {
+		t.Skip("Too close to midnight for a same-day schedule")
+	}
+
+	var sent int
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer tg.Close()
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	sched := New(db, bot.NewWithAPI(api, 123456, db), 123456, nil, nil)
+
+	group, err := db.CreateWorkoutGroup("Strength", "", false, 123456, fmt.Sprintf("[%d]", int(now.Weekday())), start.Format("15:04"), 15)
+	if err != nil {
+		t.Fatalf("CreateWorkoutGroup: %v", err)
+	}
+	if _, err := db.CreateWorkoutVariant(group.ID, "Day A", nil, ""); err != nil {
+		t.Fatalf("CreateWorkoutVariant: %v", err)
+	}
+	if err := db.AddWorkoutSkipDates(123456, []string{now.Format("2006-01-02")}, "injury"); err != nil {
+		t.Fatalf("AddWorkoutSkipDates: %v", err)
+	}
+
+	if err := sched.checkWorkoutNotifications(); err != nil {
+		t.Fatalf("checkWorkoutNotifications: %v", err)
+	}
+
+	if sent != 0 {
+		t.Errorf("Expected no notification on a skip date, got %d requests", sent)
+	}
+	session, err := db.GetSessionByGroupAndDate(group.ID, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		t.Fatalf("GetSessionByGroupAndDate: %v", err)
+	}
+	if session != nil {
+		t.Errorf("Expected no session on a skip date")
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// resetConfirmPhrase must be sent along with the token to actually wipe the data
+const resetConfirmPhrase = "RESET"
+
+// resetTokenTTL is how long a reset confirmation token stays valid
+const resetTokenTTL = 5 * time.Minute
+
+// resetTokenSecret keeps reset tokens from being usable as session tokens and vice versa
+func (s *Server) resetTokenSecret() string {
+	return s.botToken + "|account-reset"
+}
+
+// handleResetAccount wipes the user's data in two steps. A request without a token returns
+// a short-lived confirmation token; repeating the request with that token and
+// confirm="RESET" deletes all logs, readings, workouts and subscriptions.
+func (s *Server) handleResetAccount(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	subject := "reset:" + strconv.FormatInt(userID, 10)
+
+	var req struct {
+		Token   string `json:"token"`
+		Confirm string `json:"confirm"`
+	}
+	// An empty body asks for a token
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" {
+		expiresAt := time.Now().Add(resetTokenTTL)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      createSessionToken(subject, s.resetTokenSecret(), expiresAt),
+			"expires_at": expiresAt,
+			"message":    "Repeat the request with this token and confirm: \"" + resetConfirmPhrase + "\" to delete all your data",
+		})
+		return
+	}
+
+	if sub, ok := verifySessionToken(req.Token, s.resetTokenSecret()); !ok || sub != subject {
+		http.Error(w, "Invalid or expired confirmation token", http.StatusBadRequest)
+		return
+	}
+	if req.Confirm != resetConfirmPhrase {
+		http.Error(w, "confirm must be \""+resetConfirmPhrase+"\"", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.ResetUserData(userID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("User %d reset all their data", userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "ok",
+	})
+}
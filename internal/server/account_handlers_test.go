@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestHandleResetAccount(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	ctx := context.Background()
+	db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: userID, MeasuredAt: time.Now(), Systolic: 120, Diastolic: 80})
+
+	reset := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := withUser(httptest.NewRequest("POST", "/api/account/reset", strings.NewReader(body)), userID)
+		w := httptest.NewRecorder()
+		srv.handleResetAccount(w, req)
+		return w
+	}
+	readingCount := func() int {
+		readings, _ := db.GetBloodPressureReadings(ctx, userID, time.Time{}, 0, 0)
+		return len(readings)
+	}
+
+	// Step 1: ask for a token
+	w := reset("")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Token == "" {
+		t.Fatal("Expected a confirmation token")
+	}
+	if readingCount() != 1 {
+		t.Fatal("Requesting a token must not delete anything")
+	}
+
+	body := func(token, confirm string) string {
+		b, _ := json.Marshal(map[string]string{"token": token, "confirm": confirm})
+		return string(b)
+	}
+
+	// Missing confirmation phrase
+	if w := reset(body(resp.Token, "yes")); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without confirm phrase, got %d", w.Code)
+	}
+	// Token for another user, or a session token, is rejected
+	other := createSessionToken("reset:999", srv.resetTokenSecret(), time.Now().Add(time.Minute))
+	if w := reset(body(other, resetConfirmPhrase)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for another user's token, got %d", w.Code)
+	}
+	session := createSessionToken("reset:123456", srv.botToken, time.Now().Add(time.Minute))
+	if w := reset(body(session, resetConfirmPhrase)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a token signed with the session secret, got %d", w.Code)
+	}
+	if readingCount() != 1 {
+		t.Fatal("Rejected requests must not delete anything")
+	}
+
+	// Step 2: confirm
+	if w := reset(body(resp.Token, resetConfirmPhrase)); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if n := readingCount(); n != 0 {
+		t.Errorf("Expected readings to be deleted, got %d", n)
+	}
+}
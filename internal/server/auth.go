@@ -164,7 +164,7 @@ func AuthMiddleware(botToken string, allowedUserID int64) func(http.Handler) htt
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 			// 1. Check for Google Session Cookie
-			cookie, err := r.Cookie("auth_session")
+			cookie, err := r.Cookie(sessionCookieName)
 			if err == nil {
 				if email, ok := verifySessionToken(cookie.Value, botToken); ok {
 					// Create a dummy user from session
@@ -178,7 +178,7 @@ func AuthMiddleware(botToken string, allowedUserID int64) func(http.Handler) htt
 					next.ServeHTTP(w, r.WithContext(ctx))
 					return
 				}
-				log.Printf("[AUTH] Invalid session cookie from %s", r.RemoteAddr)
+				log.Printf("[AUTH] Invalid or expired session cookie from %s", r.RemoteAddr)
 			}
 
 			// 2. Check for Telegram InitData (Authorization header or query param)
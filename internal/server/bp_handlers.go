@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -41,6 +43,10 @@ func (s *Server) handleCreateBloodPressure(w http.ResponseWriter, r *http.Reques
 		Notes:      req.Notes,
 		Tag:        req.Tag,
 	}
+	if err := s.store.ApplyBPDefaults(bp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	id, err := s.store.CreateBloodPressureReading(r.Context(), bp)
 	if err != nil {
@@ -49,6 +55,64 @@ func (s *Server) handleCreateBloodPressure(w http.ResponseWriter, r *http.Reques
 	}
 
 	bp.ID = id
+	s.webhooks.NotifyBPReading(userID, bp)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bp)
+}
+
+// handleCreateTriplicateBloodPressure stores a triplicate measurement (three readings a
+// minute apart) as one reading averaging the last two. "keep_raw": true also stores the
+// three readings themselves, excluded from statistics.
+func (s *Server) handleCreateTriplicateBloodPressure(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	var req struct {
+		Readings []struct {
+			MeasuredAt time.Time `json:"measured_at"`
+			Systolic   int       `json:"systolic"`
+			Diastolic  int       `json:"diastolic"`
+			Pulse      *int      `json:"pulse,omitempty"`
+			Site       string    `json:"site,omitempty"`
+			Position   string    `json:"position,omitempty"`
+		} `json:"readings"`
+		Notes   string `json:"notes,omitempty"`
+		KeepRaw bool   `json:"keep_raw"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	readings := make([]store.BloodPressure, len(req.Readings))
+	for i, rd := range req.Readings {
+		readings[i] = store.BloodPressure{
+			UserID:     userID,
+			MeasuredAt: rd.MeasuredAt,
+			Systolic:   rd.Systolic,
+			Diastolic:  rd.Diastolic,
+			Pulse:      rd.Pulse,
+			Site:       rd.Site,
+			Position:   rd.Position,
+			Notes:      req.Notes,
+		}
+		if err := s.store.ApplyBPDefaults(&readings[i]); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if _, err := store.AverageTriplicate(readings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bp, err := s.store.CreateTriplicateBPReading(r.Context(), userID, readings, req.KeepRaw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.webhooks.NotifyBPReading(userID, bp)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(bp)
 }
@@ -76,16 +140,38 @@ func (s *Server) handleListBloodPressure(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	readings, err := s.store.GetBloodPressureReadings(r.Context(), userID, since)
+	// ?offset= skips readings directly; ?page= (from 1) skips whole pages of limit
+	offset := 0
+	if oStr := r.URL.Query().Get("offset"); oStr != "" {
+		o, err := strconv.Atoi(oStr)
+		if err != nil || o < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+		offset = o
+	} else if pStr := r.URL.Query().Get("page"); pStr != "" {
+		p, err := strconv.Atoi(pStr)
+		if err != nil || p < 1 {
+			http.Error(w, "Invalid page", http.StatusBadRequest)
+			return
+		}
+		if limit > 0 {
+			offset = (p - 1) * limit
+		}
+	}
+
+	readings, err := s.store.GetBloodPressureReadings(r.Context(), userID, since, limit, offset)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	if limit > 0 && len(readings) > limit {
-		readings = readings[:limit]
+	total, err := s.store.CountBloodPressureReadings(r.Context(), userID, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(readings)
 }
@@ -111,6 +197,66 @@ func (s *Server) handleDeleteBloodPressure(w http.ResponseWriter, r *http.Reques
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleDeleteBloodPressureRange removes every reading in ?from=&to= at once, e.g. a
+// malfunctioning monitor's batch, and reports how many were deleted
+func (s *Server) handleDeleteBloodPressureRange(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	from, to, err := parseDeleteRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := s.store.DeleteBloodPressureReadingsInRange(r.Context(), userID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "ok",
+		"deleted": deleted,
+	})
+}
+
+// parseDeleteRange reads the mandatory ?from=&to= of a bulk delete. Both accept RFC3339
+// timestamps or YYYY-MM-DD dates (a "to" date includes that whole day). The range is
+// half-open: [from, to).
+func parseDeleteRange(r *http.Request) (from, to time.Time, err error) {
+	q := r.URL.Query()
+	if q.Get("from") == "" || q.Get("to") == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("both from and to are required")
+	}
+	parse := func(name string, endOfDay bool) (time.Time, error) {
+		v := q.Get(name)
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s, expected RFC3339 or YYYY-MM-DD", name)
+		}
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if from, err = parse("from", false); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to, err = parse("to", true); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
+
+// handleImportBloodPressure imports readings, skipping duplicates. With ?validate=true
+// nothing is written; a report of valid, invalid and duplicate rows is returned instead.
 func (s *Server) handleImportBloodPressure(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
 
@@ -145,46 +291,70 @@ func (s *Server) handleImportBloodPressure(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	if err := s.store.ImportBloodPressureReadings(r.Context(), userID, readings); err != nil {
+	if r.URL.Query().Get("validate") == "true" {
+		report, err := s.store.ValidateBloodPressureImport(r.Context(), userID, readings)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	result, err := s.store.ImportBloodPressureReadings(r.Context(), userID, readings)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"imported": len(readings),
-		"status":   "success",
+		"imported":   result.Imported,
+		"duplicates": result.Duplicates,
+		"resumed":    result.Resumed,
+		"invalid":    result.Invalid,
+		"errors":     result.Errors,
+		"status":     "success",
 	})
 }
 
 func (s *Server) handleExportBloodPressure(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
 
-	// Parse query params
-	var since time.Time
-	if dStr := r.URL.Query().Get("days"); dStr != "" {
-		if days, err := strconv.Atoi(dStr); err == nil && days > 0 {
-			since = time.Now().AddDate(0, 0, -days)
-		}
+	since, until, err := parseExportRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	readings, err := s.store.GetBloodPressureReadings(r.Context(), userID, since)
+	readings, err := s.store.GetBloodPressureReadingsBetween(r.Context(), userID, since, until)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if wantsAnonymized(r) {
+		readings = anonymizeBPReadings(readings)
+	}
+
 	w.Header().Set("Content-Type", "text/csv")
 	w.Header().Set("Content-Disposition", "attachment; filename=blood_pressure_export.csv")
 
-	wr := csv.NewWriter(w)
-	defer wr.Flush()
+	if err := writeBPCSV(w, readings); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeBPCSV writes blood pressure readings as CSV
+func writeBPCSV(out io.Writer, readings []store.BloodPressure) error {
+	wr := csv.NewWriter(out)
 
 	// Write CSV header
 	header := []string{"Date", "Systolic", "Diastolic", "Pulse", "Site", "Position", "Category", "Notes", "Tag"}
 	if err := wr.Write(header); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 
 	// Write data rows
@@ -209,10 +379,12 @@ func (s *Server) handleExportBloodPressure(w http.ResponseWriter, r *http.Reques
 			bp.Tag,
 		}
 		if err := wr.Write(row); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return err
 		}
 	}
+
+	wr.Flush()
+	return wr.Error()
 }
 
 func (s *Server) handleGetBPGoal(w http.ResponseWriter, r *http.Request) {
@@ -226,17 +398,175 @@ func (s *Server) handleGetBPGoal(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(goal)
 }
 
+// maxBPStatsPrecision is the most decimals ?precision= may ask for
+const maxBPStatsPrecision = 2
+
+// bpPeriodStatsResponse is a period of BP stats with averages at the requested precision
+type bpPeriodStatsResponse struct {
+	Systolic  float64 `json:"systolic"`
+	Diastolic float64 `json:"diastolic"`
+	Precision int     `json:"precision"`
+	Days      int     `json:"days"`
+	Readings  int     `json:"readings"`
+}
+
+// handleGetBPStats returns the daily time-weighted averages. Averages are whole numbers
+// unless ?precision= (0-2) asks for decimals, e.g. 1 to spot subtle trends.
 func (s *Server) handleGetBPStats(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
 
+	precision := -1
+	if pStr := r.URL.Query().Get("precision"); pStr != "" {
+		p, err := strconv.Atoi(pStr)
+		if err != nil || p < 0 || p > maxBPStatsPrecision {
+			http.Error(w, fmt.Sprintf("precision must be between 0 and %d", maxBPStatsPrecision), http.StatusBadRequest)
+			return
+		}
+		precision = p
+	}
+
+	threshold := store.DefaultBPTrendThreshold
+	if tStr := r.URL.Query().Get("trend_threshold"); tStr != "" {
+		t, err := strconv.ParseFloat(tStr, 64)
+		if err != nil || t <= 0 {
+			http.Error(w, "trend_threshold must be a positive number of mmHg", http.StatusBadRequest)
+			return
+		}
+		threshold = t
+	}
+
 	stats, err := s.store.GetBPDailyWeightedStats(r.Context(), userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	stats.Trend = stats.BPTrend(threshold)
+
+	w.Header().Set("Content-Type", "application/json")
+	if precision < 0 {
+		json.NewEncoder(w).Encode(stats)
+		return
+	}
+
+	withPrecision := func(p *store.BPPeriodStats) *bpPeriodStatsResponse {
+		sys, dia := p.RoundedAverages(precision)
+		return &bpPeriodStatsResponse{Systolic: sys, Diastolic: dia, Precision: precision, Days: p.Days, Readings: p.Readings}
+	}
+	// Like BPStats, periods without readings are omitted
+	resp := map[string]interface{}{"trend": stats.Trend}
+	for key, p := range map[string]*store.BPPeriodStats{"stats_14": stats.Stats14, "stats_30": stats.Stats30, "stats_60": stats.Stats60} {
+		if p != nil {
+			resp[key] = withPrecision(p)
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleGetBPTimeInRange returns the time-weighted share of each BP category over ?days= (default 30)
+func (s *Server) handleGetBPTimeInRange(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	days := 30
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if d, err := strconv.Atoi(dStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	tir, err := s.store.GetBPTimeInRange(r.Context(), userID, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tir)
+}
+
+// handleGetSleepBPCorrelation relates each night's sleep to the next day's average BP
+// over the last ?days= days (default 60)
+func (s *Server) handleGetSleepBPCorrelation(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	days := 60
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if d, err := strconv.Atoi(dStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	corr, err := s.store.GetSleepBPCorrelation(r.Context(), userID, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stats)
+	json.NewEncoder(w).Encode(corr)
+}
+
+// handleGetBPAroundIntake returns BP readings within a window before and after a taken intake
+func (s *Server) handleGetBPAroundIntake(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	intakeID, err := strconv.ParseInt(r.URL.Query().Get("intake_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid intake_id", http.StatusBadRequest)
+		return
+	}
+
+	// Window in minutes on each side of the intake
+	window := 120
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		window, err = strconv.Atoi(windowStr)
+		if err != nil || window <= 0 || window > 24*60 {
+			http.Error(w, "Invalid window (1-1440 minutes)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	intake, err := s.store.GetIntake(intakeID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if intake == nil || intake.UserID != userID {
+		http.Error(w, "Intake not found", http.StatusNotFound)
+		return
+	}
+	if intake.TakenAt == nil {
+		http.Error(w, "Intake has not been taken", http.StatusBadRequest)
+		return
+	}
+
+	takenAt := *intake.TakenAt
+	span := time.Duration(window) * time.Minute
+	readings, err := s.store.GetBloodPressureReadingsBetween(r.Context(), userID, takenAt.Add(-span), takenAt.Add(span))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Readings come newest first; split into chronological before/after lists
+	before := []store.BloodPressure{}
+	after := []store.BloodPressure{}
+	for i := len(readings) - 1; i >= 0; i-- {
+		if readings[i].MeasuredAt.Before(takenAt) {
+			before = append(before, readings[i])
+		} else {
+			after = append(after, readings[i])
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"intake_id":      intake.ID,
+		"medication_id":  intake.MedicationID,
+		"taken_at":       takenAt,
+		"window_minutes": window,
+		"before":         before,
+		"after":          after,
+	})
 }
 
 // BP Reminder handlers
@@ -3,6 +3,7 @@ package server
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -83,6 +84,108 @@ func TestHandleCreateBloodPressure_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestHandleCreateBloodPressure_Defaults(t *testing.T) {
+	srv, db := createBPTestServer(t)
+	defer db.Close()
+
+	body := strings.NewReader(`{"site": "left_arm", "position": "seated"}`)
+	req := httptest.NewRequest("PATCH", "/api/settings/bp-defaults", body)
+	w := httptest.NewRecorder()
+	srv.handleUpdateBPDefaults(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	create := func(reqBody map[string]interface{}) store.BloodPressure {
+		t.Helper()
+		b, _ := json.Marshal(reqBody)
+		req := withUser(httptest.NewRequest("POST", "/api/bp", bytes.NewReader(b)), 123456)
+		w := httptest.NewRecorder()
+		srv.handleCreateBloodPressure(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+		}
+		var resp store.BloodPressure
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	// No site or position: both defaults apply
+	resp := create(map[string]interface{}{"measured_at": time.Now().Add(-time.Hour), "systolic": 125, "diastolic": 82})
+	if resp.Site != "left_arm" || resp.Position != "seated" {
+		t.Errorf("Expected left_arm/seated from defaults, got %q/%q", resp.Site, resp.Position)
+	}
+
+	// An explicit site wins, the position is still inherited
+	resp = create(map[string]interface{}{"measured_at": time.Now(), "systolic": 121, "diastolic": 79, "site": "right_arm"})
+	if resp.Site != "right_arm" || resp.Position != "seated" {
+		t.Errorf("Expected right_arm/seated, got %q/%q", resp.Site, resp.Position)
+	}
+
+	readings, err := db.GetBloodPressureReadings(context.Background(), 123456, time.Time{}, 0, 0)
+	if err != nil {
+		t.Fatalf("GetBloodPressureReadings failed: %v", err)
+	}
+	if len(readings) != 2 {
+		t.Fatalf("Expected 2 readings, got %d", len(readings))
+	}
+	for _, r := range readings {
+		if r.Site == "" || r.Position != "seated" {
+			t.Errorf("Expected stored reading to carry site and position, got %q/%q", r.Site, r.Position)
+		}
+	}
+
+	// Clearing a default stops it from being applied
+	req = httptest.NewRequest("PATCH", "/api/settings/bp-defaults", strings.NewReader(`{"position": ""}`))
+	w = httptest.NewRecorder()
+	srv.handleUpdateBPDefaults(w, req)
+	var defaults store.BPDefaults
+	json.NewDecoder(w.Body).Decode(&defaults)
+	if defaults.Site != "left_arm" || defaults.Position != "" {
+		t.Errorf("Expected only the position to be cleared, got %+v", defaults)
+	}
+	resp = create(map[string]interface{}{"measured_at": time.Now(), "systolic": 118, "diastolic": 76})
+	if resp.Site != "left_arm" || resp.Position != "" {
+		t.Errorf("Expected left_arm without position, got %q/%q", resp.Site, resp.Position)
+	}
+}
+
+func TestHandleCreateTriplicateBloodPressure(t *testing.T) {
+	srv, db := createBPTestServer(t)
+	defer db.Close()
+
+	start := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest("POST", "/api/bp/triplicate", strings.NewReader(body)), 123456)
+		w := httptest.NewRecorder()
+		srv.handleCreateTriplicateBloodPressure(w, req)
+		return w
+	}
+	reading := func(offset time.Duration, sys, dia int) string {
+		return fmt.Sprintf(`{"measured_at": %q, "systolic": %d, "diastolic": %d}`, start.Add(offset).Format(time.RFC3339), sys, dia)
+	}
+
+	w := post(fmt.Sprintf(`{"readings": [%s, %s, %s]}`,
+		reading(0, 146, 92), reading(time.Minute, 130, 84), reading(2*time.Minute, 126, 80)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var resp store.BloodPressure
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.ID == 0 || resp.Systolic != 128 || resp.Diastolic != 82 || resp.Tag != "triplicate" {
+		t.Errorf("Expected stored 128/82 tagged triplicate, got %+v", resp)
+	}
+
+	readings, _ := db.GetBloodPressureReadings(context.Background(), 123456, time.Time{}, 0, 0)
+	if len(readings) != 1 || readings[0].Systolic != 128 || readings[0].Diastolic != 82 {
+		t.Errorf("Expected the averaged reading only, got %+v", readings)
+	}
+
+	if w := post(fmt.Sprintf(`{"readings": [%s, %s]}`, reading(time.Hour, 130, 84), reading(time.Hour+time.Minute, 126, 80))); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for two readings, got %d", w.Code)
+	}
+}
+
 func TestHandleListBloodPressure(t *testing.T) {
 	srv, db := createBPTestServer(t)
 	defer db.Close()
@@ -120,6 +223,63 @@ func TestHandleListBloodPressure(t *testing.T) {
 	}
 }
 
+func TestHandleListBloodPressure_Paging(t *testing.T) {
+	srv, db := createBPTestServer(t)
+	defer db.Close()
+
+	// Five readings, two of them taken at the same time
+	ctx := ctxWithUser(123456)
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i, at := range []time.Time{base, base, base.Add(-time.Hour), base.Add(-2 * time.Hour), base.Add(-3 * time.Hour)} {
+		db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: 123456, MeasuredAt: at, Systolic: 120 + i, Diastolic: 80})
+	}
+
+	list := func(query string) ([]store.BloodPressure, *httptest.ResponseRecorder) {
+		t.Helper()
+		req := withUser(httptest.NewRequest("GET", "/api/bp?"+query, nil), 123456)
+		w := httptest.NewRecorder()
+		srv.handleListBloodPressure(w, req)
+		var readings []store.BloodPressure
+		if w.Code == http.StatusOK {
+			json.NewDecoder(w.Body).Decode(&readings)
+		}
+		return readings, w
+	}
+
+	seen := map[int64]bool{}
+	for page, want := range []int{2, 2, 1} {
+		readings, w := list(fmt.Sprintf("limit=2&page=%d", page+1))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if got := w.Header().Get("X-Total-Count"); got != "5" {
+			t.Errorf("Expected X-Total-Count 5, got %q", got)
+		}
+		if len(readings) != want {
+			t.Fatalf("Page %d: expected %d readings, got %d", page+1, want, len(readings))
+		}
+		for _, r := range readings {
+			if seen[r.ID] {
+				t.Errorf("Reading %d appeared on more than one page", r.ID)
+			}
+			seen[r.ID] = true
+		}
+	}
+
+	// Offset skips single readings and wins over page
+	readings, _ := list("limit=2&offset=3&page=1")
+	if len(readings) != 2 || readings[0].Systolic != 123 || readings[1].Systolic != 124 {
+		t.Errorf("Expected the 4th and 5th readings, got %+v", readings)
+	}
+
+	if _, w := list("offset=-1"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a negative offset, got %d", w.Code)
+	}
+	if _, w := list("page=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for page 0, got %d", w.Code)
+	}
+}
+
 func TestHandleDeleteBloodPressure(t *testing.T) {
 	srv, db := createBPTestServer(t)
 	defer db.Close()
@@ -146,12 +306,62 @@ func TestHandleDeleteBloodPressure(t *testing.T) {
 	}
 
 	// Verify
-	readings, _ := db.GetBloodPressureReadings(ctx, 123456, time.Time{})
+	readings, _ := db.GetBloodPressureReadings(ctx, 123456, time.Time{}, 0, 0)
 	if len(readings) != 0 {
 		t.Errorf("Expected 0 readings, got %d", len(readings))
 	}
 }
 
+func TestHandleDeleteBloodPressureRange(t *testing.T) {
+	srv, db := createBPTestServer(t)
+	defer db.Close()
+
+	ctx := ctxWithUser(123456)
+	base := time.Date(2024, 3, 10, 8, 0, 0, 0, time.Local)
+	for _, at := range []time.Time{base.AddDate(0, 0, -1), base, base.Add(2 * time.Hour), base.AddDate(0, 0, 1), base.AddDate(0, 0, 2)} {
+		db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: 123456, MeasuredAt: at, Systolic: 250, Diastolic: 40})
+	}
+	// Another user's reading in the range stays
+	db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: 999, MeasuredAt: base, Systolic: 120, Diastolic: 80})
+
+	req := httptest.NewRequest("DELETE", "/api/bp?from=2024-03-10&to=2024-03-11", nil)
+	req = withUser(req, 123456)
+	w := httptest.NewRecorder()
+	srv.handleDeleteBloodPressureRange(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Deleted int64 `json:"deleted"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Deleted != 3 {
+		t.Errorf("Expected 3 readings deleted, got %d", resp.Deleted)
+	}
+
+	readings, _ := db.GetBloodPressureReadings(ctx, 123456, time.Time{}, 0, 0)
+	if len(readings) != 2 {
+		t.Fatalf("Expected 2 readings left, got %d", len(readings))
+	}
+	// Newest first
+	if !readings[0].MeasuredAt.Equal(base.AddDate(0, 0, 2)) || !readings[1].MeasuredAt.Equal(base.AddDate(0, 0, -1)) {
+		t.Errorf("Expected the readings outside the range to remain, got %v and %v", readings[0].MeasuredAt, readings[1].MeasuredAt)
+	}
+	if others, _ := db.GetBloodPressureReadings(ctx, 999, time.Time{}, 0, 0); len(others) != 1 {
+		t.Errorf("Expected the other user's reading to stay, got %d", len(others))
+	}
+
+	// Without an explicit range nothing is deleted
+	req = httptest.NewRequest("DELETE", "/api/bp?from=2024-03-01", nil)
+	req = withUser(req, 123456)
+	w = httptest.NewRecorder()
+	srv.handleDeleteBloodPressureRange(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without to, got %d", w.Code)
+	}
+}
+
 func TestHandleGetBPGoal(t *testing.T) {
 	srv, db := createBPTestServer(t)
 	defer db.Close()
@@ -195,6 +405,139 @@ func TestHandleGetBPStats(t *testing.T) {
 	}
 }
 
+func TestHandleGetBPStats_Precision(t *testing.T) {
+	srv, db := createBPTestServer(t)
+	defer db.Close()
+
+	ctx := ctxWithUser(123456)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	// One reading on each of two days, so the period average is the mean of both
+	db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: 123456, MeasuredAt: today.AddDate(0, 0, -3).Add(23 * time.Hour), Systolic: 160, Diastolic: 100})
+	db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: 123456, MeasuredAt: today.AddDate(0, 0, -2).Add(9 * time.Hour), Systolic: 121, Diastolic: 81})
+
+	tests := []struct {
+		query   string
+		wantSys float64
+		wantDia float64
+	}{
+		{"", 141, 91},
+		{"?precision=0", 141, 91},
+		{"?precision=1", 140.5, 90.5},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/bp/stats"+tt.query, nil)
+		req = withUser(req, 123456)
+		w := httptest.NewRecorder()
+		srv.handleGetBPStats(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", tt.query, w.Code)
+		}
+		var resp struct {
+			Stats14 *struct {
+				Systolic  float64 `json:"systolic"`
+				Diastolic float64 `json:"diastolic"`
+			} `json:"stats_14"`
+			Trend string `json:"trend"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: failed to decode: %v", tt.query, err)
+		}
+		got := resp.Stats14
+		if got == nil {
+			t.Fatalf("%s: expected stats_14 in response", tt.query)
+		}
+		if resp.Trend != store.BPTrendStable {
+			t.Errorf("%s: expected a stable trend with two days of readings, got %q", tt.query, resp.Trend)
+		}
+		if got.Systolic != tt.wantSys || got.Diastolic != tt.wantDia {
+			t.Errorf("%s: expected %v/%v, got %v/%v", tt.query, tt.wantSys, tt.wantDia, got.Systolic, got.Diastolic)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/bp/stats?precision=5", nil)
+	req = withUser(req, 123456)
+	w := httptest.NewRecorder()
+	srv.handleGetBPStats(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for precision 5, got %d", w.Code)
+	}
+}
+
+func TestHandleGetBPAroundIntake(t *testing.T) {
+	srv, db := createBPTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	ctx := ctxWithUser(userID)
+	takenAt := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+
+	medID, _ := db.CreateMedication("Amlodipine", "5mg", "08:00", nil, nil, "", "")
+	intakeID, _ := db.CreateIntake(medID, userID, takenAt)
+	db.ConfirmIntake(intakeID, takenAt)
+
+	offsets := []time.Duration{
+		-3 * time.Hour,    // outside window
+		-90 * time.Minute, // before
+		-10 * time.Minute, // before
+		30 * time.Minute,  // after
+		110 * time.Minute, // after
+		150 * time.Minute, // outside window
+	}
+	for i, off := range offsets {
+		db.CreateBloodPressureReading(ctx, &store.BloodPressure{
+			UserID:     userID,
+			MeasuredAt: takenAt.Add(off),
+			Systolic:   140 - i, Diastolic: 90,
+		})
+	}
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/bp/around?intake_id=%d&window=120", intakeID), nil)
+	req = withUser(req, userID)
+	w := httptest.NewRecorder()
+	srv.handleGetBPAroundIntake(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Before []store.BloodPressure `json:"before"`
+		After  []store.BloodPressure `json:"after"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(resp.Before) != 2 || len(resp.After) != 2 {
+		t.Fatalf("Expected 2 before and 2 after, got %d and %d", len(resp.Before), len(resp.After))
+	}
+	// Chronological order within each side
+	if resp.Before[0].Systolic != 139 || resp.Before[1].Systolic != 138 {
+		t.Errorf("Unexpected before readings: %+v", resp.Before)
+	}
+	if resp.After[0].Systolic != 137 || resp.After[1].Systolic != 136 {
+		t.Errorf("Unexpected after readings: %+v", resp.After)
+	}
+}
+
+func TestHandleGetBPAroundIntake_NotTaken(t *testing.T) {
+	srv, db := createBPTestServer(t)
+	defer db.Close()
+
+	medID, _ := db.CreateMedication("Amlodipine", "5mg", "08:00", nil, nil, "", "")
+	intakeID, _ := db.CreateIntake(medID, 123456, time.Now())
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/bp/around?intake_id=%d", intakeID), nil)
+	req = withUser(req, 123456)
+	w := httptest.NewRecorder()
+	srv.handleGetBPAroundIntake(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for pending intake, got %d", w.Code)
+	}
+}
+
 // Helper to create context with user - removed redundant reqWithUser at bottom
 
 func TestHandleImportBloodPressure(t *testing.T) {
@@ -225,12 +568,61 @@ func TestHandleImportBloodPressure(t *testing.T) {
 
 	// Verify DB
 	ctx := ctxWithUser(123456)
-	readings, _ := db.GetBloodPressureReadings(ctx, 123456, time.Time{})
+	readings, _ := db.GetBloodPressureReadings(ctx, 123456, time.Time{}, 0, 0)
 	if len(readings) != 1 {
 		t.Errorf("Expected 1 reading, got %d", len(readings))
 	}
 }
 
+func TestHandleImportBloodPressure_Validate(t *testing.T) {
+	srv, db := createBPTestServer(t)
+	defer db.Close()
+
+	ctx := ctxWithUser(123456)
+	existing := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: 123456, MeasuredAt: existing, Systolic: 128, Diastolic: 84})
+
+	reqBody := map[string]interface{}{
+		"readings": []map[string]interface{}{
+			{"measured_at": existing, "systolic": 128, "diastolic": 84}, // Already stored
+			{"measured_at": existing.Add(24 * time.Hour), "systolic": 122, "diastolic": 79, "pulse": 66},
+			{"measured_at": existing.Add(48 * time.Hour), "systolic": 300, "diastolic": 80},
+			{"systolic": 120, "diastolic": 80},
+			{"measured_at": existing.Add(72 * time.Hour), "systolic": 118, "diastolic": 76},
+			{"measured_at": existing.Add(72 * time.Hour), "systolic": 118, "diastolic": 76}, // Repeated row
+		},
+	}
+	body, _ := json.Marshal(reqBody)
+
+	req := httptest.NewRequest("POST", "/api/bp/import?validate=true", bytes.NewReader(body))
+	req = withUser(req, 123456)
+	w := httptest.NewRecorder()
+	srv.handleImportBloodPressure(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var report store.BPImportReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+
+	if report.Total != 6 || report.Valid != 2 || report.Invalid != 2 || report.Duplicates != 2 {
+		t.Errorf("Expected 6 total, 2 valid, 2 invalid, 2 duplicates, got %+v", report)
+	}
+	if len(report.Errors) != 2 || report.Errors[0].Index != 2 || report.Errors[1].Index != 3 {
+		t.Fatalf("Expected errors for rows 2 and 3, got %+v", report.Errors)
+	}
+	if !strings.Contains(report.Errors[0].Reason, "systolic") || !strings.Contains(report.Errors[1].Reason, "measured_at") {
+		t.Errorf("Unexpected reasons: %+v", report.Errors)
+	}
+
+	readings, _ := db.GetBloodPressureReadings(ctx, 123456, time.Time{}, 0, 0)
+	if len(readings) != 1 {
+		t.Errorf("Expected validation to leave the single stored reading alone, got %d readings", len(readings))
+	}
+}
+
 func TestHandleExportBloodPressure(t *testing.T) {
 	srv, db := createBPTestServer(t)
 	defer db.Close()
@@ -262,6 +654,95 @@ func TestHandleExportBloodPressure(t *testing.T) {
 	}
 }
 
+func TestHandleExportBloodPressure_Anonymized(t *testing.T) {
+	srv, db := createBPTestServer(t)
+	defer db.Close()
+
+	ctx := ctxWithUser(123456)
+	measuredAt := time.Date(2025, 3, 14, 8, 47, 31, 0, time.UTC)
+	db.CreateBloodPressureReading(ctx, &store.BloodPressure{
+		UserID:     123456,
+		MeasuredAt: measuredAt,
+		Systolic:   131, Diastolic: 87,
+		Notes: "after argument with neighbour",
+		Tag:   "stress",
+	})
+
+	req := httptest.NewRequest("GET", "/api/bp/export?anonymize=true", nil)
+	req = withUser(req, 123456)
+	w := httptest.NewRecorder()
+
+	srv.handleExportBloodPressure(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected header and 1 row, got %d records", len(records))
+	}
+
+	row := records[1]
+	if row[0] != "2025-03-14T08:00:00Z" {
+		t.Errorf("Expected timestamp coarsened to the hour, got %s", row[0])
+	}
+	if row[1] != "131" || row[2] != "87" {
+		t.Errorf("Expected systolic/diastolic preserved, got %s/%s", row[1], row[2])
+	}
+	if row[7] != "" || row[8] != "" {
+		t.Errorf("Expected notes and tag stripped, got %q / %q", row[7], row[8])
+	}
+}
+
+func TestHandleExportBloodPressure_DateRange(t *testing.T) {
+	srv, db := createBPTestServer(t)
+	defer db.Close()
+
+	ctx := ctxWithUser(123456)
+	for _, r := range []struct {
+		at  time.Time
+		sys int
+	}{
+		{time.Date(2024, 2, 29, 23, 30, 0, 0, time.Local), 111},
+		{time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), 122},
+		{time.Date(2024, 3, 31, 23, 59, 0, 0, time.Local), 133},
+		{time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local), 144},
+	} {
+		db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: 123456, MeasuredAt: r.at, Systolic: r.sys, Diastolic: 80})
+	}
+
+	req := withUser(httptest.NewRequest("GET", "/api/bp/export?from=2024-03-01&to=2024-03-31", nil), 123456)
+	w := httptest.NewRecorder()
+	srv.handleExportBloodPressure(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	var got []string
+	for _, row := range records[1:] {
+		got = append(got, row[1])
+	}
+	if strings.Join(got, ",") != "133,122" {
+		t.Errorf("Expected only the March readings (133,122), got %v", got)
+	}
+
+	// from after to is rejected
+	req = withUser(httptest.NewRequest("GET", "/api/bp/export?from=2024-04-01&to=2024-03-01", nil), 123456)
+	w = httptest.NewRecorder()
+	srv.handleExportBloodPressure(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for from after to, got %d", w.Code)
+	}
+}
+
 // BP Reminder Handler Tests
 
 func TestHandleGetBPReminderStatus(t *testing.T) {
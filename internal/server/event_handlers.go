@@ -0,0 +1,162 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// decodeLifeEvent reads and validates an event from the request body, writing a 400
+// response and returning false if it's unusable. The date defaults to today.
+func decodeLifeEvent(w http.ResponseWriter, r *http.Request, userID int64) (store.LifeEvent, bool) {
+	var req struct {
+		Title string `json:"title"`
+		Date  string `json:"date"`
+		Note  string `json:"note,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return store.LifeEvent{}, false
+	}
+	if req.Date == "" {
+		req.Date = time.Now().Format("2006-01-02")
+	}
+
+	e := store.LifeEvent{
+		UserID: userID,
+		Title:  strings.TrimSpace(req.Title),
+		Date:   req.Date,
+		Note:   req.Note,
+	}
+	if err := e.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return store.LifeEvent{}, false
+	}
+	return e, true
+}
+
+func (s *Server) handleCreateLifeEvent(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	e, ok := decodeLifeEvent(w, r, userID)
+	if !ok {
+		return
+	}
+
+	id, err := s.store.CreateLifeEvent(r.Context(), &e)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	e.ID = id
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
+
+func (s *Server) handleUpdateLifeEvent(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	e, ok := decodeLifeEvent(w, r, userID)
+	if !ok {
+		return
+	}
+	e.ID = id
+
+	if err := s.store.UpdateLifeEvent(r.Context(), &e); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Event not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
+
+// handleListLifeEvents returns events between ?from= and ?to= (YYYY-MM-DD, both
+// inclusive), or all of them when no range is given, oldest first
+func (s *Server) handleListLifeEvents(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var fromDay, toDay string
+	if !from.IsZero() {
+		fromDay = from.Format("2006-01-02")
+	}
+	if !to.IsZero() {
+		// parseDateRange returns the start of the day after to
+		toDay = to.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+
+	events, err := s.store.GetLifeEvents(r.Context(), userID, fromDay, toDay)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []store.LifeEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+func (s *Server) handleDeleteLifeEvent(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.DeleteLifeEvent(r.Context(), id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Event not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeLifeEventsCSV writes life events as CSV
+func writeLifeEventsCSV(out io.Writer, events []store.LifeEvent) error {
+	wr := csv.NewWriter(out)
+	header := []string{"Date", "Title", "Note"}
+	if err := wr.Write(header); err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		note := strings.ReplaceAll(e.Note, "\n", " ")
+		note = strings.ReplaceAll(note, "\r", "")
+
+		if err := wr.Write([]string{e.Date, e.Title, note}); err != nil {
+			return err
+		}
+	}
+
+	wr.Flush()
+	return wr.Error()
+}
@@ -0,0 +1,613 @@
+package server
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// handleExportAllZip streams every dataset as a ZIP archive of CSV files
+func (s *Server) handleExportAllZip(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	ctx := r.Context()
+
+	meds, err := s.store.ListMedications(true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	intakes, err := s.store.GetIntakesSince(time.Time{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	readings, err := s.store.GetBloodPressureReadings(ctx, userID, time.Time{}, 0, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	weights, err := s.store.GetWeightLogs(ctx, userID, time.Time{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sleeps, err := s.store.GetSleepLogs(ctx, userID, time.Time{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	glucose, err := s.store.GetGlucoseLogs(ctx, userID, time.Time{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	symptoms, err := s.store.GetSymptomLogs(ctx, userID, time.Time{}, time.Time{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	labs, err := s.store.GetLabResults(ctx, userID, "", time.Time{}, time.Time{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	events, err := s.store.GetLifeEvents(ctx, userID, "", "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// SQLite treats a negative LIMIT as "no limit"
+	sessions, err := s.store.GetWorkoutHistory(userID, -1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsAnonymized(r) {
+		intakes = anonymizeIntakes(intakes)
+		readings = anonymizeBPReadings(readings)
+		weights = anonymizeWeightLogs(weights)
+		sleeps = anonymizeSleepLogs(sleeps)
+		glucose = anonymizeGlucoseLogs(glucose)
+		symptoms = anonymizeSymptomLogs(symptoms)
+		labs = anonymizeLabResults(labs)
+		events = anonymizeLifeEvents(events)
+	}
+
+	files := []exportFile{
+		{"medications.csv", func(out io.Writer) error { return writeMedicationsCSV(out, meds) }},
+		{"intakes.csv", func(out io.Writer) error { return writeIntakesCSV(out, intakes) }},
+		{"blood_pressure.csv", func(out io.Writer) error { return writeBPCSV(out, readings) }},
+		{"weight.csv", func(out io.Writer) error { return store.WriteWeightLibra(out, weights) }},
+		{"sleep.csv", func(out io.Writer) error { return writeSleepCSV(out, sleeps) }},
+		{"glucose.csv", func(out io.Writer) error { return writeGlucoseCSV(out, glucose) }},
+		{"symptoms.csv", func(out io.Writer) error { return writeSymptomsCSV(out, symptoms) }},
+		{"labs.csv", func(out io.Writer) error { return writeLabResultsCSV(out, labs) }},
+		{"events.csv", func(out io.Writer) error { return writeLifeEventsCSV(out, events) }},
+		{"workouts.csv", func(out io.Writer) error { return s.writeWorkoutsCSV(out, sessions) }},
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=medtracker_export.zip")
+
+	if err := writeExportZip(w, files); err != nil {
+		log.Printf("Error writing export zip: %v", err)
+	}
+}
+
+// exportFile is one file of a ZIP export
+type exportFile struct {
+	name  string
+	write func(io.Writer) error
+}
+
+// writeExportZip writes the files into a ZIP archive on out
+func writeExportZip(out io.Writer, files []exportFile) error {
+	zw := zip.NewWriter(out)
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", f.name, err)
+		}
+		if err := f.write(fw); err != nil {
+			return fmt.Errorf("writing %s: %w", f.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// handleExportSummaryZip streams the last ?days= (default 30) of intakes, blood pressure,
+// weight and sleep as a ZIP of CSV files plus a summary.txt with the averages. Datasets
+// without entries in the period are left out.
+func (s *Server) handleExportSummaryZip(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	ctx := r.Context()
+
+	days := 30
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		d, err := strconv.Atoi(dStr)
+		if err != nil || d < 1 || d > 365 {
+			http.Error(w, "days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = d
+	}
+	now := time.Now()
+	since := now.AddDate(0, 0, -days)
+
+	intakes, err := s.store.GetIntakesSince(since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	readings, err := s.store.GetBloodPressureReadings(ctx, userID, since, 0, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	weights, err := s.store.GetWeightLogs(ctx, userID, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sleeps, err := s.store.GetSleepLogs(ctx, userID, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsAnonymized(r) {
+		intakes = anonymizeIntakes(intakes)
+		readings = anonymizeBPReadings(readings)
+		weights = anonymizeWeightLogs(weights)
+		sleeps = anonymizeSleepLogs(sleeps)
+	}
+
+	var files []exportFile
+	if len(intakes) > 0 {
+		files = append(files, exportFile{"intakes.csv", func(out io.Writer) error { return writeIntakesCSV(out, intakes) }})
+	}
+	if len(readings) > 0 {
+		files = append(files, exportFile{"blood_pressure.csv", func(out io.Writer) error { return writeBPCSV(out, readings) }})
+	}
+	if len(weights) > 0 {
+		files = append(files, exportFile{"weight.csv", func(out io.Writer) error { return store.WriteWeightLibra(out, weights) }})
+	}
+	if len(sleeps) > 0 {
+		files = append(files, exportFile{"sleep.csv", func(out io.Writer) error { return writeSleepCSV(out, sleeps) }})
+	}
+	files = append(files, exportFile{"summary.txt", func(out io.Writer) error {
+		return writeHealthSummary(out, since, now, intakes, readings, weights, sleeps)
+	}})
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=medtracker_summary_%s.zip", now.Format("2006-01-02")))
+
+	if err := writeExportZip(w, files); err != nil {
+		log.Printf("Error writing summary export zip: %v", err)
+	}
+}
+
+// writeHealthSummary writes the period's averages as plain text, one line per metric
+func writeHealthSummary(out io.Writer, since, until time.Time, intakes []store.IntakeWithMedication, readings []store.BloodPressure, weights []store.WeightLog, sleeps []store.SleepLog) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Health summary %s to %s\n\n", since.Format("2006-01-02"), until.Format("2006-01-02"))
+
+	var taken, missed, due int
+	for _, in := range intakes {
+		switch in.Status {
+		case store.IntakeStatusTaken:
+			taken++
+		case store.IntakeStatusMissed:
+			missed++
+		}
+		if in.Status != store.IntakeStatusPending {
+			due++
+		}
+	}
+	if due > 0 {
+		fmt.Fprintf(&b, "Medications: %d of %d doses taken (%.1f%%), %d missed\n", taken, due, float64(taken)/float64(due)*100, missed)
+	} else {
+		b.WriteString("Medications: no doses recorded\n")
+	}
+
+	var sumSys, sumDia, sumPulse, nBP, nPulse int
+	for _, bp := range readings {
+		if bp.IgnoreCalc {
+			continue
+		}
+		sumSys += bp.Systolic
+		sumDia += bp.Diastolic
+		nBP++
+		if bp.Pulse != nil {
+			sumPulse += *bp.Pulse
+			nPulse++
+		}
+	}
+	if nBP > 0 {
+		fmt.Fprintf(&b, "Blood pressure: average %.0f/%.0f", float64(sumSys)/float64(nBP), float64(sumDia)/float64(nBP))
+		if nPulse > 0 {
+			fmt.Fprintf(&b, ", pulse %.0f", float64(sumPulse)/float64(nPulse))
+		}
+		fmt.Fprintf(&b, " (%d readings)\n", nBP)
+	} else {
+		b.WriteString("Blood pressure: no readings\n")
+	}
+
+	if len(weights) > 0 {
+		var sum float64
+		first, last := weights[0], weights[0]
+		for _, l := range weights {
+			sum += l.Weight
+			if l.MeasuredAt.Before(first.MeasuredAt) {
+				first = l
+			}
+			if l.MeasuredAt.After(last.MeasuredAt) {
+				last = l
+			}
+		}
+		fmt.Fprintf(&b, "Weight: average %.1f kg, %.1f -> %.1f kg (%d entries)\n", sum/float64(len(weights)), first.Weight, last.Weight, len(weights))
+	} else {
+		b.WriteString("Weight: no entries\n")
+	}
+
+	// Naps count towards the day they belong to
+	nights := map[string]int{}
+	for _, l := range sleeps {
+		if l.TotalMinutes != nil {
+			nights[l.Day] += *l.TotalMinutes
+		}
+	}
+	if len(nights) > 0 {
+		total := 0
+		for _, minutes := range nights {
+			total += minutes
+		}
+		avg := total / len(nights)
+		fmt.Fprintf(&b, "Sleep: average %dh %02dm per night (%d nights)\n", avg/60, avg%60, len(nights))
+	} else {
+		b.WriteString("Sleep: no entries\n")
+	}
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// writeMedicationsCSV writes medications (including archived) as CSV
+func writeMedicationsCSV(out io.Writer, meds []store.Medication) error {
+	wr := csv.NewWriter(out)
+	header := []string{"ID", "Name", "Dosage", "Schedule", "Archived", "Start Date", "End Date", "RxCUI", "Normalized Name", "Inventory"}
+	if err := wr.Write(header); err != nil {
+		return err
+	}
+
+	for _, m := range meds {
+		row := []string{
+			strconv.FormatInt(m.ID, 10),
+			m.Name,
+			m.Dosage,
+			m.Schedule,
+			strconv.FormatBool(m.Archived),
+			formatOptionalDate(m.StartDate),
+			formatOptionalDate(m.EndDate),
+			m.RxCUI,
+			m.NormalizedName,
+			formatOptionalFloat(m.InventoryCount),
+		}
+		if err := wr.Write(row); err != nil {
+			return err
+		}
+	}
+
+	wr.Flush()
+	return wr.Error()
+}
+
+// writeIntakesCSV writes the intake log as CSV
+func writeIntakesCSV(out io.Writer, intakes []store.IntakeWithMedication) error {
+	wr := csv.NewWriter(out)
+	header := []string{"Scheduled At", "Taken At", "Medication", "Dosage", "Status", "Notes", "Substituted With"}
+	if err := wr.Write(header); err != nil {
+		return err
+	}
+
+	for _, in := range intakes {
+		takenAt := ""
+		if in.TakenAt != nil {
+			takenAt = in.TakenAt.Format(time.RFC3339)
+		}
+		row := []string{
+			in.ScheduledAt.Format(time.RFC3339),
+			takenAt,
+			in.MedicationName,
+			in.MedicationDosage,
+			in.Status,
+			strings.ReplaceAll(in.Notes, "\n", " "),
+			in.SubstitutedWith,
+		}
+		if err := wr.Write(row); err != nil {
+			return err
+		}
+	}
+
+	wr.Flush()
+	return wr.Error()
+}
+
+// handleExportSleep streams sleep logs from the last ?days= (default 90) as CSV
+func (s *Server) handleExportSleep(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	days := 90
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if d, err := strconv.Atoi(dStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	logs, err := s.store.GetSleepLogs(r.Context(), userID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsAnonymized(r) {
+		logs = anonymizeSleepLogs(logs)
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=sleep_export.csv")
+
+	if err := writeSleepCSV(w, logs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeSleepCSV writes sleep logs as CSV
+func writeSleepCSV(out io.Writer, logs []store.SleepLog) error {
+	wr := csv.NewWriter(out)
+	header := []string{"Day", "Start", "End", "Total Minutes", "Deep Minutes", "Light Minutes", "REM Minutes", "Awake Minutes", "Heart Rate Avg", "SpO2 Avg", "Notes"}
+	if err := wr.Write(header); err != nil {
+		return err
+	}
+
+	for _, l := range logs {
+		notes := strings.ReplaceAll(l.Notes, "\n", " ")
+		notes = strings.ReplaceAll(notes, "\r", "")
+
+		row := []string{
+			l.Day,
+			l.StartTime.Format(time.RFC3339),
+			l.EndTime.Format(time.RFC3339),
+			formatOptionalInt(l.TotalMinutes),
+			formatOptionalInt(l.DeepMinutes),
+			formatOptionalInt(l.LightMinutes),
+			formatOptionalInt(l.REMMinutes),
+			formatOptionalInt(l.AwakeMinutes),
+			formatOptionalInt(l.HeartRateAvg),
+			formatOptionalInt(l.SpO2Avg),
+			notes,
+		}
+		if err := wr.Write(row); err != nil {
+			return err
+		}
+	}
+
+	wr.Flush()
+	return wr.Error()
+}
+
+// writeWorkoutsCSV writes one row per logged exercise; sessions without logs get a single row
+func (s *Server) writeWorkoutsCSV(out io.Writer, sessions []store.WorkoutSession) error {
+	wr := csv.NewWriter(out)
+	header := []string{"Date", "Time", "Group", "Variant", "Status", "Exercise", "Sets", "Reps", "Weight (kg)", "Exercise Status"}
+	if err := wr.Write(header); err != nil {
+		return err
+	}
+
+	groupNames := map[int64]string{-1: "Ad-hoc"}
+	variantNames := map[int64]string{-1: "Ad-hoc"}
+
+	for _, sess := range sessions {
+		if _, ok := groupNames[sess.GroupID]; !ok {
+			if g, err := s.store.GetWorkoutGroup(sess.GroupID); err == nil && g != nil {
+				groupNames[sess.GroupID] = g.Name
+			}
+		}
+		if _, ok := variantNames[sess.VariantID]; !ok {
+			if v, err := s.store.GetWorkoutVariant(sess.VariantID); err == nil && v != nil {
+				variantNames[sess.VariantID] = v.Name
+			}
+		}
+
+		base := []string{
+			sess.ScheduledDate.Format("2006-01-02"),
+			sess.ScheduledTime,
+			groupNames[sess.GroupID],
+			variantNames[sess.VariantID],
+			sess.Status,
+		}
+
+		logs, err := s.store.GetExerciseLogs(sess.ID)
+		if err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			if err := wr.Write(append(base, "", "", "", "", "")); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, l := range logs {
+			weight := ""
+			if l.WeightKg != nil {
+				weight = fmt.Sprintf("%.1f", *l.WeightKg)
+			}
+			row := append(append([]string{}, base...),
+				l.ExerciseName,
+				formatOptionalInt(l.SetsCompleted),
+				formatOptionalInt(l.RepsCompleted),
+				weight,
+				l.Status,
+			)
+			if err := wr.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	wr.Flush()
+	return wr.Error()
+}
+
+// parseExportRange reads the export window: ?from=&to= dates (YYYY-MM-DD, both days
+// included) take precedence over a ?days= lookback. Zero times leave that end open.
+func parseExportRange(r *http.Request) (since, until time.Time, err error) {
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !from.IsZero() || !to.IsZero() {
+		if !to.IsZero() {
+			// parseDateRange returns the start of the day after to
+			to = to.Add(-time.Nanosecond)
+		}
+		return from, to, nil
+	}
+
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if days, err := strconv.Atoi(dStr); err == nil && days > 0 {
+			since = time.Now().AddDate(0, 0, -days)
+		}
+	}
+	return since, time.Time{}, nil
+}
+
+// wantsAnonymized reports whether the export was requested with ?anonymize=true
+func wantsAnonymized(r *http.Request) bool {
+	return r.URL.Query().Get("anonymize") == "true"
+}
+
+// coarsenToHour drops minutes and seconds so exact measurement times are not shared
+func coarsenToHour(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+// The anonymize* helpers return copies with notes and tags removed and timestamps
+// rounded down to the hour, keeping only the clinical values.
+
+func anonymizeBPReadings(readings []store.BloodPressure) []store.BloodPressure {
+	out := make([]store.BloodPressure, len(readings))
+	for i, bp := range readings {
+		bp.MeasuredAt = coarsenToHour(bp.MeasuredAt)
+		bp.Notes = ""
+		bp.Tag = ""
+		out[i] = bp
+	}
+	return out
+}
+
+func anonymizeWeightLogs(logs []store.WeightLog) []store.WeightLog {
+	out := make([]store.WeightLog, len(logs))
+	for i, l := range logs {
+		l.MeasuredAt = coarsenToHour(l.MeasuredAt)
+		l.Notes = ""
+		l.Tag = ""
+		out[i] = l
+	}
+	return out
+}
+
+func anonymizeIntakes(intakes []store.IntakeWithMedication) []store.IntakeWithMedication {
+	out := make([]store.IntakeWithMedication, len(intakes))
+	for i, in := range intakes {
+		in.ScheduledAt = coarsenToHour(in.ScheduledAt)
+		if in.TakenAt != nil {
+			t := coarsenToHour(*in.TakenAt)
+			in.TakenAt = &t
+		}
+		in.Notes = ""
+		out[i] = in
+	}
+	return out
+}
+
+func anonymizeSleepLogs(logs []store.SleepLog) []store.SleepLog {
+	out := make([]store.SleepLog, len(logs))
+	for i, l := range logs {
+		l.StartTime = coarsenToHour(l.StartTime)
+		l.EndTime = coarsenToHour(l.EndTime)
+		l.Notes = ""
+		out[i] = l
+	}
+	return out
+}
+
+func anonymizeGlucoseLogs(logs []store.GlucoseLog) []store.GlucoseLog {
+	out := make([]store.GlucoseLog, len(logs))
+	for i, g := range logs {
+		g.MeasuredAt = coarsenToHour(g.MeasuredAt)
+		g.Notes = ""
+		out[i] = g
+	}
+	return out
+}
+
+func anonymizeSymptomLogs(logs []store.SymptomLog) []store.SymptomLog {
+	out := make([]store.SymptomLog, len(logs))
+	for i, l := range logs {
+		l.MeasuredAt = coarsenToHour(l.MeasuredAt)
+		l.Notes = ""
+		out[i] = l
+	}
+	return out
+}
+
+func anonymizeLabResults(results []store.LabResult) []store.LabResult {
+	out := make([]store.LabResult, len(results))
+	for i, l := range results {
+		l.MeasuredAt = coarsenToHour(l.MeasuredAt)
+		l.Notes = ""
+		out[i] = l
+	}
+	return out
+}
+
+func anonymizeLifeEvents(events []store.LifeEvent) []store.LifeEvent {
+	out := make([]store.LifeEvent, len(events))
+	for i, e := range events {
+		e.Note = ""
+		out[i] = e
+	}
+	return out
+}
+
+func formatOptionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func formatOptionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+func formatOptionalDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestHandleExportAllZip(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	ctx := context.Background()
+	now := time.Now()
+
+	medID, _ := db.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	intakeID, _ := db.CreateIntake(medID, userID, now.Add(-time.Hour))
+	db.ConfirmIntake(intakeID, now)
+	db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: userID, MeasuredAt: now, Systolic: 120, Diastolic: 80})
+	db.CreateWeightLog(ctx, &store.WeightLog{UserID: userID, MeasuredAt: now, Weight: 80})
+	total := 420
+	db.ImportSleepLogs(ctx, userID, []store.SleepLog{{StartTime: now.Add(-8 * time.Hour), EndTime: now, Day: now.Format("2006-01-02"), TotalMinutes: &total}})
+	db.CreateGlucoseLog(ctx, &store.GlucoseLog{UserID: userID, MeasuredAt: now, ValueMgdl: 105})
+	temp := 38.2
+	db.CreateSymptomLog(ctx, &store.SymptomLog{UserID: userID, MeasuredAt: now, Temperature: &temp, Symptoms: "cough"})
+	db.CreateLabResult(ctx, &store.LabResult{UserID: userID, Name: "HbA1c", Value: 5.8, Unit: "%", MeasuredAt: now})
+	db.CreateLifeEvent(ctx, &store.LifeEvent{UserID: userID, Title: "Started new job", Date: now.Format("2006-01-02")})
+	group, _ := db.CreateWorkoutGroup("Strength", "", false, userID, "[1]", "09:00", 15)
+	variant, _ := db.CreateWorkoutVariant(group.ID, "Default", nil, "")
+	session, _ := db.CreateWorkoutSession(group.ID, variant.ID, userID, now, "09:00")
+	db.LogExercise(session.ID, 0, "Squat", nil, nil, nil, "completed", "")
+
+	req := httptest.NewRequest("GET", "/api/export/all.zip", nil)
+	req = withUser(req, userID)
+	w := httptest.NewRecorder()
+	srv.handleExportAllZip(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("Expected application/zip, got %s", ct)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("Failed to open zip: %v", err)
+	}
+
+	expectedHeaders := map[string]string{
+		"medications.csv":    "ID",
+		"intakes.csv":        "Scheduled At",
+		"blood_pressure.csv": "Date",
+		"weight.csv":         "#Version: 6",
+		"sleep.csv":          "Day",
+		"glucose.csv":        "Date",
+		"symptoms.csv":       "Date",
+		"labs.csv":           "Date",
+		"events.csv":         "Date",
+		"workouts.csv":       "Date",
+	}
+
+	found := make(map[string]bool)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			// Libra weight format has rows of differing width
+			r := csv.NewReader(bytes.NewReader(data))
+			r.FieldsPerRecord = -1
+			records, err = r.ReadAll()
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", f.Name, err)
+			}
+		}
+		if len(records) < 2 {
+			t.Errorf("Expected header and data rows in %s, got %d rows", f.Name, len(records))
+			continue
+		}
+		if want, ok := expectedHeaders[f.Name]; ok && records[0][0] != want {
+			t.Errorf("Expected %s header to start with %q, got %q", f.Name, want, records[0][0])
+		}
+		found[f.Name] = true
+	}
+
+	for name := range expectedHeaders {
+		if !found[name] {
+			t.Errorf("Expected %s in zip", name)
+		}
+	}
+}
+
+func TestHandleExportSummaryZip(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	ctx := context.Background()
+	now := time.Now()
+
+	medID, _ := db.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	taken, _ := db.CreateIntake(medID, userID, now.Add(-26*time.Hour))
+	db.ConfirmIntake(taken, now.Add(-26*time.Hour))
+	missed, _ := db.CreateIntake(medID, userID, now.Add(-2*time.Hour))
+	db.MarkIntakeMissed(missed, "")
+	db.CreateIntake(medID, userID, now.AddDate(0, 0, -40)) // Before the period
+	pulse := 70
+	db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: userID, MeasuredAt: now.Add(-3 * time.Hour), Systolic: 130, Diastolic: 84, Pulse: &pulse})
+	db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: userID, MeasuredAt: now.Add(-2 * time.Hour), Systolic: 126, Diastolic: 80})
+	db.CreateWeightLog(ctx, &store.WeightLog{UserID: userID, MeasuredAt: now.AddDate(0, 0, -40), Weight: 80}) // Before the period
+
+	req := withUser(httptest.NewRequest("GET", "/api/export/all?days=30", nil), userID)
+	w := httptest.NewRecorder()
+	srv.handleExportSummaryZip(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("Expected application/zip, got %s", ct)
+	}
+	wantDisposition := "attachment; filename=medtracker_summary_" + now.Format("2006-01-02") + ".zip"
+	if cd := w.Header().Get("Content-Disposition"); cd != wantDisposition {
+		t.Errorf("Expected %q, got %q", wantDisposition, cd)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("Failed to open zip: %v", err)
+	}
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(data)
+	}
+
+	// Weight and sleep have nothing in the period and are left out
+	if len(contents) != 3 || contents["intakes.csv"] == "" || contents["blood_pressure.csv"] == "" {
+		t.Fatalf("Expected intakes, blood pressure and summary only, got %v", zr.File)
+	}
+	if records, _ := csv.NewReader(strings.NewReader(contents["intakes.csv"])).ReadAll(); len(records) != 3 {
+		t.Errorf("Expected header and 2 intakes from the period, got %d rows", len(records))
+	}
+
+	summary := contents["summary.txt"]
+	for _, want := range []string{
+		"Medications: 1 of 2 doses taken (50.0%), 1 missed",
+		"Blood pressure: average 128/82, pulse 70 (2 readings)",
+		"Weight: no entries",
+		"Sleep: no entries",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", want, summary)
+		}
+	}
+
+	req = withUser(httptest.NewRequest("GET", "/api/export/all?days=0", nil), userID)
+	w = httptest.NewRecorder()
+	srv.handleExportSummaryZip(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for days=0, got %d", w.Code)
+	}
+}
+
+func TestHandleExportSleep(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	ctx := context.Background()
+	start := time.Now().Add(-24 * time.Hour).Truncate(time.Minute).UTC()
+	end := start.Add(7*time.Hour + 30*time.Minute)
+	total, deep, rem, light, awake, hr, spo2 := 450, 90, 100, 240, 20, 58, 96
+	db.ImportSleepLogs(ctx, userID, []store.SleepLog{{
+		StartTime: start, EndTime: end, Day: end.Format("2006-01-02"),
+		TotalMinutes: &total, DeepMinutes: &deep, REMMinutes: &rem, LightMinutes: &light, AwakeMinutes: &awake,
+		HeartRateAvg: &hr, SpO2Avg: &spo2, Notes: "late coffee",
+	}})
+	// Outside the requested window
+	old := time.Now().AddDate(0, 0, -30)
+	db.ImportSleepLogs(ctx, userID, []store.SleepLog{{StartTime: old, EndTime: old.Add(8 * time.Hour), Day: old.Format("2006-01-02")}})
+
+	req := httptest.NewRequest("GET", "/api/sleep/export?days=7", nil)
+	req = withUser(req, userID)
+	w := httptest.NewRecorder()
+	srv.handleExportSleep(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Expected text/csv, got %s", ct)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected header and 1 row, got %d records", len(records))
+	}
+	want := []string{end.Format("2006-01-02"), start.Format(time.RFC3339), end.Format(time.RFC3339), "450", "90", "240", "100", "20", "58", "96", "late coffee"}
+	got := records[1]
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d columns, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Column %q: expected %q, got %q", records[0][i], want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// glucoseResponse adds the value in the user's preferred unit to a stored reading
+type glucoseResponse struct {
+	store.GlucoseLog
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+func newGlucoseResponse(g store.GlucoseLog, unit string) glucoseResponse {
+	return glucoseResponse{GlucoseLog: g, Value: store.GlucoseFromMgdl(g.ValueMgdl, unit), Unit: unit}
+}
+
+// handleCreateGlucose logs a reading. The value is interpreted in ?unit / "unit" if given,
+// otherwise in the preferred unit.
+func (s *Server) handleCreateGlucose(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	var req struct {
+		MeasuredAt  time.Time `json:"measured_at"`
+		Value       float64   `json:"value"`
+		Unit        string    `json:"unit,omitempty"`
+		MealContext string    `json:"meal_context,omitempty"`
+		Notes       string    `json:"notes,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	preferred, err := s.store.GetGlucoseUnit()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	unit := preferred
+	if req.Unit != "" {
+		if unit, err = store.ParseGlucoseUnit(req.Unit); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Value <= 0 {
+		http.Error(w, "Value must be positive", http.StatusBadRequest)
+		return
+	}
+	if !store.ValidMealContext(req.MealContext) {
+		http.Error(w, "Invalid meal_context, expected one of: "+strings.Join(store.MealContexts, ", "), http.StatusBadRequest)
+		return
+	}
+	if req.MeasuredAt.IsZero() {
+		req.MeasuredAt = time.Now()
+	}
+
+	g := store.GlucoseLog{
+		UserID:      userID,
+		MeasuredAt:  req.MeasuredAt,
+		ValueMgdl:   store.GlucoseToMgdl(req.Value, unit),
+		MealContext: req.MealContext,
+		Notes:       req.Notes,
+	}
+
+	id, err := s.store.CreateGlucoseLog(r.Context(), &g)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g.ID = id
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newGlucoseResponse(g, preferred))
+}
+
+func (s *Server) handleListGlucose(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	// Parse query params
+	days := 30 // Default
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if d, err := strconv.Atoi(dStr); err == nil {
+			days = d
+		}
+	}
+
+	var since time.Time
+	if days > 0 {
+		since = time.Now().AddDate(0, 0, -days)
+	}
+
+	limit := 100 // Default
+	if lStr := r.URL.Query().Get("limit"); lStr != "" {
+		if l, err := strconv.Atoi(lStr); err == nil {
+			limit = l
+		}
+	}
+
+	unit, err := s.store.GetGlucoseUnit()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logs, err := s.store.GetGlucoseLogs(r.Context(), userID, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if limit > 0 && len(logs) > limit {
+		logs = logs[:limit]
+	}
+
+	resp := make([]glucoseResponse, 0, len(logs))
+	for _, g := range logs {
+		resp = append(resp, newGlucoseResponse(g, unit))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handleDeleteGlucose(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.DeleteGlucoseLog(r.Context(), id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Reading not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGetGlucoseStats returns averages and target range shares for the last ?days= days (default 30)
+func (s *Server) handleGetGlucoseStats(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	days := 30
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if d, err := strconv.Atoi(dStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	unit, err := s.store.GetGlucoseUnit()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	stats, err := s.store.GetGlucoseStats(r.Context(), userID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Present the values in the preferred unit; percentages stay as they are
+	stats.Average = store.GlucoseFromMgdl(stats.Average, unit)
+	stats.Min = store.GlucoseFromMgdl(stats.Min, unit)
+	stats.Max = store.GlucoseFromMgdl(stats.Max, unit)
+	if stats.FastingAvg != nil {
+		v := store.GlucoseFromMgdl(*stats.FastingAvg, unit)
+		stats.FastingAvg = &v
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":  days,
+		"unit":  unit,
+		"stats": stats,
+	})
+}
+
+func (s *Server) handleExportGlucose(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	// Parse query params
+	var since time.Time
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if days, err := strconv.Atoi(dStr); err == nil && days > 0 {
+			since = time.Now().AddDate(0, 0, -days)
+		}
+	}
+
+	logs, err := s.store.GetGlucoseLogs(r.Context(), userID, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsAnonymized(r) {
+		logs = anonymizeGlucoseLogs(logs)
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=glucose_export.csv")
+
+	if err := writeGlucoseCSV(w, logs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeGlucoseCSV writes glucose readings as CSV in both units
+func writeGlucoseCSV(out io.Writer, logs []store.GlucoseLog) error {
+	wr := csv.NewWriter(out)
+	header := []string{"Date", "Glucose (mg/dL)", "Glucose (mmol/L)", "Meal Context", "Notes"}
+	if err := wr.Write(header); err != nil {
+		return err
+	}
+
+	for _, g := range logs {
+		notes := strings.ReplaceAll(g.Notes, "\n", " ")
+		notes = strings.ReplaceAll(notes, "\r", "")
+
+		row := []string{
+			g.MeasuredAt.Format(time.RFC3339),
+			fmt.Sprintf("%.0f", g.ValueMgdl),
+			fmt.Sprintf("%.1f", store.GlucoseFromMgdl(g.ValueMgdl, store.GlucoseUnitMmol)),
+			g.MealContext,
+			notes,
+		}
+		if err := wr.Write(row); err != nil {
+			return err
+		}
+	}
+
+	wr.Flush()
+	return wr.Error()
+}
+
+func (s *Server) handleGetGlucoseUnit(w http.ResponseWriter, r *http.Request) {
+	unit, err := s.store.GetGlucoseUnit()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"unit": unit,
+	})
+}
+
+// handleUpdateGlucoseUnit sets whether glucose values are shown in mg/dL or mmol/L
+func (s *Server) handleUpdateGlucoseUnit(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Unit string `json:"unit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.SetGlucoseUnit(req.Unit); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	unit, err := s.store.GetGlucoseUnit()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "ok",
+		"unit":   unit,
+	})
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestHandleGlucose(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+
+	if err := db.SetGlucoseUnit(store.GlucoseUnitMmol); err != nil {
+		t.Fatalf("SetGlucoseUnit failed: %v", err)
+	}
+
+	// Preferred unit (mmol/L) is used when none is given
+	body, _ := json.Marshal(map[string]interface{}{"value": 5.5, "meal_context": "fasting"})
+	req := withUser(httptest.NewRequest("POST", "/api/glucose", bytes.NewReader(body)), userID)
+	w := httptest.NewRecorder()
+	srv.handleCreateGlucose(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// Explicit mg/dL
+	body, _ = json.Marshal(map[string]interface{}{"value": 150, "unit": "mgdl", "measured_at": time.Now().Add(-time.Hour)})
+	req = withUser(httptest.NewRequest("POST", "/api/glucose", bytes.NewReader(body)), userID)
+	w = httptest.NewRecorder()
+	srv.handleCreateGlucose(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// Invalid meal context
+	body, _ = json.Marshal(map[string]interface{}{"value": 100, "meal_context": "brunch"})
+	req = withUser(httptest.NewRequest("POST", "/api/glucose", bytes.NewReader(body)), userID)
+	w = httptest.NewRecorder()
+	srv.handleCreateGlucose(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid context, got %d", w.Code)
+	}
+
+	req = withUser(httptest.NewRequest("GET", "/api/glucose", nil), userID)
+	w = httptest.NewRecorder()
+	srv.handleListGlucose(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var list []glucoseResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 readings, got %d", len(list))
+	}
+	if list[0].ValueMgdl != 99 || list[0].Value != 5.5 || list[0].Unit != store.GlucoseUnitMmol {
+		t.Errorf("Unexpected newest reading: %+v", list[0])
+	}
+	if list[1].ValueMgdl != 150 || list[1].Value != 8.3 {
+		t.Errorf("Expected 150 mg/dL shown as 8.3 mmol/L, got %+v", list[1])
+	}
+
+	req = withUser(httptest.NewRequest("DELETE", fmt.Sprintf("/api/glucose/%d", list[0].ID), nil), userID)
+	req.SetPathValue("id", fmt.Sprint(list[0].ID))
+	w = httptest.NewRecorder()
+	srv.handleDeleteGlucose(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 on delete, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	srv.handleDeleteGlucose(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 on second delete, got %d", w.Code)
+	}
+
+	logs, _ := db.GetGlucoseLogs(context.Background(), userID, time.Time{})
+	if len(logs) != 1 {
+		t.Errorf("Expected 1 reading left, got %d", len(logs))
+	}
+}
@@ -2,15 +2,11 @@ package server
 
 import (
 	"context"
-	"crypto/hmac"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -125,57 +121,7 @@ func (s *Server) handleGoogleCallback(w http.ResponseWriter, r *http.Request) {
 	// We'll trust this cookie in auth middleware.
 
 	// Just use the email as session value, signed with bot token to prevent tampering
-	sessionValue := createSessionToken(userInfo.Email, s.botToken)
-	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_session",
-		Value:    sessionValue,
-		Expires:  time.Now().Add(24 * time.Hour * 30), // 30 days
-		HttpOnly: true,
-		Secure:   true,                    // Only send over HTTPS
-		SameSite: http.SameSiteLaxMode,    // CSRF protection
-		Path:     "/",
-	})
+	setSessionCookie(w, userInfo.Email, s.botToken)
 
 	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 }
-
-func createSessionToken(email, secret string) string {
-	// Simple signature: base64(email) + "." + hmac(email, secret)
-	// This is effectively a JWT-lite without the library overhead, adequate for this restricted scope
-	// But let's keep it simpler and just string concatenation verification in middleware
-	// Actually, let's just use the email. Since it's HttpOnly, JS can't read it.
-	// But user can modify it. So we need signature.
-
-	// Let's implement signature in auth.go or here
-	// Re-using ValidateWebAppData logic is not fit here.
-
-	// Simple HMAC
-	h := hmac.New(sha256.New, []byte(secret))
-	h.Write([]byte(email))
-	sig := hex.EncodeToString(h.Sum(nil))
-
-	return base64.URLEncoding.EncodeToString([]byte(email)) + "." + sig
-}
-
-func verifySessionToken(token, secret string) (string, bool) {
-	parts := strings.Split(token, ".")
-	if len(parts) != 2 {
-		return "", false
-	}
-
-	emailBytes, err := base64.URLEncoding.DecodeString(parts[0])
-	if err != nil {
-		return "", false
-	}
-	email := string(emailBytes)
-
-	h := hmac.New(sha256.New, []byte(secret))
-	h.Write([]byte(email))
-	expectedSig := hex.EncodeToString(h.Sum(nil))
-
-	if parts[1] != expectedSig {
-		return "", false
-	}
-
-	return email, true
-}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// recentInjections is how many logged injections handleGetInjectionSites returns
+const recentInjections = 10
+
+// handleGetInjectionSites returns a medication's site rotation, the suggested next site
+// and its most recent injections
+func (s *Server) handleGetInjectionSites(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+
+	s.writeInjectionSites(w, med.ID)
+}
+
+// handleSetInjectionSites replaces a medication's site rotation with {"sites": [...]};
+// an empty list stops tracking sites
+func (s *Server) handleSetInjectionSites(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Sites []string `json:"sites"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.store.SetInjectionSites(med.ID, req.Sites); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.writeInjectionSites(w, med.ID)
+}
+
+func (s *Server) writeInjectionSites(w http.ResponseWriter, medID int64) {
+	sites, err := s.store.GetInjectionSites(medID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	next, err := s.store.NextInjectionSite(medID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recent, err := s.store.GetInjectionLog(medID, recentInjections)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"medication_id": medID,
+		"sites":         sites,
+		"next_site":     next,
+		"recent":        recent,
+	})
+}
+
+// overrideInjectionSite records the site the user actually used for a just-confirmed
+// intake in place of the suggested one. Medications without a site rotation are left alone.
+func (s *Server) overrideInjectionSite(intake *store.IntakeLog, site string, injectedAt time.Time) {
+	site = strings.TrimSpace(site)
+	if site == "" {
+		return
+	}
+	sites, err := s.store.GetInjectionSites(intake.MedicationID)
+	if err != nil {
+		log.Printf("Error getting injection sites for med %d: %v", intake.MedicationID, err)
+		return
+	}
+	if len(sites) == 0 {
+		return
+	}
+	if err := s.store.RecordInjection(intake.ID, site, injectedAt); err != nil {
+		log.Printf("Error recording injection site for intake %d: %v", intake.ID, err)
+	}
+}
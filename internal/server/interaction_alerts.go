@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/korjavin/medicationtrackerbot/internal/rxnorm"
+)
+
+// interactionWarning checks the active medications for interactions after rxcui was
+// added or reactivated and returns the warning for the response. Severe interactions
+// involving rxcui are also pushed to Telegram and the browser, once per drug pair.
+func (s *Server) interactionWarning(ctx context.Context, userID int64, rxcui string) string {
+	meds, err := s.store.ListMedications(false) // Only active
+	if err != nil {
+		return ""
+	}
+	var rxcuis []string
+	for _, m := range meds {
+		if m.RxCUI != "" {
+			rxcuis = append(rxcuis, m.RxCUI)
+		}
+	}
+	// Only check if we have > 1 meds totally (the list includes the new one)
+	if len(rxcuis) < 2 {
+		return ""
+	}
+
+	interactions, _ := s.rxnorm.GetInteractions(rxcuis)
+	if len(interactions) == 0 {
+		return ""
+	}
+
+	for _, in := range interactions {
+		if !in.IsSevere() || (in.Rxcui1 != rxcui && in.Rxcui2 != rxcui) {
+			continue
+		}
+		s.alertSevereInteraction(ctx, userID, in)
+	}
+
+	warning := fmt.Sprintf("Interaction between %s and %s: %s", interactions[0].Drug1, interactions[0].Drug2, interactions[0].Description)
+	if len(interactions) > 1 {
+		warning += " (+ " + strconv.Itoa(len(interactions)-1) + " more)"
+	}
+	return warning
+}
+
+// alertSevereInteraction notifies about a severe interaction unless the pair was alerted before
+func (s *Server) alertSevereInteraction(ctx context.Context, userID int64, in rxnorm.Interaction) {
+	isNew, err := s.store.MarkInteractionAlerted(in.Rxcui1, in.Rxcui2)
+	if err != nil {
+		log.Printf("Failed to record interaction alert: %v", err)
+		return
+	}
+	if !isNew {
+		return
+	}
+
+	text := fmt.Sprintf("%s and %s: %s", in.Drug1, in.Drug2, in.Description)
+	if s.bot != nil {
+		if _, err := s.bot.SendSimpleNotification("⚠️ Severe drug interaction\n"+text, nil); err != nil {
+			log.Printf("Failed to send interaction alert: %v", err)
+		}
+	}
+	if s.webPush != nil {
+		if err := s.webPush.SendInteractionAlert(ctx, userID, text); err != nil {
+			log.Printf("Failed to push interaction alert: %v", err)
+		}
+	}
+}
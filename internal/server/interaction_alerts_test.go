@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/bot"
+)
+
+func TestSevereInteractionAlerts(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/sendMessage") {
+			r.ParseForm()
+			mu.Lock()
+			sent = append(sent, r.FormValue("text"))
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer tg.Close()
+
+	rxcuiByName := map[string]string{"Aspirin": "1191", "Vitamin C": "1151"}
+	nameByRxcui := map[string]string{"1191": "aspirin", "1151": "ascorbic acid"}
+	rxnav := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/rxcui.json"):
+			w.Write([]byte(`{"idGroup":{"rxnormId":["` + rxcuiByName[r.URL.Query().Get("name")] + `"]}}`))
+		case strings.HasSuffix(r.URL.Path, "/properties.json"):
+			rxcui := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/rxcui/"), "/properties.json")
+			w.Write([]byte(`{"properties":{"name":"` + nameByRxcui[rxcui] + `"}}`))
+		default:
+			rxcuis := r.URL.Query().Get("rxcuis")
+			var pairs []string
+			if strings.Contains(rxcuis, "1191") {
+				pairs = append(pairs, `{"interactionPair":[{
+					"interactionConcept":[
+						{"minConceptItem":{"name":"aspirin","rxcui":"1191"}},
+						{"minConceptItem":{"name":"warfarin","rxcui":"11289"}}
+					],
+					"severity":"high",
+					"description":"Increased risk of bleeding."
+				}]}`)
+			}
+			if strings.Contains(rxcuis, "1151") {
+				pairs = append(pairs, `{"interactionPair":[{
+					"interactionConcept":[
+						{"minConceptItem":{"name":"ascorbic acid","rxcui":"1151"}},
+						{"minConceptItem":{"name":"warfarin","rxcui":"11289"}}
+					],
+					"severity":"minor",
+					"description":"May slightly reduce warfarin effect."
+				}]}`)
+			}
+			w.Write([]byte(`{"fullInteractionTypeGroup":[{"fullInteractionType":[` + strings.Join(pairs, ",") + `]}]}`))
+		}
+	}))
+	defer rxnav.Close()
+
+	srv, db := createTestServer(t)
+	defer db.Close()
+	srv.rxnorm.SetRESTEndpoint(rxnav.URL)
+	srv.rxnorm.SetInteractionEndpoint(rxnav.URL + "/interaction/list.json")
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	srv.bot = bot.NewWithAPI(api, 123456, db)
+
+	db.CreateMedication("Warfarin", "5mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "11289", "warfarin")
+
+	create := func(name string) string {
+		t.Helper()
+		body := `{"name":"` + name + `","dosage":"1 tab","schedule":"{\"type\":\"daily\",\"times\":[\"08:00\"]}"}`
+		req := withUser(httptest.NewRequest("POST", "/api/medications", strings.NewReader(body)), 123456)
+		w := httptest.NewRecorder()
+		srv.handleCreateMedication(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+	sentCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(sent)
+	}
+
+	// A minor interaction is only a warning in the response
+	if resp := create("Vitamin C"); !strings.Contains(resp, "warfarin") {
+		t.Errorf("Expected a warning about warfarin, got %s", resp)
+	}
+	if n := sentCount(); n != 0 {
+		t.Fatalf("Expected no alert for a minor interaction, got %d", n)
+	}
+
+	// A severe one is pushed proactively
+	create("Aspirin")
+	if n := sentCount(); n != 1 {
+		t.Fatalf("Expected 1 alert for a severe interaction, got %d", n)
+	}
+	mu.Lock()
+	if !strings.Contains(sent[0], "aspirin") || !strings.Contains(sent[0], "bleeding") {
+		t.Errorf("Expected the alert to describe the aspirin interaction, got %q", sent[0])
+	}
+	mu.Unlock()
+
+	// The same pair is not alerted about again
+	create("Aspirin")
+	if n := sentCount(); n != 1 {
+		t.Errorf("Expected no repeat alert for the same pair, got %d alerts", n)
+	}
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// labResultRequest is the body of create and update requests
+type labResultRequest struct {
+	Name          string    `json:"name"`
+	Value         *float64  `json:"value"`
+	Unit          string    `json:"unit,omitempty"`
+	MeasuredAt    time.Time `json:"measured_at"`
+	ReferenceLow  *float64  `json:"reference_low,omitempty"`
+	ReferenceHigh *float64  `json:"reference_high,omitempty"`
+	Notes         string    `json:"notes,omitempty"`
+}
+
+func (req labResultRequest) toResult(userID int64) store.LabResult {
+	if req.MeasuredAt.IsZero() {
+		req.MeasuredAt = time.Now()
+	}
+	l := store.LabResult{
+		UserID:        userID,
+		Name:          strings.TrimSpace(req.Name),
+		Unit:          strings.TrimSpace(req.Unit),
+		MeasuredAt:    req.MeasuredAt,
+		ReferenceLow:  req.ReferenceLow,
+		ReferenceHigh: req.ReferenceHigh,
+		Notes:         req.Notes,
+	}
+	if req.Value != nil {
+		l.Value = *req.Value
+	}
+	return l
+}
+
+// decodeLabResult reads and validates a lab result from the request body, writing a
+// 400 response and returning false if it's unusable
+func decodeLabResult(w http.ResponseWriter, r *http.Request, userID int64) (store.LabResult, bool) {
+	var req labResultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return store.LabResult{}, false
+	}
+	if req.Value == nil {
+		http.Error(w, "value required", http.StatusBadRequest)
+		return store.LabResult{}, false
+	}
+
+	l := req.toResult(userID)
+	if err := l.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return store.LabResult{}, false
+	}
+	return l, true
+}
+
+func (s *Server) handleCreateLabResult(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	l, ok := decodeLabResult(w, r, userID)
+	if !ok {
+		return
+	}
+
+	id, err := s.store.CreateLabResult(r.Context(), &l)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	l.ID = id
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l)
+}
+
+func (s *Server) handleUpdateLabResult(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	l, ok := decodeLabResult(w, r, userID)
+	if !ok {
+		return
+	}
+	l.ID = id
+
+	if err := s.store.UpdateLabResult(r.Context(), &l); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Lab result not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l)
+}
+
+// handleListLabResults returns results between ?from= and ?to= (YYYY-MM-DD, both inclusive),
+// or all of them when no range is given. ?name= limits the list to one test.
+func (s *Server) handleListLabResults(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.store.GetLabResults(r.Context(), userID, strings.TrimSpace(r.URL.Query().Get("name")), from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if results == nil {
+		results = []store.LabResult{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+func (s *Server) handleDeleteLabResult(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.DeleteLabResult(r.Context(), id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Lab result not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeLabResultsCSV writes lab results as CSV
+func writeLabResultsCSV(out io.Writer, results []store.LabResult) error {
+	wr := csv.NewWriter(out)
+	header := []string{"Date", "Name", "Value", "Unit", "Reference Low", "Reference High", "Notes"}
+	if err := wr.Write(header); err != nil {
+		return err
+	}
+
+	for _, l := range results {
+		notes := strings.ReplaceAll(l.Notes, "\n", " ")
+		notes = strings.ReplaceAll(notes, "\r", "")
+
+		row := []string{
+			l.MeasuredAt.Format(time.RFC3339),
+			l.Name,
+			strconv.FormatFloat(l.Value, 'f', -1, 64),
+			l.Unit,
+			formatOptionalFloat(l.ReferenceLow),
+			formatOptionalFloat(l.ReferenceHigh),
+			notes,
+		}
+		if err := wr.Write(row); err != nil {
+			return err
+		}
+	}
+
+	wr.Flush()
+	return wr.Error()
+}
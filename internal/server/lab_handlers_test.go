@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestHandleLabResults(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+
+	create := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		req := withUser(httptest.NewRequest("POST", "/api/labs", bytes.NewReader(b)), userID)
+		w := httptest.NewRecorder()
+		srv.handleCreateLabResult(w, req)
+		return w
+	}
+
+	w := create(map[string]interface{}{
+		"name":           "LDL",
+		"value":          142,
+		"unit":           "mg/dL",
+		"measured_at":    time.Date(2024, 1, 15, 8, 0, 0, 0, time.Local),
+		"reference_high": 100,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var created store.LabResult
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.ID == 0 || created.Value != 142 || created.ReferenceHigh == nil || *created.ReferenceHigh != 100 {
+		t.Errorf("Unexpected created result: %+v", created)
+	}
+
+	create(map[string]interface{}{"name": "HbA1c", "value": 5.9, "unit": "%", "measured_at": time.Date(2024, 3, 31, 9, 0, 0, 0, time.Local)})
+	create(map[string]interface{}{"name": "LDL", "value": 98, "unit": "mg/dL", "measured_at": time.Date(2024, 4, 1, 9, 0, 0, 0, time.Local)})
+
+	if w := create(map[string]interface{}{"name": "LDL"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a value, got %d", w.Code)
+	}
+	if w := create(map[string]interface{}{"value": 1}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a name, got %d", w.Code)
+	}
+
+	// Both ends of the range are inclusive
+	req := withUser(httptest.NewRequest("GET", "/api/labs?from=2024-01-15&to=2024-03-31", nil), userID)
+	w = httptest.NewRecorder()
+	srv.handleListLabResults(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var results []store.LabResult
+	json.NewDecoder(w.Body).Decode(&results)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results in range, got %d", len(results))
+	}
+	if results[0].Name != "HbA1c" || results[1].Name != "LDL" {
+		t.Errorf("Expected newest first, got %s, %s", results[0].Name, results[1].Name)
+	}
+
+	// Without a range every result is returned; name narrows it to one test
+	req = withUser(httptest.NewRequest("GET", "/api/labs?name=LDL", nil), userID)
+	w = httptest.NewRecorder()
+	srv.handleListLabResults(w, req)
+	results = nil
+	json.NewDecoder(w.Body).Decode(&results)
+	if len(results) != 2 || results[0].Value != 98 {
+		t.Errorf("Expected 2 LDL results with the newest first, got %+v", results)
+	}
+
+	req = withUser(httptest.NewRequest("GET", "/api/labs?from=2024-04-02&to=2024-04-01", nil), userID)
+	w = httptest.NewRecorder()
+	srv.handleListLabResults(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for reversed range, got %d", w.Code)
+	}
+}
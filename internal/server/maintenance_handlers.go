@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// handleGetWALStatus reports the WAL file size and the last checkpoint
+func (s *Server) handleGetWALStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := s.store.GetWALStatus()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleCheckpointWAL runs a PASSIVE WAL checkpoint on demand
+func (s *Server) handleCheckpointWAL(w http.ResponseWriter, r *http.Request) {
+	res, err := s.store.CheckpointWAL(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// handleSchedulerTick runs one scheduler cycle immediately (due intakes, reminders and
+// snoozes) and reports what it did, for debugging without waiting for the tickers
+func (s *Server) handleSchedulerTick(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	if userID != s.allowedUserID {
+		http.Error(w, "Forbidden: admin only", http.StatusForbidden)
+		return
+	}
+	if s.scheduler == nil {
+		http.Error(w, "Scheduler not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	summary, err := s.scheduler.Tick()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -2,164 +2,996 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/rxnorm"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
 )
 
 func (s *Server) handleListMedications(w http.ResponseWriter, r *http.Request) {
 	showArchived := r.URL.Query().Get("archived") == "true"
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != "" && sortBy != "next_dose" {
+		http.Error(w, "Invalid sort (want next_dose)", http.StatusBadRequest)
+		return
+	}
+
 	meds, err := s.store.ListMedications(showArchived)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(meds)
+	if sortBy == "next_dose" {
+		userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+		s.sortByNextDose(userID, meds, time.Now())
+	}
+
+	json.NewEncoder(w).Encode(meds)
+}
+
+// sortByNextDose orders medications by their upcoming dose, soonest first. Medications
+// without one (as-needed, archived or ended) keep their relative order at the end.
+func (s *Server) sortByNextDose(userID int64, meds []store.Medication, now time.Time) {
+	next := make(map[int64]time.Time, len(meds))
+	for i := range meds {
+		if occurrences := s.store.MedicationOccurrences(userID, &meds[i], now, 1); len(occurrences) > 0 {
+			next[meds[i].ID] = occurrences[0]
+		}
+	}
+
+	sort.SliceStable(meds, func(i, j int) bool {
+		a, aOK := next[meds[i].ID]
+		b, bOK := next[meds[j].ID]
+		if aOK != bOK {
+			return aOK
+		}
+		return aOK && a.Before(b)
+	})
+}
+
+// maxDueWindowHours caps the ?hours= window of handleGetMedicationsDue at a week
+const maxDueWindowHours = 7 * 24
+
+// dueLowStockDays is the threshold for the low_stock flag, matching /api/inventory/low
+const dueLowStockDays = 7
+
+// DueMedication is a medication with the doses scheduled inside a window
+type DueMedication struct {
+	MedicationID   int64       `json:"medication_id"`
+	Name           string      `json:"name"`
+	Dosage         string      `json:"dosage"`
+	ScheduledTimes []time.Time `json:"scheduled_times"`
+	InventoryCount *float64    `json:"inventory_count,omitempty"`
+	DaysRemaining  *float64    `json:"days_remaining,omitempty"`
+	LowStock       bool        `json:"low_stock"`
+	OutOfStock     bool        `json:"out_of_stock"`
+}
+
+// handleGetMedicationsDue lists the active medications with a dose in the next ?hours=
+// (default 4), soonest first, with their inventory status for pre-trip checks
+func (s *Server) handleGetMedicationsDue(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	hours := 4
+	if hStr := r.URL.Query().Get("hours"); hStr != "" {
+		h, err := strconv.Atoi(hStr)
+		if err != nil || h <= 0 || h > maxDueWindowHours {
+			http.Error(w, fmt.Sprintf("hours must be between 1 and %d", maxDueWindowHours), http.StatusBadRequest)
+			return
+		}
+		hours = h
+	}
+
+	meds, err := s.store.ListMedications(false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	until := now.Add(time.Duration(hours) * time.Hour)
+	due := []DueMedication{}
+	for _, m := range meds {
+		// Enough occurrences to cover the window even for several doses a day
+		occurrences := s.store.MedicationOccurrences(userID, &m, now, hours/24+2)
+		var times []time.Time
+		for _, t := range occurrences {
+			if t.After(until) {
+				break
+			}
+			times = append(times, t)
+		}
+		if len(times) == 0 {
+			continue
+		}
+
+		due = append(due, DueMedication{
+			MedicationID:   m.ID,
+			Name:           m.Name,
+			Dosage:         m.Dosage,
+			ScheduledTimes: times,
+			InventoryCount: m.InventoryCount,
+			DaysRemaining:  s.store.GetDaysOfStockRemaining(userID, &m),
+			LowStock:       s.store.IsLowOnStock(userID, &m, dueLowStockDays),
+			OutOfStock:     m.InventoryCount != nil && *m.InventoryCount <= 0,
+		})
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].ScheduledTimes[0].Before(due[j].ScheduledTimes[0])
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(due)
+}
+
+// handleGetMedicationsByRxCUI returns the medications coded with an RxCUI, for
+// integrations that reference drugs by code. Unknown codes yield an empty array.
+func (s *Server) handleGetMedicationsByRxCUI(w http.ResponseWriter, r *http.Request) {
+	rxcui := r.PathValue("rxcui")
+	if _, err := strconv.ParseUint(rxcui, 10, 64); err != nil {
+		http.Error(w, "Invalid RxCUI", http.StatusBadRequest)
+		return
+	}
+
+	meds, err := s.store.GetMedicationsByRxCUI(rxcui)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(meds)
+}
+
+// ScheduleConflict describes two medications whose scheduled times clash,
+// either because they interact or because a separation rule is violated.
+type ScheduleConflict struct {
+	MedicationAID   int64  `json:"medication_a_id"`
+	MedicationAName string `json:"medication_a_name"`
+	MedicationBID   int64  `json:"medication_b_id"`
+	MedicationBName string `json:"medication_b_name"`
+	TimeA           string `json:"time_a"`
+	TimeB           string `json:"time_b"`
+	Type            string `json:"type"` // "interaction" or "separation"
+	Severity        string `json:"severity,omitempty"`
+	Description     string `json:"description,omitempty"`
+	RequiredHours   int    `json:"required_hours,omitempty"`
+}
+
+func (s *Server) handleGetScheduleConflicts(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	meds, err := s.store.ListMedications(false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var rxcuis []string
+	for _, m := range meds {
+		if m.RxCUI != "" {
+			rxcuis = append(rxcuis, m.RxCUI)
+		}
+	}
+
+	var interactions []rxnorm.Interaction
+	if len(rxcuis) > 1 {
+		interactions, err = s.rxnorm.GetInteractions(rxcuis)
+		if err != nil {
+			// Separation rules still apply without interaction data
+			log.Printf("Error checking interactions for conflicts: %v", err)
+		}
+	}
+
+	slotTimes, err := s.store.GetSlotTimes(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	conflicts := findScheduleConflicts(meds, slotTimes, interactions, time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conflicts)
+}
+
+func (s *Server) handleGetScheduleSlots(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	slots, err := s.store.GetSlotTimes(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slots)
+}
+
+// handleUpdateScheduleSlots sets slot times, e.g. {"morning": "07:30"}; all meds using the slot follow
+func (s *Server) handleUpdateScheduleSlots(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	var req map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.SetSlotTimes(userID, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slots, err := s.store.GetSlotTimes(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slots)
+}
+
+// handleGetSchedulePreview lists the notifications the scheduler would send over the next ?days= days (default 7)
+func (s *Server) handleGetSchedulePreview(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	days := 7
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		d, err := strconv.Atoi(dStr)
+		if err != nil || d < 1 || d > 31 {
+			http.Error(w, "days must be between 1 and 31", http.StatusBadRequest)
+			return
+		}
+		days = d
+	}
+
+	notifications, err := s.store.PreviewSchedule(userID, time.Now(), days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if notifications == nil {
+		notifications = []store.ScheduledNotification{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":          days,
+		"notifications": notifications,
+	})
+}
+
+// handleValidateSchedule checks a medication schedule before it is saved and returns its
+// normalized form, average daily doses and next dose times, e.g.
+// {"schedule": "{\"type\":\"daily\",\"times\":[\"20:00\",\"08:00\"]}"}
+func (s *Server) handleValidateSchedule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	var req struct {
+		Schedule string `json:"schedule"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Schedule) == "" {
+		http.Error(w, "schedule is required", http.StatusBadRequest)
+		return
+	}
+
+	m := store.Medication{Schedule: req.Schedule}
+	cfg, err := m.ValidSchedule()
+	if err != nil {
+		http.Error(w, "malformed schedule JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg.Normalize()
+
+	normalized, err := json.Marshal(cfg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Daily usage and occurrences need the slot names turned into the user's clock times
+	resolved := *cfg
+	resolved.Times = append([]string{}, cfg.Times...)
+	if len(resolved.Slots) > 0 {
+		slotTimes, err := s.store.GetSlotTimes(userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := resolved.ResolveSlots(slotTimes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"schedule":         cfg,
+		"normalized":       string(normalized),
+		"daily_usage":      resolved.DailyUsage(),
+		"next_occurrences": resolved.NextOccurrences(time.Now(), 5),
+	})
+}
+
+// findScheduleConflicts pairs up scheduled medications sharing a day and flags
+// identical slots of interacting medications as well as slots closer together
+// than either medication's separate_hours rule.
+func findScheduleConflicts(meds []store.Medication, slotTimes map[string]string, interactions []rxnorm.Interaction, now time.Time) []ScheduleConflict {
+	type scheduled struct {
+		med   store.Medication
+		sched *store.ScheduleConfig
+		days  map[int]bool
+	}
+
+	var active []scheduled
+	for _, m := range meds {
+		if m.Archived || (m.EndDate != nil && m.EndDate.Before(now)) {
+			continue
+		}
+		sched, err := m.ValidSchedule()
+		if err == nil {
+			err = sched.ResolveSlots(slotTimes)
+		}
+		if err != nil || sched.Type == "as_needed" || len(sched.Times) == 0 {
+			continue
+		}
+		days := make(map[int]bool)
+		if sched.Type == "weekly" {
+			for _, d := range sched.Days {
+				days[d] = true
+			}
+		} else {
+			for d := 0; d < 7; d++ {
+				days[d] = true
+			}
+		}
+		active = append(active, scheduled{med: m, sched: sched, days: days})
+	}
+
+	byPair := make(map[string]rxnorm.Interaction)
+	for _, in := range interactions {
+		byPair[in.Rxcui1+"+"+in.Rxcui2] = in
+		byPair[in.Rxcui2+"+"+in.Rxcui1] = in
+	}
+
+	conflicts := []ScheduleConflict{}
+	for i := 0; i < len(active); i++ {
+		for j := i + 1; j < len(active); j++ {
+			a, b := active[i], active[j]
+
+			sharesDay := false
+			for d := range a.days {
+				if b.days[d] {
+					sharesDay = true
+					break
+				}
+			}
+			if !sharesDay {
+				continue
+			}
+
+			interaction, interacts := byPair[a.med.RxCUI+"+"+b.med.RxCUI]
+			interacts = interacts && a.med.RxCUI != "" && b.med.RxCUI != ""
+
+			separate := a.sched.SeparateHours
+			if b.sched.SeparateHours > separate {
+				separate = b.sched.SeparateHours
+			}
+
+			for _, ta := range a.sched.Times {
+				for _, tb := range b.sched.Times {
+					c := ScheduleConflict{
+						MedicationAID:   a.med.ID,
+						MedicationAName: a.med.Name,
+						MedicationBID:   b.med.ID,
+						MedicationBName: b.med.Name,
+						TimeA:           ta,
+						TimeB:           tb,
+					}
+					if interacts {
+						c.Severity = interaction.Severity
+						c.Description = interaction.Description
+					}
+
+					if ta == tb && interacts {
+						c.Type = "interaction"
+						conflicts = append(conflicts, c)
+						continue
+					}
+
+					if separate > 0 {
+						diff, ok := minutesBetween(ta, tb)
+						if ok && diff < separate*60 {
+							c.Type = "separation"
+							c.RequiredHours = separate
+							conflicts = append(conflicts, c)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// minutesBetween returns the absolute distance in minutes between two "HH:MM" times.
+func minutesBetween(a, b string) (int, bool) {
+	ta, err := time.Parse("15:04", a)
+	if err != nil {
+		return 0, false
+	}
+	tb, err := time.Parse("15:04", b)
+	if err != nil {
+		return 0, false
+	}
+	diff := int(ta.Sub(tb).Minutes())
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff, true
+}
+
+func (s *Server) handleCreateMedication(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string     `json:"name"`
+		Dosage    string     `json:"dosage"`
+		Schedule  string     `json:"schedule"`
+		StartDate *time.Time `json:"start_date"`
+		EndDate   *time.Time `json:"end_date"`
+		// Optional weight-based dosing
+		DoseRatePerKg *float64 `json:"dose_rate_per_kg,omitempty"`
+		DoseUnit      string   `json:"dose_unit,omitempty"`
+		// Optional low, normal (default) or critical
+		Priority string `json:"priority,omitempty"`
+		// Optional units per pack, for pack-aware restocks and stock reports
+		PackSize *int `json:"pack_size,omitempty"`
+		// Optional minutes after which an unconfirmed dose is assumed taken
+		AutoConfirmAfter *int `json:"auto_confirm_after,omitempty"`
+		// Optional units per scheduled dose (default 1)
+		DosePerIntake *int `json:"dose_per_intake,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Priority != "" && !store.ValidPriority(req.Priority) {
+		http.Error(w, "Invalid priority, expected low, normal or critical", http.StatusBadRequest)
+		return
+	}
+	if req.DosePerIntake != nil && (*req.DosePerIntake < 1 || *req.DosePerIntake > store.MaxDoseQuantity) {
+		http.Error(w, fmt.Sprintf("dose_per_intake must be between 1 and %d", store.MaxDoseQuantity), http.StatusBadRequest)
+		return
+	}
+
+	// 1. Search RxNorm
+	rxcui, normalizedName, _ := s.rxnorm.SearchRxNorm(req.Name)
+
+	// 2. Create in DB
+	id, err := s.store.CreateMedication(req.Name, req.Dosage, req.Schedule, req.StartDate, req.EndDate, rxcui, normalizedName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if req.DoseRatePerKg != nil {
+		if err := s.setDoseRate(id, *req.DoseRatePerKg, req.DoseUnit); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.Priority != "" {
+		if err := s.store.SetMedicationPriority(id, req.Priority); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.PackSize != nil {
+		if err := s.setPackSize(id, *req.PackSize); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.AutoConfirmAfter != nil {
+		if err := s.setAutoConfirmAfter(id, *req.AutoConfirmAfter); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.DosePerIntake != nil {
+		if err := s.store.SetDosePerIntake(id, *req.DosePerIntake); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// 3. Check Interactions
+	var warning string
+	if rxcui != "" {
+		userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+		warning = s.interactionWarning(r.Context(), userID, rxcui)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      id,
+		"status":  "created",
+		"warning": warning,
+	})
+}
+
+// handleCheckInteractions resolves a candidate medication name and reports its interactions
+// with the active medications, without saving anything
+func (s *Server) handleCheckInteractions(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	rxcui, normalizedName, _ := s.rxnorm.SearchRxNorm(req.Name)
+
+	interactions := []rxnorm.Interaction{}
+	warnings := []string{}
+	if rxcui != "" {
+		meds, err := s.store.ListMedications(false) // Only active
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rxcuis := []string{rxcui}
+		for _, m := range meds {
+			if m.RxCUI != "" && m.RxCUI != rxcui {
+				rxcuis = append(rxcuis, m.RxCUI)
+			}
+		}
+
+		found, err := s.rxnorm.GetInteractions(rxcuis)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Interactions among the existing medications are not news here
+		for _, in := range found {
+			if in.Rxcui1 != rxcui && in.Rxcui2 != rxcui {
+				continue
+			}
+			interactions = append(interactions, in)
+			warnings = append(warnings, fmt.Sprintf("Interaction between %s and %s: %s", in.Drug1, in.Drug2, in.Description))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":            req.Name,
+		"rxcui":           rxcui,
+		"normalized_name": normalizedName,
+		"interactions":    interactions,
+		"warnings":        warnings,
+	})
+}
+
+func (s *Server) handleUpdateMedication(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Name           string     `json:"name"`
+		Dosage         string     `json:"dosage"`
+		Schedule       string     `json:"schedule"`
+		Archived       bool       `json:"archived"`
+		StartDate      *time.Time `json:"start_date"`
+		EndDate        *time.Time `json:"end_date"`
+		InventoryCount *float64   `json:"inventory_count"`
+		// The count the client's form was loaded with; inventory_count is only a recount
+		// when it differs
+		InventoryCountLoaded *float64 `json:"inventory_count_loaded,omitempty"`
+		// Only applied when present; 0 clears the rate
+		DoseRatePerKg *float64 `json:"dose_rate_per_kg,omitempty"`
+		DoseUnit      string   `json:"dose_unit,omitempty"`
+		// Only applied when present
+		Priority string `json:"priority,omitempty"`
+		// Only applied when present; 0 clears the pack size
+		PackSize *int `json:"pack_size,omitempty"`
+		// Only applied when present; 0 turns auto-confirm off
+		AutoConfirmAfter *int `json:"auto_confirm_after,omitempty"`
+		// Only applied when present
+		DosePerIntake *int `json:"dose_per_intake,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Priority != "" && !store.ValidPriority(req.Priority) {
+		http.Error(w, "Invalid priority, expected low, normal or critical", http.StatusBadRequest)
+		return
+	}
+	if req.DosePerIntake != nil && (*req.DosePerIntake < 1 || *req.DosePerIntake > store.MaxDoseQuantity) {
+		http.Error(w, fmt.Sprintf("dose_per_intake must be between 1 and %d", store.MaxDoseQuantity), http.StatusBadRequest)
+		return
+	}
+
+	// Search RxNorm (Always update on edit to handle renames or missing data)
+	rxcui, normalizedName, _ := s.rxnorm.SearchRxNorm(req.Name)
+
+	// If archiving, clean up pending notifications/intakes
+	if req.Archived {
+		pending, err := s.store.GetPendingIntakesForMedication(id)
+		if err == nil {
+			for _, p := range pending {
+				// 1. Delete Telegram messages
+				msgIDs, err := s.store.GetIntakeReminders(p.ID)
+				if err == nil {
+					for _, msgID := range msgIDs {
+						s.bot.DeleteMessage(msgID)
+					}
+				}
+				// 2. Delete the pending intake
+				s.store.DeleteIntake(p.ID)
+			}
+		} else {
+			log.Printf("Error getting pending intakes for cleanup: %v", err)
+		}
+	}
+
+	// An unchanged count keeps the stored one, which confirmations may have lowered since
+	// the form was loaded; a changed one is logged as a recount
+	inventoryCount := req.InventoryCount
+	if req.InventoryCountLoaded != nil && inventoryCount != nil {
+		if *inventoryCount == *req.InventoryCountLoaded {
+			current, err := s.store.GetMedication(id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if current != nil {
+				inventoryCount = current.InventoryCount
+			}
+		} else if err := s.store.SetInventory(id, inventoryCount); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := s.store.UpdateMedication(id, req.Name, req.Dosage, req.Schedule, req.Archived, req.StartDate, req.EndDate, rxcui, normalizedName, inventoryCount); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if req.DoseRatePerKg != nil {
+		if err := s.setDoseRate(id, *req.DoseRatePerKg, req.DoseUnit); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.Priority != "" {
+		if err := s.store.SetMedicationPriority(id, req.Priority); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.PackSize != nil {
+		if err := s.setPackSize(id, *req.PackSize); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.AutoConfirmAfter != nil {
+		if err := s.setAutoConfirmAfter(id, *req.AutoConfirmAfter); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.DosePerIntake != nil {
+		if err := s.store.SetDosePerIntake(id, *req.DosePerIntake); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Check interactions if unarchiving OR just updating (e.g. name change might trigger interaction)
+	// Strategy: If active (not archived), check interactions.
+	var warning string
+	if !req.Archived && rxcui != "" {
+		userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+		warning = s.interactionWarning(r.Context(), userID, rxcui)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "updated",
+		"warning": warning,
+	})
+}
+
+const defaultDoseUnit = "mg"
+
+// doseCalcDisclaimer is returned with every calculated dose
+const doseCalcDisclaimer = "Informational only, not a prescription. Always confirm the dose with a doctor or pharmacist."
+
+// handleGetAdherence returns adherence over the last ?days= days (default 30),
+// with misses of critical medications weighted more heavily
+func (s *Server) handleGetAdherence(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 || parsed > 365 {
+			http.Error(w, "days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	stats, err := s.store.GetAdherenceStats(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// handleGetMedicationAdherence reports one medication's adherence over ?days= (default 30)
+// with a per-day breakdown for heatmaps
+func (s *Server) handleGetMedicationAdherence(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 || parsed > 365 {
+			http.Error(w, "days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+
+	report, err := s.store.GetMedicationAdherence(med.ID, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleGetResponseTime returns how long reminders take to be confirmed over ?days= (default 30)
+func (s *Server) handleGetResponseTime(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 || parsed > 365 {
+			http.Error(w, "days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	stats, err := s.store.GetResponseTimeStats(userID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// handleGetAdherenceCalendar returns per-day adherence for the last ?days= days (default 90),
+// shaped for a heatmap
+func (s *Server) handleGetAdherenceCalendar(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	days := 90
+	if d := r.URL.Query().Get("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 || parsed > 365 {
+			http.Error(w, "days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	// Today counts as one of the days
+	calendar, err := s.store.GetDailyAdherence(userID, time.Now().AddDate(0, 0, -(days-1)))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(calendar)
+}
+
+// handleGetBestAdherenceStreak returns the longest run of fully adhered days ever
+func (s *Server) handleGetBestAdherenceStreak(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	streak, err := s.store.GetLongestAdherenceStreak(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(streak)
+}
+
+// setDoseRate stores a weight-based dose rate; a non-positive rate clears it
+func (s *Server) setDoseRate(id int64, ratePerKg float64, unit string) error {
+	if ratePerKg <= 0 {
+		return s.store.SetMedicationDoseRate(id, nil, "")
+	}
+	if unit == "" {
+		unit = defaultDoseUnit
+	}
+	return s.store.SetMedicationDoseRate(id, &ratePerKg, unit)
+}
+
+// setPackSize stores the pack size; zero or less clears it
+func (s *Server) setPackSize(id int64, size int) error {
+	if size <= 0 {
+		return s.store.SetPackSize(id, nil)
+	}
+	return s.store.SetPackSize(id, &size)
 }
 
-func (s *Server) handleCreateMedication(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Name      string     `json:"name"`
-		Dosage    string     `json:"dosage"`
-		Schedule  string     `json:"schedule"`
-		StartDate *time.Time `json:"start_date"`
-		EndDate   *time.Time `json:"end_date"`
+// setAutoConfirmAfter stores the auto-confirm delay in minutes; zero or less turns it off
+func (s *Server) setAutoConfirmAfter(id int64, minutes int) error {
+	if minutes <= 0 {
+		return s.store.SetAutoConfirmAfter(id, nil)
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	return s.store.SetAutoConfirmAfter(id, &minutes)
+}
+
+// handleDoseCalc computes a weight-based dose from the medication's per-kg rate and the latest weight
+func (s *Server) handleDoseCalc(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
 
-	// 1. Search RxNorm
-	rxcui, normalizedName, _ := s.rxnorm.SearchRxNorm(req.Name)
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+	if med.DoseRatePerKg == nil {
+		http.Error(w, "Medication has no weight-based dose rate", http.StatusBadRequest)
+		return
+	}
 
-	// 2. Create in DB
-	id, err := s.store.CreateMedication(req.Name, req.Dosage, req.Schedule, req.StartDate, req.EndDate, rxcui, normalizedName)
+	lastWeight, err := s.store.GetLastWeightLog(r.Context(), userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if lastWeight == nil {
+		http.Error(w, "No weight recorded; log a weight first", http.StatusUnprocessableEntity)
+		return
+	}
 
-	// 3. Check Interactions
-	var warning string
-	if rxcui != "" {
-		meds, err := s.store.ListMedications(false) // Only active
-		if err == nil {
-			var rxcuis []string
-			for _, m := range meds {
-				if m.RxCUI != "" {
-					rxcuis = append(rxcuis, m.RxCUI)
-				}
-			}
-			// Only check if we have > 1 meds totally (since we just added one, list includes it)
-			if len(rxcuis) > 1 {
-				warnings, _ := s.rxnorm.CheckInteractions(rxcuis)
-				if len(warnings) > 0 {
-					warning = warnings[0] // Just take the first one or join them
-					// Maybe join top 3
-					if len(warnings) > 1 {
-						warning += " (+ " + strconv.Itoa(len(warnings)-1) + " more)"
-					}
-				}
-			}
-		}
+	unit := med.DoseUnit
+	if unit == "" {
+		unit = defaultDoseUnit
 	}
+	dose := math.Round(*med.DoseRatePerKg*lastWeight.Weight*100) / 100
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"id":      id,
-		"status":  "created",
-		"warning": warning,
+		"medication_id":      med.ID,
+		"medication_name":    med.Name,
+		"dose_rate_per_kg":   *med.DoseRatePerKg,
+		"dose_unit":          unit,
+		"weight_kg":          lastWeight.Weight,
+		"weight_measured_at": lastWeight.MeasuredAt,
+		"recommended_dose":   dose,
+		"non_prescriptive":   true,
+		"disclaimer":         doseCalcDisclaimer,
 	})
 }
 
-func (s *Server) handleUpdateMedication(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+// handleGetPRNStats reports how often an as-needed medication was used over the last
+// ?days= (default 90), to spot escalating reliance on e.g. a painkiller
+func (s *Server) handleGetPRNStats(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
+	days := 90
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if d, err := strconv.Atoi(dStr); err == nil && d > 0 {
+			days = d
+		}
+	}
 
-	var req struct {
-		Name           string     `json:"name"`
-		Dosage         string     `json:"dosage"`
-		Schedule       string     `json:"schedule"`
-		Archived       bool       `json:"archived"`
-		StartDate      *time.Time `json:"start_date"`
-		EndDate        *time.Time `json:"end_date"`
-		InventoryCount *int       `json:"inventory_count"`
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
 		return
 	}
-
-	// Search RxNorm (Always update on edit to handle renames or missing data)
-	rxcui, normalizedName, _ := s.rxnorm.SearchRxNorm(req.Name)
-
-	// If archiving, clean up pending notifications/intakes
-	if req.Archived {
-		pending, err := s.store.GetPendingIntakesForMedication(id)
-		if err == nil {
-			for _, p := range pending {
-				// 1. Delete Telegram messages
-				msgIDs, err := s.store.GetIntakeReminders(p.ID)
-				if err == nil {
-					for _, msgID := range msgIDs {
-						s.bot.DeleteMessage(msgID)
-					}
-				}
-				// 2. Delete the pending intake
-				s.store.DeleteIntake(p.ID)
-			}
-		} else {
-			log.Printf("Error getting pending intakes for cleanup: %v", err)
-		}
+	var cfg store.ScheduleConfig
+	if err := json.Unmarshal([]byte(med.Schedule), &cfg); err != nil || cfg.Type != "as_needed" {
+		http.Error(w, "Medication is not taken as needed", http.StatusBadRequest)
+		return
 	}
 
-	if err := s.store.UpdateMedication(id, req.Name, req.Dosage, req.Schedule, req.Archived, req.StartDate, req.EndDate, rxcui, normalizedName, req.InventoryCount); err != nil {
+	stats, err := s.store.GetPRNStats(med.ID, days)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Check interactions if unarchiving OR just updating (e.g. name change might trigger interaction)
-	// Strategy: If active (not archived), check interactions.
-	var warning string
-	if !req.Archived {
-		// We have the new RxCUI now
-		if rxcui != "" {
-			meds, err := s.store.ListMedications(false) // Active only
-			if err == nil {
-				var rxcuis []string
-				for _, m := range meds {
-					// We need to exclude the current med from the list fetched from DB
-					// because the DB list technically has the OLD data for this ID if read before commit,
-					// BUT we just committed the update above. So DB list SHOULD have the new data.
-					// Let's rely on ListMedications returning the updated state.
-					if m.RxCUI != "" {
-						rxcuis = append(rxcuis, m.RxCUI)
-					}
-				}
-				if len(rxcuis) > 1 {
-					warnings, _ := s.rxnorm.CheckInteractions(rxcuis)
-					if len(warnings) > 0 {
-						warning = warnings[0]
-						if len(warnings) > 1 {
-							warning += " (+ " + strconv.Itoa(len(warnings)-1) + " more)"
-						}
-					}
-				}
-			}
-		}
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  "updated",
-		"warning": warning,
+		"medication_name": med.Name,
+		"stats":           stats,
 	})
 }
 
@@ -179,6 +1011,20 @@ func (s *Server) handleDeleteMedication(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleGetPendingIntakes lists doses waiting for confirmation, oldest first
+func (s *Server) handleGetPendingIntakes(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	pending, err := s.store.GetPendingIntakesWithMedication(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pending)
+}
+
 func (s *Server) handleUpdateIntake(w http.ResponseWriter, r *http.Request) {
 	userId := r.Context().Value(UserCtxKey).(*TelegramUser).ID
 
@@ -222,16 +1068,17 @@ func (s *Server) handleUpdateIntake(w http.ResponseWriter, r *http.Request) {
 		if up.Status == "PENDING" {
 			// If it was TAKEN, we are reverting.
 			// Inventory increment?
-			if intake.Status == "TAKEN" {
+			// A substituted dose never came out of this medication's stock
+			if intake.Status == "TAKEN" && intake.SubstitutedWith == "" {
 				// Reverting a taken status, so add back to inventory
-				if err := s.store.DecrementInventory(intake.MedicationID, -1); err != nil {
+				if err := s.store.DecrementInventory(intake.MedicationID, -intake.Quantity); err != nil {
 					log.Printf("Error incrementing inventory on revert: %v", err)
 				}
 			}
 		} else if up.Status == "TAKEN" {
 			// If it was PENDING, we are confirming.
 			if intake.Status == "PENDING" {
-				if err := s.store.DecrementInventory(intake.MedicationID, 1); err != nil {
+				if err := s.store.DecrementInventory(intake.MedicationID, intake.Quantity); err != nil {
 					log.Printf("Error decrementing inventory: %v", err)
 				}
 				// Clear reminders?
@@ -246,8 +1093,295 @@ func (s *Server) handleUpdateIntake(w http.ResponseWriter, r *http.Request) {
 
 		if err := s.store.UpdateIntake(up.ID, takenAt, up.Status); err != nil {
 			log.Printf("Error updating intake %d: %v", up.ID, err)
+			continue
+		}
+
+		if up.Status == "TAKEN" && intake.Status != "TAKEN" {
+			s.webhooks.NotifyIntakeConfirmed(userId, up.ID)
 		}
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// Page sizes of a medication's intake history
+const (
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 500
+)
+
+// handleGetMedicationHistory returns a page of one medication's intakes, newest first,
+// with the total matching count. Filters: ?from= and ?to= (YYYY-MM-DD, both inclusive)
+// and ?status= (pending, taken or missed); paging via ?limit= (default 50) and ?offset=.
+func (s *Server) handleGetMedicationHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := store.IntakeHistoryFilter{From: from, To: to, Limit: defaultHistoryPageSize}
+
+	q := r.URL.Query()
+	switch status := strings.ToUpper(q.Get("status")); status {
+	case "":
+	case store.IntakeStatusPending, store.IntakeStatusTaken, store.IntakeStatusMissed:
+		filter.Status = status
+	default:
+		http.Error(w, "Invalid status (want pending, taken or missed)", http.StatusBadRequest)
+		return
+	}
+	if lStr := q.Get("limit"); lStr != "" {
+		l, err := strconv.Atoi(lStr)
+		if err != nil || l < 1 || l > maxHistoryPageSize {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxHistoryPageSize), http.StatusBadRequest)
+			return
+		}
+		filter.Limit = l
+	}
+	if oStr := q.Get("offset"); oStr != "" {
+		o, err := strconv.Atoi(oStr)
+		if err != nil || o < 0 {
+			http.Error(w, "offset must not be negative", http.StatusBadRequest)
+			return
+		}
+		filter.Offset = o
+	}
+
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+
+	intakes, total, err := s.store.GetMedicationIntakeHistory(med.ID, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"medication_id": med.ID,
+		"total":         total,
+		"limit":         filter.Limit,
+		"offset":        filter.Offset,
+		"intakes":       intakes,
+	})
+}
+
+// handleSkipMedicationToday silences one medication's reminders for the rest of today;
+// its schedule and other medications are unaffected and reminders resume tomorrow
+func (s *Server) handleSkipMedicationToday(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+
+	today := time.Now()
+	if err := s.store.SkipMedicationOn(med.ID, today); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":        "ok",
+		"medication_id": med.ID,
+		"skipped_date":  today.Format("2006-01-02"),
+	})
+}
+
+// PillsNeeded is how many units must be picked up to cover a medication's schedule
+// until a date. Inventory is nil when the medication isn't tracking stock, in which
+// case every scheduled dose counts as needed.
+type PillsNeeded struct {
+	MedicationID   int64    `json:"medication_id"`
+	Until          string   `json:"until"`
+	DailyUsage     float64  `json:"daily_usage"`
+	DosesScheduled int      `json:"doses_scheduled"`
+	InventoryCount *float64 `json:"inventory_count"`
+	PillsNeeded    float64  `json:"pills_needed"`
+}
+
+// handleGetPillsNeeded answers ?until=YYYY-MM-DD (inclusive) with the number of units
+// still missing to cover the schedule from now until the end of that day
+func (s *Server) handleGetPillsNeeded(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	untilStr := r.URL.Query().Get("until")
+	until, err := time.ParseInLocation("2006-01-02", untilStr, time.Local)
+	if err != nil {
+		http.Error(w, "Invalid until date (use YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	if until.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)) {
+		http.Error(w, "until must not be in the past", http.StatusBadRequest)
+		return
+	}
+
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.pillsNeeded(userID, med, now, until))
+}
+
+// pillsNeeded counts the units of the doses scheduled from now through the until day and
+// subtracts the current inventory, never going below zero
+func (s *Server) pillsNeeded(userID int64, m *store.Medication, now, until time.Time) PillsNeeded {
+	result := PillsNeeded{
+		MedicationID:   m.ID,
+		Until:          until.Format("2006-01-02"),
+		InventoryCount: m.InventoryCount,
+	}
+	if cfg, err := s.store.ExpandSchedule(userID, m); err == nil {
+		result.DailyUsage = cfg.DailyUsage() * m.Units()
+	}
+
+	end := time.Date(until.Year(), until.Month(), until.Day()+1, 0, 0, 0, 0, until.Location())
+	days := int(end.Sub(now).Hours()/24) + 1
+	for _, t := range s.store.MedicationOccurrences(userID, m, now, days) {
+		if !t.Before(end) {
+			break
+		}
+		result.DosesScheduled++
+	}
+
+	result.PillsNeeded = float64(result.DosesScheduled) * m.Units()
+	if m.InventoryCount != nil {
+		result.PillsNeeded = math.Max(0, result.PillsNeeded-*m.InventoryCount)
+	}
+	return result
+}
+
+// handleGetReconciliation compares the doses scheduled over the last ?days= (default 30)
+// with those confirmed and with how the inventory changed, flagging discrepancies such
+// as missed confirmations or a recount that came up short
+func (s *Server) handleGetReconciliation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	days := 30
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if d, err := strconv.Atoi(dStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	rec, err := s.store.GetReconciliation(userID, med, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// handleGetMedicationInteractions lists every interaction between one medication and the
+// other active medications, untruncated unlike the create/update warning. Medications
+// without an RxCUI have none.
+func (s *Server) handleGetMedicationInteractions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+
+	interactions := []rxnorm.Interaction{}
+	warnings := []string{}
+	if med.RxCUI != "" {
+		meds, err := s.store.ListMedications(false) // Only active
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rxcuis := []string{med.RxCUI}
+		seen := map[string]bool{med.RxCUI: true}
+		for _, m := range meds {
+			if m.RxCUI != "" && !seen[m.RxCUI] {
+				seen[m.RxCUI] = true
+				rxcuis = append(rxcuis, m.RxCUI)
+			}
+		}
+
+		found, err := s.rxnorm.GetInteractions(rxcuis)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Interactions among the other medications belong to their own lists
+		for _, in := range found {
+			if in.Rxcui1 != med.RxCUI && in.Rxcui2 != med.RxCUI {
+				continue
+			}
+			interactions = append(interactions, in)
+			warnings = append(warnings, fmt.Sprintf("Interaction between %s and %s: %s", in.Drug1, in.Drug2, in.Description))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"medication_id": med.ID,
+		"rxcui":         med.RxCUI,
+		"interactions":  interactions,
+		"warnings":      warnings,
+	})
+}
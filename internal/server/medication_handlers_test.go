@@ -5,12 +5,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/korjavin/medicationtrackerbot/internal/rxnorm"
 	"github.com/korjavin/medicationtrackerbot/internal/store"
 )
 
@@ -170,6 +174,63 @@ func TestHandleUpdateMedication(t *testing.T) {
 	}
 }
 
+func TestHandleUpdateMedication_InventoryRecount(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	id, _ := db.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	count := 30.0
+	db.SetInventory(id, &count)
+
+	update := func(fields map[string]interface{}) {
+		t.Helper()
+		reqBody := map[string]interface{}{
+			"name":     "Aspirin",
+			"dosage":   "100mg",
+			"schedule": `{"type":"daily","times":["08:00"]}`,
+		}
+		for k, v := range fields {
+			reqBody[k] = v
+		}
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/medications/%d", id), bytes.NewReader(body))
+		req.SetPathValue("id", fmt.Sprintf("%d", id))
+		w := httptest.NewRecorder()
+		srv.handleUpdateMedication(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+		}
+	}
+	adjustments := func() float64 {
+		t.Helper()
+		med, _ := db.GetMedication(id)
+		rec, err := db.GetReconciliation(123456, med, 7)
+		if err != nil {
+			t.Fatalf("GetReconciliation failed: %v", err)
+		}
+		return rec.Adjustments
+	}
+
+	// A dose is confirmed while the edit form still shows 30
+	db.DecrementInventory(id, 1)
+	update(map[string]interface{}{"inventory_count": 30, "inventory_count_loaded": 30})
+	if med, _ := db.GetMedication(id); med.InventoryCount == nil || *med.InventoryCount != 29 {
+		t.Errorf("Expected the stored count of 29 to be kept, got %v", med.InventoryCount)
+	}
+	if got := adjustments(); got != 0 {
+		t.Errorf("Expected no adjustment for an unchanged count, got %v", got)
+	}
+
+	// Changing the count in the form is a recount
+	update(map[string]interface{}{"inventory_count": 25, "inventory_count_loaded": 30})
+	if med, _ := db.GetMedication(id); med.InventoryCount == nil || *med.InventoryCount != 25 {
+		t.Errorf("Expected the count to be 25, got %v", med.InventoryCount)
+	}
+	if got := adjustments(); got != -4 {
+		t.Errorf("Expected a -4 adjustment, got %v", got)
+	}
+}
+
 func TestHandleDeleteMedication(t *testing.T) {
 	srv, db := createTestServer(t)
 	defer db.Close()
@@ -282,3 +343,1145 @@ func TestHandleUpdateIntake(t *testing.T) {
 		t.Errorf("Expected status PENDING, got %s", intakeReverted.Status)
 	}
 }
+
+func TestHandleGetScheduleConflicts(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	// Mock RxNav interaction API reporting warfarin + aspirin
+	rxnav := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"fullInteractionTypeGroup":[{"fullInteractionType":[{"interactionPair":[{
+			"interactionConcept":[
+				{"minConceptItem":{"name":"warfarin","rxcui":"11289"}},
+				{"minConceptItem":{"name":"aspirin","rxcui":"1191"}}
+			],
+			"severity":"high",
+			"description":"Increased risk of bleeding."
+		}]}]}]}`))
+	}))
+	defer rxnav.Close()
+	srv.rxnorm.SetInteractionEndpoint(rxnav.URL)
+
+	warfarinID, _ := db.CreateMedication("Warfarin", "5mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "11289", "warfarin")
+	aspirinID, _ := db.CreateMedication("Aspirin", "81mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "1191", "aspirin")
+	// Different slot, no interaction: should not be flagged
+	db.CreateMedication("Vitamin D", "1000IU", `{"type":"daily","times":["12:00"]}`, nil, nil, "", "")
+
+	req := withUser(httptest.NewRequest("GET", "/api/medications/conflicts", nil), 123456)
+	w := httptest.NewRecorder()
+	srv.handleGetScheduleConflicts(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	var conflicts []ScheduleConflict
+	if err := json.NewDecoder(w.Body).Decode(&conflicts); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d: %+v", len(conflicts), conflicts)
+	}
+	c := conflicts[0]
+	ids := map[int64]bool{c.MedicationAID: true, c.MedicationBID: true}
+	if !ids[warfarinID] || !ids[aspirinID] {
+		t.Errorf("Expected pair %d/%d, got %d/%d", warfarinID, aspirinID, c.MedicationAID, c.MedicationBID)
+	}
+	if c.TimeA != "08:00" || c.TimeB != "08:00" {
+		t.Errorf("Expected 08:00 slot, got %s/%s", c.TimeA, c.TimeB)
+	}
+	if c.Type != "interaction" || c.Severity != "high" {
+		t.Errorf("Expected high severity interaction, got %s/%s", c.Type, c.Severity)
+	}
+}
+
+func TestFindScheduleConflicts_Separation(t *testing.T) {
+	meds := []store.Medication{
+		{ID: 1, Name: "Levothyroxine", Schedule: `{"type":"daily","times":["07:00"],"separate_hours":4}`},
+		{ID: 2, Name: "Calcium", Schedule: `{"type":"daily","times":["09:00","19:00"]}`},
+		// Weekly on a different day never overlaps
+		{ID: 3, Name: "Iron", Schedule: `{"type":"weekly","days":[1],"times":["07:00"]}`},
+		{ID: 4, Name: "Zinc", Schedule: `{"type":"weekly","days":[2],"times":["07:00"],"separate_hours":2}`},
+	}
+
+	conflicts := findScheduleConflicts(meds, store.DefaultSlotTimes, nil, time.Now())
+
+	var pairs []string
+	for _, c := range conflicts {
+		pairs = append(pairs, fmt.Sprintf("%d-%d@%s/%s", c.MedicationAID, c.MedicationBID, c.TimeA, c.TimeB))
+		if c.Type != "separation" {
+			t.Errorf("Expected separation conflict, got %s", c.Type)
+		}
+	}
+
+	// Levothyroxine vs Calcium 09:00 (2h < 4h), vs Iron and Zinc at 07:00
+	expected := []string{"1-2@07:00/09:00", "1-3@07:00/07:00", "1-4@07:00/07:00"}
+	if strings.Join(pairs, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, pairs)
+	}
+}
+
+func TestHandleConfirmSchedule_WithNote(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	medID, _ := db.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	intakeID, _ := db.CreateIntake(medID, userID, time.Now().Add(-time.Hour))
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"intake_ids": []int64{intakeID},
+		"note":       "took with breakfast",
+	})
+	req := httptest.NewRequest("POST", "/api/medications/confirm-schedule", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), UserCtxKey, &TelegramUser{ID: userID}))
+	w := httptest.NewRecorder()
+	srv.handleConfirmSchedule(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	intake, _ := db.GetIntake(intakeID)
+	if intake.Status != "TAKEN" {
+		t.Errorf("Expected status TAKEN, got %s", intake.Status)
+	}
+	if intake.Notes != "took with breakfast" {
+		t.Errorf("Expected note to be persisted, got %q", intake.Notes)
+	}
+
+	// Note must show up in the intake CSV export
+	intakes, err := db.GetIntakesSince(time.Now().AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatalf("GetIntakesSince: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := writeIntakesCSV(&buf, intakes); err != nil {
+		t.Fatalf("writeIntakesCSV: %v", err)
+	}
+	if !strings.Contains(buf.String(), "took with breakfast") {
+		t.Errorf("Expected note in CSV export, got:\n%s", buf.String())
+	}
+}
+
+func TestHandleDoseCalc(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	medID, _ := db.CreateMedication("Paracetamol", "syrup", `{"type":"as_needed"}`, nil, nil, "", "")
+	rate := 15.0
+	if err := db.SetMedicationDoseRate(medID, &rate, "mg"); err != nil {
+		t.Fatalf("SetMedicationDoseRate: %v", err)
+	}
+
+	doseCalc := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/medications/%d/dose-calc", medID), nil)
+		req.SetPathValue("id", fmt.Sprintf("%d", medID))
+		req = req.WithContext(context.WithValue(req.Context(), UserCtxKey, &TelegramUser{ID: userID}))
+		w := httptest.NewRecorder()
+		srv.handleDoseCalc(w, req)
+		return w
+	}
+
+	// No weight recorded yet
+	if w := doseCalc(); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422 without weight, got %d", w.Code)
+	}
+
+	if _, err := db.CreateWeightLog(context.Background(), &store.WeightLog{
+		UserID:     userID,
+		MeasuredAt: time.Now(),
+		Weight:     18.4,
+	}); err != nil {
+		t.Fatalf("CreateWeightLog: %v", err)
+	}
+
+	w := doseCalc()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		RecommendedDose float64 `json:"recommended_dose"`
+		DoseUnit        string  `json:"dose_unit"`
+		WeightKg        float64 `json:"weight_kg"`
+		NonPrescriptive bool    `json:"non_prescriptive"`
+		Disclaimer      string  `json:"disclaimer"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.RecommendedDose != 276 {
+		t.Errorf("Expected dose 276, got %v", resp.RecommendedDose)
+	}
+	if resp.DoseUnit != "mg" || resp.WeightKg != 18.4 {
+		t.Errorf("Unexpected unit/weight: %s / %v", resp.DoseUnit, resp.WeightKg)
+	}
+	if !resp.NonPrescriptive || resp.Disclaimer == "" {
+		t.Error("Expected response to be labeled non-prescriptive")
+	}
+}
+
+func TestHandleDoseCalc_NoRate(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	medID, _ := db.CreateMedication("Med A", "10mg", "08:00", nil, nil, "", "")
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/medications/%d/dose-calc", medID), nil)
+	req.SetPathValue("id", fmt.Sprintf("%d", medID))
+	req = req.WithContext(context.WithValue(req.Context(), UserCtxKey, &TelegramUser{ID: 123456}))
+	w := httptest.NewRecorder()
+	srv.handleDoseCalc(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for medication without rate, got %d", w.Code)
+	}
+}
+
+func TestHandleConfirmSchedule_DecrementInventoryFlag(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	medID, _ := db.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	stock := 30.0
+	db.SetInventory(medID, &stock)
+
+	confirm := func(intakeID int64, decrement *bool) {
+		t.Helper()
+		payload := map[string]interface{}{"intake_ids": []int64{intakeID}}
+		if decrement != nil {
+			payload["decrement_inventory"] = *decrement
+		}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", "/api/medications/confirm-schedule", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), UserCtxKey, &TelegramUser{ID: userID}))
+		w := httptest.NewRecorder()
+		srv.handleConfirmSchedule(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+	}
+	inventory := func() float64 {
+		t.Helper()
+		med, _ := db.GetMedication(medID)
+		return *med.InventoryCount
+	}
+
+	// Opt out: intake recorded, stock untouched
+	noDecrement := false
+	first, _ := db.CreateIntake(medID, userID, time.Now().Add(-2*time.Hour))
+	confirm(first, &noDecrement)
+
+	intake, _ := db.GetIntake(first)
+	if intake.Status != "TAKEN" {
+		t.Errorf("Expected intake TAKEN, got %s", intake.Status)
+	}
+	if got := inventory(); got != 30 {
+		t.Errorf("Expected inventory unchanged at 30, got %g", got)
+	}
+
+	// Default still decrements
+	second, _ := db.CreateIntake(medID, userID, time.Now().Add(-time.Hour))
+	confirm(second, nil)
+	if got := inventory(); got != 29 {
+		t.Errorf("Expected inventory 29 after default confirm, got %g", got)
+	}
+}
+
+func TestHandleConfirmSchedule_HalfDose(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	medID, _ := db.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	stock := 30.0
+	db.SetInventory(medID, &stock)
+	intakeID, _ := db.CreateIntake(medID, userID, time.Now().Add(-3*time.Hour))
+
+	post := func(payload map[string]interface{}) int {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", "/api/medications/confirm-schedule", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), UserCtxKey, &TelegramUser{ID: userID}))
+		w := httptest.NewRecorder()
+		srv.handleConfirmSchedule(w, req)
+		return w.Code
+	}
+
+	for _, bad := range []float64{0, -1, 21} {
+		if code := post(map[string]interface{}{"intake_ids": []int64{intakeID}, "quantity": bad}); code != http.StatusBadRequest {
+			t.Errorf("quantity %g: expected 400, got %d", bad, code)
+		}
+	}
+
+	if code := post(map[string]interface{}{"intake_ids": []int64{intakeID}, "quantity": 0.5}); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+
+	med, _ := db.GetMedication(medID)
+	if *med.InventoryCount != 29.5 {
+		t.Errorf("Expected inventory 29.5, got %g", *med.InventoryCount)
+	}
+	intake, _ := db.GetIntake(intakeID)
+	if intake.Status != "TAKEN" || intake.Quantity != 0.5 {
+		t.Errorf("Expected TAKEN with quantity 0.5, got %s with %g", intake.Status, intake.Quantity)
+	}
+
+	stats, _ := db.GetAdherenceStats(time.Now().AddDate(0, 0, -1))
+	if stats.Taken != 1 || stats.Missed != 0 {
+		t.Errorf("Expected the half dose to count as taken, got %d taken, %d missed", stats.Taken, stats.Missed)
+	}
+
+	// Reverting puts back exactly what was taken
+	body, _ := json.Marshal(map[string]interface{}{
+		"updates": []map[string]interface{}{{"id": intakeID, "status": "PENDING"}},
+	})
+	req := httptest.NewRequest("POST", "/api/intakes/update", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), UserCtxKey, &TelegramUser{ID: userID}))
+	srv.handleUpdateIntake(httptest.NewRecorder(), req)
+
+	med, _ = db.GetMedication(medID)
+	if *med.InventoryCount != 30 {
+		t.Errorf("Expected inventory restored to 30, got %g", *med.InventoryCount)
+	}
+}
+
+func TestHandleConfirmSchedule_Substitution(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	medID, _ := db.CreateMedication("Brand X", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	stock := 30.0
+	db.SetInventory(medID, &stock)
+	intakeID, _ := db.CreateIntake(medID, userID, time.Now().Add(-2*time.Hour))
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"intake_ids":       []int64{intakeID},
+		"substituted_with": "  Generic Y 10mg ",
+	})
+	req := withUser(httptest.NewRequest("POST", "/api/medications/confirm-schedule", bytes.NewReader(body)), userID)
+	w := httptest.NewRecorder()
+	srv.handleConfirmSchedule(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	intake, _ := db.GetIntake(intakeID)
+	if intake.Status != "TAKEN" || intake.SubstitutedWith != "Generic Y 10mg" {
+		t.Errorf("Expected TAKEN substituted with Generic Y 10mg, got %s with %q", intake.Status, intake.SubstitutedWith)
+	}
+	med, _ := db.GetMedication(medID)
+	if *med.InventoryCount != 30 {
+		t.Errorf("Expected Brand X inventory unchanged at 30, got %g", *med.InventoryCount)
+	}
+
+	var csvBuf bytes.Buffer
+	intakes, _ := db.GetIntakesSince(time.Now().AddDate(0, 0, -1))
+	if err := writeIntakesCSV(&csvBuf, intakes); err != nil {
+		t.Fatalf("writeIntakesCSV: %v", err)
+	}
+	if !strings.Contains(csvBuf.String(), "Substituted With") || !strings.Contains(csvBuf.String(), "Generic Y 10mg") {
+		t.Errorf("Expected substitution in intake export, got:\n%s", csvBuf.String())
+	}
+
+	// Reverting doesn't add a dose back to stock that never left it, and clears the note
+	body, _ = json.Marshal(map[string]interface{}{
+		"updates": []map[string]interface{}{{"id": intakeID, "status": "PENDING"}},
+	})
+	req = withUser(httptest.NewRequest("POST", "/api/intakes/update", bytes.NewReader(body)), userID)
+	srv.handleUpdateIntake(httptest.NewRecorder(), req)
+
+	med, _ = db.GetMedication(medID)
+	if *med.InventoryCount != 30 {
+		t.Errorf("Expected inventory still 30 after revert, got %g", *med.InventoryCount)
+	}
+	intake, _ = db.GetIntake(intakeID)
+	if intake.Status != "PENDING" || intake.SubstitutedWith != "" {
+		t.Errorf("Expected PENDING without substitution, got %s with %q", intake.Status, intake.SubstitutedWith)
+	}
+}
+
+func TestHandleGetPendingIntakes(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	now := time.Now().Truncate(time.Minute)
+	activeID, _ := db.CreateMedication("Lisinopril", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	archivedID, _ := db.CreateMedication("Old Med", "1mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+
+	later, _ := db.CreateIntake(activeID, userID, now.Add(-time.Hour))
+	earlier, _ := db.CreateIntake(activeID, userID, now.Add(-3*time.Hour))
+	taken, _ := db.CreateIntake(activeID, userID, now.Add(-5*time.Hour))
+	db.ConfirmIntake(taken, now)
+	db.CreateIntake(activeID, 999, now.Add(-time.Hour)) // Another user
+	db.CreateIntake(archivedID, userID, now.Add(-time.Hour))
+	db.UpdateMedication(archivedID, "Old Med", "1mg", `{"type":"daily","times":["08:00"]}`, true, nil, nil, "", "", nil)
+
+	req := withUser(httptest.NewRequest("GET", "/api/intakes/pending", nil), userID)
+	w := httptest.NewRecorder()
+	srv.handleGetPendingIntakes(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var pending []store.IntakeWithMedication
+	if err := json.NewDecoder(w.Body).Decode(&pending); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 pending intakes, got %d: %+v", len(pending), pending)
+	}
+	if pending[0].ID != earlier || pending[1].ID != later {
+		t.Errorf("Expected oldest first (%d, %d), got (%d, %d)", earlier, later, pending[0].ID, pending[1].ID)
+	}
+	for _, p := range pending {
+		if p.MedicationName != "Lisinopril" || p.MedicationDosage != "10mg" || p.Status != "PENDING" {
+			t.Errorf("Unexpected pending intake: %+v", p)
+		}
+	}
+	if !pending[0].ScheduledAt.Equal(now.Add(-3 * time.Hour)) {
+		t.Errorf("Expected scheduled_at %s, got %s", now.Add(-3*time.Hour), pending[0].ScheduledAt)
+	}
+}
+
+func TestHandleRestock_Packs(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	medID, _ := db.CreateMedication("Ramipril", "5mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	noPacksID, _ := db.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	size := 30
+	if err := db.SetPackSize(medID, &size); err != nil {
+		t.Fatalf("SetPackSize failed: %v", err)
+	}
+
+	restock := func(id int64, payload string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/medications/"+strconv.FormatInt(id, 10)+"/restock", strings.NewReader(payload))
+		req.SetPathValue("id", strconv.FormatInt(id, 10))
+		w := httptest.NewRecorder()
+		srv.handleRestock(w, req)
+		return w
+	}
+
+	w := restock(medID, `{"packs": 2}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		QuantityAdded  int     `json:"quantity_added"`
+		InventoryCount float64 `json:"inventory_count"`
+		PacksRemaining float64 `json:"packs_remaining"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.QuantityAdded != 60 || resp.InventoryCount != 60 || resp.PacksRemaining != 2 {
+		t.Errorf("Expected 60 units added, 60 in stock and 2 packs, got %+v", resp)
+	}
+
+	if w := restock(noPacksID, `{"packs": 1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 restocking packs without a pack size, got %d", w.Code)
+	}
+	if w := restock(medID, `{"packs": 1, "quantity": 10}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for both packs and quantity, got %d", w.Code)
+	}
+
+	// Two a day: 45 units last 22.5 days, below a 30 day threshold
+	db.DecrementInventory(medID, 15)
+	req := withUser(httptest.NewRequest("GET", "/api/inventory/low?days=30", nil), 123456)
+	w = httptest.NewRecorder()
+	srv.handleGetLowStock(w, req)
+
+	var low []struct {
+		ID             int64    `json:"id"`
+		InventoryCount float64  `json:"inventory_count"`
+		PacksRemaining *float64 `json:"packs_remaining"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&low); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(low) != 1 || low[0].ID != medID {
+		t.Fatalf("Expected only the restocked med to be low, got %+v", low)
+	}
+	if low[0].InventoryCount != 45 || low[0].PacksRemaining == nil || *low[0].PacksRemaining != 1.5 {
+		t.Errorf("Expected 45 units as 1.5 packs, got %+v", low[0])
+	}
+}
+
+func TestHandleDeleteRestock(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	medID, _ := db.CreateMedication("Ramipril", "5mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	otherID, _ := db.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	db.AddRestock(medID, 30, "", nil)
+	db.AddRestock(medID, 300, "typo", nil)
+	db.AddRestock(otherID, 10, "", nil)
+	restocks, _ := db.GetRestockHistory(medID)
+	var typoID int64
+	for _, r := range restocks {
+		if r.Note == "typo" {
+			typoID = r.ID
+		}
+	}
+	otherRestocks, _ := db.GetRestockHistory(otherID)
+
+	del := func(medID, restockID int64) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("DELETE", fmt.Sprintf("/api/medications/%d/restocks/%d", medID, restockID), nil)
+		req.SetPathValue("id", strconv.FormatInt(medID, 10))
+		req.SetPathValue("restockId", strconv.FormatInt(restockID, 10))
+		w := httptest.NewRecorder()
+		srv.handleDeleteRestock(w, req)
+		return w
+	}
+
+	// Another medication's restock can't be deleted through this one
+	if w := del(medID, otherRestocks[0].ID); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another medication's restock, got %d", w.Code)
+	}
+
+	w := del(medID, typoID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		InventoryCount float64 `json:"inventory_count"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.InventoryCount != 30 {
+		t.Errorf("Expected 30 units left, got %v", resp.InventoryCount)
+	}
+	if restocks, _ := db.GetRestockHistory(medID); len(restocks) != 1 {
+		t.Errorf("Expected 1 restock left, got %d", len(restocks))
+	}
+	if w := del(medID, typoID); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting twice, got %d", w.Code)
+	}
+
+	// Units already used can't push the inventory below zero
+	db.DecrementInventory(otherID, 4)
+	w = del(otherID, otherRestocks[0].ID)
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.InventoryCount != 0 {
+		t.Errorf("Expected the inventory clamped at 0, got %v", resp.InventoryCount)
+	}
+}
+
+func TestHandleCheckInteractions(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	rxnav := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/rxcui.json"):
+			w.Write([]byte(`{"idGroup":{"rxnormId":["1191"]}}`))
+		case strings.HasSuffix(r.URL.Path, "/properties.json"):
+			w.Write([]byte(`{"properties":{"name":"aspirin"}}`))
+		default:
+			// Candidate vs warfarin, plus an existing pair that must not be reported
+			w.Write([]byte(`{"fullInteractionTypeGroup":[{"fullInteractionType":[{"interactionPair":[{
+				"interactionConcept":[
+					{"minConceptItem":{"name":"aspirin","rxcui":"1191"}},
+					{"minConceptItem":{"name":"warfarin","rxcui":"11289"}}
+				],
+				"severity":"high",
+				"description":"Increased risk of bleeding."
+			}]},{"interactionPair":[{
+				"interactionConcept":[
+					{"minConceptItem":{"name":"warfarin","rxcui":"11289"}},
+					{"minConceptItem":{"name":"fluconazole","rxcui":"4450"}}
+				],
+				"description":"Increased warfarin levels."
+			}]}]}]}`))
+		}
+	}))
+	defer rxnav.Close()
+	srv.rxnorm.SetRESTEndpoint(rxnav.URL)
+	srv.rxnorm.SetInteractionEndpoint(rxnav.URL + "/interaction/list.json")
+
+	db.CreateMedication("Warfarin", "5mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "11289", "warfarin")
+	db.CreateMedication("Fluconazole", "150mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "4450", "fluconazole")
+
+	before, _ := db.ListMedications(true)
+
+	req := httptest.NewRequest("POST", "/api/medications/check-interactions", strings.NewReader(`{"name":"Aspirin"}`))
+	w := httptest.NewRecorder()
+	srv.handleCheckInteractions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		RxCUI          string   `json:"rxcui"`
+		NormalizedName string   `json:"normalized_name"`
+		Warnings       []string `json:"warnings"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.RxCUI != "1191" || resp.NormalizedName != "aspirin" {
+		t.Errorf("Expected aspirin/1191, got %s/%s", resp.NormalizedName, resp.RxCUI)
+	}
+	if len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "warfarin") {
+		t.Errorf("Expected one warning about warfarin, got %v", resp.Warnings)
+	}
+
+	after, _ := db.ListMedications(true)
+	if len(after) != len(before) {
+		t.Errorf("Expected no medication to be created, had %d now %d", len(before), len(after))
+	}
+}
+
+func TestHandleGetMedicationsByRxCUI(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	db.CreateMedication("Metformin", "1000mg", `{"type":"daily","times":["20:00"]}`, nil, nil, "6809", "metformin")
+	db.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "6809", "metformin")
+	db.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "1191", "aspirin")
+
+	get := func(rxcui string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/medications/by-rxcui/"+rxcui, nil)
+		req.SetPathValue("rxcui", rxcui)
+		w := httptest.NewRecorder()
+		srv.handleGetMedicationsByRxCUI(w, req)
+		return w
+	}
+
+	w := get("6809")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var meds []store.Medication
+	if err := json.NewDecoder(w.Body).Decode(&meds); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(meds) != 2 {
+		t.Fatalf("Expected 2 metformin entries, got %d", len(meds))
+	}
+	for _, m := range meds {
+		if m.RxCUI != "6809" {
+			t.Errorf("Unexpected medication %s (%s)", m.Name, m.RxCUI)
+		}
+	}
+
+	w = get("99999")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != "[]" {
+		t.Errorf("Expected an empty array for an unknown code, got %s", body)
+	}
+
+	if w := get("abc"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a malformed code, got %d", w.Code)
+	}
+}
+
+func TestHandleValidateSchedule(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	db.SetSlotTimes(userID, map[string]string{"bedtime": "22:30"})
+
+	post := func(schedule string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"schedule": schedule})
+		req := withUser(httptest.NewRequest("POST", "/api/schedule/validate", bytes.NewReader(body)), userID)
+		w := httptest.NewRecorder()
+		srv.handleValidateSchedule(w, req)
+		return w
+	}
+
+	type result struct {
+		Schedule        store.ScheduleConfig `json:"schedule"`
+		Normalized      string               `json:"normalized"`
+		DailyUsage      float64              `json:"daily_usage"`
+		NextOccurrences []time.Time          `json:"next_occurrences"`
+	}
+
+	valid := []struct {
+		name        string
+		schedule    string
+		wantUsage   float64
+		wantTimes   []string
+		occurrences int
+	}{
+		{"daily", `{"type":"daily","times":["20:00","08:00","08:00"]}`, 2, []string{"08:00", "20:00"}, 5},
+		{"weekly", `{"type":"weekly","days":[5,1,1],"times":["09:00"]}`, 2.0 / 7, []string{"09:00"}, 5},
+		{"as needed", `{"type":"as_needed"}`, 0, nil, 0},
+		{"legacy time", `08:00`, 1, []string{"08:00"}, 5},
+		{"slot", `{"type":"daily","slots":["bedtime"]}`, 1, nil, 5},
+	}
+	for _, tc := range valid {
+		t.Run(tc.name, func(t *testing.T) {
+			w := post(tc.schedule)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var res result
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if math.Abs(res.DailyUsage-tc.wantUsage) > 1e-9 {
+				t.Errorf("Expected daily usage %g, got %g", tc.wantUsage, res.DailyUsage)
+			}
+			if !reflect.DeepEqual(res.Schedule.Times, tc.wantTimes) {
+				t.Errorf("Expected normalized times %v, got %v", tc.wantTimes, res.Schedule.Times)
+			}
+			if len(res.NextOccurrences) != tc.occurrences {
+				t.Fatalf("Expected %d next occurrences, got %v", tc.occurrences, res.NextOccurrences)
+			}
+			for i, occ := range res.NextOccurrences {
+				if i > 0 && !occ.After(res.NextOccurrences[i-1]) {
+					t.Errorf("Occurrences not ascending: %v", res.NextOccurrences)
+				}
+				if tc.name == "weekly" && occ.Weekday() != time.Monday && occ.Weekday() != time.Friday {
+					t.Errorf("Weekly occurrence on %s", occ.Weekday())
+				}
+				if tc.name == "slot" && occ.Format("15:04") != "22:30" {
+					t.Errorf("Expected slot occurrence at 22:30, got %s", occ.Format("15:04"))
+				}
+			}
+			if tc.name == "weekly" && res.Normalized != `{"type":"weekly","days":[1,5],"times":["09:00"]}` {
+				t.Errorf("Unexpected normalized schedule %s", res.Normalized)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name, schedule, wantErr string
+	}{
+		{"malformed JSON", `{"type":"daily",`, "malformed schedule JSON"},
+		{"bad time", `{"type":"daily","times":["08:00","25:00"]}`, `times[1]: "25:00"`},
+		{"unpadded time", `{"type":"daily","times":["8:00"]}`, `times[0]: "8:00"`},
+		{"bad day", `{"type":"weekly","days":[7],"times":["08:00"]}`, "days[0]: 7 is out of range"},
+		{"weekly without days", `{"type":"weekly","times":["08:00"]}`, "needs at least one day"},
+		{"daily without times", `{"type":"daily"}`, "needs at least one time"},
+		// Interval schedules aren't supported by the scheduler, so they must not pass
+		{"interval", `{"type":"interval","interval_hours":8}`, `unknown schedule type "interval"`},
+		{"missing type", `{"times":["08:00"]}`, "missing schedule type"},
+		{"unknown slot", `{"type":"daily","slots":["brunch"]}`, `unknown schedule slot "brunch"`},
+		{"empty", ``, "schedule is required"},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			w := post(tc.schedule)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tc.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", tc.wantErr, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleGetMedicationsDue(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	now := time.Now()
+	daily := func(at time.Time) string {
+		return fmt.Sprintf(`{"type":"daily","times":["%s"]}`, at.Format("15:04"))
+	}
+	soonID, _ := db.CreateMedication("Soon", "10mg", daily(now.Add(time.Hour)), nil, nil, "", "")
+	laterID, _ := db.CreateMedication("Later", "5mg", daily(now.Add(2*time.Hour)), nil, nil, "", "")
+	db.CreateMedication("Tonight", "20mg", daily(now.Add(6*time.Hour)), nil, nil, "", "")
+	db.CreateMedication("Ibuprofen", "200mg", `{"type":"as_needed"}`, nil, nil, "", "")
+	ended := now.Add(-24 * time.Hour)
+	db.CreateMedication("Finished Course", "1mg", daily(now.Add(time.Hour)), nil, &ended, "", "")
+
+	stock := 3.0
+	db.SetInventory(laterID, &stock)
+
+	req := httptest.NewRequest("GET", "/api/medications/due?hours=4", nil)
+	req = withUser(req, 123456)
+	w := httptest.NewRecorder()
+	srv.handleGetMedicationsDue(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var due []DueMedication
+	if err := json.NewDecoder(w.Body).Decode(&due); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	if len(due) != 2 {
+		t.Fatalf("Expected 2 due medications, got %d: %+v", len(due), due)
+	}
+	if due[0].MedicationID != soonID || due[1].MedicationID != laterID {
+		t.Errorf("Expected Soon then Later, got %s then %s", due[0].Name, due[1].Name)
+	}
+	for _, d := range due {
+		if len(d.ScheduledTimes) != 1 || d.ScheduledTimes[0].Before(now) || d.ScheduledTimes[0].After(now.Add(4*time.Hour)) {
+			t.Errorf("%s: expected one dose inside the window, got %v", d.Name, d.ScheduledTimes)
+		}
+	}
+	if due[1].InventoryCount == nil || *due[1].InventoryCount != 3 || !due[1].LowStock {
+		t.Errorf("Expected Later to report 3 units left and low stock, got %+v", due[1])
+	}
+
+	req = httptest.NewRequest("GET", "/api/medications/due?hours=0", nil)
+	req = withUser(req, 123456)
+	w = httptest.NewRecorder()
+	srv.handleGetMedicationsDue(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for hours=0, got %d", w.Code)
+	}
+}
+
+func TestHandleListMedications_SortByNextDose(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	now := time.Now()
+	daily := func(at time.Time) string {
+		return fmt.Sprintf(`{"type":"daily","times":["%s"]}`, at.Format("15:04"))
+	}
+	prnID, _ := db.CreateMedication("Ibuprofen", "200mg", `{"type":"as_needed"}`, nil, nil, "", "")
+	eveningID, _ := db.CreateMedication("Evening", "20mg", daily(now.Add(5*time.Hour)), nil, nil, "", "")
+	soonID, _ := db.CreateMedication("Soon", "10mg", daily(now.Add(time.Hour)), nil, nil, "", "")
+	// The second time comes up first
+	twiceID, _ := db.CreateMedication("Twice", "5mg", fmt.Sprintf(`{"type":"daily","times":["%s","%s"]}`,
+		now.Add(10*time.Hour).Format("15:04"), now.Add(3*time.Hour).Format("15:04")), nil, nil, "", "")
+
+	req := httptest.NewRequest("GET", "/api/medications?sort=next_dose", nil)
+	req = withUser(req, 123456)
+	w := httptest.NewRecorder()
+	srv.handleListMedications(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var meds []store.Medication
+	if err := json.NewDecoder(w.Body).Decode(&meds); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	want := []int64{soonID, twiceID, eveningID, prnID}
+	if len(meds) != len(want) {
+		t.Fatalf("Expected %d medications, got %d", len(want), len(meds))
+	}
+	for i, id := range want {
+		if meds[i].ID != id {
+			t.Errorf("Position %d: expected medication %d, got %d (%s)", i, id, meds[i].ID, meds[i].Name)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/api/medications?sort=name", nil)
+	req = withUser(req, 123456)
+	w = httptest.NewRecorder()
+	srv.handleListMedications(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown sort, got %d", w.Code)
+	}
+}
+
+func TestHandleGetMedicationHistory(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	medID, _ := db.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	otherID, _ := db.CreateMedication("Other", "1mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 8, 0, 0, 0, time.Local) }
+	for d := 1; d <= 7; d++ {
+		id, _ := db.CreateIntake(medID, 123456, day(d))
+		switch {
+		case d == 2:
+			db.MarkIntakeMissed(id, "out of stock")
+		case d%2 == 1:
+			db.ConfirmIntake(id, day(d))
+		}
+	}
+	db.CreateIntake(otherID, 123456, day(3))
+
+	type historyResponse struct {
+		Total   int               `json:"total"`
+		Limit   int               `json:"limit"`
+		Offset  int               `json:"offset"`
+		Intakes []store.IntakeLog `json:"intakes"`
+	}
+	get := func(id int64, query string) (*httptest.ResponseRecorder, historyResponse) {
+		t.Helper()
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/medications/%d/history%s", id, query), nil)
+		req.SetPathValue("id", fmt.Sprint(id))
+		w := httptest.NewRecorder()
+		srv.handleGetMedicationHistory(w, req)
+		var resp historyResponse
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+		}
+		return w, resp
+	}
+
+	// Status filter
+	_, resp := get(medID, "?status=taken")
+	if resp.Total != 4 || len(resp.Intakes) != 4 {
+		t.Errorf("Expected 4 taken intakes, got total %d with %d intakes", resp.Total, len(resp.Intakes))
+	}
+	for _, l := range resp.Intakes {
+		if l.Status != "TAKEN" {
+			t.Errorf("Expected only taken intakes, got %s", l.Status)
+		}
+	}
+	_, resp = get(medID, "?status=MISSED")
+	if resp.Total != 1 || len(resp.Intakes) != 1 || !resp.Intakes[0].ScheduledAt.Equal(day(2)) {
+		t.Errorf("Expected the Mar 2 missed intake, got %+v", resp)
+	}
+
+	// First page, newest first; the total covers every page
+	_, resp = get(medID, "?limit=3")
+	if resp.Total != 7 || len(resp.Intakes) != 3 || resp.Limit != 3 || resp.Offset != 0 {
+		t.Fatalf("Expected 3 of 7 intakes, got total %d with %d intakes", resp.Total, len(resp.Intakes))
+	}
+	if !resp.Intakes[0].ScheduledAt.Equal(day(7)) || !resp.Intakes[2].ScheduledAt.Equal(day(5)) {
+		t.Errorf("Expected Mar 7 to Mar 5, got %v to %v", resp.Intakes[0].ScheduledAt, resp.Intakes[2].ScheduledAt)
+	}
+
+	// Last partial page and past the end
+	_, resp = get(medID, "?limit=3&offset=6")
+	if resp.Total != 7 || len(resp.Intakes) != 1 || !resp.Intakes[0].ScheduledAt.Equal(day(1)) {
+		t.Errorf("Expected only Mar 1 on the last page, got %+v", resp.Intakes)
+	}
+	w, resp := get(medID, "?limit=3&offset=7")
+	if resp.Total != 7 || resp.Intakes == nil || len(resp.Intakes) != 0 {
+		t.Errorf("Expected an empty page past the end, got %s", w.Body.String())
+	}
+
+	// Date range, both ends inclusive, combined with status
+	_, resp = get(medID, "?from=2024-03-02&to=2024-03-04")
+	if resp.Total != 3 {
+		t.Errorf("Expected 3 intakes from Mar 2 to Mar 4, got %d", resp.Total)
+	}
+	_, resp = get(medID, "?from=2024-03-02&to=2024-03-04&status=pending")
+	if resp.Total != 1 || !resp.Intakes[0].ScheduledAt.Equal(day(4)) {
+		t.Errorf("Expected the Mar 4 pending intake, got %+v", resp.Intakes)
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=501", "?offset=-1", "?status=skipped", "?from=03/01/2024"} {
+		if w, _ := get(medID, query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+	if w, _ := get(9999, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown medication, got %d", w.Code)
+	}
+}
+
+func TestHandleSkipMedicationToday(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	medID, _ := db.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	otherID, _ := db.CreateMedication("Med B", "5mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+
+	skip := func(id int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/medications/%d/skip-today", id), nil)
+		req.SetPathValue("id", fmt.Sprint(id))
+		w := httptest.NewRecorder()
+		srv.handleSkipMedicationToday(w, req)
+		return w
+	}
+
+	w := skip(medID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	now := time.Now()
+	if !strings.Contains(w.Body.String(), now.Format("2006-01-02")) {
+		t.Errorf("Expected today's date in the response, got %s", w.Body.String())
+	}
+
+	if skipped, _ := db.IsMedicationSkipped(medID, now); !skipped {
+		t.Error("Expected the medication to be skipped today")
+	}
+	if skipped, _ := db.IsMedicationSkipped(medID, now.AddDate(0, 0, 1)); skipped {
+		t.Error("Expected the medication not to be skipped tomorrow")
+	}
+	if skipped, _ := db.IsMedicationSkipped(otherID, now); skipped {
+		t.Error("Expected other medications to be unaffected")
+	}
+
+	// Skipping twice is fine
+	if w := skip(medID); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 on repeated skip, got %d", w.Code)
+	}
+	if w := skip(9999); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown medication, got %d", w.Code)
+	}
+}
+
+func TestPillsNeeded(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	twiceDaily, _ := db.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	weekly, _ := db.CreateMedication("Vitamin D", "20000IU", `{"type":"weekly","days":[1,4],"times":["09:00"]}`, nil, nil, "", "")
+	stock := 10.0
+	if err := db.SetInventory(twiceDaily, &stock); err != nil {
+		t.Fatalf("Failed to set inventory: %v", err)
+	}
+
+	// Monday morning before the first dose; two weeks through Sunday the 16th
+	now := time.Date(2024, 6, 3, 7, 0, 0, 0, time.Local)
+	until := time.Date(2024, 6, 16, 0, 0, 0, 0, time.Local)
+
+	med, _ := db.GetMedication(twiceDaily)
+	got := srv.pillsNeeded(userID, med, now, until)
+	if got.DosesScheduled != 28 {
+		t.Errorf("Expected 28 doses over two weeks, got %d", got.DosesScheduled)
+	}
+	if got.PillsNeeded != 18 {
+		t.Errorf("Expected 18 pills needed with 10 in stock, got %v", got.PillsNeeded)
+	}
+	if got.DailyUsage != 2 {
+		t.Errorf("Expected daily usage 2, got %v", got.DailyUsage)
+	}
+
+	// Starting in the evening the morning dose is already past
+	got = srv.pillsNeeded(userID, med, now.Add(12*time.Hour), until)
+	if got.DosesScheduled != 27 || got.PillsNeeded != 17 {
+		t.Errorf("Expected 27 doses and 17 pills from the evening, got %d and %v", got.DosesScheduled, got.PillsNeeded)
+	}
+
+	// Enough stock means nothing to pick up
+	plenty := 40.0
+	med.InventoryCount = &plenty
+	if got := srv.pillsNeeded(userID, med, now, until); got.PillsNeeded != 0 {
+		t.Errorf("Expected 0 pills needed with plenty in stock, got %v", got.PillsNeeded)
+	}
+
+	// Mondays and Thursdays: 3rd, 6th, 10th and 13th; without inventory every dose is needed
+	med, _ = db.GetMedication(weekly)
+	got = srv.pillsNeeded(userID, med, now, until)
+	if got.DosesScheduled != 4 || got.PillsNeeded != 4 || got.InventoryCount != nil {
+		t.Errorf("Expected 4 weekly doses all needed, got %+v", got)
+	}
+
+	call := func(id int64, until string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest("GET", fmt.Sprintf("/api/medications/%d/pills-needed?until=%s", id, until), nil), userID)
+		req.SetPathValue("id", fmt.Sprint(id))
+		w := httptest.NewRecorder()
+		srv.handleGetPillsNeeded(w, req)
+		return w
+	}
+
+	w := call(twiceDaily, time.Now().AddDate(0, 0, 14).Format("2006-01-02"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var resp PillsNeeded
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.MedicationID != twiceDaily || resp.DosesScheduled < 28 || resp.DosesScheduled > 30 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	if w := call(twiceDaily, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without until, got %d", w.Code)
+	}
+	if w := call(twiceDaily, "2000-01-01"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a past date, got %d", w.Code)
+	}
+	if w := call(9999, "2100-01-01"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown medication, got %d", w.Code)
+	}
+}
+
+func TestHandleGetMedicationInteractions(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	rxnav := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// Warfarin with aspirin and fluconazole, plus a pair that doesn't involve warfarin
+		w.Write([]byte(`{"fullInteractionTypeGroup":[{"fullInteractionType":[{"interactionPair":[{
+			"interactionConcept":[
+				{"minConceptItem":{"name":"aspirin","rxcui":"1191"}},
+				{"minConceptItem":{"name":"warfarin","rxcui":"11289"}}
+			],
+			"severity":"high",
+			"description":"Increased risk of bleeding."
+		}]},{"interactionPair":[{
+			"interactionConcept":[
+				{"minConceptItem":{"name":"warfarin","rxcui":"11289"}},
+				{"minConceptItem":{"name":"fluconazole","rxcui":"4450"}}
+			],
+			"description":"Increased warfarin levels."
+		}]},{"interactionPair":[{
+			"interactionConcept":[
+				{"minConceptItem":{"name":"aspirin","rxcui":"1191"}},
+				{"minConceptItem":{"name":"ibuprofen","rxcui":"5640"}}
+			],
+			"description":"Reduced cardioprotective effect."
+		}]}]}]}`))
+	}))
+	defer rxnav.Close()
+	srv.rxnorm.SetInteractionEndpoint(rxnav.URL + "/interaction/list.json")
+
+	warfarin, _ := db.CreateMedication("Warfarin", "5mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "11289", "warfarin")
+	db.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "1191", "aspirin")
+	db.CreateMedication("Fluconazole", "150mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "4450", "fluconazole")
+	db.CreateMedication("Ibuprofen", "400mg", `{"type":"as_needed"}`, nil, nil, "5640", "ibuprofen")
+	uncoded, _ := db.CreateMedication("Fish Oil", "1g", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+
+	get := func(id int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/medications/%d/interactions", id), nil)
+		req.SetPathValue("id", fmt.Sprint(id))
+		w := httptest.NewRecorder()
+		srv.handleGetMedicationInteractions(w, req)
+		return w
+	}
+
+	type response struct {
+		Interactions []rxnorm.Interaction `json:"interactions"`
+		Warnings     []string             `json:"warnings"`
+	}
+
+	w := get(warfarin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var resp response
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Interactions) != 2 || len(resp.Warnings) != 2 {
+		t.Fatalf("Expected both warfarin interactions, got %+v", resp)
+	}
+	if resp.Interactions[0].Severity != "high" || resp.Interactions[1].Drug2 != "fluconazole" {
+		t.Errorf("Unexpected interactions: %+v", resp.Interactions)
+	}
+	for _, warning := range resp.Warnings {
+		if strings.Contains(warning, "ibuprofen") {
+			t.Errorf("Expected no warning for a pair without warfarin, got %q", warning)
+		}
+	}
+
+	w = get(uncoded)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	resp = response{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Interactions == nil || len(resp.Interactions) != 0 || len(resp.Warnings) != 0 {
+		t.Errorf("Expected empty lists for a medication without an RxCUI, got %s", w.Body.String())
+	}
+
+	if w := get(9999); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown medication, got %d", w.Code)
+	}
+}
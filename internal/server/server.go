@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,7 +15,9 @@ import (
 
 	"github.com/korjavin/medicationtrackerbot/internal/bot"
 	"github.com/korjavin/medicationtrackerbot/internal/rxnorm"
+	"github.com/korjavin/medicationtrackerbot/internal/scheduler"
 	"github.com/korjavin/medicationtrackerbot/internal/store"
+	"github.com/korjavin/medicationtrackerbot/internal/webhook"
 	"github.com/korjavin/medicationtrackerbot/internal/webpush"
 	"golang.org/x/oauth2"
 )
@@ -30,12 +33,21 @@ type Server struct {
 	botUsername   string
 	vapidConfig   VAPIDConfig
 	webPush       *webpush.Service
+	webhooks      *webhook.Service
+	scheduler     SchedulerTicker
+}
+
+// SchedulerTicker runs one scheduler cycle on demand
+type SchedulerTicker interface {
+	Tick() (*scheduler.TickSummary, error)
 }
 
 type VAPIDConfig struct {
 	PublicKey  string
 	PrivateKey string
 	Subject    string
+	// Concurrency limits parallel pushes per notification (0 = webpush.DefaultConcurrency)
+	Concurrency int
 }
 
 func New(s *store.Store, b *bot.Bot, botToken string, allowedUserID int64, oidc OIDCConfig, botUsername string, vapidConfig VAPIDConfig) *Server {
@@ -48,20 +60,33 @@ func New(s *store.Store, b *bot.Bot, botToken string, allowedUserID int64, oidc
 		oidcConfig:    oidc,
 		botUsername:   botUsername,
 		vapidConfig:   vapidConfig,
+		webhooks:      webhook.New(s),
 	}
 
 	if vapidConfig.PublicKey != "" && vapidConfig.PrivateKey != "" {
 		srv.webPush = webpush.New(s, vapidConfig.PublicKey, vapidConfig.PrivateKey, vapidConfig.Subject)
+		if vapidConfig.Concurrency > 0 {
+			srv.webPush.SetConcurrency(vapidConfig.Concurrency)
+		}
 	}
 
 	srv.initOAUTH()
 	return srv
 }
 
+// SetScheduler enables POST /api/admin/scheduler/tick
+func (s *Server) SetScheduler(sch SchedulerTicker) {
+	s.scheduler = sch
+}
+
 func (s *Server) GetWebPushService() *webpush.Service {
 	return s.webPush
 }
 
+func (s *Server) GetWebhookService() *webhook.Service {
+	return s.webhooks
+}
+
 // noCacheMiddleware adds headers to prevent caching
 func noCacheMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -98,23 +123,44 @@ func (s *Server) Routes() http.Handler {
 	mux.HandleFunc("/auth/google/login", s.handleGoogleLogin)
 	mux.HandleFunc("/auth/google/callback", s.handleGoogleCallback)
 	mux.HandleFunc("/auth/telegram/callback", s.handleTelegramCallback)
+	// Registered outside the auth middleware so an expired session can still log out
+	mux.HandleFunc("POST /api/auth/logout", s.handleLogout)
 
 	// API
 	apiMux := http.NewServeMux()
+	apiMux.HandleFunc("POST /api/auth/refresh", s.handleRefreshSession)
 	apiMux.HandleFunc("GET /api/medications", s.handleListMedications)
+	apiMux.HandleFunc("GET /api/medications/conflicts", s.handleGetScheduleConflicts)
+	apiMux.HandleFunc("GET /api/medications/due", s.handleGetMedicationsDue)
+	apiMux.HandleFunc("GET /api/medications/by-rxcui/{rxcui}", s.handleGetMedicationsByRxCUI)
 	apiMux.HandleFunc("POST /api/medications", s.handleCreateMedication)
+	apiMux.HandleFunc("POST /api/medications/check-interactions", s.handleCheckInteractions)
 	apiMux.HandleFunc("POST /api/medications/{id}", s.handleUpdateMedication)
 	apiMux.HandleFunc("DELETE /api/medications/{id}", s.handleDeleteMedication)
 	apiMux.HandleFunc("GET /api/history", s.handleListHistory)
+	apiMux.HandleFunc("GET /api/adherence", s.handleGetAdherence)
+	apiMux.HandleFunc("GET /api/adherence/calendar", s.handleGetAdherenceCalendar)
+	apiMux.HandleFunc("GET /api/adherence/best-streak", s.handleGetBestAdherenceStreak)
+	apiMux.HandleFunc("GET /api/analytics/response-time", s.handleGetResponseTime)
+	apiMux.HandleFunc("GET /api/schedule/slots", s.handleGetScheduleSlots)
+	apiMux.HandleFunc("POST /api/schedule/slots", s.handleUpdateScheduleSlots)
+	apiMux.HandleFunc("GET /api/schedule/preview", s.handleGetSchedulePreview)
+	apiMux.HandleFunc("POST /api/schedule/validate", s.handleValidateSchedule)
 
 	// Blood Pressure endpoints
 	apiMux.HandleFunc("POST /api/bp", s.handleCreateBloodPressure)
+	apiMux.HandleFunc("POST /api/bp/triplicate", s.handleCreateTriplicateBloodPressure)
 	apiMux.HandleFunc("GET /api/bp", s.handleListBloodPressure)
 	apiMux.HandleFunc("DELETE /api/bp/{id}", s.handleDeleteBloodPressure)
+	apiMux.HandleFunc("DELETE /api/bp", s.handleDeleteBloodPressureRange)
 	apiMux.HandleFunc("POST /api/bp/import", s.handleImportBloodPressure)
 	apiMux.HandleFunc("GET /api/bp/export", s.handleExportBloodPressure)
 	apiMux.HandleFunc("GET /api/bp/goal", s.handleGetBPGoal)
 	apiMux.HandleFunc("GET /api/bp/stats", s.handleGetBPStats)
+	apiMux.HandleFunc("GET /api/bp/time-in-range", s.handleGetBPTimeInRange)
+	apiMux.HandleFunc("GET /api/analysis/sleep-bp", s.handleGetSleepBPCorrelation)
+	apiMux.HandleFunc("GET /api/analysis/weight-since-med", s.handleGetWeightSinceMed)
+	apiMux.HandleFunc("GET /api/bp/around", s.handleGetBPAroundIntake)
 
 	// BP Reminder endpoints
 	apiMux.HandleFunc("GET /api/bp/reminder/status", s.handleGetBPReminderStatus)
@@ -122,10 +168,41 @@ func (s *Server) Routes() http.Handler {
 	apiMux.HandleFunc("POST /api/bp/reminder/snooze", s.handleSnoozeBPReminder)
 	apiMux.HandleFunc("POST /api/bp/reminder/dontbug", s.handleDontBugMeBPReminder)
 
+	// Glucose endpoints
+	apiMux.HandleFunc("POST /api/glucose", s.handleCreateGlucose)
+	apiMux.HandleFunc("GET /api/glucose", s.handleListGlucose)
+	apiMux.HandleFunc("DELETE /api/glucose/{id}", s.handleDeleteGlucose)
+	apiMux.HandleFunc("GET /api/glucose/stats", s.handleGetGlucoseStats)
+	apiMux.HandleFunc("GET /api/glucose/export", s.handleExportGlucose)
+
+	// Symptom and temperature endpoints
+	apiMux.HandleFunc("POST /api/symptoms", s.handleCreateSymptom)
+	apiMux.HandleFunc("GET /api/symptoms", s.handleListSymptoms)
+	apiMux.HandleFunc("PUT /api/symptoms/{id}", s.handleUpdateSymptom)
+	apiMux.HandleFunc("DELETE /api/symptoms/{id}", s.handleDeleteSymptom)
+
+	// Lab result endpoints
+	apiMux.HandleFunc("POST /api/labs", s.handleCreateLabResult)
+	apiMux.HandleFunc("GET /api/labs", s.handleListLabResults)
+	apiMux.HandleFunc("PUT /api/labs/{id}", s.handleUpdateLabResult)
+	apiMux.HandleFunc("DELETE /api/labs/{id}", s.handleDeleteLabResult)
+
+	// Life event endpoints
+	apiMux.HandleFunc("POST /api/events", s.handleCreateLifeEvent)
+	apiMux.HandleFunc("GET /api/events", s.handleListLifeEvents)
+	apiMux.HandleFunc("PUT /api/events/{id}", s.handleUpdateLifeEvent)
+	apiMux.HandleFunc("DELETE /api/events/{id}", s.handleDeleteLifeEvent)
+
+	// Sleep endpoints
+	apiMux.HandleFunc("GET /api/sleep/export", s.handleExportSleep)
+	apiMux.HandleFunc("GET /api/sleep/stats", s.handleGetSleepStats)
+
 	// Weight endpoints
 	apiMux.HandleFunc("POST /api/weight", s.handleCreateWeight)
 	apiMux.HandleFunc("GET /api/weight", s.handleListWeight)
 	apiMux.HandleFunc("DELETE /api/weight/{id}", s.handleDeleteWeight)
+	apiMux.HandleFunc("DELETE /api/weight", s.handleDeleteWeightRange)
+	apiMux.HandleFunc("POST /api/weight/recompute-trends", s.handleRecomputeWeightTrends)
 	apiMux.HandleFunc("GET /api/weight/export", s.handleExportWeight)
 	apiMux.HandleFunc("GET /api/weight/goal", s.handleGetWeightGoal)
 
@@ -135,10 +212,29 @@ func (s *Server) Routes() http.Handler {
 	apiMux.HandleFunc("POST /api/weight/reminder/snooze", s.handleSnoozeWeightReminder)
 	apiMux.HandleFunc("POST /api/weight/reminder/dontbug", s.handleDontBugMeWeightReminder)
 
+	// Notification log
+	apiMux.HandleFunc("GET /api/notifications/log", s.handleGetNotificationLog)
+
 	// Inventory endpoints
 	apiMux.HandleFunc("POST /api/medications/{id}/restock", s.handleRestock)
 	apiMux.HandleFunc("GET /api/medications/{id}/restocks", s.handleGetRestockHistory)
+	apiMux.HandleFunc("DELETE /api/medications/{id}/restocks/{restockId}", s.handleDeleteRestock)
+	apiMux.HandleFunc("GET /api/medications/{id}/dose-calc", s.handleDoseCalc)
+	apiMux.HandleFunc("GET /api/medications/{id}/prn-stats", s.handleGetPRNStats)
+	apiMux.HandleFunc("GET /api/medications/{id}/history", s.handleGetMedicationHistory)
+	apiMux.HandleFunc("POST /api/medications/{id}/skip-today", s.handleSkipMedicationToday)
+	apiMux.HandleFunc("GET /api/medications/{id}/pills-needed", s.handleGetPillsNeeded)
+	apiMux.HandleFunc("GET /api/medications/{id}/interactions", s.handleGetMedicationInteractions)
+	apiMux.HandleFunc("GET /api/medications/{id}/reconcile", s.handleGetReconciliation)
+	apiMux.HandleFunc("GET /api/medications/{id}/adherence", s.handleGetMedicationAdherence)
+	apiMux.HandleFunc("GET /api/medications/{id}/injection-sites", s.handleGetInjectionSites)
+	apiMux.HandleFunc("PUT /api/medications/{id}/injection-sites", s.handleSetInjectionSites)
 	apiMux.HandleFunc("GET /api/inventory/low", s.handleGetLowStock)
+	apiMux.HandleFunc("GET /api/restocks", s.handleGetAllRestocks)
+	apiMux.HandleFunc("GET /api/reports/spend", s.handleGetSpendReport)
+	apiMux.HandleFunc("GET /api/summary/weekly", s.handleGetWeeklySummary)
+	apiMux.HandleFunc("GET /api/trends", s.handleGetTrends)
+	apiMux.HandleFunc("GET /api/freshness", s.handleGetFreshness)
 
 	// Workout endpoints
 	apiMux.HandleFunc("GET /api/workout/groups", s.handleListWorkoutGroups)
@@ -149,33 +245,83 @@ func (s *Server) Routes() http.Handler {
 	apiMux.HandleFunc("POST /api/workout/variants/create", s.handleCreateWorkoutVariant)
 	apiMux.HandleFunc("PUT /api/workout/variants/update", s.handleUpdateWorkoutVariant)
 	apiMux.HandleFunc("DELETE /api/workout/variants/delete", s.handleDeleteWorkoutVariant)
+	apiMux.HandleFunc("PUT /api/workout/variants/order", s.handleReorderWorkoutVariants)
 	apiMux.HandleFunc("GET /api/workout/exercises", s.handleListExercisesByVariant)
 	apiMux.HandleFunc("POST /api/workout/exercises/create", s.handleCreateExercise)
 	apiMux.HandleFunc("PUT /api/workout/exercises/update", s.handleUpdateExercise)
 	apiMux.HandleFunc("DELETE /api/workout/exercises/delete", s.handleDeleteExercise)
 	apiMux.HandleFunc("GET /api/workout/sessions", s.handleListWorkoutSessions)
 	apiMux.HandleFunc("GET /api/workout/sessions/next", s.handleGetNextWorkout)
+	apiMux.HandleFunc("GET /api/workout/next/full", s.handleGetNextWorkoutFull)
 	apiMux.HandleFunc("GET /api/workout/sessions/details", s.handleGetSessionDetails)
 	apiMux.HandleFunc("POST /api/workout/sessions/adhoc", s.handleCreateAdHocWorkoutSession) // Ad-hoc workout
 	apiMux.HandleFunc("GET /api/workout/stats", s.handleGetWorkoutStats)
 	apiMux.HandleFunc("GET /api/workout/rotation/state", s.handleGetRotationState)
+	apiMux.HandleFunc("GET /api/workout/rotation/current", s.handleGetCurrentRotationVariant)
 	apiMux.HandleFunc("POST /api/workout/rotation/initialize", s.handleInitializeRotation)
 	apiMux.HandleFunc("POST /api/workout/sessions/logs/update", s.handleUpdateExerciseLog)
+	apiMux.HandleFunc("POST /api/workout/sessions/snooze-all", s.handleSnoozeAllWorkoutSessions)
 	apiMux.HandleFunc("POST /api/workout/sessions/{id}/snooze", s.handleSnoozeWorkoutSession)
 	apiMux.HandleFunc("POST /api/workout/sessions/{id}/skip", s.handleSkipWorkoutSession)
 	apiMux.HandleFunc("POST /api/workout/sessions/{id}/start", s.handleStartWorkoutSession)
+	apiMux.HandleFunc("POST /api/workout/sessions/{id}/complete-all", s.handleCompleteAllExercises)
 	apiMux.HandleFunc("PUT /api/workout/sessions/status", s.handleUpdateSessionStatus)
+	apiMux.HandleFunc("GET /api/workout/skip-dates", s.handleListWorkoutSkipDates)
+	apiMux.HandleFunc("POST /api/workout/skip-dates", s.handleAddWorkoutSkipDates)
+	apiMux.HandleFunc("DELETE /api/workout/skip-dates/{date}", s.handleDeleteWorkoutSkipDate)
 	apiMux.HandleFunc("GET /api/workout/exercises/unique", s.handleGetUniqueExercises)
+	apiMux.HandleFunc("GET /api/workout/exercises/suggest", s.handleSuggestExerciseName)
+	apiMux.HandleFunc("GET /api/workout/exercises/history", s.handleGetExerciseHistory)
+	apiMux.HandleFunc("GET /api/workout/exercises/trend", s.handleGetExerciseTrend)
 	apiMux.HandleFunc("POST /api/workout/sessions/logs/create", s.handleAddExerciseToSession)
 
+	// Export endpoints
+	apiMux.HandleFunc("GET /api/export/all.zip", s.handleExportAllZip)
+	apiMux.HandleFunc("GET /api/export/all", s.handleExportSummaryZip)
+
+	// Account
+	apiMux.HandleFunc("POST /api/account/reset", s.handleResetAccount)
+
+	// Database maintenance
+	apiMux.HandleFunc("GET /api/admin/wal", s.handleGetWALStatus)
+	apiMux.HandleFunc("POST /api/admin/wal/checkpoint", s.handleCheckpointWAL)
+	apiMux.HandleFunc("POST /api/admin/scheduler/tick", s.handleSchedulerTick)
+
+	// Settings
+	apiMux.HandleFunc("GET /api/settings/retention", s.handleGetRetention)
+	apiMux.HandleFunc("POST /api/settings/retention", s.handleUpdateRetention)
+	apiMux.HandleFunc("GET /api/settings/reminder-template", s.handleGetReminderTemplate)
+	apiMux.HandleFunc("POST /api/settings/reminder-template", s.handleUpdateReminderTemplate)
+	apiMux.HandleFunc("GET /api/settings/currency", s.handleGetCurrency)
+	apiMux.HandleFunc("POST /api/settings/currency", s.handleUpdateCurrency)
+	apiMux.HandleFunc("GET /api/settings/glucose-unit", s.handleGetGlucoseUnit)
+	apiMux.HandleFunc("POST /api/settings/glucose-unit", s.handleUpdateGlucoseUnit)
+	apiMux.HandleFunc("GET /api/settings/med-snooze", s.handleGetMedSnooze)
+	apiMux.HandleFunc("POST /api/settings/med-snooze", s.handleUpdateMedSnooze)
+	apiMux.HandleFunc("GET /api/settings/channels", s.handleGetReminderChannels)
+	apiMux.HandleFunc("PATCH /api/settings/channels", s.handleUpdateReminderChannels)
+	apiMux.HandleFunc("GET /api/settings/confirm-window", s.handleGetConfirmWindow)
+	apiMux.HandleFunc("POST /api/settings/confirm-window", s.handleUpdateConfirmWindow)
+	apiMux.HandleFunc("GET /api/settings/week-start", s.handleGetWeekStart)
+	apiMux.HandleFunc("POST /api/settings/week-start", s.handleUpdateWeekStart)
+	apiMux.HandleFunc("GET /api/settings/bp-defaults", s.handleGetBPDefaults)
+	apiMux.HandleFunc("PATCH /api/settings/bp-defaults", s.handleUpdateBPDefaults)
+
+	// Webhook endpoints
+	apiMux.HandleFunc("GET /api/webhooks", s.handleListWebhooks)
+	apiMux.HandleFunc("POST /api/webhooks", s.handleCreateWebhook)
+	apiMux.HandleFunc("DELETE /api/webhooks/{id}", s.handleDeleteWebhook)
+
 	// Web Push endpoints
 	apiMux.HandleFunc("GET /api/webpush/vapid-public-key", s.handleGetVAPIDPublicKey)
 	apiMux.HandleFunc("POST /api/webpush/subscribe", s.handleSubscribePush)
 	apiMux.HandleFunc("POST /api/webpush/unsubscribe", s.handleUnsubscribePush)
 	apiMux.HandleFunc("GET /api/webpush/subscriptions", s.handleListPushSubscriptions)
 	apiMux.HandleFunc("POST /api/webpush/test-medication", s.handleSendTestMedicationNotification)
+	apiMux.HandleFunc("POST /api/notifications/test-telegram", s.handleSendTestTelegramNotification)
 	apiMux.HandleFunc("POST /api/medications/confirm-schedule", s.handleConfirmSchedule)
 	apiMux.HandleFunc("POST /api/intakes/update", s.handleUpdateIntake)
+	apiMux.HandleFunc("GET /api/intakes/pending", s.handleGetPendingIntakes)
 
 	// Apply Middleware to API
 	authMW := AuthMiddleware(s.botToken, s.allowedUserID)
@@ -223,37 +369,77 @@ func (s *Server) handleRestock(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Quantity int    `json:"quantity"`
-		Note     string `json:"note,omitempty"`
+		Quantity int `json:"quantity"`
+		// Packs is an alternative to Quantity for medications with a pack size,
+		// e.g. 2 boxes of 30 adds 60 units
+		Packs    int      `json:"packs,omitempty"`
+		Note     string   `json:"note,omitempty"`
+		UnitCost *float64 `json:"unit_cost,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+
+	if req.Packs != 0 {
+		if req.Quantity != 0 {
+			http.Error(w, "Give either quantity or packs, not both", http.StatusBadRequest)
+			return
+		}
+		if med.PackSize == nil {
+			http.Error(w, "Medication has no pack size", http.StatusBadRequest)
+			return
+		}
+		if req.Packs < 0 {
+			http.Error(w, "Packs must be positive", http.StatusBadRequest)
+			return
+		}
+		req.Quantity = req.Packs * *med.PackSize
+	}
+
 	if req.Quantity <= 0 {
 		http.Error(w, "Quantity must be positive", http.StatusBadRequest)
 		return
 	}
+	if req.UnitCost != nil && *req.UnitCost < 0 {
+		http.Error(w, "Unit cost must not be negative", http.StatusBadRequest)
+		return
+	}
 
-	if err := s.store.AddRestock(id, req.Quantity, req.Note); err != nil {
+	if err := s.store.AddRestock(id, req.Quantity, req.Note, req.UnitCost); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Get updated medication to return new count
-	med, err := s.store.GetMedication(id)
+	med, err = s.store.GetMedication(id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	resp := map[string]interface{}{
 		"status":          "restocked",
 		"quantity_added":  req.Quantity,
 		"inventory_count": med.InventoryCount,
-	})
+	}
+	if med.PackSize != nil {
+		resp["pack_size"] = *med.PackSize
+		resp["packs_remaining"] = med.PacksRemaining()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (s *Server) handleGetRestockHistory(w http.ResponseWriter, r *http.Request) {
@@ -274,7 +460,94 @@ func (s *Server) handleGetRestockHistory(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(restocks)
 }
 
+// handleDeleteRestock undoes a mistaken restock and returns the corrected inventory
+func (s *Server) handleDeleteRestock(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	restockID, err := strconv.ParseInt(r.PathValue("restockId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid restock ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.DeleteRestock(id, restockID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Restock not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	med, err := s.store.GetMedication(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"status":          "deleted",
+		"inventory_count": med.InventoryCount,
+	}
+	if med.PackSize != nil {
+		resp["pack_size"] = *med.PackSize
+		resp["packs_remaining"] = med.PacksRemaining()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleGetAllRestocks lists restocks across all medications for the last ?days= days (default 90)
+func (s *Server) handleGetAllRestocks(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	days := 90
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if d, err := strconv.Atoi(dStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	restocks, err := s.store.GetAllRestocks(userID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restocks)
+}
+
+// handleGetSpendReport sums restock costs per medication for the last ?days= days (default 365)
+func (s *Server) handleGetSpendReport(w http.ResponseWriter, r *http.Request) {
+	days := 365
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if d, err := strconv.Atoi(dStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	report, err := s.store.GetSpendReport(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
 func (s *Server) handleGetLowStock(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
 	// Default to 7 days threshold
 	days := 7
 	if dStr := r.URL.Query().Get("days"); dStr != "" {
@@ -283,7 +556,7 @@ func (s *Server) handleGetLowStock(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	meds, err := s.store.GetMedicationsLowOnStock(days)
+	meds, err := s.store.GetMedicationsLowOnStock(userID, days)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -292,14 +565,16 @@ func (s *Server) handleGetLowStock(w http.ResponseWriter, r *http.Request) {
 	// Enrich with days remaining info
 	type LowStockMed struct {
 		store.Medication
-		DaysRemaining *float64 `json:"days_remaining,omitempty"`
+		DaysRemaining  *float64 `json:"days_remaining,omitempty"`
+		PacksRemaining *float64 `json:"packs_remaining,omitempty"`
 	}
 
 	result := make([]LowStockMed, 0, len(meds))
 	for _, m := range meds {
 		lsm := LowStockMed{
-			Medication:    m,
-			DaysRemaining: s.store.GetDaysOfStockRemaining(&m),
+			Medication:     m,
+			DaysRemaining:  s.store.GetDaysOfStockRemaining(userID, &m),
+			PacksRemaining: m.PacksRemaining(),
 		}
 		result = append(result, lsm)
 	}
@@ -376,16 +651,7 @@ func (s *Server) handleTelegramCallback(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Create session (same as Google auth)
-	sessionValue := createSessionToken(user.Username, s.botToken)
-	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_session",
-		Value:    sessionValue,
-		Expires:  time.Now().Add(24 * time.Hour * 30), // 30 days
-		HttpOnly: true,
-		Secure:   true,                 // Only send over HTTPS
-		SameSite: http.SameSiteLaxMode, // CSRF protection
-		Path:     "/",
-	})
+	setSessionCookie(w, user.Username, s.botToken)
 
 	log.Printf("[TG-LOGIN] Success for user_id=%d username=%s from %s", user.ID, user.Username, r.RemoteAddr)
 
@@ -467,13 +733,32 @@ func (s *Server) handleConfirmSchedule(w http.ResponseWriter, r *http.Request) {
 		ScheduledAt   string  `json:"scheduled_at"`
 		MedicationIDs []int64 `json:"medication_ids"`
 		IntakeIDs     []int64 `json:"intake_ids"`
+		Note          string  `json:"note,omitempty"`
+		// DecrementInventory defaults to true; false records the dose without touching stock
+		// (e.g. a sample not taken from the tracked bottle)
+		DecrementInventory *bool `json:"decrement_inventory,omitempty"`
+		// Quantity of units taken per medication, e.g. 0.5 for half a tablet (default: the
+		// medication's dose per intake)
+		Quantity *float64 `json:"quantity,omitempty"`
+		// SubstitutedWith names what was taken instead (e.g. a generic). The dose still counts
+		// for the scheduled medication, but its inventory is left untouched.
+		SubstitutedWith string `json:"substituted_with,omitempty"`
+		// InjectionSite overrides the suggested site for medications with a site rotation
+		InjectionSite string `json:"injection_site,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
+	substitute := strings.TrimSpace(req.SubstitutedWith)
+
+	if req.Quantity != nil && !store.ValidDoseQuantity(*req.Quantity) {
+		http.Error(w, fmt.Sprintf("quantity must be greater than 0 and at most %d", store.MaxDoseQuantity), http.StatusBadRequest)
+		return
+	}
 
 	now := time.Now()
+	decrement := (req.DecrementInventory == nil || *req.DecrementInventory) && substitute == ""
 
 	// 1. Prefer Intake IDs if available
 	if len(req.IntakeIDs) > 0 {
@@ -497,14 +782,25 @@ func (s *Server) handleConfirmSchedule(w http.ResponseWriter, r *http.Request) {
 					}
 				}
 
-				if err := s.store.ConfirmIntake(id, now); err != nil {
+				quantity := s.doseQuantity(intake.MedicationID, req.Quantity)
+				if err := s.store.ConfirmIntakeSubstituted(id, now, quantity, substitute); err != nil {
 					log.Printf("Error confirming intake %d: %v", intake.ID, err)
 				}
+				if req.Note != "" {
+					if err := s.store.SetIntakeNotes(id, req.Note); err != nil {
+						log.Printf("Error saving note for intake %d: %v", id, err)
+					}
+				}
+				s.overrideInjectionSite(intake, req.InjectionSite, now)
 
 				// Decrement inventory
-				if err := s.store.DecrementInventory(intake.MedicationID, 1); err != nil {
-					log.Printf("Error decrementing inventory: %v", err)
+				if decrement {
+					if err := s.store.DecrementInventory(intake.MedicationID, quantity); err != nil {
+						log.Printf("Error decrementing inventory: %v", err)
+					}
 				}
+
+				s.webhooks.NotifyIntakeConfirmed(userID, id)
 			}
 		}
 		w.WriteHeader(http.StatusOK)
@@ -537,14 +833,25 @@ func (s *Server) handleConfirmSchedule(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 
-			if err := s.store.ConfirmIntake(intake.ID, now); err != nil {
+			quantity := s.doseQuantity(medID, req.Quantity)
+			if err := s.store.ConfirmIntakeSubstituted(intake.ID, now, quantity, substitute); err != nil {
 				log.Printf("Error confirming intake %d: %v", intake.ID, err)
 			}
+			if req.Note != "" {
+				if err := s.store.SetIntakeNotes(intake.ID, req.Note); err != nil {
+					log.Printf("Error saving note for intake %d: %v", intake.ID, err)
+				}
+			}
+			s.overrideInjectionSite(intake, req.InjectionSite, now)
 
 			// Decrement inventory
-			if err := s.store.DecrementInventory(medID, 1); err != nil {
-				log.Printf("Error decrementing inventory: %v", err)
+			if decrement {
+				if err := s.store.DecrementInventory(medID, quantity); err != nil {
+					log.Printf("Error decrementing inventory: %v", err)
+				}
 			}
+
+			s.webhooks.NotifyIntakeConfirmed(userID, intake.ID)
 		} else if intake == nil {
 			log.Printf("Intake not found or not pending for med %d at %s", medID, req.ScheduledAt)
 		}
@@ -553,6 +860,19 @@ func (s *Server) handleConfirmSchedule(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// doseQuantity returns the units a confirmation takes: the requested quantity, or else
+// the medication's dose per intake
+func (s *Server) doseQuantity(medID int64, requested *float64) float64 {
+	if requested != nil {
+		return *requested
+	}
+	med, err := s.store.GetMedication(medID)
+	if err != nil || med == nil {
+		return 1
+	}
+	return med.Units()
+}
+
 func (s *Server) handleSendTestMedicationNotification(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
 
@@ -561,18 +881,39 @@ func (s *Server) handleSendTestMedicationNotification(w http.ResponseWriter, r *
 		return
 	}
 
-	meds, err := s.store.ListMedications(false)
+	earliestNext, medsAtEarliest, err := s.findNextDoseGroup(userID, time.Now())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if len(medsAtEarliest) == 0 {
+		http.Error(w, "No scheduled medications found to test with", http.StatusNotFound)
+		return
+	}
+
+	// Send simulated Push
+	ctx := context.Background()
+	if err := s.webPush.SendMedicationNotification(ctx, userID, medsAtEarliest, earliestNext, nil); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to send push: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Sent simulated notification for %d medication(s) scheduled at %s", len(medsAtEarliest), earliestNext.Format("15:04"))
+}
+
+// findNextDoseGroup returns the earliest upcoming dose time within the next week and the medications due then
+func (s *Server) findNextDoseGroup(userID int64, now time.Time) (time.Time, []store.Medication, error) {
+	meds, err := s.store.ListMedications(false)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
 
-	now := time.Now()
 	var earliestNext time.Time
 	var medsAtEarliest []store.Medication
 
 	for _, med := range meds {
-		cfg, err := med.ValidSchedule()
+		cfg, err := s.store.ExpandSchedule(userID, &med)
 		if err != nil || cfg.Type == "as_needed" {
 			continue
 		}
@@ -633,18 +974,33 @@ func (s *Server) handleSendTestMedicationNotification(w http.ResponseWriter, r *
 		}
 	}
 
+	return earliestNext, medsAtEarliest, nil
+}
+
+// handleSendTestTelegramNotification sends the next-dose group message through the bot so users can verify Telegram delivery
+func (s *Server) handleSendTestTelegramNotification(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	if s.bot == nil {
+		http.Error(w, "Telegram bot not configured", http.StatusBadRequest)
+		return
+	}
+
+	earliestNext, medsAtEarliest, err := s.findNextDoseGroup(userID, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	if len(medsAtEarliest) == 0 {
 		http.Error(w, "No scheduled medications found to test with", http.StatusNotFound)
 		return
 	}
 
-	// Send simulated Push
-	ctx := context.Background()
-	if err := s.webPush.SendMedicationNotification(ctx, userID, medsAtEarliest, earliestNext, nil); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to send push: %v", err), http.StatusInternalServerError)
+	if _, err := s.bot.SendGroupNotification(medsAtEarliest, earliestNext); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to send Telegram message: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "Sent simulated notification for %d medication(s) scheduled at %s", len(medsAtEarliest), earliestNext.Format("15:04"))
+	fmt.Fprintf(w, "Sent Telegram notification for %d medication(s) scheduled at %s", len(medsAtEarliest), earliestNext.Format("15:04"))
 }
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/medicationtrackerbot/internal/bot"
+	"github.com/korjavin/medicationtrackerbot/internal/scheduler"
+)
+
+func TestHandleSendTestTelegramNotification(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/sendMessage") {
+			r.ParseForm()
+			mu.Lock()
+			sent = append(sent, r.FormValue("text"))
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer tg.Close()
+
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	srv.bot = bot.NewWithAPI(api, 123456, db)
+
+	next := time.Now().Add(2 * time.Hour)
+	schedule := fmt.Sprintf(`{"type":"daily","times":["%s"]}`, next.Format("15:04"))
+	db.CreateMedication("Lisinopril", "10mg", schedule, nil, nil, "", "")
+
+	req := httptest.NewRequest("POST", "/api/notifications/test-telegram", nil)
+	req = withUser(req, 123456)
+	w := httptest.NewRecorder()
+	srv.handleSendTestTelegramNotification(w, req)
+
+	if w.Code != http.StatusOK {
+		body, _ := io.ReadAll(w.Body)
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, body)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 {
+		t.Fatalf("Expected 1 Telegram message, got %d", len(sent))
+	}
+	if !strings.Contains(sent[0], "Lisinopril") || !strings.Contains(sent[0], next.Format("15:04")) {
+		t.Errorf("Expected group message for Lisinopril at %s, got %q", next.Format("15:04"), sent[0])
+	}
+}
+
+func TestHandleSendTestTelegramNotification_NoBot(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	req := httptest.NewRequest("POST", "/api/notifications/test-telegram", nil)
+	req = withUser(req, 123456)
+	w := httptest.NewRecorder()
+	srv.handleSendTestTelegramNotification(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without bot, got %d", w.Code)
+	}
+}
+
+func TestHandleSchedulerTick(t *testing.T) {
+	tg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true, "result": {"message_id": 1, "chat": {"id": 123456}}}`))
+	}))
+	defer tg.Close()
+
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	tick := func(userID int64) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest("POST", "/api/admin/scheduler/tick", nil), userID)
+		w := httptest.NewRecorder()
+		srv.handleSchedulerTick(w, req)
+		return w
+	}
+
+	if w := tick(123456); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without a scheduler, got %d", w.Code)
+	}
+
+	api := &tgbotapi.BotAPI{Token: "TEST", Client: &http.Client{}}
+	api.SetAPIEndpoint(tg.URL + "/bot%s/%s")
+	srv.SetScheduler(scheduler.New(db, bot.NewWithAPI(api, 123456, db), 123456, nil, nil))
+
+	now := time.Now()
+	due := now.Add(-30 * time.Minute)
+	if due.Day() != now.Day() {
+		t.Skip("Too close to midnight for a same-day schedule")
+	}
+	medID, _ := db.CreateMedication("Lisinopril", "10mg", fmt.Sprintf(`{"type":"daily","times":["%s"]}`, due.Format("15:04")), nil, nil, "", "")
+
+	if w := tick(999); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for another user, got %d", w.Code)
+	}
+
+	w := tick(123456)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var summary scheduler.TickSummary
+	if err := json.NewDecoder(w.Body).Decode(&summary); err != nil {
+		t.Fatalf("Failed to decode summary: %v", err)
+	}
+	if summary.IntakesCreated != 1 || summary.NotificationGroups != 1 {
+		t.Errorf("Expected the due dose to be reported, got %+v", summary)
+	}
+
+	target := time.Date(now.Year(), now.Month(), now.Day(), due.Hour(), due.Minute(), 0, 0, now.Location())
+	if intake, err := db.GetIntakeBySchedule(medID, target); err != nil || intake == nil {
+		t.Errorf("Expected an intake for the due dose, got %v (%v)", intake, err)
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sessionCookieName = "auth_session"
+
+// sessionTTL is how long a session token stays valid unless refreshed
+const sessionTTL = 30 * 24 * time.Hour
+
+// createSessionToken signs the subject together with the expiry so neither can be altered:
+// base64(subject) + "." + unix expiry + "." + hmac(subject|expiry, secret)
+func createSessionToken(subject, secret string, expiresAt time.Time) string {
+	exp := strconv.FormatInt(expiresAt.Unix(), 10)
+	return base64.URLEncoding.EncodeToString([]byte(subject)) + "." + exp + "." + sessionSignature(subject, exp, secret)
+}
+
+// verifySessionToken returns the subject of a correctly signed token that has not expired
+func verifySessionToken(token, secret string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+
+	subjectBytes, err := base64.URLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	subject := string(subjectBytes)
+
+	expected := sessionSignature(subject, parts[1], secret)
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return "", false
+	}
+
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= exp {
+		return "", false
+	}
+
+	return subject, true
+}
+
+func sessionSignature(subject, exp, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(subject + "|" + exp))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// setSessionCookie issues a fresh session token valid for sessionTTL and returns its expiry
+func setSessionCookie(w http.ResponseWriter, subject, secret string) time.Time {
+	expiresAt := time.Now().Add(sessionTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    createSessionToken(subject, secret, expiresAt),
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   true,                 // Only send over HTTPS
+		SameSite: http.SameSiteLaxMode, // CSRF protection
+		Path:     "/",
+	})
+	return expiresAt
+}
+
+// handleRefreshSession reissues the session cookie with a new expiry for the authenticated user
+func (s *Server) handleRefreshSession(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(UserCtxKey).(*TelegramUser)
+
+	expiresAt := setSessionCookie(w, user.Username, s.botToken)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "ok",
+		"expires_at": expiresAt,
+	})
+}
+
+// handleLogout clears the session cookie
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/",
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
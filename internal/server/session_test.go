@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionToken_ExpiredRejected(t *testing.T) {
+	secret := "test-token"
+	srv := &Server{botToken: secret, allowedUserID: 123456}
+	handler := AuthMiddleware(srv.botToken, srv.allowedUserID)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(token string) int {
+		req := httptest.NewRequest("GET", "/api/medications", nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request(createSessionToken("admin@example.com", secret, time.Now().Add(time.Hour))); code != http.StatusOK {
+		t.Errorf("Expected valid token to be accepted, got %d", code)
+	}
+	if code := request(createSessionToken("admin@example.com", secret, time.Now().Add(-time.Minute))); code != http.StatusUnauthorized {
+		t.Errorf("Expected expired token to be rejected with 401, got %d", code)
+	}
+
+	// Pushing the expiry forward breaks the signature
+	parts := strings.Split(createSessionToken("admin@example.com", secret, time.Now().Add(-time.Minute)), ".")
+	parts[1] = strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	if _, ok := verifySessionToken(strings.Join(parts, "."), secret); ok {
+		t.Error("Expected token with altered expiry to be rejected")
+	}
+	if _, ok := verifySessionToken(createSessionToken("admin@example.com", "other", time.Now().Add(time.Hour)), secret); ok {
+		t.Error("Expected token signed with another secret to be rejected")
+	}
+}
+
+func TestHandleRefreshSession(t *testing.T) {
+	srv := &Server{botToken: "test-token", allowedUserID: 123456}
+
+	req := withUser(httptest.NewRequest("POST", "/api/auth/refresh", nil), 123456)
+	w := httptest.NewRecorder()
+	srv.handleRefreshSession(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			session = c
+		}
+	}
+	if session == nil {
+		t.Fatal("Expected a session cookie to be issued")
+	}
+	if _, ok := verifySessionToken(session.Value, srv.botToken); !ok {
+		t.Error("Expected the reissued token to verify")
+	}
+	if session.Expires.Before(time.Now().Add(sessionTTL - time.Minute)) {
+		t.Errorf("Expected expiry about %v ahead, got %v", sessionTTL, session.Expires)
+	}
+}
+
+func TestHandleLogout(t *testing.T) {
+	srv := &Server{botToken: "test-token", allowedUserID: 123456}
+
+	w := httptest.NewRecorder()
+	srv.handleLogout(w, httptest.NewRequest("POST", "/api/auth/logout", nil))
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookieName || cookies[0].MaxAge >= 0 || cookies[0].Value != "" {
+		t.Errorf("Expected the session cookie to be cleared, got %+v", cookies)
+	}
+}
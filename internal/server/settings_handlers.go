@@ -0,0 +1,372 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func (s *Server) handleGetRetention(w http.ResponseWriter, r *http.Request) {
+	days, err := s.store.GetRetentionDays()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"retention_days": days,
+	})
+}
+
+// handleUpdateRetention sets how many days of intakes, BP, weight and sleep records
+// are kept. The scheduler purges older records daily; 0 keeps everything.
+func (s *Server) handleUpdateRetention(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Days int `json:"days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Days < 0 {
+		http.Error(w, "days must be 0 (keep everything) or a positive number", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.SetRetentionDays(req.Days); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":         "ok",
+		"retention_days": req.Days,
+	})
+}
+
+// reminderTemplatePreview renders the template with sample values so the UI can show the result
+func reminderTemplatePreview(tmpl string) string {
+	sample := store.Medication{Name: "Aspirin", Dosage: "100mg"}
+	return store.RenderReminderTemplate(tmpl, sample, time.Date(2000, 1, 1, 8, 0, 0, 0, time.UTC))
+}
+
+func (s *Server) handleGetReminderTemplate(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	tmpl, err := s.store.GetReminderTemplate(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"template": tmpl,
+		"default":  store.DefaultReminderTemplate,
+		"preview":  reminderTemplatePreview(tmpl),
+	})
+}
+
+// handleUpdateReminderTemplate stores the text used for each medication in reminders.
+// Supports {name}, {dosage} and {time}; an empty template restores the default.
+func (s *Server) handleUpdateReminderTemplate(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	var req struct {
+		Template string `json:"template"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.SetReminderTemplate(userID, req.Template); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tmpl, err := s.store.GetReminderTemplate(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "ok",
+		"template": tmpl,
+		"preview":  reminderTemplatePreview(tmpl),
+	})
+}
+
+func (s *Server) handleGetCurrency(w http.ResponseWriter, r *http.Request) {
+	currency, err := s.store.GetCurrency()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"currency": currency,
+	})
+}
+
+// handleUpdateCurrency sets the currency used to report restock costs.
+// An empty code restores the default.
+func (s *Server) handleUpdateCurrency(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Currency string `json:"currency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.SetCurrency(req.Currency); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	currency, err := s.store.GetCurrency()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "ok",
+		"currency": currency,
+	})
+}
+
+func (s *Server) handleGetMedSnooze(w http.ResponseWriter, r *http.Request) {
+	minutes, err := s.store.GetMedSnoozeMinutes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"minutes": minutes,
+	})
+}
+
+// handleUpdateMedSnooze sets the snooze buttons shown on medication reminders,
+// e.g. {"minutes": [10, 30, 90]}. An empty list restores the default.
+func (s *Server) handleUpdateMedSnooze(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Minutes []int `json:"minutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.SetMedSnoozeMinutes(req.Minutes); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	minutes, err := s.store.GetMedSnoozeMinutes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "ok",
+		"minutes": minutes,
+	})
+}
+
+func (s *Server) handleGetReminderChannels(w http.ResponseWriter, r *http.Request) {
+	channels, err := s.store.GetReminderChannels()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(channels)
+}
+
+// handleUpdateReminderChannels changes where reminders are delivered, per type, e.g.
+// {"bp": {"telegram": false}}. Omitted types and channels keep their current setting.
+func (s *Server) handleUpdateReminderChannels(w http.ResponseWriter, r *http.Request) {
+	var req map[string]struct {
+		Telegram *bool `json:"telegram"`
+		WebPush  *bool `json:"web_push"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	current, err := s.store.GetReminderChannels()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	update := make(map[string]store.ReminderChannels, len(req))
+	for reminderType, patch := range req {
+		c := current[reminderType]
+		if patch.Telegram != nil {
+			c.Telegram = *patch.Telegram
+		}
+		if patch.WebPush != nil {
+			c.WebPush = *patch.WebPush
+		}
+		update[reminderType] = c
+	}
+
+	if err := s.store.SetReminderChannels(update); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	channels, err := s.store.GetReminderChannels()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(channels)
+}
+
+func (s *Server) handleGetConfirmWindow(w http.ResponseWriter, r *http.Request) {
+	minutes, err := s.store.GetConfirmWindowMinutes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"minutes": minutes,
+	})
+}
+
+// handleUpdateConfirmWindow sets how far "Confirm ALL" may be from a dose's scheduled time
+// and still confirm it (same day only); 0 requires the exact time
+func (s *Server) handleUpdateConfirmWindow(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Minutes int `json:"minutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.SetConfirmWindowMinutes(req.Minutes); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "ok",
+		"minutes": req.Minutes,
+	})
+}
+
+// weekStartNames maps the week_start setting's API values to weekdays
+var weekStartNames = map[string]time.Weekday{
+	"sunday": time.Sunday,
+	"monday": time.Monday,
+}
+
+func (s *Server) handleGetWeekStart(w http.ResponseWriter, r *http.Request) {
+	day, err := s.store.GetWeekStart()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"week_start": strings.ToLower(day.String()),
+	})
+}
+
+// handleUpdateWeekStart sets the first day of the week ("sunday" or "monday") used for
+// weekly buckets
+func (s *Server) handleUpdateWeekStart(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WeekStart string `json:"week_start"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	day, ok := weekStartNames[strings.ToLower(req.WeekStart)]
+	if !ok {
+		http.Error(w, "Invalid week_start (want sunday or monday)", http.StatusBadRequest)
+		return
+	}
+	if err := s.store.SetWeekStart(day); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "ok",
+		"week_start": strings.ToLower(day.String()),
+	})
+}
+
+func (s *Server) handleGetBPDefaults(w http.ResponseWriter, r *http.Request) {
+	defaults, err := s.store.GetBPDefaults()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(defaults)
+}
+
+// handleUpdateBPDefaults sets the site and position used for BP readings that omit them,
+// e.g. {"site": "left_arm", "position": "seated"}. Omitted fields keep their current
+// value; an empty string clears the default.
+func (s *Server) handleUpdateBPDefaults(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Site     *string `json:"site"`
+		Position *string `json:"position"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	defaults, err := s.store.GetBPDefaults()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.Site != nil {
+		defaults.Site = strings.TrimSpace(*req.Site)
+	}
+	if req.Position != nil {
+		defaults.Position = strings.TrimSpace(*req.Position)
+	}
+
+	if err := s.store.SetBPDefaults(*defaults); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(defaults)
+}
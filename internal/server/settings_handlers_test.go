@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestHandleUpdateRetention(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	req := httptest.NewRequest("POST", "/api/settings/retention", bytes.NewBufferString(`{"days": 180}`))
+	w := httptest.NewRecorder()
+	srv.handleUpdateRetention(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if days, _ := db.GetRetentionDays(); days != 180 {
+		t.Errorf("Expected retention 180 days, got %d", days)
+	}
+
+	req = httptest.NewRequest("POST", "/api/settings/retention", bytes.NewBufferString(`{"days": -5}`))
+	w = httptest.NewRecorder()
+	srv.handleUpdateRetention(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for negative days, got %d", w.Code)
+	}
+}
+
+func TestHandleUpdateReminderTemplate(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	req := withUser(httptest.NewRequest("POST", "/api/settings/reminder-template",
+		bytes.NewBufferString(`{"template": "Time for {name} ({dosage}) at {time}"}`)), 123456)
+	w := httptest.NewRecorder()
+	srv.handleUpdateReminderTemplate(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "Time for Aspirin (100mg) at 08:00") {
+		t.Errorf("Expected rendered preview, got %s", w.Body.String())
+	}
+	if tmpl, _ := db.GetReminderTemplate(123456); tmpl != "Time for {name} ({dosage}) at {time}" {
+		t.Errorf("Template not stored, got %q", tmpl)
+	}
+
+	req = withUser(httptest.NewRequest("POST", "/api/settings/reminder-template",
+		bytes.NewBufferString(`{"template": "Take {medication}"}`)), 123456)
+	w = httptest.NewRecorder()
+	srv.handleUpdateReminderTemplate(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown placeholder, got %d", w.Code)
+	}
+}
+
+func TestHandleUpdateReminderChannels(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	req := httptest.NewRequest("PATCH", "/api/settings/channels", bytes.NewBufferString(`{"bp": {"telegram": false}}`))
+	w := httptest.NewRecorder()
+	srv.handleUpdateReminderChannels(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	bp, _ := db.ReminderChannelsFor(store.ReminderBP)
+	if bp.Telegram || !bp.WebPush {
+		t.Errorf("Expected BP via web push only, got %+v", bp)
+	}
+	if weight, _ := db.ReminderChannelsFor(store.ReminderWeight); !weight.Telegram || !weight.WebPush {
+		t.Errorf("Expected weight reminders untouched, got %+v", weight)
+	}
+
+	req = httptest.NewRequest("PATCH", "/api/settings/channels", bytes.NewBufferString(`{"glucose": {"telegram": false}}`))
+	w = httptest.NewRecorder()
+	srv.handleUpdateReminderChannels(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown reminder type, got %d", w.Code)
+	}
+}
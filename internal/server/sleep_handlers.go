@@ -0,0 +1,32 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// handleGetSleepStats returns sleep averages, bedtime and consistency over the last
+// ?days= days (default 30)
+func (s *Server) handleGetSleepStats(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	days := 30
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		d, err := strconv.Atoi(dStr)
+		if err != nil || d < 1 || d > 365 {
+			http.Error(w, "days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = d
+	}
+
+	stats, err := s.store.GetSleepStats(r.Context(), userID, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
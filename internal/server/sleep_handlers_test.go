@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestHandleGetSleepStats(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+	end := time.Now().Add(-time.Hour)
+	total, deep := 420, 84
+	db.ImportSleepLogs(context.Background(), userID, []store.SleepLog{
+		{StartTime: end.Add(-7 * time.Hour), EndTime: end, Day: end.Format("2006-01-02"), TotalMinutes: &total, DeepMinutes: &deep},
+	})
+
+	req := withUser(httptest.NewRequest("GET", "/api/sleep/stats?days=7", nil), userID)
+	w := httptest.NewRecorder()
+	srv.handleGetSleepStats(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var stats store.SleepStats
+	json.NewDecoder(w.Body).Decode(&stats)
+	if stats.Days != 7 || stats.Nights != 1 || stats.AvgTotalMinutes == nil || *stats.AvgTotalMinutes != 420 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.AvgDeepPercent == nil || *stats.AvgDeepPercent != 20 {
+		t.Errorf("Expected 20%% deep sleep, got %v", stats.AvgDeepPercent)
+	}
+
+	req = withUser(httptest.NewRequest("GET", "/api/sleep/stats?days=400", nil), userID)
+	w = httptest.NewRecorder()
+	srv.handleGetSleepStats(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for days=400, got %d", w.Code)
+	}
+}
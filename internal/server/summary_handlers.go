@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// handleGetWeeklySummary renders the last seven days as a Markdown report (adherence,
+// BP, weight, workouts, sleep and goal progress) to email or paste to a doctor
+func (s *Server) handleGetWeeklySummary(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	report, err := s.buildWeeklySummary(r.Context(), userID, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Write([]byte(report))
+}
+
+// handleGetTrends returns ?metric= (bp, weight or sleep) over the last ?days= (default
+// 90) as a {date, value, trend} series bucketed by ?bucket= (day, week or month;
+// default day), so every chart can share one code path
+func (s *Server) handleGetTrends(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	metric := r.URL.Query().Get("metric")
+	switch metric {
+	case store.TrendMetricBP, store.TrendMetricWeight, store.TrendMetricSleep:
+	default:
+		http.Error(w, "Invalid metric (want bp, weight or sleep)", http.StatusBadRequest)
+		return
+	}
+	bucket := r.URL.Query().Get("bucket")
+	switch bucket {
+	case "":
+		bucket = store.TrendBucketDay
+	case store.TrendBucketDay, store.TrendBucketWeek, store.TrendBucketMonth:
+	default:
+		http.Error(w, "Invalid bucket (want day, week or month)", http.StatusBadRequest)
+		return
+	}
+	days := 90
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if d, err := strconv.Atoi(dStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	points, err := s.store.GetTrendSeries(r.Context(), userID, metric, days, bucket)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"metric": metric,
+		"bucket": bucket,
+		"days":   days,
+		"points": points,
+	})
+}
+
+// handleGetFreshness reports when BP, weight, sleep and each active medication were last
+// logged and how many days ago, so the UI can nudge about stale tracking
+func (s *Server) handleGetFreshness(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	freshness, err := s.store.GetFreshness(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(freshness)
+}
+
+// buildWeeklySummary covers the seven calendar days ending with now's day and compares
+// BP against the week before
+func (s *Server) buildWeeklySummary(ctx context.Context, userID int64, now time.Time) (string, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := today.AddDate(0, 0, -6)
+	prevSince := since.AddDate(0, 0, -7)
+
+	var sb strings.Builder
+	sb.WriteString("# Weekly Health Summary\n\n")
+	sb.WriteString(fmt.Sprintf("%s – %s\n", since.Format("Mon Jan 2"), today.Format("Mon Jan 2, 2006")))
+
+	// Medication adherence
+	sb.WriteString("\n## Medication Adherence\n\n")
+	adherence, err := s.store.GetAdherenceStats(since)
+	if err != nil {
+		return "", err
+	}
+	if adherence.Taken+adherence.Missed == 0 {
+		sb.WriteString("No scheduled doses this week.\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("- Taken %d of %d scheduled doses (%.1f%%)\n", adherence.Taken, adherence.Taken+adherence.Missed, adherence.Rate))
+		if adherence.CriticalMisses > 0 {
+			sb.WriteString(fmt.Sprintf("- ⚠️ %d missed doses of critical medications\n", adherence.CriticalMisses))
+		}
+		for _, m := range adherence.Medications {
+			if m.Missed > 0 {
+				sb.WriteString(fmt.Sprintf("- %s: missed %d of %d\n", m.Name, m.Missed, m.Taken+m.Missed))
+			}
+		}
+	}
+
+	// Blood pressure
+	sb.WriteString("\n## Blood Pressure\n\n")
+	readings, err := s.store.GetBloodPressureReadingsBetween(ctx, userID, prevSince, now)
+	if err != nil {
+		return "", err
+	}
+	var week, prev []store.BloodPressure
+	for _, bp := range readings {
+		if bp.IgnoreCalc {
+			continue
+		}
+		if bp.MeasuredAt.Before(since) {
+			prev = append(prev, bp)
+		} else {
+			week = append(week, bp)
+		}
+	}
+	if len(week) == 0 {
+		sb.WriteString("No readings this week.\n")
+	} else {
+		sys, dia := averageBP(week)
+		sb.WriteString(fmt.Sprintf("- %d readings, average %.0f/%.0f mmHg\n", len(week), sys, dia))
+		if len(prev) > 0 {
+			prevSys, prevDia := averageBP(prev)
+			sb.WriteString(fmt.Sprintf("- Previous week: %.0f/%.0f mmHg (%+.0f/%+.0f)\n", prevSys, prevDia, sys-prevSys, dia-prevDia))
+		}
+		goal, err := s.store.GetBPGoal()
+		if err != nil {
+			return "", err
+		}
+		if goal.TargetSystolic != nil && goal.TargetDiastolic != nil {
+			status := "at or below goal"
+			if math.Round(sys) > float64(*goal.TargetSystolic) || math.Round(dia) > float64(*goal.TargetDiastolic) {
+				status = "above goal"
+			}
+			sb.WriteString(fmt.Sprintf("- Goal: below %d/%d mmHg, %s\n", *goal.TargetSystolic, *goal.TargetDiastolic, status))
+		}
+	}
+
+	// Weight
+	sb.WriteString("\n## Weight\n\n")
+	weights, err := s.store.GetWeightLogsBetween(ctx, userID, since, now)
+	if err != nil {
+		return "", err
+	}
+	if len(weights) == 0 {
+		sb.WriteString("No weigh-ins this week.\n")
+	} else {
+		// Logs are newest first
+		latest, first := weights[0], weights[len(weights)-1]
+		sb.WriteString(fmt.Sprintf("- Latest: %.1f kg (%s)\n", latest.Weight, latest.MeasuredAt.In(now.Location()).Format("Jan 2")))
+		if len(weights) > 1 {
+			sb.WriteString(fmt.Sprintf("- Change this week: %+.1f kg over %d weigh-ins\n", latest.Weight-first.Weight, len(weights)))
+		}
+		goal, err := s.store.GetWeightGoal()
+		if err != nil {
+			return "", err
+		}
+		if goal.Goal != nil {
+			line := fmt.Sprintf("- Goal: %.1f kg, %.1f kg to go", *goal.Goal, math.Abs(latest.Weight-*goal.Goal))
+			if goal.GoalDate != nil {
+				line += " by " + goal.GoalDate.Format("Jan 2, 2006")
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	// Workouts
+	sb.WriteString("\n## Workouts\n\n")
+	sessions, err := s.store.GetWorkoutHistory(userID, 100)
+	if err != nil {
+		return "", err
+	}
+	var completed, skipped int
+	for _, sess := range sessions {
+		if sess.ScheduledDate.Before(since) || sess.ScheduledDate.After(now) {
+			continue
+		}
+		switch sess.Status {
+		case "completed":
+			completed++
+		case "skipped":
+			skipped++
+		}
+	}
+	if completed+skipped == 0 {
+		sb.WriteString("No workouts this week.\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("- Completed %d, skipped %d\n", completed, skipped))
+	}
+
+	// Sleep
+	sb.WriteString("\n## Sleep\n\n")
+	sleep, err := s.store.GetSleepLogs(ctx, userID, since)
+	if err != nil {
+		return "", err
+	}
+	var nights, totalMinutes int
+	for _, l := range sleep {
+		if l.TotalMinutes != nil {
+			nights++
+			totalMinutes += *l.TotalMinutes
+		}
+	}
+	if nights == 0 {
+		sb.WriteString("No sleep logged this week.\n")
+	} else {
+		avg := totalMinutes / nights
+		sb.WriteString(fmt.Sprintf("- %d sleep records, average %dh %02dm\n", nights, avg/60, avg%60))
+	}
+
+	return sb.String(), nil
+}
+
+// averageBP returns the mean systolic and diastolic pressure of the readings
+func averageBP(readings []store.BloodPressure) (float64, float64) {
+	var sys, dia float64
+	for _, bp := range readings {
+		sys += float64(bp.Systolic)
+		dia += float64(bp.Diastolic)
+	}
+	n := float64(len(readings))
+	return sys / n, dia / n
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestHandleGetWeeklySummary(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := int64(123456)
+	now := time.Now()
+
+	medID, _ := db.CreateMedication("Lisinopril", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	taken, _ := db.CreateIntake(medID, userID, now.Add(-26*time.Hour))
+	db.ConfirmIntake(taken, now.Add(-26*time.Hour))
+	db.CreateIntake(medID, userID, now.Add(-50*time.Hour)) // Missed
+
+	db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: userID, MeasuredAt: now.Add(-time.Hour), Systolic: 128, Diastolic: 82})
+	db.CreateBloodPressureReading(ctx, &store.BloodPressure{UserID: userID, MeasuredAt: now.AddDate(0, 0, -9), Systolic: 140, Diastolic: 90})
+	db.SetBPGoal(130, 85)
+
+	db.CreateWeightLog(ctx, &store.WeightLog{UserID: userID, MeasuredAt: now.Add(-50 * time.Hour), Weight: 82.4})
+	db.CreateWeightLog(ctx, &store.WeightLog{UserID: userID, MeasuredAt: now.Add(-time.Hour), Weight: 81.9})
+	db.SetWeightGoal(78, now.AddDate(0, 3, 0))
+
+	total := 450
+	db.ImportSleepLogs(ctx, userID, []store.SleepLog{{StartTime: now.Add(-9 * time.Hour), EndTime: now.Add(-90 * time.Minute), Day: now.Format("2006-01-02"), TotalMinutes: &total}})
+
+	req := withUser(httptest.NewRequest("GET", "/api/summary/weekly", nil), userID)
+	w := httptest.NewRecorder()
+	srv.handleGetWeeklySummary(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/markdown") {
+		t.Errorf("Expected markdown content type, got %q", ct)
+	}
+
+	report := w.Body.String()
+	for _, want := range []string{
+		"# Weekly Health Summary",
+		"## Medication Adherence",
+		"## Blood Pressure",
+		"## Weight",
+		"## Workouts",
+		"## Sleep",
+		"Taken 1 of 2 scheduled doses (50.0%)",
+		"1 readings, average 128/82 mmHg",
+		"Previous week: 140/90 mmHg (-12/-8)",
+		"Goal: below 130/85 mmHg, at or below goal",
+		"Change this week: -0.5 kg",
+		"Goal: 78.0 kg, 3.9 kg to go",
+		"average 7h 30m",
+		"No workouts this week.",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected report to contain %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestHandleGetTrends(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := int64(123456)
+	now := time.Now()
+
+	trend := 80.0
+	db.CreateWeightLog(ctx, &store.WeightLog{UserID: userID, MeasuredAt: now.AddDate(0, 0, -3), Weight: 80.4, WeightTrend: &trend})
+
+	req := withUser(httptest.NewRequest("GET", "/api/trends?metric=weight&days=30&bucket=week", nil), userID)
+	w := httptest.NewRecorder()
+	srv.handleGetTrends(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Metric string             `json:"metric"`
+		Bucket string             `json:"bucket"`
+		Days   int                `json:"days"`
+		Points []store.TrendPoint `json:"points"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if resp.Metric != "weight" || resp.Bucket != "week" || resp.Days != 30 {
+		t.Errorf("Unexpected parameters echoed: %+v", resp)
+	}
+	if len(resp.Points) != 1 || resp.Points[0].Value != 80 || resp.Points[0].Trend != nil {
+		t.Errorf("Expected one point of 80 without trend, got %+v", resp.Points)
+	}
+
+	for _, query := range []string{"", "?metric=steps", "?metric=bp&bucket=year"} {
+		req := withUser(httptest.NewRequest("GET", "/api/trends"+query, nil), userID)
+		w := httptest.NewRecorder()
+		srv.handleGetTrends(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
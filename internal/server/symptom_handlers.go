@@ -0,0 +1,203 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// symptomRequest is the body of create and update requests
+type symptomRequest struct {
+	MeasuredAt  time.Time `json:"measured_at"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Symptoms    string    `json:"symptoms,omitempty"`
+	Severity    *int      `json:"severity,omitempty"`
+	Notes       string    `json:"notes,omitempty"`
+}
+
+func (req symptomRequest) toLog(userID int64) store.SymptomLog {
+	if req.MeasuredAt.IsZero() {
+		req.MeasuredAt = time.Now()
+	}
+	return store.SymptomLog{
+		UserID:      userID,
+		MeasuredAt:  req.MeasuredAt,
+		Temperature: req.Temperature,
+		Symptoms:    strings.TrimSpace(req.Symptoms),
+		Severity:    req.Severity,
+		Notes:       req.Notes,
+	}
+}
+
+func (s *Server) handleCreateSymptom(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	var req symptomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	l := req.toLog(userID)
+	if err := l.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := s.store.CreateSymptomLog(r.Context(), &l)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	l.ID = id
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l)
+}
+
+func (s *Server) handleUpdateSymptom(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req symptomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	l := req.toLog(userID)
+	l.ID = id
+	if err := l.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.UpdateSymptomLog(r.Context(), &l); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Entry not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l)
+}
+
+// handleListSymptoms returns entries between ?from= and ?to= (YYYY-MM-DD, both inclusive),
+// or from the last ?days= days (default 30) when no range is given
+func (s *Server) handleListSymptoms(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if from.IsZero() && to.IsZero() {
+		days := 30
+		if dStr := r.URL.Query().Get("days"); dStr != "" {
+			if d, err := strconv.Atoi(dStr); err == nil && d > 0 {
+				days = d
+			}
+		}
+		from = time.Now().AddDate(0, 0, -days)
+	}
+
+	logs, err := s.store.GetSymptomLogs(r.Context(), userID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if logs == nil {
+		logs = []store.SymptomLog{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logs)
+}
+
+func (s *Server) handleDeleteSymptom(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.DeleteSymptomLog(r.Context(), id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Entry not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseDateRange reads optional ?from= and ?to= dates (YYYY-MM-DD) in local time.
+// The returned to is exclusive, i.e. the start of the day after ?to=.
+func parseDateRange(r *http.Request) (from, to time.Time, err error) {
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from date, expected YYYY-MM-DD")
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to date, expected YYYY-MM-DD")
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must not be after to")
+	}
+	return from, to, nil
+}
+
+// writeSymptomsCSV writes temperature and symptom entries as CSV
+func writeSymptomsCSV(out io.Writer, logs []store.SymptomLog) error {
+	wr := csv.NewWriter(out)
+	header := []string{"Date", "Temperature (°C)", "Symptoms", "Severity", "Notes"}
+	if err := wr.Write(header); err != nil {
+		return err
+	}
+
+	for _, l := range logs {
+		temp := ""
+		if l.Temperature != nil {
+			temp = fmt.Sprintf("%.1f", *l.Temperature)
+		}
+		notes := strings.ReplaceAll(l.Notes, "\n", " ")
+		notes = strings.ReplaceAll(notes, "\r", "")
+
+		row := []string{
+			l.MeasuredAt.Format(time.RFC3339),
+			temp,
+			strings.ReplaceAll(l.Symptoms, "\n", " "),
+			formatOptionalInt(l.Severity),
+			notes,
+		}
+		if err := wr.Write(row); err != nil {
+			return err
+		}
+	}
+
+	wr.Flush()
+	return wr.Error()
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+func TestHandleSymptoms(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	userID := int64(123456)
+
+	create := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		req := withUser(httptest.NewRequest("POST", "/api/symptoms", bytes.NewReader(b)), userID)
+		w := httptest.NewRecorder()
+		srv.handleCreateSymptom(w, req)
+		return w
+	}
+
+	w := create(map[string]interface{}{
+		"measured_at": time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local),
+		"temperature": 38.5,
+		"symptoms":    "sore throat",
+		"severity":    4,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var created store.SymptomLog
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.ID == 0 || created.Temperature == nil || *created.Temperature != 38.5 {
+		t.Errorf("Unexpected created entry: %+v", created)
+	}
+
+	create(map[string]interface{}{"measured_at": time.Date(2024, 3, 3, 21, 0, 0, 0, time.Local), "temperature": 37.2})
+	create(map[string]interface{}{"measured_at": time.Date(2024, 3, 10, 9, 0, 0, 0, time.Local), "temperature": 36.6})
+
+	if w := create(map[string]interface{}{"temperature": 60}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for implausible temperature, got %d", w.Code)
+	}
+
+	// Both ends of the range are inclusive
+	req := withUser(httptest.NewRequest("GET", "/api/symptoms?from=2024-03-01&to=2024-03-03", nil), userID)
+	w = httptest.NewRecorder()
+	srv.handleListSymptoms(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var logs []store.SymptomLog
+	json.NewDecoder(w.Body).Decode(&logs)
+	if len(logs) != 2 {
+		t.Fatalf("Expected 2 entries in range, got %d", len(logs))
+	}
+	if *logs[0].Temperature != 37.2 || logs[1].Symptoms != "sore throat" {
+		t.Errorf("Unexpected entries: %+v", logs)
+	}
+
+	req = withUser(httptest.NewRequest("GET", "/api/symptoms?from=2024-03-05&to=2024-03-01", nil), userID)
+	w = httptest.NewRecorder()
+	srv.handleListSymptoms(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for inverted range, got %d", w.Code)
+	}
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+	"github.com/korjavin/medicationtrackerbot/internal/webhook"
+)
+
+func TestWebhook_ConfirmedIntakeDelivery(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	type delivery struct {
+		body      []byte
+		signature string
+		event     string
+	}
+	received := make(chan delivery, 5)
+	attempts := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		// Fail the first attempt to exercise the retry
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{body: body, signature: r.Header.Get(webhook.SignatureHeader), event: r.Header.Get("X-Webhook-Event")}
+	}))
+	defer target.Close()
+
+	srv.webhooks.SetRetryDelay(10 * time.Millisecond)
+
+	userID := int64(123456)
+
+	// Register webhook through the API
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"url":    target.URL,
+		"events": []string{webhook.EventIntakeConfirmed},
+		"secret": "s3cret",
+	})
+	req := withUser(httptest.NewRequest("POST", "/api/webhooks", bytes.NewReader(reqBody)), userID)
+	w := httptest.NewRecorder()
+	srv.handleCreateWebhook(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	medID, _ := db.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	intakeID, _ := db.CreateIntake(medID, userID, time.Now().Add(-time.Hour))
+
+	confirmBody, _ := json.Marshal(map[string]interface{}{"intake_ids": []int64{intakeID}})
+	req = withUser(httptest.NewRequest("POST", "/api/medications/confirm-schedule", bytes.NewReader(confirmBody)), userID)
+	w = httptest.NewRecorder()
+	srv.handleConfirmSchedule(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var d delivery
+	select {
+	case d = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for webhook delivery")
+	}
+
+	if d.event != webhook.EventIntakeConfirmed {
+		t.Errorf("Expected event header %s, got %s", webhook.EventIntakeConfirmed, d.event)
+	}
+	if want := "sha256=" + webhook.Sign("s3cret", d.body); d.signature != want {
+		t.Errorf("Expected signature %s, got %s", want, d.signature)
+	}
+
+	var payload struct {
+		Event string `json:"event"`
+		Data  struct {
+			IntakeID       int64  `json:"intake_id"`
+			MedicationID   int64  `json:"medication_id"`
+			MedicationName string `json:"medication_name"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(d.body, &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if payload.Event != webhook.EventIntakeConfirmed || payload.Data.IntakeID != intakeID ||
+		payload.Data.MedicationID != medID || payload.Data.MedicationName != "Med A" {
+		t.Errorf("Unexpected payload: %s", d.body)
+	}
+}
+
+func TestHandleCreateWebhook_Validation(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	for _, body := range []string{
+		`{"url":"ftp://example.com","events":["intake.confirmed"]}`,
+		`{"url":"https://example.com","events":[]}`,
+		`{"url":"https://example.com","events":["unknown"]}`,
+	} {
+		req := withUser(httptest.NewRequest("POST", "/api/webhooks", bytes.NewReader([]byte(body))), 123456)
+		w := httptest.NewRecorder()
+		srv.handleCreateWebhook(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestHandleListWebhooks_MasksSecret(t *testing.T) {
+	srv, db := createTestServer(t)
+	defer db.Close()
+
+	body := `{"url":"https://example.com/hook","events":["intake.confirmed"],"secret":"s3cr3t-signing-key"}`
+	req := withUser(httptest.NewRequest("POST", "/api/webhooks", bytes.NewReader([]byte(body))), 123456)
+	w := httptest.NewRecorder()
+	srv.handleCreateWebhook(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = withUser(httptest.NewRequest("GET", "/api/webhooks", nil), 123456)
+	w = httptest.NewRecorder()
+	srv.handleListWebhooks(w, req)
+
+	var hooks []store.Webhook
+	if err := json.NewDecoder(w.Body).Decode(&hooks); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(hooks) != 1 || hooks[0].Secret != "****-key" {
+		t.Errorf("Expected the secret masked to its last 4 characters, got %+v", hooks)
+	}
+
+	// Deliveries still sign with the full secret
+	stored, _ := db.ListWebhooks(123456)
+	if len(stored) != 1 || stored[0].Secret != "s3cr3t-signing-key" {
+		t.Errorf("Expected the stored secret to be unchanged, got %+v", stored)
+	}
+}
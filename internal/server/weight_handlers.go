@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -22,34 +23,29 @@ func (s *Server) handleCreateWeight(w http.ResponseWriter, r *http.Request) {
 		BodyFat    *float64  `json:"body_fat,omitempty"`
 		MuscleMass *float64  `json:"muscle_mass,omitempty"`
 		Notes      string    `json:"notes,omitempty"`
+		Tag        string    `json:"tag,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	// Get last weight log to calculate trend
+	// Get last weight log to calculate trends
 	lastLog, err := s.store.GetLastWeightLog(r.Context(), userID)
 	if err != nil {
 		// Log error but continue
 	}
 
-	var previousTrend *float64
-	if lastLog != nil && lastLog.WeightTrend != nil {
-		previousTrend = lastLog.WeightTrend
-	}
-
-	weightTrend := store.CalculateWeightTrend(req.Weight, previousTrend)
-
 	wLog := &store.WeightLog{
-		UserID:      userID,
-		MeasuredAt:  req.MeasuredAt,
-		Weight:      req.Weight,
-		WeightTrend: &weightTrend,
-		BodyFat:     req.BodyFat,
-		MuscleMass:  req.MuscleMass,
-		Notes:       req.Notes,
+		UserID:     userID,
+		MeasuredAt: req.MeasuredAt,
+		Weight:     req.Weight,
+		BodyFat:    req.BodyFat,
+		MuscleMass: req.MuscleMass,
+		Notes:      req.Notes,
+		Tag:        strings.TrimSpace(req.Tag),
 	}
+	wLog.SetTrends(lastLog)
 
 	id, err := s.store.CreateWeightLog(r.Context(), wLog)
 	if err != nil {
@@ -85,7 +81,8 @@ func (s *Server) handleListWeight(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	logs, err := s.store.GetWeightLogs(r.Context(), userID, since)
+	// Optional ?tag= narrows the list to e.g. fasted morning weigh-ins
+	logs, err := s.store.GetWeightLogsByTag(r.Context(), userID, since, r.URL.Query().Get("tag"))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -120,74 +117,132 @@ func (s *Server) handleDeleteWeight(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func (s *Server) handleExportWeight(w http.ResponseWriter, r *http.Request) {
+// handleDeleteWeightRange removes every weight log in ?from=&to= at once and rebuilds
+// the trend chain so later logs don't keep trends based on the deleted ones
+func (s *Server) handleDeleteWeightRange(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
 
-	// Parse query params
-	var since time.Time
-	if dStr := r.URL.Query().Get("days"); dStr != "" {
-		if days, err := strconv.Atoi(dStr); err == nil && days > 0 {
-			since = time.Now().AddDate(0, 0, -days)
+	from, to, err := parseDeleteRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := s.store.DeleteWeightLogsInRange(r.Context(), userID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if deleted > 0 {
+		if _, _, err := s.store.RecomputeWeightTrends(r.Context(), userID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 	}
 
-	logs, err := s.store.GetWeightLogs(r.Context(), userID, since)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "ok",
+		"deleted": deleted,
+	})
+}
+
+// handleRecomputeWeightTrends rebuilds the weight trend chain in measured_at order,
+// fixing trends left inconsistent by out-of-order inserts, imports or deletes
+func (s *Server) handleRecomputeWeightTrends(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	total, updated, err := s.store.RecomputeWeightTrends(r.Context(), userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/csv")
-	w.Header().Set("Content-Disposition", "attachment; filename=weight_export.csv")
-
-	wr := csv.NewWriter(w)
-	defer wr.Flush()
-
-	// Write CSV header in Libra format
-	wr.Write([]string{"#Version: 6"})
-	wr.Write([]string{"#Units: kg"})
-	wr.Write([]string{""})
-	wr.Write([]string{"#date;weight;weight trend;body fat;body fat trend;muscle mass;muscle mass trend;log"})
-
-	// Write data rows
-	for _, wLog := range logs {
-		weight := fmt.Sprintf("%.1f", wLog.Weight)
-		weightTrend := ""
-		if wLog.WeightTrend != nil {
-			weightTrend = fmt.Sprintf("%.1f", *wLog.WeightTrend)
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "ok",
+		"total":   total,
+		"updated": updated,
+	})
+}
 
-		bodyFat := ""
-		if wLog.BodyFat != nil {
-			bodyFat = fmt.Sprintf("%.1f", *wLog.BodyFat)
-		}
+// handleExportWeight downloads weigh-ins as ?format=libra (default), a file the Libra
+// app imports as is, or as plain CSV with ?format=csv
+func (s *Server) handleExportWeight(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
 
-		bodyFatTrend := ""
-		if wLog.BodyFatTrend != nil {
-			bodyFatTrend = fmt.Sprintf("%.1f", *wLog.BodyFatTrend)
-		}
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "libra":
+		format = "libra"
+	case "csv":
+	default:
+		http.Error(w, "Invalid format (want libra or csv)", http.StatusBadRequest)
+		return
+	}
 
-		muscleMass := ""
-		if wLog.MuscleMass != nil {
-			muscleMass = fmt.Sprintf("%.1f", *wLog.MuscleMass)
-		}
+	since, until, err := parseExportRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		muscleMassTrend := ""
-		if wLog.MuscleMassTrend != nil {
-			muscleMassTrend = fmt.Sprintf("%.1f", *wLog.MuscleMassTrend)
-		}
+	logs, err := s.store.GetWeightLogsBetween(r.Context(), userID, since, until)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		notes := strings.ReplaceAll(wLog.Notes, "\n", " ")
-		notes = strings.ReplaceAll(notes, "\r", "")
+	if wantsAnonymized(r) {
+		logs = anonymizeWeightLogs(logs)
+	}
 
+	w.Header().Set("Content-Type", "text/csv")
+	if format == "libra" {
+		w.Header().Set("Content-Disposition", "attachment; filename=weight_libra.csv")
+		err = store.WriteWeightLibra(w, logs)
+	} else {
+		w.Header().Set("Content-Disposition", "attachment; filename=weight_export.csv")
+		err = writeWeightCSV(w, logs)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeWeightCSV writes weight logs as plain CSV, one column per field
+func writeWeightCSV(out io.Writer, logs []store.WeightLog) error {
+	wr := csv.NewWriter(out)
+	header := []string{"Date", "Weight", "Weight Trend", "Body Fat", "Body Fat Trend", "Muscle Mass", "Muscle Mass Trend", "Tag", "Notes"}
+	if err := wr.Write(header); err != nil {
+		return err
+	}
+
+	optional := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.1f", *v)
+	}
+	for _, l := range logs {
 		row := []string{
-			wLog.MeasuredAt.Format("2006-01-02T15:04:05.000Z") + ";" + weight + ";" + weightTrend + ";" + bodyFat + ";" + bodyFatTrend + ";" + muscleMass + ";" + muscleMassTrend + ";" + notes,
+			l.MeasuredAt.Format(time.RFC3339),
+			fmt.Sprintf("%.1f", l.Weight),
+			optional(l.WeightTrend),
+			optional(l.BodyFat),
+			optional(l.BodyFatTrend),
+			optional(l.MuscleMass),
+			optional(l.MuscleMassTrend),
+			l.Tag,
+			l.Notes,
 		}
 		if err := wr.Write(row); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return err
 		}
 	}
+
+	wr.Flush()
+	return wr.Error()
 }
 
 func (s *Server) handleGetWeightGoal(w http.ResponseWriter, r *http.Request) {
@@ -286,3 +341,49 @@ func (s *Server) handleDontBugMeWeightReminder(w http.ResponseWriter, r *http.Re
 		"message": "Weight reminders disabled for 24 hours",
 	})
 }
+
+// handleGetWeightSinceMed compares the average weight in the ?days= (default 90) before
+// and after a medication's start date, for meds known to cause weight gain or loss
+func (s *Server) handleGetWeightSinceMed(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserCtxKey).(*TelegramUser).ID
+
+	medID, err := strconv.ParseInt(r.URL.Query().Get("med_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid med_id", http.StatusBadRequest)
+		return
+	}
+	days := 90
+	if dStr := r.URL.Query().Get("days"); dStr != "" {
+		if d, err := strconv.Atoi(dStr); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	med, err := s.store.GetMedication(medID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if med == nil {
+		http.Error(w, "Medication not found", http.StatusNotFound)
+		return
+	}
+	if med.StartDate == nil {
+		http.Error(w, "Medication has no start date", http.StatusBadRequest)
+		return
+	}
+
+	comparison, err := s.store.CompareWeightAround(r.Context(), userID, *med.StartDate, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"medication_id":   med.ID,
+		"medication_name": med.Name,
+		"start_date":      med.StartDate,
+		"comparison":      comparison,
+	})
+}
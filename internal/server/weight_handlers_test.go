@@ -3,8 +3,10 @@ package server
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -82,6 +84,61 @@ func TestHandleCreateWeight(t *testing.T) {
 	}
 }
 
+func TestHandleCreateWeight_BodyCompositionTrends(t *testing.T) {
+	srv, db := createWeightTestServer(t)
+	defer db.Close()
+
+	start := time.Now().Add(-72 * time.Hour)
+	create := func(i int, body map[string]interface{}) store.WeightLog {
+		t.Helper()
+		body["measured_at"] = start.Add(time.Duration(i) * 24 * time.Hour)
+		b, _ := json.Marshal(body)
+		req := weightReqWithUser(httptest.NewRequest("POST", "/api/weight", bytes.NewReader(b)), 123456)
+		w := httptest.NewRecorder()
+		srv.handleCreateWeight(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+		}
+		var resp store.WeightLog
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+	near := func(v *float64, want float64) bool {
+		return v != nil && math.Abs(*v-want) < 1e-9
+	}
+
+	// The first reading starts the trends
+	first := create(0, map[string]interface{}{"weight": 80.0, "body_fat": 25.0, "muscle_mass": 35.0})
+	if !near(first.BodyFatTrend, 25) || !near(first.MuscleMassTrend, 35) {
+		t.Errorf("Expected trends to start at the readings, got %v and %v", first.BodyFatTrend, first.MuscleMassTrend)
+	}
+
+	// 0.1 * 20 + 0.9 * 25 = 24.5; muscle mass wasn't measured and carries forward
+	second := create(1, map[string]interface{}{"weight": 80.0, "body_fat": 20.0})
+	if !near(second.BodyFatTrend, 24.5) {
+		t.Errorf("Expected body fat trend 24.5, got %v", second.BodyFatTrend)
+	}
+	if second.MuscleMass != nil || !near(second.MuscleMassTrend, 35) {
+		t.Errorf("Expected muscle mass trend 35 carried forward, got %v", second.MuscleMassTrend)
+	}
+
+	// Neither measured: both trends stay as they were
+	third := create(2, map[string]interface{}{"weight": 79.0})
+	if !near(third.BodyFatTrend, 24.5) || !near(third.MuscleMassTrend, 35) {
+		t.Errorf("Expected trends carried forward, got %v and %v", third.BodyFatTrend, third.MuscleMassTrend)
+	}
+
+	// The stored trends reach the Libra export
+	var buf bytes.Buffer
+	logs, _ := db.GetWeightLogs(weightCtxWithUser(123456), 123456, time.Time{})
+	if err := store.WriteWeightLibra(&buf, logs); err != nil {
+		t.Fatalf("WriteWeightLibra failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), ";24.5;") {
+		t.Errorf("Expected the body fat trend in the Libra export, got:\n%s", buf.String())
+	}
+}
+
 func TestHandleListWeight(t *testing.T) {
 	srv, db := createWeightTestServer(t)
 	defer db.Close()
@@ -172,6 +229,66 @@ func TestHandleExportWeight(t *testing.T) {
 	if !strings.Contains(w.Body.String(), "80.0") {
 		t.Errorf("Expected body to contain '80.0', got %s", w.Body.String())
 	}
+	if !strings.HasPrefix(w.Body.String(), "#Version: 6\n#Units: kg\n\n#date;weight;") {
+		t.Errorf("Expected Libra format by default, got %s", w.Body.String())
+	}
+
+	req = weightReqWithUser(httptest.NewRequest("GET", "/api/weight/export?format=csv", nil), 123456)
+	w = httptest.NewRecorder()
+	srv.handleExportWeight(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for csv, got %d", w.Code)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, got %v", err)
+	}
+	if len(records) != 2 || records[0][0] != "Date" || records[1][1] != "80.0" || records[1][8] != "Test Note" {
+		t.Errorf("Unexpected plain CSV: %q", records)
+	}
+
+	req = weightReqWithUser(httptest.NewRequest("GET", "/api/weight/export?format=xml", nil), 123456)
+	w = httptest.NewRecorder()
+	srv.handleExportWeight(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown format, got %d", w.Code)
+	}
+}
+
+func TestHandleExportWeight_DateRange(t *testing.T) {
+	srv, db := createWeightTestServer(t)
+	defer db.Close()
+
+	ctx := weightCtxWithUser(123456)
+	for _, l := range []struct {
+		at     time.Time
+		weight float64
+	}{
+		{time.Date(2024, 2, 28, 8, 0, 0, 0, time.Local), 81.1},
+		{time.Date(2024, 3, 15, 8, 0, 0, 0, time.Local), 82.2},
+		{time.Date(2024, 4, 2, 8, 0, 0, 0, time.Local), 83.3},
+	} {
+		db.CreateWeightLog(ctx, &store.WeightLog{UserID: 123456, MeasuredAt: l.at, Weight: l.weight})
+	}
+
+	req := weightReqWithUser(httptest.NewRequest("GET", "/api/weight/export?from=2024-03-01&to=2024-03-31", nil), 123456)
+	w := httptest.NewRecorder()
+	srv.handleExportWeight(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "82.2") || strings.Contains(body, "81.1") || strings.Contains(body, "83.3") {
+		t.Errorf("Expected only the March log, got %s", body)
+	}
+
+	req = weightReqWithUser(httptest.NewRequest("GET", "/api/weight/export?from=2024-03-01&to=bad", nil), 123456)
+	w = httptest.NewRecorder()
+	srv.handleExportWeight(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid date, got %d", w.Code)
+	}
 }
 
 func TestHandleGetWeightGoal(t *testing.T) {
@@ -197,3 +314,269 @@ func TestHandleGetWeightGoal(t *testing.T) {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 }
+
+func TestHandleRecomputeWeightTrends(t *testing.T) {
+	srv, db := createWeightTestServer(t)
+	defer db.Close()
+
+	base := time.Now().AddDate(0, 0, -10)
+	create := func(daysAfter int, weight float64) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"measured_at": base.AddDate(0, 0, daysAfter),
+			"weight":      weight,
+		})
+		req := weightReqWithUser(httptest.NewRequest("POST", "/api/weight", bytes.NewReader(body)), 123456)
+		w := httptest.NewRecorder()
+		srv.handleCreateWeight(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	// Steadily falling weight, but day 1 is entered after day 2
+	create(0, 100)
+	create(2, 90)
+	create(1, 95)
+
+	req := weightReqWithUser(httptest.NewRequest("POST", "/api/weight/recompute-trends", nil), 123456)
+	w := httptest.NewRecorder()
+	srv.handleRecomputeWeightTrends(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Total   int `json:"total"`
+		Updated int `json:"updated"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Total != 3 || resp.Updated != 2 {
+		t.Errorf("Expected 3 total / 2 updated, got %+v", resp)
+	}
+
+	logs, _ := db.GetWeightLogs(weightCtxWithUser(123456), 123456, time.Time{})
+	var prev *float64
+	for i := len(logs) - 1; i >= 0; i-- {
+		want := store.CalculateWeightTrend(logs[i].Weight, prev)
+		got := logs[i].WeightTrend
+		if got == nil || fmt.Sprintf("%.6f", *got) != fmt.Sprintf("%.6f", want) {
+			t.Errorf("Log %d: expected trend %.4f, got %v", i, want, got)
+		}
+		if prev != nil && got != nil && *got >= *prev {
+			t.Errorf("Expected falling trend chain, %.4f follows %.4f", *got, *prev)
+		}
+		prev = &want
+	}
+
+	// A second run finds nothing left to fix
+	w = httptest.NewRecorder()
+	srv.handleRecomputeWeightTrends(w, req)
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Updated != 0 {
+		t.Errorf("Expected no updates on second run, got %d", resp.Updated)
+	}
+}
+
+func TestHandleRecomputeWeightTrends_BodyComposition(t *testing.T) {
+	srv, db := createWeightTestServer(t)
+	defer db.Close()
+
+	base := time.Now().AddDate(0, 0, -10)
+	create := func(daysAfter int, weight, bodyFat, muscleMass float64) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"measured_at": base.AddDate(0, 0, daysAfter),
+			"weight":      weight,
+			"body_fat":    bodyFat,
+			"muscle_mass": muscleMass,
+		})
+		req := weightReqWithUser(httptest.NewRequest("POST", "/api/weight", bytes.NewReader(body)), 123456)
+		w := httptest.NewRecorder()
+		srv.handleCreateWeight(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	// Entered out of order, so every chain is built from the wrong predecessor
+	create(0, 100, 30, 40)
+	create(2, 90, 20, 50)
+	create(1, 95, 25, 45)
+
+	req := weightReqWithUser(httptest.NewRequest("POST", "/api/weight/recompute-trends", nil), 123456)
+	w := httptest.NewRecorder()
+	srv.handleRecomputeWeightTrends(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	logs, _ := db.GetWeightLogs(weightCtxWithUser(123456), 123456, time.Time{})
+	var prev *store.WeightLog
+	for i := len(logs) - 1; i >= 0; i-- {
+		want := logs[i]
+		want.SetTrends(prev)
+		for name, pair := range map[string][2]*float64{
+			"body fat":    {logs[i].BodyFatTrend, want.BodyFatTrend},
+			"muscle mass": {logs[i].MuscleMassTrend, want.MuscleMassTrend},
+		} {
+			if pair[0] == nil || fmt.Sprintf("%.6f", *pair[0]) != fmt.Sprintf("%.6f", *pair[1]) {
+				t.Errorf("Log %d: expected %s trend %.4f, got %v", i, name, *pair[1], pair[0])
+			}
+		}
+		prev = &want
+	}
+}
+
+func TestHandleWeightTags(t *testing.T) {
+	srv, db := createWeightTestServer(t)
+	defer db.Close()
+
+	for i, tag := range []string{"morning-fasted", "post-workout", "morning-fasted", ""} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"measured_at": time.Now().Add(-time.Duration(i) * time.Hour),
+			"weight":      80.0 + float64(i),
+			"tag":         tag,
+		})
+		req := weightReqWithUser(httptest.NewRequest("POST", "/api/weight", bytes.NewReader(body)), 123456)
+		w := httptest.NewRecorder()
+		srv.handleCreateWeight(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// Round trip through the store
+	logs, err := db.GetWeightLogs(weightCtxWithUser(123456), 123456, time.Time{})
+	if err != nil {
+		t.Fatalf("GetWeightLogs failed: %v", err)
+	}
+	if len(logs) != 4 || logs[0].Tag != "morning-fasted" || logs[1].Tag != "post-workout" || logs[3].Tag != "" {
+		t.Fatalf("Unexpected tags: %+v", logs)
+	}
+
+	req := weightReqWithUser(httptest.NewRequest("GET", "/api/weight?tag=morning-fasted", nil), 123456)
+	w := httptest.NewRecorder()
+	srv.handleListWeight(w, req)
+
+	var filtered []store.WeightLog
+	if err := json.NewDecoder(w.Body).Decode(&filtered); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(filtered) != 2 {
+		t.Fatalf("Expected 2 morning-fasted logs, got %d", len(filtered))
+	}
+	for _, l := range filtered {
+		if l.Tag != "morning-fasted" {
+			t.Errorf("Unexpected tag %q in filtered list", l.Tag)
+		}
+	}
+
+	req = weightReqWithUser(httptest.NewRequest("GET", "/api/weight/export", nil), 123456)
+	w = httptest.NewRecorder()
+	srv.handleExportWeight(w, req)
+	if !strings.Contains(w.Body.String(), "#post-workout") {
+		t.Errorf("Expected export to include the tag, got %s", w.Body.String())
+	}
+}
+
+func TestHandleGetWeightSinceMed(t *testing.T) {
+	srv, db := createWeightTestServer(t)
+	defer db.Close()
+
+	ctx := weightCtxWithUser(123456)
+	start := time.Now().AddDate(0, 0, -20).Truncate(time.Hour)
+	medID, _ := db.CreateMedication("Mirtazapine", "15mg", `{"type":"daily","times":["21:00"]}`, &start, nil, "", "")
+	noStartID, _ := db.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := weightReqWithUser(httptest.NewRequest("GET", "/api/analysis/weight-since-med?"+query, nil), 123456)
+		w := httptest.NewRecorder()
+		srv.handleGetWeightSinceMed(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) store.WeightComparison {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var res struct {
+			Comparison store.WeightComparison `json:"comparison"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return res.Comparison
+	}
+
+	// Only one weigh-in before the start: not enough to compare
+	db.CreateWeightLog(ctx, &store.WeightLog{UserID: 123456, MeasuredAt: start.AddDate(0, 0, -10), Weight: 80.0})
+	db.CreateWeightLog(ctx, &store.WeightLog{UserID: 123456, MeasuredAt: start.AddDate(0, 0, 5), Weight: 82.0})
+	db.CreateWeightLog(ctx, &store.WeightLog{UserID: 123456, MeasuredAt: start.AddDate(0, 0, 15), Weight: 83.0})
+	res := decode(get(fmt.Sprintf("med_id=%d", medID)))
+	if !res.InsufficientData || res.Delta != nil || res.BeforeCount != 1 || res.AfterCount != 2 {
+		t.Errorf("Expected insufficient data with 1 before and 2 after, got %+v", res)
+	}
+
+	// Weight rises after the start
+	db.CreateWeightLog(ctx, &store.WeightLog{UserID: 123456, MeasuredAt: start.AddDate(0, 0, -3), Weight: 81.0})
+	db.CreateWeightLog(ctx, &store.WeightLog{UserID: 123456, MeasuredAt: start.AddDate(0, 0, -200), Weight: 70.0}) // Outside the window
+	res = decode(get(fmt.Sprintf("med_id=%d", medID)))
+	if res.InsufficientData || res.Delta == nil {
+		t.Fatalf("Expected a delta, got %+v", res)
+	}
+	if *res.BeforeAvg != 80.5 || *res.AfterAvg != 82.5 || *res.Delta != 2.0 {
+		t.Errorf("Expected 80.5 -> 82.5 (+2.0), got %v -> %v (%+v)", *res.BeforeAvg, *res.AfterAvg, *res.Delta)
+	}
+
+	if w := get(fmt.Sprintf("med_id=%d", noStartID)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a medication without start date, got %d", w.Code)
+	}
+	if w := get("med_id=999"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown medication, got %d", w.Code)
+	}
+	if w := get("med_id=abc"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid med_id, got %d", w.Code)
+	}
+}
+
+func TestHandleDeleteWeightRange(t *testing.T) {
+	srv, db := createWeightTestServer(t)
+	defer db.Close()
+
+	ctx := weightCtxWithUser(123456)
+	base := time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC)
+	for i, weight := range []float64{80, 20, 21, 79} {
+		db.CreateWeightLog(ctx, &store.WeightLog{UserID: 123456, MeasuredAt: base.Add(time.Duration(i) * time.Hour), Weight: weight})
+	}
+
+	from := base.Add(time.Hour).Format(time.RFC3339)
+	to := base.Add(3 * time.Hour).Format(time.RFC3339)
+	req := httptest.NewRequest("DELETE", "/api/weight?from="+from+"&to="+to, nil)
+	req = weightReqWithUser(req, 123456)
+	w := httptest.NewRecorder()
+	srv.handleDeleteWeightRange(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Deleted int64 `json:"deleted"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Deleted != 2 {
+		t.Errorf("Expected 2 logs deleted, got %d", resp.Deleted)
+	}
+
+	logs, _ := db.GetWeightLogs(ctx, 123456, time.Time{})
+	if len(logs) != 2 {
+		t.Fatalf("Expected 2 logs left, got %d", len(logs))
+	}
+	// Newest first; the trend no longer reflects the deleted garbage
+	if logs[0].Weight != 79 || logs[1].Weight != 80 {
+		t.Errorf("Expected the 80 and 79 kg logs to remain, got %.1f and %.1f", logs[1].Weight, logs[0].Weight)
+	}
+	if logs[0].WeightTrend == nil || *logs[0].WeightTrend < 79 {
+		t.Errorf("Expected the trend to be recomputed without the deleted logs, got %v", logs[0].WeightTrend)
+	}
+}
@@ -1,10 +1,12 @@
 package server
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/korjavin/medicationtrackerbot/internal/store"
@@ -221,6 +223,47 @@ func (s *Server) handleDeleteWorkoutVariant(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleReorderWorkoutVariants sets the rotation sequence of a group's variants, e.g.
+// {"group_id": 1, "variant_ids": [3, 1, 2]}
+func (s *Server) handleReorderWorkoutVariants(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		GroupID    int64   `json:"group_id"`
+		VariantIDs []int64 `json:"variant_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	variants, err := s.store.ListVariantsByGroup(req.GroupID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(variants) == 0 {
+		http.Error(w, "Group has no variants", http.StatusNotFound)
+		return
+	}
+	if err := store.ValidateVariantOrder(variants, req.VariantIDs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.ReorderWorkoutVariants(req.GroupID, req.VariantIDs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	variants, err = s.store.ListVariantsByGroup(req.GroupID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(variants)
+}
+
 // -- Exercise Handlers --
 
 func (s *Server) handleListExercisesByVariant(w http.ResponseWriter, r *http.Request) {
@@ -243,19 +286,29 @@ func (s *Server) handleListExercisesByVariant(w http.ResponseWriter, r *http.Req
 
 func (s *Server) handleCreateExercise(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		VariantID      int64    `json:"variant_id"`
-		ExerciseName   string   `json:"exercise_name"`
-		TargetSets     int      `json:"target_sets"`
-		TargetRepsMin  int      `json:"target_reps_min"`
-		TargetRepsMax  *int     `json:"target_reps_max"`
-		TargetWeightKg *float64 `json:"target_weight_kg"`
-		OrderIndex     int      `json:"order_index"`
+		VariantID       int64    `json:"variant_id"`
+		ExerciseName    string   `json:"exercise_name"`
+		TargetSets      int      `json:"target_sets"`
+		TargetRepsMin   int      `json:"target_reps_min"`
+		TargetRepsMax   *int     `json:"target_reps_max"`
+		TargetWeightKg  *float64 `json:"target_weight_kg"`
+		TargetWeightMin *float64 `json:"target_weight_min"`
+		TargetWeightMax *float64 `json:"target_weight_max"`
+		OrderIndex      int      `json:"order_index"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if store.NormalizeExerciseName(req.ExerciseName) == "" {
+		http.Error(w, "exercise_name is required", http.StatusBadRequest)
+		return
+	}
+	if err := store.ValidateWeightRange(req.TargetWeightMin, req.TargetWeightMax); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	exercise, err := s.store.AddExerciseToVariant(
 		req.VariantID,
@@ -270,6 +323,13 @@ func (s *Server) handleCreateExercise(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if req.TargetWeightMin != nil || req.TargetWeightMax != nil {
+		if err := s.store.SetExerciseWeightRange(exercise.ID, req.TargetWeightMin, req.TargetWeightMax); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		exercise.TargetWeightMin, exercise.TargetWeightMax = req.TargetWeightMin, req.TargetWeightMax
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -285,18 +345,28 @@ func (s *Server) handleUpdateExercise(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		ExerciseName   string   `json:"exercise_name"`
-		TargetSets     int      `json:"target_sets"`
-		TargetRepsMin  int      `json:"target_reps_min"`
-		TargetRepsMax  *int     `json:"target_reps_max"`
-		TargetWeightKg *float64 `json:"target_weight_kg"`
-		OrderIndex     int      `json:"order_index"`
+		ExerciseName    string   `json:"exercise_name"`
+		TargetSets      int      `json:"target_sets"`
+		TargetRepsMin   int      `json:"target_reps_min"`
+		TargetRepsMax   *int     `json:"target_reps_max"`
+		TargetWeightKg  *float64 `json:"target_weight_kg"`
+		TargetWeightMin *float64 `json:"target_weight_min"`
+		TargetWeightMax *float64 `json:"target_weight_max"`
+		OrderIndex      int      `json:"order_index"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if store.NormalizeExerciseName(req.ExerciseName) == "" {
+		http.Error(w, "exercise_name is required", http.StatusBadRequest)
+		return
+	}
+	if err := store.ValidateWeightRange(req.TargetWeightMin, req.TargetWeightMax); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	err = s.store.UpdateWorkoutExercise(
 		id,
@@ -311,6 +381,10 @@ func (s *Server) handleUpdateExercise(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := s.store.SetExerciseWeightRange(id, req.TargetWeightMin, req.TargetWeightMax); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -422,21 +496,88 @@ func (s *Server) handleGetSessionDetails(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Targets of the planned exercises, so logs can be compared against them
+	exercises, err := s.store.ListExercisesByVariant(session.VariantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	response := struct {
-		Session interface{} `json:"session"`
-		Logs    interface{} `json:"logs"`
+		Session   interface{}             `json:"session"`
+		Logs      interface{}             `json:"logs"`
+		Exercises []store.WorkoutExercise `json:"exercises"`
 	}{
-		Session: session,
-		Logs:    logs,
+		Session:   session,
+		Logs:      logs,
+		Exercises: exercises,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
-	now := time.Now()
+// upcomingWorkout is the imminent workout: a snoozed session that is ready,
+// or the earliest scheduled occurrence of any active group
+type upcomingWorkout struct {
+	SessionID     int64
+	GroupID       int64
+	GroupName     string
+	VariantID     int64
+	VariantName   string
+	ScheduledDate time.Time
+	ScheduledTime string
+	Status        string
+	SnoozedUntil  *time.Time
+	IsSnoozed     bool
+}
+
+// sessionJSON renders the session part of the next-workout responses
+func (u *upcomingWorkout) sessionJSON() map[string]interface{} {
+	session := map[string]interface{}{
+		"id":             u.SessionID,
+		"scheduled_date": u.ScheduledDate,
+		"scheduled_time": u.ScheduledTime,
+		"status":         u.Status,
+		"is_snoozed":     u.IsSnoozed,
+	}
+	if u.IsSnoozed {
+		session["snoozed_until"] = u.SnoozedUntil
+	}
+	return session
+}
 
+// variantForGroup picks the variant the group's next session will use:
+// the rotation's current variant, falling back to the first one
+func (s *Server) variantForGroup(group store.WorkoutGroup) int64 {
+	if group.IsRotating {
+		if rotationState, _ := s.store.GetRotationState(group.ID); rotationState != nil {
+			return rotationState.CurrentVariantID
+		}
+	}
+	variants, _ := s.store.ListVariantsByGroup(group.ID)
+	if len(variants) > 0 {
+		return variants[0].ID
+	}
+	return 0
+}
+
+// workoutSkipDateSet returns the user's skip dates from the day of now onwards
+func (s *Server) workoutSkipDateSet(now time.Time) (map[string]bool, error) {
+	dates, err := s.store.ListWorkoutSkipDates(s.allowedUserID, now.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(dates))
+	for _, d := range dates {
+		set[d.Date] = true
+	}
+	return set, nil
+}
+
+// findNextWorkout returns the imminent workout, or nil if nothing is scheduled
+// in the next two weeks. Sessions that don't exist yet have SessionID 0.
+func (s *Server) findNextWorkout(now time.Time) (*upcomingWorkout, error) {
 	// FIRST: Check for snoozed sessions that are ready to start
 	snoozedSessions, err := s.store.GetSnoozedSessions(s.allowedUserID)
 	if err == nil && len(snoozedSessions) > 0 {
@@ -455,39 +596,26 @@ func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
 		if earliestSnoozed != nil {
 			group, _ := s.store.GetWorkoutGroup(earliestSnoozed.GroupID)
 			variant, _ := s.store.GetWorkoutVariant(earliestSnoozed.VariantID)
-			exercises, _ := s.store.ListExercisesByVariant(earliestSnoozed.VariantID)
 
-			groupName := "Unknown"
-			variantName := "Unknown"
+			next := &upcomingWorkout{
+				SessionID:     earliestSnoozed.ID,
+				GroupID:       earliestSnoozed.GroupID,
+				GroupName:     "Unknown",
+				VariantID:     earliestSnoozed.VariantID,
+				VariantName:   "Unknown",
+				ScheduledDate: earliestSnoozed.ScheduledDate,
+				ScheduledTime: earliestSnoozed.ScheduledTime,
+				Status:        earliestSnoozed.Status,
+				SnoozedUntil:  earliestSnoozed.SnoozedUntil,
+				IsSnoozed:     true,
+			}
 			if group != nil {
-				groupName = group.Name
+				next.GroupName = group.Name
 			}
 			if variant != nil {
-				variantName = variant.Name
+				next.VariantName = variant.Name
 			}
-
-			response := struct {
-				Session        interface{} `json:"session"`
-				GroupName      string      `json:"group_name"`
-				VariantName    string      `json:"variant_name"`
-				ExercisesCount int         `json:"exercises_count"`
-			}{
-				Session: map[string]interface{}{
-					"id":             earliestSnoozed.ID,
-					"scheduled_date": earliestSnoozed.ScheduledDate,
-					"scheduled_time": earliestSnoozed.ScheduledTime,
-					"status":         earliestSnoozed.Status,
-					"snoozed_until":  earliestSnoozed.SnoozedUntil,
-					"is_snoozed":     true,
-				},
-				GroupName:      groupName,
-				VariantName:    variantName,
-				ExercisesCount: len(exercises),
-			}
-
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(response)
-			return
+			return next, nil
 		}
 	}
 
@@ -495,21 +623,15 @@ func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
 	// Get all active workout groups
 	groups, err := s.store.ListWorkoutGroups(s.allowedUserID, true)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	var nextWorkout *struct {
-		SessionID      int64
-		GroupID        int64
-		GroupName      string
-		VariantID      int64
-		VariantName    string
-		ScheduledDate  time.Time
-		ScheduledTime  string
-		ExercisesCount int
-		Status         string
+	skipDates, err := s.workoutSkipDateSet(now)
+	if err != nil {
+		return nil, err
 	}
+
+	var nextWorkout *upcomingWorkout
 	var earliestTime time.Time
 
 	for _, group := range groups {
@@ -524,7 +646,7 @@ func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
 			checkDate := now.AddDate(0, 0, daysAhead)
 			dayOfWeek := int(checkDate.Weekday())
 
-			if !contains(daysOfWeek, dayOfWeek) {
+			if !contains(daysOfWeek, dayOfWeek) || skipDates[checkDate.Format("2006-01-02")] {
 				continue
 			}
 
@@ -543,25 +665,7 @@ func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
 
 			// Check if this is earlier than our current candidate
 			if nextWorkout == nil || scheduledDateTime.Before(earliestTime) {
-				// Determine variant
-				var variantID int64
-				if group.IsRotating {
-					rotationState, _ := s.store.GetRotationState(group.ID)
-					if rotationState != nil {
-						variantID = rotationState.CurrentVariantID
-					} else {
-						variants, _ := s.store.ListVariantsByGroup(group.ID)
-						if len(variants) > 0 {
-							variantID = variants[0].ID
-						}
-					}
-				} else {
-					variants, _ := s.store.ListVariantsByGroup(group.ID)
-					if len(variants) > 0 {
-						variantID = variants[0].ID
-					}
-				}
-
+				variantID := s.variantForGroup(group)
 				if variantID == 0 {
 					continue
 				}
@@ -571,8 +675,6 @@ func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
 					continue
 				}
 
-				exercises, _ := s.store.ListExercisesByVariant(variantID)
-
 				// Check if there's an existing session for this date
 				sessionDate := time.Date(checkDate.Year(), checkDate.Month(), checkDate.Day(), 0, 0, 0, 0, now.Location())
 				existing, _ := s.store.GetSessionByGroupAndDate(group.ID, sessionDate)
@@ -588,26 +690,15 @@ func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
 					sessionID = existing.ID
 				}
 
-				nextWorkout = &struct {
-					SessionID      int64
-					GroupID        int64
-					GroupName      string
-					VariantID      int64
-					VariantName    string
-					ScheduledDate  time.Time
-					ScheduledTime  string
-					ExercisesCount int
-					Status         string
-				}{
-					SessionID:      sessionID,
-					GroupID:        group.ID,
-					GroupName:      group.Name,
-					VariantID:      variantID,
-					VariantName:    variant.Name,
-					ScheduledDate:  scheduledDateTime,
-					ScheduledTime:  group.ScheduledTime,
-					ExercisesCount: len(exercises),
-					Status:         status,
+				nextWorkout = &upcomingWorkout{
+					SessionID:     sessionID,
+					GroupID:       group.ID,
+					GroupName:     group.Name,
+					VariantID:     variantID,
+					VariantName:   variant.Name,
+					ScheduledDate: scheduledDateTime,
+					ScheduledTime: group.ScheduledTime,
+					Status:        status,
 				}
 				earliestTime = scheduledDateTime
 			}
@@ -616,6 +707,16 @@ func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	return nextWorkout, nil
+}
+
+func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
+	nextWorkout, err := s.findNextWorkout(time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	if nextWorkout == nil {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(nil)
@@ -625,10 +726,6 @@ func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
 	// If the session doesn't exist yet (SessionID is 0), create it now
 	// This ensures the frontend has a valid ID to call /start on
 	if nextWorkout.SessionID == 0 {
-		// Need to strip time component from ScheduledDate for consistency with how it was checked
-		// Actually CreateWorkoutSession takes time.Time for date, which we have (scheduledDateTime)
-		// but we typically store just the date part for ScheduledDate in DB?
-		// Let's check CreateWorkoutSession impl. It stores what we pass.
 		// Standardize on using the date part for the Date field
 		dateOnly := time.Date(nextWorkout.ScheduledDate.Year(), nextWorkout.ScheduledDate.Month(), nextWorkout.ScheduledDate.Day(), 0, 0, 0, 0, nextWorkout.ScheduledDate.Location())
 
@@ -640,9 +737,6 @@ func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
 			nextWorkout.ScheduledTime,
 		)
 		if err != nil {
-			// Log error but maybe return what we have? Or fail?
-			// If we fail here, the user sees nothing. Better to return the transient object but they can't start it?
-			// No, better to fail so we see the error.
 			http.Error(w, fmt.Sprintf("Error creating session: %v", err), http.StatusInternalServerError)
 			return
 		}
@@ -650,28 +744,97 @@ func (s *Server) handleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
 		nextWorkout.Status = newSession.Status
 	}
 
+	exercises, _ := s.store.ListExercisesByVariant(nextWorkout.VariantID)
+
 	response := struct {
 		Session        interface{} `json:"session"`
 		GroupName      string      `json:"group_name"`
 		VariantName    string      `json:"variant_name"`
 		ExercisesCount int         `json:"exercises_count"`
 	}{
-		Session: map[string]interface{}{
-			"id":             nextWorkout.SessionID,
-			"scheduled_date": nextWorkout.ScheduledDate,
-			"scheduled_time": nextWorkout.ScheduledTime,
-			"status":         nextWorkout.Status,
-			"is_snoozed":     false,
-		},
+		Session:        nextWorkout.sessionJSON(),
 		GroupName:      nextWorkout.GroupName,
 		VariantName:    nextWorkout.VariantName,
-		ExercisesCount: nextWorkout.ExercisesCount,
+		ExercisesCount: len(exercises),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// plannedExercise is an exercise of the upcoming session with the weight to use
+type plannedExercise struct {
+	store.WorkoutExercise
+	// CarryOverWeightKg is the weight used the last time this exercise was completed
+	CarryOverWeightKg *float64 `json:"carryover_weight_kg,omitempty"`
+	// PlannedWeightKg is the carried-over weight, or the target if there is no history
+	PlannedWeightKg *float64 `json:"planned_weight_kg,omitempty"`
+}
+
+// handleGetNextWorkoutFull returns the imminent session with its full exercise plan,
+// so the UI can render it ahead of time. Unlike /sessions/next it never creates a session.
+func (s *Server) handleGetNextWorkoutFull(w http.ResponseWriter, r *http.Request) {
+	nextWorkout, err := s.findNextWorkout(time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if nextWorkout == nil {
+		json.NewEncoder(w).Encode(nil)
+		return
+	}
+
+	plan, err := s.planVariantExercises(nextWorkout.VariantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	session := nextWorkout.sessionJSON()
+	if nextWorkout.SessionID == 0 {
+		session["id"] = nil
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session":      session,
+		"group_name":   nextWorkout.GroupName,
+		"variant_name": nextWorkout.VariantName,
+		"exercises":    plan,
+	})
+}
+
+// planVariantExercises lists the variant's exercises with the weights carried over
+// from the last time each was completed
+func (s *Server) planVariantExercises(variantID int64) ([]plannedExercise, error) {
+	exercises, err := s.store.ListExercisesByVariant(variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]plannedExercise, 0, len(exercises))
+	for _, ex := range exercises {
+		carryOver, err := s.store.GetCarryOverWeight(s.allowedUserID, ex.ExerciseName)
+		if err != nil {
+			return nil, err
+		}
+		planned := ex.TargetWeightKg
+		if planned == nil {
+			planned = ex.TargetWeightMin // Start a range at its lower bound
+		}
+		if carryOver != nil {
+			planned = carryOver
+		}
+		plan = append(plan, plannedExercise{
+			WorkoutExercise:   ex,
+			CarryOverWeightKg: carryOver,
+			PlannedWeightKg:   planned,
+		})
+	}
+	return plan, nil
+}
+
 // Helper function
 func contains(slice []int, val int) bool {
 	for _, item := range slice {
@@ -765,6 +928,52 @@ func (s *Server) handleGetRotationState(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(state)
 }
 
+// handleGetCurrentRotationVariant returns the variant up next in a rotating group with
+// its full exercise plan, so the UI can show "up next: Day B"
+func (s *Server) handleGetCurrentRotationVariant(w http.ResponseWriter, r *http.Request) {
+	groupID, err := strconv.ParseInt(r.URL.Query().Get("group_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	state, err := s.store.GetRotationState(groupID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if state == nil {
+		http.Error(w, "Rotation state not found", http.StatusNotFound)
+		return
+	}
+	variant, err := s.store.GetWorkoutVariant(state.CurrentVariantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if variant == nil {
+		http.Error(w, "Current variant not found", http.StatusNotFound)
+		return
+	}
+
+	plan, err := s.planVariantExercises(variant.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"group_id":          groupID,
+		"variant_id":        variant.ID,
+		"variant_name":      variant.Name,
+		"description":       variant.Description,
+		"rotation_order":    variant.RotationOrder,
+		"last_session_date": state.LastSessionDate,
+		"exercises":         plan,
+	})
+}
+
 func (s *Server) handleInitializeRotation(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		GroupID           int64 `json:"group_id"`
@@ -819,6 +1028,103 @@ func (s *Server) handleGetUniqueExercises(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(exercises)
 }
 
+// handleSuggestExerciseName returns the normalized form of a name and, if a
+// differently spelled variant was used before, that canonical name.
+func (s *Server) handleSuggestExerciseName(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	suggestion, err := s.store.SuggestExerciseName(s.allowedUserID, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"normalized": store.NormalizeExerciseName(name),
+		"suggestion": suggestion,
+	})
+}
+
+// handleGetExerciseHistory returns the logged history and personal record of an
+// exercise, aggregated across spelling variants of its name.
+func (s *Server) handleGetExerciseHistory(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	history, err := s.store.GetExerciseHistory(s.allowedUserID, name, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if history == nil {
+		history = []store.ExerciseHistoryEntry{}
+	}
+
+	pr, err := s.store.GetExercisePersonalRecord(s.allowedUserID, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"exercise_name":   store.NormalizeExerciseName(name),
+		"history":         history,
+		"personal_record": pr,
+	})
+}
+
+// handleGetExerciseTrend returns per-session volume and relative intensity of an
+// exercise over the last days (default 180).
+func (s *Server) handleGetExerciseTrend(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	days := 180
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		days = d
+	}
+
+	points, err := s.store.GetExerciseTrend(s.allowedUserID, name, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if points == nil {
+		points = []store.ExerciseTrendPoint{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"exercise_name": store.NormalizeExerciseName(name),
+		"days":          days,
+		"points":        points,
+	})
+}
+
 func (s *Server) handleAddExerciseToSession(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		SessionID      int64    `json:"session_id"`
@@ -945,6 +1251,109 @@ func (s *Server) handleSnoozeWorkoutSession(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleSnoozeAllWorkoutSessions snoozes every session of today that hasn't been started yet
+func (s *Server) handleSnoozeAllWorkoutSessions(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Minutes int `json:"minutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Minutes <= 0 {
+		req.Minutes = 60 // Default
+	}
+
+	sessions, err := s.store.GetOpenSessionsForDate(s.allowedUserID, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	snoozed := 0
+	for _, sess := range sessions {
+		if err := s.store.SnoozeSession(sess.ID, time.Duration(req.Minutes)*time.Minute); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		snoozed++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"snoozed": snoozed,
+		"minutes": req.Minutes,
+	})
+}
+
+// handleAddWorkoutSkipDates marks days (vacation, injury) on which no workouts are
+// planned. Rotation isn't advanced for them since no session is ever created.
+func (s *Server) handleAddWorkoutSkipDates(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Dates  []string `json:"dates"`
+		Reason string   `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(req.Dates) == 0 {
+		http.Error(w, "At least one date is required", http.StatusBadRequest)
+		return
+	}
+
+	dates := make([]string, 0, len(req.Dates))
+	for _, d := range req.Dates {
+		t, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		dates = append(dates, t.Format("2006-01-02"))
+	}
+
+	if err := s.store.AddWorkoutSkipDates(s.allowedUserID, dates, strings.TrimSpace(req.Reason)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeWorkoutSkipDates(w)
+}
+
+// handleListWorkoutSkipDates returns today's and future skip dates
+func (s *Server) handleListWorkoutSkipDates(w http.ResponseWriter, r *http.Request) {
+	s.writeWorkoutSkipDates(w)
+}
+
+func (s *Server) writeWorkoutSkipDates(w http.ResponseWriter) {
+	dates, err := s.store.ListWorkoutSkipDates(s.allowedUserID, time.Now().Format("2006-01-02"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dates)
+}
+
+func (s *Server) handleDeleteWorkoutSkipDate(w http.ResponseWriter, r *http.Request) {
+	date := r.PathValue("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.DeleteWorkoutSkipDate(s.allowedUserID, date); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Skip date not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (s *Server) handleSkipWorkoutSession(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -962,6 +1371,42 @@ func (s *Server) handleSkipWorkoutSession(w http.ResponseWriter, r *http.Request
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleCompleteAllExercises logs every remaining planned exercise at its targets and finishes the session
+func (s *Server) handleCompleteAllExercises(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	session, err := s.store.GetWorkoutSession(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if session == nil || session.UserID != s.allowedUserID {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if session.Status == "completed" {
+		http.Error(w, "Session already completed", http.StatusConflict)
+		return
+	}
+
+	logged, err := s.store.CompleteSessionAsPlanned(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           "completed",
+		"exercises_logged": logged,
+	})
+}
+
 func (s *Server) handleStartWorkoutSession(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -99,3 +100,79 @@ func TestHandleAddExerciseToSession(t *testing.T) {
 		t.Errorf("Expected 403 Forbidden, got %d. Body: %s", wForbidden.Code, wForbidden.Body.String())
 	}
 }
+
+func TestHandleCreateExercise_WeightRange(t *testing.T) {
+	db, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	defer db.Close()
+
+	srv := &Server{store: db, allowedUserID: 123456}
+	group, _ := db.CreateWorkoutGroup("Group", "Desc", false, 123456, "[]", "10:00", 15)
+	variant, _ := db.CreateWorkoutVariant(group.ID, "Variant", nil, "")
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"variant_id": variant.ID, "exercise_name": "Kettlebell Swing", "target_sets": 5, "target_reps_min": 10,
+		"target_weight_min": 16, "target_weight_max": 24,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/workout/exercises/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	srv.handleCreateExercise(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created store.WorkoutExercise
+	json.NewDecoder(w.Body).Decode(&created)
+
+	exercises, _ := db.ListExercisesByVariant(variant.ID)
+	if len(exercises) != 1 {
+		t.Fatalf("Expected 1 exercise, got %d", len(exercises))
+	}
+	ex := exercises[0]
+	if ex.ID != created.ID || ex.TargetWeightMin == nil || *ex.TargetWeightMin != 16 || ex.TargetWeightMax == nil || *ex.TargetWeightMax != 24 {
+		t.Errorf("Expected 16-24kg to round-trip, got %v-%v", ex.TargetWeightMin, ex.TargetWeightMax)
+	}
+	if got := ex.TargetWeightLabel(); got != "16-24kg" {
+		t.Errorf("Expected label 16-24kg, got %q", got)
+	}
+
+	// Updating narrows the range; min above max is rejected
+	body, _ = json.Marshal(map[string]interface{}{
+		"exercise_name": "Kettlebell Swing", "target_sets": 5, "target_reps_min": 10,
+		"target_weight_min": 20, "target_weight_max": 22.5,
+	})
+	req = httptest.NewRequest(http.MethodPut, "/api/workout/exercises/update?id="+strconv.FormatInt(ex.ID, 10), bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	srv.handleUpdateExercise(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	updated, _ := db.GetWorkoutExercise(ex.ID)
+	if updated.TargetWeightMin == nil || *updated.TargetWeightMin != 20 || updated.TargetWeightMax == nil || *updated.TargetWeightMax != 22.5 {
+		t.Errorf("Expected 20-22.5kg after update, got %v-%v", updated.TargetWeightMin, updated.TargetWeightMax)
+	}
+
+	body, _ = json.Marshal(map[string]interface{}{
+		"exercise_name": "Kettlebell Swing", "target_sets": 5, "target_reps_min": 10,
+		"target_weight_min": 30, "target_weight_max": 20,
+	})
+	req = httptest.NewRequest(http.MethodPut, "/api/workout/exercises/update?id="+strconv.FormatInt(ex.ID, 10), bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	srv.handleUpdateExercise(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for min > max, got %d", w.Code)
+	}
+	unchanged, _ := db.GetWorkoutExercise(ex.ID)
+	if *unchanged.TargetWeightMin != 20 {
+		t.Errorf("Expected the rejected update to leave the range alone, got min %v", *unchanged.TargetWeightMin)
+	}
+
+	if err := db.SetExerciseWeightRange(ex.ID, ptrFloat(10), ptrFloat(5)); err == nil {
+		t.Error("Expected the store to reject min > max")
+	}
+}
+
+func ptrFloat(f float64) *float64 {
+	return &f
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -231,3 +232,466 @@ func TestHandleGetNextWorkout_LazyCreation(t *testing.T) {
 		}
 	}
 }
+
+func TestHandleCompleteAllExercises(t *testing.T) {
+	db, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	defer db.Close()
+
+	srv := &Server{
+		store:         db,
+		allowedUserID: 123456,
+	}
+	userID := int64(123456)
+
+	group, err := db.CreateWorkoutGroup("Split", "", true, userID, "[1,3,5]", "09:00", 15)
+	if err != nil {
+		t.Fatalf("Failed to create workout group: %v", err)
+	}
+	orderA, orderB := 0, 1
+	variantA, _ := db.CreateWorkoutVariant(group.ID, "Push", &orderA, "")
+	variantB, _ := db.CreateWorkoutVariant(group.ID, "Pull", &orderB, "")
+	if err := db.InitializeRotation(group.ID, variantA.ID); err != nil {
+		t.Fatalf("Failed to initialize rotation: %v", err)
+	}
+
+	weight := 60.0
+	bench, _ := db.AddExerciseToVariant(variantA.ID, "Bench Press", 3, 8, nil, &weight, 0)
+	db.AddExerciseToVariant(variantA.ID, "Push Ups", 3, 15, nil, nil, 1)
+	db.AddExerciseToVariant(variantA.ID, "Dips", 3, 10, nil, nil, 2)
+
+	session, err := db.CreateWorkoutSession(group.ID, variantA.ID, userID, time.Now(), "09:00")
+	if err != nil {
+		t.Fatalf("Failed to create workout session: %v", err)
+	}
+
+	// One exercise was already logged individually and must not be duplicated
+	sets, reps := 3, 10
+	if _, err := db.LogExercise(session.ID, bench.ID, bench.ExerciseName, &sets, &reps, &weight, "completed", ""); err != nil {
+		t.Fatalf("Failed to log exercise: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/workout/sessions/%d/complete-all", session.ID), nil)
+	req.SetPathValue("id", fmt.Sprintf("%d", session.ID))
+	w := httptest.NewRecorder()
+	srv.handleCompleteAllExercises(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		ExercisesLogged int `json:"exercises_logged"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.ExercisesLogged != 2 {
+		t.Errorf("Expected 2 exercises logged, got %d", resp.ExercisesLogged)
+	}
+
+	logs, err := db.GetExerciseLogs(session.ID)
+	if err != nil {
+		t.Fatalf("Failed to get exercise logs: %v", err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("Expected 3 exercise logs, got %d", len(logs))
+	}
+	for _, l := range logs {
+		if l.Status != "completed" {
+			t.Errorf("Expected %s to be completed, got %s", l.ExerciseName, l.Status)
+		}
+		if l.ExerciseName == "Push Ups" && (l.SetsCompleted == nil || *l.SetsCompleted != 3 || l.RepsCompleted == nil || *l.RepsCompleted != 15) {
+			t.Errorf("Expected Push Ups logged at targets 3x15, got %+v", l)
+		}
+	}
+
+	updated, _ := db.GetWorkoutSession(session.ID)
+	if updated.Status != "completed" || updated.CompletedAt == nil {
+		t.Errorf("Expected session completed, got status %s", updated.Status)
+	}
+
+	state, _ := db.GetRotationState(group.ID)
+	if state == nil || state.CurrentVariantID != variantB.ID {
+		t.Errorf("Expected rotation to advance to variant %d, got %+v", variantB.ID, state)
+	}
+
+	// Completing again is rejected
+	w = httptest.NewRecorder()
+	srv.handleCompleteAllExercises(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for completed session, got %d", w.Code)
+	}
+}
+
+func TestHandleGetNextWorkoutFull(t *testing.T) {
+	db, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	defer db.Close()
+
+	userID := int64(123456)
+	srv := &Server{store: db, allowedUserID: userID}
+
+	// Nothing scheduled yet
+	req := httptest.NewRequest(http.MethodGet, "/api/workout/next/full", nil)
+	w := httptest.NewRecorder()
+	srv.handleGetNextWorkoutFull(w, req)
+	if body := bytes.TrimSpace(w.Body.Bytes()); string(body) != "null" {
+		t.Fatalf("Expected null with nothing scheduled, got %s", body)
+	}
+
+	group, err := db.CreateWorkoutGroup("Everyday Group", "Test", false, userID, "[0,1,2,3,4,5,6]", "23:59", 15)
+	if err != nil {
+		t.Fatalf("Failed to create workout group: %v", err)
+	}
+	rotationOrder := 0
+	variant, err := db.CreateWorkoutVariant(group.ID, "Variant A", &rotationOrder, "")
+	if err != nil {
+		t.Fatalf("Failed to create workout variant: %v", err)
+	}
+	repsMax := 12
+	target := 20.0
+	squat, _ := db.AddExerciseToVariant(variant.ID, "Squat", 3, 8, &repsMax, &target, 0)
+	db.AddExerciseToVariant(variant.ID, "Push-ups", 3, 15, nil, nil, 1)
+
+	// Yesterday's session carries 22.5kg over for squats
+	past, _ := db.CreateWorkoutSession(group.ID, variant.ID, userID, time.Now().AddDate(0, 0, -1), "23:59")
+	sets, reps, used := 3, 10, 22.5
+	db.LogExercise(past.ID, squat.ID, "Squat", &sets, &reps, &used, "completed", "")
+	db.UpdateSessionStatus(past.ID, "completed")
+
+	w = httptest.NewRecorder()
+	srv.handleGetNextWorkoutFull(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Session struct {
+			ID            *int64 `json:"id"`
+			ScheduledTime string `json:"scheduled_time"`
+			Status        string `json:"status"`
+		} `json:"session"`
+		GroupName   string `json:"group_name"`
+		VariantName string `json:"variant_name"`
+		Exercises   []struct {
+			ExerciseName      string   `json:"exercise_name"`
+			TargetSets        int      `json:"target_sets"`
+			TargetRepsMin     int      `json:"target_reps_min"`
+			TargetRepsMax     *int     `json:"target_reps_max"`
+			TargetWeightKg    *float64 `json:"target_weight_kg"`
+			CarryOverWeightKg *float64 `json:"carryover_weight_kg"`
+			PlannedWeightKg   *float64 `json:"planned_weight_kg"`
+		} `json:"exercises"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if resp.GroupName != "Everyday Group" || resp.VariantName != "Variant A" {
+		t.Errorf("Unexpected group/variant: %q / %q", resp.GroupName, resp.VariantName)
+	}
+	if resp.Session.ScheduledTime != "23:59" || resp.Session.Status != "pending" {
+		t.Errorf("Unexpected session: %+v", resp.Session)
+	}
+	if resp.Session.ID != nil {
+		t.Errorf("Expected no session to be created, got id %d", *resp.Session.ID)
+	}
+	if len(resp.Exercises) != 2 {
+		t.Fatalf("Expected 2 exercises, got %d", len(resp.Exercises))
+	}
+
+	sq := resp.Exercises[0]
+	if sq.ExerciseName != "Squat" || sq.TargetSets != 3 || sq.TargetRepsMin != 8 || sq.TargetRepsMax == nil || *sq.TargetRepsMax != 12 {
+		t.Errorf("Unexpected squat targets: %+v", sq)
+	}
+	if sq.TargetWeightKg == nil || *sq.TargetWeightKg != 20 {
+		t.Errorf("Expected target weight 20, got %v", sq.TargetWeightKg)
+	}
+	if sq.CarryOverWeightKg == nil || *sq.CarryOverWeightKg != 22.5 || sq.PlannedWeightKg == nil || *sq.PlannedWeightKg != 22.5 {
+		t.Errorf("Expected carried-over 22.5kg, got carryover=%v planned=%v", sq.CarryOverWeightKg, sq.PlannedWeightKg)
+	}
+
+	pu := resp.Exercises[1]
+	if pu.ExerciseName != "Push-ups" || pu.TargetRepsMin != 15 || pu.CarryOverWeightKg != nil || pu.PlannedWeightKg != nil {
+		t.Errorf("Unexpected push-ups plan: %+v", pu)
+	}
+
+	sessions, _ := db.GetWorkoutHistory(userID, 100)
+	if len(sessions) != 1 {
+		t.Errorf("Expected the full plan to be read-only, got %d sessions", len(sessions))
+	}
+}
+
+func TestHandleSnoozeAllWorkoutSessions(t *testing.T) {
+	db, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	defer db.Close()
+
+	userID := int64(123456)
+	srv := &Server{store: db, allowedUserID: userID}
+
+	var sessionIDs []int64
+	for i, name := range []string{"Morning", "Evening", "Done"} {
+		group, err := db.CreateWorkoutGroup(name, "Test", false, userID, "[0,1,2,3,4,5,6]", "09:00", 15)
+		if err != nil {
+			t.Fatalf("Failed to create workout group: %v", err)
+		}
+		order := 0
+		variant, err := db.CreateWorkoutVariant(group.ID, name+" Variant", &order, "")
+		if err != nil {
+			t.Fatalf("Failed to create workout variant: %v", err)
+		}
+		session, err := db.CreateWorkoutSession(group.ID, variant.ID, userID, time.Now(), fmt.Sprintf("%02d:00", 9+i))
+		if err != nil {
+			t.Fatalf("Failed to create workout session: %v", err)
+		}
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+	if err := db.UpdateSessionStatus(sessionIDs[1], "notified"); err != nil {
+		t.Fatalf("Failed to update status: %v", err)
+	}
+	if err := db.UpdateSessionStatus(sessionIDs[2], "completed"); err != nil {
+		t.Fatalf("Failed to update status: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/workout/sessions/snooze-all", bytes.NewBufferString(`{"minutes": 30}`))
+	w := httptest.NewRecorder()
+	srv.handleSnoozeAllWorkoutSessions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Snoozed int `json:"snoozed"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Snoozed != 2 {
+		t.Errorf("Expected 2 sessions snoozed, got %d", resp.Snoozed)
+	}
+
+	for i, id := range sessionIDs {
+		session, err := db.GetWorkoutSession(id)
+		if err != nil {
+			t.Fatalf("Failed to get session: %v", err)
+		}
+		if i < 2 {
+			if session.SnoozedUntil == nil || session.SnoozedUntil.Before(time.Now().Add(25*time.Minute)) {
+				t.Errorf("Session %d: expected snoozed_until ~30 minutes ahead, got %v", id, session.SnoozedUntil)
+			}
+			if session.SnoozeCount != 1 {
+				t.Errorf("Session %d: expected snooze count 1, got %d", id, session.SnoozeCount)
+			}
+		} else if session.SnoozedUntil != nil || session.SnoozeCount != 0 {
+			t.Errorf("Completed session %d should not be snoozed", id)
+		}
+	}
+}
+
+func TestHandleAddWorkoutSkipDates_NextWorkoutJumpsOver(t *testing.T) {
+	db, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	defer db.Close()
+
+	userID := int64(123456)
+	srv := &Server{store: db, allowedUserID: userID}
+
+	group, err := db.CreateWorkoutGroup("Everyday Group", "Test", true, userID, "[0,1,2,3,4,5,6]", "23:59", 15)
+	if err != nil {
+		t.Fatalf("Failed to create workout group: %v", err)
+	}
+	orderA, orderB := 0, 1
+	variantA, _ := db.CreateWorkoutVariant(group.ID, "Variant A", &orderA, "")
+	db.CreateWorkoutVariant(group.ID, "Variant B", &orderB, "")
+	if err := db.InitializeRotation(group.ID, variantA.ID); err != nil {
+		t.Fatalf("Failed to initialize rotation: %v", err)
+	}
+
+	// Vacation today and tomorrow
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	skipped := []string{today.Format("2006-01-02"), today.AddDate(0, 0, 1).Format("2006-01-02")}
+	body, _ := json.Marshal(map[string]interface{}{"dates": skipped, "reason": "vacation"})
+	req := httptest.NewRequest(http.MethodPost, "/api/workout/skip-dates", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	srv.handleAddWorkoutSkipDates(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var listed []store.WorkoutSkipDate
+	json.NewDecoder(w.Body).Decode(&listed)
+	if len(listed) != 2 || listed[0].Reason != "vacation" {
+		t.Fatalf("Expected 2 skip dates, got %+v", listed)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/workout/sessions/next", nil)
+	w = httptest.NewRecorder()
+	srv.handleGetNextWorkout(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Session struct {
+			ScheduledDate time.Time `json:"scheduled_date"`
+		} `json:"session"`
+		VariantName string `json:"variant_name"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got, want := resp.Session.ScheduledDate.In(now.Location()).Format("2006-01-02"), today.AddDate(0, 0, 2).Format("2006-01-02"); got != want {
+		t.Errorf("Expected next workout on %s, got %s", want, got)
+	}
+	if resp.VariantName != "Variant A" {
+		t.Errorf("Expected the rotation to still be on Variant A, got %q", resp.VariantName)
+	}
+
+	// Nothing was materialized for the skipped days, so the rotation didn't move
+	for i := range skipped {
+		session, err := db.GetSessionByGroupAndDate(group.ID, today.AddDate(0, 0, i))
+		if err != nil {
+			t.Fatalf("GetSessionByGroupAndDate: %v", err)
+		}
+		if session != nil {
+			t.Errorf("Expected no session on skipped day %s", skipped[i])
+		}
+	}
+	state, err := db.GetRotationState(group.ID)
+	if err != nil || state == nil {
+		t.Fatalf("GetRotationState: %v", err)
+	}
+	if state.CurrentVariantID != variantA.ID {
+		t.Errorf("Expected rotation to stay on Variant A, got variant %d", state.CurrentVariantID)
+	}
+}
+
+func TestHandleAddWorkoutSkipDates_InvalidDate(t *testing.T) {
+	db, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	defer db.Close()
+	srv := &Server{store: db, allowedUserID: 123456}
+
+	for _, body := range []string{`{"dates":["2026-13-01"]}`, `{"dates":[]}`, `not json`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/workout/skip-dates", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srv.handleAddWorkoutSkipDates(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestHandleReorderWorkoutVariants(t *testing.T) {
+	db, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	defer db.Close()
+	srv := &Server{store: db, allowedUserID: 123456}
+
+	group, _ := db.CreateWorkoutGroup("Split", "", true, 123456, "[1,3,5]", "18:00", 15)
+	one, two := 1, 2
+	a, _ := db.CreateWorkoutVariant(group.ID, "Day A", &one, "")
+	b, _ := db.CreateWorkoutVariant(group.ID, "Day B", &two, "")
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/workout/variants/order", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srv.handleReorderWorkoutVariants(w, req)
+		return w
+	}
+
+	w := put(fmt.Sprintf(`{"group_id":%d,"variant_ids":[%d,%d]}`, group.ID, b.ID, a.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var variants []store.WorkoutVariant
+	if err := json.NewDecoder(w.Body).Decode(&variants); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(variants) != 2 || variants[0].ID != b.ID || *variants[0].RotationOrder != 1 || *variants[1].RotationOrder != 2 {
+		t.Errorf("Expected Day B first with rotation_order 1, got %+v", variants)
+	}
+
+	if w := put(fmt.Sprintf(`{"group_id":%d,"variant_ids":[%d]}`, group.ID, a.ID)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an incomplete list, got %d", w.Code)
+	}
+	if w := put(`{"group_id":999,"variant_ids":[1]}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a group without variants, got %d", w.Code)
+	}
+}
+
+func TestHandleGetCurrentRotationVariant(t *testing.T) {
+	db, err := store.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	defer db.Close()
+
+	userID := int64(123456)
+	srv := &Server{store: db, allowedUserID: userID}
+
+	group, _ := db.CreateWorkoutGroup("Upper/Lower", "", true, userID, "[1,4]", "18:00", 15)
+	one, two := 1, 2
+	upper, _ := db.CreateWorkoutVariant(group.ID, "Day A", &one, "Upper body")
+	lower, _ := db.CreateWorkoutVariant(group.ID, "Day B", &two, "Lower body")
+	db.AddExerciseToVariant(upper.ID, "Bench Press", 3, 8, nil, nil, 0)
+	target := 60.0
+	squat, _ := db.AddExerciseToVariant(lower.ID, "Squat", 4, 5, nil, &target, 0)
+	db.AddExerciseToVariant(lower.ID, "Lunges", 3, 10, nil, nil, 1)
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/workout/rotation/current?group_id=%d", group.ID), nil)
+		w := httptest.NewRecorder()
+		srv.handleGetCurrentRotationVariant(w, req)
+		return w
+	}
+
+	if w := get(); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 before the rotation is initialized, got %d", w.Code)
+	}
+
+	// A previous lower-body session carries 65kg over for squats
+	past, _ := db.CreateWorkoutSession(group.ID, lower.ID, userID, time.Now().AddDate(0, 0, -3), "18:00")
+	sets, reps, used := 4, 5, 65.0
+	db.LogExercise(past.ID, squat.ID, "Squat", &sets, &reps, &used, "completed", "")
+	db.InitializeRotation(group.ID, lower.ID)
+
+	w := get()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		VariantID   int64  `json:"variant_id"`
+		VariantName string `json:"variant_name"`
+		Description string `json:"description"`
+		Exercises   []struct {
+			ExerciseName    string   `json:"exercise_name"`
+			TargetSets      int      `json:"target_sets"`
+			PlannedWeightKg *float64 `json:"planned_weight_kg"`
+		} `json:"exercises"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if resp.VariantID != lower.ID || resp.VariantName != "Day B" || resp.Description != "Lower body" {
+		t.Errorf("Expected Day B (Lower body), got %+v", resp)
+	}
+	if len(resp.Exercises) != 2 || resp.Exercises[0].ExerciseName != "Squat" || resp.Exercises[1].ExerciseName != "Lunges" {
+		t.Fatalf("Expected the Day B plan (Squat, Lunges), got %+v", resp.Exercises)
+	}
+	if pw := resp.Exercises[0].PlannedWeightKg; pw == nil || *pw != 65 {
+		t.Errorf("Expected squats planned at the carried-over 65kg, got %v", pw)
+	}
+}
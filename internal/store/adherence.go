@@ -0,0 +1,403 @@
+package store
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Medication priorities
+const (
+	PriorityLow      = "low"
+	PriorityNormal   = "normal"
+	PriorityCritical = "critical"
+)
+
+// priorityWeights controls how much a dose counts towards the weighted adherence score
+var priorityWeights = map[string]float64{
+	PriorityLow:      1,
+	PriorityNormal:   2,
+	PriorityCritical: 5,
+}
+
+// ValidPriority reports whether p is a known medication priority
+func ValidPriority(p string) bool {
+	_, ok := priorityWeights[p]
+	return ok
+}
+
+// adherenceGracePeriod is how long a pending dose may stay unconfirmed before it counts as missed
+const adherenceGracePeriod = 2 * time.Hour
+
+// MedicationAdherence summarizes taken and missed doses of one medication
+type MedicationAdherence struct {
+	MedicationID int64   `json:"medication_id"`
+	Name         string  `json:"name"`
+	Priority     string  `json:"priority"`
+	Taken        int     `json:"taken"`
+	Missed       int     `json:"missed"`
+	Rate         float64 `json:"rate"` // Percentage of doses taken
+}
+
+// AdherenceStats summarizes scheduled doses since a point in time
+type AdherenceStats struct {
+	Since          time.Time `json:"since"`
+	Taken          int       `json:"taken"`
+	Missed         int       `json:"missed"`
+	Rate           float64   `json:"rate"`           // Percentage of doses taken
+	WeightedScore  float64   `json:"weighted_score"` // Like Rate, but misses of critical meds weigh more
+	CriticalMisses int       `json:"critical_misses"`
+	// Medications are ordered critical first, then by most misses
+	Medications []MedicationAdherence `json:"medications"`
+}
+
+// GetAdherenceStats computes adherence over intakes scheduled since the given time.
+// Pending doses count as missed once they are older than the grace period.
+func (s *Store) GetAdherenceStats(since time.Time) (*AdherenceStats, error) {
+	now := nowFunc()
+	rows, err := s.db.Query(`
+		SELECT il.medication_id, m.name, m.priority, il.status, il.scheduled_at
+		FROM intake_log il
+		JOIN medications m ON m.id = il.medication_id
+		WHERE il.scheduled_at >= ? AND il.scheduled_at <= ?`, since, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &AdherenceStats{Since: since, Medications: []MedicationAdherence{}}
+	byMed := make(map[int64]*MedicationAdherence)
+	var weightedTaken, weightedTotal float64
+
+	for rows.Next() {
+		var medID int64
+		var name, priority, status string
+		var scheduledAt time.Time
+		if err := rows.Scan(&medID, &name, &priority, &status, &scheduledAt); err != nil {
+			return nil, err
+		}
+
+		var taken bool
+		switch status {
+		case "TAKEN":
+			taken = true
+		case "PENDING":
+			if now.Sub(scheduledAt) < adherenceGracePeriod {
+				continue
+			}
+		}
+
+		ma, ok := byMed[medID]
+		if !ok {
+			ma = &MedicationAdherence{MedicationID: medID, Name: name, Priority: priority}
+			byMed[medID] = ma
+		}
+
+		weight, ok := priorityWeights[priority]
+		if !ok {
+			weight = priorityWeights[PriorityNormal]
+		}
+		weightedTotal += weight
+
+		if taken {
+			ma.Taken++
+			stats.Taken++
+			weightedTaken += weight
+		} else {
+			ma.Missed++
+			stats.Missed++
+			if priority == PriorityCritical {
+				stats.CriticalMisses++
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats.Rate = percentage(float64(stats.Taken), float64(stats.Taken+stats.Missed))
+	stats.WeightedScore = percentage(weightedTaken, weightedTotal)
+
+	for _, ma := range byMed {
+		ma.Rate = percentage(float64(ma.Taken), float64(ma.Taken+ma.Missed))
+		stats.Medications = append(stats.Medications, *ma)
+	}
+	sort.Slice(stats.Medications, func(i, j int) bool {
+		a, b := stats.Medications[i], stats.Medications[j]
+		if ac, bc := a.Priority == PriorityCritical, b.Priority == PriorityCritical; ac != bc {
+			return ac
+		}
+		if a.Missed != b.Missed {
+			return a.Missed > b.Missed
+		}
+		return a.Name < b.Name
+	})
+
+	return stats, nil
+}
+
+// DailyAdherence is the share of scheduled doses taken on one calendar day
+type DailyAdherence struct {
+	Date     string   `json:"date"`  // YYYY-MM-DD
+	Ratio    *float64 `json:"ratio"` // nil when nothing was scheduled
+	Taken    int      `json:"taken"`
+	Expected int      `json:"expected"`
+}
+
+// GetDailyAdherence returns one entry per day from since up to today. Expected doses
+// come from expanding the active medications' schedules (only times that have passed,
+// and not before a medication was created or started); taken doses from intake_log.
+func (s *Store) GetDailyAdherence(userID int64, since time.Time) ([]DailyAdherence, error) {
+	now := nowFunc()
+	loc := now.Location()
+	since = since.In(loc)
+	start := time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, loc)
+
+	meds, err := s.ListMedications(false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Taken intakes per day and medication
+	taken := make(map[string]map[int64]int)
+	rows, err := s.db.Query(`
+		SELECT medication_id, scheduled_at FROM intake_log
+		WHERE status = 'TAKEN' AND scheduled_at >= ? AND scheduled_at <= ?`, start, now)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var medID int64
+		var scheduledAt time.Time
+		if err := rows.Scan(&medID, &scheduledAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		day := scheduledAt.In(loc).Format("2006-01-02")
+		if taken[day] == nil {
+			taken[day] = make(map[int64]int)
+		}
+		taken[day][medID]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	type schedule struct {
+		med   Medication
+		cfg   *ScheduleConfig
+		since time.Time
+	}
+	var schedules []schedule
+	for _, m := range meds {
+		cfg, err := s.ExpandSchedule(userID, &m)
+		if err != nil || cfg.Type == "as_needed" {
+			continue
+		}
+		created := m.CreatedAt.In(loc)
+		activeFrom := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, loc)
+		if m.StartDate != nil && m.StartDate.After(activeFrom) {
+			activeFrom = *m.StartDate
+		}
+		schedules = append(schedules, schedule{med: m, cfg: cfg, since: activeFrom})
+	}
+
+	days := []DailyAdherence{}
+	for day := start; !day.After(now); day = day.AddDate(0, 0, 1) {
+		entry := DailyAdherence{Date: day.Format("2006-01-02")}
+
+		for _, sc := range schedules {
+			if sc.cfg.Type == "weekly" && !containsDay(sc.cfg.Days, int(day.Weekday())) {
+				continue
+			}
+
+			expected := 0
+			for _, ts := range sc.cfg.Times {
+				t, err := time.Parse("15:04", ts)
+				if err != nil {
+					continue
+				}
+				target := time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+				if target.After(now) || target.Before(sc.since) {
+					continue
+				}
+				if sc.med.EndDate != nil && target.After(*sc.med.EndDate) {
+					continue
+				}
+				expected++
+			}
+			if expected == 0 {
+				continue
+			}
+
+			entry.Expected += expected
+			// Extra unscheduled doses can't push a day above 100%
+			entry.Taken += min(taken[entry.Date][sc.med.ID], expected)
+		}
+
+		if entry.Expected > 0 {
+			ratio := math.Round(float64(entry.Taken)/float64(entry.Expected)*100) / 100
+			entry.Ratio = &ratio
+		}
+		days = append(days, entry)
+	}
+
+	return days, nil
+}
+
+// AdherenceStreak is a run of consecutive days on which every scheduled dose was taken
+type AdherenceStreak struct {
+	Days      int    `json:"days"`
+	StartDate string `json:"start_date,omitempty"` // YYYY-MM-DD
+	EndDate   string `json:"end_date,omitempty"`
+}
+
+// GetLongestAdherenceStreak returns the best streak ever, going back to the oldest active
+// medication. Days with nothing scheduled neither break nor extend a streak; of equally
+// long streaks the earliest is returned.
+func (s *Store) GetLongestAdherenceStreak(userID int64) (*AdherenceStreak, error) {
+	meds, err := s.ListMedications(false)
+	if err != nil {
+		return nil, err
+	}
+	best := &AdherenceStreak{}
+	if len(meds) == 0 {
+		return best, nil
+	}
+	since := meds[0].CreatedAt
+	for _, m := range meds[1:] {
+		if m.CreatedAt.Before(since) {
+			since = m.CreatedAt
+		}
+	}
+
+	days, err := s.GetDailyAdherence(userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	current := AdherenceStreak{}
+	for _, d := range days {
+		if d.Ratio == nil {
+			continue
+		}
+		if d.Taken < d.Expected {
+			current = AdherenceStreak{}
+			continue
+		}
+		if current.Days == 0 {
+			current.StartDate = d.Date
+		}
+		current.Days++
+		current.EndDate = d.Date
+		if current.Days > best.Days {
+			*best = current
+		}
+	}
+	return best, nil
+}
+
+// MedicationAdherenceDay counts one medication's doses scheduled on a calendar day
+type MedicationAdherenceDay struct {
+	Date   string   `json:"date"` // YYYY-MM-DD
+	Taken  int      `json:"taken"`
+	Missed int      `json:"missed"`
+	Total  int      `json:"total"`
+	Rate   *float64 `json:"rate"` // Percentage of doses taken; nil when nothing was due
+}
+
+// MedicationAdherenceReport is one medication's adherence over the last days
+type MedicationAdherenceReport struct {
+	MedicationID  int64                    `json:"medication_id"`
+	Days          int                      `json:"days"`
+	AdherenceRate float64                  `json:"adherence_rate"` // Percentage of doses taken
+	Taken         int                      `json:"taken"`
+	Missed        int                      `json:"missed"`
+	Pending       int                      `json:"pending"` // Still within the grace period, not in Total
+	Total         int                      `json:"total"`
+	Daily         []MedicationAdherenceDay `json:"daily"` // Oldest first, one entry per day up to today
+}
+
+// GetMedicationAdherence computes one medication's adherence from its intakes scheduled
+// over the last days. As in GetAdherenceStats, pending doses count as missed once they
+// are older than the grace period. Intakes count whether or not the medication has been
+// archived since, so a medication stopped mid-window keeps its history.
+func (s *Store) GetMedicationAdherence(medID int64, days int) (*MedicationAdherenceReport, error) {
+	now := nowFunc()
+	loc := now.Location()
+	first := now.AddDate(0, 0, -(days - 1))
+	start := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+
+	rows, err := s.db.Query(`
+		SELECT status, scheduled_at FROM intake_log
+		WHERE medication_id = ? AND scheduled_at >= ? AND scheduled_at <= ?`, medID, start, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &MedicationAdherenceReport{MedicationID: medID, Days: days, Daily: []MedicationAdherenceDay{}}
+	byDay := make(map[string]*MedicationAdherenceDay)
+	for day := start; !day.After(now); day = day.AddDate(0, 0, 1) {
+		report.Daily = append(report.Daily, MedicationAdherenceDay{Date: day.Format("2006-01-02")})
+	}
+	for i := range report.Daily {
+		byDay[report.Daily[i].Date] = &report.Daily[i]
+	}
+
+	for rows.Next() {
+		var status string
+		var scheduledAt time.Time
+		if err := rows.Scan(&status, &scheduledAt); err != nil {
+			return nil, err
+		}
+		entry := byDay[scheduledAt.In(loc).Format("2006-01-02")]
+		if entry == nil {
+			continue
+		}
+
+		switch {
+		case status == "TAKEN":
+			entry.Taken++
+			report.Taken++
+		case status == "PENDING" && now.Sub(scheduledAt) < adherenceGracePeriod:
+			report.Pending++
+			continue
+		default:
+			entry.Missed++
+			report.Missed++
+		}
+		entry.Total++
+		report.Total++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report.AdherenceRate = percentage(float64(report.Taken), float64(report.Total))
+	for i := range report.Daily {
+		if d := &report.Daily[i]; d.Total > 0 {
+			rate := percentage(float64(d.Taken), float64(d.Total))
+			d.Rate = &rate
+		}
+	}
+	return report, nil
+}
+
+func containsDay(days []int, day int) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// percentage returns part/total as a percentage rounded to one decimal (0 if total is 0)
+func percentage(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(part/total*1000) / 10
+}
@@ -0,0 +1,274 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+// weightedScoreMissing records ten taken days for a critical and a low-priority med,
+// except that the given med misses one dose, and returns the resulting stats
+func weightedScoreMissing(t *testing.T, missCritical bool) *AdherenceStats {
+	t.Helper()
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	critID, _ := s.CreateMedication("Warfarin", "5mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	lowID, _ := s.CreateMedication("Vitamin D", "1000IU", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	if err := s.SetMedicationPriority(critID, PriorityCritical); err != nil {
+		t.Fatalf("SetMedicationPriority failed: %v", err)
+	}
+	if err := s.SetMedicationPriority(lowID, PriorityLow); err != nil {
+		t.Fatalf("SetMedicationPriority failed: %v", err)
+	}
+
+	missed := lowID
+	if missCritical {
+		missed = critID
+	}
+
+	now := time.Now()
+	for day := 1; day <= 10; day++ {
+		at := now.AddDate(0, 0, -day)
+		for _, medID := range []int64{critID, lowID} {
+			id, _ := s.CreateIntake(medID, 1, at)
+			if medID == missed && day == 1 {
+				s.MarkIntakeMissed(id, "")
+				continue
+			}
+			s.ConfirmIntake(id, at)
+		}
+	}
+
+	stats, err := s.GetAdherenceStats(now.AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("GetAdherenceStats failed: %v", err)
+	}
+	return stats
+}
+
+func TestGetAdherenceStats_CriticalMissesWeighMore(t *testing.T) {
+	critical := weightedScoreMissing(t, true)
+	low := weightedScoreMissing(t, false)
+
+	// The plain rate does not care which medication was missed
+	if critical.Rate != 95 || low.Rate != 95 {
+		t.Errorf("Expected 95%% plain rate in both cases, got %.1f and %.1f", critical.Rate, low.Rate)
+	}
+	if critical.WeightedScore >= low.WeightedScore {
+		t.Errorf("Expected critical miss to lower the weighted score more: critical %.1f, low %.1f",
+			critical.WeightedScore, low.WeightedScore)
+	}
+
+	if critical.CriticalMisses != 1 || low.CriticalMisses != 0 {
+		t.Errorf("Unexpected critical miss counts: %d and %d", critical.CriticalMisses, low.CriticalMisses)
+	}
+	// Critical medications are listed first
+	if len(low.Medications) != 2 || low.Medications[0].Priority != PriorityCritical {
+		t.Errorf("Expected critical medication first, got %+v", low.Medications)
+	}
+}
+
+func TestSetMedicationPriority_Invalid(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	id, _ := s.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	med, _ := s.GetMedication(id)
+	if med.Priority != PriorityNormal {
+		t.Errorf("Expected default priority %q, got %q", PriorityNormal, med.Priority)
+	}
+	if err := s.SetMedicationPriority(id, "urgent"); err == nil {
+		t.Error("Expected error for unknown priority")
+	}
+}
+
+func TestGetDailyAdherence(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	fixedNow := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	dailyID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	weeklyID, _ := s.CreateMedication("Methotrexate", "10mg", `{"type":"weekly","days":[1],"times":["09:00"]}`, nil, nil, "", "")
+	prnID, _ := s.CreateMedication("Ibuprofen", "200mg", `{"type":"as_needed"}`, nil, nil, "", "")
+	s.db.Exec("UPDATE medications SET created_at = ? WHERE id = ?", "2024-03-05 00:00:00", dailyID)
+	s.db.Exec("UPDATE medications SET created_at = ? WHERE id = ?", "2024-03-01 00:00:00", weeklyID)
+
+	at := func(day, hour int) time.Time { return time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC) }
+	take := func(medID int64, scheduled time.Time) {
+		t.Helper()
+		id, err := s.CreateIntake(medID, 1, scheduled)
+		if err != nil {
+			t.Fatalf("CreateIntake failed: %v", err)
+		}
+		if err := s.ConfirmIntake(id, scheduled); err != nil {
+			t.Fatalf("ConfirmIntake failed: %v", err)
+		}
+	}
+
+	take(dailyID, at(5, 8))
+	take(dailyID, at(5, 20))
+	take(dailyID, at(6, 8))
+	missedID, _ := s.CreateIntake(dailyID, 1, at(7, 8))
+	s.MarkIntakeMissed(missedID, "")
+	take(prnID, at(7, 14)) // As-needed doses are never expected
+	take(dailyID, at(8, 8))
+	take(dailyID, at(8, 9)) // Extra dose beyond the schedule
+	take(dailyID, at(8, 20))
+	take(dailyID, at(9, 8))
+	take(dailyID, at(9, 20))
+	take(dailyID, at(10, 8))
+
+	days, err := s.GetDailyAdherence(1, at(3, 0))
+	if err != nil {
+		t.Fatalf("GetDailyAdherence failed: %v", err)
+	}
+
+	want := []struct {
+		date            string
+		taken, expected int
+		ratio           float64 // -1 means nothing scheduled
+	}{
+		{"2024-03-03", 0, 0, -1},
+		{"2024-03-04", 0, 1, 0}, // Monday: weekly dose only, daily med not created yet
+		{"2024-03-05", 2, 2, 1},
+		{"2024-03-06", 1, 2, 0.5},
+		{"2024-03-07", 0, 2, 0},
+		{"2024-03-08", 2, 2, 1}, // Three doses taken, capped at the two expected
+		{"2024-03-09", 2, 2, 1},
+		{"2024-03-10", 1, 1, 1}, // 20:00 hasn't happened yet
+	}
+	if len(days) != len(want) {
+		t.Fatalf("Expected %d days, got %d: %+v", len(want), len(days), days)
+	}
+	for i, w := range want {
+		d := days[i]
+		if d.Date != w.date || d.Taken != w.taken || d.Expected != w.expected {
+			t.Errorf("Day %d: got %s %d/%d, want %s %d/%d", i, d.Date, d.Taken, d.Expected, w.date, w.taken, w.expected)
+		}
+		if w.ratio < 0 {
+			if d.Ratio != nil {
+				t.Errorf("%s: expected nil ratio, got %v", d.Date, *d.Ratio)
+			}
+		} else if d.Ratio == nil || *d.Ratio != w.ratio {
+			t.Errorf("%s: expected ratio %v, got %v", d.Date, w.ratio, d.Ratio)
+		}
+	}
+}
+
+func TestGetLongestAdherenceStreak(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	fixedNow := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	if streak, err := s.GetLongestAdherenceStreak(1); err != nil || streak.Days != 0 {
+		t.Fatalf("Expected no streak without medications, got %+v (%v)", streak, err)
+	}
+
+	medID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	s.db.Exec("UPDATE medications SET created_at = ? WHERE id = ?", "2024-03-01 00:00:00", medID)
+
+	// Taken on the 1st-3rd, 5th-9th and 12th-15th; the 4th, 10th and 11th are missed
+	for day := 1; day <= 15; day++ {
+		if day == 4 || day == 10 || day == 11 {
+			continue
+		}
+		scheduled := time.Date(2024, 3, day, 8, 0, 0, 0, time.UTC)
+		id, err := s.CreateIntake(medID, 1, scheduled)
+		if err != nil {
+			t.Fatalf("CreateIntake failed: %v", err)
+		}
+		s.ConfirmIntake(id, scheduled)
+	}
+
+	streak, err := s.GetLongestAdherenceStreak(1)
+	if err != nil {
+		t.Fatalf("GetLongestAdherenceStreak failed: %v", err)
+	}
+	if streak.Days != 5 || streak.StartDate != "2024-03-05" || streak.EndDate != "2024-03-09" {
+		t.Errorf("Expected the 5-day streak from 2024-03-05 to 2024-03-09, got %+v", streak)
+	}
+}
+
+func TestGetMedicationAdherence(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	fixedNow := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	medID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	otherID, _ := s.CreateMedication("Vitamin D", "1000IU", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+
+	at := func(day, hour int) time.Time { return time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC) }
+	taken := func(medID int64, t time.Time) {
+		id, _ := s.CreateIntake(medID, 1, t)
+		s.ConfirmIntake(id, t)
+	}
+
+	taken(medID, at(7, 8)) // Before the window
+	taken(medID, at(8, 8))
+	id, _ := s.CreateIntake(medID, 1, at(8, 20))
+	s.MarkIntakeMissed(id, "")
+	taken(medID, at(9, 8))
+	taken(medID, at(9, 20))
+	s.CreateIntake(medID, 1, at(10, 8))  // Pending past the grace period: missed
+	s.CreateIntake(medID, 1, at(10, 11)) // Still within the grace period
+	taken(otherID, at(9, 8))
+
+	// Archiving keeps the history
+	if _, err := s.db.Exec("UPDATE medications SET archived = 1 WHERE id = ?", medID); err != nil {
+		t.Fatalf("Failed to archive: %v", err)
+	}
+
+	report, err := s.GetMedicationAdherence(medID, 3)
+	if err != nil {
+		t.Fatalf("GetMedicationAdherence failed: %v", err)
+	}
+	if report.Taken != 3 || report.Missed != 2 || report.Pending != 1 || report.Total != 5 || report.AdherenceRate != 60 {
+		t.Errorf("Expected 3 taken, 2 missed, 1 pending of 5 (60%%), got %+v", report)
+	}
+
+	if len(report.Daily) != 3 {
+		t.Fatalf("Expected 3 days, got %d", len(report.Daily))
+	}
+	want := []struct {
+		date        string
+		taken, miss int
+		rate        float64
+	}{
+		{"2024-03-08", 1, 1, 50},
+		{"2024-03-09", 2, 0, 100},
+		{"2024-03-10", 0, 1, 0},
+	}
+	for i, w := range want {
+		d := report.Daily[i]
+		if d.Date != w.date || d.Taken != w.taken || d.Missed != w.miss || d.Rate == nil || *d.Rate != w.rate {
+			t.Errorf("Day %d: expected %+v, got %+v", i, w, d)
+		}
+	}
+}
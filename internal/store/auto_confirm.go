@@ -0,0 +1,85 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// AutoConfirmedNote is recorded on intakes the scheduler assumed taken
+const AutoConfirmedNote = "auto-confirmed"
+
+// SetAutoConfirmAfter sets how many minutes after the scheduled time an unconfirmed dose
+// is assumed taken (nil to never assume so)
+func (s *Store) SetAutoConfirmAfter(medID int64, minutes *int) error {
+	if minutes != nil && *minutes <= 0 {
+		return fmt.Errorf("auto-confirm delay must be positive")
+	}
+	_, err := s.db.Exec("UPDATE medications SET auto_confirm_after = ? WHERE id = ?", minutes, medID)
+	return err
+}
+
+// AutoConfirmOverdueIntakes marks the pending doses of active auto-confirm medications
+// as taken once their window has passed, at the scheduled time, and takes one dose (the
+// dose per intake) from inventory for each. Doses on a skip date are left alone. These doses are never marked
+// missed for being overdue. Returns the confirmed intakes.
+func (s *Store) AutoConfirmOverdueIntakes(now time.Time) ([]IntakeLog, error) {
+	rows, err := s.db.Query(`
+		SELECT il.id, il.medication_id, il.user_id, il.scheduled_at, m.auto_confirm_after, m.dose_per_intake
+		FROM intake_log il
+		JOIN medications m ON il.medication_id = m.id
+		WHERE il.status = 'PENDING' AND m.archived = 0 AND m.auto_confirm_after IS NOT NULL
+		ORDER BY il.scheduled_at`)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []IntakeLog
+	for rows.Next() {
+		var l IntakeLog
+		var minutes int
+		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &minutes, &l.Quantity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if now.Sub(l.ScheduledAt) >= time.Duration(minutes)*time.Minute {
+			due = append(due, l)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	confirmed := []IntakeLog{}
+	for _, l := range due {
+		skipped, err := s.IsMedicationSkipped(l.MedicationID, l.ScheduledAt.Local())
+		if err != nil {
+			return confirmed, err
+		}
+		if skipped {
+			continue
+		}
+
+		// The dose may have been confirmed since it was read
+		res, err := s.db.Exec("UPDATE intake_log SET status = 'TAKEN', taken_at = ?, quantity = ?, notes = COALESCE(NULLIF(notes, ''), ?) WHERE id = ? AND status = 'PENDING'",
+			l.ScheduledAt, l.Quantity, AutoConfirmedNote, l.ID)
+		if err != nil {
+			return confirmed, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			continue
+		}
+		if err := s.DecrementInventory(l.MedicationID, l.Quantity); err != nil {
+			return confirmed, err
+		}
+		if err := s.recordRotationInjection(l.ID, l.ScheduledAt); err != nil {
+			return confirmed, err
+		}
+
+		takenAt := l.ScheduledAt
+		l.Status = "TAKEN"
+		l.TakenAt = &takenAt
+		confirmed = append(confirmed, l)
+	}
+	return confirmed, nil
+}
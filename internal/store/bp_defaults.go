@@ -0,0 +1,44 @@
+package store
+
+import "database/sql"
+
+// BPDefaults are the site and position of the user's usual BP measurement
+type BPDefaults struct {
+	Site     string `json:"site"`
+	Position string `json:"position"`
+}
+
+// GetBPDefaults returns the configured BP defaults; empty fields have no default
+func (s *Store) GetBPDefaults() (*BPDefaults, error) {
+	var site, position sql.NullString
+	err := s.db.QueryRow("SELECT bp_default_site, bp_default_position FROM settings WHERE id = 1").Scan(&site, &position)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return &BPDefaults{Site: site.String, Position: position.String}, nil
+}
+
+// SetBPDefaults stores the BP defaults; an empty field clears that default
+func (s *Store) SetBPDefaults(d BPDefaults) error {
+	_, err := s.db.Exec("UPDATE settings SET bp_default_site = NULLIF(?, ''), bp_default_position = NULLIF(?, '') WHERE id = 1",
+		d.Site, d.Position)
+	return err
+}
+
+// ApplyBPDefaults fills in the site and position of a reading that omits them
+func (s *Store) ApplyBPDefaults(bp *BloodPressure) error {
+	if bp.Site != "" && bp.Position != "" {
+		return nil
+	}
+	d, err := s.GetBPDefaults()
+	if err != nil {
+		return err
+	}
+	if bp.Site == "" {
+		bp.Site = d.Site
+	}
+	if bp.Position == "" {
+		bp.Position = d.Position
+	}
+	return nil
+}
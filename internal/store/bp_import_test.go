@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestImportBloodPressureReadings_ResumeAfterFailure(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	userID := int64(1)
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	var readings []BloodPressure
+	for i := 0; i < 10; i++ {
+		readings = append(readings, BloodPressure{
+			MeasuredAt: base.Add(time.Duration(i) * time.Hour),
+			Systolic:   120 + i,
+			Diastolic:  80,
+		})
+	}
+
+	// Simulate a failure after the first chunk is committed
+	ctx, cancel := context.WithCancel(context.Background())
+	result, err := db.ImportBloodPressureReadingsChunked(ctx, userID, readings, 4, func(p BPImportResult) {
+		cancel()
+	})
+	if err == nil {
+		t.Fatal("expected import to fail after cancellation")
+	}
+	if result.Processed != 4 || result.Imported != 4 {
+		t.Errorf("expected 4 rows committed before failure, got processed=%d imported=%d", result.Processed, result.Imported)
+	}
+
+	stored, err := db.GetBloodPressureReadings(context.Background(), userID, time.Time{}, 0, 0)
+	if err != nil {
+		t.Fatalf("GetBloodPressureReadings: %v", err)
+	}
+	if len(stored) != 4 {
+		t.Fatalf("expected 4 readings after partial import, got %d", len(stored))
+	}
+
+	// Re-run: resumes from the marker and completes without duplicates
+	var progressCalls int
+	result, err = db.ImportBloodPressureReadingsChunked(context.Background(), userID, readings, 4, func(p BPImportResult) {
+		progressCalls++
+	})
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if result.Resumed != 3 {
+		t.Errorf("expected 3 rows skipped by the resume marker, got %d", result.Resumed)
+	}
+	if result.Imported != 6 || result.Duplicates != 1 {
+		t.Errorf("expected 6 imported and 1 duplicate, got %d and %d", result.Imported, result.Duplicates)
+	}
+	if result.Processed != 10 || progressCalls != 2 {
+		t.Errorf("expected all 10 processed in 2 chunks, got %d in %d", result.Processed, progressCalls)
+	}
+
+	stored, err = db.GetBloodPressureReadings(context.Background(), userID, time.Time{}, 0, 0)
+	if err != nil {
+		t.Fatalf("GetBloodPressureReadings: %v", err)
+	}
+	if len(stored) != 10 {
+		t.Errorf("expected 10 readings after resume, got %d", len(stored))
+	}
+
+	// Marker is cleared once complete, so importing the same data again only finds duplicates
+	result, err = db.ImportBloodPressureReadings(context.Background(), userID, readings)
+	if err != nil {
+		t.Fatalf("re-import failed: %v", err)
+	}
+	if result.Resumed != 0 || result.Imported != 0 || result.Duplicates != 10 {
+		t.Errorf("expected a full pass with only duplicates, got %+v", result)
+	}
+}
+
+func TestImportBloodPressureReadings_MarkerPerImport(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	userID := int64(1)
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	series := func(offset int) []BloodPressure {
+		var readings []BloodPressure
+		for i := 0; i < 8; i++ {
+			readings = append(readings, BloodPressure{
+				MeasuredAt: base.Add(time.Duration(i) * time.Hour),
+				Systolic:   120 + offset + i,
+				Diastolic:  80,
+			})
+		}
+		return readings
+	}
+
+	// Interrupt the import of one file after its first chunk
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := db.ImportBloodPressureReadingsChunked(ctx, userID, series(0), 4, func(p BPImportResult) {
+		cancel()
+	}); err == nil {
+		t.Fatal("expected import to fail after cancellation")
+	}
+
+	// A different file covering the same hours is imported in full
+	result, err := db.ImportBloodPressureReadingsChunked(context.Background(), userID, series(20), 4, nil)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Resumed != 0 || result.Imported != 8 {
+		t.Errorf("expected all 8 rows of the other file imported, got %+v", result)
+	}
+}
+
+func TestImportBloodPressureReadings_SkipsInvalid(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	userID := int64(1)
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	readings := []BloodPressure{
+		{MeasuredAt: base, Systolic: 120, Diastolic: 80},
+		{MeasuredAt: base.Add(time.Hour), Systolic: 300, Diastolic: 80},
+		{MeasuredAt: base.Add(2 * time.Hour), Systolic: 125, Diastolic: 82},
+	}
+
+	// The dry run and the import agree on what gets in
+	report, err := db.ValidateBloodPressureImport(context.Background(), userID, readings)
+	if err != nil {
+		t.Fatalf("ValidateBloodPressureImport: %v", err)
+	}
+	result, err := db.ImportBloodPressureReadings(context.Background(), userID, readings)
+	if err != nil {
+		t.Fatalf("ImportBloodPressureReadings: %v", err)
+	}
+	if result.Imported != report.Valid || result.Invalid != report.Invalid {
+		t.Errorf("Expected import to match the report %+v, got %+v", report, result)
+	}
+	if result.Imported != 2 || len(result.Errors) != 1 || result.Errors[0].Index != 1 || result.Processed != 3 {
+		t.Errorf("Expected 2 imported and row 1 reported invalid, got %+v", result)
+	}
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Plausible BP values, shared by imports and the /bp command
+const (
+	MinSystolic  = 60
+	MaxSystolic  = 250
+	MinDiastolic = 40
+	MaxDiastolic = 150
+	MinPulse     = 40
+	MaxPulse     = 200
+)
+
+// BPImportRowError explains why a reading would be rejected
+type BPImportRowError struct {
+	Index  int    `json:"index"` // Position in the submitted readings
+	Reason string `json:"reason"`
+}
+
+// BPImportReport is the outcome of a dry-run import. Valid counts the readings that
+// would be imported; invalid rows and duplicates of stored readings (or of earlier rows)
+// are skipped by the import and aren't included.
+type BPImportReport struct {
+	Total      int                `json:"total"`
+	Valid      int                `json:"valid"`
+	Invalid    int                `json:"invalid"`
+	Duplicates int                `json:"duplicates"`
+	Errors     []BPImportRowError `json:"errors"`
+}
+
+// Validate reports the first problem with an imported reading
+func (bp *BloodPressure) Validate() error {
+	if bp.MeasuredAt.IsZero() {
+		return fmt.Errorf("missing measured_at")
+	}
+	if bp.Systolic < MinSystolic || bp.Systolic > MaxSystolic {
+		return fmt.Errorf("systolic %d out of range (%d-%d)", bp.Systolic, MinSystolic, MaxSystolic)
+	}
+	if bp.Diastolic < MinDiastolic || bp.Diastolic > MaxDiastolic {
+		return fmt.Errorf("diastolic %d out of range (%d-%d)", bp.Diastolic, MinDiastolic, MaxDiastolic)
+	}
+	if bp.Diastolic >= bp.Systolic {
+		return fmt.Errorf("diastolic %d must be below systolic %d", bp.Diastolic, bp.Systolic)
+	}
+	if bp.Pulse != nil && (*bp.Pulse < MinPulse || *bp.Pulse > MaxPulse) {
+		return fmt.Errorf("pulse %d out of range (%d-%d)", *bp.Pulse, MinPulse, MaxPulse)
+	}
+	return nil
+}
+
+// ValidateBloodPressureImport checks readings as ImportBloodPressureReadings would see
+// them without writing anything. Duplicates match the unique index: same time, systolic
+// and diastolic as a stored reading or an earlier row.
+func (s *Store) ValidateBloodPressureImport(ctx context.Context, userID int64, readings []BloodPressure) (*BPImportReport, error) {
+	type readingKey struct {
+		measuredAt          time.Time
+		systolic, diastolic int
+	}
+
+	report := &BPImportReport{Total: len(readings), Errors: []BPImportRowError{}}
+	seen := make(map[readingKey]bool)
+	for i, bp := range readings {
+		if err := bp.Validate(); err != nil {
+			report.Invalid++
+			report.Errors = append(report.Errors, BPImportRowError{Index: i, Reason: err.Error()})
+			continue
+		}
+
+		key := readingKey{bp.MeasuredAt.UTC(), bp.Systolic, bp.Diastolic}
+		if seen[key] {
+			report.Duplicates++
+			continue
+		}
+		seen[key] = true
+
+		var exists int
+		err := s.db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM blood_pressure_readings WHERE user_id = ? AND measured_at = ? AND systolic = ? AND diastolic = ?",
+			userID, bp.MeasuredAt, bp.Systolic, bp.Diastolic).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if exists > 0 {
+			report.Duplicates++
+			continue
+		}
+		report.Valid++
+	}
+	return report, nil
+}
@@ -178,7 +178,7 @@ func (s *Store) GetLastBPReading(ctx context.Context, userID int64) (*BloodPress
 func (s *Store) GetDominantBPCategory(ctx context.Context, userID int64) (string, error) {
 	// Get readings from last 14 days
 	since := time.Now().AddDate(0, 0, -14)
-	readings, err := s.GetBloodPressureReadings(ctx, userID, since)
+	readings, err := s.GetBloodPressureReadings(ctx, userID, since, 0, 0)
 	if err != nil {
 		return "", err
 	}
@@ -217,7 +217,7 @@ func (s *Store) GetDominantBPCategory(ctx context.Context, userID int64) (string
 func (s *Store) CalculatePreferredReminderHour(ctx context.Context, userID int64) (int, error) {
 	// Get readings from last 14 days
 	since := time.Now().AddDate(0, 0, -14)
-	readings, err := s.GetBloodPressureReadings(ctx, userID, since)
+	readings, err := s.GetBloodPressureReadings(ctx, userID, since, 0, 0)
 	if err != nil {
 		return 20, err // Return default on error
 	}
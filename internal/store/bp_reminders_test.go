@@ -513,7 +513,7 @@ func TestGetBloodPressureReadings_Sorting(t *testing.T) {
 	}
 
 	// Fetch readings
-	readings, err := store.GetBloodPressureReadings(ctx, userID, time.Time{})
+	readings, err := store.GetBloodPressureReadings(ctx, userID, time.Time{}, 0, 0)
 	if err != nil {
 		t.Fatalf("Failed to get BP readings: %v", err)
 	}
@@ -373,3 +373,155 @@ func TestGetBPDailyWeightedStats_PartialPeriodOnlyIn60Days(t *testing.T) {
 		t.Fatalf("unexpected days: got %d want 1", stats.Stats60.Days)
 	}
 }
+
+func TestGetBPTimeInRange(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := int64(1)
+
+	fixedNow := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	add := func(ts time.Time, sys, dia int) {
+		t.Helper()
+		_, err := db.CreateBloodPressureReading(ctx, &BloodPressure{
+			UserID:     userID,
+			MeasuredAt: ts,
+			Systolic:   sys,
+			Diastolic:  dia,
+		})
+		if err != nil {
+			t.Fatalf("failed to insert reading: %v", err)
+		}
+	}
+
+	// Jan 8: Normal 00:00-12:00, Elevated 12:00-18:00, Stage 1 18:00-24:00
+	add(time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC), 110, 70)
+	add(time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC), 125, 75)
+	add(time.Date(2025, 1, 8, 18, 0, 0, 0, time.UTC), 135, 85)
+	// Jan 10: Stage 2 08:00-11:00, Crisis 11:00-12:00 (now)
+	add(time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC), 150, 95)
+	add(time.Date(2025, 1, 10, 11, 0, 0, 0, time.UTC), 190, 110)
+	// Outside the 30-day window
+	add(time.Date(2024, 11, 1, 8, 0, 0, 0, time.UTC), 190, 130)
+
+	tir, err := db.GetBPTimeInRange(ctx, userID, 30)
+	if err != nil {
+		t.Fatalf("failed to get time in range: %v", err)
+	}
+
+	// 28 hours in total
+	want := map[string]float64{
+		"Normal":              42.9, // 12h
+		"Elevated":            21.4, // 6h
+		"High BP Stage 1":     21.4, // 6h
+		"High BP Stage 2":     10.7, // 3h
+		"Hypertensive Crisis": 3.6,  // 1h
+	}
+	if tir.TotalMinutes != 28*60 || tir.Readings != 5 {
+		t.Fatalf("unexpected totals: %d minutes, %d readings", tir.TotalMinutes, tir.Readings)
+	}
+	if len(tir.Categories) != len(BPCategories) {
+		t.Fatalf("expected %d categories, got %d", len(BPCategories), len(tir.Categories))
+	}
+
+	var sum float64
+	for i, c := range tir.Categories {
+		if c.Category != BPCategories[i] {
+			t.Errorf("unexpected category order: got %s at %d", c.Category, i)
+		}
+		if c.Percent != want[c.Category] {
+			t.Errorf("%s: got %.1f%% want %.1f%%", c.Category, c.Percent, want[c.Category])
+		}
+		sum += c.Percent
+	}
+	if math.Abs(sum-100) > 1e-9 {
+		t.Errorf("percentages should sum to 100, got %v", sum)
+	}
+
+	// Three equal thirds still add up to 100
+	db2, _ := New(":memory:")
+	defer db2.Close()
+	for i, bp := range [][2]int{{110, 70}, {125, 75}, {135, 85}} {
+		db2.CreateBloodPressureReading(ctx, &BloodPressure{UserID: userID, MeasuredAt: time.Date(2025, 1, 10, 3+3*i, 0, 0, 0, time.UTC), Systolic: bp[0], Diastolic: bp[1]})
+	}
+	tir, err = db2.GetBPTimeInRange(ctx, userID, 30)
+	if err != nil {
+		t.Fatalf("failed to get time in range: %v", err)
+	}
+	sum = 0
+	for _, c := range tir.Categories {
+		sum += c.Percent
+	}
+	if math.Abs(sum-100) > 1e-9 {
+		t.Errorf("percentages should sum to 100 after rounding, got %v", sum)
+	}
+}
+
+func TestGetBPDailyWeightedStats_Trend(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := int64(1)
+
+	fixedNow := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	add := func(daysAgo, sys int) {
+		t.Helper()
+		day := fixedNow.AddDate(0, 0, -daysAgo)
+		_, err := db.CreateBloodPressureReading(ctx, &BloodPressure{
+			UserID:     userID,
+			MeasuredAt: time.Date(day.Year(), day.Month(), day.Day(), 8, 0, 0, 0, time.UTC),
+			Systolic:   sys,
+			Diastolic:  85,
+		})
+		if err != nil {
+			t.Fatalf("failed to insert reading: %v", err)
+		}
+	}
+
+	// Recent days alone can't show a direction
+	for d := 1; d <= 10; d++ {
+		add(d, 130)
+	}
+	stats, err := db.GetBPDailyWeightedStats(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.Trend != BPTrendStable {
+		t.Errorf("expected stable without older readings, got %q", stats.Trend)
+	}
+
+	// 31 older days at 140 put the 60-day average at ~137.6, 7.6 above the last 14 days
+	for d := 20; d <= 50; d++ {
+		add(d, 140)
+	}
+	stats, err = db.GetBPDailyWeightedStats(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.Trend != BPTrendFalling {
+		t.Errorf("expected falling, got %q (14d %.1f, 60d %.1f)", stats.Trend, stats.Stats14.SystolicMean, stats.Stats60.SystolicMean)
+	}
+	if trend := stats.BPTrend(10); trend != BPTrendStable {
+		t.Errorf("expected stable with a 10 mmHg threshold, got %q", trend)
+	}
+
+	if trend := (&BPStats{}).BPTrend(DefaultBPTrendThreshold); trend != BPTrendStable {
+		t.Errorf("expected stable without stats, got %q", trend)
+	}
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"math"
+)
+
+// BPCategories lists the blood pressure categories from best to worst
+var BPCategories = []string{"Normal", "Elevated", "High BP Stage 1", "High BP Stage 2", "Hypertensive Crisis"}
+
+// BPCategoryTime is the time-weighted share of one category
+type BPCategoryTime struct {
+	Category string  `json:"category"`
+	Minutes  int     `json:"minutes"`
+	Percent  float64 `json:"percent"`
+}
+
+// BPTimeInRange reports how the time covered by readings splits across categories
+type BPTimeInRange struct {
+	Days         int              `json:"days"`
+	Readings     int              `json:"readings"`
+	TotalMinutes int              `json:"total_minutes"`
+	Categories   []BPCategoryTime `json:"categories"` // Ordered as BPCategories
+}
+
+// GetBPTimeInRange computes the share of time spent in each category over the last
+// days days, weighting readings the same way as GetBPDailyWeightedStats. Percentages
+// sum to 100 when there is any data.
+func (s *Store) GetBPTimeInRange(ctx context.Context, userID int64, days int) (*BPTimeInRange, error) {
+	now := nowFunc().UTC()
+	periodStart := truncateToDayUTC(now.AddDate(0, 0, -days))
+
+	readings, err := s.getBPReadingsForStats(ctx, userID, periodStart)
+	if err != nil {
+		return nil, err
+	}
+
+	durations := make(map[string]float64)
+	var total float64
+	for _, seg := range bpWeightedSegments(readings, now) {
+		durations[CalculateBPCategory(seg.reading.Systolic, seg.reading.Diastolic)] += seg.durSec
+		total += seg.durSec
+	}
+
+	result := &BPTimeInRange{
+		Days:         days,
+		Readings:     len(readings),
+		TotalMinutes: int(math.Round(total / 60)),
+		Categories:   make([]BPCategoryTime, 0, len(BPCategories)),
+	}
+
+	var sum float64
+	largest := -1
+	for i, cat := range BPCategories {
+		ct := BPCategoryTime{
+			Category: cat,
+			Minutes:  int(math.Round(durations[cat] / 60)),
+			Percent:  percentage(durations[cat], total),
+		}
+		sum += ct.Percent
+		if largest < 0 || durations[cat] > durations[BPCategories[largest]] {
+			largest = i
+		}
+		result.Categories = append(result.Categories, ct)
+	}
+
+	// Put the rounding drift on the biggest category so the shares add up to exactly 100
+	if total > 0 {
+		drift := math.Round((100-sum)*10) / 10
+		result.Categories[largest].Percent = math.Round((result.Categories[largest].Percent+drift)*10) / 10
+	}
+
+	return result, nil
+}
@@ -0,0 +1,35 @@
+package store
+
+// BP trend directions
+const (
+	BPTrendRising  = "rising"
+	BPTrendFalling = "falling"
+	BPTrendStable  = "stable"
+)
+
+// DefaultBPTrendThreshold is how many mmHg the 14-day systolic average must differ from
+// the 60-day one before BP counts as rising or falling
+const DefaultBPTrendThreshold = 3.0
+
+// bpTrendMinDays is how many days with readings both the last 14 days and the 46 days
+// before them need for a trend; with less the direction is just noise
+const bpTrendMinDays = 3
+
+// BPTrend compares the 14-day systolic average with the 60-day one. Without enough
+// days of readings in both the recent and the older part of the window it is stable.
+func (st *BPStats) BPTrend(threshold float64) string {
+	if st.Stats14 == nil || st.Stats60 == nil {
+		return BPTrendStable
+	}
+	if st.Stats14.Days < bpTrendMinDays || st.Stats60.Days-st.Stats14.Days < bpTrendMinDays {
+		return BPTrendStable
+	}
+
+	switch diff := st.Stats14.SystolicMean - st.Stats60.SystolicMean; {
+	case diff >= threshold:
+		return BPTrendRising
+	case diff <= -threshold:
+		return BPTrendFalling
+	}
+	return BPTrendStable
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// Tags of triplicate measurements
+const (
+	TagTriplicate    = "triplicate"
+	TagTriplicateRaw = "triplicate-raw"
+)
+
+// AverageTriplicate combines three readings taken a minute apart per the clinical
+// protocol: the first is discarded and the other two are averaged. Pulse is averaged
+// over the kept readings that have one. The result carries the first kept reading's
+// time, site and position.
+func AverageTriplicate(readings []BloodPressure) (*BloodPressure, error) {
+	if len(readings) != 3 {
+		return nil, fmt.Errorf("expected 3 readings, got %d", len(readings))
+	}
+	for i := range readings {
+		if err := readings[i].Validate(); err != nil {
+			return nil, fmt.Errorf("reading %d: %w", i+1, err)
+		}
+	}
+
+	kept := readings[1:]
+	avg := &BloodPressure{
+		UserID:     kept[0].UserID,
+		MeasuredAt: kept[0].MeasuredAt,
+		Site:       kept[0].Site,
+		Position:   kept[0].Position,
+		Notes:      kept[0].Notes,
+		Tag:        TagTriplicate,
+	}
+
+	var sumSys, sumDia, sumPulse, pulses int
+	for _, bp := range kept {
+		sumSys += bp.Systolic
+		sumDia += bp.Diastolic
+		if bp.Pulse != nil {
+			sumPulse += *bp.Pulse
+			pulses++
+		}
+	}
+	avg.Systolic = int(math.Round(float64(sumSys) / float64(len(kept))))
+	avg.Diastolic = int(math.Round(float64(sumDia) / float64(len(kept))))
+	if pulses > 0 {
+		pulse := int(math.Round(float64(sumPulse) / float64(pulses)))
+		avg.Pulse = &pulse
+	}
+	avg.Category = CalculateBPCategory(avg.Systolic, avg.Diastolic)
+	return avg, nil
+}
+
+// CreateTriplicateBPReading stores the average of a triplicate measurement tagged
+// "triplicate". With keepRaw the three readings are stored too, tagged "triplicate-raw"
+// and excluded from statistics so they don't count twice; a raw reading identical to the
+// average (same time and values) is already represented by it and skipped. Returns the
+// averaged reading.
+func (s *Store) CreateTriplicateBPReading(ctx context.Context, userID int64, readings []BloodPressure, keepRaw bool) (*BloodPressure, error) {
+	avg, err := AverageTriplicate(readings)
+	if err != nil {
+		return nil, err
+	}
+	avg.UserID = userID
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO blood_pressure_readings (user_id, measured_at, systolic, diastolic, pulse, site, position, category, ignore_calc, notes, tag) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		avg.UserID, avg.MeasuredAt, avg.Systolic, avg.Diastolic, avg.Pulse, avg.Site, avg.Position, avg.Category, false, avg.Notes, avg.Tag)
+	if err != nil {
+		return nil, err
+	}
+	if avg.ID, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+
+	if keepRaw {
+		for _, bp := range readings {
+			category := CalculateBPCategory(bp.Systolic, bp.Diastolic)
+			_, err := tx.ExecContext(ctx,
+				"INSERT OR IGNORE INTO blood_pressure_readings (user_id, measured_at, systolic, diastolic, pulse, site, position, category, ignore_calc, notes, tag) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				userID, bp.MeasuredAt, bp.Systolic, bp.Diastolic, bp.Pulse, bp.Site, bp.Position, category, true, bp.Notes, TagTriplicateRaw)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return avg, tx.Commit()
+}
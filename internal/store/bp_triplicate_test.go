@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCreateTriplicateBPReading(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	userID := int64(1)
+	start := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+	pulse := func(v int) *int { return &v }
+
+	triplicate := func(offset time.Duration) []BloodPressure {
+		at := start.Add(offset)
+		return []BloodPressure{
+			{MeasuredAt: at, Systolic: 150, Diastolic: 96, Pulse: pulse(84)}, // White-coat spike, discarded
+			{MeasuredAt: at.Add(time.Minute), Systolic: 134, Diastolic: 86, Pulse: pulse(72)},
+			{MeasuredAt: at.Add(2 * time.Minute), Systolic: 128, Diastolic: 81, Pulse: pulse(69)},
+		}
+	}
+
+	avg, err := s.CreateTriplicateBPReading(ctx, userID, triplicate(0), false)
+	if err != nil {
+		t.Fatalf("CreateTriplicateBPReading failed: %v", err)
+	}
+	// (134+128)/2, (86+81)/2 rounded, (72+69)/2 rounded
+	if avg.Systolic != 131 || avg.Diastolic != 84 || avg.Pulse == nil || *avg.Pulse != 71 {
+		t.Errorf("Expected 131/84 pulse 71, got %d/%d pulse %v", avg.Systolic, avg.Diastolic, avg.Pulse)
+	}
+	if avg.Tag != TagTriplicate || !avg.MeasuredAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected triplicate tag at the second reading's time, got %q at %v", avg.Tag, avg.MeasuredAt)
+	}
+
+	readings, _ := s.GetBloodPressureReadings(ctx, userID, time.Time{}, 0, 0)
+	if len(readings) != 1 {
+		t.Fatalf("Expected only the averaged reading to be stored, got %d", len(readings))
+	}
+	if readings[0].Systolic != 131 || readings[0].Diastolic != 84 || readings[0].Tag != TagTriplicate {
+		t.Errorf("Unexpected stored reading: %+v", readings[0])
+	}
+
+	// Raw readings are kept but don't count towards statistics
+	if _, err := s.CreateTriplicateBPReading(ctx, userID, triplicate(12*time.Hour), true); err != nil {
+		t.Fatalf("CreateTriplicateBPReading with raw readings failed: %v", err)
+	}
+	readings, _ = s.GetBloodPressureReadings(ctx, userID, time.Time{}, 0, 0)
+	if len(readings) != 5 {
+		t.Fatalf("Expected 2 averaged and 3 raw readings, got %d", len(readings))
+	}
+	raw := 0
+	for _, r := range readings {
+		if r.Tag == TagTriplicateRaw {
+			raw++
+			if !r.IgnoreCalc {
+				t.Errorf("Expected raw reading to be excluded from statistics: %+v", r)
+			}
+		}
+	}
+	if raw != 3 {
+		t.Errorf("Expected 3 raw readings, got %d", raw)
+	}
+	forStats, _ := s.getBPReadingsForStats(ctx, userID, time.Time{})
+	if len(forStats) != 2 {
+		t.Errorf("Expected statistics to see only the 2 averaged readings, got %d", len(forStats))
+	}
+
+	if _, err := s.CreateTriplicateBPReading(ctx, userID, triplicate(0)[:2], false); err == nil {
+		t.Error("Expected an error for two readings")
+	}
+	invalid := triplicate(24 * time.Hour)
+	invalid[2].Systolic = 20
+	if _, err := s.CreateTriplicateBPReading(ctx, userID, invalid, false); err == nil {
+		t.Error("Expected an error for an implausible reading")
+	}
+}
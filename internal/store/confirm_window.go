@@ -0,0 +1,138 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultConfirmWindowMinutes is the confirm window until the user sets one
+	DefaultConfirmWindowMinutes = 12 * 60
+	maxConfirmWindowMinutes     = 24 * 60
+)
+
+// GetConfirmWindowMinutes returns how far a confirmation may be from a pending dose's
+// scheduled time and still confirm it
+func (s *Store) GetConfirmWindowMinutes() (int, error) {
+	var minutes sql.NullInt64
+	err := s.db.QueryRow("SELECT confirm_window_minutes FROM settings WHERE id = 1").Scan(&minutes)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	if !minutes.Valid {
+		return DefaultConfirmWindowMinutes, nil
+	}
+	return int(minutes.Int64), nil
+}
+
+// ConfirmWindow is GetConfirmWindowMinutes as a duration, for matching a confirmation
+// to its pending intake
+func (s *Store) ConfirmWindow() (time.Duration, error) {
+	minutes, err := s.GetConfirmWindowMinutes()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// SetConfirmWindowMinutes stores the confirm window (0 to 24 hours); 0 requires the
+// exact scheduled time
+func (s *Store) SetConfirmWindowMinutes(minutes int) error {
+	if minutes < 0 || minutes > maxConfirmWindowMinutes {
+		return fmt.Errorf("confirm window must be between 0 and %d minutes", maxConfirmWindowMinutes)
+	}
+	_, err := s.db.Exec("UPDATE settings SET confirm_window_minutes = ? WHERE id = 1", minutes)
+	return err
+}
+
+// pendingIntakesNear returns the user's pending intakes of active medications that share
+// the scheduled time closest to scheduledAt. Only intakes on the same calendar day (in
+// scheduledAt's location) and within the confirm window qualify, so a stale "Confirm ALL"
+// button still resolves its doses but never reaches into another day. A dose also has to
+// be closer to scheduledAt than any other dose of its medication that day, so a stale
+// morning button whose doses were already taken doesn't confirm the evening ones.
+func (s *Store) pendingIntakesNear(userID int64, scheduledAt time.Time) ([]IntakeLog, error) {
+	window, err := s.ConfirmWindow()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, medication_id, user_id, scheduled_at, status FROM intake_log
+		WHERE user_id = ?
+		  AND medication_id IN (SELECT id FROM medications WHERE archived = 0)`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loc := scheduledAt.Location()
+	year, month, day := scheduledAt.Date()
+
+	var candidates []IntakeLog
+	doseTimes := make(map[int64][]time.Time)
+	for rows.Next() {
+		var l IntakeLog
+		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.Status); err != nil {
+			return nil, err
+		}
+		if y, m, d := l.ScheduledAt.In(loc).Date(); y != year || m != month || d != day {
+			continue
+		}
+		doseTimes[l.MedicationID] = append(doseTimes[l.MedicationID], l.ScheduledAt)
+		if l.Status != "PENDING" {
+			continue
+		}
+		if absDuration(l.ScheduledAt.Sub(scheduledAt)) > window {
+			continue
+		}
+		candidates = append(candidates, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var closest []IntakeLog
+	for _, l := range candidates {
+		if isClosestDose(l.ScheduledAt, doseTimes[l.MedicationID], scheduledAt) {
+			closest = append(closest, l)
+		}
+	}
+	candidates = closest
+
+	var nearest *time.Time
+	for i := range candidates {
+		at := candidates[i].ScheduledAt
+		if nearest == nil || absDuration(at.Sub(scheduledAt)) < absDuration(nearest.Sub(scheduledAt)) {
+			nearest = &at
+		}
+	}
+
+	var logs []IntakeLog
+	for _, l := range candidates {
+		if l.ScheduledAt.Equal(*nearest) {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+// isClosestDose reports whether at is strictly closer to target than every other
+// time in doseTimes
+func isClosestDose(at time.Time, doseTimes []time.Time, target time.Time) bool {
+	distance := absDuration(at.Sub(target))
+	for _, other := range doseTimes {
+		if !other.Equal(at) && absDuration(other.Sub(target)) <= distance {
+			return false
+		}
+	}
+	return true
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
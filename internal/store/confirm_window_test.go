@@ -0,0 +1,102 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfirmIntakesBySchedule_Tolerance(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	medA, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	medB, _ := s.CreateMedication("Lisinopril", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+
+	morning := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+	evening := time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC)
+	yesterday := morning.AddDate(0, 0, -1).Add(12 * time.Hour)
+
+	aMorning, _ := s.CreateIntake(medA, 1, morning)
+	bMorning, _ := s.CreateIntake(medB, 1, morning)
+	aEvening, _ := s.CreateIntake(medA, 1, evening)
+	aYesterday, _ := s.CreateIntake(medA, 1, yesterday)
+
+	// A stale reminder whose timestamp lost its precision, confirmed hours later
+	target := morning.Add(90 * time.Second)
+	pending, err := s.GetPendingIntakesBySchedule(1, target)
+	if err != nil {
+		t.Fatalf("GetPendingIntakesBySchedule failed: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("Expected the two morning doses, got %d", len(pending))
+	}
+
+	if err := s.ConfirmIntakesBySchedule(1, target, morning.Add(6*time.Hour)); err != nil {
+		t.Fatalf("ConfirmIntakesBySchedule failed: %v", err)
+	}
+
+	want := map[int64]string{aMorning: "TAKEN", bMorning: "TAKEN", aEvening: "PENDING", aYesterday: "PENDING"}
+	for id, status := range want {
+		intake, err := s.GetIntake(id)
+		if err != nil {
+			t.Fatalf("GetIntake failed: %v", err)
+		}
+		if intake.Status != status {
+			t.Errorf("Intake at %v: expected %s, got %s", intake.ScheduledAt, status, intake.Status)
+		}
+	}
+}
+
+func TestConfirmIntakesBySchedule_OutsideWindow(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.SetConfirmWindowMinutes(30); err != nil {
+		t.Fatalf("SetConfirmWindowMinutes failed: %v", err)
+	}
+
+	medID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	morning := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+	id, _ := s.CreateIntake(medID, 1, morning)
+
+	if err := s.ConfirmIntakesBySchedule(1, morning.Add(45*time.Minute), morning.Add(time.Hour)); err != nil {
+		t.Fatalf("ConfirmIntakesBySchedule failed: %v", err)
+	}
+	intake, _ := s.GetIntake(id)
+	if intake.Status != "PENDING" {
+		t.Errorf("Expected the dose outside the window to stay pending, got %s", intake.Status)
+	}
+}
+
+func TestConfirmIntakesBySchedule_StaleButtonSkipsLaterDose(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	medID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	morning := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+	evening := time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC)
+	morningID, _ := s.CreateIntake(medID, 1, morning)
+	eveningID, _ := s.CreateIntake(medID, 1, evening)
+
+	if err := s.ConfirmIntake(morningID, morning.Add(5*time.Minute)); err != nil {
+		t.Fatalf("ConfirmIntake failed: %v", err)
+	}
+
+	// The morning "Confirm ALL" pressed again in the evening
+	if err := s.ConfirmIntakesBySchedule(1, morning, evening.Add(-time.Hour)); err != nil {
+		t.Fatalf("ConfirmIntakesBySchedule failed: %v", err)
+	}
+	intake, _ := s.GetIntake(eveningID)
+	if intake.Status != "PENDING" {
+		t.Errorf("Expected the evening dose to stay pending, got %s", intake.Status)
+	}
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LifeEvent marks something that happened on a day, like "started new job" or "flu",
+// to explain changes in BP or weight on charts
+type LifeEvent struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"user_id"`
+	Title  string `json:"title"`
+	Date   string `json:"date"` // YYYY-MM-DD
+	Note   string `json:"note,omitempty"`
+}
+
+// Validate checks that the event has a title and a valid date
+func (e *LifeEvent) Validate() error {
+	if strings.TrimSpace(e.Title) == "" {
+		return fmt.Errorf("title required")
+	}
+	if _, err := time.Parse("2006-01-02", e.Date); err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", e.Date)
+	}
+	return nil
+}
+
+func (s *Store) CreateLifeEvent(ctx context.Context, e *LifeEvent) (int64, error) {
+	if err := e.Validate(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO life_events (user_id, title, date, note) VALUES (?, ?, ?, ?)",
+		e.UserID, e.Title, e.Date, e.Note)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateLifeEvent replaces an existing event. Returns sql.ErrNoRows if the event doesn't
+// exist or belongs to another user.
+func (s *Store) UpdateLifeEvent(ctx context.Context, e *LifeEvent) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE life_events SET title = ?, date = ?, note = ? WHERE id = ? AND user_id = ?",
+		e.Title, e.Date, e.Note, e.ID, e.UserID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetLifeEvents returns the user's events dated from through to (YYYY-MM-DD, both
+// inclusive), oldest first. An empty from or to leaves that side open.
+func (s *Store) GetLifeEvents(ctx context.Context, userID int64, from, to string) ([]LifeEvent, error) {
+	query := "SELECT id, user_id, title, date, note FROM life_events WHERE user_id = ?"
+	args := []interface{}{userID}
+
+	if from != "" {
+		query += " AND date >= ?"
+		args = append(args, from)
+	}
+	if to != "" {
+		query += " AND date <= ?"
+		args = append(args, to)
+	}
+	query += " ORDER BY date ASC, id ASC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []LifeEvent
+	for rows.Next() {
+		var e LifeEvent
+		var note sql.NullString
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Title, &e.Date, &note); err != nil {
+			return nil, err
+		}
+		e.Note = note.String
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (s *Store) DeleteLifeEvent(ctx context.Context, id, userID int64) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM life_events WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestLifeEvents(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	userID, otherID := int64(123456), int64(654321)
+
+	events := []LifeEvent{
+		{UserID: userID, Title: "Flu", Date: "2024-02-10", Note: "fever for three days"},
+		{UserID: userID, Title: "Started new job", Date: "2024-01-15"},
+		{UserID: userID, Title: "Changed diet", Date: "2024-03-01"},
+		{UserID: otherID, Title: "Vacation", Date: "2024-02-01"},
+	}
+	for i := range events {
+		id, err := s.CreateLifeEvent(ctx, &events[i])
+		if err != nil {
+			t.Fatalf("CreateLifeEvent failed: %v", err)
+		}
+		events[i].ID = id
+	}
+
+	for _, e := range []LifeEvent{
+		{UserID: userID, Title: " ", Date: "2024-01-01"},
+		{UserID: userID, Title: "Moved", Date: "01.02.2024"},
+	} {
+		if _, err := s.CreateLifeEvent(ctx, &e); err == nil {
+			t.Errorf("Expected validation error for %+v", e)
+		}
+	}
+
+	// Both ends inclusive, oldest first, only the user's own events
+	got, err := s.GetLifeEvents(ctx, userID, "2024-01-15", "2024-02-10")
+	if err != nil {
+		t.Fatalf("GetLifeEvents failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Title != "Started new job" || got[1].Title != "Flu" || got[1].Note != "fever for three days" {
+		t.Fatalf("Expected the job and flu events, got %+v", got)
+	}
+
+	got, _ = s.GetLifeEvents(ctx, userID, "", "")
+	if len(got) != 3 {
+		t.Errorf("Expected 3 events without a range, got %d", len(got))
+	}
+	got, _ = s.GetLifeEvents(ctx, otherID, "", "")
+	if len(got) != 1 || got[0].Title != "Vacation" {
+		t.Errorf("Expected only the other user's event, got %+v", got)
+	}
+
+	// Another user's event can't be changed or deleted
+	foreign := events[3]
+	foreign.UserID = userID
+	foreign.Title = "Hijacked"
+	if err := s.UpdateLifeEvent(ctx, &foreign); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows updating another user's event, got %v", err)
+	}
+	if err := s.DeleteLifeEvent(ctx, events[3].ID, userID); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows deleting another user's event, got %v", err)
+	}
+
+	events[0].Date = "2024-02-12"
+	if err := s.UpdateLifeEvent(ctx, &events[0]); err != nil {
+		t.Fatalf("UpdateLifeEvent failed: %v", err)
+	}
+	if err := s.DeleteLifeEvent(ctx, events[1].ID, userID); err != nil {
+		t.Fatalf("DeleteLifeEvent failed: %v", err)
+	}
+	got, _ = s.GetLifeEvents(ctx, userID, "", "")
+	if len(got) != 2 || got[0].Date != "2024-02-12" {
+		t.Errorf("Expected the moved flu event first of 2, got %+v", got)
+	}
+}
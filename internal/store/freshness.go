@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Freshness metrics
+const (
+	FreshnessMetricBP         = "bp"
+	FreshnessMetricWeight     = "weight"
+	FreshnessMetricSleep      = "sleep"
+	FreshnessMetricMedication = "medication"
+)
+
+// MetricFreshness is when something was last logged. LastAt and DaysSince are nil when
+// it never was; DaysSince counts calendar days, so an entry from yesterday evening is 1.
+type MetricFreshness struct {
+	Metric       string     `json:"metric"`
+	MedicationID *int64     `json:"medication_id,omitempty"`
+	Name         string     `json:"name,omitempty"`
+	LastAt       *time.Time `json:"last_at"`
+	DaysSince    *int       `json:"days_since"`
+}
+
+// GetFreshness returns the latest BP reading, weigh-in and night of sleep, followed by the
+// last taken dose of each active medication (by name)
+func (s *Store) GetFreshness(ctx context.Context, userID int64) ([]MetricFreshness, error) {
+	now := nowFunc()
+	queries := []struct {
+		metric string
+		query  string
+	}{
+		{FreshnessMetricBP, "SELECT MAX(measured_at) FROM blood_pressure_readings WHERE user_id = ?"},
+		{FreshnessMetricWeight, "SELECT MAX(measured_at) FROM weight_logs WHERE user_id = ?"},
+		{FreshnessMetricSleep, "SELECT MAX(end_time) FROM sleep_logs WHERE user_id = ?"},
+	}
+
+	result := []MetricFreshness{}
+	for _, q := range queries {
+		var last sql.NullString
+		if err := s.db.QueryRowContext(ctx, q.query, userID).Scan(&last); err != nil {
+			return nil, err
+		}
+		f := MetricFreshness{Metric: q.metric}
+		if last.Valid {
+			if t, ok := parseDBTime(last.String); ok {
+				f.setLast(t, now)
+			}
+		}
+		result = append(result, f)
+	}
+
+	meds, err := s.ListMedications(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range meds {
+		id := m.ID
+		f := MetricFreshness{Metric: FreshnessMetricMedication, MedicationID: &id, Name: m.Name}
+		if m.LastTakenAt != nil {
+			f.setLast(*m.LastTakenAt, now)
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+func (f *MetricFreshness) setLast(t, now time.Time) {
+	t = t.In(now.Location())
+	f.LastAt = &t
+
+	lastDay := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	days := int(today.Sub(lastDay).Hours()/24 + 0.5) // Rounded across DST changes
+	if days < 0 {
+		days = 0
+	}
+	f.DaysSince = &days
+}
+
+// parseDBTime parses a timestamp read back as text, e.g. from an aggregate whose column
+// type the driver no longer knows
+func parseDBTime(value string) (time.Time, bool) {
+	formats := []string{
+		"2006-01-02 15:04:05.999999999 -0700 MST", // time.Time as written by the driver
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05", // Simple
+		time.RFC3339,
+	}
+	for _, layout := range formats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
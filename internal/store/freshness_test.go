@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetFreshness(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	userID := int64(1)
+
+	fixedNow := time.Date(2025, 6, 30, 8, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	// Nothing logged yet
+	freshness, err := s.GetFreshness(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetFreshness failed: %v", err)
+	}
+	if len(freshness) != 3 {
+		t.Fatalf("Expected bp, weight and sleep entries, got %+v", freshness)
+	}
+	for _, f := range freshness {
+		if f.LastAt != nil || f.DaysSince != nil {
+			t.Errorf("Expected %s never logged, got %+v", f.Metric, f)
+		}
+	}
+
+	// BP from this morning and a week earlier: the latest counts
+	for _, at := range []time.Time{fixedNow.AddDate(0, 0, -7), fixedNow.Add(-time.Hour)} {
+		bp := BloodPressure{UserID: userID, MeasuredAt: at, Systolic: 120, Diastolic: 80}
+		if _, err := s.CreateBloodPressureReading(ctx, &bp); err != nil {
+			t.Fatalf("CreateBloodPressureReading failed: %v", err)
+		}
+	}
+	// Stale weight: nine days ago
+	weight := WeightLog{UserID: userID, MeasuredAt: time.Date(2025, 6, 21, 22, 0, 0, 0, time.UTC), Weight: 80}
+	if _, err := s.CreateWeightLog(ctx, &weight); err != nil {
+		t.Fatalf("CreateWeightLog failed: %v", err)
+	}
+	// Last night's sleep ended this morning
+	sleep := []SleepLog{{StartTime: time.Date(2025, 6, 29, 23, 0, 0, 0, time.UTC), EndTime: time.Date(2025, 6, 30, 6, 30, 0, 0, time.UTC), Day: "2025-06-30"}}
+	if _, _, err := s.ImportSleepLogs(ctx, userID, sleep); err != nil {
+		t.Fatalf("ImportSleepLogs failed: %v", err)
+	}
+
+	// One medication taken yesterday evening, one never taken
+	medID, _ := s.CreateMedication("Lisinopril", "10mg", `{"type":"daily","times":["20:00"]}`, nil, nil, "", "")
+	s.CreateMedication("Aspirin", "100mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	intakeID, _ := s.CreateIntake(medID, userID, time.Date(2025, 6, 29, 20, 0, 0, 0, time.UTC))
+	if err := s.ConfirmIntake(intakeID, time.Date(2025, 6, 29, 20, 5, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("ConfirmIntake failed: %v", err)
+	}
+
+	freshness, err = s.GetFreshness(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetFreshness failed: %v", err)
+	}
+	if len(freshness) != 5 {
+		t.Fatalf("Expected 5 entries, got %+v", freshness)
+	}
+
+	check := func(f MetricFreshness, metric string, wantLast time.Time, wantDays int) {
+		t.Helper()
+		if f.Metric != metric {
+			t.Fatalf("Expected metric %s, got %s", metric, f.Metric)
+		}
+		if f.LastAt == nil || !f.LastAt.Equal(wantLast) {
+			t.Errorf("%s %s: expected last at %v, got %v", metric, f.Name, wantLast, f.LastAt)
+		}
+		if f.DaysSince == nil || *f.DaysSince != wantDays {
+			t.Errorf("%s %s: expected %d days since, got %v", metric, f.Name, wantDays, f.DaysSince)
+		}
+	}
+	check(freshness[0], FreshnessMetricBP, fixedNow.Add(-time.Hour), 0)
+	check(freshness[1], FreshnessMetricWeight, weight.MeasuredAt, 9)
+	check(freshness[2], FreshnessMetricSleep, sleep[0].EndTime, 0)
+
+	// Medications are listed by name
+	if freshness[3].Name != "Aspirin" || freshness[3].LastAt != nil || freshness[3].DaysSince != nil {
+		t.Errorf("Expected Aspirin never taken, got %+v", freshness[3])
+	}
+	check(freshness[4], FreshnessMetricMedication, time.Date(2025, 6, 29, 20, 5, 0, 0, time.UTC), 1)
+	if freshness[4].MedicationID == nil || *freshness[4].MedicationID != medID {
+		t.Errorf("Expected medication ID %d, got %v", medID, freshness[4].MedicationID)
+	}
+}
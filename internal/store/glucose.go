@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Glucose units. Values are stored in mg/dL and converted for display.
+const (
+	GlucoseUnitMgdl = "mg/dL"
+	GlucoseUnitMmol = "mmol/L"
+)
+
+// MgdlPerMmol converts glucose between mmol/L and mg/dL
+const MgdlPerMmol = 18.0
+
+// Meal contexts of a glucose reading
+const (
+	MealContextFasting    = "fasting"
+	MealContextBeforeMeal = "before_meal"
+	MealContextAfterMeal  = "after_meal"
+	MealContextBedtime    = "bedtime"
+	MealContextRandom     = "random"
+)
+
+// MealContexts lists every supported meal context
+var MealContexts = []string{MealContextFasting, MealContextBeforeMeal, MealContextAfterMeal, MealContextBedtime, MealContextRandom}
+
+// Target range used for time-in-range style stats, in mg/dL
+const (
+	GlucoseRangeLowMgdl  = 70
+	GlucoseRangeHighMgdl = 180
+)
+
+type GlucoseLog struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"user_id"`
+	MeasuredAt  time.Time `json:"measured_at"`
+	ValueMgdl   float64   `json:"value_mgdl"`
+	MealContext string    `json:"meal_context,omitempty"`
+	Notes       string    `json:"notes,omitempty"`
+}
+
+// GlucoseStats summarizes readings since a point in time. Values are in mg/dL.
+type GlucoseStats struct {
+	Count      int      `json:"count"`
+	Average    float64  `json:"average"`
+	Min        float64  `json:"min"`
+	Max        float64  `json:"max"`
+	InRangePct float64  `json:"in_range_pct"`
+	BelowPct   float64  `json:"below_pct"`
+	AbovePct   float64  `json:"above_pct"`
+	FastingAvg *float64 `json:"fasting_average,omitempty"`
+}
+
+// ParseGlucoseUnit accepts common spellings ("mgdl", "mg/dl", "mmol", "mmol/l")
+func ParseGlucoseUnit(unit string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "mg/dl", "mgdl", "mg":
+		return GlucoseUnitMgdl, nil
+	case "mmol/l", "mmol", "mmoll":
+		return GlucoseUnitMmol, nil
+	}
+	return "", fmt.Errorf("unknown glucose unit %q, expected mg/dL or mmol/L", unit)
+}
+
+// ValidMealContext reports whether c is empty or a known meal context
+func ValidMealContext(c string) bool {
+	if c == "" {
+		return true
+	}
+	for _, mc := range MealContexts {
+		if c == mc {
+			return true
+		}
+	}
+	return false
+}
+
+// GlucoseToMgdl converts a value in the given unit to mg/dL
+func GlucoseToMgdl(value float64, unit string) float64 {
+	if unit == GlucoseUnitMmol {
+		return value * MgdlPerMmol
+	}
+	return value
+}
+
+// GlucoseFromMgdl converts a stored mg/dL value to the given unit, rounded for display
+func GlucoseFromMgdl(mgdl float64, unit string) float64 {
+	if unit == GlucoseUnitMmol {
+		return math.Round(mgdl/MgdlPerMmol*10) / 10
+	}
+	return math.Round(mgdl)
+}
+
+// GetGlucoseUnit returns the preferred display unit (mg/dL unless set)
+func (s *Store) GetGlucoseUnit() (string, error) {
+	var unit sql.NullString
+	err := s.db.QueryRow("SELECT glucose_unit FROM settings WHERE id = 1").Scan(&unit)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	if !unit.Valid || unit.String == "" {
+		return GlucoseUnitMgdl, nil
+	}
+	return unit.String, nil
+}
+
+// SetGlucoseUnit stores the preferred display unit
+func (s *Store) SetGlucoseUnit(unit string) error {
+	parsed, err := ParseGlucoseUnit(unit)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("UPDATE settings SET glucose_unit = ? WHERE id = 1", parsed)
+	return err
+}
+
+func (s *Store) CreateGlucoseLog(ctx context.Context, g *GlucoseLog) (int64, error) {
+	if g.ValueMgdl <= 0 {
+		return 0, fmt.Errorf("glucose value must be positive")
+	}
+	if !ValidMealContext(g.MealContext) {
+		return 0, fmt.Errorf("invalid meal context %q", g.MealContext)
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO glucose_logs (user_id, measured_at, value_mgdl, meal_context, notes) VALUES (?, ?, ?, ?, ?)",
+		g.UserID, g.MeasuredAt, g.ValueMgdl, g.MealContext, g.Notes)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetGlucoseLogs returns the user's readings since the given time, newest first
+func (s *Store) GetGlucoseLogs(ctx context.Context, userID int64, since time.Time) ([]GlucoseLog, error) {
+	query := "SELECT id, user_id, measured_at, value_mgdl, meal_context, notes FROM glucose_logs WHERE user_id = ?"
+	args := []interface{}{userID}
+
+	if !since.IsZero() {
+		query += " AND measured_at >= ?"
+		args = append(args, since)
+	}
+	query += " ORDER BY measured_at DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []GlucoseLog
+	for rows.Next() {
+		var g GlucoseLog
+		var mealContext, notes sql.NullString
+		if err := rows.Scan(&g.ID, &g.UserID, &g.MeasuredAt, &g.ValueMgdl, &mealContext, &notes); err != nil {
+			return nil, err
+		}
+		if mealContext.Valid {
+			g.MealContext = mealContext.String
+		}
+		if notes.Valid {
+			g.Notes = notes.String
+		}
+		logs = append(logs, g)
+	}
+	return logs, rows.Err()
+}
+
+func (s *Store) DeleteGlucoseLog(ctx context.Context, id, userID int64) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM glucose_logs WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetGlucoseStats computes averages and the share of readings below, within and above
+// the 70-180 mg/dL target range
+func (s *Store) GetGlucoseStats(ctx context.Context, userID int64, since time.Time) (*GlucoseStats, error) {
+	logs, err := s.GetGlucoseLogs(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &GlucoseStats{Count: len(logs)}
+	if len(logs) == 0 {
+		return stats, nil
+	}
+
+	var sum, fastingSum float64
+	var below, above, fasting int
+	stats.Min, stats.Max = logs[0].ValueMgdl, logs[0].ValueMgdl
+	for _, g := range logs {
+		sum += g.ValueMgdl
+		stats.Min = math.Min(stats.Min, g.ValueMgdl)
+		stats.Max = math.Max(stats.Max, g.ValueMgdl)
+		switch {
+		case g.ValueMgdl < GlucoseRangeLowMgdl:
+			below++
+		case g.ValueMgdl > GlucoseRangeHighMgdl:
+			above++
+		}
+		if g.MealContext == MealContextFasting {
+			fastingSum += g.ValueMgdl
+			fasting++
+		}
+	}
+
+	n := float64(len(logs))
+	stats.Average = math.Round(sum/n*10) / 10
+	stats.BelowPct = percentage(float64(below), n)
+	stats.AbovePct = percentage(float64(above), n)
+	stats.InRangePct = percentage(n-float64(below+above), n)
+	if fasting > 0 {
+		avg := math.Round(fastingSum/float64(fasting)*10) / 10
+		stats.FastingAvg = &avg
+	}
+	return stats, nil
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestGlucoseUnitConversion(t *testing.T) {
+	tests := []struct {
+		value float64
+		unit  string
+		mgdl  float64
+	}{
+		{100, GlucoseUnitMgdl, 100},
+		{5.5, GlucoseUnitMmol, 99},
+		{10, GlucoseUnitMmol, 180},
+	}
+	for _, tt := range tests {
+		mgdl := GlucoseToMgdl(tt.value, tt.unit)
+		if mgdl != tt.mgdl {
+			t.Errorf("GlucoseToMgdl(%v, %s) = %v, want %v", tt.value, tt.unit, mgdl, tt.mgdl)
+		}
+		if back := GlucoseFromMgdl(mgdl, tt.unit); back != tt.value {
+			t.Errorf("GlucoseFromMgdl(%v, %s) = %v, want %v", mgdl, tt.unit, back, tt.value)
+		}
+	}
+
+	if got := GlucoseFromMgdl(105, GlucoseUnitMmol); got != 5.8 {
+		t.Errorf("Expected 105 mg/dL to show as 5.8 mmol/L, got %v", got)
+	}
+
+	for in, want := range map[string]string{"mgdl": GlucoseUnitMgdl, "MG/DL": GlucoseUnitMgdl, "mmol": GlucoseUnitMmol, "mmol/L": GlucoseUnitMmol} {
+		if got, err := ParseGlucoseUnit(in); err != nil || got != want {
+			t.Errorf("ParseGlucoseUnit(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseGlucoseUnit("kg"); err == nil {
+		t.Error("Expected error for unknown unit")
+	}
+}
+
+func TestGlucoseLogCRUD(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	userID := int64(123456)
+	now := time.Now()
+
+	unit, err := s.GetGlucoseUnit()
+	if err != nil || unit != GlucoseUnitMgdl {
+		t.Fatalf("Expected default unit mg/dL, got %q (%v)", unit, err)
+	}
+	if err := s.SetGlucoseUnit("mmol"); err != nil {
+		t.Fatalf("SetGlucoseUnit failed: %v", err)
+	}
+	if unit, _ := s.GetGlucoseUnit(); unit != GlucoseUnitMmol {
+		t.Errorf("Expected mmol/L after update, got %q", unit)
+	}
+	if err := s.SetGlucoseUnit("kg"); err == nil {
+		t.Error("Expected error for invalid unit")
+	}
+
+	readings := []GlucoseLog{
+		{UserID: userID, MeasuredAt: now.Add(-3 * time.Hour), ValueMgdl: 90, MealContext: MealContextFasting},
+		{UserID: userID, MeasuredAt: now.Add(-2 * time.Hour), ValueMgdl: 200, MealContext: MealContextAfterMeal, Notes: "pizza"},
+		{UserID: userID, MeasuredAt: now.Add(-1 * time.Hour), ValueMgdl: 60},
+		{UserID: userID, MeasuredAt: now.AddDate(0, 0, -40), ValueMgdl: 110}, // Outside the window
+		{UserID: 999, MeasuredAt: now, ValueMgdl: 150},                       // Other user
+	}
+	var ids []int64
+	for i := range readings {
+		id, err := s.CreateGlucoseLog(ctx, &readings[i])
+		if err != nil {
+			t.Fatalf("CreateGlucoseLog failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if _, err := s.CreateGlucoseLog(ctx, &GlucoseLog{UserID: userID, MeasuredAt: now, ValueMgdl: 100, MealContext: "brunch"}); err == nil {
+		t.Error("Expected error for invalid meal context")
+	}
+	if _, err := s.CreateGlucoseLog(ctx, &GlucoseLog{UserID: userID, MeasuredAt: now, ValueMgdl: 0}); err == nil {
+		t.Error("Expected error for zero value")
+	}
+
+	since := now.AddDate(0, 0, -30)
+	logs, err := s.GetGlucoseLogs(ctx, userID, since)
+	if err != nil {
+		t.Fatalf("GetGlucoseLogs failed: %v", err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("Expected 3 readings, got %d", len(logs))
+	}
+	if logs[0].ValueMgdl != 60 || logs[1].Notes != "pizza" || logs[2].MealContext != MealContextFasting {
+		t.Errorf("Unexpected readings or order: %+v", logs)
+	}
+
+	stats, err := s.GetGlucoseStats(ctx, userID, since)
+	if err != nil {
+		t.Fatalf("GetGlucoseStats failed: %v", err)
+	}
+	if stats.Count != 3 || stats.Min != 60 || stats.Max != 200 || stats.Average != 116.7 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.InRangePct != 33.3 || stats.BelowPct != 33.3 || stats.AbovePct != 33.3 {
+		t.Errorf("Unexpected range split: %+v", stats)
+	}
+	if stats.FastingAvg == nil || *stats.FastingAvg != 90 {
+		t.Errorf("Expected fasting average 90, got %v", stats.FastingAvg)
+	}
+
+	if err := s.DeleteGlucoseLog(ctx, ids[0], userID); err != nil {
+		t.Fatalf("DeleteGlucoseLog failed: %v", err)
+	}
+	if err := s.DeleteGlucoseLog(ctx, ids[4], userID); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows deleting another user's reading, got %v", err)
+	}
+	logs, _ = s.GetGlucoseLogs(ctx, userID, time.Time{})
+	if len(logs) != 3 {
+		t.Errorf("Expected 3 readings after delete, got %d", len(logs))
+	}
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// InjectionLogEntry is the site used for one injection
+type InjectionLogEntry struct {
+	ID           int64     `json:"id"`
+	MedicationID int64     `json:"medication_id"`
+	IntakeID     *int64    `json:"intake_id,omitempty"`
+	Site         string    `json:"site"`
+	InjectedAt   time.Time `json:"injected_at"`
+}
+
+// GetInjectionSites returns the medication's injection sites in rotation order; empty
+// for medications that aren't injected
+func (s *Store) GetInjectionSites(medID int64) ([]string, error) {
+	rows, err := s.db.Query("SELECT site FROM medication_injection_sites WHERE medication_id = ? ORDER BY position", medID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sites := []string{}
+	for rows.Next() {
+		var site string
+		if err := rows.Scan(&site); err != nil {
+			return nil, err
+		}
+		sites = append(sites, site)
+	}
+	return sites, rows.Err()
+}
+
+// SetInjectionSites replaces the medication's site rotation. Sites are trimmed and must be
+// unique; an empty list stops tracking sites. The injection log is kept.
+func (s *Store) SetInjectionSites(medID int64, sites []string) error {
+	seen := make(map[string]bool, len(sites))
+	cleaned := make([]string, 0, len(sites))
+	for _, site := range sites {
+		site = strings.TrimSpace(site)
+		if site == "" {
+			return fmt.Errorf("injection site must not be empty")
+		}
+		if seen[strings.ToLower(site)] {
+			return fmt.Errorf("duplicate injection site %q", site)
+		}
+		seen[strings.ToLower(site)] = true
+		cleaned = append(cleaned, site)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM medication_injection_sites WHERE medication_id = ?", medID); err != nil {
+		return err
+	}
+	for i, site := range cleaned {
+		if _, err := tx.Exec("INSERT INTO medication_injection_sites (medication_id, position, site) VALUES (?, ?, ?)", medID, i, site); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// NextInjectionSite suggests the site after the last one used in the rotation, starting
+// over with the first site (also when the last one has since been removed). Returns ""
+// for medications without sites.
+func (s *Store) NextInjectionSite(medID int64) (string, error) {
+	sites, err := s.GetInjectionSites(medID)
+	if err != nil || len(sites) == 0 {
+		return "", err
+	}
+
+	var last string
+	err = s.db.QueryRow("SELECT site FROM injection_log WHERE medication_id = ? ORDER BY injected_at DESC, id DESC LIMIT 1", medID).Scan(&last)
+	if err == sql.ErrNoRows {
+		return sites[0], nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for i, site := range sites {
+		if strings.EqualFold(site, last) {
+			return sites[(i+1)%len(sites)], nil
+		}
+	}
+	return sites[0], nil
+}
+
+// RecordInjection stores the site used for an intake, replacing the one recorded for it
+// before (e.g. the suggested site, corrected by the user)
+func (s *Store) RecordInjection(intakeID int64, site string, injectedAt time.Time) error {
+	site = strings.TrimSpace(site)
+	if site == "" {
+		return fmt.Errorf("injection site must not be empty")
+	}
+	intake, err := s.GetIntake(intakeID)
+	if err != nil {
+		return err
+	}
+	if intake == nil {
+		return fmt.Errorf("intake %d not found", intakeID)
+	}
+
+	_, err = s.db.Exec(`INSERT INTO injection_log (medication_id, user_id, intake_id, site, injected_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(intake_id) DO UPDATE SET site = excluded.site, injected_at = excluded.injected_at`,
+		intake.MedicationID, intake.UserID, intakeID, site, injectedAt)
+	return err
+}
+
+// recordRotationInjection logs the suggested site for a newly confirmed intake of a
+// medication with a site rotation, unless a site was already recorded for it
+func (s *Store) recordRotationInjection(intakeID int64, injectedAt time.Time) error {
+	var medID int64
+	var logged int
+	err := s.db.QueryRow(`SELECT medication_id, (SELECT COUNT(*) FROM injection_log WHERE intake_id = intake_log.id)
+		FROM intake_log WHERE id = ?`, intakeID).Scan(&medID, &logged)
+	if err == sql.ErrNoRows || logged > 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	site, err := s.NextInjectionSite(medID)
+	if err != nil || site == "" {
+		return err
+	}
+	return s.RecordInjection(intakeID, site, injectedAt)
+}
+
+// GetInjectionLog returns the medication's most recent injections, newest first
+func (s *Store) GetInjectionLog(medID int64, limit int) ([]InjectionLogEntry, error) {
+	rows, err := s.db.Query(`SELECT id, medication_id, intake_id, site, injected_at FROM injection_log
+		WHERE medication_id = ? ORDER BY injected_at DESC, id DESC LIMIT ?`, medID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []InjectionLogEntry{}
+	for rows.Next() {
+		var e InjectionLogEntry
+		var intakeID sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.MedicationID, &intakeID, &e.Site, &e.InjectedAt); err != nil {
+			return nil, err
+		}
+		if intakeID.Valid {
+			e.IntakeID = &intakeID.Int64
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
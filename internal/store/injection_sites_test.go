@@ -0,0 +1,94 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInjectionSiteRotation(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	userID := int64(1)
+	medID, _ := s.CreateMedication("Semaglutide", "0.5mg", `{"type":"weekly","days":[1],"times":["09:00"]}`, nil, nil, "", "")
+	pillID, _ := s.CreateMedication("Vitamin D", "1000IU", `{"type":"daily","times":["09:00"]}`, nil, nil, "", "")
+
+	if next, err := s.NextInjectionSite(medID); err != nil || next != "" {
+		t.Fatalf("Expected no suggestion without sites, got %q (%v)", next, err)
+	}
+	if err := s.SetInjectionSites(medID, []string{"Left abdomen", "left abdomen"}); err == nil {
+		t.Error("Expected an error for duplicate sites")
+	}
+	if err := s.SetInjectionSites(medID, []string{" Left abdomen ", "Right abdomen", "Left thigh"}); err != nil {
+		t.Fatalf("SetInjectionSites failed: %v", err)
+	}
+	if sites, _ := s.GetInjectionSites(medID); len(sites) != 3 || sites[0] != "Left abdomen" {
+		t.Fatalf("Expected 3 trimmed sites in order, got %q", sites)
+	}
+
+	start := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	confirm := func(week int) int64 {
+		t.Helper()
+		at := start.AddDate(0, 0, 7*week)
+		id, err := s.CreateIntake(medID, userID, at)
+		if err != nil {
+			t.Fatalf("CreateIntake failed: %v", err)
+		}
+		if err := s.ConfirmIntake(id, at.Add(5*time.Minute)); err != nil {
+			t.Fatalf("ConfirmIntake failed: %v", err)
+		}
+		return id
+	}
+
+	// Successive injections walk the rotation and wrap around
+	for week, want := range []string{"Left abdomen", "Right abdomen", "Left thigh", "Left abdomen"} {
+		if next, _ := s.NextInjectionSite(medID); next != want {
+			t.Fatalf("Week %d: expected suggestion %q, got %q", week, want, next)
+		}
+		confirm(week)
+	}
+	if next, _ := s.NextInjectionSite(medID); next != "Right abdomen" {
+		t.Errorf("Expected Right abdomen after wrapping, got %q", next)
+	}
+
+	// The user used another site than suggested: the rotation continues from it
+	id := confirm(4)
+	if err := s.RecordInjection(id, "Left thigh", start.AddDate(0, 0, 28)); err != nil {
+		t.Fatalf("RecordInjection failed: %v", err)
+	}
+	log, err := s.GetInjectionLog(medID, 10)
+	if err != nil {
+		t.Fatalf("GetInjectionLog failed: %v", err)
+	}
+	if len(log) != 5 || log[0].Site != "Left thigh" || log[0].IntakeID == nil || *log[0].IntakeID != id {
+		t.Fatalf("Expected the override to replace the suggested site, got %+v", log)
+	}
+	if next, _ := s.NextInjectionSite(medID); next != "Left abdomen" {
+		t.Errorf("Expected Left abdomen after Left thigh, got %q", next)
+	}
+
+	// Batch confirmation logs sites too; other medications log nothing
+	at := start.AddDate(0, 0, 35)
+	s.CreateIntake(medID, userID, at)
+	s.CreateIntake(pillID, userID, at)
+	if err := s.ConfirmIntakesBySchedule(userID, at, at); err != nil {
+		t.Fatalf("ConfirmIntakesBySchedule failed: %v", err)
+	}
+	if log, _ := s.GetInjectionLog(medID, 1); len(log) != 1 || log[0].Site != "Left abdomen" {
+		t.Errorf("Expected batch confirmation to log Left abdomen, got %+v", log)
+	}
+	if log, _ := s.GetInjectionLog(pillID, 10); len(log) != 0 {
+		t.Errorf("Expected no injections for a medication without sites, got %+v", log)
+	}
+
+	// Clearing the rotation stops suggestions
+	if err := s.SetInjectionSites(medID, nil); err != nil {
+		t.Fatalf("SetInjectionSites failed: %v", err)
+	}
+	if next, _ := s.NextInjectionSite(medID); next != "" {
+		t.Errorf("Expected no suggestion after clearing sites, got %q", next)
+	}
+}
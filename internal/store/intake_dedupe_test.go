@@ -0,0 +1,54 @@
+package store
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestUniqueIntakeMigration_MergesDuplicates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	goose.SetDialect("sqlite3")
+	goose.SetBaseFS(embedMigrations)
+	if err := goose.UpTo(db, "migrations", 20); err != nil {
+		t.Fatalf("Failed to migrate to 020: %v", err)
+	}
+
+	at := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+	db.Exec("INSERT INTO medications (id, name, dosage, schedule) VALUES (1, 'Aspirin', '100mg', '{}')")
+	// A pending duplicate with the reminder and the note, and the confirmed dose
+	db.Exec("INSERT INTO intake_log (id, medication_id, user_id, scheduled_at, status, notes) VALUES (1, 1, 1, ?, 'PENDING', 'with food')", at)
+	db.Exec("INSERT INTO intake_log (id, medication_id, user_id, scheduled_at, status, taken_at) VALUES (2, 1, 1, ?, 'TAKEN', ?)", at, at)
+	db.Exec("INSERT INTO intake_log (id, medication_id, user_id, scheduled_at, status) VALUES (3, 1, 1, ?, 'PENDING')", at.Add(12*time.Hour))
+	db.Exec("INSERT INTO intake_reminders (intake_id, message_id) VALUES (1, 42)")
+	db.Close()
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	defer s.Close()
+
+	var count int
+	s.db.QueryRow("SELECT COUNT(*) FROM intake_log").Scan(&count)
+	if count != 2 {
+		t.Errorf("Expected the duplicate to be merged away, got %d intakes", count)
+	}
+	kept, err := s.GetIntake(2)
+	if err != nil || kept == nil || kept.Status != "TAKEN" || kept.Notes != "with food" {
+		t.Errorf("Expected the taken intake to keep the duplicate's note, got %+v (%v)", kept, err)
+	}
+	if reminders, _ := s.GetIntakeReminders(2); len(reminders) != 1 || reminders[0] != 42 {
+		t.Errorf("Expected the reminder to move to the kept intake, got %v", reminders)
+	}
+	if _, err := s.CreateIntake(1, 1, at); err == nil {
+		t.Error("Expected the unique index to reject a new duplicate")
+	}
+}
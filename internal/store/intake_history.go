@@ -0,0 +1,74 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Intake statuses
+const (
+	IntakeStatusPending = "PENDING"
+	IntakeStatusTaken   = "TAKEN"
+	IntakeStatusMissed  = "MISSED"
+)
+
+// IntakeHistoryFilter narrows a medication's intake history. Zero values don't filter;
+// a Limit of 0 returns every matching intake.
+type IntakeHistoryFilter struct {
+	From   time.Time // Inclusive
+	To     time.Time // Exclusive
+	Status string
+	Limit  int
+	Offset int
+}
+
+// GetMedicationIntakeHistory returns one page of a medication's intakes, newest first,
+// along with the number of intakes matching the filter across all pages
+func (s *Store) GetMedicationIntakeHistory(medID int64, f IntakeHistoryFilter) ([]IntakeLog, int, error) {
+	where := " WHERE medication_id = ?"
+	args := []interface{}{medID}
+
+	if !f.From.IsZero() {
+		where += " AND scheduled_at >= ?"
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		where += " AND scheduled_at < ?"
+		args = append(args, f.To)
+	}
+	if f.Status != "" {
+		where += " AND status = ?"
+		args = append(args, f.Status)
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM intake_log"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT id, medication_id, user_id, scheduled_at, taken_at, status, notes, quantity, substituted_with FROM intake_log" +
+		where + " ORDER BY scheduled_at DESC, id DESC"
+	if f.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, f.Limit, f.Offset)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	logs := []IntakeLog{}
+	for rows.Next() {
+		var l IntakeLog
+		var notes, substitutedWith sql.NullString
+		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.TakenAt, &l.Status, &notes, &l.Quantity, &substitutedWith); err != nil {
+			return nil, 0, err
+		}
+		l.Notes = notes.String
+		l.SubstitutedWith = substitutedWith.String
+		logs = append(logs, l)
+	}
+	return logs, total, rows.Err()
+}
@@ -0,0 +1,15 @@
+package store
+
+// MarkInteractionAlerted records an alert for the interaction between two RxCUIs, in
+// either order. Returns false if the pair was alerted about before.
+func (s *Store) MarkInteractionAlerted(rxcui1, rxcui2 string) (bool, error) {
+	if rxcui2 < rxcui1 {
+		rxcui1, rxcui2 = rxcui2, rxcui1
+	}
+	res, err := s.db.Exec("INSERT OR IGNORE INTO interaction_alerts (rxcui1, rxcui2) VALUES (?, ?)", rxcui1, rxcui2)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
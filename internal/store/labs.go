@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LabResult is a single lab value such as total cholesterol or HbA1c
+type LabResult struct {
+	ID            int64     `json:"id"`
+	UserID        int64     `json:"user_id"`
+	Name          string    `json:"name"`
+	Value         float64   `json:"value"`
+	Unit          string    `json:"unit,omitempty"`
+	MeasuredAt    time.Time `json:"measured_at"`
+	ReferenceLow  *float64  `json:"reference_low,omitempty"`
+	ReferenceHigh *float64  `json:"reference_high,omitempty"`
+	Notes         string    `json:"notes,omitempty"`
+}
+
+// Validate checks that the result is named and its reference range is ordered
+func (l *LabResult) Validate() error {
+	if strings.TrimSpace(l.Name) == "" {
+		return fmt.Errorf("name required")
+	}
+	if l.ReferenceLow != nil && l.ReferenceHigh != nil && *l.ReferenceLow > *l.ReferenceHigh {
+		return fmt.Errorf("reference_low must not be above reference_high")
+	}
+	return nil
+}
+
+func (s *Store) CreateLabResult(ctx context.Context, l *LabResult) (int64, error) {
+	if err := l.Validate(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO lab_results (user_id, name, value, unit, measured_at, reference_low, reference_high, notes) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		l.UserID, l.Name, l.Value, l.Unit, l.MeasuredAt, l.ReferenceLow, l.ReferenceHigh, l.Notes)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateLabResult replaces the values of an existing result. Returns sql.ErrNoRows if
+// the result doesn't exist or belongs to another user.
+func (s *Store) UpdateLabResult(ctx context.Context, l *LabResult) error {
+	if err := l.Validate(); err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE lab_results SET name = ?, value = ?, unit = ?, measured_at = ?, reference_low = ?, reference_high = ?, notes = ? WHERE id = ? AND user_id = ?",
+		l.Name, l.Value, l.Unit, l.MeasuredAt, l.ReferenceLow, l.ReferenceHigh, l.Notes, l.ID, l.UserID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetLabResults returns the user's results measured in [from, to), newest first,
+// optionally limited to one test name (case-insensitive). A zero from or to leaves
+// that side open.
+func (s *Store) GetLabResults(ctx context.Context, userID int64, name string, from, to time.Time) ([]LabResult, error) {
+	query := "SELECT id, user_id, name, value, unit, measured_at, reference_low, reference_high, notes FROM lab_results WHERE user_id = ?"
+	args := []interface{}{userID}
+
+	if name != "" {
+		query += " AND name = ? COLLATE NOCASE"
+		args = append(args, name)
+	}
+	if !from.IsZero() {
+		query += " AND measured_at >= ?"
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		query += " AND measured_at < ?"
+		args = append(args, to)
+	}
+	query += " ORDER BY measured_at DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []LabResult
+	for rows.Next() {
+		var l LabResult
+		var unit, notes sql.NullString
+		var low, high sql.NullFloat64
+		if err := rows.Scan(&l.ID, &l.UserID, &l.Name, &l.Value, &unit, &l.MeasuredAt, &low, &high, &notes); err != nil {
+			return nil, err
+		}
+		if unit.Valid {
+			l.Unit = unit.String
+		}
+		if low.Valid {
+			l.ReferenceLow = &low.Float64
+		}
+		if high.Valid {
+			l.ReferenceHigh = &high.Float64
+		}
+		if notes.Valid {
+			l.Notes = notes.String
+		}
+		results = append(results, l)
+	}
+	return results, rows.Err()
+}
+
+func (s *Store) DeleteLabResult(ctx context.Context, id, userID int64) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM lab_results WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestLabResults(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	userID := int64(123456)
+	month := func(m time.Month) time.Time {
+		return time.Date(2024, m, 10, 8, 0, 0, 0, time.UTC)
+	}
+	ref := func(v float64) *float64 { return &v }
+
+	results := []LabResult{
+		{UserID: userID, Name: "LDL", Value: 160, Unit: "mg/dL", MeasuredAt: month(1), ReferenceHigh: ref(100)},
+		{UserID: userID, Name: "HbA1c", Value: 6.1, Unit: "%", MeasuredAt: month(2), ReferenceLow: ref(4), ReferenceHigh: ref(5.6)},
+		{UserID: userID, Name: "LDL", Value: 120, Unit: "mg/dL", MeasuredAt: month(4), Notes: "after 3 months on statin"},
+		{UserID: userID, Name: "LDL", Value: 95, Unit: "mg/dL", MeasuredAt: month(7)},
+	}
+	for i := range results {
+		id, err := s.CreateLabResult(ctx, &results[i])
+		if err != nil {
+			t.Fatalf("CreateLabResult failed: %v", err)
+		}
+		results[i].ID = id
+	}
+
+	invalid := []LabResult{
+		{UserID: userID, Name: " ", Value: 1, MeasuredAt: month(1)},
+		{UserID: userID, Name: "TSH", Value: 1, MeasuredAt: month(1), ReferenceLow: ref(4), ReferenceHigh: ref(0.4)},
+	}
+	for _, l := range invalid {
+		if _, err := s.CreateLabResult(ctx, &l); err == nil {
+			t.Errorf("Expected validation error for %+v", l)
+		}
+	}
+
+	// February through June only
+	labs, err := s.GetLabResults(ctx, userID, "", month(2), month(6))
+	if err != nil {
+		t.Fatalf("GetLabResults failed: %v", err)
+	}
+	if len(labs) != 2 {
+		t.Fatalf("Expected 2 results in range, got %d", len(labs))
+	}
+	if labs[0].Value != 120 || labs[0].Notes != "after 3 months on statin" || labs[0].ReferenceLow != nil {
+		t.Errorf("Unexpected newest result: %+v", labs[0])
+	}
+	if labs[1].Name != "HbA1c" || labs[1].ReferenceLow == nil || *labs[1].ReferenceLow != 4 || *labs[1].ReferenceHigh != 5.6 {
+		t.Errorf("Unexpected second result: %+v", labs[1])
+	}
+
+	// Name filter ignores case
+	labs, _ = s.GetLabResults(ctx, userID, "ldl", time.Time{}, time.Time{})
+	if len(labs) != 3 {
+		t.Fatalf("Expected 3 LDL results, got %d", len(labs))
+	}
+
+	// Update and delete
+	results[0].Value = 165
+	if err := s.UpdateLabResult(ctx, &results[0]); err != nil {
+		t.Fatalf("UpdateLabResult failed: %v", err)
+	}
+	other := results[1]
+	other.UserID = 999
+	if err := s.UpdateLabResult(ctx, &other); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows updating another user's result, got %v", err)
+	}
+	if err := s.DeleteLabResult(ctx, results[3].ID, userID); err != nil {
+		t.Fatalf("DeleteLabResult failed: %v", err)
+	}
+	if err := s.DeleteLabResult(ctx, results[3].ID, userID); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows on second delete, got %v", err)
+	}
+
+	labs, _ = s.GetLabResults(ctx, userID, "", time.Time{}, time.Time{})
+	if len(labs) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(labs))
+	}
+	if labs[2].Value != 165 {
+		t.Errorf("Expected updated value 165, got %v", labs[2].Value)
+	}
+}
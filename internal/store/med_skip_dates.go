@@ -0,0 +1,20 @@
+package store
+
+import "time"
+
+// SkipMedicationOn silences a medication's reminders on the calendar day of t.
+// Skipping a day twice is a no-op.
+func (s *Store) SkipMedicationOn(medID int64, t time.Time) error {
+	_, err := s.db.Exec("INSERT OR IGNORE INTO medication_skip_dates (medication_id, skip_date) VALUES (?, ?)",
+		medID, t.Format("2006-01-02"))
+	return err
+}
+
+// IsMedicationSkipped reports whether the medication's reminders are silenced on the
+// calendar day of t
+func (s *Store) IsMedicationSkipped(medID int64, t time.Time) (bool, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM medication_skip_dates WHERE medication_id = ? AND skip_date = ?",
+		medID, t.Format("2006-01-02")).Scan(&n)
+	return n > 0, err
+}
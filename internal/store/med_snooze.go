@@ -0,0 +1,106 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMedSnoozeMinutes are the snooze buttons shown on medication reminders when none are configured
+var DefaultMedSnoozeMinutes = []int{15, 30, 60}
+
+const (
+	maxMedSnoozePresets = 4
+	maxMedSnoozeMinutes = 12 * 60
+)
+
+// GetMedSnoozeMinutes returns the configured snooze durations in minutes, shortest first
+func (s *Store) GetMedSnoozeMinutes() ([]int, error) {
+	var value sql.NullString
+	err := s.db.QueryRow("SELECT med_snooze_minutes FROM settings WHERE id = 1").Scan(&value)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if !value.Valid || value.String == "" {
+		return append([]int{}, DefaultMedSnoozeMinutes...), nil
+	}
+
+	var minutes []int
+	for _, part := range strings.Split(value.String, ",") {
+		m, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stored snooze duration %q", part)
+		}
+		minutes = append(minutes, m)
+	}
+	return minutes, nil
+}
+
+// SetMedSnoozeMinutes stores up to four snooze durations (1 minute to 12 hours),
+// sorted and without duplicates. An empty list restores the default.
+func (s *Store) SetMedSnoozeMinutes(minutes []int) error {
+	var value interface{}
+	if len(minutes) > 0 {
+		seen := make(map[int]bool, len(minutes))
+		var unique []int
+		for _, m := range minutes {
+			if m < 1 || m > maxMedSnoozeMinutes {
+				return fmt.Errorf("snooze durations must be between 1 and %d minutes", maxMedSnoozeMinutes)
+			}
+			if !seen[m] {
+				seen[m] = true
+				unique = append(unique, m)
+			}
+		}
+		if len(unique) > maxMedSnoozePresets {
+			return fmt.Errorf("at most %d snooze durations are allowed", maxMedSnoozePresets)
+		}
+		sort.Ints(unique)
+
+		parts := make([]string, len(unique))
+		for i, m := range unique {
+			parts[i] = strconv.Itoa(m)
+		}
+		value = strings.Join(parts, ",")
+	}
+	_, err := s.db.Exec("UPDATE settings SET med_snooze_minutes = ? WHERE id = 1", value)
+	return err
+}
+
+// SnoozeIntake holds off reminders for a pending intake until the given time
+func (s *Store) SnoozeIntake(id int64, until time.Time) error {
+	_, err := s.db.Exec("UPDATE intake_log SET snoozed_until = ? WHERE id = ? AND status = 'PENDING'", until, id)
+	return err
+}
+
+// ClearIntakeSnooze lets the regular reminders pick the intake up again
+func (s *Store) ClearIntakeSnooze(id int64) error {
+	_, err := s.db.Exec("UPDATE intake_log SET snoozed_until = NULL WHERE id = ?", id)
+	return err
+}
+
+// GetDueSnoozedIntakes returns pending intakes whose snooze has run out by now
+func (s *Store) GetDueSnoozedIntakes(now time.Time) ([]IntakeLog, error) {
+	rows, err := s.db.Query(`
+		SELECT id, medication_id, user_id, scheduled_at, status, snoozed_until
+		FROM intake_log
+		WHERE status = 'PENDING' AND snoozed_until IS NOT NULL AND snoozed_until <= ?
+		ORDER BY scheduled_at`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []IntakeLog
+	for rows.Next() {
+		var l IntakeLog
+		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.Status, &l.SnoozedUntil); err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
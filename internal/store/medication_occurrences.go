@@ -0,0 +1,38 @@
+package store
+
+import "time"
+
+// MedicationOccurrences returns the medication's dose times from from on, covering at least
+// the given number of days' worth of doses and respecting its start and end dates. As-needed,
+// archived and unparseable schedules have none.
+func (s *Store) MedicationOccurrences(userID int64, m *Medication, from time.Time, days int) []time.Time {
+	if m.Archived {
+		return nil
+	}
+	cfg, err := s.ExpandSchedule(userID, m)
+	if err != nil || cfg.Type == "as_needed" || len(cfg.Times) == 0 {
+		return nil
+	}
+	if m.StartDate != nil && m.StartDate.After(from) {
+		from = *m.StartDate
+	}
+
+	var result []time.Time
+	for _, t := range cfg.NextOccurrences(from, days*len(cfg.Times)) {
+		if m.EndDate != nil && t.After(*m.EndDate) {
+			break
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// NextOccurrence returns the medication's first dose at or after from, or false if it
+// has none
+func (s *Store) NextOccurrence(userID int64, m *Medication, from time.Time) (time.Time, bool) {
+	occurrences := s.MedicationOccurrences(userID, m, from, 1)
+	if len(occurrences) == 0 {
+		return time.Time{}, false
+	}
+	return occurrences[0], true
+}
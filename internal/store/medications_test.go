@@ -0,0 +1,228 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArchiveExpiredMedications(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	fixedNow := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	pastEnd := fixedNow.AddDate(0, 0, -1)
+	futureEnd := fixedNow.AddDate(0, 0, 5)
+	expiredID, _ := db.CreateMedication("Amoxicillin", "500mg", "08:00", nil, &pastEnd, "", "")
+	currentID, _ := db.CreateMedication("Ibuprofen", "200mg", "08:00", nil, &futureEnd, "", "")
+	openEndedID, _ := db.CreateMedication("Vitamin D", "1000IU", "08:00", nil, nil, "", "")
+
+	archived, err := db.ArchiveExpiredMedications()
+	if err != nil {
+		t.Fatalf("ArchiveExpiredMedications: %v", err)
+	}
+	if len(archived) != 1 || archived[0].ID != expiredID {
+		t.Fatalf("expected only Amoxicillin to be archived, got %+v", archived)
+	}
+
+	for id, wantArchived := range map[int64]bool{expiredID: true, currentID: false, openEndedID: false} {
+		m, err := db.GetMedication(id)
+		if err != nil {
+			t.Fatalf("GetMedication: %v", err)
+		}
+		if m.Archived != wantArchived {
+			t.Errorf("medication %s: archived=%v, want %v", m.Name, m.Archived, wantArchived)
+		}
+	}
+
+	// Already archived medications are not reported again
+	archived, err = db.ArchiveExpiredMedications()
+	if err != nil {
+		t.Fatalf("ArchiveExpiredMedications: %v", err)
+	}
+	if len(archived) != 0 {
+		t.Errorf("expected no medications on second run, got %d", len(archived))
+	}
+}
+
+func TestGetAllRestocks(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	medA, _ := s.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	medB, _ := s.CreateMedication("Med B", "5mg", `{"type":"daily","times":["20:00"]}`, nil, nil, "", "")
+
+	restock := func(medID int64, qty int, daysAgo int) {
+		t.Helper()
+		if err := s.AddRestock(medID, qty, "", nil); err != nil {
+			t.Fatalf("AddRestock failed: %v", err)
+		}
+		at := time.Now().UTC().AddDate(0, 0, -daysAgo).Format("2006-01-02 15:04:05")
+		if _, err := s.db.Exec("UPDATE medication_restocks SET restocked_at = ? WHERE id = (SELECT MAX(id) FROM medication_restocks)", at); err != nil {
+			t.Fatalf("Failed to backdate restock: %v", err)
+		}
+	}
+	restock(medA, 30, 40)
+	restock(medB, 60, 10)
+	restock(medA, 30, 5)
+	restock(medB, 90, 200) // Outside the window
+
+	restocks, err := s.GetAllRestocks(1, time.Now().AddDate(0, 0, -90))
+	if err != nil {
+		t.Fatalf("GetAllRestocks failed: %v", err)
+	}
+	if len(restocks) != 3 {
+		t.Fatalf("Expected 3 restocks, got %d", len(restocks))
+	}
+
+	wantNames := []string{"Med A", "Med B", "Med A"}
+	wantQty := []int{30, 60, 30}
+	for i, r := range restocks {
+		if r.MedicationName != wantNames[i] || r.Quantity != wantQty[i] {
+			t.Errorf("Restock %d: got %s x%d, want %s x%d", i, r.MedicationName, r.Quantity, wantNames[i], wantQty[i])
+		}
+		if i > 0 && r.RestockedAt.After(restocks[i-1].RestockedAt) {
+			t.Errorf("Expected newest first, restock %d is newer than %d", i, i-1)
+		}
+	}
+}
+
+func TestConfirmIntakeQuantity_HalfDose(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	medID, _ := s.CreateMedication("Bisoprolol", "5mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	stock := 10.0
+	if err := s.SetInventory(medID, &stock); err != nil {
+		t.Fatalf("SetInventory failed: %v", err)
+	}
+
+	scheduledAt := time.Now().Add(-3 * time.Hour)
+	intakeID, _ := s.CreateIntake(medID, 1, scheduledAt)
+	if err := s.ConfirmIntakeQuantity(intakeID, scheduledAt, 0.5); err != nil {
+		t.Fatalf("ConfirmIntakeQuantity failed: %v", err)
+	}
+	if err := s.DecrementInventory(medID, 0.5); err != nil {
+		t.Fatalf("DecrementInventory failed: %v", err)
+	}
+
+	med, _ := s.GetMedication(medID)
+	if med.InventoryCount == nil || *med.InventoryCount != 9.5 {
+		t.Errorf("Expected inventory 9.5, got %v", med.InventoryCount)
+	}
+	intake, _ := s.GetIntake(intakeID)
+	if intake.Status != "TAKEN" || intake.Quantity != 0.5 {
+		t.Errorf("Expected TAKEN with quantity 0.5, got %s with %g", intake.Status, intake.Quantity)
+	}
+
+	// A second half brings the count back to a whole number
+	if err := s.DecrementInventory(medID, 0.5); err != nil {
+		t.Fatalf("DecrementInventory failed: %v", err)
+	}
+	med, _ = s.GetMedication(medID)
+	if *med.InventoryCount != 9 {
+		t.Errorf("Expected inventory 9, got %g", *med.InventoryCount)
+	}
+
+	stats, err := s.GetAdherenceStats(scheduledAt.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetAdherenceStats failed: %v", err)
+	}
+	if stats.Taken != 1 || stats.Missed != 0 || stats.Rate != 100 {
+		t.Errorf("Expected the half dose to count as taken, got %+v", stats)
+	}
+}
+
+func TestGetNearestPendingIntake(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	medID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	now := time.Date(2025, 5, 2, 19, 50, 0, 0, time.UTC)
+	morning, _ := s.CreateIntake(medID, 1, time.Date(2025, 5, 2, 8, 0, 0, 0, time.UTC))
+	evening, _ := s.CreateIntake(medID, 1, time.Date(2025, 5, 2, 20, 0, 0, 0, time.UTC))
+	s.CreateIntake(medID, 1, time.Date(2025, 5, 1, 8, 0, 0, 0, time.UTC)) // Outside the window
+
+	// Confirming ten minutes early matches the evening dose, not the older morning one
+	got, err := s.GetNearestPendingIntake(medID, now, 12*time.Hour)
+	if err != nil {
+		t.Fatalf("GetNearestPendingIntake failed: %v", err)
+	}
+	if got == nil || got.ID != evening {
+		t.Fatalf("Expected evening intake %d, got %+v", evening, got)
+	}
+
+	// Once the evening dose is taken, the morning one is next
+	s.ConfirmIntake(evening, now)
+	got, _ = s.GetNearestPendingIntake(medID, now, 12*time.Hour)
+	if got == nil || got.ID != morning {
+		t.Fatalf("Expected morning intake %d, got %+v", morning, got)
+	}
+
+	// Nothing pending close enough
+	s.ConfirmIntake(morning, now)
+	if got, _ := s.GetNearestPendingIntake(medID, now, 12*time.Hour); got != nil {
+		t.Errorf("Expected no intake within the window, got %+v", got)
+	}
+
+}
+
+func TestDosePerIntake(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	medID, _ := s.CreateMedication("Levetiracetam", "500mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	if m, _ := s.GetMedication(medID); m.DosePerIntake != 1 {
+		t.Fatalf("Expected dose per intake to default to 1, got %d", m.DosePerIntake)
+	}
+	if err := s.SetDosePerIntake(medID, 0); err == nil {
+		t.Error("Expected a dose per intake of 0 to be rejected")
+	}
+	if err := s.SetDosePerIntake(medID, 2); err != nil {
+		t.Fatalf("SetDosePerIntake failed: %v", err)
+	}
+	count := 20.0
+	s.SetInventory(medID, &count)
+
+	// Two doses a day of two tablets each: 20 tablets last 5 days
+	m, _ := s.GetMedication(medID)
+	if days := s.GetDaysOfStockRemaining(1, m); days == nil || *days != 5 {
+		t.Errorf("Expected 5 days of stock, got %v", days)
+	}
+	if !s.IsLowOnStock(1, m, 7) {
+		t.Error("Expected 5 days of stock to be low against a 7 day threshold")
+	}
+
+	// A confirmation records the whole dose
+	intakeID, _ := s.CreateIntake(medID, 1, time.Now())
+	if err := s.ConfirmIntake(intakeID, time.Now()); err != nil {
+		t.Fatalf("ConfirmIntake failed: %v", err)
+	}
+	if err := s.DecrementInventoryByDose(medID); err != nil {
+		t.Fatalf("DecrementInventoryByDose failed: %v", err)
+	}
+	if in, _ := s.GetIntake(intakeID); in.Quantity != 2 {
+		t.Errorf("Expected quantity 2, got %v", in.Quantity)
+	}
+	if m, _ := s.GetMedication(medID); *m.InventoryCount != 18 {
+		t.Errorf("Expected 18 left, got %v", *m.InventoryCount)
+	}
+}
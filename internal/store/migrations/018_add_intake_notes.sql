@@ -0,0 +1,6 @@
+-- +goose Up
+-- Free-text note attached to a confirmed dose (e.g. "took with breakfast")
+ALTER TABLE intake_log ADD COLUMN notes TEXT;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
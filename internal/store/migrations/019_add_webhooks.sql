@@ -0,0 +1,17 @@
+-- +goose Up
+-- Outbound webhooks forwarding events to external systems (IFTTT, Home Assistant)
+CREATE TABLE webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    events TEXT NOT NULL, -- comma-separated event types
+    secret TEXT NOT NULL, -- HMAC-SHA256 signing key
+    active BOOLEAN DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhooks_user_id ON webhooks(user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_webhooks_user_id;
+DROP TABLE IF EXISTS webhooks;
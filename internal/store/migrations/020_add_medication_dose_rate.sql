@@ -0,0 +1,7 @@
+-- +goose Up
+-- Optional weight-based dose rate (e.g. 15 mg/kg) for pediatric or weight-dosed medications
+ALTER TABLE medications ADD COLUMN dose_rate_per_kg REAL;
+ALTER TABLE medications ADD COLUMN dose_unit TEXT;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
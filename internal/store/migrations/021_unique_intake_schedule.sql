@@ -0,0 +1,35 @@
+-- +goose Up
+-- Merge duplicate intakes created by overlapping scheduler ticks into one row per
+-- medication/time: the most advanced one (TAKEN, then MISSED, then the oldest) is kept,
+-- takes over the duplicates' reminder messages and, if it has none, their notes
+CREATE TEMP TABLE intake_duplicates AS
+SELECT id, keep_id FROM (
+    SELECT id, FIRST_VALUE(id) OVER (
+        PARTITION BY medication_id, scheduled_at
+        ORDER BY CASE status WHEN 'TAKEN' THEN 0 WHEN 'MISSED' THEN 1 ELSE 2 END, id
+    ) AS keep_id
+    FROM intake_log
+) WHERE id != keep_id;
+
+UPDATE intake_reminders
+SET intake_id = (SELECT keep_id FROM intake_duplicates WHERE intake_duplicates.id = intake_reminders.intake_id)
+WHERE intake_id IN (SELECT id FROM intake_duplicates);
+
+UPDATE intake_log
+SET notes = (
+    SELECT d.notes FROM intake_log d
+    JOIN intake_duplicates x ON x.id = d.id
+    WHERE x.keep_id = intake_log.id AND d.notes IS NOT NULL AND d.notes != ''
+    ORDER BY d.id LIMIT 1
+)
+WHERE (notes IS NULL OR notes = '')
+  AND id IN (SELECT keep_id FROM intake_duplicates);
+
+DELETE FROM intake_log WHERE id IN (SELECT id FROM intake_duplicates);
+
+DROP TABLE intake_duplicates;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_intake_log_med_scheduled ON intake_log(medication_id, scheduled_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_intake_log_med_scheduled;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Remove duplicate readings so imports can rely on a unique index for dedupe
+DELETE FROM blood_pressure_readings
+WHERE id NOT IN (
+    SELECT MIN(id) FROM blood_pressure_readings
+    GROUP BY user_id, measured_at, systolic, diastolic
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bp_unique_reading ON blood_pressure_readings(user_id, measured_at, systolic, diastolic);
+
+-- Resume marker for chunked imports: the newest reading committed by an unfinished import
+CREATE TABLE IF NOT EXISTS bp_import_state (
+    user_id INTEGER PRIMARY KEY,
+    last_measured_at DATETIME NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS bp_import_state;
+DROP INDEX IF EXISTS idx_bp_unique_reading;
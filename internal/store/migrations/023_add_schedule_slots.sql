@@ -0,0 +1,12 @@
+-- +goose Up
+-- Per-user clock times for named schedule slots (e.g. morning=08:00)
+CREATE TABLE IF NOT EXISTS schedule_slots (
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    time TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, name)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS schedule_slots;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Number of days of health records to keep; NULL or 0 keeps everything
+ALTER TABLE settings ADD COLUMN retention_days INTEGER;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
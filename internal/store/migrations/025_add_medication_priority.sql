@@ -0,0 +1,6 @@
+-- +goose Up
+-- low, normal or critical; critical misses weigh more in adherence reporting
+ALTER TABLE medications ADD COLUMN priority TEXT NOT NULL DEFAULT 'normal';
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
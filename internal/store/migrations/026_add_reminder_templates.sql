@@ -0,0 +1,10 @@
+-- +goose Up
+-- Per-user text used for each medication in reminders, with {name}, {dosage} and {time} placeholders
+CREATE TABLE IF NOT EXISTS reminder_templates (
+    user_id INTEGER PRIMARY KEY,
+    template TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS reminder_templates;
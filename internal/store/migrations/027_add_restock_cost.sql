@@ -0,0 +1,8 @@
+-- +goose Up
+-- Optional price paid per unit, used for spend reports
+ALTER TABLE medication_restocks ADD COLUMN unit_cost REAL;
+-- ISO 4217 code used to display costs
+ALTER TABLE settings ADD COLUMN currency TEXT;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
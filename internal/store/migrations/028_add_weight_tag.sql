@@ -0,0 +1,6 @@
+-- +goose Up
+-- Free-form context of a weigh-in, e.g. "morning-fasted" or "post-workout"
+ALTER TABLE weight_logs ADD COLUMN tag TEXT;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
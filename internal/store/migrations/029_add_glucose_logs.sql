@@ -0,0 +1,21 @@
+-- +goose Up
+-- Blood glucose readings, always stored in mg/dL
+CREATE TABLE IF NOT EXISTS glucose_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    measured_at DATETIME NOT NULL,
+    value_mgdl REAL NOT NULL,
+    meal_context TEXT, -- fasting, before_meal, after_meal, bedtime, random
+    notes TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_glucose_user_measured ON glucose_logs(user_id, measured_at);
+
+-- Display unit for glucose values (mg/dL or mmol/L)
+ALTER TABLE settings ADD COLUMN glucose_unit TEXT;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_glucose_user_measured;
+DROP TABLE IF EXISTS glucose_logs;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Temperature and symptom entries for tracking the course of an illness
+CREATE TABLE IF NOT EXISTS symptom_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    measured_at DATETIME NOT NULL,
+    temperature REAL, -- Celsius
+    symptoms TEXT,
+    severity INTEGER, -- 1 (mild) to 10 (severe)
+    notes TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_symptom_user_measured ON symptom_logs(user_id, measured_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_symptom_user_measured;
+DROP TABLE IF EXISTS symptom_logs;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- When a reminder message was sent, for response time analytics
+ALTER TABLE intake_reminders ADD COLUMN sent_at DATETIME;
+UPDATE intake_reminders SET sent_at = created_at WHERE sent_at IS NULL;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Fraction of a unit taken per dose (e.g. 0.5 for half a tablet).
+-- medications.inventory_count keeps its INTEGER affinity; SQLite stores fractional counts as REAL.
+ALTER TABLE intake_log ADD COLUMN quantity REAL NOT NULL DEFAULT 1;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
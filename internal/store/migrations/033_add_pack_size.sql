@@ -0,0 +1,6 @@
+-- +goose Up
+-- Units per pack or blister, so inventory can be restocked and reported in packs
+ALTER TABLE medications ADD COLUMN pack_size INTEGER;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
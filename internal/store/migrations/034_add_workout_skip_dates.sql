@@ -0,0 +1,14 @@
+-- +goose Up
+-- Days without workouts (vacation, injury); no session is planned or notified on them
+CREATE TABLE IF NOT EXISTS workout_skip_dates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    skip_date TEXT NOT NULL, -- YYYY-MM-DD in local time
+    reason TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, skip_date),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS workout_skip_dates;
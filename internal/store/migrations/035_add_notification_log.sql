@@ -0,0 +1,19 @@
+-- +goose Up
+-- Every notification send attempt, to debug reminders that never arrived
+CREATE TABLE IF NOT EXISTS notification_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    channel TEXT NOT NULL, -- 'telegram' or 'webpush'
+    target TEXT NOT NULL, -- Chat ID or push endpoint
+    summary TEXT NOT NULL,
+    success BOOLEAN NOT NULL,
+    error TEXT,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_log_user_created ON notification_log(user_id, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_notification_log_user_created;
+DROP TABLE IF EXISTS notification_log;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Prescribed weight range; target_weight_kg stays for single-weight programs
+ALTER TABLE workout_exercises ADD COLUMN target_weight_min DECIMAL;
+ALTER TABLE workout_exercises ADD COLUMN target_weight_max DECIMAL;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Name of the medication actually taken when the scheduled one was substituted (e.g. a generic)
+ALTER TABLE intake_log ADD COLUMN substituted_with TEXT;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
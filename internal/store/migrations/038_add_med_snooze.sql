@@ -0,0 +1,9 @@
+-- +goose Up
+-- Pending doses can be snoozed from the reminder; the scheduler re-reminds once this passes
+ALTER TABLE intake_log ADD COLUMN snoozed_until DATETIME;
+
+-- Comma-separated snooze durations in minutes shown on medication reminders (NULL = 15,30,60)
+ALTER TABLE settings ADD COLUMN med_snooze_minutes TEXT;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
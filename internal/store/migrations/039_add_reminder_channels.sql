@@ -0,0 +1,6 @@
+-- +goose Up
+-- JSON object of reminder type -> {"telegram": bool, "web_push": bool}; missing types use both channels
+ALTER TABLE settings ADD COLUMN reminder_channels TEXT;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
@@ -0,0 +1,7 @@
+-- +goose Up
+-- How far (in minutes) a batch confirmation may be from a pending dose's scheduled time
+-- and still confirm it, e.g. from a stale reminder (NULL = 720)
+ALTER TABLE settings ADD COLUMN confirm_window_minutes INTEGER;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Periodic lab values (cholesterol, A1c, ...) as context for how medications work over months
+CREATE TABLE IF NOT EXISTS lab_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    value REAL NOT NULL,
+    unit TEXT,
+    measured_at DATETIME NOT NULL,
+    reference_low REAL,
+    reference_high REAL,
+    notes TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_lab_results_user_measured ON lab_results(user_id, measured_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_lab_results_user_measured;
+DROP TABLE IF EXISTS lab_results;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Site and position applied to new BP readings that don't specify them (NULL = none)
+ALTER TABLE settings ADD COLUMN bp_default_site TEXT;
+ALTER TABLE settings ADD COLUMN bp_default_position TEXT;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
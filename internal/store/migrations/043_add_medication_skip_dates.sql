@@ -0,0 +1,13 @@
+-- +goose Up
+-- Days on which a medication's reminders are silenced ("skip today"); its schedule is unchanged
+CREATE TABLE IF NOT EXISTS medication_skip_dates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    medication_id INTEGER NOT NULL,
+    skip_date TEXT NOT NULL, -- YYYY-MM-DD in local time
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (medication_id, skip_date),
+    FOREIGN KEY (medication_id) REFERENCES medications(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS medication_skip_dates;
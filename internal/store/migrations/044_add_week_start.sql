@@ -0,0 +1,6 @@
+-- +goose Up
+-- First day of the week for weekly aggregations: 0 = Sunday, 1 = Monday (NULL = Monday)
+ALTER TABLE settings ADD COLUMN week_start INTEGER;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
@@ -0,0 +1,29 @@
+-- +goose Up
+-- Injection sites of an injectable medication, in rotation order
+CREATE TABLE IF NOT EXISTS medication_injection_sites (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    medication_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    site TEXT NOT NULL,
+    UNIQUE (medication_id, position),
+    FOREIGN KEY (medication_id) REFERENCES medications(id) ON DELETE CASCADE
+);
+
+-- Site used for each confirmed injection
+CREATE TABLE IF NOT EXISTS injection_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    medication_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    intake_id INTEGER UNIQUE,
+    site TEXT NOT NULL,
+    injected_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (medication_id) REFERENCES medications(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_injection_log_medication ON injection_log(medication_id, injected_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_injection_log_medication;
+DROP TABLE IF EXISTS injection_log;
+DROP TABLE IF EXISTS medication_injection_sites;
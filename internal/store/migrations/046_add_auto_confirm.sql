@@ -0,0 +1,6 @@
+-- +goose Up
+-- Minutes after the scheduled time an unconfirmed dose is assumed taken, NULL = never
+ALTER TABLE medications ADD COLUMN auto_confirm_after INTEGER;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Significant life events ("started new job", "flu") shown on charts to explain trends
+CREATE TABLE IF NOT EXISTS life_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    date TEXT NOT NULL, -- YYYY-MM-DD
+    note TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_life_events_user_date ON life_events(user_id, date);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_life_events_user_date;
+DROP TABLE IF EXISTS life_events;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Web pushes that failed for a transient reason, retried with backoff by the scheduler
+CREATE TABLE IF NOT EXISTS push_retry_queue (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    endpoint TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    next_attempt_at DATETIME NOT NULL,
+    last_error TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_retry_queue_next ON push_retry_queue(next_attempt_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_push_retry_queue_next;
+DROP TABLE IF EXISTS push_retry_queue;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Severe interaction pairs already alerted about, so each pair is only pushed once
+CREATE TABLE IF NOT EXISTS interaction_alerts (
+    rxcui1 TEXT NOT NULL,
+    rxcui2 TEXT NOT NULL,
+    alerted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (rxcui1, rxcui2)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS interaction_alerts;
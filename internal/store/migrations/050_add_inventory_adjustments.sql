@@ -0,0 +1,16 @@
+-- +goose Up
+-- Manual inventory corrections, i.e. the count entered vs what the inventory said
+CREATE TABLE IF NOT EXISTS inventory_adjustments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    medication_id INTEGER NOT NULL,
+    previous_count REAL NOT NULL,
+    new_count REAL NOT NULL,
+    adjusted_at DATETIME NOT NULL,
+    FOREIGN KEY(medication_id) REFERENCES medications(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_adjustments_med_id ON inventory_adjustments(medication_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_inventory_adjustments_med_id;
+DROP TABLE IF EXISTS inventory_adjustments;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Units taken per scheduled dose, e.g. 2 tablets; confirmations take this many from inventory
+ALTER TABLE medications ADD COLUMN dose_per_intake INTEGER NOT NULL DEFAULT 1;
+
+-- +goose Down
+-- SQLite doesn't support DROP COLUMN directly, but goose down is rarely used in practice
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Resume markers are kept per import, so an interrupted import of one file can't make
+-- the import of another file skip rows
+DROP TABLE IF EXISTS bp_import_state;
+CREATE TABLE bp_import_state (
+    user_id INTEGER NOT NULL,
+    import_id TEXT NOT NULL,
+    last_measured_at DATETIME NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, import_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS bp_import_state;
+CREATE TABLE IF NOT EXISTS bp_import_state (
+    user_id INTEGER PRIMARY KEY,
+    last_measured_at DATETIME NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
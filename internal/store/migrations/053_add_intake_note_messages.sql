@@ -0,0 +1,33 @@
+-- +goose Up
+-- Confirmation messages a note can be replied to. Kept apart from intake_reminders, whose
+-- messages are deleted once the dose is confirmed, missed or archived.
+CREATE TABLE intake_note_messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    intake_id INTEGER NOT NULL,
+    message_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(intake_id) REFERENCES intake_log(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_intake_note_messages_message_id ON intake_note_messages(message_id);
+
+-- Confirmations used to be stored as reminders: messages sent after the dose was taken
+INSERT INTO intake_note_messages (intake_id, message_id, created_at)
+SELECT ir.intake_id, ir.message_id, ir.created_at
+FROM intake_reminders ir
+JOIN intake_log il ON il.id = ir.intake_id
+WHERE il.status = 'TAKEN' AND il.taken_at IS NOT NULL AND ir.sent_at IS NOT NULL
+  AND julianday(ir.sent_at) > julianday(il.taken_at);
+
+DELETE FROM intake_reminders
+WHERE id IN (
+    SELECT ir.id FROM intake_reminders ir
+    JOIN intake_log il ON il.id = ir.intake_id
+    WHERE il.status = 'TAKEN' AND il.taken_at IS NOT NULL AND ir.sent_at IS NOT NULL
+      AND julianday(ir.sent_at) > julianday(il.taken_at)
+);
+
+-- +goose Down
+INSERT INTO intake_reminders (intake_id, message_id, created_at, sent_at)
+SELECT intake_id, message_id, created_at, created_at FROM intake_note_messages;
+DROP INDEX IF EXISTS idx_intake_note_messages_message_id;
+DROP TABLE IF EXISTS intake_note_messages;
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// notificationSummaryLimit caps the stored summary so logs of long messages stay small
+const notificationSummaryLimit = 120
+
+// NotificationLogEntry records one attempt to deliver a notification
+type NotificationLogEntry struct {
+	ID        int64     `json:"id"`
+	Channel   string    `json:"channel"` // ChannelTelegram or ChannelWebPush
+	Target    string    `json:"target"`
+	Summary   string    `json:"summary"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LogNotification records a send attempt; sendErr is the delivery error, if any
+func (s *Store) LogNotification(userID int64, channel, target, summary string, sendErr error) error {
+	var errText interface{}
+	if sendErr != nil {
+		errText = sendErr.Error()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO notification_log (user_id, channel, target, summary, success, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		userID, channel, target, truncateSummary(summary), sendErr == nil, errText, nowFunc())
+	return err
+}
+
+// GetNotificationLog returns the user's send attempts since the given time, newest first
+func (s *Store) GetNotificationLog(userID int64, since time.Time) ([]NotificationLogEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT id, channel, target, summary, success, error, created_at
+		FROM notification_log
+		WHERE user_id = ? AND created_at >= ?
+		ORDER BY created_at DESC, id DESC`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []NotificationLogEntry{}
+	for rows.Next() {
+		var e NotificationLogEntry
+		var errText sql.NullString
+		if err := rows.Scan(&e.ID, &e.Channel, &e.Target, &e.Summary, &e.Success, &errText, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Error = errText.String
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// truncateSummary flattens a message onto one line, shortened to the summary limit
+func truncateSummary(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) > notificationSummaryLimit {
+		return string(runes[:notificationSummaryLimit-1]) + "…"
+	}
+	return text
+}
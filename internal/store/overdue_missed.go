@@ -0,0 +1,65 @@
+package store
+
+import "time"
+
+// OverdueReason is recorded on intakes marked missed because nobody confirmed them in time
+const OverdueReason = "not confirmed in time"
+
+// MarkOverdueIntakesMissed marks pending doses scheduled more than olderThan ago as
+// missed. Archived medications are left alone, as are auto-confirm medications, whose
+// overdue doses are assumed taken instead, doses snoozed past now and doses on a day the
+// medication was skipped, since skipping them was the user's choice. Notes already on a
+// dose are kept. Returns the intakes marked missed.
+func (s *Store) MarkOverdueIntakesMissed(olderThan time.Duration) ([]IntakeLog, error) {
+	now := nowFunc()
+	rows, err := s.db.Query(`
+		SELECT il.id, il.medication_id, il.user_id, il.scheduled_at, il.snoozed_until
+		FROM intake_log il
+		JOIN medications m ON il.medication_id = m.id
+		WHERE il.status = 'PENDING' AND m.archived = 0 AND m.auto_confirm_after IS NULL
+		ORDER BY il.scheduled_at`)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []IntakeLog
+	for rows.Next() {
+		var l IntakeLog
+		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.SnoozedUntil); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if now.Sub(l.ScheduledAt) <= olderThan || (l.SnoozedUntil != nil && l.SnoozedUntil.After(now)) {
+			continue
+		}
+		due = append(due, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	missed := []IntakeLog{}
+	for _, l := range due {
+		skipped, err := s.IsMedicationSkipped(l.MedicationID, l.ScheduledAt.Local())
+		if err != nil {
+			return missed, err
+		}
+		if skipped {
+			continue
+		}
+
+		// The dose may have been confirmed since it was read
+		res, err := s.db.Exec("UPDATE intake_log SET status = 'MISSED', notes = COALESCE(NULLIF(notes, ''), ?) WHERE id = ? AND status = 'PENDING'",
+			OverdueReason, l.ID)
+		if err != nil {
+			return missed, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			continue
+		}
+		l.Status = "MISSED"
+		missed = append(missed, l)
+	}
+	return missed, nil
+}
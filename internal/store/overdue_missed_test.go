@@ -0,0 +1,42 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarkOverdueIntakesMissed_SkippedDay(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	fixedNow := time.Date(2025, 5, 2, 20, 0, 0, 0, time.Local)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	schedule := `{"type":"daily","times":["09:00"]}`
+	skippedMed, _ := s.CreateMedication("Lisinopril", "10mg", schedule, nil, nil, "", "")
+	otherMed, _ := s.CreateMedication("Aspirin", "100mg", schedule, nil, nil, "", "")
+	at := time.Date(2025, 5, 2, 9, 0, 0, 0, time.Local)
+	skipped, _ := s.CreateIntake(skippedMed, 1, at)
+	overdue, _ := s.CreateIntake(otherMed, 1, at)
+
+	// "Skip today" after the day's dose was already created
+	if err := s.SkipMedicationOn(skippedMed, fixedNow); err != nil {
+		t.Fatalf("SkipMedicationOn failed: %v", err)
+	}
+
+	missed, err := s.MarkOverdueIntakesMissed(6 * time.Hour)
+	if err != nil {
+		t.Fatalf("MarkOverdueIntakesMissed failed: %v", err)
+	}
+	if len(missed) != 1 || missed[0].ID != overdue {
+		t.Fatalf("Expected only the other medication's dose marked missed, got %+v", missed)
+	}
+	if in, _ := s.GetIntake(skipped); in.Status != IntakeStatusPending {
+		t.Errorf("Expected the skipped dose left alone, got %s", in.Status)
+	}
+}
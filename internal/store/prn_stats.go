@@ -0,0 +1,115 @@
+package store
+
+import (
+	"math"
+	"time"
+)
+
+// PRN usage trends
+const (
+	PRNTrendIncreasing = "increasing"
+	PRNTrendDecreasing = "decreasing"
+	PRNTrendStable     = "stable"
+)
+
+// prnTrendThreshold is how much the second half of the window must differ from the
+// first (relative to the first) before the use counts as increasing or decreasing
+const prnTrendThreshold = 0.25
+
+// PRNTimeOfDay counts uses per part of the day (local time)
+type PRNTimeOfDay struct {
+	Night     int `json:"night"`     // 00:00-05:59
+	Morning   int `json:"morning"`   // 06:00-11:59
+	Afternoon int `json:"afternoon"` // 12:00-17:59
+	Evening   int `json:"evening"`   // 18:00-23:59
+}
+
+// PRNStats summarizes how an as-needed medication has been used over a window
+type PRNStats struct {
+	MedicationID    int64        `json:"medication_id"`
+	Days            int          `json:"days"`
+	Uses            int          `json:"uses"`
+	UsesPerWeek     float64      `json:"uses_per_week"`
+	LongestGapHours float64      `json:"longest_gap_hours"` // Between two consecutive uses
+	LastUsedAt      *time.Time   `json:"last_used_at"`
+	TimeOfDay       PRNTimeOfDay `json:"time_of_day"`
+	FirstHalfUses   int          `json:"first_half_uses"`
+	SecondHalfUses  int          `json:"second_half_uses"`
+	Trend           string       `json:"trend"`
+}
+
+// GetPRNStats computes usage of a medication from its taken intakes over the last
+// days, using the time it was taken (or scheduled, if unknown). The trend compares
+// the older half of the window with the recent half.
+func (s *Store) GetPRNStats(medID int64, days int) (*PRNStats, error) {
+	now := nowFunc()
+	loc := now.Location()
+	since := now.AddDate(0, 0, -days)
+	midpoint := since.Add(now.Sub(since) / 2)
+
+	rows, err := s.db.Query(`
+		SELECT scheduled_at, taken_at FROM intake_log
+		WHERE medication_id = ? AND status = 'TAKEN' AND COALESCE(taken_at, scheduled_at) >= ?
+		ORDER BY COALESCE(taken_at, scheduled_at) ASC`, medID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &PRNStats{MedicationID: medID, Days: days, Trend: PRNTrendStable}
+	var prev *time.Time
+	var longestGap time.Duration
+	for rows.Next() {
+		var usedAt time.Time
+		var takenAt *time.Time
+		if err := rows.Scan(&usedAt, &takenAt); err != nil {
+			return nil, err
+		}
+		if takenAt != nil {
+			usedAt = *takenAt
+		}
+		usedAt = usedAt.In(loc)
+		stats.Uses++
+
+		switch h := usedAt.Hour(); {
+		case h < 6:
+			stats.TimeOfDay.Night++
+		case h < 12:
+			stats.TimeOfDay.Morning++
+		case h < 18:
+			stats.TimeOfDay.Afternoon++
+		default:
+			stats.TimeOfDay.Evening++
+		}
+
+		if usedAt.Before(midpoint) {
+			stats.FirstHalfUses++
+		} else {
+			stats.SecondHalfUses++
+		}
+
+		if prev != nil && usedAt.Sub(*prev) > longestGap {
+			longestGap = usedAt.Sub(*prev)
+		}
+		prev = &usedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats.LastUsedAt = prev
+	stats.LongestGapHours = math.Round(longestGap.Hours()*10) / 10
+	if days > 0 {
+		stats.UsesPerWeek = math.Round(float64(stats.Uses)/float64(days)*7*100) / 100
+	}
+
+	first, second := float64(stats.FirstHalfUses), float64(stats.SecondHalfUses)
+	switch {
+	case second > first && (first == 0 || (second-first)/first > prnTrendThreshold):
+		stats.Trend = PRNTrendIncreasing
+	case first > second && (first-second)/first > prnTrendThreshold:
+		stats.Trend = PRNTrendDecreasing
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetPRNStats(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	fixedNow := time.Date(2024, 3, 29, 12, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	prnID, _ := s.CreateMedication("Ibuprofen", "200mg", `{"type":"as_needed"}`, nil, nil, "", "")
+	otherID, _ := s.CreateMedication("Paracetamol", "500mg", `{"type":"as_needed"}`, nil, nil, "", "")
+
+	at := func(day, hour int) time.Time { return time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC) }
+	take := func(medID int64, usedAt time.Time) {
+		t.Helper()
+		id, err := s.CreateIntake(medID, 1, usedAt)
+		if err != nil {
+			t.Fatalf("CreateIntake failed: %v", err)
+		}
+		if err := s.ConfirmIntake(id, usedAt); err != nil {
+			t.Fatalf("ConfirmIntake failed: %v", err)
+		}
+	}
+
+	take(prnID, at(1, 10))  // Before the 14-day window
+	take(prnID, at(16, 9))  // Morning
+	take(prnID, at(20, 14)) // Afternoon
+	take(prnID, at(23, 3))  // Night
+	take(prnID, at(24, 20)) // Evening
+	take(prnID, at(26, 21)) // Evening
+	take(prnID, at(28, 8))  // Morning
+	take(otherID, at(27, 8))
+	s.CreateIntake(prnID, 1, at(28, 14)) // Pending doses weren't used
+
+	stats, err := s.GetPRNStats(prnID, 14)
+	if err != nil {
+		t.Fatalf("GetPRNStats failed: %v", err)
+	}
+
+	if stats.Uses != 6 {
+		t.Errorf("Expected 6 uses, got %d", stats.Uses)
+	}
+	if stats.UsesPerWeek != 3 {
+		t.Errorf("Expected 3 uses per week, got %v", stats.UsesPerWeek)
+	}
+	want := PRNTimeOfDay{Night: 1, Morning: 2, Afternoon: 1, Evening: 2}
+	if stats.TimeOfDay != want {
+		t.Errorf("Expected time of day %+v, got %+v", want, stats.TimeOfDay)
+	}
+	// Mar 16 09:00 -> Mar 20 14:00
+	if stats.LongestGapHours != 101 {
+		t.Errorf("Expected longest gap of 101h, got %v", stats.LongestGapHours)
+	}
+	if stats.LastUsedAt == nil || !stats.LastUsedAt.Equal(at(28, 8)) {
+		t.Errorf("Expected last use at %v, got %v", at(28, 8), stats.LastUsedAt)
+	}
+	// The window splits at Mar 22 12:00: 2 uses before, 4 after
+	if stats.FirstHalfUses != 2 || stats.SecondHalfUses != 4 {
+		t.Errorf("Expected 2/4 uses per half, got %d/%d", stats.FirstHalfUses, stats.SecondHalfUses)
+	}
+	if stats.Trend != PRNTrendIncreasing {
+		t.Errorf("Expected increasing trend, got %q", stats.Trend)
+	}
+}
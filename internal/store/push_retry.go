@@ -0,0 +1,74 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PushRetry is a web push waiting to be sent again after a transient failure
+type PushRetry struct {
+	ID            int64     `json:"id"`
+	UserID        int64     `json:"user_id"`
+	Endpoint      string    `json:"endpoint"`
+	Payload       string    `json:"payload"`  // JSON notification payload as first sent
+	Attempts      int       `json:"attempts"` // Sends tried so far, the original included
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// EnqueuePushRetry queues a failed push for another attempt at nextAttempt
+func (s *Store) EnqueuePushRetry(userID int64, endpoint, payload string, nextAttempt time.Time, lastErr string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO push_retry_queue (user_id, endpoint, payload, attempts, next_attempt_at, last_error)
+		VALUES (?, ?, ?, 1, ?, ?)`, userID, endpoint, payload, nextAttempt, lastErr)
+	return err
+}
+
+// GetPushRetries returns every queued push, oldest first
+func (s *Store) GetPushRetries() ([]PushRetry, error) {
+	rows, err := s.db.Query("SELECT id, user_id, endpoint, payload, attempts, next_attempt_at, last_error FROM push_retry_queue ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	retries := []PushRetry{}
+	for rows.Next() {
+		var r PushRetry
+		var lastErr sql.NullString
+		if err := rows.Scan(&r.ID, &r.UserID, &r.Endpoint, &r.Payload, &r.Attempts, &r.NextAttemptAt, &lastErr); err != nil {
+			return nil, err
+		}
+		r.LastError = lastErr.String
+		retries = append(retries, r)
+	}
+	return retries, rows.Err()
+}
+
+// GetDuePushRetries returns the queued pushes whose next attempt is due by now
+func (s *Store) GetDuePushRetries(now time.Time) ([]PushRetry, error) {
+	retries, err := s.GetPushRetries()
+	if err != nil {
+		return nil, err
+	}
+	due := []PushRetry{}
+	for _, r := range retries {
+		if !r.NextAttemptAt.After(now) {
+			due = append(due, r)
+		}
+	}
+	return due, nil
+}
+
+// ReschedulePushRetry records another failed attempt and when to try next
+func (s *Store) ReschedulePushRetry(id int64, nextAttempt time.Time, lastErr string) error {
+	_, err := s.db.Exec("UPDATE push_retry_queue SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ?",
+		nextAttempt, lastErr, id)
+	return err
+}
+
+// DeletePushRetry removes a push from the queue once it was delivered or given up on
+func (s *Store) DeletePushRetry(id int64) error {
+	_, err := s.db.Exec("DELETE FROM push_retry_queue WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Reconciliation discrepancies
+const (
+	// Scheduled doses that were never confirmed, e.g. taken but not tapped
+	DiscrepancyUnconfirmedDoses = "unconfirmed_doses"
+	// More confirmations than scheduled doses, e.g. a dose confirmed twice
+	DiscrepancyExtraConfirmations = "extra_confirmations"
+	// A recount found fewer units than confirmations left, i.e. the inventory dropped more
+	DiscrepancyInventoryShortfall = "inventory_shortfall"
+	// A recount found more units than confirmations left, i.e. doses confirmed but not taken
+	DiscrepancyInventorySurplus = "inventory_surplus"
+)
+
+// Reconciliation compares a medication's scheduled, confirmed and inventory consumption
+// over a window. InventoryChange is restocked - confirmed + adjustments; InventoryStart
+// and InventoryEnd are only set while inventory is tracked.
+type Reconciliation struct {
+	MedicationID      int64     `json:"medication_id"`
+	Days              int       `json:"days"`
+	Since             time.Time `json:"since"`
+	ScheduledDoses    int       `json:"scheduled_doses"`
+	ConfirmedDoses    int       `json:"confirmed_doses"`
+	ConfirmedQuantity float64   `json:"confirmed_quantity"` // Units taken off the inventory by confirmations
+	Restocked         float64   `json:"restocked"`
+	Adjustments       float64   `json:"adjustments"` // Net manual corrections, negative when units went missing
+	InventoryChange   float64   `json:"inventory_change"`
+	InventoryStart    *float64  `json:"inventory_start"`
+	InventoryEnd      *float64  `json:"inventory_end"`
+	Discrepancies     []string  `json:"discrepancies"`
+}
+
+// recordInventoryAdjustment logs a manual inventory change of a tracked medication
+// before it is applied, so reconciliation can tell recounts from confirmations
+func (s *Store) recordInventoryAdjustment(medID int64, count *float64) error {
+	if count == nil {
+		return nil
+	}
+	var previous sql.NullFloat64
+	err := s.db.QueryRow("SELECT inventory_count FROM medications WHERE id = ?", medID).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !previous.Valid || previous.Float64 == *count {
+		return nil
+	}
+	_, err = s.db.Exec("INSERT INTO inventory_adjustments (medication_id, previous_count, new_count, adjusted_at) VALUES (?, ?, ?, ?)",
+		medID, previous.Float64, *count, nowFunc())
+	return err
+}
+
+// GetReconciliation reconciles a medication over the last days. Scheduled doses come
+// from expanding its schedule (skipped days excluded) and only count once the adherence
+// grace period has passed, as do the confirmations compared with them. Confirmed units
+// are those of taken doses that weren't substituted, by when they were taken.
+func (s *Store) GetReconciliation(userID int64, m *Medication, days int) (*Reconciliation, error) {
+	now := nowFunc()
+	since := now.AddDate(0, 0, -days)
+	cutoff := now.Add(-adherenceGracePeriod)
+
+	rec := &Reconciliation{MedicationID: m.ID, Days: days, Since: since, Discrepancies: []string{}}
+
+	scheduled := false
+	if cfg, err := s.ExpandSchedule(userID, m); err == nil && cfg.Type != "as_needed" && len(cfg.Times) > 0 {
+		scheduled = true
+		from := since
+		if created := m.CreatedAt.In(now.Location()); created.After(from) {
+			from = created
+		}
+		if m.StartDate != nil && m.StartDate.After(from) {
+			from = *m.StartDate
+		}
+		for _, t := range cfg.NextOccurrences(from, (days+1)*len(cfg.Times)) {
+			if t.After(cutoff) || (m.EndDate != nil && t.After(*m.EndDate)) {
+				break
+			}
+			skipped, err := s.IsMedicationSkipped(m.ID, t)
+			if err != nil {
+				return nil, err
+			}
+			if !skipped {
+				rec.ScheduledDoses++
+			}
+		}
+	}
+
+	rows, err := s.db.Query(`
+		SELECT scheduled_at, taken_at, quantity, substituted_with FROM intake_log
+		WHERE medication_id = ? AND status = 'TAKEN'`, m.ID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var scheduledAt time.Time
+		var takenAt *time.Time
+		var quantity float64
+		var substitutedWith sql.NullString
+		if err := rows.Scan(&scheduledAt, &takenAt, &quantity, &substitutedWith); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if !scheduledAt.Before(since) && !scheduledAt.After(cutoff) {
+			rec.ConfirmedDoses++
+		}
+		at := scheduledAt
+		if takenAt != nil {
+			at = *takenAt
+		}
+		if !at.Before(since) && !at.After(now) && substitutedWith.String == "" {
+			rec.ConfirmedQuantity += quantity
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	restocks, err := s.GetRestockHistory(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range restocks {
+		if !r.RestockedAt.Before(since) {
+			rec.Restocked += float64(r.Quantity)
+		}
+	}
+
+	rows, err = s.db.Query("SELECT previous_count, new_count, adjusted_at FROM inventory_adjustments WHERE medication_id = ?", m.ID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var previous, counted float64
+		var adjustedAt time.Time
+		if err := rows.Scan(&previous, &counted, &adjustedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if !adjustedAt.Before(since) {
+			rec.Adjustments += counted - previous
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rec.ConfirmedQuantity = roundTo(rec.ConfirmedQuantity, 2)
+	rec.Adjustments = roundTo(rec.Adjustments, 2)
+	rec.InventoryChange = roundTo(rec.Restocked-rec.ConfirmedQuantity+rec.Adjustments, 2)
+	if m.InventoryCount != nil {
+		end := *m.InventoryCount
+		start := roundTo(end-rec.InventoryChange, 2)
+		rec.InventoryEnd = &end
+		rec.InventoryStart = &start
+	}
+
+	if scheduled {
+		if rec.ConfirmedDoses < rec.ScheduledDoses {
+			rec.Discrepancies = append(rec.Discrepancies, DiscrepancyUnconfirmedDoses)
+		} else if rec.ConfirmedDoses > rec.ScheduledDoses {
+			rec.Discrepancies = append(rec.Discrepancies, DiscrepancyExtraConfirmations)
+		}
+	}
+	if rec.Adjustments < 0 {
+		rec.Discrepancies = append(rec.Discrepancies, DiscrepancyInventoryShortfall)
+	} else if rec.Adjustments > 0 {
+		rec.Discrepancies = append(rec.Discrepancies, DiscrepancyInventorySurplus)
+	}
+	return rec, nil
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetReconciliation(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	fixedNow := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	medID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	if _, err := s.db.Exec("UPDATE medications SET created_at = ? WHERE id = ?", fixedNow.AddDate(0, 0, -30), medID); err != nil {
+		t.Fatalf("Failed to backdate medication: %v", err)
+	}
+	if err := s.AddRestock(medID, 30, "", nil); err != nil {
+		t.Fatalf("AddRestock failed: %v", err)
+	}
+
+	// 08:00 on Mar 4-10 is in the last 7 days; the dose on Mar 6 goes unconfirmed
+	for day := 4; day <= 10; day++ {
+		at := time.Date(2024, 3, day, 8, 0, 0, 0, time.UTC)
+		id, _ := s.CreateIntake(medID, 1, at)
+		if day == 6 {
+			continue
+		}
+		s.ConfirmIntake(id, at)
+		s.DecrementInventory(medID, 1)
+	}
+
+	// A recount finds 20 instead of the 24 left after six confirmations
+	m, _ := s.GetMedication(medID)
+	counted := 20.0
+	if err := s.SetInventory(medID, &counted); err != nil {
+		t.Fatalf("SetInventory failed: %v", err)
+	}
+	m, _ = s.GetMedication(medID)
+
+	rec, err := s.GetReconciliation(1, m, 7)
+	if err != nil {
+		t.Fatalf("GetReconciliation failed: %v", err)
+	}
+	if rec.ScheduledDoses != 7 || rec.ConfirmedDoses != 6 || rec.ConfirmedQuantity != 6 {
+		t.Errorf("Expected 7 scheduled and 6 confirmed doses, got %+v", rec)
+	}
+	if rec.Restocked != 30 || rec.Adjustments != -4 || rec.InventoryChange != 20 {
+		t.Errorf("Expected 30 restocked, -4 adjusted, +20 change, got %+v", rec)
+	}
+	if rec.InventoryStart == nil || *rec.InventoryStart != 0 || rec.InventoryEnd == nil || *rec.InventoryEnd != 20 {
+		t.Errorf("Expected inventory to go from 0 to 20, got %v to %v", rec.InventoryStart, rec.InventoryEnd)
+	}
+	want := []string{DiscrepancyUnconfirmedDoses, DiscrepancyInventoryShortfall}
+	if !reflect.DeepEqual(rec.Discrepancies, want) {
+		t.Errorf("Expected discrepancies %v, got %v", want, rec.Discrepancies)
+	}
+
+	// Saving the medication without changing the count is not an adjustment
+	if err := s.UpdateMedication(medID, m.Name, m.Dosage, m.Schedule, false, nil, nil, "", "", &counted); err != nil {
+		t.Fatalf("UpdateMedication failed: %v", err)
+	}
+	rec, _ = s.GetReconciliation(1, m, 7)
+	if rec.Adjustments != -4 {
+		t.Errorf("Expected adjustments to stay at -4, got %v", rec.Adjustments)
+	}
+}
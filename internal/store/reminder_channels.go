@@ -0,0 +1,88 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Reminder types whose delivery channels can be configured
+const (
+	ReminderMedication = "medication"
+	ReminderBP         = "bp"
+	ReminderWeight     = "weight"
+	ReminderWorkout    = "workout"
+)
+
+// ReminderTypes lists the configurable reminder types
+var ReminderTypes = []string{ReminderMedication, ReminderBP, ReminderWeight, ReminderWorkout}
+
+// ReminderChannels selects where one type of reminder is delivered. Both off silences it.
+type ReminderChannels struct {
+	Telegram bool `json:"telegram"`
+	WebPush  bool `json:"web_push"`
+}
+
+// defaultReminderChannels sends every reminder everywhere, as before channels were configurable
+var defaultReminderChannels = ReminderChannels{Telegram: true, WebPush: true}
+
+// GetReminderChannels returns the channel choice for every reminder type
+func (s *Store) GetReminderChannels() (map[string]ReminderChannels, error) {
+	var value sql.NullString
+	err := s.db.QueryRow("SELECT reminder_channels FROM settings WHERE id = 1").Scan(&value)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	stored := make(map[string]ReminderChannels)
+	if value.Valid && value.String != "" {
+		if err := json.Unmarshal([]byte(value.String), &stored); err != nil {
+			return nil, err
+		}
+	}
+
+	channels := make(map[string]ReminderChannels, len(ReminderTypes))
+	for _, t := range ReminderTypes {
+		c, ok := stored[t]
+		if !ok {
+			c = defaultReminderChannels
+		}
+		channels[t] = c
+	}
+	return channels, nil
+}
+
+// ReminderChannelsFor returns the channel choice for one reminder type
+func (s *Store) ReminderChannelsFor(reminderType string) (ReminderChannels, error) {
+	channels, err := s.GetReminderChannels()
+	if err != nil {
+		return ReminderChannels{}, err
+	}
+	c, ok := channels[reminderType]
+	if !ok {
+		return ReminderChannels{}, fmt.Errorf("unknown reminder type %q", reminderType)
+	}
+	return c, nil
+}
+
+// SetReminderChannels stores the channel choice for the given reminder types; types not
+// mentioned keep their current setting
+func (s *Store) SetReminderChannels(update map[string]ReminderChannels) error {
+	channels, err := s.GetReminderChannels()
+	if err != nil {
+		return err
+	}
+	for t, c := range update {
+		if _, ok := channels[t]; !ok {
+			return fmt.Errorf("unknown reminder type %q (want medication, bp, weight or workout)", t)
+		}
+		channels[t] = c
+	}
+
+	data, err := json.Marshal(channels)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("UPDATE settings SET reminder_channels = ? WHERE id = 1", string(data))
+	return err
+}
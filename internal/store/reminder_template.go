@@ -0,0 +1,90 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultReminderTemplate renders a medication the way reminders always have
+const DefaultReminderTemplate = "{name} ({dosage})"
+
+// maxReminderTemplateLength keeps rendered messages well under Telegram's limit
+const maxReminderTemplateLength = 500
+
+var reminderPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+var reminderPlaceholders = map[string]bool{
+	"{name}":   true,
+	"{dosage}": true,
+	"{time}":   true,
+}
+
+// GetReminderTemplate returns the user's reminder template, or the default if none is set
+func (s *Store) GetReminderTemplate(userID int64) (string, error) {
+	var tmpl string
+	err := s.db.QueryRow("SELECT template FROM reminder_templates WHERE user_id = ?", userID).Scan(&tmpl)
+	if err == sql.ErrNoRows {
+		return DefaultReminderTemplate, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return tmpl, nil
+}
+
+// SetReminderTemplate validates and stores the user's reminder template.
+// An empty template restores the default.
+func (s *Store) SetReminderTemplate(userID int64, tmpl string) error {
+	tmpl = strings.TrimSpace(tmpl)
+	if tmpl == "" {
+		_, err := s.db.Exec("DELETE FROM reminder_templates WHERE user_id = ?", userID)
+		return err
+	}
+	if err := ValidateReminderTemplate(tmpl); err != nil {
+		return err
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO reminder_templates (user_id, template, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			template = excluded.template,
+			updated_at = CURRENT_TIMESTAMP`,
+		userID, tmpl)
+	return err
+}
+
+// ValidateReminderTemplate checks that the template names the medication and only
+// uses known placeholders
+func ValidateReminderTemplate(tmpl string) error {
+	if len(tmpl) > maxReminderTemplateLength {
+		return fmt.Errorf("template is too long (max %d characters)", maxReminderTemplateLength)
+	}
+	for _, p := range reminderPlaceholder.FindAllString(tmpl, -1) {
+		if !reminderPlaceholders[p] {
+			return fmt.Errorf("unknown placeholder %s, use {name}, {dosage} or {time}", p)
+		}
+	}
+	if !strings.Contains(tmpl, "{name}") {
+		return fmt.Errorf("template must include {name}")
+	}
+	return nil
+}
+
+// RenderReminderTemplate substitutes the medication's details into the template.
+// Empty brackets left by a missing dosage are dropped.
+func RenderReminderTemplate(tmpl string, m Medication, at time.Time) string {
+	out := strings.NewReplacer(
+		"{name}", m.Name,
+		"{dosage}", m.Dosage,
+		"{time}", at.Format("15:04"),
+	).Replace(tmpl)
+	if m.Dosage == "" {
+		out = strings.ReplaceAll(out, " ()", "")
+		out = strings.ReplaceAll(out, "()", "")
+	}
+	return strings.TrimSpace(out)
+}
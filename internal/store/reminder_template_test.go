@@ -0,0 +1,59 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenderReminderTemplate(t *testing.T) {
+	at := time.Date(2026, 1, 1, 8, 30, 0, 0, time.UTC)
+	med := Medication{Name: "Aspirin", Dosage: "100mg"}
+
+	if got := RenderReminderTemplate(DefaultReminderTemplate, med, at); got != "Aspirin (100mg)" {
+		t.Errorf("Default template rendered %q", got)
+	}
+	if got := RenderReminderTemplate(DefaultReminderTemplate, Medication{Name: "Vitamin D"}, at); got != "Vitamin D" {
+		t.Errorf("Default template without dosage rendered %q", got)
+	}
+
+	custom := "{time}: Zeit für {name}, {dosage} 💪"
+	if got := RenderReminderTemplate(custom, med, at); got != "08:30: Zeit für Aspirin, 100mg 💪" {
+		t.Errorf("Custom template rendered %q", got)
+	}
+}
+
+func TestSetReminderTemplate(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	if tmpl, _ := s.GetReminderTemplate(1); tmpl != DefaultReminderTemplate {
+		t.Errorf("Expected default template, got %q", tmpl)
+	}
+
+	if err := s.SetReminderTemplate(1, "Take {name} now"); err != nil {
+		t.Fatalf("SetReminderTemplate failed: %v", err)
+	}
+	if tmpl, _ := s.GetReminderTemplate(1); tmpl != "Take {name} now" {
+		t.Errorf("Expected custom template, got %q", tmpl)
+	}
+	if tmpl, _ := s.GetReminderTemplate(2); tmpl != DefaultReminderTemplate {
+		t.Errorf("Expected other user to keep the default, got %q", tmpl)
+	}
+
+	for _, bad := range []string{"Take {nmae}", "Take your pills at {time}"} {
+		if err := s.SetReminderTemplate(1, bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+
+	// Empty restores the default
+	if err := s.SetReminderTemplate(1, ""); err != nil {
+		t.Fatalf("SetReminderTemplate reset failed: %v", err)
+	}
+	if tmpl, _ := s.GetReminderTemplate(1); tmpl != DefaultReminderTemplate {
+		t.Errorf("Expected default after reset, got %q", tmpl)
+	}
+}
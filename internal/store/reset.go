@@ -0,0 +1,59 @@
+package store
+
+// ResetUserData deletes everything the user has recorded — intakes, readings, logs,
+// workouts, push subscriptions and webhooks — and restores the default settings, all in
+// one transaction. Medications and their restock history are kept.
+func (s *Store) ResetUserData(userID int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Children first: foreign keys aren't enforced, so cascades can't be relied on
+	statements := []string{
+		"DELETE FROM intake_reminders WHERE intake_id IN (SELECT id FROM intake_log WHERE user_id = ?)",
+		"DELETE FROM intake_note_messages WHERE intake_id IN (SELECT id FROM intake_log WHERE user_id = ?)",
+		"DELETE FROM intake_log WHERE user_id = ?",
+		"DELETE FROM blood_pressure_readings WHERE user_id = ?",
+		"DELETE FROM bp_import_state WHERE user_id = ?",
+		"DELETE FROM bp_reminder_state WHERE user_id = ?",
+		"DELETE FROM weight_logs WHERE user_id = ?",
+		"DELETE FROM weight_reminder_state WHERE user_id = ?",
+		"DELETE FROM sleep_logs WHERE user_id = ?",
+		"DELETE FROM glucose_logs WHERE user_id = ?",
+		"DELETE FROM symptom_logs WHERE user_id = ?",
+		"DELETE FROM lab_results WHERE user_id = ?",
+		"DELETE FROM life_events WHERE user_id = ?",
+		"DELETE FROM injection_log WHERE user_id = ?",
+		"DELETE FROM workout_exercise_logs WHERE session_id IN (SELECT id FROM workout_sessions WHERE user_id = ?)",
+		"DELETE FROM workout_sessions WHERE user_id = ?",
+		"DELETE FROM workout_exercises WHERE variant_id IN (SELECT v.id FROM workout_variants v JOIN workout_groups g ON g.id = v.group_id WHERE g.user_id = ?)",
+		"DELETE FROM workout_rotation_state WHERE group_id IN (SELECT id FROM workout_groups WHERE user_id = ?)",
+		"DELETE FROM workout_schedule_snapshots WHERE group_id IN (SELECT id FROM workout_groups WHERE user_id = ?)",
+		"DELETE FROM workout_variants WHERE group_id IN (SELECT id FROM workout_groups WHERE user_id = ?)",
+		"DELETE FROM workout_groups WHERE user_id = ?",
+		"DELETE FROM workout_skip_dates WHERE user_id = ?",
+		"DELETE FROM push_subscriptions WHERE user_id = ?",
+		"DELETE FROM push_retry_queue WHERE user_id = ?",
+		"DELETE FROM notification_log WHERE user_id = ?",
+		"DELETE FROM webhooks WHERE user_id = ?",
+		"DELETE FROM reminder_templates WHERE user_id = ?",
+		"DELETE FROM schedule_slots WHERE user_id = ?",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, userID); err != nil {
+			return err
+		}
+	}
+
+	// Settings is a single row; recreating it brings back every default
+	if _, err := tx.Exec("DELETE FROM settings"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO settings (id) VALUES (1)"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestResetUserData(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	userID := int64(123456)
+	now := time.Now()
+
+	medID, _ := s.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	intakeID, _ := s.CreateIntake(medID, userID, now.Add(-time.Hour))
+	s.AddIntakeReminder(intakeID, 42)
+	s.CreateBloodPressureReading(ctx, &BloodPressure{UserID: userID, MeasuredAt: now, Systolic: 120, Diastolic: 80})
+	s.CreateWeightLog(ctx, &WeightLog{UserID: userID, MeasuredAt: now, Weight: 80})
+	s.CreateGlucoseLog(ctx, &GlucoseLog{UserID: userID, MeasuredAt: now, ValueMgdl: 100})
+	group, _ := s.CreateWorkoutGroup("Strength", "", false, userID, "[1]", "09:00", 15)
+	variant, _ := s.CreateWorkoutVariant(group.ID, "Default", nil, "")
+	session, _ := s.CreateWorkoutSession(group.ID, variant.ID, userID, now, "09:00")
+	s.LogExercise(session.ID, 0, "Squat", nil, nil, nil, "completed", "")
+	s.CreatePushSubscription(userID, "https://push.example.com/1", "auth", "key")
+	s.CreateWebhook(userID, "https://hooks.example.com", []string{"intake.confirmed"}, "secret")
+	s.SetReminderTemplate(userID, "Take {name}")
+	s.SetCurrency("EUR")
+	s.SetRetentionDays(90)
+	s.SetWeightGoal(75, now.AddDate(0, 3, 0))
+
+	// Another user's data must survive
+	s.CreateBloodPressureReading(ctx, &BloodPressure{UserID: 999, MeasuredAt: now, Systolic: 130, Diastolic: 85})
+
+	if err := s.ResetUserData(userID); err != nil {
+		t.Fatalf("ResetUserData failed: %v", err)
+	}
+
+	for _, table := range []string{"intake_log", "intake_reminders", "weight_logs", "glucose_logs", "workout_groups", "workout_variants", "workout_sessions", "workout_exercise_logs", "push_subscriptions", "webhooks", "reminder_templates"} {
+		var n int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatalf("Failed to count %s: %v", table, err)
+		}
+		if n != 0 {
+			t.Errorf("Expected %s to be empty, got %d rows", table, n)
+		}
+	}
+
+	readings, _ := s.GetBloodPressureReadings(ctx, 999, time.Time{}, 0, 0)
+	if len(readings) != 1 {
+		t.Errorf("Expected other user's reading to be kept, got %d", len(readings))
+	}
+	if med, _ := s.GetMedication(medID); med == nil {
+		t.Error("Expected medications to be kept")
+	}
+
+	// Settings are back to their defaults
+	if c, _ := s.GetCurrency(); c != DefaultCurrency {
+		t.Errorf("Expected default currency, got %q", c)
+	}
+	if d, _ := s.GetRetentionDays(); d != 0 {
+		t.Errorf("Expected retention disabled, got %d", d)
+	}
+	if g, _ := s.GetWeightGoal(); g != nil && g.Goal != nil {
+		t.Errorf("Expected no weight goal, got %+v", g)
+	}
+	if tmpl, _ := s.GetReminderTemplate(userID); tmpl != DefaultReminderTemplate {
+		t.Errorf("Expected default reminder template, got %q", tmpl)
+	}
+
+	// The settings row is usable again
+	if err := s.SetCurrency("GBP"); err != nil {
+		t.Fatalf("SetCurrency after reset failed: %v", err)
+	}
+	if c, _ := s.GetCurrency(); c != "GBP" {
+		t.Errorf("Expected GBP after reset and update, got %q", c)
+	}
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// MedicationResponseTime summarizes how quickly reminders for one medication are confirmed
+type MedicationResponseTime struct {
+	MedicationID   int64    `json:"medication_id"`
+	Name           string   `json:"name"`
+	Reminded       int      `json:"reminded"`                  // Intakes that got at least one reminder
+	Confirmed      int      `json:"confirmed"`                 // ...and were taken afterwards
+	Ignored        int      `json:"ignored"`                   // ...and were never taken
+	AverageMinutes *float64 `json:"average_minutes,omitempty"` // nil when nothing was confirmed
+	MedianMinutes  *float64 `json:"median_minutes,omitempty"`
+}
+
+// ResponseTimeStats is the reminder confirmation latency since a point in time
+type ResponseTimeStats struct {
+	Since          time.Time                `json:"since"`
+	AverageMinutes *float64                 `json:"average_minutes,omitempty"`
+	MedianMinutes  *float64                 `json:"median_minutes,omitempty"`
+	Medications    []MedicationResponseTime `json:"medications"` // Slowest median first
+}
+
+// GetResponseTimeStats measures, for intakes scheduled since the given time, the time
+// from the first reminder (normally the scheduled notification) to the confirmation.
+// Reminded doses that were never taken count as ignored once the grace period has passed.
+func (s *Store) GetResponseTimeStats(userID int64, since time.Time) (*ResponseTimeStats, error) {
+	rows, err := s.db.Query(`
+		SELECT il.id, il.medication_id, m.name, il.status, il.taken_at, ir.sent_at
+		FROM intake_log il
+		JOIN medications m ON m.id = il.medication_id
+		JOIN intake_reminders ir ON ir.intake_id = il.id
+		WHERE il.user_id = ? AND il.scheduled_at >= ? AND ir.sent_at IS NOT NULL`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type intakeReminder struct {
+		medID     int64
+		name      string
+		status    string
+		takenAt   *time.Time
+		firstSent time.Time
+	}
+	intakes := make(map[int64]*intakeReminder)
+	var order []int64
+
+	for rows.Next() {
+		var id, medID int64
+		var name, status string
+		var takenAt *time.Time
+		var sentAt time.Time
+		if err := rows.Scan(&id, &medID, &name, &status, &takenAt, &sentAt); err != nil {
+			return nil, err
+		}
+		ir, ok := intakes[id]
+		if !ok {
+			ir = &intakeReminder{medID: medID, name: name, status: status, takenAt: takenAt, firstSent: sentAt}
+			intakes[id] = ir
+			order = append(order, id)
+		} else if sentAt.Before(ir.firstSent) {
+			ir.firstSent = sentAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	byMed := make(map[int64]*MedicationResponseTime)
+	latencies := make(map[int64][]float64)
+	var all []float64
+	var medOrder []int64
+
+	now := nowFunc()
+	for _, id := range order {
+		ir := intakes[id]
+		// A fresh reminder may still be answered
+		if ir.status == "PENDING" && now.Sub(ir.firstSent) < adherenceGracePeriod {
+			continue
+		}
+
+		mrt, ok := byMed[ir.medID]
+		if !ok {
+			mrt = &MedicationResponseTime{MedicationID: ir.medID, Name: ir.name}
+			byMed[ir.medID] = mrt
+			medOrder = append(medOrder, ir.medID)
+		}
+		mrt.Reminded++
+
+		if ir.status != "TAKEN" || ir.takenAt == nil {
+			mrt.Ignored++
+			continue
+		}
+		mrt.Confirmed++
+		minutes := ir.takenAt.Sub(ir.firstSent).Minutes()
+		latencies[ir.medID] = append(latencies[ir.medID], minutes)
+		all = append(all, minutes)
+	}
+
+	stats := &ResponseTimeStats{Since: since, Medications: []MedicationResponseTime{}}
+	stats.AverageMinutes, stats.MedianMinutes = averageAndMedian(all)
+	for _, medID := range medOrder {
+		mrt := byMed[medID]
+		mrt.AverageMinutes, mrt.MedianMinutes = averageAndMedian(latencies[medID])
+		stats.Medications = append(stats.Medications, *mrt)
+	}
+
+	sort.SliceStable(stats.Medications, func(i, j int) bool {
+		a, b := stats.Medications[i].MedianMinutes, stats.Medications[j].MedianMinutes
+		if (a == nil) != (b == nil) {
+			return b == nil
+		}
+		if a != nil && *a != *b {
+			return *a > *b
+		}
+		return stats.Medications[i].Name < stats.Medications[j].Name
+	})
+
+	return stats, nil
+}
+
+// averageAndMedian returns both rounded to one decimal, or nils for no values
+func averageAndMedian(values []float64) (*float64, *float64) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	avg := math.Round(sum/float64(len(sorted))*10) / 10
+
+	mid := len(sorted) / 2
+	median := sorted[mid]
+	if len(sorted)%2 == 0 {
+		median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+	median = math.Round(median*10) / 10
+
+	return &avg, &median
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetResponseTimeStats(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	fixedNow := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	t.Cleanup(func() { nowFunc = origNow })
+
+	userID := int64(1)
+	fastID, _ := s.CreateMedication("Fast", "1mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	slowID, _ := s.CreateMedication("Slow", "1mg", `{"type":"daily","times":["20:00"]}`, nil, nil, "", "")
+
+	// remind records a reminder sent at the given time
+	remind := func(intakeID int64, at time.Time) {
+		t.Helper()
+		nowFunc = func() time.Time { return at }
+		if err := s.AddIntakeReminder(intakeID, int(at.Unix()%100000)); err != nil {
+			t.Fatalf("AddIntakeReminder failed: %v", err)
+		}
+	}
+
+	// Fast: reminded at 09:00, confirmed 5, 15 and 10 minutes later
+	for i, delay := range []int{5, 15, 10} {
+		scheduled := time.Date(2024, 3, 1+i, 8, 0, 0, 0, time.UTC)
+		id, _ := s.CreateIntake(fastID, userID, scheduled)
+		sent := scheduled.Add(time.Hour)
+		remind(id, sent)
+		remind(id, sent.Add(time.Hour)) // A later reminder must not shorten the latency
+		taken := sent.Add(time.Duration(delay) * time.Minute)
+		s.ConfirmIntake(id, taken)
+		s.AddIntakeNoteMessage(id, 1) // Confirmation message, not a reminder
+	}
+
+	// Slow: confirmed 90 minutes after the reminder, and one dose ignored
+	scheduled := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+	id, _ := s.CreateIntake(slowID, userID, scheduled)
+	remind(id, scheduled.Add(time.Hour))
+	s.ConfirmIntake(id, scheduled.Add(150*time.Minute))
+
+	scheduled = time.Date(2024, 3, 2, 20, 0, 0, 0, time.UTC)
+	id, _ = s.CreateIntake(slowID, userID, scheduled)
+	remind(id, scheduled.Add(time.Hour))
+	s.MarkIntakeMissed(id, "")
+
+	// Pending with a fresh reminder: not counted yet
+	scheduled = fixedNow.Add(-70 * time.Minute)
+	id, _ = s.CreateIntake(slowID, userID, scheduled)
+	remind(id, fixedNow.Add(-10*time.Minute))
+
+	// Taken without any reminder: not part of the stats
+	id, _ = s.CreateIntake(fastID, userID, time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC))
+	s.ConfirmIntake(id, time.Date(2024, 3, 5, 8, 2, 0, 0, time.UTC))
+
+	nowFunc = func() time.Time { return fixedNow }
+	stats, err := s.GetResponseTimeStats(userID, fixedNow.AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("GetResponseTimeStats failed: %v", err)
+	}
+
+	if len(stats.Medications) != 2 {
+		t.Fatalf("Expected 2 medications, got %d", len(stats.Medications))
+	}
+
+	slow, fast := stats.Medications[0], stats.Medications[1]
+	if slow.Name != "Slow" {
+		t.Fatalf("Expected slowest medication first, got %s", slow.Name)
+	}
+	if slow.Reminded != 2 || slow.Confirmed != 1 || slow.Ignored != 1 {
+		t.Errorf("Unexpected Slow counts: %+v", slow)
+	}
+	if slow.MedianMinutes == nil || *slow.MedianMinutes != 90 || *slow.AverageMinutes != 90 {
+		t.Errorf("Expected 90 minute latency for Slow, got %+v", slow)
+	}
+
+	if fast.Reminded != 3 || fast.Confirmed != 3 || fast.Ignored != 0 {
+		t.Errorf("Unexpected Fast counts: %+v", fast)
+	}
+	if fast.MedianMinutes == nil || *fast.MedianMinutes != 10 || *fast.AverageMinutes != 10 {
+		t.Errorf("Expected 10 minute latency for Fast, got avg %v median %v", fast.AverageMinutes, fast.MedianMinutes)
+	}
+
+	// Overall: 5, 10, 15, 90
+	if *stats.MedianMinutes != 12.5 || *stats.AverageMinutes != 30 {
+		t.Errorf("Unexpected overall latency: avg %v median %v", *stats.AverageMinutes, *stats.MedianMinutes)
+	}
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// PurgeResult counts the records removed by PurgeOlderThan
+type PurgeResult struct {
+	Intakes       int64 `json:"intakes"`
+	BloodPressure int64 `json:"blood_pressure"`
+	Weight        int64 `json:"weight"`
+	Sleep         int64 `json:"sleep"`
+}
+
+// GetRetentionDays returns the configured retention window in days (0 = keep everything)
+func (s *Store) GetRetentionDays() (int, error) {
+	var days sql.NullInt64
+	err := s.db.QueryRow("SELECT retention_days FROM settings WHERE id = 1").Scan(&days)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(days.Int64), nil
+}
+
+// SetRetentionDays stores the retention window; 0 disables purging
+func (s *Store) SetRetentionDays(days int) error {
+	if days < 0 {
+		return fmt.Errorf("retention days must not be negative")
+	}
+	var value interface{}
+	if days > 0 {
+		value = days
+	}
+	_, err := s.db.Exec("UPDATE settings SET retention_days = ? WHERE id = 1", value)
+	return err
+}
+
+// PurgeOlderThan deletes the user's intakes, BP readings, weight logs and sleep logs
+// recorded before the retention window. Medications and goals are kept.
+func (s *Store) PurgeOlderThan(userID int64, days int) (*PurgeResult, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("retention window must be at least 1 day")
+	}
+	cutoff := nowFunc().AddDate(0, 0, -days)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Message references go first so they don't outlive their intakes
+	for _, table := range []string{"intake_reminders", "intake_note_messages"} {
+		if _, err := tx.Exec(`
+			DELETE FROM `+table+` WHERE intake_id IN (
+				SELECT id FROM intake_log WHERE user_id = ? AND scheduled_at < ?
+			)`, userID, cutoff); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &PurgeResult{}
+	purges := []struct {
+		query string
+		count *int64
+	}{
+		{"DELETE FROM intake_log WHERE user_id = ? AND scheduled_at < ?", &result.Intakes},
+		{"DELETE FROM blood_pressure_readings WHERE user_id = ? AND measured_at < ?", &result.BloodPressure},
+		{"DELETE FROM weight_logs WHERE user_id = ? AND measured_at < ?", &result.Weight},
+		{"DELETE FROM sleep_logs WHERE user_id = ? AND start_time < ?", &result.Sleep},
+	}
+	for _, p := range purges {
+		res, err := tx.Exec(p.query, userID, cutoff)
+		if err != nil {
+			return nil, err
+		}
+		if *p.count, err = res.RowsAffected(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
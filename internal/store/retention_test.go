@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPurgeOlderThan(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	userID := int64(1)
+	now := time.Now()
+	old := now.AddDate(0, 0, -40)
+	recent := now.AddDate(0, 0, -5)
+
+	medID, _ := s.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	for _, ts := range []time.Time{old, recent} {
+		if _, err := s.CreateIntake(medID, userID, ts); err != nil {
+			t.Fatalf("CreateIntake failed: %v", err)
+		}
+		if _, err := s.CreateBloodPressureReading(ctx, &BloodPressure{UserID: userID, MeasuredAt: ts, Systolic: 120, Diastolic: 80}); err != nil {
+			t.Fatalf("CreateBloodPressureReading failed: %v", err)
+		}
+		if _, err := s.CreateWeightLog(ctx, &WeightLog{UserID: userID, MeasuredAt: ts, Weight: 80}); err != nil {
+			t.Fatalf("CreateWeightLog failed: %v", err)
+		}
+		if _, _, err := s.ImportSleepLogs(ctx, userID, []SleepLog{{StartTime: ts, EndTime: ts.Add(8 * time.Hour), Day: ts.Format("2006-01-02")}}); err != nil {
+			t.Fatalf("ImportSleepLogs failed: %v", err)
+		}
+	}
+	// Another user's old data is untouched
+	s.CreateBloodPressureReading(ctx, &BloodPressure{UserID: 2, MeasuredAt: old, Systolic: 130, Diastolic: 85})
+	s.SetWeightGoal(75, now.AddDate(0, 3, 0))
+
+	result, err := s.PurgeOlderThan(userID, 30)
+	if err != nil {
+		t.Fatalf("PurgeOlderThan failed: %v", err)
+	}
+	if result.Intakes != 1 || result.BloodPressure != 1 || result.Weight != 1 || result.Sleep != 1 {
+		t.Errorf("Expected one record of each kind purged, got %+v", result)
+	}
+
+	intakes, _ := s.GetIntakesSince(time.Time{})
+	if len(intakes) != 1 || !intakes[0].ScheduledAt.Equal(recent) {
+		t.Errorf("Expected only the recent intake to remain, got %+v", intakes)
+	}
+	readings, _ := s.GetBloodPressureReadings(ctx, userID, time.Time{}, 0, 0)
+	if len(readings) != 1 {
+		t.Errorf("Expected 1 BP reading left, got %d", len(readings))
+	}
+	weights, _ := s.GetWeightLogs(ctx, userID, time.Time{})
+	if len(weights) != 1 {
+		t.Errorf("Expected 1 weight log left, got %d", len(weights))
+	}
+	sleeps, _ := s.GetSleepLogs(ctx, userID, time.Time{})
+	if len(sleeps) != 1 {
+		t.Errorf("Expected 1 sleep log left, got %d", len(sleeps))
+	}
+	others, _ := s.GetBloodPressureReadings(ctx, 2, time.Time{}, 0, 0)
+	if len(others) != 1 {
+		t.Errorf("Expected other user's reading to remain, got %d", len(others))
+	}
+
+	// Medications and goals are kept
+	if med, _ := s.GetMedication(medID); med == nil {
+		t.Error("Expected medication to be kept")
+	}
+	if goal, _ := s.GetWeightGoal(); goal.Goal == nil {
+		t.Error("Expected weight goal to be kept")
+	}
+
+	if _, err := s.PurgeOlderThan(userID, 0); err == nil {
+		t.Error("Expected error for empty retention window")
+	}
+}
+
+func TestRetentionDaysSetting(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	if days, err := s.GetRetentionDays(); err != nil || days != 0 {
+		t.Fatalf("Expected retention disabled by default, got %d (%v)", days, err)
+	}
+	if err := s.SetRetentionDays(90); err != nil {
+		t.Fatalf("SetRetentionDays failed: %v", err)
+	}
+	if days, _ := s.GetRetentionDays(); days != 90 {
+		t.Errorf("Expected 90, got %d", days)
+	}
+	if err := s.SetRetentionDays(-1); err == nil {
+		t.Error("Expected error for negative retention")
+	}
+}
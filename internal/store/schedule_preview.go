@@ -0,0 +1,212 @@
+package store
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Notification channels reported by PreviewSchedule
+const (
+	ChannelTelegram = "telegram"
+	ChannelWebPush  = "web_push"
+)
+
+// PreviewMedication is one medication included in a previewed notification
+type PreviewMedication struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Dosage string `json:"dosage,omitempty"`
+}
+
+// ScheduledNotification is a notification the scheduler would send
+type ScheduledNotification struct {
+	At          time.Time           `json:"at"`
+	Type        string              `json:"type"` // "medication" or "workout"
+	UserID      int64               `json:"user_id"`
+	Channels    []string            `json:"channels"`
+	Medications []PreviewMedication `json:"medications,omitempty"`
+	// Workout notifications only
+	GroupID     int64      `json:"group_id,omitempty"`
+	GroupName   string     `json:"group_name,omitempty"`
+	VariantName string     `json:"variant_name,omitempty"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+}
+
+// PreviewSchedule lists the notifications the scheduler would send in [from, from+days),
+// without creating intakes or sending anything. Doses due at the same time are grouped
+// into one notification like the scheduler does; out-of-stock medications are left out
+// because their doses are marked missed instead. Workout notifications fire
+// notification_advance_minutes before the session, using the current rotation variant.
+func (s *Store) PreviewSchedule(userID int64, from time.Time, days int) ([]ScheduledNotification, error) {
+	until := from.AddDate(0, 0, days)
+	loc := from.Location()
+	startDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+
+	subs, err := s.GetPushSubscriptions(userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := s.GetReminderChannels()
+	if err != nil {
+		return nil, err
+	}
+	channelsFor := func(reminderType string) []string {
+		var channels []string
+		if prefs[reminderType].Telegram {
+			channels = append(channels, ChannelTelegram)
+		}
+		if prefs[reminderType].WebPush && len(subs) > 0 {
+			channels = append(channels, ChannelWebPush)
+		}
+		return channels
+	}
+	medChannels := channelsFor(ReminderMedication)
+	workoutChannels := channelsFor(ReminderWorkout)
+
+	inWindow := func(t time.Time) bool {
+		return !t.Before(from) && t.Before(until)
+	}
+
+	var result []ScheduledNotification
+
+	// Medications, grouped by target time
+	meds, err := s.ListMedications(false)
+	if err != nil {
+		return nil, err
+	}
+	byTarget := make(map[int64]*ScheduledNotification)
+	for _, m := range meds {
+		// Nothing is sent for out-of-stock medications or when medication reminders are silenced
+		if (m.InventoryCount != nil && *m.InventoryCount <= 0) || len(medChannels) == 0 {
+			continue
+		}
+		cfg, err := s.ExpandSchedule(userID, &m)
+		if err != nil || cfg.Type == "as_needed" {
+			continue
+		}
+
+		for day := startDay; day.Before(until); day = day.AddDate(0, 0, 1) {
+			if cfg.Type == "weekly" && !containsDay(cfg.Days, int(day.Weekday())) {
+				continue
+			}
+			for _, ts := range cfg.Times {
+				t, err := time.Parse("15:04", ts)
+				if err != nil {
+					continue
+				}
+				target := time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+				if !inWindow(target) {
+					continue
+				}
+				if m.StartDate != nil && target.Before(*m.StartDate) {
+					continue
+				}
+				if m.EndDate != nil && target.After(*m.EndDate) {
+					continue
+				}
+
+				n, ok := byTarget[target.Unix()]
+				if !ok {
+					n = &ScheduledNotification{At: target, Type: "medication", UserID: userID, Channels: medChannels}
+					byTarget[target.Unix()] = n
+				}
+				n.Medications = append(n.Medications, PreviewMedication{ID: m.ID, Name: m.Name, Dosage: m.Dosage})
+			}
+		}
+	}
+	for _, n := range byTarget {
+		result = append(result, *n)
+	}
+
+	// Workouts
+	groups, err := s.ListWorkoutGroups(userID, true)
+	if err != nil {
+		return nil, err
+	}
+	skipList, err := s.ListWorkoutSkipDates(userID, startDay.AddDate(0, 0, -1).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	skipDates := make(map[string]bool, len(skipList))
+	for _, d := range skipList {
+		skipDates[d.Date] = true
+	}
+	for _, g := range groups {
+		if len(workoutChannels) == 0 {
+			break // Workout reminders are silenced
+		}
+		var daysOfWeek []int
+		if err := json.Unmarshal([]byte(g.DaysOfWeek), &daysOfWeek); err != nil {
+			continue
+		}
+		t, err := time.Parse("15:04", g.ScheduledTime)
+		if err != nil {
+			continue
+		}
+		variant, err := s.previewVariant(g)
+		if err != nil {
+			return nil, err
+		}
+		if variant == nil {
+			continue // The scheduler skips groups without variants
+		}
+
+		// The advance notice can fall on the day before the window starts
+		for day := startDay.AddDate(0, 0, -1); day.Before(until); day = day.AddDate(0, 0, 1) {
+			if !containsDay(daysOfWeek, int(day.Weekday())) || skipDates[day.Format("2006-01-02")] {
+				continue
+			}
+			startsAt := time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+			notifyAt := startsAt.Add(-time.Duration(g.NotificationAdvanceMinutes) * time.Minute)
+			if !inWindow(notifyAt) {
+				continue
+			}
+			result = append(result, ScheduledNotification{
+				At:          notifyAt,
+				Type:        "workout",
+				UserID:      userID,
+				Channels:    workoutChannels,
+				GroupID:     g.ID,
+				GroupName:   g.Name,
+				VariantName: variant.Name,
+				StartsAt:    &startsAt,
+			})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].At.Equal(result[j].At) {
+			return result[i].At.Before(result[j].At)
+		}
+		return result[i].Type > result[j].Type // Medications first
+	})
+	for _, n := range result {
+		sort.Slice(n.Medications, func(i, j int) bool { return n.Medications[i].Name < n.Medications[j].Name })
+	}
+
+	return result, nil
+}
+
+// previewVariant picks the variant the scheduler would use next: the current rotation
+// variant for rotating groups, otherwise the first one. Nil if the group has none.
+func (s *Store) previewVariant(g WorkoutGroup) (*WorkoutVariant, error) {
+	variants, err := s.ListVariantsByGroup(g.ID)
+	if err != nil || len(variants) == 0 {
+		return nil, err
+	}
+	if g.IsRotating {
+		state, err := s.GetRotationState(g.ID)
+		if err != nil {
+			return nil, err
+		}
+		if state != nil {
+			for _, v := range variants {
+				if v.ID == state.CurrentVariantID {
+					return &v, nil
+				}
+			}
+		}
+	}
+	return &variants[0], nil
+}
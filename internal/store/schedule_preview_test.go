@@ -0,0 +1,129 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPreviewSchedule_MixedSchedules(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	const userID = int64(1)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	// Wednesday noon, so the window runs until Saturday noon
+	from := at(7, 12, 0)
+
+	s.CreateMedication("Amlodipine", "5mg", `{"type":"daily","times":["08:00","20:00"]}`, nil, nil, "", "")
+	s.CreateMedication("Bisoprolol", "2.5mg", `{"type":"weekly","days":[5],"times":["08:00"]}`, nil, nil, "", "")
+	start := at(9, 0, 0)
+	s.CreateMedication("Cetirizine", "10mg", `{"type":"daily","times":["09:00"]}`, &start, nil, "", "")
+	end := at(8, 23, 59)
+	s.CreateMedication("Doxycycline", "100mg", `{"type":"daily","times":["20:00"]}`, nil, &end, "", "")
+	s.CreateMedication("Ibuprofen", "400mg", `{"type":"as_needed"}`, nil, nil, "", "")
+	emptyID, _ := s.CreateMedication("Metformin", "500mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	zero := 0.0
+	if err := s.SetInventory(emptyID, &zero); err != nil {
+		t.Fatalf("SetInventory failed: %v", err)
+	}
+
+	// Rotating group on Thursday and Saturday at 10:00, notified 30 minutes early
+	group, err := s.CreateWorkoutGroup("Strength", "", true, userID, "[4,6]", "10:00", 30)
+	if err != nil {
+		t.Fatalf("CreateWorkoutGroup failed: %v", err)
+	}
+	orderA, orderB := 1, 2
+	s.CreateWorkoutVariant(group.ID, "Day A", &orderA, "")
+	dayB, _ := s.CreateWorkoutVariant(group.ID, "Day B", &orderB, "")
+	if err := s.InitializeRotation(group.ID, dayB.ID); err != nil {
+		t.Fatalf("InitializeRotation failed: %v", err)
+	}
+	// Groups without variants are skipped by the scheduler
+	if _, err := s.CreateWorkoutGroup("Cardio", "", false, userID, "[0,1,2,3,4,5,6]", "07:00", 15); err != nil {
+		t.Fatalf("CreateWorkoutGroup failed: %v", err)
+	}
+
+	if err := s.CreatePushSubscription(userID, "https://push.example/1", "auth", "key"); err != nil {
+		t.Fatalf("CreatePushSubscription failed: %v", err)
+	}
+
+	got, err := s.PreviewSchedule(userID, from, 3)
+	if err != nil {
+		t.Fatalf("PreviewSchedule failed: %v", err)
+	}
+
+	type occurrence struct {
+		At   time.Time
+		What []string // Medication names, or the workout variant
+	}
+	want := []occurrence{
+		{at(7, 20, 0), []string{"Amlodipine", "Doxycycline"}},
+		{at(8, 8, 0), []string{"Amlodipine"}},
+		{at(8, 9, 30), []string{"Day B"}},
+		{at(8, 20, 0), []string{"Amlodipine", "Doxycycline"}},
+		{at(9, 8, 0), []string{"Amlodipine", "Bisoprolol"}},
+		{at(9, 9, 0), []string{"Cetirizine"}},
+		{at(9, 20, 0), []string{"Amlodipine"}},
+		{at(10, 8, 0), []string{"Amlodipine"}},
+		{at(10, 9, 0), []string{"Cetirizine"}},
+		{at(10, 9, 30), []string{"Day B"}},
+	}
+
+	var occurrences []occurrence
+	for _, n := range got {
+		o := occurrence{At: n.At}
+		switch n.Type {
+		case "medication":
+			for _, m := range n.Medications {
+				o.What = append(o.What, m.Name)
+			}
+		case "workout":
+			o.What = []string{n.VariantName}
+			if n.GroupName != "Strength" || n.StartsAt == nil || !n.StartsAt.Equal(n.At.Add(30*time.Minute)) {
+				t.Errorf("Unexpected workout notification: %+v", n)
+			}
+		}
+		if !reflect.DeepEqual(n.Channels, []string{ChannelTelegram, ChannelWebPush}) || n.UserID != userID {
+			t.Errorf("Unexpected recipients for %s: user %d, channels %v", n.At, n.UserID, n.Channels)
+		}
+		occurrences = append(occurrences, o)
+	}
+
+	if !reflect.DeepEqual(occurrences, want) {
+		t.Errorf("Preview mismatch\n got: %v\nwant: %v", occurrences, want)
+	}
+}
+
+func TestPreviewSchedule_AdvanceNoticeCrossesWindowStart(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	// Monday 00:10 session notified an hour early, on Sunday evening
+	group, _ := s.CreateWorkoutGroup("Swim", "", false, 1, "[1]", "00:10", 60)
+	s.CreateWorkoutVariant(group.ID, "Default", nil, "")
+
+	from := time.Date(2026, 1, 4, 23, 0, 0, 0, time.UTC) // Sunday
+	got, err := s.PreviewSchedule(1, from, 1)
+	if err != nil {
+		t.Fatalf("PreviewSchedule failed: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Expected 1 notification, got %d: %+v", len(got), got)
+	}
+	if want := time.Date(2026, 1, 4, 23, 10, 0, 0, time.UTC); !got[0].At.Equal(want) {
+		t.Errorf("Expected notification at %s, got %s", want, got[0].At)
+	}
+	if !reflect.DeepEqual(got[0].Channels, []string{ChannelTelegram}) {
+		t.Errorf("Expected telegram only without push subscriptions, got %v", got[0].Channels)
+	}
+}
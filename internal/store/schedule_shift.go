@@ -0,0 +1,61 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ShiftScheduleTime moves one of the medication's dose times by delta and saves the
+// schedule, returning the new HH:MM time. Only times listed in the schedule itself can
+// move (slot times are shared by other medications), a dose can't cross midnight and
+// can't land on another of its times.
+func (s *Store) ShiftScheduleTime(medID int64, from string, delta time.Duration) (string, error) {
+	m, err := s.GetMedication(medID)
+	if err != nil {
+		return "", err
+	}
+	if m == nil {
+		return "", fmt.Errorf("medication %d not found", medID)
+	}
+	cfg, err := m.ValidSchedule()
+	if err != nil {
+		return "", err
+	}
+
+	idx := -1
+	for i, t := range cfg.Times {
+		if t == from {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return "", fmt.Errorf("%s is not one of the schedule's own times", from)
+	}
+
+	t, err := time.Parse("15:04", from)
+	if err != nil {
+		return "", err
+	}
+	shifted := t.Add(delta)
+	if shifted.Day() != t.Day() {
+		return "", fmt.Errorf("can't move %s past midnight", from)
+	}
+	to := shifted.Format("15:04")
+	for _, existing := range cfg.Times {
+		if existing == to {
+			return "", fmt.Errorf("already scheduled at %s", to)
+		}
+	}
+
+	cfg.Times[idx] = to
+	cfg.Normalize()
+	schedule, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.db.Exec("UPDATE medications SET schedule = ? WHERE id = ?", string(schedule), medID); err != nil {
+		return "", err
+	}
+	return to, nil
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSlotTimes are used for slots the user has not configured
+var DefaultSlotTimes = map[string]string{
+	"morning": "08:00",
+	"noon":    "12:00",
+	"evening": "18:00",
+	"bedtime": "22:00",
+}
+
+// GetSlotTimes returns the user's slot name -> "HH:MM" mapping, including defaults
+func (s *Store) GetSlotTimes(userID int64) (map[string]string, error) {
+	slots := make(map[string]string, len(DefaultSlotTimes))
+	for name, t := range DefaultSlotTimes {
+		slots[name] = t
+	}
+
+	rows, err := s.db.Query("SELECT name, time FROM schedule_slots WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, t string
+		if err := rows.Scan(&name, &t); err != nil {
+			return nil, err
+		}
+		slots[name] = t
+	}
+	return slots, rows.Err()
+}
+
+// SetSlotTimes stores clock times for the given slots. Slots not mentioned keep their current time.
+func (s *Store) SetSlotTimes(userID int64, slots map[string]string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for name, t := range slots {
+		name = normalizeSlotName(name)
+		if name == "" {
+			return fmt.Errorf("slot name is required")
+		}
+		if _, err := time.Parse("15:04", t); err != nil || len(t) != 5 {
+			return fmt.Errorf("invalid time %q for slot %s, expected HH:MM", t, name)
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO schedule_slots (user_id, name, time, updated_at)
+			VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT(user_id, name) DO UPDATE SET
+				time = excluded.time,
+				updated_at = CURRENT_TIMESTAMP`,
+			userID, name, t)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ExpandSchedule parses the medication's schedule and resolves any slot references
+// to the user's current slot times
+func (s *Store) ExpandSchedule(userID int64, m *Medication) (*ScheduleConfig, error) {
+	cfg, err := m.ValidSchedule()
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Slots) == 0 {
+		return cfg, nil
+	}
+
+	slotTimes, err := s.GetSlotTimes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ResolveSlots(slotTimes); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ResolveSlots merges the clock times of the referenced slots into Times (sorted, without duplicates)
+func (c *ScheduleConfig) ResolveSlots(slotTimes map[string]string) error {
+	seen := make(map[string]bool, len(c.Times))
+	for _, t := range c.Times {
+		seen[t] = true
+	}
+
+	for _, slot := range c.Slots {
+		t, ok := slotTimes[normalizeSlotName(slot)]
+		if !ok {
+			return fmt.Errorf("unknown schedule slot %q", slot)
+		}
+		if !seen[t] {
+			seen[t] = true
+			c.Times = append(c.Times, t)
+		}
+	}
+
+	sort.Strings(c.Times)
+	return nil
+}
+
+func normalizeSlotName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandSchedule_Slots(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	userID := int64(1)
+	id, _ := db.CreateMedication("Metformin", "500mg", `{"type":"daily","slots":["morning","Evening"],"times":["13:00"]}`, nil, nil, "", "")
+	med, err := db.GetMedication(id)
+	if err != nil {
+		t.Fatalf("GetMedication: %v", err)
+	}
+
+	cfg, err := db.ExpandSchedule(userID, med)
+	if err != nil {
+		t.Fatalf("ExpandSchedule: %v", err)
+	}
+	if want := []string{"08:00", "13:00", "18:00"}; !reflect.DeepEqual(cfg.Times, want) {
+		t.Errorf("expected default slot times %v, got %v", want, cfg.Times)
+	}
+
+	// Moving "morning" shifts the medication without editing it
+	if err := db.SetSlotTimes(userID, map[string]string{"morning": "07:15"}); err != nil {
+		t.Fatalf("SetSlotTimes: %v", err)
+	}
+	cfg, err = db.ExpandSchedule(userID, med)
+	if err != nil {
+		t.Fatalf("ExpandSchedule: %v", err)
+	}
+	if want := []string{"07:15", "13:00", "18:00"}; !reflect.DeepEqual(cfg.Times, want) {
+		t.Errorf("expected updated slot times %v, got %v", want, cfg.Times)
+	}
+
+	slots, err := db.GetSlotTimes(userID)
+	if err != nil {
+		t.Fatalf("GetSlotTimes: %v", err)
+	}
+	if slots["morning"] != "07:15" || slots["evening"] != "18:00" {
+		t.Errorf("unexpected slot times: %v", slots)
+	}
+
+	// Other users keep the defaults
+	other, _ := db.GetSlotTimes(2)
+	if other["morning"] != "08:00" {
+		t.Errorf("expected other user to keep default morning, got %s", other["morning"])
+	}
+}
+
+func TestDaysOfStockRemaining_SlotSharingATime(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	// "morning" resolves to 08:00, which is already listed, so it's one dose a day
+	id, _ := db.CreateMedication("Metformin", "500mg", `{"type":"daily","slots":["morning"],"times":["08:00"]}`, nil, nil, "", "")
+	count := 10.0
+	db.SetInventory(id, &count)
+	med, _ := db.GetMedication(id)
+
+	if days := db.GetDaysOfStockRemaining(1, med); days == nil || *days != 10 {
+		t.Errorf("expected 10 days of stock, got %v", days)
+	}
+}
+
+func TestSetSlotTimes_Validation(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SetSlotTimes(1, map[string]string{"morning": "8am"}); err == nil {
+		t.Error("expected invalid time to be rejected")
+	}
+
+	id, _ := db.CreateMedication("Med", "1", `{"type":"daily","slots":["lunch"]}`, nil, nil, "", "")
+	med, _ := db.GetMedication(id)
+	if _, err := db.ExpandSchedule(1, med); err == nil {
+		t.Error("expected unknown slot to fail expansion")
+	}
+
+	// Custom slots become available once configured
+	if err := db.SetSlotTimes(1, map[string]string{"Lunch": "12:30"}); err != nil {
+		t.Fatalf("SetSlotTimes: %v", err)
+	}
+	cfg, err := db.ExpandSchedule(1, med)
+	if err != nil {
+		t.Fatalf("ExpandSchedule: %v", err)
+	}
+	if len(cfg.Times) != 1 || cfg.Times[0] != "12:30" {
+		t.Errorf("expected lunch at 12:30, got %v", cfg.Times)
+	}
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Validate reports the first problem with the schedule: an unknown type, times that
+// aren't HH:MM, days outside 0-6, or a daily/weekly schedule with nothing to take
+func (c *ScheduleConfig) Validate() error {
+	switch c.Type {
+	case "daily", "weekly", "as_needed":
+	case "":
+		return fmt.Errorf("missing schedule type (want daily, weekly or as_needed)")
+	default:
+		return fmt.Errorf("unknown schedule type %q (want daily, weekly or as_needed)", c.Type)
+	}
+
+	for i, ts := range c.Times {
+		if _, err := time.Parse("15:04", ts); err != nil || len(ts) != 5 {
+			return fmt.Errorf("times[%d]: %q is not a valid HH:MM time", i, ts)
+		}
+	}
+	for i, d := range c.Days {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("days[%d]: %d is out of range (0=Sunday ... 6=Saturday)", i, d)
+		}
+	}
+	if c.SeparateHours < 0 {
+		return fmt.Errorf("separate_hours must not be negative")
+	}
+
+	if c.Type == "as_needed" {
+		return nil
+	}
+	if len(c.Times) == 0 && len(c.Slots) == 0 {
+		return fmt.Errorf("%s schedule needs at least one time or slot", c.Type)
+	}
+	if c.Type == "weekly" && len(c.Days) == 0 {
+		return fmt.Errorf("weekly schedule needs at least one day")
+	}
+	return nil
+}
+
+// Normalize sorts times and days and drops duplicates; weekdays only matter for weekly schedules
+func (c *ScheduleConfig) Normalize() {
+	seenTimes := make(map[string]bool, len(c.Times))
+	var times []string
+	for _, t := range c.Times {
+		if !seenTimes[t] {
+			seenTimes[t] = true
+			times = append(times, t)
+		}
+	}
+	sort.Strings(times)
+	c.Times = times
+
+	if c.Type != "weekly" {
+		c.Days = nil
+		return
+	}
+	seenDays := make(map[int]bool, len(c.Days))
+	var days []int
+	for _, d := range c.Days {
+		if !seenDays[d] {
+			seenDays[d] = true
+			days = append(days, d)
+		}
+	}
+	sort.Ints(days)
+	c.Days = days
+}
+
+// DailyUsage returns the average number of doses per day (0 for as-needed schedules).
+// Slots must already be resolved into Times.
+func (c *ScheduleConfig) DailyUsage() float64 {
+	timesPerDay := float64(len(c.Times))
+
+	switch c.Type {
+	case "daily":
+		return timesPerDay
+	case "weekly":
+		// Days per week that the medication is taken
+		return float64(len(c.Days)) / 7.0 * timesPerDay
+	}
+	return 0
+}
+
+// NextOccurrences returns up to n dose times at or after from. Slots must already be
+// resolved into Times; as-needed schedules have no occurrences.
+func (c *ScheduleConfig) NextOccurrences(from time.Time, n int) []time.Time {
+	result := []time.Time{}
+	if c.Type == "as_needed" || len(c.Times) == 0 {
+		return result
+	}
+
+	times := append([]string{}, c.Times...)
+	sort.Strings(times)
+
+	loc := from.Location()
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	// Each week holds at least one dose, so n+1 weeks is always enough
+	for i := 0; i <= 7*(n+1) && len(result) < n; i++ {
+		d := day.AddDate(0, 0, i)
+		if c.Type == "weekly" && !containsDay(c.Days, int(d.Weekday())) {
+			continue
+		}
+		for _, ts := range times {
+			t, err := time.Parse("15:04", ts)
+			if err != nil {
+				continue
+			}
+			target := time.Date(d.Year(), d.Month(), d.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+			if target.Before(from) {
+				continue
+			}
+			result = append(result, target)
+			if len(result) == n {
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+// minCorrelationPairs is the fewest paired days a correlation is computed from
+const minCorrelationPairs = 3
+
+// SleepBPPair joins one night's sleep with the BP readings of the day it ended
+type SleepBPPair struct {
+	Date         string  `json:"date"` // Day the sleep ended, YYYY-MM-DD
+	TotalMinutes *int    `json:"total_minutes,omitempty"`
+	DeepMinutes  *int    `json:"deep_minutes,omitempty"`
+	AvgSystolic  float64 `json:"avg_systolic"`
+	AvgDiastolic float64 `json:"avg_diastolic"`
+	Readings     int     `json:"readings"`
+}
+
+// SleepBPCorrelation holds Pearson coefficients between sleep minutes and next-day BP.
+// Negative values mean shorter nights go with higher BP. A coefficient is nil when
+// there are too few pairs or no variation.
+type SleepBPCorrelation struct {
+	Days                  int           `json:"days"`
+	Pairs                 []SleepBPPair `json:"pairs"`
+	TotalSleepSystolic    *float64      `json:"total_sleep_systolic"`
+	TotalSleepDiastolic   *float64      `json:"total_sleep_diastolic"`
+	DeepSleepSystolic     *float64      `json:"deep_sleep_systolic"`
+	DeepSleepDiastolic    *float64      `json:"deep_sleep_diastolic"`
+	NightsWithoutReadings int           `json:"nights_without_readings"`
+}
+
+// GetSleepBPCorrelation pairs each night of the last N days with the average BP of the
+// day it ended on. Nights without readings that day, and days without sleep data, are
+// left out. Several sleep logs ending on the same day (naps) are summed.
+func (s *Store) GetSleepBPCorrelation(ctx context.Context, userID int64, days int) (*SleepBPCorrelation, error) {
+	now := nowFunc()
+	loc := now.Location()
+	since := now.AddDate(0, 0, -days)
+
+	sleeps, err := s.GetSleepLogs(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	readings, err := s.GetBloodPressureReadings(ctx, userID, since, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	type sleepDay struct {
+		total, deep *int
+	}
+	nights := make(map[string]*sleepDay)
+	for _, sl := range sleeps {
+		day := sl.EndTime.In(loc).Format("2006-01-02")
+		sd, ok := nights[day]
+		if !ok {
+			sd = &sleepDay{}
+			nights[day] = sd
+		}
+		sd.total = addOptionalInt(sd.total, sl.TotalMinutes)
+		sd.deep = addOptionalInt(sd.deep, sl.DeepMinutes)
+	}
+
+	type bpDay struct {
+		sys, dia, n int
+	}
+	bpDays := make(map[string]*bpDay)
+	for _, bp := range readings {
+		day := bp.MeasuredAt.In(loc).Format("2006-01-02")
+		bd, ok := bpDays[day]
+		if !ok {
+			bd = &bpDay{}
+			bpDays[day] = bd
+		}
+		bd.sys += bp.Systolic
+		bd.dia += bp.Diastolic
+		bd.n++
+	}
+
+	result := &SleepBPCorrelation{Days: days, Pairs: []SleepBPPair{}}
+	for day, sd := range nights {
+		bd, ok := bpDays[day]
+		if !ok {
+			result.NightsWithoutReadings++
+			continue
+		}
+		result.Pairs = append(result.Pairs, SleepBPPair{
+			Date:         day,
+			TotalMinutes: sd.total,
+			DeepMinutes:  sd.deep,
+			AvgSystolic:  math.Round(float64(bd.sys)/float64(bd.n)*10) / 10,
+			AvgDiastolic: math.Round(float64(bd.dia)/float64(bd.n)*10) / 10,
+			Readings:     bd.n,
+		})
+	}
+	sort.Slice(result.Pairs, func(i, j int) bool { return result.Pairs[i].Date < result.Pairs[j].Date })
+
+	// Each coefficient uses the pairs that have the sleep value it needs
+	var totals, totalSys, totalDia, deeps, deepSys, deepDia []float64
+	for _, p := range result.Pairs {
+		if p.TotalMinutes != nil {
+			totals = append(totals, float64(*p.TotalMinutes))
+			totalSys = append(totalSys, p.AvgSystolic)
+			totalDia = append(totalDia, p.AvgDiastolic)
+		}
+		if p.DeepMinutes != nil {
+			deeps = append(deeps, float64(*p.DeepMinutes))
+			deepSys = append(deepSys, p.AvgSystolic)
+			deepDia = append(deepDia, p.AvgDiastolic)
+		}
+	}
+	result.TotalSleepSystolic = pearson(totals, totalSys)
+	result.TotalSleepDiastolic = pearson(totals, totalDia)
+	result.DeepSleepSystolic = pearson(deeps, deepSys)
+	result.DeepSleepDiastolic = pearson(deeps, deepDia)
+
+	return result, nil
+}
+
+// pearson returns the correlation coefficient of xs and ys rounded to three decimals,
+// or nil with fewer than minCorrelationPairs values or when either series is constant
+func pearson(xs, ys []float64) *float64 {
+	n := len(xs)
+	if n < minCorrelationPairs || n != len(ys) {
+		return nil
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return nil
+	}
+
+	r := math.Round(cov/math.Sqrt(varX*varY)*1000) / 1000
+	return &r
+}
+
+// addOptionalInt sums two optional values; nil only if both are nil
+func addOptionalInt(a, b *int) *int {
+	if b == nil {
+		return a
+	}
+	sum := *b
+	if a != nil {
+		sum += *a
+	}
+	return &sum
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetSleepBPCorrelation_PoorSleepPrecedesHigherBP(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := int64(1)
+
+	fixedNow := time.Date(2025, 6, 30, 20, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	ptr := func(v int) *int { return &v }
+	night := func(daysAgo, total, deep int) SleepLog {
+		end := time.Date(2025, 6, 30-daysAgo, 7, 0, 0, 0, time.UTC)
+		return SleepLog{
+			StartTime:    end.Add(-time.Duration(total) * time.Minute),
+			EndTime:      end,
+			Day:          end.AddDate(0, 0, -1).Format("2006-01-02"),
+			TotalMinutes: ptr(total),
+			DeepMinutes:  ptr(deep),
+		}
+	}
+	addBP := func(daysAgo, hour, sys, dia int) {
+		t.Helper()
+		_, err := db.CreateBloodPressureReading(ctx, &BloodPressure{
+			UserID:     userID,
+			MeasuredAt: time.Date(2025, 6, 30-daysAgo, hour, 0, 0, 0, time.UTC),
+			Systolic:   sys,
+			Diastolic:  dia,
+		})
+		if err != nil {
+			t.Fatalf("failed to insert reading: %v", err)
+		}
+	}
+
+	// Short nights are followed by higher readings
+	logs := []SleepLog{
+		night(6, 300, 40),
+		night(5, 480, 100),
+		night(4, 360, 60),
+		night(3, 450, 90),
+		night(2, 330, 50),
+		night(1, 420, 80),
+		night(0, 400, 70), // No reading yet today
+	}
+	if _, _, err := db.ImportSleepLogs(ctx, userID, logs); err != nil {
+		t.Fatalf("ImportSleepLogs failed: %v", err)
+	}
+	addBP(6, 8, 150, 96)
+	addBP(6, 20, 146, 94) // Day average 148/95
+	addBP(5, 9, 122, 78)
+	addBP(4, 9, 140, 90)
+	addBP(3, 9, 126, 80)
+	addBP(2, 9, 145, 92)
+	addBP(1, 9, 130, 84)
+	addBP(8, 9, 170, 100) // No sleep logged for that night
+
+	corr, err := db.GetSleepBPCorrelation(ctx, userID, 10)
+	if err != nil {
+		t.Fatalf("GetSleepBPCorrelation failed: %v", err)
+	}
+
+	if len(corr.Pairs) != 6 {
+		t.Fatalf("Expected 6 paired days, got %d: %+v", len(corr.Pairs), corr.Pairs)
+	}
+	if corr.NightsWithoutReadings != 1 {
+		t.Errorf("Expected 1 night without next-day readings, got %d", corr.NightsWithoutReadings)
+	}
+	first := corr.Pairs[0]
+	if first.Date != "2025-06-24" || *first.TotalMinutes != 300 || first.AvgSystolic != 148 || first.AvgDiastolic != 95 || first.Readings != 2 {
+		t.Errorf("Unexpected first pair: %+v", first)
+	}
+
+	// More sleep goes with lower BP, so sleep and BP are inversely related,
+	// which is the same as sleep loss and BP rising together
+	for name, r := range map[string]*float64{
+		"total/systolic":  corr.TotalSleepSystolic,
+		"total/diastolic": corr.TotalSleepDiastolic,
+		"deep/systolic":   corr.DeepSleepSystolic,
+		"deep/diastolic":  corr.DeepSleepDiastolic,
+	} {
+		if r == nil {
+			t.Errorf("%s: expected a coefficient, got nil", name)
+			continue
+		}
+		if *r > -0.9 {
+			t.Errorf("%s: expected a strong inverse relationship, got %.3f", name, *r)
+		}
+		if *r < -1 {
+			t.Errorf("%s: coefficient %.3f out of range", name, *r)
+		}
+	}
+}
+
+func TestPearson(t *testing.T) {
+	if r := pearson([]float64{1, 2, 3}, []float64{2, 4, 6}); r == nil || *r != 1 {
+		t.Errorf("Expected perfect positive correlation, got %v", r)
+	}
+	if r := pearson([]float64{1, 2, 3}, []float64{6, 4, 2}); r == nil || *r != -1 {
+		t.Errorf("Expected perfect negative correlation, got %v", r)
+	}
+	if r := pearson([]float64{1, 2}, []float64{1, 2}); r != nil {
+		t.Errorf("Expected nil with too few pairs, got %v", *r)
+	}
+	if r := pearson([]float64{1, 2, 3}, []float64{5, 5, 5}); r != nil {
+		t.Errorf("Expected nil for a constant series, got %v", *r)
+	}
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// bedtimeSpreadForZeroScore is the bedtime standard deviation at which the consistency
+// score reaches 0
+const bedtimeSpreadForZeroScore = 120.0
+
+// SleepStats summarizes the sleep logs of a period. Averages are nil without data to
+// compute them from; bedtime and wake time are HH:MM in local time.
+type SleepStats struct {
+	Days            int      `json:"days"`
+	Nights          int      `json:"nights"` // Days with at least one sleep log
+	AvgTotalMinutes *float64 `json:"avg_total_minutes"`
+	AvgDeepPercent  *float64 `json:"avg_deep_percent"`
+	AvgREMPercent   *float64 `json:"avg_rem_percent"`
+	AvgBedtime      string   `json:"avg_bedtime,omitempty"`
+	AvgWakeTime     string   `json:"avg_wake_time,omitempty"`
+	// Standard deviation of the bedtime in minutes, and a 0-100 score that is 100 for
+	// the same bedtime every night; both need at least two nights
+	BedtimeStdDevMinutes *float64 `json:"bedtime_stddev_minutes"`
+	ConsistencyScore     *int     `json:"consistency_score"`
+}
+
+// GetSleepStats aggregates the sleep of the last N days. Logs are grouped by their sleep
+// day: naps add to that day's total, and the longest log is the night whose start and
+// end give the bedtime and wake time. Deep and REM percentages are averaged over the
+// logs that have those stages and a total; logs without stages still count towards the
+// total sleep average.
+func (s *Store) GetSleepStats(ctx context.Context, userID int64, days int) (*SleepStats, error) {
+	now := nowFunc()
+	loc := now.Location()
+
+	logs, err := s.GetSleepLogs(ctx, userID, now.AddDate(0, 0, -days))
+	if err != nil {
+		return nil, err
+	}
+
+	type sleepDay struct {
+		total *int
+		main  SleepLog
+	}
+	nights := map[string]*sleepDay{}
+	var deepPercents, remPercents []float64
+	for _, l := range logs {
+		sd, ok := nights[l.Day]
+		if !ok {
+			sd = &sleepDay{main: l}
+			nights[l.Day] = sd
+		} else if l.EndTime.Sub(l.StartTime) > sd.main.EndTime.Sub(sd.main.StartTime) {
+			sd.main = l
+		}
+		sd.total = addOptionalInt(sd.total, l.TotalMinutes)
+
+		if l.TotalMinutes == nil || *l.TotalMinutes <= 0 {
+			continue
+		}
+		if l.DeepMinutes != nil {
+			deepPercents = append(deepPercents, float64(*l.DeepMinutes)/float64(*l.TotalMinutes)*100)
+		}
+		if l.REMMinutes != nil {
+			remPercents = append(remPercents, float64(*l.REMMinutes)/float64(*l.TotalMinutes)*100)
+		}
+	}
+
+	stats := &SleepStats{Days: days, Nights: len(nights)}
+	var totals, bedtimes, wakeTimes []float64
+	for _, sd := range nights {
+		if sd.total != nil {
+			totals = append(totals, float64(*sd.total))
+		}
+		bedtimes = append(bedtimes, minuteOfDay(sd.main.StartTime.In(loc)))
+		wakeTimes = append(wakeTimes, minuteOfDay(sd.main.EndTime.In(loc)))
+	}
+	stats.AvgTotalMinutes = roundedMean(totals)
+	stats.AvgDeepPercent = roundedMean(deepPercents)
+	stats.AvgREMPercent = roundedMean(remPercents)
+
+	if len(bedtimes) > 0 {
+		bedtime, spread := circularMeanMinutes(bedtimes)
+		wakeTime, _ := circularMeanMinutes(wakeTimes)
+		stats.AvgBedtime = formatMinuteOfDay(bedtime)
+		stats.AvgWakeTime = formatMinuteOfDay(wakeTime)
+		if len(bedtimes) > 1 {
+			sd := roundTo(spread, 1)
+			score := int(math.Round(100 * math.Max(0, 1-spread/bedtimeSpreadForZeroScore)))
+			stats.BedtimeStdDevMinutes = &sd
+			stats.ConsistencyScore = &score
+		}
+	}
+	return stats, nil
+}
+
+func minuteOfDay(t time.Time) float64 {
+	return float64(t.Hour()*60 + t.Minute())
+}
+
+// circularMeanMinutes averages times of day on the 24-hour clock, so 23:30 and 00:30
+// average to midnight rather than noon. Also returns the standard deviation in minutes
+// around that mean.
+func circularMeanMinutes(minutes []float64) (mean, stdDev float64) {
+	const day = 24 * 60
+	var sumSin, sumCos float64
+	for _, m := range minutes {
+		angle := m / day * 2 * math.Pi
+		sumSin += math.Sin(angle)
+		sumCos += math.Cos(angle)
+	}
+	mean = math.Atan2(sumSin, sumCos) / (2 * math.Pi) * day
+	if mean < 0 {
+		mean += day
+	}
+
+	var sumSq float64
+	for _, m := range minutes {
+		diff := math.Mod(m-mean+day+day/2, day) - day/2
+		sumSq += diff * diff
+	}
+	return mean, math.Sqrt(sumSq / float64(len(minutes)))
+}
+
+func formatMinuteOfDay(m float64) string {
+	total := int(math.Round(m)) % (24 * 60)
+	return fmt.Sprintf("%02d:%02d", total/60, total%60)
+}
+
+// roundedMean returns the mean rounded to one decimal, or nil for no values
+func roundedMean(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := roundTo(sum/float64(len(values)), 1)
+	return &mean
+}
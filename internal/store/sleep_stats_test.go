@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetSleepStats(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := int64(1)
+
+	fixedNow := time.Date(2025, 6, 30, 20, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	if stats, err := db.GetSleepStats(ctx, userID, 30); err != nil || stats.Nights != 0 || stats.AvgTotalMinutes != nil || stats.ConsistencyScore != nil {
+		t.Fatalf("Expected empty stats without logs, got %+v (err %v)", stats, err)
+	}
+
+	ptr := func(v int) *int { return &v }
+	logs := []SleepLog{
+		{
+			StartTime: time.Date(2025, 6, 26, 23, 0, 0, 0, time.UTC), EndTime: time.Date(2025, 6, 27, 7, 0, 0, 0, time.UTC),
+			Day: "2025-06-26", TotalMinutes: ptr(450), DeepMinutes: ptr(90), REMMinutes: ptr(90),
+		},
+		{
+			StartTime: time.Date(2025, 6, 28, 0, 0, 0, 0, time.UTC), EndTime: time.Date(2025, 6, 28, 7, 30, 0, 0, time.UTC),
+			Day: "2025-06-27", TotalMinutes: ptr(420), DeepMinutes: ptr(42),
+		},
+		// No stages: counts towards the total only
+		{
+			StartTime: time.Date(2025, 6, 28, 23, 30, 0, 0, time.UTC), EndTime: time.Date(2025, 6, 29, 7, 0, 0, 0, time.UTC),
+			Day: "2025-06-28", TotalMinutes: ptr(400),
+		},
+		// A nap adds to the day's total but doesn't move its bedtime
+		{
+			StartTime: time.Date(2025, 6, 29, 14, 0, 0, 0, time.UTC), EndTime: time.Date(2025, 6, 29, 14, 30, 0, 0, time.UTC),
+			Day: "2025-06-28", TotalMinutes: ptr(30),
+		},
+	}
+	if _, _, err := db.ImportSleepLogs(ctx, userID, logs); err != nil {
+		t.Fatalf("ImportSleepLogs failed: %v", err)
+	}
+
+	stats, err := db.GetSleepStats(ctx, userID, 30)
+	if err != nil {
+		t.Fatalf("GetSleepStats failed: %v", err)
+	}
+	if stats.Nights != 3 {
+		t.Errorf("Expected 3 nights, got %d", stats.Nights)
+	}
+	if stats.AvgTotalMinutes == nil || *stats.AvgTotalMinutes != 433.3 {
+		t.Errorf("Expected average total 433.3, got %v", stats.AvgTotalMinutes)
+	}
+	if stats.AvgDeepPercent == nil || *stats.AvgDeepPercent != 15 {
+		t.Errorf("Expected average deep 15%%, got %v", stats.AvgDeepPercent)
+	}
+	if stats.AvgREMPercent == nil || *stats.AvgREMPercent != 20 {
+		t.Errorf("Expected average REM 20%%, got %v", stats.AvgREMPercent)
+	}
+	// 23:00, 00:00 and 23:30 average across midnight
+	if stats.AvgBedtime != "23:30" || stats.AvgWakeTime != "07:10" {
+		t.Errorf("Expected bedtime 23:30 and wake time 07:10, got %s and %s", stats.AvgBedtime, stats.AvgWakeTime)
+	}
+	if stats.BedtimeStdDevMinutes == nil || *stats.BedtimeStdDevMinutes != 24.5 {
+		t.Errorf("Expected bedtime deviation 24.5 minutes, got %v", stats.BedtimeStdDevMinutes)
+	}
+	if stats.ConsistencyScore == nil || *stats.ConsistencyScore != 80 {
+		t.Errorf("Expected consistency score 80, got %v", stats.ConsistencyScore)
+	}
+}
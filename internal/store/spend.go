@@ -0,0 +1,107 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency is reported when no currency has been configured
+const DefaultCurrency = "USD"
+
+// MedicationSpend is the total cost of one medication's restocks
+type MedicationSpend struct {
+	MedicationID int64   `json:"medication_id"`
+	Name         string  `json:"name"`
+	Restocks     int     `json:"restocks"`
+	Units        int     `json:"units"`
+	Total        float64 `json:"total"`
+}
+
+// SpendReport sums restock costs since a point in time.
+// Restocks without a unit cost are not included.
+type SpendReport struct {
+	Since    time.Time `json:"since"`
+	Currency string    `json:"currency"`
+	Total    float64   `json:"total"`
+	// Medications are ordered by highest spend first
+	Medications []MedicationSpend `json:"medications"`
+}
+
+// GetCurrency returns the configured ISO 4217 currency code
+func (s *Store) GetCurrency() (string, error) {
+	var currency sql.NullString
+	err := s.db.QueryRow("SELECT currency FROM settings WHERE id = 1").Scan(&currency)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	if !currency.Valid || currency.String == "" {
+		return DefaultCurrency, nil
+	}
+	return currency.String, nil
+}
+
+// SetCurrency stores a three-letter currency code; an empty code restores the default
+func (s *Store) SetCurrency(code string) error {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	var value interface{}
+	if code != "" {
+		if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return fmt.Errorf("currency must be a three-letter code like USD or EUR")
+		}
+		value = code
+	}
+	_, err := s.db.Exec("UPDATE settings SET currency = ? WHERE id = 1", value)
+	return err
+}
+
+// GetSpendReport sums quantity * unit cost per medication for restocks since the given time
+func (s *Store) GetSpendReport(since time.Time) (*SpendReport, error) {
+	currency, err := s.GetCurrency()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT r.medication_id, m.name, COUNT(*), SUM(r.quantity), SUM(r.quantity * r.unit_cost)
+		FROM medication_restocks r
+		JOIN medications m ON m.id = r.medication_id
+		WHERE r.unit_cost IS NOT NULL AND r.restocked_at >= ?
+		GROUP BY r.medication_id, m.name`, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &SpendReport{Since: since, Currency: currency, Medications: []MedicationSpend{}}
+	for rows.Next() {
+		var ms MedicationSpend
+		if err := rows.Scan(&ms.MedicationID, &ms.Name, &ms.Restocks, &ms.Units, &ms.Total); err != nil {
+			return nil, err
+		}
+		report.Total += ms.Total
+		ms.Total = roundCents(ms.Total)
+		report.Medications = append(report.Medications, ms)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report.Total = roundCents(report.Total)
+	sort.Slice(report.Medications, func(i, j int) bool {
+		a, b := report.Medications[i], report.Medications[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Name < b.Name
+	})
+
+	return report, nil
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
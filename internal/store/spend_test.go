@@ -0,0 +1,104 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetSpendReport(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	medA, _ := s.CreateMedication("Med A", "10mg", `{"type":"daily","times":["08:00"]}`, nil, nil, "", "")
+	medB, _ := s.CreateMedication("Med B", "5mg", `{"type":"daily","times":["20:00"]}`, nil, nil, "", "")
+
+	restock := func(medID int64, qty int, unitCost *float64, daysAgo int) {
+		t.Helper()
+		if err := s.AddRestock(medID, qty, "", unitCost); err != nil {
+			t.Fatalf("AddRestock failed: %v", err)
+		}
+		at := time.Now().UTC().AddDate(0, 0, -daysAgo).Format("2006-01-02 15:04:05")
+		if _, err := s.db.Exec("UPDATE medication_restocks SET restocked_at = ? WHERE id = (SELECT MAX(id) FROM medication_restocks)", at); err != nil {
+			t.Fatalf("Failed to backdate restock: %v", err)
+		}
+	}
+	cost := func(v float64) *float64 { return &v }
+
+	restock(medA, 30, cost(0.5), 100) // 15.00
+	restock(medA, 30, cost(0.55), 10) // 16.50
+	restock(medB, 60, cost(0.25), 50) // 15.00
+	restock(medB, 90, nil, 20)        // No cost, excluded
+	restock(medB, 30, cost(1.0), 400) // Outside the window
+
+	if err := s.SetCurrency("eur"); err != nil {
+		t.Fatalf("SetCurrency failed: %v", err)
+	}
+
+	report, err := s.GetSpendReport(time.Now().AddDate(0, 0, -365))
+	if err != nil {
+		t.Fatalf("GetSpendReport failed: %v", err)
+	}
+
+	if report.Currency != "EUR" {
+		t.Errorf("Expected currency EUR, got %s", report.Currency)
+	}
+	if report.Total != 46.5 {
+		t.Errorf("Expected total 46.5, got %v", report.Total)
+	}
+	if len(report.Medications) != 2 {
+		t.Fatalf("Expected 2 medications, got %d", len(report.Medications))
+	}
+
+	a := report.Medications[0]
+	if a.Name != "Med A" || a.Total != 31.5 || a.Restocks != 2 || a.Units != 60 {
+		t.Errorf("Unexpected spend for Med A: %+v", a)
+	}
+	b := report.Medications[1]
+	if b.Name != "Med B" || b.Total != 15 || b.Restocks != 1 || b.Units != 60 {
+		t.Errorf("Unexpected spend for Med B: %+v", b)
+	}
+
+	history, err := s.GetRestockHistory(medB)
+	if err != nil {
+		t.Fatalf("GetRestockHistory failed: %v", err)
+	}
+	var withCost int
+	for _, r := range history {
+		if r.UnitCost != nil {
+			withCost++
+		}
+	}
+	if withCost != 2 {
+		t.Errorf("Expected 2 restocks of Med B with a cost, got %d", withCost)
+	}
+}
+
+func TestSetCurrency(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	if c, _ := s.GetCurrency(); c != DefaultCurrency {
+		t.Errorf("Expected default currency %s, got %s", DefaultCurrency, c)
+	}
+	if err := s.SetCurrency("euro"); err == nil {
+		t.Error("Expected error for invalid currency code")
+	}
+	if err := s.SetCurrency("GBP"); err != nil {
+		t.Fatalf("SetCurrency failed: %v", err)
+	}
+	if c, _ := s.GetCurrency(); c != "GBP" {
+		t.Errorf("Expected GBP, got %s", c)
+	}
+	if err := s.SetCurrency(""); err != nil {
+		t.Fatalf("SetCurrency reset failed: %v", err)
+	}
+	if c, _ := s.GetCurrency(); c != DefaultCurrency {
+		t.Errorf("Expected reset to %s, got %s", DefaultCurrency, c)
+	}
+}
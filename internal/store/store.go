@@ -2,11 +2,15 @@ package store
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"embed"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/pressly/goose/v3"
@@ -17,7 +21,11 @@ import (
 var embedMigrations embed.FS
 
 type Store struct {
-	db *sql.DB
+	db   *sql.DB
+	path string // Database file, empty for in-memory databases
+
+	walMu          sync.Mutex
+	lastCheckpoint *CheckpointResult
 }
 
 var nowFunc = time.Now
@@ -26,6 +34,10 @@ type ScheduleConfig struct {
 	Type  string   `json:"type"`            // "daily", "weekly", "as_needed"
 	Days  []int    `json:"days,omitempty"`  // 0=Sunday, 1=Monday...
 	Times []string `json:"times,omitempty"` // ["08:00", "20:00"]
+	// Slots references named per-user times (e.g. ["morning"]), resolved by Store.ExpandSchedule
+	Slots []string `json:"slots,omitempty"`
+	// SeparateHours keeps this medication at least N hours apart from other medications
+	SeparateHours int `json:"separate_hours,omitempty"`
 }
 
 type Medication struct {
@@ -40,7 +52,15 @@ type Medication struct {
 	CreatedAt      time.Time  `json:"created_at"`
 	RxCUI          string     `json:"rxcui,omitempty"`
 	NormalizedName string     `json:"normalized_name,omitempty"`
-	InventoryCount *int       `json:"inventory_count,omitempty"` // NULL = not tracking
+	InventoryCount *float64   `json:"inventory_count,omitempty"`  // NULL = not tracking; fractional after partial doses
+	DoseRatePerKg  *float64   `json:"dose_rate_per_kg,omitempty"` // Weight-based dosing, e.g. mg per kg
+	DoseUnit       string     `json:"dose_unit,omitempty"`        // Unit of the calculated dose (default "mg")
+	Priority       string     `json:"priority"`                   // low, normal or critical
+	PackSize       *int       `json:"pack_size,omitempty"`        // Units per pack/blister, NULL = not sold in packs
+	// Minutes after the scheduled time an unconfirmed dose is assumed taken, NULL = never
+	AutoConfirmAfter *int `json:"auto_confirm_after,omitempty"`
+	// Units taken per scheduled dose, e.g. 2 tablets (default 1)
+	DosePerIntake int `json:"dose_per_intake"`
 }
 
 type Restock struct {
@@ -48,9 +68,17 @@ type Restock struct {
 	MedicationID int64     `json:"medication_id"`
 	Quantity     int       `json:"quantity"`
 	Note         string    `json:"note,omitempty"`
+	UnitCost     *float64  `json:"unit_cost,omitempty"` // Price per unit; nil when not recorded
 	RestockedAt  time.Time `json:"restocked_at"`
 }
 
+// RestockWithMedication is a restock event joined with its medication's details
+type RestockWithMedication struct {
+	Restock
+	MedicationName   string `json:"medication_name"`
+	MedicationDosage string `json:"medication_dosage"`
+}
+
 func (m *Medication) ValidSchedule() (*ScheduleConfig, error) {
 	var s ScheduleConfig
 	// Check if legacy "HH:MM"
@@ -73,6 +101,12 @@ type IntakeLog struct {
 	ScheduledAt  time.Time  `json:"scheduled_at"`
 	TakenAt      *time.Time `json:"taken_at,omitempty"`
 	Status       string     `json:"status"` // PENDING, TAKEN, MISSED
+	Notes        string     `json:"notes,omitempty"`
+	Quantity     float64    `json:"quantity"` // Units taken, e.g. 0.5 for half a tablet
+	// SubstitutedWith names what was taken instead of the scheduled medication, if anything
+	SubstitutedWith string `json:"substituted_with,omitempty"`
+	// SnoozedUntil holds off reminders for a pending dose (only loaded with pending intakes)
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
 }
 
 type IntakeWithMedication struct {
@@ -107,6 +141,7 @@ type WeightLog struct {
 	MuscleMass      *float64  `json:"muscle_mass,omitempty"`
 	MuscleMassTrend *float64  `json:"muscle_mass_trend,omitempty"`
 	Notes           string    `json:"notes,omitempty"`
+	Tag             string    `json:"tag,omitempty"` // e.g. "morning-fasted", "post-workout"
 }
 
 type SleepLog struct {
@@ -181,7 +216,7 @@ func New(dbPath string) (*Store, error) {
 		return nil, fmt.Errorf("failed to migrate db: %w", err)
 	}
 
-	return &Store{db: db}, nil
+	return &Store{db: db, path: databaseFile(dbPath)}, nil
 }
 
 func (s *Store) Close() error {
@@ -203,6 +238,7 @@ func (s *Store) ListMedications(showArchived bool) ([]Medication, error) {
 	query := `
 		SELECT 
 			m.id, m.name, m.dosage, m.schedule, m.archived, m.start_date, m.end_date, m.created_at, m.rxcui, m.normalized_name, m.inventory_count,
+			m.dose_rate_per_kg, m.dose_unit, m.priority, m.pack_size, m.auto_confirm_after, m.dose_per_intake,
 			MAX(CASE WHEN l.status = 'TAKEN' THEN l.taken_at ELSE NULL END) as last_taken
 		FROM medications m
 		LEFT JOIN intake_log l ON m.id = l.medication_id
@@ -224,9 +260,12 @@ func (s *Store) ListMedications(showArchived bool) ([]Medication, error) {
 		var lastTaken sql.NullString // Scan into string first
 		// Handle nullable fields
 		var rxcui, normalizedName sql.NullString
-		var inventoryCount sql.NullInt64
+		var inventoryCount sql.NullFloat64
+		var doseRate sql.NullFloat64
+		var doseUnit sql.NullString
+		var packSize, autoConfirm sql.NullInt64
 
-		if err := rows.Scan(&m.ID, &m.Name, &m.Dosage, &m.Schedule, &m.Archived, &m.StartDate, &m.EndDate, &m.CreatedAt, &rxcui, &normalizedName, &inventoryCount, &lastTaken); err != nil {
+		if err := rows.Scan(&m.ID, &m.Name, &m.Dosage, &m.Schedule, &m.Archived, &m.StartDate, &m.EndDate, &m.CreatedAt, &rxcui, &normalizedName, &inventoryCount, &doseRate, &doseUnit, &m.Priority, &packSize, &autoConfirm, &m.DosePerIntake, &lastTaken); err != nil {
 			return nil, err
 		}
 
@@ -237,22 +276,27 @@ func (s *Store) ListMedications(showArchived bool) ([]Medication, error) {
 			m.NormalizedName = normalizedName.String
 		}
 		if inventoryCount.Valid {
-			ic := int(inventoryCount.Int64)
+			ic := inventoryCount.Float64
 			m.InventoryCount = &ic
 		}
+		if doseRate.Valid {
+			m.DoseRatePerKg = &doseRate.Float64
+		}
+		if doseUnit.Valid {
+			m.DoseUnit = doseUnit.String
+		}
+		if packSize.Valid {
+			ps := int(packSize.Int64)
+			m.PackSize = &ps
+		}
+		if autoConfirm.Valid {
+			ac := int(autoConfirm.Int64)
+			m.AutoConfirmAfter = &ac
+		}
 
 		if lastTaken.Valid {
-			// Helper to parse potential SQLite formats
-			formats := []string{
-				"2006-01-02 15:04:05.999999999-07:00", // Default driver format
-				"2006-01-02 15:04:05",                 // Simple
-				time.RFC3339,
-			}
-			for _, layout := range formats {
-				if t, err := time.Parse(layout, lastTaken.String); err == nil {
-					m.LastTakenAt = &t
-					break
-				}
+			if t, ok := parseDBTime(lastTaken.String); ok {
+				m.LastTakenAt = &t
 			}
 		}
 
@@ -264,9 +308,12 @@ func (s *Store) ListMedications(showArchived bool) ([]Medication, error) {
 func (s *Store) GetMedication(id int64) (*Medication, error) {
 	var m Medication
 	var rxcui, normalizedName sql.NullString
-	var inventoryCount sql.NullInt64
-	err := s.db.QueryRow("SELECT id, name, dosage, schedule, archived, start_date, end_date, created_at, rxcui, normalized_name, inventory_count FROM medications WHERE id = ?", id).Scan(
-		&m.ID, &m.Name, &m.Dosage, &m.Schedule, &m.Archived, &m.StartDate, &m.EndDate, &m.CreatedAt, &rxcui, &normalizedName, &inventoryCount,
+	var inventoryCount sql.NullFloat64
+	var doseRate sql.NullFloat64
+	var doseUnit sql.NullString
+	var packSize, autoConfirm sql.NullInt64
+	err := s.db.QueryRow("SELECT id, name, dosage, schedule, archived, start_date, end_date, created_at, rxcui, normalized_name, inventory_count, dose_rate_per_kg, dose_unit, priority, pack_size, auto_confirm_after, dose_per_intake FROM medications WHERE id = ?", id).Scan(
+		&m.ID, &m.Name, &m.Dosage, &m.Schedule, &m.Archived, &m.StartDate, &m.EndDate, &m.CreatedAt, &rxcui, &normalizedName, &inventoryCount, &doseRate, &doseUnit, &m.Priority, &packSize, &autoConfirm, &m.DosePerIntake,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil // Not found
@@ -282,14 +329,64 @@ func (s *Store) GetMedication(id int64) (*Medication, error) {
 		m.NormalizedName = normalizedName.String
 	}
 	if inventoryCount.Valid {
-		ic := int(inventoryCount.Int64)
+		ic := inventoryCount.Float64
 		m.InventoryCount = &ic
 	}
+	if doseRate.Valid {
+		m.DoseRatePerKg = &doseRate.Float64
+	}
+	if doseUnit.Valid {
+		m.DoseUnit = doseUnit.String
+	}
+	if packSize.Valid {
+		ps := int(packSize.Int64)
+		m.PackSize = &ps
+	}
+	if autoConfirm.Valid {
+		ac := int(autoConfirm.Int64)
+		m.AutoConfirmAfter = &ac
+	}
 
 	return &m, nil
 }
 
-func (s *Store) UpdateMedication(id int64, name, dosage, schedule string, archived bool, startDate, endDate *time.Time, rxcui, normalizedName string, inventoryCount *int) error {
+// GetMedicationsByRxCUI returns all medications, archived included, coded with the given
+// RxCUI: active ones first, then by name. The same drug can be on the list at two doses.
+func (s *Store) GetMedicationsByRxCUI(rxcui string) ([]Medication, error) {
+	rows, err := s.db.Query("SELECT id FROM medications WHERE rxcui = ? ORDER BY archived, name", rxcui)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	meds := []Medication{}
+	for _, id := range ids {
+		m, err := s.GetMedication(id)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			meds = append(meds, *m)
+		}
+	}
+	return meds, nil
+}
+
+// UpdateMedication saves a full edit of the medication. The inventory count is written as
+// given but never logged as a recount; use SetInventory for that.
+func (s *Store) UpdateMedication(id int64, name, dosage, schedule string, archived bool, startDate, endDate *time.Time, rxcui, normalizedName string, inventoryCount *float64) error {
 	_, err := s.db.Exec("UPDATE medications SET name = ?, dosage = ?, schedule = ?, archived = ?, start_date = ?, end_date = ?, rxcui = ?, normalized_name = ?, inventory_count = ? WHERE id = ?",
 		name, dosage, schedule, archived, startDate, endDate, rxcui, normalizedName, inventoryCount, id)
 	return err
@@ -300,23 +397,111 @@ func (s *Store) DeleteMedication(id int64) error {
 	return err
 }
 
+// ArchiveExpiredMedications archives active medications whose end date has passed
+// and returns the medications that were archived
+func (s *Store) ArchiveExpiredMedications() ([]Medication, error) {
+	meds, err := s.ListMedications(false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := nowFunc()
+	var archived []Medication
+	for _, m := range meds {
+		if m.EndDate == nil || !now.After(*m.EndDate) {
+			continue
+		}
+		if _, err := s.db.Exec("UPDATE medications SET archived = 1 WHERE id = ?", m.ID); err != nil {
+			return archived, err
+		}
+		m.Archived = true
+		archived = append(archived, m)
+	}
+	return archived, nil
+}
+
+// SetMedicationDoseRate stores the weight-based dose rate (per kg) and its unit (nil rate to clear)
+func (s *Store) SetMedicationDoseRate(id int64, ratePerKg *float64, unit string) error {
+	var u interface{}
+	if ratePerKg != nil {
+		u = unit
+	}
+	_, err := s.db.Exec("UPDATE medications SET dose_rate_per_kg = ?, dose_unit = ? WHERE id = ?", ratePerKg, u, id)
+	return err
+}
+
+// SetMedicationPriority sets the medication's priority (low, normal or critical)
+func (s *Store) SetMedicationPriority(id int64, priority string) error {
+	if !ValidPriority(priority) {
+		return fmt.Errorf("invalid priority %q, expected low, normal or critical", priority)
+	}
+	_, err := s.db.Exec("UPDATE medications SET priority = ? WHERE id = ?", priority, id)
+	return err
+}
+
 // -- Inventory Functions --
 
-// DecrementInventory reduces the inventory count by the given quantity
+// SetPackSize sets how many units come in one pack (nil to clear)
+func (s *Store) SetPackSize(medID int64, size *int) error {
+	if size != nil && *size <= 0 {
+		return fmt.Errorf("pack size must be positive")
+	}
+	_, err := s.db.Exec("UPDATE medications SET pack_size = ? WHERE id = ?", size, medID)
+	return err
+}
+
+// SetDosePerIntake sets how many units one scheduled dose takes, e.g. 2 tablets
+func (s *Store) SetDosePerIntake(medID int64, units int) error {
+	if units < 1 || units > MaxDoseQuantity {
+		return fmt.Errorf("dose per intake must be between 1 and %d", MaxDoseQuantity)
+	}
+	_, err := s.db.Exec("UPDATE medications SET dose_per_intake = ? WHERE id = ?", units, medID)
+	return err
+}
+
+// Units returns the units one scheduled dose takes, at least 1
+func (m *Medication) Units() float64 {
+	if m.DosePerIntake < 1 {
+		return 1
+	}
+	return float64(m.DosePerIntake)
+}
+
+// PacksRemaining converts the inventory to packs, rounded to one decimal.
+// Nil unless both inventory and pack size are tracked.
+func (m *Medication) PacksRemaining() *float64 {
+	if m.InventoryCount == nil || m.PackSize == nil || *m.PackSize <= 0 {
+		return nil
+	}
+	packs := math.Round(*m.InventoryCount/float64(*m.PackSize)*10) / 10
+	return &packs
+}
+
+// DecrementInventory reduces the inventory count by the given quantity, which may be fractional
 // Only decrements if inventory is being tracked (not NULL)
-func (s *Store) DecrementInventory(medID int64, qty int) error {
+func (s *Store) DecrementInventory(medID int64, qty float64) error {
 	_, err := s.db.Exec("UPDATE medications SET inventory_count = inventory_count - ? WHERE id = ? AND inventory_count IS NOT NULL", qty, medID)
 	return err
 }
 
-// SetInventory sets the inventory count for a medication (nil to disable tracking)
-func (s *Store) SetInventory(medID int64, count *int) error {
+// DecrementInventoryByDose takes one scheduled dose (the medication's dose per intake) from inventory
+func (s *Store) DecrementInventoryByDose(medID int64) error {
+	_, err := s.db.Exec("UPDATE medications SET inventory_count = inventory_count - dose_per_intake WHERE id = ? AND inventory_count IS NOT NULL", medID)
+	return err
+}
+
+// SetInventory sets the inventory count for a medication (nil to disable tracking),
+// logging a changed count as a manual adjustment
+func (s *Store) SetInventory(medID int64, count *float64) error {
+	if err := s.recordInventoryAdjustment(medID, count); err != nil {
+		return err
+	}
 	_, err := s.db.Exec("UPDATE medications SET inventory_count = ? WHERE id = ?", count, medID)
 	return err
 }
 
-// AddRestock adds inventory and logs the restock event
-func (s *Store) AddRestock(medID int64, qty int, note string) error {
+// AddRestock adds inventory and logs the restock event with an optional unit cost
+func (s *Store) AddRestock(medID int64, qty int, note string, unitCost *float64) error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
@@ -333,7 +518,37 @@ func (s *Store) AddRestock(medID int64, qty int, note string) error {
 	}
 
 	// Log restock event
-	_, err = tx.Exec("INSERT INTO medication_restocks (medication_id, quantity, note) VALUES (?, ?, ?)", medID, qty, note)
+	_, err = tx.Exec("INSERT INTO medication_restocks (medication_id, quantity, note, unit_cost) VALUES (?, ?, ?, ?)", medID, qty, note, unitCost)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteRestock undoes a restock of the medication: the event is removed and its quantity
+// taken back off the inventory, never going below zero. Returns sql.ErrNoRows if the
+// restock doesn't exist or belongs to another medication.
+func (s *Store) DeleteRestock(medID, restockID int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var qty int
+	err = tx.QueryRow("SELECT quantity FROM medication_restocks WHERE id = ? AND medication_id = ?", restockID, medID).Scan(&qty)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM medication_restocks WHERE id = ?", restockID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE medications
+		SET inventory_count = MAX(inventory_count - ?, 0)
+		WHERE id = ? AND inventory_count IS NOT NULL`, qty, medID)
 	if err != nil {
 		return err
 	}
@@ -343,7 +558,7 @@ func (s *Store) AddRestock(medID int64, qty int, note string) error {
 
 // GetRestockHistory returns restock events for a medication
 func (s *Store) GetRestockHistory(medID int64) ([]Restock, error) {
-	rows, err := s.db.Query("SELECT id, medication_id, quantity, note, restocked_at FROM medication_restocks WHERE medication_id = ? ORDER BY restocked_at DESC", medID)
+	rows, err := s.db.Query("SELECT id, medication_id, quantity, note, unit_cost, restocked_at FROM medication_restocks WHERE medication_id = ? ORDER BY restocked_at DESC", medID)
 	if err != nil {
 		return nil, err
 	}
@@ -353,20 +568,59 @@ func (s *Store) GetRestockHistory(medID int64) ([]Restock, error) {
 	for rows.Next() {
 		var r Restock
 		var note sql.NullString
-		if err := rows.Scan(&r.ID, &r.MedicationID, &r.Quantity, &note, &r.RestockedAt); err != nil {
+		var unitCost sql.NullFloat64
+		if err := rows.Scan(&r.ID, &r.MedicationID, &r.Quantity, &note, &unitCost, &r.RestockedAt); err != nil {
 			return nil, err
 		}
 		if note.Valid {
 			r.Note = note.String
 		}
+		if unitCost.Valid {
+			r.UnitCost = &unitCost.Float64
+		}
 		restocks = append(restocks, r)
 	}
 	return restocks, nil
 }
 
+// GetAllRestocks returns restock events of all medications since the given time, newest first.
+// Medications belong to the bot's single user, so userID only documents the caller's intent
+// until medications carry an owner.
+func (s *Store) GetAllRestocks(userID int64, since time.Time) ([]RestockWithMedication, error) {
+	// restocked_at is written by SQLite's CURRENT_TIMESTAMP, i.e. UTC "YYYY-MM-DD HH:MM:SS"
+	rows, err := s.db.Query(`
+		SELECT r.id, r.medication_id, r.quantity, r.note, r.unit_cost, r.restocked_at, m.name, m.dosage
+		FROM medication_restocks r
+		JOIN medications m ON m.id = r.medication_id
+		WHERE r.restocked_at >= ?
+		ORDER BY r.restocked_at DESC, r.id DESC`, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	restocks := []RestockWithMedication{}
+	for rows.Next() {
+		var r RestockWithMedication
+		var note sql.NullString
+		var unitCost sql.NullFloat64
+		if err := rows.Scan(&r.ID, &r.MedicationID, &r.Quantity, &note, &unitCost, &r.RestockedAt, &r.MedicationName, &r.MedicationDosage); err != nil {
+			return nil, err
+		}
+		if note.Valid {
+			r.Note = note.String
+		}
+		if unitCost.Valid {
+			r.UnitCost = &unitCost.Float64
+		}
+		restocks = append(restocks, r)
+	}
+	return restocks, rows.Err()
+}
+
 // GetMedicationsLowOnStock returns medications with inventory tracking that are low on stock
 // daysThreshold: warn if stock lasts fewer than this many days
-func (s *Store) GetMedicationsLowOnStock(daysThreshold int) ([]Medication, error) {
+func (s *Store) GetMedicationsLowOnStock(userID int64, daysThreshold int) ([]Medication, error) {
 	// First get all active medications with inventory tracking
 	meds, err := s.ListMedications(false)
 	if err != nil {
@@ -380,7 +634,7 @@ func (s *Store) GetMedicationsLowOnStock(daysThreshold int) ([]Medication, error
 		}
 
 		// Calculate daily usage from schedule
-		dailyUsage := s.calculateDailyUsage(&m)
+		dailyUsage := s.calculateDailyUsage(userID, &m)
 		if dailyUsage == 0 {
 			continue // As-needed or invalid schedule
 		}
@@ -420,39 +674,24 @@ func (s *Store) hasEnoughStock(m *Medication, dailyUsage float64, daysThreshold
 	return daysOfStock >= float64(daysThreshold)
 }
 
-// calculateDailyUsage returns the average daily intakes for a medication
-func (s *Store) calculateDailyUsage(m *Medication) float64 {
-	cfg, err := m.ValidSchedule()
+// calculateDailyUsage returns the average units used per day: daily intakes times the dose per intake.
+// Slots are resolved to the user's times first, so one that shares a time with another dose counts once.
+func (s *Store) calculateDailyUsage(userID int64, m *Medication) float64 {
+	cfg, err := s.ExpandSchedule(userID, m)
 	if err != nil {
 		return 0
 	}
 
-	if cfg.Type == "as_needed" {
-		return 0 // Can't calculate for as-needed
-	}
-
-	timesPerDay := float64(len(cfg.Times))
-
-	if cfg.Type == "daily" {
-		return timesPerDay
-	}
-
-	if cfg.Type == "weekly" {
-		// Days per week that the medication is taken
-		daysPerWeek := float64(len(cfg.Days))
-		return (daysPerWeek / 7.0) * timesPerDay
-	}
-
-	return 0
+	return cfg.DailyUsage() * m.Units()
 }
 
 // GetDaysOfStockRemaining calculates how many days of stock remain for a medication
-func (s *Store) GetDaysOfStockRemaining(m *Medication) *float64 {
+func (s *Store) GetDaysOfStockRemaining(userID int64, m *Medication) *float64 {
 	if m.InventoryCount == nil {
 		return nil
 	}
 
-	dailyUsage := s.calculateDailyUsage(m)
+	dailyUsage := s.calculateDailyUsage(userID, m)
 	if dailyUsage == 0 {
 		return nil
 	}
@@ -462,12 +701,12 @@ func (s *Store) GetDaysOfStockRemaining(m *Medication) *float64 {
 }
 
 // IsLowOnStock checks if a medication is low on stock considering its end date
-func (s *Store) IsLowOnStock(m *Medication, daysThreshold int) bool {
+func (s *Store) IsLowOnStock(userID int64, m *Medication, daysThreshold int) bool {
 	if m.InventoryCount == nil {
 		return false
 	}
 
-	dailyUsage := s.calculateDailyUsage(m)
+	dailyUsage := s.calculateDailyUsage(userID, m)
 	if dailyUsage == 0 {
 		return false
 	}
@@ -486,8 +725,82 @@ func (s *Store) CreateIntake(medID, userID int64, scheduledAt time.Time) (int64,
 	return res.LastInsertId()
 }
 
+// CreateIntakeIfAbsent creates a pending intake unless one already exists for the
+// medication at scheduledAt. Returns the intake ID and whether it was newly created.
+func (s *Store) CreateIntakeIfAbsent(medID, userID int64, scheduledAt time.Time) (int64, bool, error) {
+	existing, err := s.GetIntakeBySchedule(medID, scheduledAt)
+	if err != nil {
+		return 0, false, err
+	}
+	if existing != nil {
+		return existing.ID, false, nil
+	}
+
+	// The unique index on (medication_id, scheduled_at) is the backstop for concurrent callers
+	res, err := s.db.Exec(`INSERT INTO intake_log (medication_id, user_id, scheduled_at, status) VALUES (?, ?, ?, 'PENDING')
+		ON CONFLICT(medication_id, scheduled_at) DO NOTHING`, medID, userID, scheduledAt)
+	if err != nil {
+		return 0, false, err
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		existing, err := s.GetIntakeBySchedule(medID, scheduledAt)
+		if err != nil || existing == nil {
+			return 0, false, err
+		}
+		return existing.ID, false, nil
+	}
+
+	id, err := res.LastInsertId()
+	return id, true, err
+}
+
+// ConfirmIntake marks an intake as taken with one scheduled dose, i.e. the
+// medication's dose per intake
 func (s *Store) ConfirmIntake(id int64, takenAt time.Time) error {
-	_, err := s.db.Exec("UPDATE intake_log SET status = 'TAKEN', taken_at = ? WHERE id = ?", takenAt, id)
+	dose := 1.0
+	err := s.db.QueryRow("SELECT m.dose_per_intake FROM intake_log il JOIN medications m ON il.medication_id = m.id WHERE il.id = ?", id).Scan(&dose)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return s.ConfirmIntakeQuantity(id, takenAt, dose)
+}
+
+// ConfirmIntakeQuantity marks an intake as taken with a partial or multiple dose.
+// Adherence only looks at the status, so a half dose still counts as taken.
+func (s *Store) ConfirmIntakeQuantity(id int64, takenAt time.Time, quantity float64) error {
+	return s.ConfirmIntakeSubstituted(id, takenAt, quantity, "")
+}
+
+// ConfirmIntakeSubstituted marks an intake as taken, recording that substitute (e.g. a
+// generic) was taken in place of the scheduled medication. An empty substitute clears it.
+// Medications with an injection site rotation log the suggested site.
+func (s *Store) ConfirmIntakeSubstituted(id int64, takenAt time.Time, quantity float64, substitute string) error {
+	var substitutedWith interface{}
+	if substitute != "" {
+		substitutedWith = substitute
+	}
+	_, err := s.db.Exec("UPDATE intake_log SET status = 'TAKEN', taken_at = ?, quantity = ?, substituted_with = ? WHERE id = ?",
+		takenAt, quantity, substitutedWith, id)
+	if err != nil {
+		return err
+	}
+	return s.recordRotationInjection(id, takenAt)
+}
+
+// ValidDoseQuantity reports whether q is a usable dose quantity
+func ValidDoseQuantity(q float64) bool {
+	return q > 0 && q <= MaxDoseQuantity
+}
+
+// MaxDoseQuantity caps the units a single confirmation may take from inventory
+const MaxDoseQuantity = 20
+
+// OutOfStockReason is recorded on intakes that were missed because inventory ran out
+const OutOfStockReason = "out of stock"
+
+// MarkIntakeMissed marks an intake as MISSED and records the reason in its notes
+func (s *Store) MarkIntakeMissed(id int64, reason string) error {
+	_, err := s.db.Exec("UPDATE intake_log SET status = 'MISSED', taken_at = NULL, notes = ? WHERE id = ?", reason, id)
 	return err
 }
 
@@ -498,12 +811,16 @@ func (s *Store) UpdateIntake(id int64, takenAt time.Time, status string) error {
 	} else {
 		takenAtVal = nil
 	}
-	_, err := s.db.Exec("UPDATE intake_log SET status = ?, taken_at = ? WHERE id = ?", status, takenAtVal, id)
+	// A substitution only describes a taken dose
+	_, err := s.db.Exec(`
+		UPDATE intake_log
+		SET status = ?, taken_at = ?, substituted_with = CASE WHEN ? = 'TAKEN' THEN substituted_with END
+		WHERE id = ?`, status, takenAtVal, status, id)
 	return err
 }
 
 func (s *Store) GetPendingIntakes() ([]IntakeLog, error) {
-	rows, err := s.db.Query("SELECT id, medication_id, user_id, scheduled_at, status FROM intake_log WHERE status = 'PENDING'")
+	rows, err := s.db.Query("SELECT id, medication_id, user_id, scheduled_at, status, snoozed_until FROM intake_log WHERE status = 'PENDING'")
 	if err != nil {
 		return nil, err
 	}
@@ -512,7 +829,7 @@ func (s *Store) GetPendingIntakes() ([]IntakeLog, error) {
 	var logs []IntakeLog
 	for rows.Next() {
 		var l IntakeLog
-		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.Status); err != nil {
+		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.Status, &l.SnoozedUntil); err != nil {
 			return nil, err
 		}
 		logs = append(logs, l)
@@ -521,7 +838,7 @@ func (s *Store) GetPendingIntakes() ([]IntakeLog, error) {
 }
 
 func (s *Store) GetIntakeHistory(medID int, days int) ([]IntakeLog, error) {
-	query := "SELECT id, medication_id, user_id, scheduled_at, taken_at, status FROM intake_log WHERE 1=1"
+	query := "SELECT id, medication_id, user_id, scheduled_at, taken_at, status, notes, quantity, substituted_with FROM intake_log WHERE 1=1"
 	args := []interface{}{}
 
 	if medID > 0 {
@@ -546,9 +863,14 @@ func (s *Store) GetIntakeHistory(medID int, days int) ([]IntakeLog, error) {
 	var logs []IntakeLog
 	for rows.Next() {
 		var l IntakeLog
-		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.TakenAt, &l.Status); err != nil {
+		var notes, substitutedWith sql.NullString
+		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.TakenAt, &l.Status, &notes, &l.Quantity, &substitutedWith); err != nil {
 			return nil, err
 		}
+		if notes.Valid {
+			l.Notes = notes.String
+		}
+		l.SubstitutedWith = substitutedWith.String
 		logs = append(logs, l)
 	}
 	return logs, nil
@@ -556,8 +878,9 @@ func (s *Store) GetIntakeHistory(medID int, days int) ([]IntakeLog, error) {
 
 func (s *Store) GetIntake(id int64) (*IntakeLog, error) {
 	var l IntakeLog
-	err := s.db.QueryRow("SELECT id, medication_id, user_id, scheduled_at, taken_at, status FROM intake_log WHERE id = ?", id).Scan(
-		&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.TakenAt, &l.Status,
+	var notes, substitutedWith sql.NullString
+	err := s.db.QueryRow("SELECT id, medication_id, user_id, scheduled_at, taken_at, status, notes, quantity, substituted_with FROM intake_log WHERE id = ?", id).Scan(
+		&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.TakenAt, &l.Status, &notes, &l.Quantity, &substitutedWith,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil // Not found
@@ -565,6 +888,10 @@ func (s *Store) GetIntake(id int64) (*IntakeLog, error) {
 	if err != nil {
 		return nil, err
 	}
+	if notes.Valid {
+		l.Notes = notes.String
+	}
+	l.SubstitutedWith = substitutedWith.String
 	return &l, nil
 }
 
@@ -577,8 +904,9 @@ func (s *Store) GetIntakeBySchedule(medID int64, scheduledAt time.Time) (*Intake
 	// Let's rely on driver.
 
 	var l IntakeLog
-	err := s.db.QueryRow("SELECT id, medication_id, user_id, scheduled_at, taken_at, status FROM intake_log WHERE medication_id = ? AND scheduled_at = ?", medID, scheduledAt).Scan(
-		&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.TakenAt, &l.Status,
+	var notes, substitutedWith sql.NullString
+	err := s.db.QueryRow("SELECT id, medication_id, user_id, scheduled_at, taken_at, status, notes, quantity, substituted_with FROM intake_log WHERE medication_id = ? AND scheduled_at = ?", medID, scheduledAt).Scan(
+		&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.TakenAt, &l.Status, &notes, &l.Quantity, &substitutedWith,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -586,27 +914,55 @@ func (s *Store) GetIntakeBySchedule(medID int64, scheduledAt time.Time) (*Intake
 	if err != nil {
 		return nil, err
 	}
+	if notes.Valid {
+		l.Notes = notes.String
+	}
+	l.SubstitutedWith = substitutedWith.String
 	return &l, nil
 }
 
+// ConfirmIntakesBySchedule marks the pending doses of the schedule time nearest to
+// scheduledAt as taken; see pendingIntakesNear for which doses qualify
 func (s *Store) ConfirmIntakesBySchedule(userID int64, scheduledAt time.Time, takenAt time.Time) error {
-	// Only confirm intakes for medications that are NOT archived (archived = 0)
-	_, err := s.db.Exec(`
-		UPDATE intake_log 
-		SET status = 'TAKEN', taken_at = ? 
-		WHERE user_id = ? 
-		  AND scheduled_at = ? 
-		  AND status = 'PENDING'
-		  AND medication_id IN (SELECT id FROM medications WHERE archived = 0)
-	`, takenAt, userID, scheduledAt)
+	pending, err := s.pendingIntakesNear(userID, scheduledAt)
+	if err != nil {
+		return err
+	}
+	for _, l := range pending {
+		if _, err := s.db.Exec("UPDATE intake_log SET status = 'TAKEN', taken_at = ?, quantity = (SELECT dose_per_intake FROM medications WHERE id = intake_log.medication_id) WHERE id = ? AND status = 'PENDING'", takenAt, l.ID); err != nil {
+			return err
+		}
+		if err := s.recordRotationInjection(l.ID, takenAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetIntakeNotes attaches a free-text note to an intake
+func (s *Store) SetIntakeNotes(id int64, notes string) error {
+	_, err := s.db.Exec("UPDATE intake_log SET notes = ? WHERE id = ?", notes, id)
 	return err
 }
 
 func (s *Store) AddIntakeReminder(intakeID int64, messageID int) error {
-	_, err := s.db.Exec("INSERT INTO intake_reminders (intake_id, message_id) VALUES (?, ?)", intakeID, messageID)
+	_, err := s.db.Exec("INSERT INTO intake_reminders (intake_id, message_id, sent_at) VALUES (?, ?, ?)", intakeID, messageID, nowFunc())
 	return err
 }
 
+// GetLastIntakeReminderAt returns when the intake's latest reminder was sent, nil if none was
+func (s *Store) GetLastIntakeReminderAt(intakeID int64) (*time.Time, error) {
+	var sentAt time.Time
+	err := s.db.QueryRow("SELECT sent_at FROM intake_reminders WHERE intake_id = ? AND sent_at IS NOT NULL ORDER BY sent_at DESC LIMIT 1", intakeID).Scan(&sentAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sentAt, nil
+}
+
 func (s *Store) GetIntakeReminders(intakeID int64) ([]int, error) {
 	rows, err := s.db.Query("SELECT message_id FROM intake_reminders WHERE intake_id = ?", intakeID)
 	if err != nil {
@@ -624,21 +980,69 @@ func (s *Store) GetIntakeReminders(intakeID int64) ([]int, error) {
 	return ids, nil
 }
 
-func (s *Store) GetPendingIntakesBySchedule(userID int64, scheduledAt time.Time) ([]IntakeLog, error) {
-	rows, err := s.db.Query("SELECT id, medication_id, user_id, scheduled_at, status FROM intake_log WHERE user_id = ? AND scheduled_at = ? AND status = 'PENDING'", userID, scheduledAt)
+// AddIntakeNoteMessage links a confirmation message to an intake, so a reply to it adds a
+// note. Unlike reminders these messages are never deleted.
+func (s *Store) AddIntakeNoteMessage(intakeID int64, messageID int) error {
+	_, err := s.db.Exec("INSERT INTO intake_note_messages (intake_id, message_id) VALUES (?, ?)", intakeID, messageID)
+	return err
+}
+
+// GetIntakeIDsByReminderMessage returns the intakes a Telegram reminder or confirmation
+// message was sent for
+func (s *Store) GetIntakeIDsByReminderMessage(messageID int) ([]int64, error) {
+	rows, err := s.db.Query(`
+		SELECT intake_id FROM intake_reminders WHERE message_id = ?
+		UNION
+		SELECT intake_id FROM intake_note_messages WHERE message_id = ?`, messageID, messageID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var logs []IntakeLog
+	var ids []int64
 	for rows.Next() {
-		var l IntakeLog
-		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.Status); err != nil {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GetPendingIntakesWithMedication returns the user's pending intakes of active
+// medications with their name and dosage, oldest first
+func (s *Store) GetPendingIntakesWithMedication(userID int64) ([]IntakeWithMedication, error) {
+	rows, err := s.db.Query(`
+		SELECT il.id, il.medication_id, il.user_id, il.scheduled_at, il.status, il.notes, il.quantity, il.substituted_with,
+			m.name, m.dosage
+		FROM intake_log il
+		JOIN medications m ON il.medication_id = m.id
+		WHERE il.user_id = ? AND il.status = 'PENDING' AND m.archived = 0
+		ORDER BY il.scheduled_at ASC, m.name ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []IntakeWithMedication{}
+	for rows.Next() {
+		var l IntakeWithMedication
+		var notes, substitutedWith sql.NullString
+		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.Status, &notes, &l.Quantity, &substitutedWith, &l.MedicationName, &l.MedicationDosage); err != nil {
+			return nil, err
+		}
+		if notes.Valid {
+			l.Notes = notes.String
+		}
+		l.SubstitutedWith = substitutedWith.String
 		logs = append(logs, l)
 	}
-	return logs, nil
+	return logs, rows.Err()
+}
+
+// GetPendingIntakesBySchedule returns the pending doses ConfirmIntakesBySchedule would confirm
+func (s *Store) GetPendingIntakesBySchedule(userID int64, scheduledAt time.Time) ([]IntakeLog, error) {
+	return s.pendingIntakesNear(userID, scheduledAt)
 }
 
 func (s *Store) GetPendingIntakesForMedication(medID int64) ([]IntakeLog, error) {
@@ -658,6 +1062,32 @@ func (s *Store) GetPendingIntakesForMedication(medID int64) ([]IntakeLog, error)
 	return logs, nil
 }
 
+// GetNearestPendingIntake returns the medication's pending intake scheduled closest to at,
+// ignoring intakes more than window away. Nil if none qualifies.
+func (s *Store) GetNearestPendingIntake(medID int64, at time.Time, window time.Duration) (*IntakeLog, error) {
+	pending, err := s.GetPendingIntakesForMedication(medID)
+	if err != nil {
+		return nil, err
+	}
+
+	var nearest *IntakeLog
+	var nearestDist time.Duration
+	for i := range pending {
+		dist := pending[i].ScheduledAt.Sub(at)
+		if dist < 0 {
+			dist = -dist
+		}
+		if dist > window {
+			continue
+		}
+		if nearest == nil || dist < nearestDist {
+			nearest = &pending[i]
+			nearestDist = dist
+		}
+	}
+	return nearest, nil
+}
+
 func (s *Store) DeleteIntake(id int64) error {
 	_, err := s.db.Exec("DELETE FROM intake_log WHERE id = ?", id)
 	return err
@@ -756,16 +1186,27 @@ func (s *Store) SetBPGoal(targetSystolic, targetDiastolic int) error {
 // -- Downloads --
 
 func (s *Store) GetIntakesSince(since time.Time) ([]IntakeWithMedication, error) {
+	return s.GetIntakesBetween(since, time.Time{})
+}
+
+// GetIntakesBetween returns intakes scheduled within [from, to], newest first.
+// A zero to leaves the range open-ended.
+func (s *Store) GetIntakesBetween(from, to time.Time) ([]IntakeWithMedication, error) {
 	query := `
 		SELECT
-			il.id, il.medication_id, il.user_id, il.scheduled_at, il.taken_at, il.status,
+			il.id, il.medication_id, il.user_id, il.scheduled_at, il.taken_at, il.status, il.notes, il.quantity, il.substituted_with,
 			m.name AS medication_name, m.dosage AS medication_dosage
 		FROM intake_log il
 		JOIN medications m ON il.medication_id = m.id
-		WHERE il.scheduled_at >= ?
-		ORDER BY il.scheduled_at DESC
-	`
-	rows, err := s.db.Query(query, since)
+		WHERE il.scheduled_at >= ?`
+	args := []interface{}{from}
+	if !to.IsZero() {
+		query += " AND il.scheduled_at <= ?"
+		args = append(args, to)
+	}
+	query += " ORDER BY il.scheduled_at DESC"
+
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -774,9 +1215,14 @@ func (s *Store) GetIntakesSince(since time.Time) ([]IntakeWithMedication, error)
 	var logs []IntakeWithMedication
 	for rows.Next() {
 		var l IntakeWithMedication
-		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.TakenAt, &l.Status, &l.MedicationName, &l.MedicationDosage); err != nil {
+		var notes, substitutedWith sql.NullString
+		if err := rows.Scan(&l.ID, &l.MedicationID, &l.UserID, &l.ScheduledAt, &l.TakenAt, &l.Status, &notes, &l.Quantity, &substitutedWith, &l.MedicationName, &l.MedicationDosage); err != nil {
 			return nil, err
 		}
+		if notes.Valid {
+			l.Notes = notes.String
+		}
+		l.SubstitutedWith = substitutedWith.String
 		logs = append(logs, l)
 	}
 	return logs, nil
@@ -798,7 +1244,36 @@ func (s *Store) CreateBloodPressureReading(ctx context.Context, bp *BloodPressur
 	return res.LastInsertId()
 }
 
-func (s *Store) GetBloodPressureReadings(ctx context.Context, userID int64, since time.Time) ([]BloodPressure, error) {
+// GetBloodPressureReadings returns readings since the given time, newest first. A positive
+// limit returns one page of at most limit readings after skipping offset; 0 returns all.
+func (s *Store) GetBloodPressureReadings(ctx context.Context, userID int64, since time.Time, limit, offset int) ([]BloodPressure, error) {
+	return s.getBloodPressureReadings(ctx, userID, since, time.Time{}, limit, offset)
+}
+
+// CountBloodPressureReadings counts the readings since the given time, for paging
+func (s *Store) CountBloodPressureReadings(ctx context.Context, userID int64, since time.Time) (int, error) {
+	query := "SELECT COUNT(*) FROM blood_pressure_readings WHERE user_id = ?"
+	args := []interface{}{userID}
+	if !since.IsZero() {
+		query += " AND measured_at >= ?"
+		args = append(args, since)
+	}
+	var n int
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+// GetRecentBloodPressureReadings returns at most limit readings since the given time, newest first
+func (s *Store) GetRecentBloodPressureReadings(ctx context.Context, userID int64, since time.Time, limit int) ([]BloodPressure, error) {
+	return s.getBloodPressureReadings(ctx, userID, since, time.Time{}, limit, 0)
+}
+
+// GetBloodPressureReadingsBetween returns readings measured within [from, to], newest first
+func (s *Store) GetBloodPressureReadingsBetween(ctx context.Context, userID int64, from, to time.Time) ([]BloodPressure, error) {
+	return s.getBloodPressureReadings(ctx, userID, from, to, 0, 0)
+}
+
+func (s *Store) getBloodPressureReadings(ctx context.Context, userID int64, since, until time.Time, limit, offset int) ([]BloodPressure, error) {
 	query := "SELECT id, user_id, measured_at, systolic, diastolic, pulse, site, position, category, ignore_calc, notes, tag FROM blood_pressure_readings WHERE user_id = ?"
 	args := []interface{}{userID}
 
@@ -806,8 +1281,17 @@ func (s *Store) GetBloodPressureReadings(ctx context.Context, userID int64, sinc
 		query += " AND measured_at >= ?"
 		args = append(args, since)
 	}
+	if !until.IsZero() {
+		query += " AND measured_at <= ?"
+		args = append(args, until)
+	}
 
-	query += " ORDER BY measured_at DESC"
+	// id breaks ties between readings taken at the same time, keeping pages stable
+	query += " ORDER BY measured_at DESC, id DESC"
+	if limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -862,21 +1346,120 @@ func (s *Store) DeleteBloodPressureReading(ctx context.Context, id, userID int64
 	return nil
 }
 
-func (s *Store) ImportBloodPressureReadings(ctx context.Context, userID int64, readings []BloodPressure) error {
+// DeleteBloodPressureReadingsInRange deletes the user's readings measured in [from, to)
+// and returns how many were removed
+func (s *Store) DeleteBloodPressureReadingsInRange(ctx context.Context, userID int64, from, to time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM blood_pressure_readings WHERE user_id = ? AND measured_at >= ? AND measured_at < ?", userID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// bpImportChunkSize is the number of rows committed per transaction during an import
+const bpImportChunkSize = 500
+
+// BPImportResult reports the progress of a blood pressure import
+type BPImportResult struct {
+	Total      int                `json:"total"`
+	Processed  int                `json:"processed"`
+	Imported   int                `json:"imported"`
+	Duplicates int                `json:"duplicates"`
+	Resumed    int                `json:"resumed"` // Rows skipped because an earlier interrupted run already committed them
+	Invalid    int                `json:"invalid"` // Rows skipped because they failed validation, explained in Errors
+	Errors     []BPImportRowError `json:"errors"`
+}
+
+// ImportBloodPressureReadings imports readings in chunks of bpImportChunkSize
+func (s *Store) ImportBloodPressureReadings(ctx context.Context, userID int64, readings []BloodPressure) (*BPImportResult, error) {
+	return s.ImportBloodPressureReadingsChunked(ctx, userID, readings, bpImportChunkSize, nil)
+}
+
+// ImportBloodPressureReadingsChunked imports readings oldest first, committing every chunkSize rows.
+// After each commit a resume marker (the last imported timestamp) is saved for this set of
+// readings, so re-running an interrupted import of the same data skips what was already
+// committed. Readings failing Validate are skipped and reported, as the dry-run report
+// predicts; duplicates are ignored via the unique index. progress, if set, is called after each committed chunk.
+func (s *Store) ImportBloodPressureReadingsChunked(ctx context.Context, userID int64, readings []BloodPressure, chunkSize int, progress func(BPImportResult)) (*BPImportResult, error) {
+	if chunkSize <= 0 {
+		chunkSize = bpImportChunkSize
+	}
+
+	result := &BPImportResult{Total: len(readings), Errors: []BPImportRowError{}}
+	sorted := make([]BloodPressure, 0, len(readings))
+	for i, bp := range readings {
+		if err := bp.Validate(); err != nil {
+			result.Invalid++
+			result.Errors = append(result.Errors, BPImportRowError{Index: i, Reason: err.Error()})
+			continue
+		}
+		sorted = append(sorted, bp)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].MeasuredAt.Before(sorted[j].MeasuredAt)
+	})
+	importID := bpImportID(sorted)
+
+	next := 0
+	marker, err := s.getBPImportMarker(ctx, userID, importID)
+	if err != nil {
+		return result, err
+	}
+	if marker != nil {
+		// Readings at the marker itself are retried; the unique index drops them if already present
+		for next < len(sorted) && sorted[next].MeasuredAt.Before(*marker) {
+			next++
+			result.Resumed++
+		}
+	}
+	result.Processed = result.Invalid + next
+
+	for start := next; start < len(sorted); start += chunkSize {
+		end := min(start+chunkSize, len(sorted))
+		imported, err := s.importBPChunk(ctx, userID, importID, sorted[start:end])
+		if err != nil {
+			return result, err
+		}
+		result.Imported += imported
+		result.Duplicates += (end - start) - imported
+		result.Processed = result.Invalid + end
+
+		if progress != nil {
+			progress(*result)
+		}
+	}
+
+	// Finished: drop the marker so a later import of older data is not skipped
+	_, err = s.db.ExecContext(ctx, "DELETE FROM bp_import_state WHERE user_id = ? AND import_id = ?", userID, importID)
+	return result, err
+}
+
+// bpImportID identifies a set of readings, so a resume marker only applies to the same data
+func bpImportID(sorted []BloodPressure) string {
+	h := sha256.New()
+	for _, bp := range sorted {
+		fmt.Fprintf(h, "%d|%d|%d\n", bp.MeasuredAt.UnixNano(), bp.Systolic, bp.Diastolic)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// importBPChunk inserts one chunk and advances the resume marker in the same transaction
+func (s *Store) importBPChunk(ctx context.Context, userID int64, importID string, chunk []BloodPressure) (int, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback()
 
 	stmt, err := tx.PrepareContext(ctx,
-		"INSERT INTO blood_pressure_readings (user_id, measured_at, systolic, diastolic, pulse, site, position, category, ignore_calc, notes, tag) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		"INSERT OR IGNORE INTO blood_pressure_readings (user_id, measured_at, systolic, diastolic, pulse, site, position, category, ignore_calc, notes, tag) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer stmt.Close()
 
-	for _, bp := range readings {
+	imported := 0
+	for _, bp := range chunk {
 		bp.UserID = userID
 		if bp.Category == "" && !bp.IgnoreCalc {
 			bp.Category = CalculateBPCategory(bp.Systolic, bp.Diastolic)
@@ -889,13 +1472,40 @@ func (s *Store) ImportBloodPressureReadings(ctx context.Context, userID int64, r
 			pulse = nil
 		}
 
-		_, err := stmt.ExecContext(ctx, bp.UserID, bp.MeasuredAt, bp.Systolic, bp.Diastolic, pulse, bp.Site, bp.Position, bp.Category, bp.IgnoreCalc, bp.Notes, bp.Tag)
+		res, err := stmt.ExecContext(ctx, bp.UserID, bp.MeasuredAt, bp.Systolic, bp.Diastolic, pulse, bp.Site, bp.Position, bp.Category, bp.IgnoreCalc, bp.Notes, bp.Tag)
 		if err != nil {
-			return err
+			return 0, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			imported++
 		}
 	}
 
-	return tx.Commit()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO bp_import_state (user_id, import_id, last_measured_at, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, import_id) DO UPDATE SET
+			last_measured_at = excluded.last_measured_at,
+			updated_at = CURRENT_TIMESTAMP`,
+		userID, importID, chunk[len(chunk)-1].MeasuredAt)
+	if err != nil {
+		return 0, err
+	}
+
+	return imported, tx.Commit()
+}
+
+// getBPImportMarker returns the resume marker of an unfinished import, or nil
+func (s *Store) getBPImportMarker(ctx context.Context, userID int64, importID string) (*time.Time, error) {
+	var marker time.Time
+	err := s.db.QueryRowContext(ctx, "SELECT last_measured_at FROM bp_import_state WHERE user_id = ? AND import_id = ?", userID, importID).Scan(&marker)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &marker, nil
 }
 
 // BPPeriodStats represents daily-weighted BP stats for a specific time period
@@ -904,6 +1514,16 @@ type BPPeriodStats struct {
 	Diastolic int `json:"diastolic"`
 	Days      int `json:"days"`     // Number of days with readings
 	Readings  int `json:"readings"` // Total number of readings
+	// Unrounded averages, for callers that want decimals (see RoundedAverages)
+	SystolicMean  float64 `json:"-"`
+	DiastolicMean float64 `json:"-"`
+}
+
+// RoundedAverages returns the systolic and diastolic averages rounded to the given
+// number of decimals (0 gives the same values as Systolic and Diastolic)
+func (p *BPPeriodStats) RoundedAverages(decimals int) (float64, float64) {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(p.SystolicMean*scale) / scale, math.Round(p.DiastolicMean*scale) / scale
 }
 
 // BPStats contains daily time-weighted blood pressure statistics for multiple time periods
@@ -911,6 +1531,7 @@ type BPStats struct {
 	Stats14 *BPPeriodStats `json:"stats_14,omitempty"`
 	Stats30 *BPPeriodStats `json:"stats_30,omitempty"`
 	Stats60 *BPPeriodStats `json:"stats_60,omitempty"`
+	Trend   string         `json:"trend"` // Systolic direction, see BPTrend
 }
 
 // GetBPDailyWeightedStats calculates daily time-weighted blood pressure averages.
@@ -921,30 +1542,13 @@ func (s *Store) GetBPDailyWeightedStats(ctx context.Context, userID int64) (*BPS
 	maxDays := 60
 	windowStart := truncateToDayUTC(now.AddDate(0, 0, -maxDays))
 
-	var readings []BloodPressure
-	{
-		rows, err := s.db.QueryContext(ctx,
-			"SELECT measured_at, systolic, diastolic FROM blood_pressure_readings WHERE user_id = ? AND ignore_calc = 0 AND measured_at >= ? ORDER BY measured_at ASC",
-			userID, windowStart)
-		if err != nil {
-			return nil, err
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var bp BloodPressure
-			if err := rows.Scan(&bp.MeasuredAt, &bp.Systolic, &bp.Diastolic); err != nil {
-				return nil, err
-			}
-			readings = append(readings, bp)
-		}
-		if err := rows.Err(); err != nil {
-			return nil, err
-		}
+	readings, err := s.getBPReadingsForStats(ctx, userID, windowStart)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(readings) == 0 {
-		return &BPStats{}, nil
+		return &BPStats{Trend: BPTrendStable}, nil
 	}
 
 	type dayAgg struct {
@@ -955,44 +1559,15 @@ func (s *Store) GetBPDailyWeightedStats(ctx context.Context, userID int64) (*BPS
 
 	dayAggs := map[time.Time]*dayAgg{}
 
-	// For each day, weight readings only within that day.
-	for i := 0; i < len(readings); i++ {
-		if i+1 < len(readings) && readings[i+1].MeasuredAt.Equal(readings[i].MeasuredAt) {
-			continue
-		}
-		start := readings[i].MeasuredAt.UTC()
-		if start.After(now) {
-			continue
-		}
-		dayStart := truncateToDayUTC(start)
-		dayEnd := dayStart.Add(24 * time.Hour)
-
-		end := dayEnd
-		if i+1 < len(readings) {
-			next := readings[i+1].MeasuredAt.UTC()
-			if truncateToDayUTC(next).Equal(dayStart) {
-				end = next
-			}
-		}
-		if end.After(now) {
-			end = now
-		}
-		if !end.After(start) {
-			continue
-		}
-
-		dur := end.Sub(start).Seconds()
-		if dur <= 0 {
-			continue
-		}
-		agg := dayAggs[dayStart]
+	for _, seg := range bpWeightedSegments(readings, now) {
+		agg := dayAggs[seg.day]
 		if agg == nil {
 			agg = &dayAgg{}
-			dayAggs[dayStart] = agg
+			dayAggs[seg.day] = agg
 		}
-		agg.sumSys += float64(readings[i].Systolic) * dur
-		agg.sumDia += float64(readings[i].Diastolic) * dur
-		agg.durSec += dur
+		agg.sumSys += float64(seg.reading.Systolic) * seg.durSec
+		agg.sumDia += float64(seg.reading.Diastolic) * seg.durSec
+		agg.durSec += seg.durSec
 	}
 
 	buildStats := func(periodDays int) *BPPeriodStats {
@@ -1027,11 +1602,14 @@ func (s *Store) GetBPDailyWeightedStats(ctx context.Context, userID int64) (*BPS
 			readingsCount++
 		}
 
+		meanSys, meanDia := sumSys/float64(days), sumDia/float64(days)
 		return &BPPeriodStats{
-			Systolic:  int(math.Round(sumSys / float64(days))),
-			Diastolic: int(math.Round(sumDia / float64(days))),
-			Days:      days,
-			Readings:  readingsCount,
+			Systolic:      int(math.Round(meanSys)),
+			Diastolic:     int(math.Round(meanDia)),
+			Days:          days,
+			Readings:      readingsCount,
+			SystolicMean:  meanSys,
+			DiastolicMean: meanDia,
 		}
 	}
 
@@ -1039,10 +1617,78 @@ func (s *Store) GetBPDailyWeightedStats(ctx context.Context, userID int64) (*BPS
 	result.Stats14 = buildStats(14)
 	result.Stats30 = buildStats(30)
 	result.Stats60 = buildStats(60)
+	result.Trend = result.BPTrend(DefaultBPTrendThreshold)
 
 	return result, nil
 }
 
+// getBPReadingsForStats loads readings used for statistics (ignore_calc excluded), oldest first
+func (s *Store) getBPReadingsForStats(ctx context.Context, userID int64, since time.Time) ([]BloodPressure, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT measured_at, systolic, diastolic FROM blood_pressure_readings WHERE user_id = ? AND ignore_calc = 0 AND measured_at >= ? ORDER BY measured_at ASC",
+		userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var readings []BloodPressure
+	for rows.Next() {
+		var bp BloodPressure
+		if err := rows.Scan(&bp.MeasuredAt, &bp.Systolic, &bp.Diastolic); err != nil {
+			return nil, err
+		}
+		readings = append(readings, bp)
+	}
+	return readings, rows.Err()
+}
+
+// bpSegment is the stretch of a day a reading is taken to represent
+type bpSegment struct {
+	reading BloodPressure
+	day     time.Time // UTC day start
+	durSec  float64
+}
+
+// bpWeightedSegments weights each reading by the time until the next reading of the
+// same day, or until the end of the day (capped at now) for the day's last reading.
+// Readings sharing a timestamp are collapsed to the last one.
+func bpWeightedSegments(readings []BloodPressure, now time.Time) []bpSegment {
+	var segments []bpSegment
+	for i := 0; i < len(readings); i++ {
+		if i+1 < len(readings) && readings[i+1].MeasuredAt.Equal(readings[i].MeasuredAt) {
+			continue
+		}
+		start := readings[i].MeasuredAt.UTC()
+		if start.After(now) {
+			continue
+		}
+		dayStart := truncateToDayUTC(start)
+		dayEnd := dayStart.Add(24 * time.Hour)
+
+		end := dayEnd
+		if i+1 < len(readings) {
+			next := readings[i+1].MeasuredAt.UTC()
+			if truncateToDayUTC(next).Equal(dayStart) {
+				end = next
+			}
+		}
+		if end.After(now) {
+			end = now
+		}
+		if !end.After(start) {
+			continue
+		}
+
+		dur := end.Sub(start).Seconds()
+		if dur <= 0 {
+			continue
+		}
+		segments = append(segments, bpSegment{reading: readings[i], day: dayStart, durSec: dur})
+	}
+	return segments
+}
+
 func truncateToDayUTC(t time.Time) time.Time {
 	utc := t.UTC()
 	return time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
@@ -1052,8 +1698,8 @@ func truncateToDayUTC(t time.Time) time.Time {
 
 func (s *Store) CreateWeightLog(ctx context.Context, w *WeightLog) (int64, error) {
 	res, err := s.db.ExecContext(ctx,
-		"INSERT INTO weight_logs (user_id, measured_at, weight, weight_trend, body_fat, body_fat_trend, muscle_mass, muscle_mass_trend, notes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
-		w.UserID, w.MeasuredAt, w.Weight, w.WeightTrend, w.BodyFat, w.BodyFatTrend, w.MuscleMass, w.MuscleMassTrend, w.Notes)
+		"INSERT INTO weight_logs (user_id, measured_at, weight, weight_trend, body_fat, body_fat_trend, muscle_mass, muscle_mass_trend, notes, tag) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		w.UserID, w.MeasuredAt, w.Weight, w.WeightTrend, w.BodyFat, w.BodyFatTrend, w.MuscleMass, w.MuscleMassTrend, w.Notes, w.Tag)
 	if err != nil {
 		return 0, err
 	}
@@ -1061,15 +1707,46 @@ func (s *Store) CreateWeightLog(ctx context.Context, w *WeightLog) (int64, error
 }
 
 func (s *Store) GetWeightLogs(ctx context.Context, userID int64, since time.Time) ([]WeightLog, error) {
-	query := "SELECT id, user_id, measured_at, weight, weight_trend, body_fat, body_fat_trend, muscle_mass, muscle_mass_trend, notes FROM weight_logs WHERE user_id = ?"
+	return s.getWeightLogs(ctx, userID, since, time.Time{}, "", 0)
+}
+
+// GetWeightLogsBetween returns logs measured within [from, to], newest first
+func (s *Store) GetWeightLogsBetween(ctx context.Context, userID int64, from, to time.Time) ([]WeightLog, error) {
+	return s.getWeightLogs(ctx, userID, from, to, "", 0)
+}
+
+// GetWeightLogsByTag returns the logs since the given time that carry the tag, newest first
+func (s *Store) GetWeightLogsByTag(ctx context.Context, userID int64, since time.Time, tag string) ([]WeightLog, error) {
+	return s.getWeightLogs(ctx, userID, since, time.Time{}, tag, 0)
+}
+
+// GetRecentWeightLogs returns at most limit logs since the given time, newest first
+func (s *Store) GetRecentWeightLogs(ctx context.Context, userID int64, since time.Time, limit int) ([]WeightLog, error) {
+	return s.getWeightLogs(ctx, userID, since, time.Time{}, "", limit)
+}
+
+func (s *Store) getWeightLogs(ctx context.Context, userID int64, since, until time.Time, tag string, limit int) ([]WeightLog, error) {
+	query := "SELECT id, user_id, measured_at, weight, weight_trend, body_fat, body_fat_trend, muscle_mass, muscle_mass_trend, notes, tag FROM weight_logs WHERE user_id = ?"
 	args := []interface{}{userID}
 
 	if !since.IsZero() {
 		query += " AND measured_at >= ?"
 		args = append(args, since)
 	}
+	if !until.IsZero() {
+		query += " AND measured_at <= ?"
+		args = append(args, until)
+	}
+	if tag != "" {
+		query += " AND tag = ?"
+		args = append(args, tag)
+	}
 
 	query += " ORDER BY measured_at DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -1081,9 +1758,9 @@ func (s *Store) GetWeightLogs(ctx context.Context, userID int64, since time.Time
 	for rows.Next() {
 		var w WeightLog
 		var weightTrend, bodyFat, bodyFatTrend, muscleMass, muscleMassTrend sql.NullFloat64
-		var notes sql.NullString
+		var notes, tag sql.NullString
 
-		if err := rows.Scan(&w.ID, &w.UserID, &w.MeasuredAt, &w.Weight, &weightTrend, &bodyFat, &bodyFatTrend, &muscleMass, &muscleMassTrend, &notes); err != nil {
+		if err := rows.Scan(&w.ID, &w.UserID, &w.MeasuredAt, &w.Weight, &weightTrend, &bodyFat, &bodyFatTrend, &muscleMass, &muscleMassTrend, &notes, &tag); err != nil {
 			return nil, err
 		}
 
@@ -1105,6 +1782,9 @@ func (s *Store) GetWeightLogs(ctx context.Context, userID int64, since time.Time
 		if notes.Valid {
 			w.Notes = notes.String
 		}
+		if tag.Valid {
+			w.Tag = tag.String
+		}
 
 		logs = append(logs, w)
 	}
@@ -1123,14 +1803,24 @@ func (s *Store) DeleteWeightLog(ctx context.Context, id, userID int64) error {
 	return nil
 }
 
+// DeleteWeightLogsInRange deletes the user's weight logs measured in [from, to) and
+// returns how many were removed. Trends of later logs are left as they were.
+func (s *Store) DeleteWeightLogsInRange(ctx context.Context, userID int64, from, to time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM weight_logs WHERE user_id = ? AND measured_at >= ? AND measured_at < ?", userID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (s *Store) GetLastWeightLog(ctx context.Context, userID int64) (*WeightLog, error) {
 	var w WeightLog
 	var weightTrend, bodyFat, bodyFatTrend, muscleMass, muscleMassTrend sql.NullFloat64
-	var notes sql.NullString
+	var notes, tag sql.NullString
 
 	err := s.db.QueryRowContext(ctx,
-		"SELECT id, user_id, measured_at, weight, weight_trend, body_fat, body_fat_trend, muscle_mass, muscle_mass_trend, notes FROM weight_logs WHERE user_id = ? ORDER BY measured_at DESC LIMIT 1",
-		userID).Scan(&w.ID, &w.UserID, &w.MeasuredAt, &w.Weight, &weightTrend, &bodyFat, &bodyFatTrend, &muscleMass, &muscleMassTrend, &notes)
+		"SELECT id, user_id, measured_at, weight, weight_trend, body_fat, body_fat_trend, muscle_mass, muscle_mass_trend, notes, tag FROM weight_logs WHERE user_id = ? ORDER BY measured_at DESC LIMIT 1",
+		userID).Scan(&w.ID, &w.UserID, &w.MeasuredAt, &w.Weight, &weightTrend, &bodyFat, &bodyFatTrend, &muscleMass, &muscleMassTrend, &notes, &tag)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -1157,6 +1847,9 @@ func (s *Store) GetLastWeightLog(ctx context.Context, userID int64) (*WeightLog,
 	if notes.Valid {
 		w.Notes = notes.String
 	}
+	if tag.Valid {
+		w.Tag = tag.String
+	}
 
 	return &w, nil
 }
@@ -1164,11 +1857,11 @@ func (s *Store) GetLastWeightLog(ctx context.Context, userID int64) (*WeightLog,
 func (s *Store) GetHighestWeightRecord(ctx context.Context, userID int64) (*WeightLog, error) {
 	var w WeightLog
 	var weightTrend, bodyFat, bodyFatTrend, muscleMass, muscleMassTrend sql.NullFloat64
-	var notes sql.NullString
+	var notes, tag sql.NullString
 
 	err := s.db.QueryRowContext(ctx,
-		"SELECT id, user_id, measured_at, weight, weight_trend, body_fat, body_fat_trend, muscle_mass, muscle_mass_trend, notes FROM weight_logs WHERE user_id = ? ORDER BY weight DESC LIMIT 1",
-		userID).Scan(&w.ID, &w.UserID, &w.MeasuredAt, &w.Weight, &weightTrend, &bodyFat, &bodyFatTrend, &muscleMass, &muscleMassTrend, &notes)
+		"SELECT id, user_id, measured_at, weight, weight_trend, body_fat, body_fat_trend, muscle_mass, muscle_mass_trend, notes, tag FROM weight_logs WHERE user_id = ? ORDER BY weight DESC LIMIT 1",
+		userID).Scan(&w.ID, &w.UserID, &w.MeasuredAt, &w.Weight, &weightTrend, &bodyFat, &bodyFatTrend, &muscleMass, &muscleMassTrend, &notes, &tag)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -1195,6 +1888,9 @@ func (s *Store) GetHighestWeightRecord(ctx context.Context, userID int64) (*Weig
 	if notes.Valid {
 		w.Notes = notes.String
 	}
+	if tag.Valid {
+		w.Tag = tag.String
+	}
 
 	return &w, nil
 }
@@ -1209,6 +1905,94 @@ func CalculateWeightTrend(currentWeight float64, previousTrend *float64) float64
 	return alpha*currentWeight + (1-alpha)**previousTrend
 }
 
+// CalculateOptionalTrend continues the same EMA for a measurement not taken every time,
+// like body fat: without a current value the previous trend carries forward unchanged
+func CalculateOptionalTrend(current, previousTrend *float64) *float64 {
+	if current == nil {
+		return previousTrend
+	}
+	trend := CalculateWeightTrend(*current, previousTrend)
+	return &trend
+}
+
+// SetTrends fills in the weight, body fat and muscle mass trends of a new log from the
+// previous one (nil for the first log). A previous log without a trend, e.g. one imported
+// with raw values only, continues from its value instead.
+func (w *WeightLog) SetTrends(previous *WeightLog) {
+	var weightTrend, bodyFatTrend, muscleMassTrend *float64
+	if previous != nil {
+		weightTrend = previous.WeightTrend
+		if weightTrend == nil {
+			weightTrend = &previous.Weight
+		}
+		bodyFatTrend = previous.BodyFatTrend
+		if bodyFatTrend == nil {
+			bodyFatTrend = previous.BodyFat
+		}
+		muscleMassTrend = previous.MuscleMassTrend
+		if muscleMassTrend == nil {
+			muscleMassTrend = previous.MuscleMass
+		}
+	}
+
+	trend := CalculateWeightTrend(w.Weight, weightTrend)
+	w.WeightTrend = &trend
+	w.BodyFatTrend = CalculateOptionalTrend(w.BodyFat, bodyFatTrend)
+	w.MuscleMassTrend = CalculateOptionalTrend(w.MuscleMass, muscleMassTrend)
+}
+
+// sameTrend reports whether two optional trends are equal within float noise
+func sameTrend(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return math.Abs(*a-*b) < 1e-9
+}
+
+// RecomputeWeightTrends rebuilds the weight, body fat and muscle mass trend chains of all
+// the user's logs in measured_at order. Returns the number of logs examined and how many
+// logs had a trend change.
+func (s *Store) RecomputeWeightTrends(ctx context.Context, userID int64) (int, int, error) {
+	logs, err := s.GetWeightLogs(ctx, userID, time.Time{})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	updated := 0
+	var previous *WeightLog
+	// Logs come newest first; the chain has to be built oldest first
+	for i := len(logs) - 1; i >= 0; i-- {
+		l := logs[i]
+		stored := l
+		l.SetTrends(previous)
+		logs[i] = l
+		previous = &logs[i]
+
+		if sameTrend(stored.WeightTrend, l.WeightTrend) &&
+			sameTrend(stored.BodyFatTrend, l.BodyFatTrend) &&
+			sameTrend(stored.MuscleMassTrend, l.MuscleMassTrend) {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE weight_logs SET weight_trend = ?, body_fat_trend = ?, muscle_mass_trend = ? WHERE id = ?",
+			l.WeightTrend, l.BodyFatTrend, l.MuscleMassTrend, l.ID); err != nil {
+			return 0, 0, err
+		}
+		updated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return len(logs), updated, nil
+}
+
 func (s *Store) ImportSleepLogs(ctx context.Context, userID int64, logs []SleepLog) (int, int, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -1251,14 +2035,23 @@ func (s *Store) ImportSleepLogs(ctx context.Context, userID int64, logs []SleepL
 
 // GetSleepLogs retrieves sleep logs for a user since a given date
 func (s *Store) GetSleepLogs(ctx context.Context, userID int64, since time.Time) ([]SleepLog, error) {
+	return s.GetSleepLogsBetween(ctx, userID, since, time.Time{})
+}
+
+// GetSleepLogsBetween retrieves sleep logs that started between from and to (zero for no bound), newest first
+func (s *Store) GetSleepLogsBetween(ctx context.Context, userID int64, from, to time.Time) ([]SleepLog, error) {
 	query := `SELECT id, user_id, start_time, end_time, timezone_offset, day, light_minutes, deep_minutes, rem_minutes,
 		 awake_minutes, total_minutes, turn_over_count, heart_rate_avg, spo2_avg, user_modified, notes, created_at
 		 FROM sleep_logs WHERE user_id = ?`
 	args := []interface{}{userID}
 
-	if !since.IsZero() {
+	if !from.IsZero() {
 		query += " AND start_time >= ?"
-		args = append(args, since)
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		query += " AND start_time <= ?"
+		args = append(args, to)
 	}
 
 	query += " ORDER BY start_time DESC"
@@ -1279,6 +2072,10 @@ func (s *Store) GetSleepLogs(ctx context.Context, userID int64, since time.Time)
 			&light, &deep, &rem, &awake, &total, &turnOver, &hr, &spo2, &sl.UserModified, &notes, &sl.CreatedAt); err != nil {
 			return nil, err
 		}
+		// The driver reads the DATE column back as a timestamp; keep just YYYY-MM-DD
+		if len(sl.Day) > len("2006-01-02") {
+			sl.Day = sl.Day[:len("2006-01-02")]
+		}
 
 		if light.Valid {
 			val := int(light.Int64)
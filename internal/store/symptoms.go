@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Plausible body temperatures in Celsius
+const (
+	MinTemperatureC = 30.0
+	MaxTemperatureC = 45.0
+)
+
+// FeverThresholdC is the temperature from which an entry counts as fever
+const FeverThresholdC = 38.0
+
+type SymptomLog struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"user_id"`
+	MeasuredAt  time.Time `json:"measured_at"`
+	Temperature *float64  `json:"temperature,omitempty"` // Celsius
+	Symptoms    string    `json:"symptoms,omitempty"`
+	Severity    *int      `json:"severity,omitempty"` // 1-10
+	Notes       string    `json:"notes,omitempty"`
+}
+
+// Validate checks that the entry records something and that its values are plausible
+func (l *SymptomLog) Validate() error {
+	if l.Temperature == nil && strings.TrimSpace(l.Symptoms) == "" {
+		return fmt.Errorf("temperature or symptoms required")
+	}
+	if l.Temperature != nil && (*l.Temperature < MinTemperatureC || *l.Temperature > MaxTemperatureC) {
+		return fmt.Errorf("temperature must be between %.0f and %.0f °C", MinTemperatureC, MaxTemperatureC)
+	}
+	if l.Severity != nil && (*l.Severity < 1 || *l.Severity > 10) {
+		return fmt.Errorf("severity must be between 1 and 10")
+	}
+	return nil
+}
+
+func (s *Store) CreateSymptomLog(ctx context.Context, l *SymptomLog) (int64, error) {
+	if err := l.Validate(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO symptom_logs (user_id, measured_at, temperature, symptoms, severity, notes) VALUES (?, ?, ?, ?, ?, ?)",
+		l.UserID, l.MeasuredAt, l.Temperature, l.Symptoms, l.Severity, l.Notes)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateSymptomLog replaces the values of an existing entry. Returns sql.ErrNoRows if
+// the entry doesn't exist or belongs to another user.
+func (s *Store) UpdateSymptomLog(ctx context.Context, l *SymptomLog) error {
+	if err := l.Validate(); err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE symptom_logs SET measured_at = ?, temperature = ?, symptoms = ?, severity = ?, notes = ? WHERE id = ? AND user_id = ?",
+		l.MeasuredAt, l.Temperature, l.Symptoms, l.Severity, l.Notes, l.ID, l.UserID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetSymptomLogs returns the user's entries measured in [from, to), newest first.
+// A zero from or to leaves that side open.
+func (s *Store) GetSymptomLogs(ctx context.Context, userID int64, from, to time.Time) ([]SymptomLog, error) {
+	query := "SELECT id, user_id, measured_at, temperature, symptoms, severity, notes FROM symptom_logs WHERE user_id = ?"
+	args := []interface{}{userID}
+
+	if !from.IsZero() {
+		query += " AND measured_at >= ?"
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		query += " AND measured_at < ?"
+		args = append(args, to)
+	}
+	query += " ORDER BY measured_at DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []SymptomLog
+	for rows.Next() {
+		var l SymptomLog
+		var temperature sql.NullFloat64
+		var severity sql.NullInt64
+		var symptoms, notes sql.NullString
+		if err := rows.Scan(&l.ID, &l.UserID, &l.MeasuredAt, &temperature, &symptoms, &severity, &notes); err != nil {
+			return nil, err
+		}
+		if temperature.Valid {
+			l.Temperature = &temperature.Float64
+		}
+		if severity.Valid {
+			v := int(severity.Int64)
+			l.Severity = &v
+		}
+		if symptoms.Valid {
+			l.Symptoms = symptoms.String
+		}
+		if notes.Valid {
+			l.Notes = notes.String
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+func (s *Store) DeleteSymptomLog(ctx context.Context, id, userID int64) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM symptom_logs WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
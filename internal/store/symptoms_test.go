@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestSymptomLogs(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	userID := int64(123456)
+	day := func(d int, hour int) time.Time {
+		return time.Date(2024, 3, d, hour, 0, 0, 0, time.UTC)
+	}
+	temp := func(v float64) *float64 { return &v }
+	severity := 6
+
+	entries := []SymptomLog{
+		{UserID: userID, MeasuredAt: day(1, 8), Temperature: temp(38.5)},
+		{UserID: userID, MeasuredAt: day(2, 20), Temperature: temp(37.9), Symptoms: "cough", Severity: &severity},
+		{UserID: userID, MeasuredAt: day(3, 9), Symptoms: "fatigue"},
+		{UserID: userID, MeasuredAt: day(5, 9), Temperature: temp(36.6)},
+	}
+	for i := range entries {
+		id, err := s.CreateSymptomLog(ctx, &entries[i])
+		if err != nil {
+			t.Fatalf("CreateSymptomLog failed: %v", err)
+		}
+		entries[i].ID = id
+	}
+
+	invalid := []SymptomLog{
+		{UserID: userID, MeasuredAt: day(1, 9)},                                    // Nothing recorded
+		{UserID: userID, MeasuredAt: day(1, 9), Temperature: temp(52)},             // Implausible
+		{UserID: userID, MeasuredAt: day(1, 9), Symptoms: "x", Severity: new(int)}, // Severity 0
+	}
+	for _, l := range invalid {
+		if _, err := s.CreateSymptomLog(ctx, &l); err == nil {
+			t.Errorf("Expected validation error for %+v", l)
+		}
+	}
+
+	// Days 2 and 3 only
+	logs, err := s.GetSymptomLogs(ctx, userID, day(2, 0), day(4, 0))
+	if err != nil {
+		t.Fatalf("GetSymptomLogs failed: %v", err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("Expected 2 entries in range, got %d", len(logs))
+	}
+	if logs[0].Symptoms != "fatigue" || logs[0].Temperature != nil {
+		t.Errorf("Unexpected newest entry: %+v", logs[0])
+	}
+	if logs[1].Temperature == nil || *logs[1].Temperature != 37.9 || logs[1].Severity == nil || *logs[1].Severity != 6 {
+		t.Errorf("Unexpected second entry: %+v", logs[1])
+	}
+
+	// Update and delete
+	entries[0].Symptoms = "chills"
+	if err := s.UpdateSymptomLog(ctx, &entries[0]); err != nil {
+		t.Fatalf("UpdateSymptomLog failed: %v", err)
+	}
+	if err := s.DeleteSymptomLog(ctx, entries[3].ID, userID); err != nil {
+		t.Fatalf("DeleteSymptomLog failed: %v", err)
+	}
+	if err := s.DeleteSymptomLog(ctx, entries[3].ID, userID); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows on second delete, got %v", err)
+	}
+
+	logs, _ = s.GetSymptomLogs(ctx, userID, time.Time{}, time.Time{})
+	if len(logs) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(logs))
+	}
+	if logs[2].Symptoms != "chills" {
+		t.Errorf("Expected updated symptoms, got %q", logs[2].Symptoms)
+	}
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Trend metrics
+const (
+	TrendMetricBP     = "bp"
+	TrendMetricWeight = "weight"
+	TrendMetricSleep  = "sleep"
+)
+
+// Trend buckets
+const (
+	TrendBucketDay   = "day"
+	TrendBucketWeek  = "week"
+	TrendBucketMonth = "month"
+)
+
+// TrendPoint is one bucket of a trend series. Trend is the change from the previous
+// bucket (nil for the first one), so charts can color rising and falling stretches.
+type TrendPoint struct {
+	Date  string   `json:"date"` // Bucket start, YYYY-MM-DD
+	Value float64  `json:"value"`
+	Trend *float64 `json:"trend"`
+}
+
+// dailyValue is one metric value for a calendar day
+type dailyValue struct {
+	day   time.Time
+	value float64
+}
+
+// GetTrendSeries returns a metric over the last days as one point per bucket, each the
+// mean of its daily values: the time-weighted systolic average for bp (UTC days, as in
+// the BP stats), the EMA weight trend for weight, and total minutes asleep for sleep.
+// Buckets without data are left out; weeks start on the configured week start.
+func (s *Store) GetTrendSeries(ctx context.Context, userID int64, metric string, days int, bucket string) ([]TrendPoint, error) {
+	switch bucket {
+	case TrendBucketDay, TrendBucketWeek, TrendBucketMonth:
+	default:
+		return nil, fmt.Errorf("unknown bucket %q (want day, week or month)", bucket)
+	}
+
+	weekStart, err := s.GetWeekStart()
+	if err != nil {
+		return nil, err
+	}
+
+	now := nowFunc()
+	since := now.AddDate(0, 0, -days)
+
+	var daily []dailyValue
+	switch metric {
+	case TrendMetricBP:
+		daily, err = s.dailyWeightedSystolic(ctx, userID, since, now)
+	case TrendMetricWeight:
+		daily, err = s.dailyWeightTrend(ctx, userID, since, now.Location())
+	case TrendMetricSleep:
+		daily, err = s.dailySleepMinutes(ctx, userID, since, now.Location())
+	default:
+		return nil, fmt.Errorf("unknown metric %q (want bp, weight or sleep)", metric)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return bucketDailyValues(daily, bucket, weekStart), nil
+}
+
+func (s *Store) dailyWeightedSystolic(ctx context.Context, userID int64, since, now time.Time) ([]dailyValue, error) {
+	readings, err := s.getBPReadingsForStats(ctx, userID, truncateToDayUTC(since))
+	if err != nil {
+		return nil, err
+	}
+
+	type dayAgg struct {
+		sumSys float64
+		durSec float64
+	}
+	aggs := map[time.Time]*dayAgg{}
+	for _, seg := range bpWeightedSegments(readings, now.UTC()) {
+		agg := aggs[seg.day]
+		if agg == nil {
+			agg = &dayAgg{}
+			aggs[seg.day] = agg
+		}
+		agg.sumSys += float64(seg.reading.Systolic) * seg.durSec
+		agg.durSec += seg.durSec
+	}
+
+	var daily []dailyValue
+	for day, agg := range aggs {
+		daily = append(daily, dailyValue{day: day, value: agg.sumSys / agg.durSec})
+	}
+	return daily, nil
+}
+
+// dailyWeightTrend uses the day's last trend value, or the weight itself before any trend exists
+func (s *Store) dailyWeightTrend(ctx context.Context, userID int64, since time.Time, loc *time.Location) ([]dailyValue, error) {
+	logs, err := s.GetWeightLogs(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := map[time.Time]WeightLog{}
+	for _, l := range logs {
+		local := l.MeasuredAt.In(loc)
+		day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		if prev, ok := latest[day]; !ok || l.MeasuredAt.After(prev.MeasuredAt) {
+			latest[day] = l
+		}
+	}
+
+	var daily []dailyValue
+	for day, l := range latest {
+		value := l.Weight
+		if l.WeightTrend != nil {
+			value = *l.WeightTrend
+		}
+		daily = append(daily, dailyValue{day: day, value: value})
+	}
+	return daily, nil
+}
+
+// dailySleepMinutes sums the sleep (naps included) attributed to each day
+func (s *Store) dailySleepMinutes(ctx context.Context, userID int64, since time.Time, loc *time.Location) ([]dailyValue, error) {
+	logs, err := s.GetSleepLogs(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := map[time.Time]float64{}
+	for _, l := range logs {
+		if l.TotalMinutes == nil {
+			continue
+		}
+		day, err := time.ParseInLocation("2006-01-02", l.Day, loc)
+		if err != nil {
+			continue
+		}
+		totals[day] += float64(*l.TotalMinutes)
+	}
+
+	var daily []dailyValue
+	for day, total := range totals {
+		daily = append(daily, dailyValue{day: day, value: total})
+	}
+	return daily, nil
+}
+
+// bucketDailyValues averages daily values per bucket, oldest bucket first
+func bucketDailyValues(daily []dailyValue, bucket string, weekStart time.Weekday) []TrendPoint {
+	type bucketAgg struct {
+		sum float64
+		n   int
+	}
+	aggs := map[string]*bucketAgg{}
+	for _, d := range daily {
+		key := bucketStart(d.day, bucket, weekStart).Format("2006-01-02")
+		agg := aggs[key]
+		if agg == nil {
+			agg = &bucketAgg{}
+			aggs[key] = agg
+		}
+		agg.sum += d.value
+		agg.n++
+	}
+
+	keys := make([]string, 0, len(aggs))
+	for k := range aggs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	points := []TrendPoint{}
+	for i, k := range keys {
+		p := TrendPoint{Date: k, Value: roundTo(aggs[k].sum/float64(aggs[k].n), 1)}
+		if i > 0 {
+			change := roundTo(p.Value-points[i-1].Value, 1)
+			p.Trend = &change
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+func bucketStart(day time.Time, bucket string, weekStart time.Weekday) time.Time {
+	switch bucket {
+	case TrendBucketWeek:
+		return StartOfWeek(day, weekStart)
+	case TrendBucketMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	}
+	return day
+}
+
+func roundTo(v float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(v*scale) / scale
+}
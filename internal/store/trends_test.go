@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetTrendSeries(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	userID := int64(1)
+
+	// A Monday evening
+	fixedNow := time.Date(2025, 6, 30, 20, 0, 0, 0, time.UTC)
+	origNow := nowFunc
+	nowFunc = func() time.Time { return fixedNow }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2025, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	ptrF := func(v float64) *float64 { return &v }
+	ptrI := func(v int) *int { return &v }
+
+	check := func(name string, got []TrendPoint, want []TrendPoint) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %d points, got %d: %+v", name, len(want), len(got), got)
+		}
+		for i := range want {
+			g, w := got[i], want[i]
+			if g.Date != w.Date || g.Value != w.Value || (g.Trend == nil) != (w.Trend == nil) || (g.Trend != nil && *g.Trend != *w.Trend) {
+				t.Errorf("%s point %d: expected %s=%v (trend %v), got %s=%v (trend %v)",
+					name, i, w.Date, w.Value, fmtTrend(w.Trend), g.Date, g.Value, fmtTrend(g.Trend))
+			}
+		}
+	}
+
+	t.Run("bp", func(t *testing.T) {
+		for _, bp := range []BloodPressure{
+			// Jun 23: 130 for 12h, 120 for the last 4h of the day -> 127.5
+			{UserID: userID, MeasuredAt: at(6, 23, 8), Systolic: 130, Diastolic: 85},
+			{UserID: userID, MeasuredAt: at(6, 23, 20), Systolic: 120, Diastolic: 80},
+			{UserID: userID, MeasuredAt: at(6, 25, 12), Systolic: 140, Diastolic: 90},
+			{UserID: userID, MeasuredAt: at(6, 30, 8), Systolic: 110, Diastolic: 70},
+			{UserID: userID, MeasuredAt: at(6, 27, 8), Systolic: 200, Diastolic: 120, IgnoreCalc: true},
+		} {
+			if _, err := s.CreateBloodPressureReading(ctx, &bp); err != nil {
+				t.Fatalf("CreateBloodPressureReading failed: %v", err)
+			}
+		}
+
+		points, err := s.GetTrendSeries(ctx, userID, TrendMetricBP, 14, TrendBucketDay)
+		if err != nil {
+			t.Fatalf("GetTrendSeries failed: %v", err)
+		}
+		check("bp daily", points, []TrendPoint{
+			{Date: "2025-06-23", Value: 127.5},
+			{Date: "2025-06-25", Value: 140, Trend: ptrF(12.5)},
+			{Date: "2025-06-30", Value: 110, Trend: ptrF(-30)},
+		})
+
+		points, err = s.GetTrendSeries(ctx, userID, TrendMetricBP, 14, TrendBucketWeek)
+		if err != nil {
+			t.Fatalf("GetTrendSeries failed: %v", err)
+		}
+		check("bp weekly", points, []TrendPoint{
+			{Date: "2025-06-23", Value: 133.8},
+			{Date: "2025-06-30", Value: 110, Trend: ptrF(-23.8)},
+		})
+	})
+
+	t.Run("weight", func(t *testing.T) {
+		for _, w := range []WeightLog{
+			{UserID: userID, MeasuredAt: at(5, 20, 7), Weight: 81.5, WeightTrend: ptrF(81)},
+			{UserID: userID, MeasuredAt: at(6, 2, 7), Weight: 80.4, WeightTrend: ptrF(80)},
+			{UserID: userID, MeasuredAt: at(6, 2, 21), Weight: 79.2, WeightTrend: ptrF(79.8)}, // Last of the day wins
+			{UserID: userID, MeasuredAt: at(6, 20, 7), Weight: 78.8, WeightTrend: ptrF(79)},
+			{UserID: userID, MeasuredAt: at(6, 29, 7), Weight: 78},                       // No trend yet: the weight stands in
+			{UserID: userID, MeasuredAt: at(3, 1, 7), Weight: 90, WeightTrend: ptrF(90)}, // Outside the window
+		} {
+			if _, err := s.CreateWeightLog(ctx, &w); err != nil {
+				t.Fatalf("CreateWeightLog failed: %v", err)
+			}
+		}
+
+		points, err := s.GetTrendSeries(ctx, userID, TrendMetricWeight, 60, TrendBucketMonth)
+		if err != nil {
+			t.Fatalf("GetTrendSeries failed: %v", err)
+		}
+		check("weight monthly", points, []TrendPoint{
+			{Date: "2025-05-01", Value: 81},
+			{Date: "2025-06-01", Value: 78.9, Trend: ptrF(-2.1)},
+		})
+	})
+
+	t.Run("sleep", func(t *testing.T) {
+		logs := []SleepLog{
+			{StartTime: at(6, 28, 0), EndTime: at(6, 28, 7), Day: "2025-06-28", TotalMinutes: ptrI(420)},
+			{StartTime: at(6, 28, 14), EndTime: at(6, 28, 15), Day: "2025-06-28", TotalMinutes: ptrI(30)}, // Nap
+			{StartTime: at(6, 29, 1), EndTime: at(6, 29, 7), Day: "2025-06-29", TotalMinutes: ptrI(390)},
+			{StartTime: at(6, 30, 1), EndTime: at(6, 30, 2), Day: "2025-06-30"}, // No total recorded
+		}
+		if _, _, err := s.ImportSleepLogs(ctx, userID, logs); err != nil {
+			t.Fatalf("ImportSleepLogs failed: %v", err)
+		}
+
+		points, err := s.GetTrendSeries(ctx, userID, TrendMetricSleep, 7, TrendBucketDay)
+		if err != nil {
+			t.Fatalf("GetTrendSeries failed: %v", err)
+		}
+		check("sleep daily", points, []TrendPoint{
+			{Date: "2025-06-28", Value: 450},
+			{Date: "2025-06-29", Value: 390, Trend: ptrF(-60)},
+		})
+
+		// Saturday and Sunday share a Monday-first week but not a Sunday-first one
+		points, err = s.GetTrendSeries(ctx, userID, TrendMetricSleep, 7, TrendBucketWeek)
+		if err != nil {
+			t.Fatalf("GetTrendSeries failed: %v", err)
+		}
+		check("sleep weekly from Monday", points, []TrendPoint{
+			{Date: "2025-06-23", Value: 420},
+		})
+
+		if err := s.SetWeekStart(time.Sunday); err != nil {
+			t.Fatalf("SetWeekStart failed: %v", err)
+		}
+		t.Cleanup(func() { s.SetWeekStart(DefaultWeekStart) })
+		points, err = s.GetTrendSeries(ctx, userID, TrendMetricSleep, 7, TrendBucketWeek)
+		if err != nil {
+			t.Fatalf("GetTrendSeries failed: %v", err)
+		}
+		check("sleep weekly from Sunday", points, []TrendPoint{
+			{Date: "2025-06-22", Value: 450},
+			{Date: "2025-06-29", Value: 390, Trend: ptrF(-60)},
+		})
+	})
+
+	if _, err := s.GetTrendSeries(ctx, userID, "steps", 30, TrendBucketDay); err == nil {
+		t.Error("Expected an error for an unknown metric")
+	}
+	if _, err := s.GetTrendSeries(ctx, userID, TrendMetricBP, 30, "year"); err == nil {
+		t.Error("Expected an error for an unknown bucket")
+	}
+	if err := s.SetWeekStart(time.Wednesday); err == nil {
+		t.Error("Expected an error for a week starting on Wednesday")
+	}
+}
+
+func fmtTrend(v *float64) interface{} {
+	if v == nil {
+		return "none"
+	}
+	return *v
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// CheckpointResult reports what a WAL checkpoint did
+type CheckpointResult struct {
+	At                 time.Time `json:"at"`
+	Busy               bool      `json:"busy"`                // Another connection prevented a full checkpoint
+	LogFrames          int       `json:"log_frames"`          // Frames in the WAL
+	CheckpointedFrames int       `json:"checkpointed_frames"` // Frames copied back into the database
+	WALSizeBefore      int64     `json:"wal_size_before"`     // Bytes
+	WALSizeAfter       int64     `json:"wal_size_after"`      // Bytes
+}
+
+// WALStatus describes the current WAL file and the most recent checkpoint
+type WALStatus struct {
+	SizeBytes      int64             `json:"size_bytes"`
+	LastCheckpoint *CheckpointResult `json:"last_checkpoint,omitempty"`
+}
+
+// databaseFile strips the driver's "file:" prefix and query parameters from a DSN.
+// In-memory databases have no file and return "".
+func databaseFile(dsn string) string {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	if path == "" || path == ":memory:" {
+		return ""
+	}
+	return path
+}
+
+// WALSize returns the size of the WAL file in bytes (0 if there is none)
+func (s *Store) WALSize() (int64, error) {
+	if s.path == "" {
+		return 0, nil
+	}
+	info, err := os.Stat(s.path + "-wal")
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// CheckpointWAL runs a PASSIVE checkpoint. It never waits for readers or writers, so
+// Litestream's long-running read transaction keeps the frames it hasn't replicated yet
+// and replication is not blocked; whatever can be copied back is, so the WAL can be
+// reused from the start instead of growing.
+func (s *Store) CheckpointWAL(ctx context.Context) (*CheckpointResult, error) {
+	s.walMu.Lock()
+	defer s.walMu.Unlock()
+
+	result := &CheckpointResult{At: nowFunc()}
+
+	var err error
+	if result.WALSizeBefore, err = s.WALSize(); err != nil {
+		return nil, err
+	}
+
+	var busy int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(PASSIVE)").Scan(&busy, &result.LogFrames, &result.CheckpointedFrames); err != nil {
+		return nil, err
+	}
+	result.Busy = busy != 0
+
+	if result.WALSizeAfter, err = s.WALSize(); err != nil {
+		return nil, err
+	}
+
+	s.lastCheckpoint = result
+	return result, nil
+}
+
+// GetWALStatus returns the current WAL size and the last checkpoint result, if any
+func (s *Store) GetWALStatus() (*WALStatus, error) {
+	size, err := s.WALSize()
+	if err != nil {
+		return nil, err
+	}
+
+	s.walMu.Lock()
+	defer s.walMu.Unlock()
+	return &WALStatus{SizeBytes: size, LastCheckpoint: s.lastCheckpoint}, nil
+}
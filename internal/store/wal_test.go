@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpointWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(path + "?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	if status, err := s.GetWALStatus(); err != nil || status.LastCheckpoint != nil {
+		t.Fatalf("Expected no checkpoint yet, got %+v (%v)", status, err)
+	}
+
+	for i := 0; i < 50; i++ {
+		s.CreateWeightLog(ctx, &WeightLog{UserID: 1, MeasuredAt: time.Now().Add(time.Duration(i) * time.Minute), Weight: 80})
+	}
+
+	size, err := s.WALSize()
+	if err != nil {
+		t.Fatalf("WALSize failed: %v", err)
+	}
+	if size == 0 {
+		t.Fatal("Expected a non-empty WAL after writes")
+	}
+
+	res, err := s.CheckpointWAL(ctx)
+	if err != nil {
+		t.Fatalf("CheckpointWAL failed: %v", err)
+	}
+	if res.Busy {
+		t.Error("Expected checkpoint not to be busy without other connections")
+	}
+	if res.LogFrames == 0 || res.CheckpointedFrames != res.LogFrames {
+		t.Errorf("Expected all frames checkpointed, got %d/%d", res.CheckpointedFrames, res.LogFrames)
+	}
+	if res.WALSizeBefore != size {
+		t.Errorf("Expected WAL size before %d, got %d", size, res.WALSizeBefore)
+	}
+
+	status, err := s.GetWALStatus()
+	if err != nil {
+		t.Fatalf("GetWALStatus failed: %v", err)
+	}
+	if status.LastCheckpoint != res {
+		t.Error("Expected status to report the last checkpoint")
+	}
+
+	// In-memory databases have no WAL file
+	mem, _ := New(":memory:")
+	defer mem.Close()
+	if size, err := mem.WALSize(); err != nil || size != 0 {
+		t.Errorf("Expected 0 WAL size for :memory:, got %d (%v)", size, err)
+	}
+	if _, err := mem.CheckpointWAL(ctx); err != nil {
+		t.Errorf("CheckpointWAL on :memory: failed: %v", err)
+	}
+}
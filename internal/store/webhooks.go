@@ -0,0 +1,123 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Webhook is an outbound integration notified about selected event types
+type Webhook struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateWebhook registers a webhook for the given event types
+func (s *Store) CreateWebhook(userID int64, url string, events []string, secret string) (*Webhook, error) {
+	res, err := s.db.Exec(`
+		INSERT INTO webhooks (user_id, url, events, secret)
+		VALUES (?, ?, ?, ?)`,
+		userID, url, strings.Join(events, ","), secret)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetWebhook(id)
+}
+
+// GetWebhook returns a webhook by ID, or nil if not found
+func (s *Store) GetWebhook(id int64) (*Webhook, error) {
+	var wh Webhook
+	var events string
+	err := s.db.QueryRow(`
+		SELECT id, user_id, url, events, secret, active, created_at
+		FROM webhooks WHERE id = ?`, id).Scan(
+		&wh.ID, &wh.UserID, &wh.URL, &events, &wh.Secret, &wh.Active, &wh.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	wh.Events = splitEvents(events)
+	return &wh, nil
+}
+
+// ListWebhooks returns all webhooks of a user
+func (s *Store) ListWebhooks(userID int64) ([]Webhook, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, url, events, secret, active, created_at
+		FROM webhooks WHERE user_id = ?
+		ORDER BY id ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []Webhook
+	for rows.Next() {
+		var wh Webhook
+		var events string
+		if err := rows.Scan(&wh.ID, &wh.UserID, &wh.URL, &events, &wh.Secret, &wh.Active, &wh.CreatedAt); err != nil {
+			return nil, err
+		}
+		wh.Events = splitEvents(events)
+		hooks = append(hooks, wh)
+	}
+	return hooks, nil
+}
+
+// GetWebhooksForEvent returns the active webhooks of a user subscribed to an event type
+func (s *Store) GetWebhooksForEvent(userID int64, event string) ([]Webhook, error) {
+	hooks, err := s.ListWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []Webhook
+	for _, wh := range hooks {
+		if !wh.Active {
+			continue
+		}
+		for _, e := range wh.Events {
+			if e == event {
+				matching = append(matching, wh)
+				break
+			}
+		}
+	}
+	return matching, nil
+}
+
+// DeleteWebhook removes a webhook owned by the user
+func (s *Store) DeleteWebhook(id, userID int64) error {
+	res, err := s.db.Exec("DELETE FROM webhooks WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func splitEvents(events string) []string {
+	var out []string
+	for _, e := range strings.Split(events, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
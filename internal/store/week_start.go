@@ -0,0 +1,38 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DefaultWeekStart is used until the user picks a week start
+const DefaultWeekStart = time.Monday
+
+// GetWeekStart returns the first day of the week for weekly aggregations
+func (s *Store) GetWeekStart() (time.Weekday, error) {
+	var day sql.NullInt64
+	err := s.db.QueryRow("SELECT week_start FROM settings WHERE id = 1").Scan(&day)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	if !day.Valid {
+		return DefaultWeekStart, nil
+	}
+	return time.Weekday(day.Int64), nil
+}
+
+// SetWeekStart stores the first day of the week; only Sunday and Monday are supported
+func (s *Store) SetWeekStart(day time.Weekday) error {
+	if day != time.Sunday && day != time.Monday {
+		return fmt.Errorf("week must start on Sunday or Monday")
+	}
+	_, err := s.db.Exec("UPDATE settings SET week_start = ? WHERE id = 1", int(day))
+	return err
+}
+
+// StartOfWeek returns the first day of the week containing day
+func StartOfWeek(day time.Time, first time.Weekday) time.Time {
+	offset := (int(day.Weekday()) - int(first) + 7) % 7
+	return time.Date(day.Year(), day.Month(), day.Day()-offset, 0, 0, 0, 0, day.Location())
+}
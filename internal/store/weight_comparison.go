@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// minWeightComparisonLogs is the fewest weigh-ins each side of a comparison needs
+const minWeightComparisonLogs = 2
+
+// WeightComparison contrasts the average weight before and after a point in time,
+// e.g. a medication's start date. Averages are nil without weigh-ins on that side;
+// Delta is nil while either side has fewer than minWeightComparisonLogs weigh-ins.
+type WeightComparison struct {
+	At               time.Time `json:"at"`
+	Days             int       `json:"days"` // Window on each side
+	BeforeCount      int       `json:"before_count"`
+	AfterCount       int       `json:"after_count"`
+	BeforeAvg        *float64  `json:"before_avg"`
+	AfterAvg         *float64  `json:"after_avg"`
+	Delta            *float64  `json:"delta"` // AfterAvg - BeforeAvg in kg
+	InsufficientData bool      `json:"insufficient_data"`
+}
+
+// CompareWeightAround splits the weigh-ins of the days before and after at and
+// averages each side. Logs measured exactly at the split count as after.
+func (s *Store) CompareWeightAround(ctx context.Context, userID int64, at time.Time, days int) (*WeightComparison, error) {
+	logs, err := s.GetWeightLogsBetween(ctx, userID, at.AddDate(0, 0, -days), at.AddDate(0, 0, days))
+	if err != nil {
+		return nil, err
+	}
+
+	var beforeSum, afterSum float64
+	result := &WeightComparison{At: at, Days: days}
+	for _, l := range logs {
+		if l.MeasuredAt.Before(at) {
+			beforeSum += l.Weight
+			result.BeforeCount++
+		} else {
+			afterSum += l.Weight
+			result.AfterCount++
+		}
+	}
+
+	if result.BeforeCount > 0 {
+		avg := roundKg(beforeSum / float64(result.BeforeCount))
+		result.BeforeAvg = &avg
+	}
+	if result.AfterCount > 0 {
+		avg := roundKg(afterSum / float64(result.AfterCount))
+		result.AfterAvg = &avg
+	}
+
+	if result.BeforeCount < minWeightComparisonLogs || result.AfterCount < minWeightComparisonLogs {
+		result.InsufficientData = true
+		return result, nil
+	}
+	delta := roundKg(afterSum/float64(result.AfterCount) - beforeSum/float64(result.BeforeCount))
+	result.Delta = &delta
+	return result, nil
+}
+
+// roundKg rounds a weight to two decimals
+func roundKg(kg float64) float64 {
+	return math.Round(kg*100) / 100
+}
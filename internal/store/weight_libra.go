@@ -0,0 +1,51 @@
+package store
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// libraHeader is the preamble of a Libra (Android weight tracker) export, which its
+// importer expects verbatim
+const libraHeader = "#Version: 6\n#Units: kg\n\n#date;weight;weight trend;body fat;body fat trend;muscle mass;muscle mass trend;log\n"
+
+// libraLogCleaner keeps the free-text log column on one line and free of delimiters
+var libraLogCleaner = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", ";", ",")
+
+// WriteWeightLibra writes weight logs in Libra's import format: the fixed header, then
+// one semicolon-separated line per log with the UTC time, values with one decimal (empty
+// when unknown) and the log text. Nothing is quoted; the tag travels as a hashtag in the
+// log text since Libra has no tag column.
+func WriteWeightLibra(out io.Writer, logs []WeightLog) error {
+	wr := bufio.NewWriter(out)
+	wr.WriteString(libraHeader)
+
+	for _, l := range logs {
+		text := l.Notes
+		if l.Tag != "" {
+			text = "#" + l.Tag + " " + text
+		}
+		fields := []string{
+			l.MeasuredAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+			fmt.Sprintf("%.1f", l.Weight),
+			libraValue(l.WeightTrend),
+			libraValue(l.BodyFat),
+			libraValue(l.BodyFatTrend),
+			libraValue(l.MuscleMass),
+			libraValue(l.MuscleMassTrend),
+			strings.TrimSpace(libraLogCleaner.Replace(text)),
+		}
+		wr.WriteString(strings.Join(fields, ";"))
+		wr.WriteString("\n")
+	}
+	return wr.Flush()
+}
+
+func libraValue(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", *v)
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteWeightLibra(t *testing.T) {
+	ptrF := func(v float64) *float64 { return &v }
+	cet := time.FixedZone("CET", 3600)
+
+	logs := []WeightLog{
+		{MeasuredAt: time.Date(2024, 1, 15, 7, 30, 0, 0, time.UTC), Weight: 80.46, WeightTrend: ptrF(80.3)},
+		{
+			// Local times are written in UTC
+			MeasuredAt:      time.Date(2024, 1, 16, 8, 5, 9, 120e6, cet),
+			Weight:          79.9,
+			WeightTrend:     ptrF(80.21),
+			BodyFat:         ptrF(21.5),
+			BodyFatTrend:    ptrF(21.6),
+			MuscleMass:      ptrF(35),
+			MuscleMassTrend: ptrF(34.96),
+			Notes:           "after run; \"felt great\", hydrated\nsecond line",
+			Tag:             "post-workout",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteWeightLibra(&buf, logs); err != nil {
+		t.Fatalf("WriteWeightLibra failed: %v", err)
+	}
+
+	want := "#Version: 6\n" +
+		"#Units: kg\n" +
+		"\n" +
+		"#date;weight;weight trend;body fat;body fat trend;muscle mass;muscle mass trend;log\n" +
+		"2024-01-15T07:30:00.000Z;80.5;80.3;;;;;\n" +
+		"2024-01-16T07:05:09.120Z;79.9;80.2;21.5;21.6;35.0;35.0;#post-workout after run, \"felt great\", hydrated second line\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Unexpected Libra output:\ngot:\n%q\nwant:\n%q", got, want)
+	}
+}
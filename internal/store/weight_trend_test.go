@@ -0,0 +1,29 @@
+package store
+
+import "testing"
+
+func TestSetTrends_PreviousWithoutTrend(t *testing.T) {
+	fat, muscle := 20.0, 35.0
+	// Imported with raw values only
+	previous := &WeightLog{Weight: 80, BodyFat: &fat, MuscleMass: &muscle}
+
+	newFat, newMuscle := 21.0, 36.0
+	w := &WeightLog{Weight: 82, BodyFat: &newFat, MuscleMass: &newMuscle}
+	w.SetTrends(previous)
+
+	prevWeight := 80.0
+	for name, pair := range map[string][2]float64{
+		"weight":      {*w.WeightTrend, CalculateWeightTrend(82, &prevWeight)},
+		"body fat":    {*w.BodyFatTrend, CalculateWeightTrend(21, &fat)},
+		"muscle mass": {*w.MuscleMassTrend, CalculateWeightTrend(36, &muscle)},
+	} {
+		if pair[0] != pair[1] {
+			t.Errorf("Expected %s trend %.4f continuing from the previous value, got %.4f", name, pair[1], pair[0])
+		}
+	}
+
+	// Starting from the raw value differs from starting a new trend
+	if *w.WeightTrend == CalculateWeightTrend(82, nil) {
+		t.Errorf("Expected the weight trend to continue from 80, got a fresh trend %.4f", *w.WeightTrend)
+	}
+}
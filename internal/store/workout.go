@@ -3,7 +3,9 @@ package store
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
+	"unicode"
 )
 
 // -- Workout Tracking --
@@ -35,14 +37,16 @@ type WorkoutVariant struct {
 
 // WorkoutExercise represents an exercise within a variant
 type WorkoutExercise struct {
-	ID             int64    `json:"id"`
-	VariantID      int64    `json:"variant_id"`
-	ExerciseName   string   `json:"exercise_name"`
-	TargetSets     int      `json:"target_sets"`
-	TargetRepsMin  int      `json:"target_reps_min"`
-	TargetRepsMax  *int     `json:"target_reps_max,omitempty"`
-	TargetWeightKg *float64 `json:"target_weight_kg,omitempty"`
-	OrderIndex     int      `json:"order_index"`
+	ID              int64    `json:"id"`
+	VariantID       int64    `json:"variant_id"`
+	ExerciseName    string   `json:"exercise_name"`
+	TargetSets      int      `json:"target_sets"`
+	TargetRepsMin   int      `json:"target_reps_min"`
+	TargetRepsMax   *int     `json:"target_reps_max,omitempty"`
+	TargetWeightKg  *float64 `json:"target_weight_kg,omitempty"`
+	TargetWeightMin *float64 `json:"target_weight_min,omitempty"`
+	TargetWeightMax *float64 `json:"target_weight_max,omitempty"`
+	OrderIndex      int      `json:"order_index"`
 }
 
 // WorkoutSession represents an actual workout instance
@@ -269,7 +273,104 @@ func (s *Store) DeleteWorkoutVariant(id int64) error {
 
 // -- Exercise Methods --
 
+// NormalizeExerciseName trims and collapses whitespace, splits CamelCase words
+// and title-cases the result, so "bench  press", "BENCH PRESS" and "BenchPress"
+// all become "Bench Press". In mixed-case names all-caps words (e.g. the "RDL"
+// in "Romanian RDL") are kept as acronyms.
+func NormalizeExerciseName(name string) string {
+	var spaced []rune
+	runes := []rune(strings.TrimSpace(name))
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			spaced = append(spaced, ' ')
+		}
+		spaced = append(spaced, r)
+	}
+
+	words := strings.Fields(string(spaced))
+	allCaps := strings.ToUpper(string(spaced)) == string(spaced)
+	for i, w := range words {
+		if !allCaps && len([]rune(w)) > 1 && strings.ToUpper(w) == w {
+			continue
+		}
+		wr := []rune(strings.ToLower(w))
+		wr[0] = unicode.ToUpper(wr[0])
+		words[i] = string(wr)
+	}
+	return strings.Join(words, " ")
+}
+
+// exerciseNameKey reduces a name to its matching key, used to match spelling
+// variants like "Push-ups", "push ups" and "pushups". It must stay in sync with
+// exerciseNameKeySQL so lookups can be done in the database.
+func exerciseNameKey(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == ' ' || r == '-' || r == '_' || r == '.':
+			continue
+		case r >= 'A' && r <= 'Z':
+			// SQLite's LOWER only folds ASCII
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// exerciseNameKeySQL returns the SQL equivalent of exerciseNameKey for column.
+func exerciseNameKeySQL(column string) string {
+	return "LOWER(REPLACE(REPLACE(REPLACE(REPLACE(" + column + ", ' ', ''), '-', ''), '_', ''), '.', ''))"
+}
+
+// SuggestExerciseName returns a previously used exercise name that matches the
+// given one once spacing, case and punctuation are ignored. Returns "" if the
+// name is new or already spelled the canonical way.
+func (s *Store) SuggestExerciseName(userID int64, name string) (string, error) {
+	key := exerciseNameKey(name)
+	rows, err := s.db.Query(`
+		SELECT we.exercise_name
+		FROM workout_exercises we
+		JOIN workout_variants wv ON we.variant_id = wv.id
+		JOIN workout_groups wg ON wv.group_id = wg.id
+		WHERE wg.user_id = ? AND `+exerciseNameKeySQL("we.exercise_name")+` = ?
+		UNION ALL
+		SELECT wel.exercise_name
+		FROM workout_exercise_logs wel
+		JOIN workout_sessions ws ON wel.session_id = ws.id
+		WHERE ws.user_id = ? AND `+exerciseNameKeySQL("wel.exercise_name")+` = ?`, userID, key, userID, key)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var existing string
+		if err := rows.Scan(&existing); err != nil {
+			return "", err
+		}
+		counts[existing]++
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	// Prefer the most frequently used spelling
+	best, bestCount := "", 0
+	for n, c := range counts {
+		if c > bestCount || (c == bestCount && n < best) {
+			best, bestCount = n, c
+		}
+	}
+	if best == name {
+		return "", nil
+	}
+	return best, nil
+}
+
 func (s *Store) AddExerciseToVariant(variantID int64, exerciseName string, targetSets, targetRepsMin int, targetRepsMax *int, targetWeightKg *float64, orderIndex int) (*WorkoutExercise, error) {
+	exerciseName = NormalizeExerciseName(exerciseName)
 	res, err := s.db.Exec(`
 		INSERT INTO workout_exercises (variant_id, exercise_name, target_sets, target_reps_min, target_reps_max, target_weight_kg, order_index)
 		VALUES (?, ?, ?, ?, ?, ?, ?)`,
@@ -288,7 +389,7 @@ func (s *Store) AddExerciseToVariant(variantID int64, exerciseName string, targe
 
 func (s *Store) ListExercisesByVariant(variantID int64) ([]WorkoutExercise, error) {
 	rows, err := s.db.Query(`
-		SELECT id, variant_id, exercise_name, target_sets, target_reps_min, target_reps_max, target_weight_kg, order_index
+		SELECT id, variant_id, exercise_name, target_sets, target_reps_min, target_reps_max, target_weight_kg, target_weight_min, target_weight_max, order_index
 		FROM workout_exercises 
 		WHERE variant_id = ? 
 		ORDER BY order_index ASC`, variantID)
@@ -301,8 +402,8 @@ func (s *Store) ListExercisesByVariant(variantID int64) ([]WorkoutExercise, erro
 	for rows.Next() {
 		var e WorkoutExercise
 		var repsMax sql.NullInt64
-		var weightKg sql.NullFloat64
-		if err := rows.Scan(&e.ID, &e.VariantID, &e.ExerciseName, &e.TargetSets, &e.TargetRepsMin, &repsMax, &weightKg, &e.OrderIndex); err != nil {
+		var weightKg, weightMin, weightMax sql.NullFloat64
+		if err := rows.Scan(&e.ID, &e.VariantID, &e.ExerciseName, &e.TargetSets, &e.TargetRepsMin, &repsMax, &weightKg, &weightMin, &weightMax, &e.OrderIndex); err != nil {
 			return nil, err
 		}
 		if repsMax.Valid {
@@ -312,6 +413,7 @@ func (s *Store) ListExercisesByVariant(variantID int64) ([]WorkoutExercise, erro
 		if weightKg.Valid {
 			e.TargetWeightKg = &weightKg.Float64
 		}
+		e.setWeightRange(weightMin, weightMax)
 		exercises = append(exercises, e)
 	}
 	return exercises, nil
@@ -320,11 +422,11 @@ func (s *Store) ListExercisesByVariant(variantID int64) ([]WorkoutExercise, erro
 func (s *Store) GetWorkoutExercise(id int64) (*WorkoutExercise, error) {
 	var e WorkoutExercise
 	var repsMax sql.NullInt64
-	var weightKg sql.NullFloat64
+	var weightKg, weightMin, weightMax sql.NullFloat64
 	err := s.db.QueryRow(`
-		SELECT id, variant_id, exercise_name, target_sets, target_reps_min, target_reps_max, target_weight_kg, order_index
+		SELECT id, variant_id, exercise_name, target_sets, target_reps_min, target_reps_max, target_weight_kg, target_weight_min, target_weight_max, order_index
 		FROM workout_exercises WHERE id = ?`, id).Scan(
-		&e.ID, &e.VariantID, &e.ExerciseName, &e.TargetSets, &e.TargetRepsMin, &repsMax, &weightKg, &e.OrderIndex,
+		&e.ID, &e.VariantID, &e.ExerciseName, &e.TargetSets, &e.TargetRepsMin, &repsMax, &weightKg, &weightMin, &weightMax, &e.OrderIndex,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -339,10 +441,12 @@ func (s *Store) GetWorkoutExercise(id int64) (*WorkoutExercise, error) {
 	if weightKg.Valid {
 		e.TargetWeightKg = &weightKg.Float64
 	}
+	e.setWeightRange(weightMin, weightMax)
 	return &e, nil
 }
 
 func (s *Store) UpdateWorkoutExercise(id int64, exerciseName string, targetSets, targetRepsMin int, targetRepsMax *int, targetWeightKg *float64, orderIndex int) error {
+	exerciseName = NormalizeExerciseName(exerciseName)
 	_, err := s.db.Exec(`
 		UPDATE workout_exercises 
 		SET exercise_name = ?, target_sets = ?, target_reps_min = ?, target_reps_max = ?, target_weight_kg = ?, order_index = ?
@@ -362,7 +466,7 @@ func (s *Store) GetAllUniqueExercises(userID int64) ([]WorkoutExercise, error) {
 	// For duplicates, select any version (we'll use MAX(id) to be deterministic)
 	query := `
 		SELECT we.id, we.variant_id, we.exercise_name, we.target_sets, 
-			we.target_reps_min, we.target_reps_max, we.target_weight_kg, we.order_index
+			we.target_reps_min, we.target_reps_max, we.target_weight_kg, we.target_weight_min, we.target_weight_max, we.order_index
 		FROM workout_exercises we
 		JOIN workout_variants wv ON we.variant_id = wv.id
 		JOIN workout_groups wg ON wv.group_id = wg.id
@@ -373,7 +477,7 @@ func (s *Store) GetAllUniqueExercises(userID int64) ([]WorkoutExercise, error) {
 				JOIN workout_variants wv2 ON we2.variant_id = wv2.id
 				JOIN workout_groups wg2 ON wv2.group_id = wg2.id
 				WHERE wg2.user_id = ? AND wg2.active = 1
-				GROUP BY we2.exercise_name
+				GROUP BY `+exerciseNameKeySQL("we2.exercise_name")+`
 			)
 		ORDER BY we.exercise_name ASC`
 
@@ -387,8 +491,8 @@ func (s *Store) GetAllUniqueExercises(userID int64) ([]WorkoutExercise, error) {
 	for rows.Next() {
 		var e WorkoutExercise
 		var repsMax sql.NullInt64
-		var weightKg sql.NullFloat64
-		if err := rows.Scan(&e.ID, &e.VariantID, &e.ExerciseName, &e.TargetSets, &e.TargetRepsMin, &repsMax, &weightKg, &e.OrderIndex); err != nil {
+		var weightKg, weightMin, weightMax sql.NullFloat64
+		if err := rows.Scan(&e.ID, &e.VariantID, &e.ExerciseName, &e.TargetSets, &e.TargetRepsMin, &repsMax, &weightKg, &weightMin, &weightMax, &e.OrderIndex); err != nil {
 			return nil, err
 		}
 		if repsMax.Valid {
@@ -398,9 +502,10 @@ func (s *Store) GetAllUniqueExercises(userID int64) ([]WorkoutExercise, error) {
 		if weightKg.Valid {
 			e.TargetWeightKg = &weightKg.Float64
 		}
+		e.setWeightRange(weightMin, weightMax)
 		exercises = append(exercises, e)
 	}
-	return exercises, nil
+	return exercises, rows.Err()
 }
 
 // -- Rotation State Methods --
@@ -434,23 +539,40 @@ func (s *Store) InitializeRotation(groupID, startingVariantID int64) error {
 }
 
 func (s *Store) AdvanceRotation(groupID int64) error {
+	nextVariantID, err := s.nextRotationVariantID(groupID)
+	if err != nil {
+		return err
+	}
+
+	// Update state
+	_, err = s.db.Exec(advanceRotationQuery, nextVariantID, groupID)
+	return err
+}
+
+const advanceRotationQuery = `
+		UPDATE workout_rotation_state 
+		SET current_variant_id = ?, last_session_date = DATE('now'), updated_at = CURRENT_TIMESTAMP
+		WHERE group_id = ?`
+
+// nextRotationVariantID returns the variant that follows the group's current one
+func (s *Store) nextRotationVariantID(groupID int64) (int64, error) {
 	// Get current state
 	state, err := s.GetRotationState(groupID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if state == nil {
-		return fmt.Errorf("no rotation state found for group %d", groupID)
+		return 0, fmt.Errorf("no rotation state found for group %d", groupID)
 	}
 
 	// Get all variants ordered by rotation_order
 	variants, err := s.ListVariantsByGroup(groupID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if len(variants) == 0 {
-		return fmt.Errorf("no variants found for group %d", groupID)
+		return 0, fmt.Errorf("no variants found for group %d", groupID)
 	}
 
 	// Find current index
@@ -469,15 +591,7 @@ func (s *Store) AdvanceRotation(groupID int64) error {
 
 	// Advance to next (circular)
 	nextIndex := (currentIndex + 1) % len(variants)
-	nextVariantID := variants[nextIndex].ID
-
-	// Update state
-	_, err = s.db.Exec(`
-		UPDATE workout_rotation_state 
-		SET current_variant_id = ?, last_session_date = DATE('now'), updated_at = CURRENT_TIMESTAMP
-		WHERE group_id = ?`,
-		nextVariantID, groupID)
-	return err
+	return variants[nextIndex].ID, nil
 }
 
 // -- Session Methods --
@@ -637,6 +751,81 @@ func (s *Store) CompleteSession(id int64) error {
 	return err
 }
 
+// CompleteSessionAsPlanned logs every planned exercise that has no log yet as completed
+// with its target sets/reps/weight, completes the session and advances the rotation of
+// rotating groups, all in one transaction. Returns the number of exercises logged.
+func (s *Store) CompleteSessionAsPlanned(sessionID int64) (int, error) {
+	session, err := s.GetWorkoutSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+	if session == nil {
+		return 0, sql.ErrNoRows
+	}
+
+	exercises, err := s.ListExercisesByVariant(session.VariantID)
+	if err != nil {
+		return 0, err
+	}
+	logs, err := s.GetExerciseLogs(sessionID)
+	if err != nil {
+		return 0, err
+	}
+	logged := make(map[int64]bool)
+	for _, l := range logs {
+		logged[l.ExerciseID] = true
+	}
+
+	// Resolve the next rotation variant up front; all reads happen before the transaction
+	var nextVariantID int64
+	group, err := s.GetWorkoutGroup(session.GroupID)
+	if err != nil {
+		return 0, err
+	}
+	rotate := group != nil && group.IsRotating
+	if rotate {
+		if nextVariantID, err = s.nextRotationVariantID(group.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	count := 0
+	for _, ex := range exercises {
+		if logged[ex.ID] {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO workout_exercise_logs (session_id, exercise_id, exercise_name, sets_completed, reps_completed, weight_kg, status, notes)
+			VALUES (?, ?, ?, ?, ?, ?, 'completed', '')`,
+			sessionID, ex.ID, NormalizeExerciseName(ex.ExerciseName), ex.TargetSets, ex.TargetRepsMin, ex.TargetWeightKg)
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE workout_sessions 
+		SET status = 'completed', completed_at = CURRENT_TIMESTAMP 
+		WHERE id = ?`, sessionID); err != nil {
+		return 0, err
+	}
+
+	if rotate {
+		if _, err := tx.Exec(advanceRotationQuery, nextVariantID, group.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, tx.Commit()
+}
+
 func (s *Store) SkipSession(id int64) error {
 	_, err := s.db.Exec("UPDATE workout_sessions SET status = 'skipped' WHERE id = ?", id)
 	return err
@@ -667,6 +856,7 @@ func (s *Store) SetSessionNotificationMessageID(id int64, messageID int) error {
 // -- Exercise Log Methods --
 
 func (s *Store) LogExercise(sessionID, exerciseID int64, exerciseName string, setsCompleted, repsCompleted *int, weightKg *float64, status, notes string) (int64, error) {
+	exerciseName = NormalizeExerciseName(exerciseName)
 	res, err := s.db.Exec(`
 		INSERT INTO workout_exercise_logs (session_id, exercise_id, exercise_name, sets_completed, reps_completed, weight_kg, status, notes)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
@@ -766,6 +956,111 @@ func (s *Store) GetGroupSnapshots(groupID int64) ([]WorkoutScheduleSnapshot, err
 
 // -- History & Stats Methods --
 
+// ExerciseHistoryEntry is a logged exercise together with the date of its session
+type ExerciseHistoryEntry struct {
+	WorkoutExerciseLog
+	SessionDate time.Time `json:"session_date"`
+}
+
+// GetExerciseHistory returns completed logs for an exercise across all sessions,
+// newest first. Names are matched by their normalized form so "Bench Press",
+// "bench press" and "BenchPress" aggregate together.
+func (s *Store) GetExerciseHistory(userID int64, exerciseName string, limit int) ([]ExerciseHistoryEntry, error) {
+	query := `
+		SELECT wel.id, wel.session_id, wel.exercise_id, wel.exercise_name, wel.sets_completed, wel.reps_completed,
+		       wel.weight_kg, wel.status, wel.notes, wel.logged_at, ws.scheduled_date
+		FROM workout_exercise_logs wel
+		JOIN workout_sessions ws ON wel.session_id = ws.id
+		WHERE ws.user_id = ? AND wel.status = 'completed' AND ` + exerciseNameKeySQL("wel.exercise_name") + ` = ?
+		ORDER BY ws.scheduled_date DESC, wel.logged_at DESC`
+	args := []interface{}{userID, exerciseNameKey(exerciseName)}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []ExerciseHistoryEntry
+	for rows.Next() {
+		var e ExerciseHistoryEntry
+		var setsCompleted, repsCompleted sql.NullInt64
+		var weightKg sql.NullFloat64
+		var notes sql.NullString
+
+		if err := rows.Scan(&e.ID, &e.SessionID, &e.ExerciseID, &e.ExerciseName, &setsCompleted, &repsCompleted,
+			&weightKg, &e.Status, &notes, &e.LoggedAt, &e.SessionDate); err != nil {
+			return nil, err
+		}
+		if setsCompleted.Valid {
+			v := int(setsCompleted.Int64)
+			e.SetsCompleted = &v
+		}
+		if repsCompleted.Valid {
+			v := int(repsCompleted.Int64)
+			e.RepsCompleted = &v
+		}
+		if weightKg.Valid {
+			e.WeightKg = &weightKg.Float64
+		}
+		if notes.Valid {
+			e.Notes = notes.String
+		}
+
+		history = append(history, e)
+	}
+	return history, rows.Err()
+}
+
+// GetExercisePersonalRecord returns the heaviest completed log for an exercise
+// (ties broken by reps), aggregated by normalized name. Returns nil if none.
+func (s *Store) GetExercisePersonalRecord(userID int64, exerciseName string) (*ExerciseHistoryEntry, error) {
+	history, err := s.GetExerciseHistory(userID, exerciseName, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *ExerciseHistoryEntry
+	for i := range history {
+		e := &history[i]
+		if e.WeightKg == nil {
+			continue
+		}
+		if best == nil || *e.WeightKg > *best.WeightKg ||
+			(*e.WeightKg == *best.WeightKg && intOrZero(e.RepsCompleted) > intOrZero(best.RepsCompleted)) {
+			best = e
+		}
+	}
+	return best, nil
+}
+
+// GetCarryOverWeight returns the weight of the most recent completed log of an
+// exercise that recorded one, so the next session can start from it. Returns nil if none.
+func (s *Store) GetCarryOverWeight(userID int64, exerciseName string) (*float64, error) {
+	history, err := s.GetExerciseHistory(userID, exerciseName, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range history {
+		if e.WeightKg != nil {
+			return e.WeightKg, nil
+		}
+	}
+	return nil, nil
+}
+
+func intOrZero(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
 func (s *Store) GetWorkoutHistory(userID int64, limit int) ([]WorkoutSession, error) {
 	query := `
 		SELECT id, group_id, variant_id, user_id, scheduled_date, scheduled_time, status, started_at, completed_at, snoozed_until, snooze_count, notification_message_id, notes
@@ -861,3 +1156,52 @@ func (s *Store) GetSnoozedSessions(userID int64) ([]WorkoutSession, error) {
 	}
 	return sessions, nil
 }
+
+// GetOpenSessionsForDate returns the user's sessions on the given day that have not been
+// started, completed or skipped yet
+func (s *Store) GetOpenSessionsForDate(userID int64, date time.Time) ([]WorkoutSession, error) {
+	query := `
+		SELECT id, group_id, variant_id, user_id, scheduled_date, scheduled_time, status, started_at, completed_at, snoozed_until, snooze_count, notification_message_id, notes
+		FROM workout_sessions
+		WHERE user_id = ? AND scheduled_date LIKE ? AND status IN ('pending', 'notified')
+		ORDER BY scheduled_time ASC`
+
+	rows, err := s.db.Query(query, userID, date.Format("2006-01-02")+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []WorkoutSession
+	for rows.Next() {
+		var ws WorkoutSession
+		var startedAt, completedAt, snoozedUntil sql.NullTime
+		var notificationMsgID sql.NullInt64
+		var notes sql.NullString
+
+		if err := rows.Scan(&ws.ID, &ws.GroupID, &ws.VariantID, &ws.UserID, &ws.ScheduledDate, &ws.ScheduledTime, &ws.Status,
+			&startedAt, &completedAt, &snoozedUntil, &ws.SnoozeCount, &notificationMsgID, &notes); err != nil {
+			return nil, err
+		}
+
+		if startedAt.Valid {
+			ws.StartedAt = &startedAt.Time
+		}
+		if completedAt.Valid {
+			ws.CompletedAt = &completedAt.Time
+		}
+		if snoozedUntil.Valid {
+			ws.SnoozedUntil = &snoozedUntil.Time
+		}
+		if notificationMsgID.Valid {
+			msgID := int(notificationMsgID.Int64)
+			ws.NotificationMessageID = &msgID
+		}
+		if notes.Valid {
+			ws.Notes = notes.String
+		}
+
+		sessions = append(sessions, ws)
+	}
+	return sessions, rows.Err()
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// WorkoutSkipDate is a day on which no workouts are planned
+type WorkoutSkipDate struct {
+	Date   string `json:"date"` // YYYY-MM-DD
+	Reason string `json:"reason,omitempty"`
+}
+
+// AddWorkoutSkipDates marks the given YYYY-MM-DD dates as workout-free. Dates that are
+// already marked keep their original reason.
+func (s *Store) AddWorkoutSkipDates(userID int64, dates []string, reason string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range dates {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO workout_skip_dates (user_id, skip_date, reason) VALUES (?, ?, ?)`,
+			userID, d, reason); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteWorkoutSkipDate unmarks a date. Returns sql.ErrNoRows if it wasn't marked.
+func (s *Store) DeleteWorkoutSkipDate(userID int64, date string) error {
+	res, err := s.db.Exec("DELETE FROM workout_skip_dates WHERE user_id = ? AND skip_date = ?", userID, date)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListWorkoutSkipDates returns skip dates on or after from (YYYY-MM-DD), earliest first
+func (s *Store) ListWorkoutSkipDates(userID int64, from string) ([]WorkoutSkipDate, error) {
+	rows, err := s.db.Query(`
+		SELECT skip_date, reason FROM workout_skip_dates
+		WHERE user_id = ? AND skip_date >= ?
+		ORDER BY skip_date`, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dates := []WorkoutSkipDate{}
+	for rows.Next() {
+		var d WorkoutSkipDate
+		var reason sql.NullString
+		if err := rows.Scan(&d.Date, &reason); err != nil {
+			return nil, err
+		}
+		d.Reason = reason.String
+		dates = append(dates, d)
+	}
+	return dates, rows.Err()
+}
+
+// IsWorkoutSkipDate reports whether the calendar day of t is marked as workout-free
+func (s *Store) IsWorkoutSkipDate(userID int64, t time.Time) (bool, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM workout_skip_dates WHERE user_id = ? AND skip_date = ?",
+		userID, t.Format("2006-01-02")).Scan(&n)
+	return n > 0, err
+}
@@ -18,30 +18,31 @@ func setupTestDB(t *testing.T) *Store {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	// Read the workout migration file
-	migrationPath := filepath.Join("migrations", "012_add_workout_tracking.sql")
-	schemaBytes, err := os.ReadFile(migrationPath)
-	if err != nil {
-		t.Fatalf("Failed to read migration file: %v", err)
-	}
+	// The workout schema plus later migrations that alter its tables
+	for _, name := range []string{"012_add_workout_tracking.sql", "036_add_exercise_weight_range.sql"} {
+		schemaBytes, err := os.ReadFile(filepath.Join("migrations", name))
+		if err != nil {
+			t.Fatalf("Failed to read migration file: %v", err)
+		}
 
-	// Extract only the SQL between "-- +goose Up" and "-- +goose Down"
-	schemaSQL := string(schemaBytes)
-	upStart := strings.Index(schemaSQL, "-- +goose Up")
-	downStart := strings.Index(schemaSQL, "-- +goose Down")
+		// Extract only the SQL between "-- +goose Up" and "-- +goose Down"
+		schemaSQL := string(schemaBytes)
+		upStart := strings.Index(schemaSQL, "-- +goose Up")
+		downStart := strings.Index(schemaSQL, "-- +goose Down")
 
-	if upStart == -1 || downStart == -1 {
-		t.Fatalf("Migration file doesn't contain goose directives")
-	}
+		if upStart == -1 || downStart == -1 {
+			t.Fatalf("Migration file %s doesn't contain goose directives", name)
+		}
 
-	// Get SQL between directives, skipping the "-- +goose Up" line itself
-	upSQL := schemaSQL[upStart:downStart]
-	upSQL = strings.TrimPrefix(upSQL, "-- +goose Up")
-	upSQL = strings.TrimSpace(upSQL)
+		// Get SQL between directives, skipping the "-- +goose Up" line itself
+		upSQL := schemaSQL[upStart:downStart]
+		upSQL = strings.TrimPrefix(upSQL, "-- +goose Up")
+		upSQL = strings.TrimSpace(upSQL)
 
-	// Execute the migration
-	if _, err := db.Exec(upSQL); err != nil {
-		t.Fatalf("Failed to execute migration: %v", err)
+		// Execute the migration
+		if _, err := db.Exec(upSQL); err != nil {
+			t.Fatalf("Failed to execute migration %s: %v", name, err)
+		}
 	}
 
 	return &Store{db: db}
@@ -638,3 +639,208 @@ func TestGetAllUniqueExercises(t *testing.T) {
 		t.Errorf("Expected 0 exercises for user2, got %d", len(emptyExercises))
 	}
 }
+
+func TestNormalizeExerciseName(t *testing.T) {
+	cases := map[string]string{
+		"  bench   press ": "Bench Press",
+		"BenchPress":       "Bench Press",
+		"BENCH PRESS":      "Bench Press",
+		"Romanian RDL":     "Romanian RDL",
+		"push-ups":         "Push-ups",
+		"":                 "",
+	}
+	for in, want := range cases {
+		if got := NormalizeExerciseName(in); got != want {
+			t.Errorf("NormalizeExerciseName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestGetExerciseHistory_AggregatesNameVariants verifies that differently spelled
+// names of the same exercise are aggregated into one history and PR
+func TestGetExerciseHistory_AggregatesNameVariants(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	userID := int64(1)
+	group, _ := s.CreateWorkoutGroup("G", "", false, userID, "[1]", "09:00", 15)
+	variant, _ := s.CreateWorkoutVariant(group.ID, "V", nil, "")
+
+	reps := 8
+	for i, entry := range []struct {
+		name   string
+		weight float64
+	}{
+		{"Bench Press", 60},
+		{"bench press", 65},
+		{"BenchPress", 70},
+		{"Squat", 100},
+	} {
+		session, err := s.CreateWorkoutSession(group.ID, variant.ID, userID, time.Now().AddDate(0, 0, -i), "09:00")
+		if err != nil {
+			t.Fatalf("CreateWorkoutSession: %v", err)
+		}
+		weight := entry.weight
+		if _, err := s.LogExercise(session.ID, 0, entry.name, &reps, &reps, &weight, "completed", ""); err != nil {
+			t.Fatalf("LogExercise: %v", err)
+		}
+	}
+
+	history, err := s.GetExerciseHistory(userID, "bench  PRESS", 0)
+	if err != nil {
+		t.Fatalf("GetExerciseHistory: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 aggregated entries, got %d", len(history))
+	}
+	for _, h := range history {
+		if h.ExerciseName != "Bench Press" {
+			t.Errorf("Expected normalized name 'Bench Press', got %q", h.ExerciseName)
+		}
+	}
+
+	pr, err := s.GetExercisePersonalRecord(userID, "benchpress")
+	if err != nil {
+		t.Fatalf("GetExercisePersonalRecord: %v", err)
+	}
+	if pr == nil || *pr.WeightKg != 70 {
+		t.Errorf("Expected PR of 70kg, got %+v", pr)
+	}
+}
+
+func TestSuggestExerciseName(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	group, _ := s.CreateWorkoutGroup("G", "", false, 1, "[1]", "09:00", 15)
+	variant, _ := s.CreateWorkoutVariant(group.ID, "V", nil, "")
+	s.AddExerciseToVariant(variant.ID, "Push-ups", 3, 10, nil, nil, 0)
+
+	suggestion, err := s.SuggestExerciseName(1, "pushups")
+	if err != nil {
+		t.Fatalf("SuggestExerciseName: %v", err)
+	}
+	if suggestion != "Push-ups" {
+		t.Errorf("Expected suggestion 'Push-ups', got %q", suggestion)
+	}
+
+	suggestion, _ = s.SuggestExerciseName(1, "Push-ups")
+	if suggestion != "" {
+		t.Errorf("Expected no suggestion for canonical name, got %q", suggestion)
+	}
+}
+
+// TestExerciseNameKey_MatchesInSQL verifies that the unique exercise list and
+// the history lookup group names the same way as exerciseNameKey
+func TestExerciseNameKey_MatchesInSQL(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	group, _ := s.CreateWorkoutGroup("G", "", false, 1, "[1]", "09:00", 15)
+	variant, _ := s.CreateWorkoutVariant(group.ID, "V", nil, "")
+	names := []string{"Push-ups", "Push Ups", "push_ups", "Pull-ups"}
+	for _, name := range names {
+		s.AddExerciseToVariant(variant.ID, name, 3, 10, nil, nil, 0)
+	}
+
+	unique, err := s.GetAllUniqueExercises(1)
+	if err != nil {
+		t.Fatalf("GetAllUniqueExercises: %v", err)
+	}
+	if len(unique) != 2 {
+		t.Errorf("Expected 2 unique exercises, got %d: %+v", len(unique), unique)
+	}
+
+	reps := 10
+	for i, name := range names {
+		session, _ := s.CreateWorkoutSession(group.ID, variant.ID, 1, time.Now().AddDate(0, 0, -i), "09:00")
+		if _, err := s.LogExercise(session.ID, 0, name, &reps, &reps, nil, "completed", ""); err != nil {
+			t.Fatalf("LogExercise: %v", err)
+		}
+	}
+
+	history, err := s.GetExerciseHistory(1, "PUSHUPS", 0)
+	if err != nil {
+		t.Fatalf("GetExerciseHistory: %v", err)
+	}
+	if len(history) != 3 {
+		t.Errorf("Expected 3 push-up entries, got %d", len(history))
+	}
+
+	history, _ = s.GetExerciseHistory(1, "pushups", 2)
+	if len(history) != 2 {
+		t.Errorf("Expected the limit to cap history at 2, got %d", len(history))
+	}
+}
+
+func TestReorderWorkoutVariants(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.db.Close()
+
+	group, err := store.CreateWorkoutGroup("Split", "", true, 1, "[1,3,5]", "18:00", 15)
+	if err != nil {
+		t.Fatalf("Failed to create workout group: %v", err)
+	}
+	var ids []int64
+	for i, name := range []string{"Day A", "Day B", "Day C"} {
+		order := i + 1
+		v, err := store.CreateWorkoutVariant(group.ID, name, &order, "")
+		if err != nil {
+			t.Fatalf("Failed to create variant: %v", err)
+		}
+		ids = append(ids, v.ID)
+	}
+	a, b, c := ids[0], ids[1], ids[2]
+
+	// Day B (position 2) is up next
+	if err := store.InitializeRotation(group.ID, b); err != nil {
+		t.Fatalf("Failed to initialize rotation: %v", err)
+	}
+
+	if err := store.ReorderWorkoutVariants(group.ID, []int64{c, a, b}); err != nil {
+		t.Fatalf("ReorderWorkoutVariants failed: %v", err)
+	}
+
+	variants, err := store.ListVariantsByGroup(group.ID)
+	if err != nil {
+		t.Fatalf("ListVariantsByGroup failed: %v", err)
+	}
+	var names []string
+	for i, v := range variants {
+		names = append(names, v.Name)
+		if v.RotationOrder == nil || *v.RotationOrder != i+1 {
+			t.Errorf("Expected %s to have rotation_order %d, got %v", v.Name, i+1, v.RotationOrder)
+		}
+	}
+	if strings.Join(names, ",") != "Day C,Day A,Day B" {
+		t.Errorf("Expected new sequence C, A, B, got %v", names)
+	}
+
+	// The rotation stays at position 2, which is now Day A
+	state, err := store.GetRotationState(group.ID)
+	if err != nil || state == nil {
+		t.Fatalf("GetRotationState failed: %v", err)
+	}
+	if state.CurrentVariantID != a {
+		t.Errorf("Expected rotation to point at Day A (%d), got %d", a, state.CurrentVariantID)
+	}
+
+	// Incomplete, duplicate or foreign lists are rejected without changes
+	for _, bad := range [][]int64{{a, b}, {a, a, b}, {a, b, 999}} {
+		if err := store.ReorderWorkoutVariants(group.ID, bad); err == nil {
+			t.Errorf("Expected %v to be rejected", bad)
+		}
+	}
+	if variants, _ := store.ListVariantsByGroup(group.ID); variants[0].ID != c {
+		t.Errorf("Expected rejected reorders to leave Day C first, got %s", variants[0].Name)
+	}
+}
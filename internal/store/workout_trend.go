@@ -0,0 +1,110 @@
+package store
+
+import (
+	"sort"
+	"time"
+)
+
+// ExerciseTrendPoint summarizes one session of an exercise. Volume is sets × reps × weight
+// over the logs that recorded all three. RelativeIntensity is the average weight lifted as
+// a percentage of the best estimated 1RM reached up to and including that session, so it
+// shows whether sessions got harder rather than just longer.
+type ExerciseTrendPoint struct {
+	SessionID         int64    `json:"session_id"`
+	Date              string   `json:"date"` // Session date, YYYY-MM-DD
+	Sets              int      `json:"sets"`
+	Reps              int      `json:"reps"` // Total over all sets
+	VolumeKg          float64  `json:"volume_kg"`
+	TopWeightKg       *float64 `json:"top_weight_kg"`
+	Estimated1RM      *float64 `json:"estimated_1rm"`      // Best of the session
+	RelativeIntensity *float64 `json:"relative_intensity"` // Percent of the best 1RM so far
+}
+
+// Estimate1RM estimates the one-rep max from a set with the Epley formula
+func Estimate1RM(weightKg float64, reps int) float64 {
+	if reps <= 1 {
+		return weightKg
+	}
+	return weightKg * (1 + float64(reps)/30)
+}
+
+// GetExerciseTrend returns one point per session of an exercise since the given time,
+// oldest first. Earlier sessions still count towards the best 1RM.
+func (s *Store) GetExerciseTrend(userID int64, exerciseName string, since time.Time) ([]ExerciseTrendPoint, error) {
+	history, err := s.GetExerciseHistory(userID, exerciseName, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	type session struct {
+		id   int64
+		date time.Time
+		logs []ExerciseHistoryEntry
+	}
+	sessions := map[int64]*session{}
+	for _, e := range history {
+		sess := sessions[e.SessionID]
+		if sess == nil {
+			sess = &session{id: e.SessionID, date: e.SessionDate}
+			sessions[e.SessionID] = sess
+		}
+		sess.logs = append(sess.logs, e)
+	}
+	ordered := make([]*session, 0, len(sessions))
+	for _, sess := range sessions {
+		ordered = append(ordered, sess)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if !ordered[i].date.Equal(ordered[j].date) {
+			return ordered[i].date.Before(ordered[j].date)
+		}
+		return ordered[i].id < ordered[j].id
+	})
+
+	points := []ExerciseTrendPoint{}
+	best1RM := 0.0
+	for _, sess := range ordered {
+		p := ExerciseTrendPoint{SessionID: sess.id, Date: sess.date.Format("2006-01-02")}
+		var weights []float64
+		for _, e := range sess.logs {
+			sets, reps := intOrZero(e.SetsCompleted), intOrZero(e.RepsCompleted)
+			p.Sets += sets
+			p.Reps += sets * reps
+			if e.WeightKg == nil || reps == 0 {
+				continue
+			}
+
+			weight := *e.WeightKg
+			p.VolumeKg += float64(sets*reps) * weight
+			weights = append(weights, weight)
+			if p.TopWeightKg == nil || weight > *p.TopWeightKg {
+				p.TopWeightKg = &weight
+			}
+			if est := Estimate1RM(weight, reps); p.Estimated1RM == nil || est > *p.Estimated1RM {
+				p.Estimated1RM = &est
+			}
+		}
+
+		if p.Estimated1RM != nil {
+			if *p.Estimated1RM > best1RM {
+				best1RM = *p.Estimated1RM
+			}
+			est := roundTo(*p.Estimated1RM, 1)
+			p.Estimated1RM = &est
+		}
+		if len(weights) > 0 && best1RM > 0 {
+			sum := 0.0
+			for _, w := range weights {
+				sum += w
+			}
+			intensity := roundTo(sum/float64(len(weights))/best1RM*100, 1)
+			p.RelativeIntensity = &intensity
+		}
+		p.VolumeKg = roundTo(p.VolumeKg, 1)
+
+		if !sess.date.Before(since) {
+			points = append(points, p)
+		}
+	}
+	return points, nil
+}
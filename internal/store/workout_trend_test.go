@@ -0,0 +1,89 @@
+package store
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGetExerciseTrend(t *testing.T) {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	userID := int64(1)
+	group, _ := s.CreateWorkoutGroup("G", "", false, userID, "[1]", "09:00", 15)
+	variant, _ := s.CreateWorkoutVariant(group.ID, "V", nil, "")
+
+	type set struct {
+		name         string
+		sets, reps   int
+		weight       float64
+		noWeightLogs bool
+	}
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	for _, sess := range []struct {
+		date time.Time
+		logs []set
+	}{
+		{day(6), []set{{name: "Squat", sets: 3, reps: 5, weight: 110}}},
+		{day(13), []set{{name: "squat", sets: 3, reps: 5, weight: 105}, {name: "Squat", sets: 2, reps: 10, weight: 80}, {name: "Bench Press", sets: 3, reps: 8, weight: 60}}},
+		{day(20), []set{{name: "Squat", sets: 5, reps: 3, weight: 100}}},
+	} {
+		session, err := s.CreateWorkoutSession(group.ID, variant.ID, userID, sess.date, "09:00")
+		if err != nil {
+			t.Fatalf("CreateWorkoutSession: %v", err)
+		}
+		for _, l := range sess.logs {
+			sets, reps, weight := l.sets, l.reps, l.weight
+			if _, err := s.LogExercise(session.ID, 0, l.name, &sets, &reps, &weight, "completed", ""); err != nil {
+				t.Fatalf("LogExercise: %v", err)
+			}
+		}
+	}
+
+	if got := Estimate1RM(100, 1); got != 100 {
+		t.Errorf("Expected a single rep to be the 1RM, got %v", got)
+	}
+	if got := Estimate1RM(100, 6); got != 120 {
+		t.Errorf("Expected 120 for 100kg x 6, got %v", got)
+	}
+
+	// The first session falls outside the window but its 1RM (110 x 5 = 128.3) still
+	// sets the bar for the later ones
+	points, err := s.GetExerciseTrend(userID, "SQUAT", day(10))
+	if err != nil {
+		t.Fatalf("GetExerciseTrend: %v", err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 sessions in range, got %+v", points)
+	}
+
+	approx := func(v *float64, want float64) bool { return v != nil && math.Abs(*v-want) < 1e-9 }
+
+	// 3x5 @105 and 2x10 @80: 1575 + 1600 kg, average 92.5 kg of 128.3
+	p := points[0]
+	if p.Date != "2025-01-13" || p.Sets != 5 || p.Reps != 35 || p.VolumeKg != 3175 {
+		t.Errorf("Unexpected second session: %+v", p)
+	}
+	if !approx(p.TopWeightKg, 105) || !approx(p.Estimated1RM, 122.5) || !approx(p.RelativeIntensity, 72.1) {
+		t.Errorf("Expected top 105, 1RM 122.5 and intensity 72.1, got %v, %v, %v", *p.TopWeightKg, *p.Estimated1RM, *p.RelativeIntensity)
+	}
+
+	// 5x3 @100: less volume than the first session, 77.9% of the best 1RM
+	p = points[1]
+	if p.Date != "2025-01-20" || p.VolumeKg != 1500 || !approx(p.Estimated1RM, 110) || !approx(p.RelativeIntensity, 77.9) {
+		t.Errorf("Unexpected third session: %+v", p)
+	}
+
+	all, _ := s.GetExerciseTrend(userID, "Squat", time.Time{})
+	if len(all) != 3 || all[0].VolumeKg != 1650 || !approx(all[0].Estimated1RM, 128.3) || !approx(all[0].RelativeIntensity, 85.7) {
+		t.Errorf("Unexpected first session: %+v", all)
+	}
+
+	if none, err := s.GetExerciseTrend(userID, "Deadlift", time.Time{}); err != nil || len(none) != 0 {
+		t.Errorf("Expected no points for an unlogged exercise, got %+v (%v)", none, err)
+	}
+}
@@ -0,0 +1,71 @@
+package store
+
+import "fmt"
+
+// ValidateVariantOrder checks that ids lists each of the group's variants exactly once
+func ValidateVariantOrder(variants []WorkoutVariant, ids []int64) error {
+	if len(ids) != len(variants) {
+		return fmt.Errorf("expected all %d variants of the group, got %d", len(variants), len(ids))
+	}
+	inGroup := make(map[int64]bool, len(variants))
+	for _, v := range variants {
+		inGroup[v.ID] = true
+	}
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if !inGroup[id] {
+			return fmt.Errorf("variant %d does not belong to the group", id)
+		}
+		if seen[id] {
+			return fmt.Errorf("variant %d is listed twice", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// ReorderWorkoutVariants sets rotation_order 1..n following ids. The rotation keeps its
+// place in the cycle: if the current variant moved, the variant now in its old position
+// becomes current, so "day 2 is next" still holds after rearranging the days.
+func (s *Store) ReorderWorkoutVariants(groupID int64, ids []int64) error {
+	variants, err := s.ListVariantsByGroup(groupID)
+	if err != nil {
+		return err
+	}
+	if err := ValidateVariantOrder(variants, ids); err != nil {
+		return err
+	}
+	state, err := s.GetRotationState(groupID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range ids {
+		if _, err := tx.Exec("UPDATE workout_variants SET rotation_order = ? WHERE id = ?", i+1, id); err != nil {
+			return err
+		}
+	}
+
+	if state != nil {
+		for i, v := range variants {
+			if v.ID == state.CurrentVariantID && ids[i] != v.ID {
+				_, err := tx.Exec(`
+					UPDATE workout_rotation_state
+					SET current_variant_id = ?, updated_at = CURRENT_TIMESTAMP
+					WHERE group_id = ?`, ids[i], groupID)
+				if err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	return tx.Commit()
+}
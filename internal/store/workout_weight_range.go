@@ -0,0 +1,54 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ValidateWeightRange checks optional target weight bounds: both non-negative and min <= max
+func ValidateWeightRange(min, max *float64) error {
+	if (min != nil && *min < 0) || (max != nil && *max < 0) {
+		return fmt.Errorf("target weights must not be negative")
+	}
+	if min != nil && max != nil && *min > *max {
+		return fmt.Errorf("target_weight_min must not exceed target_weight_max")
+	}
+	return nil
+}
+
+// SetExerciseWeightRange stores the prescribed weight range of an exercise; nil clears a bound
+func (s *Store) SetExerciseWeightRange(id int64, min, max *float64) error {
+	if err := ValidateWeightRange(min, max); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("UPDATE workout_exercises SET target_weight_min = ?, target_weight_max = ? WHERE id = ?", min, max, id)
+	return err
+}
+
+// setWeightRange copies scanned range bounds onto the exercise
+func (e *WorkoutExercise) setWeightRange(min, max sql.NullFloat64) {
+	if min.Valid {
+		e.TargetWeightMin = &min.Float64
+	}
+	if max.Valid {
+		e.TargetWeightMax = &max.Float64
+	}
+}
+
+// TargetWeightLabel formats the prescribed weight, e.g. "20-25kg" for a range or "20kg"
+// for a single target. Empty if the exercise has no weight.
+func (e *WorkoutExercise) TargetWeightLabel() string {
+	switch {
+	case e.TargetWeightMin != nil && e.TargetWeightMax != nil && *e.TargetWeightMin != *e.TargetWeightMax:
+		return fmt.Sprintf("%g-%gkg", *e.TargetWeightMin, *e.TargetWeightMax)
+	case e.TargetWeightMin != nil && e.TargetWeightMax != nil:
+		return fmt.Sprintf("%gkg", *e.TargetWeightMin)
+	case e.TargetWeightMin != nil:
+		return fmt.Sprintf("≥%gkg", *e.TargetWeightMin)
+	case e.TargetWeightMax != nil:
+		return fmt.Sprintf("≤%gkg", *e.TargetWeightMax)
+	case e.TargetWeightKg != nil:
+		return fmt.Sprintf("%gkg", *e.TargetWeightKg)
+	}
+	return ""
+}
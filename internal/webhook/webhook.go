@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// Event types that can be forwarded to webhooks
+const (
+	EventIntakeConfirmed = "intake.confirmed"
+	EventBPCrisis        = "bp.crisis"
+	EventLowStock        = "stock.low"
+)
+
+// Events lists every supported event type
+var Events = []string{EventIntakeConfirmed, EventBPCrisis, EventLowStock}
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body
+const SignatureHeader = "X-Webhook-Signature"
+
+type Service struct {
+	store       *store.Store
+	httpClient  *http.Client
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+func New(store *store.Store) *Service {
+	return &Service{
+		store:       store,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: 3,
+		retryDelay:  5 * time.Second,
+	}
+}
+
+// SetRetryDelay overrides the base delay between delivery attempts (used by tests).
+func (s *Service) SetRetryDelay(d time.Duration) {
+	s.retryDelay = d
+}
+
+// Payload is the JSON body posted to webhooks
+type Payload struct {
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body using secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch delivers an event to all subscribed webhooks of the user asynchronously.
+// Safe to call on a nil Service.
+func (s *Service) Dispatch(userID int64, event string, data interface{}) {
+	if s == nil {
+		return
+	}
+
+	hooks, err := s.store.GetWebhooksForEvent(userID, event)
+	if err != nil {
+		log.Printf("Failed to load webhooks for %s: %v", event, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(Payload{Event: event, Timestamp: time.Now().UTC(), Data: data})
+	if err != nil {
+		log.Printf("Failed to marshal webhook payload: %v", err)
+		return
+	}
+
+	for _, wh := range hooks {
+		go s.deliver(wh, event, body)
+	}
+}
+
+// deliver posts the payload, retrying with a growing delay on failure
+func (s *Service) deliver(wh store.Webhook, event string, body []byte) {
+	var lastErr error
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(s.retryDelay * time.Duration(attempt-1))
+		}
+		if lastErr = s.post(wh, event, body); lastErr == nil {
+			return
+		}
+	}
+	log.Printf("Webhook %d delivery of %s failed after %d attempts: %v", wh.ID, event, s.maxAttempts, lastErr)
+}
+
+func (s *Service) post(wh store.Webhook, event string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(wh.Secret, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// NotifyIntakeConfirmed forwards a confirmed intake with its medication details
+func (s *Service) NotifyIntakeConfirmed(userID, intakeID int64) {
+	if s == nil {
+		return
+	}
+
+	intake, err := s.store.GetIntake(intakeID)
+	if err != nil || intake == nil {
+		log.Printf("Webhook: intake %d not found: %v", intakeID, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"intake_id":     intake.ID,
+		"medication_id": intake.MedicationID,
+		"scheduled_at":  intake.ScheduledAt,
+		"taken_at":      intake.TakenAt,
+		"notes":         intake.Notes,
+	}
+	if med, err := s.store.GetMedication(intake.MedicationID); err == nil && med != nil {
+		data["medication_name"] = med.Name
+		data["dosage"] = med.Dosage
+	}
+
+	s.Dispatch(userID, EventIntakeConfirmed, data)
+}
+
+// NotifyBPReading forwards a reading if it falls in the hypertensive crisis category
+func (s *Service) NotifyBPReading(userID int64, bp *store.BloodPressure) {
+	if s == nil || bp.Category != "Hypertensive Crisis" {
+		return
+	}
+
+	s.Dispatch(userID, EventBPCrisis, map[string]interface{}{
+		"reading_id":  bp.ID,
+		"measured_at": bp.MeasuredAt,
+		"systolic":    bp.Systolic,
+		"diastolic":   bp.Diastolic,
+		"pulse":       bp.Pulse,
+		"category":    bp.Category,
+	})
+}
+
+// NotifyLowStock forwards the medications that are running low
+func (s *Service) NotifyLowStock(userID int64, meds []store.Medication) {
+	if s == nil || len(meds) == 0 {
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(meds))
+	for _, m := range meds {
+		items = append(items, map[string]interface{}{
+			"medication_id":   m.ID,
+			"medication_name": m.Name,
+			"inventory_count": m.InventoryCount,
+			"days_remaining":  s.store.GetDaysOfStockRemaining(userID, &m),
+		})
+	}
+
+	s.Dispatch(userID, EventLowStock, map[string]interface{}{"medications": items})
+}
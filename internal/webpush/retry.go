@@ -0,0 +1,111 @@
+package webpush
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// MaxPushAttempts is how many times a push is tried, the first send included, before
+// it is dropped from the retry queue
+const MaxPushAttempts = 5
+
+// retryBaseDelay is the wait before the first retry; it doubles with every attempt
+const retryBaseDelay = time.Minute
+
+// retryDelay returns how long to wait after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	return retryBaseDelay << (attempts - 1)
+}
+
+// isTransient reports whether a failed push may succeed later: network errors,
+// timeouts, rate limiting and server errors. Other responses, and local failures like
+// encrypting the payload or disabling a gone subscription, won't change on retry.
+func isTransient(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr)
+}
+
+// enqueueRetry queues a transiently failed push. Best-effort, like the notification log.
+func (s *Service) enqueueRetry(userID int64, endpoint string, payload []byte, sendErr error) {
+	next := time.Now().Add(retryDelay(1))
+	if err := s.store.EnqueuePushRetry(userID, endpoint, string(payload), next, sendErr.Error()); err != nil {
+		log.Printf("Failed to queue web push retry for %s: %v", endpoint, err)
+	}
+}
+
+// RetryQueued sends the queued pushes that are due by now. Delivered pushes leave the
+// queue, as do pushes to subscriptions that are gone (410 or disabled), pushes failing
+// permanently and pushes that reached MaxPushAttempts; the rest wait twice as long as
+// before for their next attempt.
+func (s *Service) RetryQueued(ctx context.Context, now time.Time) error {
+	retries, err := s.store.GetDuePushRetries(now)
+	if err != nil {
+		return err
+	}
+
+	subsByUser := map[int64]map[string]store.PushSubscription{}
+	for _, r := range retries {
+		subs, ok := subsByUser[r.UserID]
+		if !ok {
+			list, err := s.store.GetPushSubscriptions(r.UserID)
+			if err != nil {
+				return err
+			}
+			subs = make(map[string]store.PushSubscription, len(list))
+			for _, sub := range list {
+				subs[sub.Endpoint] = sub
+			}
+			subsByUser[r.UserID] = subs
+		}
+
+		sub, ok := subs[r.Endpoint]
+		if !ok {
+			// Unsubscribed or disabled since the push failed
+			if err := s.store.DeletePushRetry(r.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		sendErr := s.sendToSubscription(ctx, sub, []byte(r.Payload))
+		if err := s.store.LogNotification(r.UserID, store.ChannelWebPush, r.Endpoint, retrySummary(r.Payload), sendErr); err != nil {
+			log.Printf("Failed to log notification: %v", err)
+		}
+
+		attempts := r.Attempts + 1
+		switch {
+		case sendErr == nil:
+			err = s.store.DeletePushRetry(r.ID)
+		case !isTransient(sendErr) || attempts >= MaxPushAttempts:
+			log.Printf("Giving up on web push to %s after %d attempts: %v", r.Endpoint, attempts, sendErr)
+			err = s.store.DeletePushRetry(r.ID)
+		default:
+			err = s.store.ReschedulePushRetry(r.ID, now.Add(retryDelay(attempts)), sendErr.Error())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retrySummary describes a queued payload for the notification log
+func retrySummary(payload string) string {
+	var p NotificationPayload
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		return "retry"
+	}
+	return strings.TrimSpace("retry: " + p.Title + ": " + p.Body)
+}
@@ -1,23 +1,34 @@
 package webpush
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/SherClockHolmes/webpush-go"
 	"github.com/korjavin/medicationtrackerbot/internal/store"
 )
 
+// DefaultConcurrency is how many subscriptions are pushed to in parallel
+const DefaultConcurrency = 4
+
+// defaultSendTimeout bounds a single push request so a slow endpoint can't stall a batch
+const defaultSendTimeout = 10 * time.Second
+
 type Service struct {
 	store           *store.Store
 	vapidPublicKey  string
 	vapidPrivateKey string
 	vapidSubject    string
+	concurrency     int
+	sendTimeout     time.Duration
 }
 
 func New(store *store.Store, publicKey, privateKey, subject string) *Service {
@@ -26,7 +37,22 @@ func New(store *store.Store, publicKey, privateKey, subject string) *Service {
 		vapidPublicKey:  publicKey,
 		vapidPrivateKey: privateKey,
 		vapidSubject:    subject,
+		concurrency:     DefaultConcurrency,
+		sendTimeout:     defaultSendTimeout,
+	}
+}
+
+// SetConcurrency sets how many subscriptions are pushed to in parallel (minimum 1)
+func (s *Service) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
 	}
+	s.concurrency = n
+}
+
+// SetSendTimeout overrides the per-request timeout (used by tests).
+func (s *Service) SetSendTimeout(d time.Duration) {
+	s.sendTimeout = d
 }
 
 // NotificationPayload matches the structure expected by the SW
@@ -83,7 +109,7 @@ func (s *Service) SendMedicationNotification(ctx context.Context, userID int64,
 		},
 	}
 
-	return s.sendToUser(userID, payload)
+	return s.sendToUser(ctx, userID, payload)
 }
 
 func (s *Service) SendLowStockNotification(ctx context.Context, userID int64, meds []store.Medication) error {
@@ -109,27 +135,61 @@ func (s *Service) SendLowStockNotification(ctx context.Context, userID int64, me
 		},
 	}
 
-	return s.sendToUser(userID, payload)
+	return s.sendToUser(ctx, userID, payload)
 }
 
+// SendInteractionAlert warns about a severe interaction between active medications
+func (s *Service) SendInteractionAlert(ctx context.Context, userID int64, body string) error {
+	if s.vapidPublicKey == "" || s.vapidPrivateKey == "" {
+		return nil
+	}
+
+	payload := NotificationPayload{
+		Title: "Severe Drug Interaction",
+		Body:  body,
+		Icon:  "/static/android-chrome-192x192.png",
+		Tag:   "interaction",
+		Data: map[string]interface{}{
+			"type": "interaction",
+		},
+	}
+
+	return s.sendToUser(ctx, userID, payload)
+}
+
+// SendWorkoutNotification announces an upcoming workout session. Clicking it opens the
+// app with the session ready to start.
 func (s *Service) SendWorkoutNotification(ctx context.Context, userID int64, session *store.WorkoutSession, group *store.WorkoutGroup, variant *store.WorkoutVariant) error {
 	if s.vapidPublicKey == "" || s.vapidPrivateKey == "" {
 		return nil
 	}
 
+	return s.sendToUser(ctx, userID, workoutPayload(session, group, variant))
+}
+
+// workoutPayload builds the push for a workout session, including a deep link to start it
+func workoutPayload(session *store.WorkoutSession, group *store.WorkoutGroup, variant *store.WorkoutVariant) NotificationPayload {
 	title := "Time to Workout!"
+	if group.NotificationAdvanceMinutes > 0 {
+		title = fmt.Sprintf("Workout in %d minutes", group.NotificationAdvanceMinutes)
+	}
 	body := fmt.Sprintf("%s - %s", group.Name, variant.Name)
+	if group.ScheduledTime != "" {
+		body += " at " + group.ScheduledTime
+	}
 
-	payload := NotificationPayload{
+	return NotificationPayload{
 		Title: title,
 		Body:  body,
 		Icon:  "/static/android-chrome-192x192.png",
 		Tag:   fmt.Sprintf("workout-%d", session.ID),
 		Data: map[string]interface{}{
-			"type":       "workout",
-			"session_id": session.ID,
-			"group_name": group.Name,
-			"variant":    variant.Name,
+			"type":           "workout",
+			"session_id":     session.ID,
+			"group_name":     group.Name,
+			"variant":        variant.Name,
+			"scheduled_time": group.ScheduledTime,
+			"url":            fmt.Sprintf("/?action=workout_start&session_id=%d", session.ID),
 		},
 		Actions: []NotificationAction{
 			{Action: "start", Title: "Start"},
@@ -137,8 +197,6 @@ func (s *Service) SendWorkoutNotification(ctx context.Context, userID int64, ses
 			{Action: "skip", Title: "Skip"},
 		},
 	}
-
-	return s.sendToUser(userID, payload)
 }
 
 func (s *Service) SendBPReminderNotification(ctx context.Context, userID int64, enhanced bool) error {
@@ -168,7 +226,7 @@ func (s *Service) SendBPReminderNotification(ctx context.Context, userID int64,
 		},
 	}
 
-	return s.sendToUser(userID, payload)
+	return s.sendToUser(ctx, userID, payload)
 }
 
 // SendWeightReminderNotification sends a weight reminder notification via Web Push
@@ -195,10 +253,18 @@ func (s *Service) SendWeightReminderNotification(ctx context.Context, userID int
 		},
 	}
 
-	return s.sendToUser(userID, payload)
+	return s.sendToUser(ctx, userID, payload)
 }
 
-func (s *Service) sendToUser(userID int64, payload NotificationPayload) error {
+// sendResult is the outcome of one push within a batch
+type sendResult struct {
+	endpoint string
+	err      error
+}
+
+// sendToUser pushes the payload to all of the user's subscriptions using a bounded
+// worker pool and waits for the batch to finish. Failures are joined into one error.
+func (s *Service) sendToUser(ctx context.Context, userID int64, payload NotificationPayload) error {
 	subs, err := s.store.GetPushSubscriptions(userID)
 	if err != nil {
 		return err
@@ -213,17 +279,63 @@ func (s *Service) sendToUser(userID int64, payload NotificationPayload) error {
 		return err
 	}
 
-	// Send to all user subscriptions
+	jobs := make(chan store.PushSubscription)
+	var (
+		mu      sync.Mutex
+		errs    []error
+		results []sendResult
+		wg      sync.WaitGroup
+	)
+
+	workers := min(s.concurrency, len(subs))
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sub := range jobs {
+				err := s.sendToSubscription(ctx, sub, payloadBytes)
+				if err != nil {
+					log.Printf("WebPush error for %s: %v", sub.Endpoint, err)
+				}
+				mu.Lock()
+				results = append(results, sendResult{endpoint: sub.Endpoint, err: err})
+				if err != nil {
+					errs = append(errs, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
 	for _, sub := range subs {
-		go func(subscription store.PushSubscription) {
-			s.sendToSubscription(subscription, payloadBytes)
-		}(sub)
+		jobs <- sub
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Logged after the batch so the workers don't contend for SQLite writes.
+	// Best-effort: a failed log write must not turn into a failed push.
+	summary := strings.TrimSpace(payload.Title + ": " + payload.Body)
+	for _, res := range results {
+		if err := s.store.LogNotification(userID, store.ChannelWebPush, res.endpoint, summary, res.err); err != nil {
+			log.Printf("Failed to log notification: %v", err)
+		}
+		if res.err != nil && isTransient(res.err) {
+			s.enqueueRetry(userID, res.endpoint, bytes.Clone(payloadBytes), res.err)
+		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
-func (s *Service) sendToSubscription(sub store.PushSubscription, payload []byte) {
+// sendToSubscription delivers one push, disabling the subscription if the endpoint reports it gone
+func (s *Service) sendToSubscription(ctx context.Context, sub store.PushSubscription, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.sendTimeout)
+	defer cancel()
+
+	// webpush-go pads the payload in place, so concurrent sends each need their own copy
+	payload = bytes.Clone(payload)
+
 	wpSub := &webpush.Subscription{
 		Endpoint: sub.Endpoint,
 		Keys: webpush.Keys{
@@ -232,15 +344,14 @@ func (s *Service) sendToSubscription(sub store.PushSubscription, payload []byte)
 		},
 	}
 
-	resp, err := webpush.SendNotification(payload, wpSub, &webpush.Options{
+	resp, err := webpush.SendNotificationWithContext(ctx, payload, wpSub, &webpush.Options{
 		Subscriber:      s.vapidSubject,
 		VAPIDPublicKey:  s.vapidPublicKey,
 		VAPIDPrivateKey: s.vapidPrivateKey,
 		TTL:             3600 * 12, // 12 hours
 	})
 	if err != nil {
-		log.Printf("WebPush error for %s: %v", sub.Endpoint, err)
-		return
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -248,9 +359,19 @@ func (s *Service) sendToSubscription(sub store.PushSubscription, payload []byte)
 		// Subscription is no longer valid
 		log.Printf("WebPush subscription gone: %s", sub.Endpoint)
 		if err := s.store.DisablePushSubscription(sub.Endpoint); err != nil {
-			log.Printf("Failed to disable subscription: %v", err)
+			return fmt.Errorf("failed to disable subscription: %w", err)
 		}
 	} else if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		log.Printf("WebPush unexpected status %d for %s", resp.StatusCode, sub.Endpoint)
+		return &statusError{code: resp.StatusCode}
 	}
+	return nil
+}
+
+// statusError is an unexpected response from a push endpoint
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.code)
 }
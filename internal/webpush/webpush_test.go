@@ -0,0 +1,264 @@
+package webpush
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SherClockHolmes/webpush-go"
+	"github.com/korjavin/medicationtrackerbot/internal/store"
+)
+
+// subscriptionKeys returns a valid browser-side auth secret and P-256 public key
+func subscriptionKeys(t *testing.T) (auth, p256dh string) {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	secret := make([]byte, 16)
+	rand.Read(secret)
+	return base64.RawURLEncoding.EncodeToString(secret), base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+}
+
+func TestSendToUser_SlowEndpointDoesNotStallBatch(t *testing.T) {
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate VAPID keys: %v", err)
+	}
+
+	const userID = int64(1)
+	var delivered atomic.Int32
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(1500 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer slow.Close()
+	gone := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer gone.Close()
+
+	endpoints := []string{slow.URL, gone.URL + "/gone"}
+	for i := 0; i < 4; i++ {
+		fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			delivered.Add(1)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer fast.Close()
+		endpoints = append(endpoints, fast.URL)
+	}
+	for _, ep := range endpoints {
+		auth, p256dh := subscriptionKeys(t)
+		if err := s.CreatePushSubscription(userID, ep, auth, p256dh); err != nil {
+			t.Fatalf("Failed to create subscription: %v", err)
+		}
+	}
+
+	svc := New(s, publicKey, privateKey, "mailto:test@example.com")
+	svc.SetConcurrency(2)
+	svc.SetSendTimeout(200 * time.Millisecond)
+
+	start := time.Now()
+	err = svc.SendLowStockNotification(context.Background(), userID, []store.Medication{{Name: "Aspirin"}})
+	elapsed := time.Since(start)
+
+	if elapsed > time.Second {
+		t.Errorf("Batch took %v, expected the slow endpoint to time out", elapsed)
+	}
+	if err == nil {
+		t.Error("Expected the slow endpoint's timeout to be reported")
+	}
+	if got := delivered.Load(); got != 4 {
+		t.Errorf("Expected 4 fast deliveries, got %d", got)
+	}
+
+	// The 410 endpoint is pruned, the others stay enabled
+	subs, err := s.GetPushSubscriptions(userID)
+	if err != nil {
+		t.Fatalf("GetPushSubscriptions failed: %v", err)
+	}
+	if len(subs) != len(endpoints)-1 {
+		t.Errorf("Expected %d enabled subscriptions, got %d", len(endpoints)-1, len(subs))
+	}
+	for _, sub := range subs {
+		if sub.Endpoint == gone.URL+"/gone" {
+			t.Error("Expected gone subscription to be disabled")
+		}
+	}
+}
+
+func TestSendToUser_LogsAttempts(t *testing.T) {
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate VAPID keys: %v", err)
+	}
+
+	const userID = int64(1)
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ok.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	for _, ep := range []string{ok.URL, broken.URL} {
+		auth, p256dh := subscriptionKeys(t)
+		if err := s.CreatePushSubscription(userID, ep, auth, p256dh); err != nil {
+			t.Fatalf("Failed to create subscription: %v", err)
+		}
+	}
+
+	svc := New(s, publicKey, privateKey, "mailto:test@example.com")
+	if err := svc.SendWeightReminderNotification(context.Background(), userID); err == nil {
+		t.Error("Expected the broken endpoint to be reported")
+	}
+
+	entries, err := s.GetNotificationLog(userID, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetNotificationLog failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 log entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if e.Channel != store.ChannelWebPush || e.Summary == "" {
+			t.Errorf("Unexpected entry: %+v", e)
+		}
+		switch e.Target {
+		case ok.URL:
+			if !e.Success || e.Error != "" {
+				t.Errorf("Expected a successful entry for the working endpoint, got %+v", e)
+			}
+		case broken.URL:
+			if e.Success || !strings.Contains(e.Error, "500") {
+				t.Errorf("Expected a failed entry for the broken endpoint, got %+v", e)
+			}
+		default:
+			t.Errorf("Unexpected target %q", e.Target)
+		}
+	}
+}
+
+func TestRetryQueued(t *testing.T) {
+	s, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate VAPID keys: %v", err)
+	}
+
+	const userID = int64(1)
+	var flakyStatus atomic.Int32
+	flakyStatus.Store(http.StatusServiceUnavailable)
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(flakyStatus.Load()))
+	}))
+	defer flaky.Close()
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+
+	for _, ep := range []string{flaky.URL, rejecting.URL} {
+		auth, p256dh := subscriptionKeys(t)
+		if err := s.CreatePushSubscription(userID, ep, auth, p256dh); err != nil {
+			t.Fatalf("Failed to create subscription: %v", err)
+		}
+	}
+
+	svc := New(s, publicKey, privateKey, "mailto:test@example.com")
+	if err := svc.SendBPReminderNotification(context.Background(), userID, false); err == nil {
+		t.Error("Expected the failures to be reported")
+	}
+
+	// Only the transient 503 is queued; a 400 won't succeed on retry
+	retries, err := s.GetPushRetries()
+	if err != nil {
+		t.Fatalf("GetPushRetries failed: %v", err)
+	}
+	if len(retries) != 1 || retries[0].Endpoint != flaky.URL || retries[0].Attempts != 1 {
+		t.Fatalf("Expected one queued retry for the flaky endpoint, got %+v", retries)
+	}
+
+	// Not due yet
+	if err := svc.RetryQueued(context.Background(), time.Now()); err != nil {
+		t.Fatalf("RetryQueued failed: %v", err)
+	}
+	if retries, _ := s.GetPushRetries(); len(retries) != 1 || retries[0].Attempts != 1 {
+		t.Fatalf("Expected the retry to wait for its backoff, got %+v", retries)
+	}
+
+	// Still failing: attempt counted and the backoff doubles
+	now := time.Now().Add(retryDelay(1))
+	if err := svc.RetryQueued(context.Background(), now); err != nil {
+		t.Fatalf("RetryQueued failed: %v", err)
+	}
+	retries, _ = s.GetPushRetries()
+	if len(retries) != 1 || retries[0].Attempts != 2 || !retries[0].NextAttemptAt.Equal(now.Add(retryDelay(2))) {
+		t.Fatalf("Expected a second attempt rescheduled %v later, got %+v", retryDelay(2), retries)
+	}
+
+	// Success clears the queue
+	flakyStatus.Store(http.StatusCreated)
+	if err := svc.RetryQueued(context.Background(), now.Add(retryDelay(2))); err != nil {
+		t.Fatalf("RetryQueued failed: %v", err)
+	}
+	if retries, _ := s.GetPushRetries(); len(retries) != 0 {
+		t.Errorf("Expected the delivered push to leave the queue, got %+v", retries)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &statusError{code: http.StatusBadGateway}, true},
+		{"rate limited", &statusError{code: http.StatusTooManyRequests}, true},
+		{"rejected", &statusError{code: http.StatusBadRequest}, false},
+		{"timeout", fmt.Errorf("send: %w", context.DeadlineExceeded), true},
+		{"network", &url.Error{Op: "Post", URL: "https://push.example.com", Err: errors.New("connection refused")}, true},
+		{"encryption", errors.New("crypto/ecdh: invalid public key"), false},
+		{"disable failed", fmt.Errorf("failed to disable subscription: %w", errors.New("database is locked")), false},
+	}
+	for _, c := range cases {
+		if got := isTransient(c.err); got != c.want {
+			t.Errorf("%s: isTransient(%v) = %v, want %v", c.name, c.err, got, c.want)
+		}
+	}
+}
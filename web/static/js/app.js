@@ -428,6 +428,7 @@ async function apiCall(endpoint, method = "GET", body = null) {
 // State
 let medications = [];
 let editingMedId = null;
+let loadedInventoryCount = null; // Inventory count the edit form was loaded with
 
 // Helper for European Date Format (DD.MM.YYYY HH:MM)
 const formatDate = (dateStr) => {
@@ -537,6 +538,7 @@ window.reloadCurrentTab = reloadCurrentTab;
 
 function showAddModal() {
     editingMedId = null;
+    loadedInventoryCount = null;
     document.getElementById('modal-overlay').classList.remove('hidden');
     document.getElementById('med-modal').classList.remove('hidden');
 
@@ -598,6 +600,7 @@ function showEditModal(id) {
     const hasInventory = med.inventory_count !== null && med.inventory_count !== undefined;
     document.getElementById('med-track-inventory').checked = hasInventory;
     document.getElementById('med-inventory-count').value = hasInventory ? med.inventory_count : '';
+    loadedInventoryCount = hasInventory ? med.inventory_count : null;
     if (hasInventory) {
         document.getElementById('inventory-fields').classList.remove('hidden');
         document.getElementById('restock-section').style.display = 'block';
@@ -717,6 +720,7 @@ async function handleRestock() {
     if (res) {
         // Update displayed count
         document.getElementById('med-inventory-count').value = res.inventory_count;
+        loadedInventoryCount = res.inventory_count;
         qtyInput.value = '';
         loadRestockHistory(editingMedId);
         tg.showAlert(`Added ${qty} units. New total: ${res.inventory_count}`);
@@ -1179,6 +1183,9 @@ async function saveMedication() {
         end_date: endDateRaw ? new Date(endDateRaw).toISOString() : null,
         inventory_count: inventoryCount
     };
+    if (editingMedId && loadedInventoryCount !== null) {
+        payload.inventory_count_loaded = loadedInventoryCount;
+    }
 
     let res;
     if (editingMedId) {
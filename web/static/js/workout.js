@@ -475,7 +475,10 @@ async function loadExercisesForVariant(variantId) {
             const repsText = ex.target_reps_max
                 ? `${ex.target_reps_min}-${ex.target_reps_max}`
                 : `${ex.target_reps_min}`;
-            const weightText = ex.target_weight_kg ? ` @ ${ex.target_weight_kg}kg` : '';
+            let weightText = ex.target_weight_kg ? ` @ ${ex.target_weight_kg}kg` : '';
+            if (ex.target_weight_min != null && ex.target_weight_max != null) {
+                weightText = ` @ ${ex.target_weight_min}-${ex.target_weight_max}kg`;
+            }
 
             html += `
                 <div style="background: #f0f4ff; padding: 8px 10px; border-radius: 6px; margin-bottom: 6px; display: flex; justify-content: space-between; align-items: center;">
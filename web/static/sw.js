@@ -291,11 +291,14 @@ self.addEventListener('notificationclick', (event) => {
     } else if (data.type === 'workout') {
         // For workout, open the app for all actions for now to show the modal options
         // We could implement background handlers later
-        const params = new URLSearchParams();
-        params.set('action', 'workout_start');
-        if (data.session_id) params.set('session_id', data.session_id);
-
-        const url = '/?' + params.toString();
+        // The server sends a ready-made deep link; build one for older payloads
+        let url = data.url;
+        if (!url) {
+            const params = new URLSearchParams();
+            params.set('action', 'workout_start');
+            if (data.session_id) params.set('session_id', data.session_id);
+            url = '/?' + params.toString();
+        }
         event.waitUntil(clients.openWindow(url));
     } else if (data.type === 'bp_reminder') {
         if (action === 'bp_confirm') {